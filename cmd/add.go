@@ -1,10 +1,21 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
 	"github.com/surge-downloader/surge/internal/utils"
 )
 
@@ -19,6 +30,44 @@ var addCmd = &cobra.Command{
 
 		batchFile, _ := cmd.Flags().GetString("batch")
 		output, _ := cmd.Flags().GetString("output")
+		retryLast, _ := cmd.Flags().GetBool("last")
+		tags, _ := cmd.Flags().GetStringSlice("tag")
+		useArchive, _ := cmd.Flags().GetBool("download-archive")
+		headerLines, _ := cmd.Flags().GetStringArray("header")
+		connections, _ := cmd.Flags().GetInt("connections")
+		speedLimitKB, _ := cmd.Flags().GetInt64("speed-limit")
+		chunkSizeKB, _ := cmd.Flags().GetInt64("chunk-size")
+		userAgent, _ := cmd.Flags().GetString("user-agent")
+		aria2File, _ := cmd.Flags().GetString("aria2")
+		outputDocument, _ := cmd.Flags().GetString("output-document")
+		continueExisting, _ := cmd.Flags().GetBool("continue")
+		limitRate, _ := cmd.Flags().GetString("limit-rate")
+		tries, _ := cmd.Flags().GetInt("tries")
+
+		headers, err := utils.ParseHeaders(headerLines)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if connections < 0 || speedLimitKB < 0 || chunkSizeKB < 0 || tries < 0 {
+			fmt.Fprintln(os.Stderr, "Error: --connections, --speed-limit, --chunk-size and --tries cannot be negative")
+			os.Exit(1)
+		}
+		speedLimit := speedLimitKB * 1024
+		if limitRate != "" {
+			if speedLimitKB > 0 {
+				fmt.Fprintln(os.Stderr, "Error: use either --speed-limit or --limit-rate")
+				os.Exit(1)
+			}
+			if speedLimit, err = parseLimitRate(limitRate); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if outputDocument != "" && output != "" {
+			fmt.Fprintln(os.Stderr, "Error: use either --output or --output-document")
+			os.Exit(1)
+		}
 
 		// Collect URLs
 		var urls []string
@@ -36,33 +85,120 @@ var addCmd = &cobra.Command{
 			urls = append(urls, fileUrls...)
 		}
 
-		if len(urls) == 0 {
+		// 3. Most recent rejected/failed URL from history
+		if retryLast {
+			last, err := state.GetLastFailedURL()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading URL history: %v\n", err)
+				os.Exit(1)
+			}
+			if last == nil {
+				fmt.Println("No failed or rejected URLs in history.")
+				return
+			}
+			infof("Retrying %s\n", last.URL)
+			urls = append(urls, last.URL)
+		}
+
+		base := DownloadRequest{
+			Tags:            tags,
+			Path:            output,
+			DownloadArchive: useArchive,
+			Headers:         headers,
+			Connections:     connections,
+			SpeedLimit:      speedLimit,
+			ChunkSize:       chunkSizeKB * 1024,
+			UserAgent:       userAgent,
+			Tries:           tries,
+		}
+		var requests []DownloadRequest
+		for _, arg := range urls {
+			url, mirrors := ParseURLArg(arg)
+			if url == "" {
+				continue
+			}
+			req := base
+			req.URL, req.Mirrors = url, mirrors
+			requests = append(requests, req)
+		}
+
+		// 4. Entries of an aria2 session file
+		if aria2File != "" {
+			imported, err := readAria2Requests(aria2File, base)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading aria2 session: %v\n", err)
+				os.Exit(1)
+			}
+			requests = append(requests, imported...)
+		}
+
+		if len(requests) == 0 {
 			_ = cmd.Help()
 			return
 		}
+		if outputDocument != "" {
+			if len(requests) != 1 {
+				fmt.Fprintln(os.Stderr, "Error: --output-document takes a single URL")
+				os.Exit(1)
+			}
+			if err := setOutputDocument(&requests[0], outputDocument); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
 
 		baseURL, token, err := resolveAPIConnection(true)
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
-			os.Exit(1)
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCodeFor(err))
+		}
+
+		var existing []types.DownloadStatus
+		if continueExisting {
+			if existing, err = GetRemoteDownloads(baseURL, token); err != nil {
+				fmt.Fprintf(os.Stderr, "Error listing downloads for --continue: %v\n", err)
+				os.Exit(exitCodeFor(err))
+			}
 		}
 
 		// Send downloads to server
-		count := 0
-		for _, arg := range urls {
-			url, mirrors := ParseURLArg(arg)
-			if url == "" {
+		count, resumed := 0, 0
+		for _, req := range requests {
+			if d := findContinuable(existing, req); d != nil {
+				if d.Status != "paused" && d.Status != "error" {
+					infof("Skipped %s: already %s as %s\n", req.URL, d.Status, d.ID)
+					continue
+				}
+				if err := resumeOnServer(baseURL, token, d.ID); err != nil {
+					setExitCode(exitCodeFor(err))
+					fmt.Fprintf(os.Stderr, "Error resuming %s: %v\n", d.ID, err)
+					continue
+				}
+				infof("Resuming %s (%s)\n", d.Filename, d.ID)
+				resumed++
 				continue
 			}
-			if err := sendToServer(url, mirrors, output, baseURL, token); err != nil {
-				fmt.Printf("Error adding %s: %v\n", url, err)
+			err := sendRequestToServer(req, baseURL, token)
+			if err != nil {
+				setExitCode(exitCodeFor(err))
+			}
+			if errors.Is(err, errAlreadyDownloaded) {
+				infof("Skipped %s: already downloaded\n", req.URL)
+				continue
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error adding %s: %v\n", req.URL, err)
+				_ = state.RecordURLHistory(req.URL, state.URLHistoryRejected, err.Error())
 				continue
 			}
 			count++
 		}
 
 		if count > 0 {
-			fmt.Printf("Successfully added %d downloads.\n", count)
+			infof("Successfully added %d downloads.\n", count)
+		}
+		if resumed > 0 {
+			infof("Resumed %d downloads.\n", resumed)
 		}
 	},
 }
@@ -71,4 +207,145 @@ func init() {
 	rootCmd.AddCommand(addCmd)
 	addCmd.Flags().StringP("batch", "b", "", "File containing URLs to download (one per line)")
 	addCmd.Flags().StringP("output", "o", "", "Output directory")
+	addCmd.Flags().StringP("output-document", "O", "", "Save the download as this file, like wget -O (a single URL only)")
+	addCmd.Flags().BoolP("continue", "c", false, "Resume an unfinished download of the same URL instead of adding it again")
+	addCmd.Flags().String("limit-rate", "", "Speed limit for each of these downloads in bytes/sec, with k, m or g suffixes, e.g. 500k")
+	addCmd.Flags().Int("tries", 0, "Attempts per chunk before these downloads fail (0 = settings)")
+	addCmd.Flags().Bool("last", false, "Retry the most recent rejected or failed URL")
+	addCmd.Flags().StringSliceP("tag", "t", nil, "Tag the downloads, e.g. --tag work,iso (repeatable)")
+	addCmd.Flags().StringArrayP("header", "H", nil, "Send a header with the download, e.g. -H 'Cookie: id=1' (repeatable, kept for resumes)")
+	addCmd.Flags().Int("connections", 0, "Connections per host for these downloads (0 = settings)")
+	addCmd.Flags().Int64("speed-limit", 0, "Speed limit for each of these downloads in KB/s (0 = unlimited)")
+	addCmd.Flags().Int64("chunk-size", 0, "Minimum chunk size for these downloads in KB (0 = settings)")
+	addCmd.Flags().String("user-agent", "", "User-Agent for these downloads (default: settings)")
+	addCmd.Flags().String("aria2", "", "Add the downloads of an aria2 session or input file")
+	addCmd.Flags().Bool("download-archive", false, "Skip URLs that have been downloaded before (always on when the download_archive setting is)")
+}
+
+// setOutputDocument points req at file, as wget -O does: its directory,
+// resolved here since the server may run elsewhere, and its name
+func setOutputDocument(req *DownloadRequest, file string) error {
+	if file == "-" {
+		return errors.New("--output-document cannot write to stdout")
+	}
+	dir, name := filepath.Split(file)
+	if name == "" || name == "." || name == ".." {
+		return fmt.Errorf("--output-document needs a file name, got %q", file)
+	}
+	if dir == "" {
+		dir = "."
+	}
+	if !strings.HasPrefix(dir, "~") {
+		dir = utils.EnsureAbsPath(dir)
+	}
+	req.Path, req.Filename = dir, name
+	return nil
+}
+
+// parseLimitRate reads a wget --limit-rate such as 500k, 1.5m or 20000, in
+// bytes/sec; suffixes are powers of 1024
+func parseLimitRate(s string) (int64, error) {
+	mult := 1.0
+	num := strings.TrimSpace(s)
+	if num != "" {
+		switch num[len(num)-1] {
+		case 'k', 'K':
+			mult = types.KB
+		case 'm', 'M':
+			mult = types.MB
+		case 'g', 'G':
+			mult = types.GB
+		}
+		if mult != 1 {
+			num = num[:len(num)-1]
+		}
+	}
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid --limit-rate %q, want e.g. 500k or 2m", s)
+	}
+	return int64(n * mult), nil
+}
+
+// continuableStatuses are the downloads --continue picks up instead of adding
+// the URL again
+var continuableStatuses = []string{"queued", "downloading", "pausing", "paused", "error"}
+
+// findContinuable returns the unfinished download --continue picks up for
+// req: the same URL and, when req names its file, the same file name
+func findContinuable(downloads []types.DownloadStatus, req DownloadRequest) *types.DownloadStatus {
+	for i, d := range downloads {
+		if d.URL != req.URL || !slices.Contains(continuableStatuses, d.Status) {
+			continue
+		}
+		if req.Filename != "" && d.Filename != req.Filename {
+			continue
+		}
+		return &downloads[i]
+	}
+	return nil
+}
+
+// resumeOnServer resumes download id on the running server
+func resumeOnServer(baseURL, token, id string) error {
+	resp, err := doAPIRequest(http.MethodPost, baseURL, token, "/resume?id="+url.QueryEscape(id), nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server error: %s - %s", resp.Status, core.ReadAPIError(resp).Detail)
+	}
+	return nil
+}
+
+// readAria2Requests turns the entries of an aria2 session file into download
+// requests. What an entry sets wins over base, which comes from the flags;
+// entries Surge can't download, such as torrents, are reported and skipped.
+func readAria2Requests(path string, base DownloadRequest) ([]DownloadRequest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	entries, err := core.ParseAria2Session(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var requests []DownloadRequest
+	for _, e := range entries {
+		if !core.Aria2Supported(e) {
+			fmt.Printf("Skipped %s: only http and https downloads can be imported\n", strings.Join(e.URIs, " "))
+			continue
+		}
+		req := base
+		req.URL, req.Mirrors = e.URIs[0], e.URIs[1:]
+		req.Filename = e.Out
+		if e.Dir != "" {
+			req.Path = e.Dir
+		}
+		if len(e.Headers) > 0 {
+			req.Headers = maps.Clone(base.Headers)
+			if req.Headers == nil {
+				req.Headers = make(map[string]string, len(e.Headers))
+			}
+			maps.Copy(req.Headers, e.Headers)
+		}
+		if e.Connections > 0 {
+			req.Connections = e.Connections
+		}
+		if e.SpeedLimit > 0 {
+			req.SpeedLimit = e.SpeedLimit
+		}
+		if e.ChunkSize > 0 {
+			req.ChunkSize = e.ChunkSize
+		}
+		if e.UserAgent != "" {
+			req.UserAgent = e.UserAgent
+		}
+		requests = append(requests, req)
+	}
+	return requests, nil
 }
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestReadAria2Requests(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aria2.session")
+	session := "https://example.com/a.iso\thttps://mirror.example.org/a.iso\n dir=/data\n out=a.iso\n header=Cookie: id=1\n split=2\n" +
+		"magnet:?xt=urn:btih:abc\n" +
+		"https://example.com/b.iso\n"
+	if err := os.WriteFile(path, []byte(session), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	base := DownloadRequest{Path: "/downloads", Tags: []string{"aria2"}, Headers: map[string]string{"User-Agent": "surge"}, Connections: 8}
+	requests, err := readAria2Requests(path, base)
+	if err != nil {
+		t.Fatalf("readAria2Requests: %v", err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("requests = %+v, want the two http entries", requests)
+	}
+
+	a := requests[0]
+	if a.URL != "https://example.com/a.iso" || len(a.Mirrors) != 1 || a.Path != "/data" || a.Filename != "a.iso" || a.Connections != 2 {
+		t.Errorf("first request = %+v", a)
+	}
+	if a.Headers["Cookie"] != "id=1" || a.Headers["User-Agent"] != "surge" {
+		t.Errorf("headers = %v, want the entry's merged over the flags'", a.Headers)
+	}
+	if len(base.Headers) != 1 {
+		t.Errorf("the flags' headers were changed: %v", base.Headers)
+	}
+
+	b := requests[1]
+	if b.Path != "/downloads" || b.Connections != 8 || b.Tags[0] != "aria2" {
+		t.Errorf("second request = %+v, want the flags' settings", b)
+	}
+}
+
+func TestParseLimitRate(t *testing.T) {
+	for in, want := range map[string]int64{"20000": 20000, "500k": 500 * 1024, "1.5M": 1536 * 1024, "1g": 1 << 30} {
+		if got, err := parseLimitRate(in); err != nil || got != want {
+			t.Errorf("parseLimitRate(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "k", "fast", "-5k", "0"} {
+		if _, err := parseLimitRate(in); err == nil {
+			t.Errorf("parseLimitRate(%q) succeeded, want an error", in)
+		}
+	}
+}
+
+func TestSetOutputDocument(t *testing.T) {
+	var req DownloadRequest
+	if err := setOutputDocument(&req, "/data/ubuntu.iso"); err != nil || req.Path != "/data" || req.Filename != "ubuntu.iso" {
+		t.Errorf("absolute file: %+v, %v", req, err)
+	}
+	wd, _ := os.Getwd()
+	if err := setOutputDocument(&req, "out.bin"); err != nil || req.Path != wd || req.Filename != "out.bin" {
+		t.Errorf("bare name: %+v, %v; want it in %s", req, err, wd)
+	}
+	if err := setOutputDocument(&req, "~/isos/a.iso"); err != nil || req.Path != "~/isos/" {
+		t.Errorf("home path: %+v, %v; want ~ left to the server", req, err)
+	}
+	for _, in := range []string{"-", "/data/"} {
+		if err := setOutputDocument(&req, in); err == nil {
+			t.Errorf("setOutputDocument(%q) succeeded, want an error", in)
+		}
+	}
+}
+
+func TestFindContinuable(t *testing.T) {
+	downloads := []types.DownloadStatus{
+		{ID: "done", URL: "https://example.com/a.iso", Filename: "a.iso", Status: "completed"},
+		{ID: "other", URL: "https://example.com/a.iso", Filename: "renamed.iso", Status: "paused"},
+		{ID: "failed", URL: "https://example.com/a.iso", Filename: "a.iso", Status: "error"},
+	}
+	if d := findContinuable(downloads, DownloadRequest{URL: "https://example.com/a.iso"}); d == nil || d.ID != "other" {
+		t.Errorf("by URL: got %+v, want the first unfinished one", d)
+	}
+	if d := findContinuable(downloads, DownloadRequest{URL: "https://example.com/a.iso", Filename: "a.iso"}); d == nil || d.ID != "failed" {
+		t.Errorf("by URL and name: got %+v, want failed", d)
+	}
+	if d := findContinuable(downloads, DownloadRequest{URL: "https://example.com/b.iso"}); d != nil {
+		t.Errorf("new URL: got %+v, want nil", d)
+	}
+}
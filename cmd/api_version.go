@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Every route is served under apiVersionPrefix and, for clients written
+// before the API was versioned, at its bare path too; both answer alike until
+// a later version changes something. A client may pin the version it was
+// written for with apiVersionHeader.
+const (
+	apiVersion       = "1"
+	apiVersionPrefix = "/v" + apiVersion
+	apiVersionHeader = "X-Surge-API-Version"
+)
+
+// versionMiddleware serves /v1/<path> as <path>, rejects requests for a
+// version this daemon does not speak and names the version that answered in
+// every response
+func versionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(apiVersionHeader, apiVersion)
+
+		if requested := strings.TrimPrefix(strings.TrimSpace(r.Header.Get(apiVersionHeader)), "v"); requested != "" && requested != apiVersion {
+			httpError(w, fmt.Sprintf("Unsupported API version %q (this server speaks %s)", requested, apiVersion), http.StatusBadRequest)
+			return
+		}
+
+		rest, ok := strings.CutPrefix(r.URL.Path, apiVersionPrefix)
+		if !ok || (rest != "" && rest[0] != '/') {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if rest == "" {
+			rest = "/"
+		}
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = rest
+		r2.URL.RawPath = ""
+		next.ServeHTTP(w, r2)
+	})
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestVersionedAPI(t *testing.T) {
+	const token = "version-token"
+	baseURL := startAuthedTestServer(t, &fakeRemoteDownloadService{}, token)
+
+	get := func(path, version string, authed bool) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, baseURL+path, nil)
+		if authed {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if version != "" {
+			req.Header.Set(apiVersionHeader, version)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		_ = resp.Body.Close()
+		return resp
+	}
+
+	for _, tc := range []struct {
+		path, version string
+		authed        bool
+		want          int
+	}{
+		{"/v1/list", "", true, http.StatusOK},
+		{"/list", "", true, http.StatusOK}, // Unversioned paths keep working
+		{"/v1/list", "1", true, http.StatusOK},
+		{"/list", "v1", true, http.StatusOK},
+		{"/v1/list", "", false, http.StatusUnauthorized},
+		{"/v1/health", "", false, http.StatusOK},
+		{"/v1" + openAPIPath, "", false, http.StatusOK},
+		{"/v1/list", "2", true, http.StatusBadRequest},
+		{"/v1x/list", "", true, http.StatusNotFound},
+	} {
+		resp := get(tc.path, tc.version, tc.authed)
+		if resp.StatusCode != tc.want {
+			t.Errorf("GET %s (version %q, authed %v) = %d, want %d", tc.path, tc.version, tc.authed, resp.StatusCode, tc.want)
+		}
+		if got := resp.Header.Get(apiVersionHeader); got != apiVersion {
+			t.Errorf("GET %s: %s = %q, want %q", tc.path, apiVersionHeader, got, apiVersion)
+		}
+	}
+}
@@ -75,6 +75,10 @@ func TestCmd_AutoResume_Execution(t *testing.T) {
 	if err := state.SaveState(testURL, testDest, manualState); err != nil {
 		t.Fatal(err)
 	}
+	// The partial file a real pause leaves behind
+	if err := os.WriteFile(testDest+types.IncompleteSuffix, make([]byte, 500), 0o644); err != nil {
+		t.Fatal(err)
+	}
 
 	// 5. Initialize GlobalPool + GlobalService
 	GlobalProgressCh = make(chan any, 10)
@@ -106,3 +110,49 @@ func TestCmd_AutoResume_Execution(t *testing.T) {
 		t.Error("Download was not added to GlobalPool by resumePausedDownloads")
 	}
 }
+
+func TestResumableDownloads_SkipsDownloadsPausedOnPurpose(t *testing.T) {
+	state.CloseDB()
+	state.Configure(filepath.Join(t.TempDir(), "surge.db"))
+	t.Cleanup(state.CloseDB)
+
+	for _, e := range []types.DownloadEntry{
+		{ID: "by-user", URL: "http://example.com/a", DestPath: "/tmp/a", Status: "paused", PauseReason: types.PauseUser},
+		{ID: "disk-full", URL: "http://example.com/b", DestPath: "/tmp/b", Status: "paused", PauseReason: types.PauseDiskFull},
+		{ID: "on-exit", URL: "http://example.com/c", DestPath: "/tmp/c", Status: "paused", PauseReason: types.PauseShutdown},
+		{ID: "crashed", URL: "http://example.com/d", DestPath: "/tmp/d", Status: "paused", PauseReason: types.PauseInterrupted},
+		{ID: "legacy", URL: "http://example.com/e", DestPath: "/tmp/e", Status: "paused"},
+		{ID: "waiting", URL: "http://example.com/f", DestPath: "/tmp/f", Status: "queued"},
+	} {
+		if err := state.AddToMasterList(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ids := func(entries []types.DownloadEntry) map[string]bool {
+		got := make(map[string]bool)
+		for _, e := range entries {
+			got[e.ID] = true
+		}
+		return got
+	}
+
+	settings := config.DefaultSettings()
+	settings.General.AutoResume = true
+	got := ids(resumableDownloads(settings))
+	for _, id := range []string{"on-exit", "crashed", "legacy", "waiting"} {
+		if !got[id] {
+			t.Errorf("%s was not resumed", id)
+		}
+	}
+	for _, id := range []string{"by-user", "disk-full"} {
+		if got[id] {
+			t.Errorf("%s was resumed", id)
+		}
+	}
+
+	settings.General.AutoResume = false
+	if got := ids(resumableDownloads(settings)); len(got) != 1 || !got["waiting"] {
+		t.Errorf("with auto_resume off, resumed %v, want only the queued download", got)
+	}
+}
@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/processing"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+var batchCmd = &cobra.Command{
+	Use:   "batch <file>",
+	Short: "Queue every URL in a file",
+	Long: `Queue every download listed in a file, one per line. A line holds a URL,
+optionally followed by mirrors of it and the filename to save it as, separated
+by whitespace. Lines starting with # and text after " #" are comments.
+
+  https://example.com/ubuntu.iso https://mirror.example.org/ubuntu.iso ubuntu-24.04.iso
+
+With Surge running the downloads are sent to it; otherwise they are probed
+and written to the queue, and start the next time Surge runs.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		mustInitializeGlobalState()
+
+		output, _ := cmd.Flags().GetString("output")
+		tags, _ := cmd.Flags().GetStringSlice("tag")
+		useArchive, _ := cmd.Flags().GetBool("download-archive")
+
+		entries, err := readBatchFile(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading batch file: %v\n", err)
+			os.Exit(1)
+		}
+		if len(entries) == 0 {
+			fmt.Printf("No URLs in %s\n", args[0])
+			return
+		}
+
+		baseURL, token, err := resolveAPIConnection(false)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if baseURL == "" {
+			count := queueBatchOffline(context.Background(), entries, output, tags, useArchive)
+			if count > 0 {
+				fmt.Printf("Queued %d downloads; they start the next time Surge runs.\n", count)
+			}
+			return
+		}
+
+		count := 0
+		for _, e := range entries {
+			req := DownloadRequest{
+				URL:             e.URL,
+				Mirrors:         e.Mirrors,
+				Filename:        e.Filename,
+				Path:            output,
+				Tags:            tags,
+				DownloadArchive: useArchive,
+			}
+			err := sendRequestToServer(req, baseURL, token)
+			if errors.Is(err, errAlreadyDownloaded) {
+				fmt.Printf("Skipped %s: already downloaded\n", req.URL)
+				continue
+			}
+			if err != nil {
+				fmt.Printf("Error adding %s: %v\n", req.URL, err)
+				_ = state.RecordURLHistory(req.URL, state.URLHistoryRejected, err.Error())
+				continue
+			}
+			count++
+		}
+		if count > 0 {
+			fmt.Printf("Successfully added %d downloads.\n", count)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(batchCmd)
+	batchCmd.Flags().StringP("output", "o", "", "Output directory")
+	batchCmd.Flags().StringSliceP("tag", "t", nil, "Tag the downloads, e.g. --tag work,iso (repeatable)")
+	batchCmd.Flags().Bool("download-archive", false, "Skip URLs that have been downloaded before (always on when the download_archive setting is)")
+}
+
+// batchEntry is one line of a batch file. Mirrors holds every URL of the
+// line, URL included, as surge add sends them.
+type batchEntry struct {
+	URL      string
+	Mirrors  []string
+	Filename string
+}
+
+// readBatchFile parses a batch file. A line is whitespace-separated URLs,
+// the first being the download and the rest its mirrors, and at most one
+// other word, the filename. Any malformed line fails the whole file, so
+// nothing is queued from a file with a typo.
+func readBatchFile(path string) ([]batchEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return parseBatch(f)
+}
+
+// parseBatch parses the lines of a batch file read from r
+func parseBatch(r io.Reader) ([]batchEntry, error) {
+	var entries []batchEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		e, err := parseBatchLine(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		if e != nil {
+			entries = append(entries, *e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// parseBatchLine parses one line of a batch file, returning nil for blank
+// and comment lines
+func parseBatchLine(line string) (*batchEntry, error) {
+	var fields []string
+	for _, field := range strings.Fields(line) {
+		if strings.HasPrefix(field, "#") {
+			break // A comment runs to the end of the line
+		}
+		fields = append(fields, field)
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	var e batchEntry
+	for _, field := range fields {
+		if strings.Contains(field, "://") {
+			e.Mirrors = append(e.Mirrors, field)
+			continue
+		}
+		if e.Filename != "" {
+			return nil, fmt.Errorf("more than one filename: %q and %q", e.Filename, field)
+		}
+		e.Filename = field
+	}
+	if len(e.Mirrors) == 0 {
+		return nil, fmt.Errorf("no URL in %q", strings.Join(fields, " "))
+	}
+	e.URL = e.Mirrors[0]
+	return &e, nil
+}
+
+// queueBatchOffline writes the entries to the queue in the state database
+// when no Surge is running to send them to; the next one to start picks
+// them up. Each entry is probed and queued on its own, so one bad URL
+// doesn't hold back the others. It returns how many were queued.
+func queueBatchOffline(ctx context.Context, entries []batchEntry, output string, tags []string, useArchive bool) int {
+	settings := getSettings()
+	outPath := utils.EnsureAbsPath(resolveOutputDir(output, false, "", settings))
+	isExplicit := isExplicitOutputPath(outPath, settings.General.DefaultDownloadDir)
+
+	// Nothing runs the downloads now: the rows stay queued once written
+	lifecycle := processing.NewLifecycleManager(nil, func(_ *processing.DownloadRequest, id string) (string, error) {
+		return id, nil
+	})
+
+	count := 0
+	for _, e := range entries {
+		req := &processing.DownloadRequest{
+			URL:                e.URL,
+			Filename:           e.Filename,
+			Path:               outPath,
+			Mirrors:            e.Mirrors,
+			Tags:               tags,
+			IsExplicitCategory: isExplicit,
+			DownloadArchive:    useArchive,
+		}
+		report, err := lifecycle.EnqueueBatch(ctx, []*processing.DownloadRequest{req})
+		if report == nil {
+			fmt.Printf("Error adding %s: %v\n", e.URL, err)
+			continue
+		}
+		item := report.Items[0]
+		switch item.Status {
+		case processing.BatchItemQueued:
+			fmt.Printf("Queued %s as %s\n", e.URL, item.Filename)
+			count++
+		case processing.BatchItemSkipped:
+			fmt.Printf("Skipped %s: already downloaded\n", e.URL)
+		default:
+			if item.Error == "" && err != nil {
+				item.Error = err.Error()
+			}
+			fmt.Printf("Error adding %s: %s\n", e.URL, item.Error)
+		}
+	}
+	return count
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// maxBatchSize bounds the downloads one batch may add
+const maxBatchSize = 1000
+
+// batchRequest adds several downloads in one call, such as the extension's
+// "download all links". Group, when set, tags every one of them.
+type batchRequest struct {
+	Group     string            `json:"group,omitempty"`
+	Downloads []DownloadRequest `json:"downloads"`
+}
+
+// batchResult is what became of one download of a batch, in request order
+type batchResult struct {
+	downloadOutcome
+	Code string `json:"code,omitempty"` // Problem code when Status is error
+}
+
+type batchResponse struct {
+	Group   string        `json:"group,omitempty"`
+	Results []batchResult `json:"results"`
+}
+
+// batchHandler adds each download of a batch as POST /download would. One
+// failing doesn't stop the rest; its result carries the error instead.
+func batchHandler(defaultOutputDir string, service core.DownloadService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if draining.Load() {
+			httpError(w, "Surge is shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		var req batchRequest
+		if err := decodeJSONBody(r, &req); err != nil {
+			httpError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(req.Downloads) == 0 {
+			httpError(w, "No downloads given", http.StatusBadRequest)
+			return
+		}
+		if len(req.Downloads) > maxBatchSize {
+			writeProblem(w, http.StatusRequestEntityTooLarge, core.CodeTooLarge, fmt.Sprintf("At most %d downloads per batch", maxBatchSize))
+			return
+		}
+
+		resp := batchResponse{Group: req.Group, Results: make([]batchResult, 0, len(req.Downloads))}
+		for _, item := range req.Downloads {
+			if req.Group != "" {
+				item.Tags = utils.NormalizeTags(append(item.Tags, req.Group))
+			}
+			outcome, err := addDownload(r.Context(), item, defaultOutputDir, service)
+			if err != nil {
+				result := batchResult{downloadOutcome: downloadOutcome{Status: "error", Message: err.Error(), URL: item.URL}, Code: core.CodeInternal}
+				var apiErr *core.APIError
+				if errors.As(err, &apiErr) {
+					result.Message, result.Code = apiErr.Detail, apiErr.Code
+				}
+				resp.Results = append(resp.Results, result)
+				continue
+			}
+			resp.Results = append(resp.Results, batchResult{downloadOutcome: outcome})
+		}
+		writeJSONResponse(w, http.StatusOK, resp)
+	}
+}
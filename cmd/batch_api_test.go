@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/download"
+	"github.com/surge-downloader/surge/internal/processing"
+)
+
+// tagRecordingService records the tags of the downloads it adds
+type tagRecordingService struct {
+	fakeRemoteDownloadService
+	tags [][]string
+}
+
+func (s *tagRecordingService) Add(req *processing.DownloadRequest) (string, error) {
+	s.tags = append(s.tags, req.Tags)
+	return s.fakeRemoteDownloadService.Add(req)
+}
+
+// isolateDownloadGlobals gives a test default settings, its own worker pool
+// and no lifecycle manager, so requests go straight to the test's service
+func isolateDownloadGlobals(t *testing.T) {
+	t.Helper()
+	origSettings, origPool, origLifecycle := globalSettings, GlobalPool, GlobalLifecycle
+	t.Cleanup(func() { globalSettings, GlobalPool, GlobalLifecycle = origSettings, origPool, origLifecycle })
+	globalSettings = config.DefaultSettings()
+	GlobalPool = download.NewWorkerPool(nil, 1) // Required by handleDownload
+	GlobalLifecycle = nil
+}
+
+func TestBatchEndpoint(t *testing.T) {
+	isolateDownloadGlobals(t)
+
+	service := &tagRecordingService{}
+	mux := http.NewServeMux()
+	registerHTTPRoutes(mux, 0, t.TempDir(), service)
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/downloads", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := post(`{"group": "Gallery", "downloads": [
+		{"url": "https://example.com/1.jpg", "skip_approval": true, "tags": ["photos"]},
+		{"url": "https://example.com/2.jpg", "path": "../outside", "skip_approval": true},
+		{"url": "https://example.com/3.jpg", "skip_approval": true}
+	]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("batch = %d %s", rec.Code, rec.Body.String())
+	}
+	var resp batchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Group != "Gallery" || len(resp.Results) != 3 {
+		t.Fatalf("response = %+v", resp)
+	}
+	if r := resp.Results[0]; r.Status != "queued" || r.ID != "remote-add-id" {
+		t.Errorf("first result = %+v", r)
+	}
+	if r := resp.Results[1]; r.Status != "error" || r.Code != core.CodePathForbidden || r.URL != "https://example.com/2.jpg" {
+		t.Errorf("second result = %+v", r)
+	}
+	if r := resp.Results[2]; r.Status != "queued" {
+		t.Errorf("third result = %+v", r)
+	}
+
+	if len(service.tags) != 2 || strings.Join(service.tags[0], ",") != "photos,gallery" || strings.Join(service.tags[1], ",") != "gallery" {
+		t.Errorf("tags = %v", service.tags)
+	}
+
+	for _, body := range []string{`{"downloads": []}`, `{"downloads": {}}`} {
+		if rec := post(body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s = %d, want 400", body, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/state"
+)
+
+func TestReadBatchFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "urls.txt")
+	content := strings.Join([]string{
+		"# Distros",
+		"",
+		"https://example.com/a.iso",
+		"  https://example.com/b.iso https://mirror.example.org/b.iso   # two sources",
+		"https://example.com/c?id=1#frag c.bin",
+		"c.bin\thttps://example.com/d.bin",
+	}, "\n")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readBatchFile(path)
+	if err != nil {
+		t.Fatalf("readBatchFile: %v", err)
+	}
+	want := []batchEntry{
+		{URL: "https://example.com/a.iso", Mirrors: []string{"https://example.com/a.iso"}},
+		{URL: "https://example.com/b.iso", Mirrors: []string{"https://example.com/b.iso", "https://mirror.example.org/b.iso"}},
+		{URL: "https://example.com/c?id=1#frag", Mirrors: []string{"https://example.com/c?id=1#frag"}, Filename: "c.bin"},
+		{URL: "https://example.com/d.bin", Mirrors: []string{"https://example.com/d.bin"}, Filename: "c.bin"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("entries = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestReadBatchFile_RejectsBadLines(t *testing.T) {
+	for _, line := range []string{
+		"a.iso b.iso https://example.com/a.iso",
+		"just-a-name",
+	} {
+		path := filepath.Join(t.TempDir(), "urls.txt")
+		if err := os.WriteFile(path, []byte("https://example.com/ok\n"+line+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readBatchFile(path); err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+			t.Errorf("readBatchFile(%q) error = %v, want a line 2 error", line, err)
+		}
+	}
+}
+
+func TestQueueBatchOffline_WritesQueuedRows(t *testing.T) {
+	setupIsolatedCmdState(t)
+	defer state.CloseDB()
+	removeActivePort()
+
+	origSettings := globalSettings
+	t.Cleanup(func() { globalSettings = origSettings })
+	globalSettings = config.DefaultSettings()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, "file.bin", time.Time{}, strings.NewReader("0123456789"))
+	}))
+	defer server.Close()
+
+	outDir := t.TempDir()
+	entries := []batchEntry{
+		{URL: server.URL + "/file.bin", Mirrors: []string{server.URL + "/file.bin"}, Filename: "renamed.bin"},
+		{URL: server.URL + "/missing", Mirrors: []string{server.URL + "/missing"}},
+	}
+	var count int
+	_ = captureStdout(t, func() {
+		count = queueBatchOffline(context.Background(), entries, outDir, []string{"batch"}, false)
+	})
+	if count != 1 {
+		t.Fatalf("queued %d downloads, want 1", count)
+	}
+
+	downloads, err := state.ListAllDownloads()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(downloads) != 1 {
+		t.Fatalf("state holds %d downloads, want 1: %+v", len(downloads), downloads)
+	}
+	d := downloads[0]
+	if d.Status != "queued" || d.Filename != "renamed.bin" || d.TotalSize != 10 || filepath.Dir(d.DestPath) != outDir {
+		t.Errorf("queued row = %+v", d)
+	}
+	if len(d.Tags) != 1 || d.Tags[0] != "batch" {
+		t.Errorf("tags = %v, want [batch]", d.Tags)
+	}
+}
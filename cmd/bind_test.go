@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/config"
+)
+
+func TestServerBindAddress(t *testing.T) {
+	origSettings, origFlag := globalSettings, bindAddressFlag
+	t.Cleanup(func() { globalSettings, bindAddressFlag = origSettings, origFlag })
+	globalSettings = config.DefaultSettings()
+	bindAddressFlag = ""
+
+	if got := serverBindAddress(); got != serverBindHost {
+		t.Fatalf("default = %q, want %q", got, serverBindHost)
+	}
+	globalSettings.Server.BindAddress = "127.0.0.1"
+	if got := serverBindAddress(); got != "127.0.0.1" {
+		t.Fatalf("setting = %q, want 127.0.0.1", got)
+	}
+	bindAddressFlag = "::1"
+	if got := serverBindAddress(); got != "::1" {
+		t.Fatalf("flag = %q, want ::1", got)
+	}
+
+	bindAddressFlag = "127.0.0.1"
+	port, ln, err := bindServerListener(0)
+	if err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	_ = ln.Close()
+	addr := ln.Addr().(*net.TCPAddr)
+	if !addr.IP.IsLoopback() || addr.Port != port {
+		t.Fatalf("listened on %s, want 127.0.0.1:%d", addr, port)
+	}
+
+	bindAddressFlag = "not an address"
+	if _, _, err := bindServerListener(0); err == nil {
+		t.Fatal("invalid bind address accepted")
+	}
+}
+
+func TestCorsMiddleware_AllowedOrigins(t *testing.T) {
+	origSettings := globalSettings
+	t.Cleanup(func() { globalSettings = origSettings })
+	globalSettings = config.DefaultSettings()
+	globalSettings.Server.AllowedOrigins = []string{"https://nas.lan"}
+
+	handler := corsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/list", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve("https://nas.lan"); rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "https://nas.lan" {
+		t.Fatalf("allowed origin: %d, %q", rec.Code, rec.Header().Get("Access-Control-Allow-Origin"))
+	}
+	// Built-in origins are merged with the setting
+	if rec := serve("moz-extension://0d7f4a4e-5b1c-4c8e-9d1a-2f0e6b7c8d9e"); rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") == "" {
+		t.Fatalf("extension origin: %d, %q", rec.Code, rec.Header().Get("Access-Control-Allow-Origin"))
+	}
+	if rec := serve("https://evil.example"); rec.Code != http.StatusForbidden {
+		t.Fatalf("other origin = %d, want 403", rec.Code)
+	}
+	if rec := serve("http://example.com"); rec.Code != http.StatusOK {
+		t.Fatalf("same origin = %d, want 200", rec.Code)
+	}
+	if rec := serve(""); rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("no origin: %d, %q", rec.Code, rec.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
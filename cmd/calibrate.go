@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/calibrate"
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// firstRunCalibrationTimeout bounds the background calibration so a dead
+// endpoint can't keep it going
+const firstRunCalibrationTimeout = 30 * time.Second
+
+var calibrateCmd = &cobra.Command{
+	Use:   "calibrate",
+	Short: "Measure bandwidth and latency and tune connection settings",
+	Long: `Download from a few well-known test endpoints for a few seconds, measure the
+link's bandwidth and round-trip time, and derive max_connections_per_host,
+min_chunk_size and worker_buffer_size from the bandwidth-delay product.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		endpoints, _ := cmd.Flags().GetStringSlice("url")
+		duration, _ := cmd.Flags().GetDuration("duration")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		jsonOutput := globalJSON
+
+		if !jsonOutput {
+			fmt.Println("Measuring bandwidth and latency...")
+		}
+		result, err := calibrate.Measure(cmd.Context(), calibrate.Options{Endpoints: endpoints, Duration: duration})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		tuning := calibrate.Recommend(result)
+
+		if !dryRun {
+			settings, err := config.LoadSettings()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading settings: %v\n", err)
+				os.Exit(1)
+			}
+			tuning.Apply(settings, time.Now())
+			if err := config.SaveSettings(settings); err != nil {
+				fmt.Fprintf(os.Stderr, "Error saving settings: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		if jsonOutput {
+			data, _ := json.MarshalIndent(map[string]any{"result": result, "tuning": tuning, "saved": !dryRun}, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+
+		fmt.Printf("Endpoint:     %s\n", result.Endpoint)
+		fmt.Printf("Bandwidth:    %s/s\n", utils.ConvertBytesToHumanReadable(int64(result.Bandwidth)))
+		fmt.Printf("Latency:      %v\n", result.RTT.Round(time.Millisecond))
+		fmt.Printf("BDP:          %s\n\n", utils.ConvertBytesToHumanReadable(result.BDP()))
+		fmt.Printf("max_connections_per_host  %d\n", tuning.MaxConnectionsPerHost)
+		fmt.Printf("min_chunk_size            %s\n", utils.ConvertBytesToHumanReadable(tuning.MinChunkSize))
+		fmt.Printf("worker_buffer_size        %s\n", utils.ConvertBytesToHumanReadable(int64(tuning.WorkerBufferSize)))
+		if dryRun {
+			fmt.Println("\nDry run: settings not changed.")
+		} else {
+			fmt.Println("\nSettings saved. A running Surge instance uses them after a restart.")
+		}
+	},
+}
+
+// startFirstRunCalibration tunes the connection settings in the background
+// when they have never been calibrated and still hold the defaults. It stands
+// down when startingDownloads, since measuring alongside them would slow them
+// and skew the result; the next start without downloads tries again.
+func startFirstRunCalibration(startingDownloads bool) {
+	if !calibrate.NeedsFirstRun(getSettings()) {
+		return
+	}
+	if startingDownloads {
+		utils.Debug("First-run calibration deferred: downloads are starting")
+		return
+	}
+	service := GlobalService
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), firstRunCalibrationTimeout)
+		defer cancel()
+
+		result, err := calibrate.Measure(ctx, calibrate.Options{})
+		if err != nil {
+			// Left uncalibrated, so the next start tries again
+			utils.Debug("First-run calibration skipped: %v", err)
+			return
+		}
+
+		// Re-read so changes made while measuring aren't lost
+		settings, err := config.LoadSettings()
+		if err != nil || !calibrate.NeedsFirstRun(settings) {
+			return
+		}
+		tuning := calibrate.Recommend(result)
+		tuning.Apply(settings, time.Now())
+		if err := config.SaveSettings(settings); err != nil {
+			utils.Debug("First-run calibration: failed to save settings: %v", err)
+			return
+		}
+		utils.Debug("First-run calibration: %+v (rtt %v, %.0f B/s via %s)", tuning, result.RTT, result.Bandwidth, result.Endpoint)
+
+		if local, ok := service.(*core.LocalDownloadService); ok {
+			if err := local.ReloadSettings(); err != nil {
+				utils.Debug("First-run calibration: failed to reload settings: %v", err)
+			}
+		}
+	}()
+}
+
+// downloadsStarting reports whether this launch queues or auto-resumes any
+// downloads
+func downloadsStarting(args []string, batchFile string, noResume bool) bool {
+	return len(args) > 0 || batchFile != "" || (!noResume && len(resumableDownloads(getSettings())) > 0)
+}
+
+func init() {
+	rootCmd.AddCommand(calibrateCmd)
+	calibrateCmd.Flags().StringSlice("url", nil, "Test endpoint to download from (repeatable; default: built-in endpoints)")
+	calibrateCmd.Flags().Duration("duration", 5*time.Second, "How long to download from each endpoint")
+	calibrateCmd.Flags().Bool("dry-run", false, "Show the suggested settings without saving them")
+}
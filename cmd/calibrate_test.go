@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestDownloadsStarting(t *testing.T) {
+	state.CloseDB()
+	state.Configure(filepath.Join(t.TempDir(), "surge.db"))
+	t.Cleanup(state.CloseDB)
+
+	origSettings := globalSettings
+	globalSettings = config.DefaultSettings()
+	globalSettings.General.AutoResume = false
+	t.Cleanup(func() { globalSettings = origSettings })
+
+	if downloadsStarting(nil, "", false) {
+		t.Fatal("nothing to start on an empty database")
+	}
+	if !downloadsStarting([]string{"https://example.com/a.iso"}, "", true) {
+		t.Error("URLs on the command line start downloads")
+	}
+	if !downloadsStarting(nil, "urls.txt", true) {
+		t.Error("a batch file starts downloads")
+	}
+
+	if err := state.AddToMasterList(types.DownloadEntry{ID: "p", URL: "https://example.com/p", DestPath: "/tmp/p", Status: "paused"}); err != nil {
+		t.Fatal(err)
+	}
+	if downloadsStarting(nil, "", false) {
+		t.Error("paused downloads stay paused with auto_resume off")
+	}
+	globalSettings.General.AutoResume = true
+	if !downloadsStarting(nil, "", false) {
+		t.Error("paused downloads resume with auto_resume on")
+	}
+	if downloadsStarting(nil, "", true) {
+		t.Error("--no-resume starts nothing")
+	}
+}
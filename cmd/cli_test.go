@@ -489,7 +489,7 @@ func TestPrintDownloads_FromDatabase_TableAndJSON(t *testing.T) {
 	}
 
 	tableOut := captureStdout(t, func() {
-		printDownloads(false, "", "", false)
+		printDownloads(false, "", "", false, "")
 	})
 	if !strings.Contains(tableOut, "ID") {
 		t.Fatalf("expected table header in output, got: %s", tableOut)
@@ -505,7 +505,7 @@ func TestPrintDownloads_FromDatabase_TableAndJSON(t *testing.T) {
 	}
 
 	jsonOut := captureStdout(t, func() {
-		printDownloads(true, "", "", false)
+		printDownloads(true, "", "", false, "")
 	})
 	var infos []downloadInfo
 	if err := json.Unmarshal([]byte(jsonOut), &infos); err != nil {
@@ -525,7 +525,7 @@ func TestPrintDownloads_JSONEmpty(t *testing.T) {
 	removeActivePort()
 
 	out := captureStdout(t, func() {
-		printDownloads(true, "", "", false)
+		printDownloads(true, "", "", false, "")
 	})
 	var infos []any
 	if err := json.Unmarshal([]byte(out), &infos); err != nil {
@@ -559,7 +559,7 @@ func TestPrintDownloads_StrictRemoteEmpty_DoesNotFallbackToDB(t *testing.T) {
 	defer server.Close()
 
 	out := captureStdout(t, func() {
-		printDownloads(true, server.URL, "", true)
+		printDownloads(true, server.URL, "", true, "")
 	})
 	if strings.TrimSpace(out) != "[]" {
 		t.Fatalf("expected strict remote empty json array, got %q", strings.TrimSpace(out))
@@ -637,7 +637,7 @@ func TestSendToServer_SuccessAndServerError(t *testing.T) {
 			})
 
 			port := ln.Addr().(*net.TCPAddr).Port
-			err = sendToServer("https://example.com/file.zip", nil, "", fmt.Sprintf("http://127.0.0.1:%d", port), "")
+			err = sendToServer("https://example.com/file.zip", nil, nil, "", fmt.Sprintf("http://127.0.0.1:%d", port), "")
 			if tt.wantErr && err == nil {
 				t.Fatal("expected error, got nil")
 			}
@@ -672,7 +672,7 @@ func TestSendToServer_UsesBearerTokenFromEnv(t *testing.T) {
 	t.Cleanup(func() { _ = server.Close() })
 
 	port := ln.Addr().(*net.TCPAddr).Port
-	err = sendToServer("https://example.com/file.zip", nil, "", fmt.Sprintf("http://127.0.0.1:%d", port), resolveLocalToken())
+	err = sendToServer("https://example.com/file.zip", nil, nil, "", fmt.Sprintf("http://127.0.0.1:%d", port), resolveLocalToken())
 	if err != nil {
 		t.Fatalf("expected authenticated request to succeed, got error: %v", err)
 	}
@@ -715,14 +715,32 @@ func TestStartHTTPServer_HealthEndpoint(t *testing.T) {
 	port := ln.Addr().(*net.TCPAddr).Port
 
 	// Start server in background
+	setupXDGEnvIsolation(t)
+	const token = "health-test-token"
 	svc := core.NewLocalDownloadService(nil) // Mock service with nil pool/chan for health check
-	go startHTTPServer(ln, port, "", svc, "")
+	go startHTTPServer(ln, nil, port, "", svc, token)
 
 	// Give server time to start
 	time.Sleep(50 * time.Millisecond)
 
+	// Without the token only the status is shown
+	public, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/health", port))
+	if err != nil {
+		t.Fatalf("Failed to get health: %v", err)
+	}
+	var publicResult map[string]interface{}
+	if err := json.NewDecoder(public.Body).Decode(&publicResult); err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	_ = public.Body.Close()
+	if publicResult["status"] != "ok" || len(publicResult) != 1 {
+		t.Errorf("public health = %v, want only the status", publicResult)
+	}
+
 	// Test health endpoint
-	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/health", port))
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("http://127.0.0.1:%d/health", port), nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		t.Fatalf("Failed to get health: %v", err)
 	}
@@ -754,7 +772,7 @@ func TestStartHTTPServer_HasCORSHeaders(t *testing.T) {
 	port := ln.Addr().(*net.TCPAddr).Port
 
 	svc := core.NewLocalDownloadService(nil)
-	go startHTTPServer(ln, port, "", svc, "")
+	go startHTTPServer(ln, nil, port, "", svc, "")
 	time.Sleep(50 * time.Millisecond)
 
 	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/health", port))
@@ -777,7 +795,7 @@ func TestStartHTTPServer_OptionsRequest(t *testing.T) {
 	port := ln.Addr().(*net.TCPAddr).Port
 
 	svc := core.NewLocalDownloadService(nil)
-	go startHTTPServer(ln, port, "", svc, "")
+	go startHTTPServer(ln, nil, port, "", svc, "")
 	time.Sleep(50 * time.Millisecond)
 
 	req, _ := http.NewRequest(http.MethodOptions, fmt.Sprintf("http://127.0.0.1:%d/download", port), nil)
@@ -802,7 +820,7 @@ func TestStartHTTPServer_DownloadEndpoint_MethodNotAllowed(t *testing.T) {
 	port := ln.Addr().(*net.TCPAddr).Port
 
 	svc := core.NewLocalDownloadService(nil)
-	go startHTTPServer(ln, port, "", svc, "")
+	go startHTTPServer(ln, nil, port, "", svc, "")
 	time.Sleep(50 * time.Millisecond)
 
 	token := ensureAuthToken()
@@ -830,7 +848,7 @@ func TestStartHTTPServer_DownloadEndpoint_BadRequest(t *testing.T) {
 	port := ln.Addr().(*net.TCPAddr).Port
 
 	svc := core.NewLocalDownloadService(nil)
-	go startHTTPServer(ln, port, "", svc, "")
+	go startHTTPServer(ln, nil, port, "", svc, "")
 	time.Sleep(50 * time.Millisecond)
 
 	// POST with invalid JSON
@@ -858,7 +876,7 @@ func TestStartHTTPServer_DownloadEndpoint_MissingURL(t *testing.T) {
 	port := ln.Addr().(*net.TCPAddr).Port
 
 	svc := core.NewLocalDownloadService(nil)
-	go startHTTPServer(ln, port, "", svc, "")
+	go startHTTPServer(ln, nil, port, "", svc, "")
 	time.Sleep(50 * time.Millisecond)
 
 	// POST with missing URL
@@ -886,7 +904,7 @@ func TestStartHTTPServer_NotFoundEndpoint(t *testing.T) {
 	port := ln.Addr().(*net.TCPAddr).Port
 
 	svc := core.NewLocalDownloadService(nil)
-	go startHTTPServer(ln, port, "", svc, "")
+	go startHTTPServer(ln, nil, port, "", svc, "")
 	time.Sleep(50 * time.Millisecond)
 
 	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("http://127.0.0.1:%d/nonexistent", port), nil)
@@ -1174,7 +1192,7 @@ func TestLsCmd_Use(t *testing.T) {
 }
 
 func TestLsCmd_Flags(t *testing.T) {
-	jsonFlag := lsCmd.Flags().Lookup("json")
+	jsonFlag := lsCmd.InheritedFlags().Lookup("json")
 	if jsonFlag == nil {
 		t.Error("Missing 'json' flag")
 	}
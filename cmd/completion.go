@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/engine/state"
+)
+
+// downloadCandidate is a download offered by shell completion
+type downloadCandidate struct {
+	ID       string
+	Filename string
+	Status   string
+}
+
+// listCompletionCandidates returns the downloads of the running Surge, or,
+// with none running, those in the state database
+func listCompletionCandidates() []downloadCandidate {
+	baseURL, token, err := resolveAPIConnection(false)
+	if err != nil {
+		return nil
+	}
+	if baseURL != "" {
+		downloads, err := GetRemoteDownloads(baseURL, token)
+		if err != nil {
+			return nil
+		}
+		candidates := make([]downloadCandidate, 0, len(downloads))
+		for _, d := range downloads {
+			candidates = append(candidates, downloadCandidate{ID: d.ID, Filename: d.Filename, Status: d.Status})
+		}
+		return candidates
+	}
+
+	if err := initializeGlobalState(); err != nil {
+		return nil
+	}
+	entries, err := state.ListAllDownloads()
+	if err != nil {
+		return nil
+	}
+	candidates := make([]downloadCandidate, 0, len(entries))
+	for _, e := range entries {
+		candidates = append(candidates, downloadCandidate{ID: e.ID, Filename: e.Filename, Status: e.Status})
+	}
+	return candidates
+}
+
+// completeDownloadIDs returns a completion function offering the IDs of
+// downloads in one of statuses, any when none are given, each described by
+// its filename and status. Only the first argument is completed unless
+// multiple is set.
+func completeDownloadIDs(multiple bool, statuses ...string) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 && !multiple {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return matchDownloadCandidates(listCompletionCandidates(), args, toComplete, statuses), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// matchDownloadCandidates formats the candidates whose ID starts with
+// toComplete as "<id>\t<filename> (<status>)", skipping IDs already given
+func matchDownloadCandidates(candidates []downloadCandidate, args []string, toComplete string, statuses []string) []string {
+	var out []string
+	for _, c := range candidates {
+		if !strings.HasPrefix(c.ID, toComplete) || slices.Contains(args, c.ID) {
+			continue
+		}
+		if len(statuses) > 0 && !slices.Contains(statuses, c.Status) {
+			continue
+		}
+		out = append(out, fmt.Sprintf("%s\t%s (%s)", c.ID, c.Filename, c.Status))
+	}
+	return out
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMatchDownloadCandidates(t *testing.T) {
+	candidates := []downloadCandidate{
+		{ID: "aa11", Filename: "one.iso", Status: "downloading"},
+		{ID: "aa22", Filename: "two.iso", Status: "paused"},
+		{ID: "bb33", Filename: "three.iso", Status: "completed"},
+	}
+
+	got := matchDownloadCandidates(candidates, nil, "aa", nil)
+	want := []string{"aa11\tone.iso (downloading)", "aa22\ttwo.iso (paused)"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("prefix aa = %q, want %q", got, want)
+	}
+
+	got = matchDownloadCandidates(candidates, nil, "", []string{"paused", "error"})
+	if !slices.Equal(got, []string{"aa22\ttwo.iso (paused)"}) {
+		t.Fatalf("paused only = %q", got)
+	}
+
+	got = matchDownloadCandidates(candidates, []string{"aa11"}, "", nil)
+	if len(got) != 2 || slices.Contains(got, "aa11\tone.iso (downloading)") {
+		t.Fatalf("IDs already given should be skipped: %q", got)
+	}
+}
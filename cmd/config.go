@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Read and change settings",
+	Long: `Read and change the settings of the TUI settings screen by key, e.g.
+max_connections_per_host, without editing settings.json. Values are entered as
+on that screen: sizes in the unit it shows (MB, KB), durations in seconds.`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:               "get <key>",
+	Short:             "Print a setting",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeSettingKeys,
+	Run: func(cmd *cobra.Command, args []string) {
+		settings, _, err := loadConfigSettings()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		value, err := settings.GetValue(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if globalJSON {
+			data, _ := json.Marshal(map[string]string{"key": args[0], "value": value})
+			fmt.Println(string(data))
+			return
+		}
+		fmt.Println(value)
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:               "set <key> <value>",
+	Short:             "Change a setting",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeSettingKeys,
+	Run: func(cmd *cobra.Command, args []string) {
+		key, input := args[0], args[1]
+		settings, save, err := loadConfigSettings()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := settings.SetValue(key, input); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", key, err)
+			os.Exit(1)
+		}
+		if err := settings.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := save(settings); err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving settings: %v\n", err)
+			os.Exit(1)
+		}
+
+		value, _ := settings.GetValue(key)
+		fmt.Printf("%s = %s\n", key, value)
+		if _, meta, _ := config.LookupSetting(key); strings.Contains(meta.Description, "Requires restart") {
+			fmt.Println("Takes effect the next time Surge starts.")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+}
+
+// completeSettingKeys completes the key argument of config get and set
+func completeSettingKeys(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var keys []string
+	for _, key := range config.SettingKeys() {
+		if strings.HasPrefix(key, toComplete) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, cobra.ShellCompDirectiveNoFileComp
+}
+
+// loadConfigSettings returns the settings and a function saving them: through
+// the API of a running Surge, which applies them at once, or to settings.json
+func loadConfigSettings() (*config.Settings, func(*config.Settings) error, error) {
+	baseURL, token, err := resolveAPIConnection(false)
+	if err != nil {
+		return nil, nil, err
+	}
+	if baseURL == "" {
+		settings, err := config.LoadSettings()
+		return settings, config.SaveSettings, err
+	}
+
+	settings, err := settingsRequest(http.MethodGet, baseURL, token, nil)
+	save := func(s *config.Settings) error {
+		_, err := settingsRequest(http.MethodPut, baseURL, token, s)
+		return err
+	}
+	return settings, save, err
+}
+
+// settingsRequest calls the server's /settings endpoint, sending body when
+// not nil, and returns the settings it answers with
+func settingsRequest(method, baseURL, token string, body *config.Settings) (*config.Settings, error) {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	resp, err := doAPIRequest(method, baseURL, token, "/settings", reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to server: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			utils.Debug("Error closing response body: %v", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %s: %s", resp.Status, core.ReadAPIError(resp).Detail)
+	}
+	settings := config.DefaultSettings()
+	if err := json.NewDecoder(resp.Body).Decode(settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestSettingsRequest(t *testing.T) {
+	setupIsolatedCmdState(t)
+	baseURL := startAuthedTestServer(t, &fakeRemoteDownloadService{}, "secret")
+
+	settings, err := settingsRequest(http.MethodGet, baseURL, "secret", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := settings.SetValue("max_task_retries", "7"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := settingsRequest(http.MethodPut, baseURL, "secret", settings); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := settingsRequest(http.MethodGet, baseURL, "secret", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Performance.MaxTaskRetries != 7 {
+		t.Fatalf("max_task_retries = %d, want 7", saved.Performance.MaxTaskRetries)
+	}
+	if _, err := settingsRequest(http.MethodGet, baseURL, "wrong", nil); err == nil {
+		t.Fatal("expected an error with a bad token")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/surge-downloader/surge/internal/core"
 	"github.com/surge-downloader/surge/internal/tui"
+	"github.com/surge-downloader/surge/internal/utils"
 )
 
 var connectCmd = &cobra.Command{
@@ -26,12 +28,17 @@ var connectCmd = &cobra.Command{
 			target = args[0]
 		} else {
 			port := readActivePort()
-			if port == 0 {
-				fmt.Println("No local Surge server detected. Start one with 'surge' or 'surge server', or specify a target: surge connect <host:port>")
-				os.Exit(1)
+			socket := readActiveSocket()
+			switch {
+			case port > 0:
+				target = localBaseURL(port)
+				fmt.Printf("Auto-detected local server on port %d\n", port)
+			case socket != "":
+				target = core.UnixScheme + socket
+				fmt.Printf("Auto-detected local server on %s\n", socket)
+			default:
+				target = discoverConnectTarget()
 			}
-			target = fmt.Sprintf("127.0.0.1:%d", port)
-			fmt.Printf("Auto-detected local server on port %d\n", port)
 		}
 		connectAndRunTUI(cmd, target)
 	},
@@ -58,7 +65,12 @@ func connectAndRunTUI(cmd *cobra.Command, target string) {
 
 	fmt.Printf("Connecting to %s...\n", baseURL)
 
-	service := core.NewRemoteDownloadService(baseURL, token)
+	tlsConfig, err := clientTLSConfig()
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	service := core.NewRemoteDownloadServiceTLS(baseURL, token, tlsConfig)
 	_, err = service.List()
 	if err != nil {
 		fmt.Printf("Failed to connect: %v\n", err)
@@ -74,7 +86,9 @@ func connectAndRunTUI(cmd *cobra.Command, target string) {
 
 	port := 0
 	serverHost := hostnameFromTarget(target)
-	if u, err := url.Parse(baseURL); err == nil {
+	if socket, ok := core.SocketPath(baseURL); ok {
+		serverHost = socket
+	} else if u, err := url.Parse(baseURL); err == nil {
 		if h := u.Hostname(); h != "" {
 			serverHost = h
 		}
@@ -98,6 +112,29 @@ func connectAndRunTUI(cmd *cobra.Command, target string) {
 	}
 }
 
+// discoverConnectTarget looks for a daemon on the network when none runs
+// locally, and exits unless exactly one answers
+func discoverConnectTarget() string {
+	daemons, err := browseDaemons(discoverTimeout)
+	if err != nil {
+		utils.Debug("mDNS discovery failed: %v", err)
+	}
+	switch len(daemons) {
+	case 0:
+		fmt.Println("No local Surge server detected. Start one with 'surge' or 'surge server', or specify a target: surge connect <host:port>")
+	case 1:
+		fmt.Printf("Discovered %s at %s\n", daemons[0].Instance, daemons[0].URL())
+		return daemons[0].URL()
+	default:
+		fmt.Println("Several Surge daemons found on the network, pick one: surge connect <url>")
+		for _, d := range daemons {
+			fmt.Printf("  %s  %s\n", d.URL(), d.Instance)
+		}
+	}
+	os.Exit(1)
+	return ""
+}
+
 func newRemoteRootModel(port int, service core.DownloadService, serverHost string) tui.RootModel {
 	m := tui.InitialRootModel(port, Version, service, nil, false)
 	m.ServerHost = serverHost
@@ -113,6 +150,10 @@ func resolveTokenForTarget(target string) (string, error) {
 	if token != "" {
 		return token, nil
 	}
+	// The socket's file permissions stand in for the token
+	if _, ok := core.SocketPath(target); ok {
+		return "", nil
+	}
 
 	host := target
 	if strings.Contains(target, "://") {
@@ -131,13 +172,19 @@ func resolveTokenForTarget(target string) (string, error) {
 }
 
 func resolveConnectBaseURL(target string, allowInsecureHTTP bool) (string, error) {
+	if strings.HasPrefix(target, core.UnixScheme) {
+		if socket, ok := core.SocketPath(target); !ok || !filepath.IsAbs(socket) {
+			return "", fmt.Errorf("invalid target: unix socket path must be absolute")
+		}
+		return target, nil
+	}
 	if strings.Contains(target, "://") {
 		u, err := url.Parse(target)
 		if err != nil {
 			return "", fmt.Errorf("invalid target: %v", err)
 		}
 		if u.Scheme != "http" && u.Scheme != "https" {
-			return "", fmt.Errorf("unsupported scheme %q (use http or https)", u.Scheme)
+			return "", fmt.Errorf("unsupported scheme %q (use http, https or unix)", u.Scheme)
 		}
 		if u.Host == "" {
 			return "", fmt.Errorf("invalid target: missing host")
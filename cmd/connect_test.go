@@ -7,6 +7,7 @@ import (
 	"github.com/surge-downloader/surge/internal/core"
 	"github.com/surge-downloader/surge/internal/engine/events"
 	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/processing"
 	"github.com/surge-downloader/surge/internal/tui"
 )
 
@@ -28,16 +29,16 @@ func (f *fakeRemoteDownloadService) History() ([]types.DownloadEntry, error) {
 	return nil, nil
 }
 
-func (f *fakeRemoteDownloadService) Add(url, path, filename string, mirrors []string, headers map[string]string, isExplicitCategory bool, totalSize int64, supportsRange bool) (string, error) {
+func (f *fakeRemoteDownloadService) Add(req *processing.DownloadRequest) (string, error) {
 	f.addCalls++
-	f.lastURL = url
-	f.lastPath = path
-	f.lastFile = filename
-	f.lastExplicit = isExplicitCategory
+	f.lastURL = req.URL
+	f.lastPath = req.Path
+	f.lastFile = req.Filename
+	f.lastExplicit = req.IsExplicitCategory
 	return "remote-add-id", nil
 }
 
-func (f *fakeRemoteDownloadService) AddWithID(url, path, filename string, mirrors []string, headers map[string]string, id string, totalSize int64, supportsRange bool) (string, error) {
+func (f *fakeRemoteDownloadService) AddWithID(req *processing.DownloadRequest, id string) (string, error) {
 	return id, nil
 }
 
@@ -49,6 +50,8 @@ func (f *fakeRemoteDownloadService) ResumeBatch(ids []string) []error { return n
 
 func (f *fakeRemoteDownloadService) UpdateURL(id string, newURL string) error { return nil }
 
+func (f *fakeRemoteDownloadService) UpdateNote(id string, update types.NoteUpdate) error { return nil }
+
 func (f *fakeRemoteDownloadService) Delete(id string) error { return nil }
 
 func (f *fakeRemoteDownloadService) StreamEvents(ctx context.Context) (<-chan interface{}, func(), error) {
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/config"
+)
+
+// daemonStartTimeout is how long surge daemon waits for the detached server
+// to come up before giving up on it
+const daemonStartTimeout = 15 * time.Second
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon [url]...",
+	Short: "Run the Surge server in the background",
+	Long: `Run the Surge API server headless, without the TUI. By default it detaches
+from the terminal and returns once the server is up, logging to daemon.log in
+the logs directory; --foreground keeps it attached, for systemd, launchd or a
+container. Either way the PID is written to the runtime directory (and to
+--pid-file), and SIGTERM pauses every download and saves its progress before
+exiting. It takes the flags of "surge server".`,
+	Run: func(cmd *cobra.Command, args []string) {
+		foreground, _ := cmd.Flags().GetBool("foreground")
+		extraPIDFile, _ = cmd.Flags().GetString("pid-file")
+		if extraPIDFile != "" {
+			extraPIDFile, _ = filepath.Abs(extraPIDFile)
+		}
+
+		if foreground {
+			serverStartCmd.Run(cmd, args)
+			return
+		}
+
+		if status, _ := checkServerStatus(); status.Running {
+			fmt.Fprintf(os.Stderr, "Error: Surge server is already running (PID: %d).\n", status.PID)
+			os.Exit(1)
+		}
+
+		logFile, _ := cmd.Flags().GetString("log-file")
+		if logFile == "" {
+			logFile = filepath.Join(config.GetLogsDir(), "daemon.log")
+		}
+		pid, err := startDetachedDaemon(logFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Surge daemon started (PID: %d). Logging to %s\n", pid, logFile)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+	daemonCmd.Flags().Bool("foreground", false, "Stay attached to the terminal instead of detaching")
+	daemonCmd.Flags().String("pid-file", "", "Also write the PID to this file")
+	daemonCmd.Flags().String("log-file", "", "Where a detached daemon writes its output (default: daemon.log in the logs directory)")
+}
+
+// daemonChildArgs returns the arguments that rerun this command in the
+// foreground, for the detached copy to run
+func daemonChildArgs(args []string) []string {
+	out := slices.Clone(args)
+	if !slices.Contains(out, "--foreground") {
+		out = append(out, "--foreground")
+	}
+	return out
+}
+
+// startDetachedDaemon starts this executable again as surge daemon
+// --foreground in a session of its own, its output going to logFile, and
+// waits for it to serve. It returns the daemon's PID.
+func startDetachedDaemon(logFile string) (int, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf("finding the surge executable: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(logFile), 0o755); err != nil {
+		return 0, err
+	}
+	out, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return 0, fmt.Errorf("opening log file: %w", err)
+	}
+	defer func() { _ = out.Close() }()
+
+	child := exec.Command(exe, daemonChildArgs(os.Args[1:])...)
+	child.Stdout = out
+	child.Stderr = out
+	child.SysProcAttr = detachedProcAttr()
+	if err := child.Start(); err != nil {
+		return 0, fmt.Errorf("starting daemon: %w", err)
+	}
+
+	exited := make(chan error, 1)
+	go func() { exited <- child.Wait() }()
+	if err := waitForDaemon(child.Process.Pid, exited, daemonStartTimeout); err != nil {
+		return 0, fmt.Errorf("%w; see %s", err, logFile)
+	}
+	return child.Process.Pid, nil
+}
+
+// waitForDaemon waits until the server with pid has written its PID and
+// the port or socket it serves on, or fails when it exits or timeout passes
+func waitForDaemon(pid int, exited <-chan error, timeout time.Duration) error {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if readPID() == pid && (readActivePort() > 0 || readActiveSocket() != "") {
+			return nil
+		}
+		select {
+		case err := <-exited:
+			if err == nil {
+				return fmt.Errorf("daemon exited during startup")
+			}
+			return fmt.Errorf("daemon exited during startup: %w", err)
+		case <-deadline.C:
+			return fmt.Errorf("daemon did not start within %s", timeout)
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+)
+
+func TestDaemonChildArgs(t *testing.T) {
+	got := daemonChildArgs([]string{"daemon", "--port", "1800", "https://example.com/a.iso"})
+	want := []string{"daemon", "--port", "1800", "https://example.com/a.iso", "--foreground"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("daemonChildArgs = %v, want %v", got, want)
+	}
+	if again := daemonChildArgs(want); !reflect.DeepEqual(again, want) {
+		t.Errorf("daemonChildArgs added --foreground twice: %v", again)
+	}
+}
+
+func TestDaemonCmd_TakesServerFlags(t *testing.T) {
+	for _, name := range []string{"port", "token", "socket", "no-resume", "foreground", "pid-file"} {
+		if daemonCmd.Flags().Lookup(name) == nil {
+			t.Errorf("surge daemon is missing --%s", name)
+		}
+	}
+}
+
+func TestWaitForDaemon(t *testing.T) {
+	setupXDGEnvIsolation(t)
+	if err := config.EnsureDirs(); err != nil {
+		t.Fatal(err)
+	}
+	runtimeDir := config.GetRuntimeDir()
+	const pid = 4242
+
+	// Exits before serving
+	exited := make(chan error, 1)
+	exited <- errors.New("exit status 1")
+	if err := waitForDaemon(pid, exited, time.Second); err == nil || !strings.Contains(err.Error(), "exit status 1") {
+		t.Errorf("waitForDaemon after exit = %v", err)
+	}
+
+	// A port file left by another process doesn't count
+	if err := os.WriteFile(filepath.Join(runtimeDir, "port"), []byte("1700"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitForDaemon(pid, make(chan error), 200*time.Millisecond); err == nil {
+		t.Error("waitForDaemon succeeded without the daemon's PID")
+	}
+
+	go func() {
+		time.Sleep(150 * time.Millisecond)
+		_ = os.WriteFile(filepath.Join(runtimeDir, "pid"), []byte("4242"), 0o644)
+	}()
+	if err := waitForDaemon(pid, make(chan error), 2*time.Second); err != nil {
+		t.Errorf("waitForDaemon = %v, want ready", err)
+	}
+}
+
+func TestSavePID_WritesExtraPIDFile(t *testing.T) {
+	setupXDGEnvIsolation(t)
+	if err := config.EnsureDirs(); err != nil {
+		t.Fatal(err)
+	}
+	extraPIDFile = filepath.Join(t.TempDir(), "surge.pid")
+	t.Cleanup(func() { extraPIDFile = "" })
+
+	savePID()
+	data, err := os.ReadFile(extraPIDFile)
+	if err != nil || string(data) != strconv.Itoa(os.Getpid()) {
+		t.Fatalf("extra PID file = %q, %v", data, err)
+	}
+	if readPID() != os.Getpid() {
+		t.Errorf("readPID = %d, want %d", readPID(), os.Getpid())
+	}
+
+	removePID()
+	if _, err := os.Stat(extraPIDFile); !os.IsNotExist(err) {
+		t.Errorf("extra PID file not removed: %v", err)
+	}
+}
@@ -0,0 +1,306 @@
+package cmd
+
+import (
+	"io"
+	"net/http"
+)
+
+// dashboardPath serves the web dashboard. The page carries no data, so it is
+// served without the token; its script calls the API with the token the user
+// enters, which the browser keeps in localStorage.
+const dashboardPath = "/ui/"
+
+func registerDashboardRoutes(mux *http.ServeMux) {
+	mux.HandleFunc(dashboardPath, requireMethod(http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != dashboardPath {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		_, _ = io.WriteString(w, dashboardPage)
+	}))
+}
+
+// dashboardPage lists downloads from /list, keeps them current from the
+// /events stream and adds, pauses and resumes through the API. EventSource
+// can't send the Authorization header, so the stream is read with fetch.
+const dashboardPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Surge</title>
+<style>
+body { font-family: sans-serif; background: #111; color: #eee; margin: 2em; }
+input, button { background: #222; color: #eee; border: 1px solid #555; border-radius: 4px; padding: 0.4em; }
+button { cursor: pointer; }
+form { margin-bottom: 1em; display: flex; gap: 0.5em; flex-wrap: wrap; }
+#url { flex: 1; min-width: 20em; }
+#msg { color: #f38ba8; min-height: 1.2em; }
+.dl { margin-bottom: 1.5em; }
+.head { display: flex; justify-content: space-between; gap: 1em; }
+.name { overflow: hidden; text-overflow: ellipsis; white-space: nowrap; }
+.bar { background: #333; height: 1em; border-radius: 4px; overflow: hidden; margin-top: 0.3em; }
+.fill { background: #7d56f4; height: 100%; }
+.error .fill { background: #f38ba8; }
+.completed .fill { background: #a6e3a1; }
+.meta { color: #aaa; font-size: 0.9em; margin-top: 0.3em; }
+</style>
+</head>
+<body>
+<h1>Surge</h1>
+<form id="login" hidden>
+<input id="token" type="password" placeholder="API token (see surge token)" size="40" required>
+<button>Connect</button>
+</form>
+<div id="app" hidden>
+<form id="add">
+<input id="url" placeholder="URL to download" required>
+<input id="path" placeholder="Directory (optional)">
+<button>Add</button>
+<button type="button" id="logout">Forget token</button>
+</form>
+<p id="msg"></p>
+<div id="list"></div>
+</div>
+<script>
+"use strict";
+const MB = 1024 * 1024;
+let token = "";
+let stream = null; // AbortController of the running event stream
+let downloads = [];
+let renderQueued = false;
+let refreshTimer = null;
+
+function $(id) { return document.getElementById(id); }
+
+function showMessage(text) { $("msg").textContent = text || ""; }
+
+async function api(method, path, body) {
+  const opts = { method: method, headers: { "Authorization": "Bearer " + token } };
+  if (body !== undefined) {
+    opts.headers["Content-Type"] = "application/json";
+    opts.body = JSON.stringify(body);
+  }
+  const resp = await fetch(path, opts);
+  if (resp.status === 401) {
+    logout();
+    throw new Error("The token was rejected");
+  }
+  const isJSON = (resp.headers.get("Content-Type") || "").startsWith("application/json");
+  const data = isJSON ? await resp.json() : (await resp.text()).trim();
+  if (!resp.ok) {
+    throw new Error(isJSON ? (data.message || resp.statusText) : data);
+  }
+  return data;
+}
+
+function formatBytes(n) {
+  const units = ["B", "KB", "MB", "GB", "TB"];
+  let i = 0;
+  while (n >= 1024 && i < units.length - 1) { n /= 1024; i++; }
+  return n.toFixed(i ? 1 : 0) + " " + units[i];
+}
+
+function formatETA(s) {
+  if (s >= 3600) return Math.floor(s / 3600) + "h" + Math.floor(s % 3600 / 60) + "m";
+  if (s >= 60) return Math.floor(s / 60) + "m" + (s % 60) + "s";
+  return s + "s";
+}
+
+function scheduleRender() {
+  if (renderQueued) return;
+  renderQueued = true;
+  requestAnimationFrame(function () { renderQueued = false; render(); });
+}
+
+function render() {
+  const list = $("list");
+  list.replaceChildren();
+  if (downloads.length === 0) {
+    const p = document.createElement("p");
+    p.textContent = "No downloads.";
+    list.append(p);
+    return;
+  }
+  for (const d of downloads) {
+    const row = document.createElement("div");
+    row.className = "dl " + d.status;
+
+    const head = document.createElement("div");
+    head.className = "head";
+    const name = document.createElement("span");
+    name.className = "name";
+    name.textContent = (d.filename || d.url) + " · " + d.status + " · " + (d.progress || 0).toFixed(1) + "%";
+    name.title = d.url;
+    head.append(name);
+
+    let action = null;
+    if (d.status === "downloading" || d.status === "queued") action = "pause";
+    if (d.status === "paused" || d.status === "error") action = "resume";
+    if (action) {
+      const btn = document.createElement("button");
+      btn.textContent = action === "pause" ? "Pause" : "Resume";
+      btn.onclick = function () {
+        api("POST", "/" + action + "?id=" + encodeURIComponent(d.id))
+          .then(function () { showMessage(""); scheduleRefresh(); })
+          .catch(function (err) { showMessage(err.message); });
+      };
+      head.append(btn);
+    }
+    row.append(head);
+
+    const bar = document.createElement("div");
+    bar.className = "bar";
+    const fill = document.createElement("div");
+    fill.className = "fill";
+    fill.style.width = Math.min(d.progress || 0, 100) + "%";
+    bar.append(fill);
+    row.append(bar);
+
+    const meta = [];
+    if (d.total_size) meta.push(formatBytes(d.downloaded) + " / " + formatBytes(d.total_size));
+    if (d.status === "downloading" && d.speed) meta.push(d.speed.toFixed(1) + " MB/s");
+    if (d.status === "downloading" && d.eta) meta.push(formatETA(d.eta) + " left");
+    if (d.error) meta.push(d.error);
+    const metaDiv = document.createElement("div");
+    metaDiv.className = "meta";
+    metaDiv.textContent = meta.join(" · ");
+    row.append(metaDiv);
+
+    list.append(row);
+  }
+}
+
+async function refresh() {
+  downloads = await api("GET", "/list");
+  render();
+}
+
+// Anything but progress can change which downloads exist or their status, so
+// those events refetch the list, at most a few times a second
+function scheduleRefresh() {
+  if (refreshTimer) return;
+  refreshTimer = setTimeout(function () {
+    refreshTimer = null;
+    refresh().catch(function (err) { showMessage(err.message); });
+  }, 250);
+}
+
+function applyProgress(p) {
+  const d = downloads.find(function (x) { return x.id === p.DownloadID; });
+  if (!d) {
+    scheduleRefresh();
+    return;
+  }
+  d.status = "downloading";
+  d.downloaded = p.Downloaded;
+  if (p.Total > 0) {
+    d.total_size = p.Total;
+    d.progress = p.Downloaded / p.Total * 100;
+    d.eta = p.Speed > 0 ? Math.round((p.Total - p.Downloaded) / p.Speed) : 0;
+  }
+  d.speed = p.Speed / MB;
+  scheduleRender();
+}
+
+function handleFrame(frame) {
+  let type = "";
+  let data = "";
+  for (const line of frame.split("\n")) {
+    if (line.startsWith("event: ")) type = line.slice(7);
+    else if (line.startsWith("data: ")) data += line.slice(6);
+  }
+  if (type === "progress") {
+    try { applyProgress(JSON.parse(data)); } catch (err) { /* skip a malformed frame */ }
+  } else if (type) {
+    scheduleRefresh();
+  }
+}
+
+async function streamEvents(signal) {
+  while (!signal.aborted) {
+    try {
+      const resp = await fetch("/events", { headers: { "Authorization": "Bearer " + token }, signal: signal });
+      if (resp.status === 401) {
+        logout();
+        return;
+      }
+      // Subscribed first, so nothing between the list and the stream is lost
+      await refresh();
+      showMessage("");
+      const reader = resp.body.pipeThrough(new TextDecoderStream()).getReader();
+      let buf = "";
+      for (;;) {
+        const chunk = await reader.read();
+        if (chunk.done) break;
+        buf += chunk.value;
+        let i;
+        while ((i = buf.indexOf("\n\n")) >= 0) {
+          handleFrame(buf.slice(0, i));
+          buf = buf.slice(i + 2);
+        }
+      }
+    } catch (err) {
+      if (signal.aborted) return;
+      showMessage("Disconnected from Surge, retrying...");
+    }
+    await new Promise(function (resolve) { setTimeout(resolve, 2000); });
+  }
+}
+
+function login(t) {
+  token = t;
+  localStorage.setItem("surge-token", t);
+  $("login").hidden = true;
+  $("app").hidden = false;
+  if (stream) stream.abort();
+  stream = new AbortController();
+  streamEvents(stream.signal);
+}
+
+function logout() {
+  if (stream) stream.abort();
+  stream = null;
+  token = "";
+  localStorage.removeItem("surge-token");
+  $("app").hidden = true;
+  $("login").hidden = false;
+}
+
+$("login").onsubmit = function (e) {
+  e.preventDefault();
+  login($("token").value.trim());
+};
+
+$("logout").onclick = logout;
+
+$("add").onsubmit = function (e) {
+  e.preventDefault();
+  const body = { url: $("url").value.trim(), skip_approval: true };
+  const path = $("path").value.trim();
+  if (path) body.path = path;
+  api("POST", "/download", body)
+    .then(function (res) {
+      $("url").value = "";
+      showMessage(res.status === "queued" ? "" : res.message);
+      scheduleRefresh();
+    })
+    .catch(function (err) { showMessage(err.message); });
+};
+
+// A link ending in #token=<token> signs in without typing it
+const fragment = new URLSearchParams(location.hash.slice(1));
+if (fragment.get("token")) {
+  history.replaceState(null, "", location.pathname);
+  login(fragment.get("token"));
+} else if (localStorage.getItem("surge-token")) {
+  login(localStorage.getItem("surge-token"));
+} else {
+  logout();
+}
+</script>
+</body>
+</html>
+`
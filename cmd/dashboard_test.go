@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDashboard_ServedWithoutToken(t *testing.T) {
+	baseURL := startAuthedTestServer(t, &fakeRemoteDownloadService{}, "ui-token")
+
+	resp, err := http.Get(baseURL + dashboardPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Fatalf("GET %s = %d %s", dashboardPath, resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	for _, want := range []string{`fetch("/events"`, `api("GET", "/list")`, `"/download"`} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("dashboard page is missing %s", want)
+		}
+	}
+
+	// Only the page itself is public
+	resp, err = http.Get(baseURL + dashboardPath + "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("GET %sother = %d, want 401", dashboardPath, resp.StatusCode)
+	}
+}
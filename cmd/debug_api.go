@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+
+	"github.com/surge-downloader/surge/internal/engine/concurrent"
+)
+
+// registerDebugRoutes exposes pprof and engine gauges. They sit behind the same
+// auth middleware as the rest of the API.
+func registerDebugRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	mux.HandleFunc("/debug/metrics", requireMethod(http.MethodGet, func(w http.ResponseWriter, _ *http.Request) {
+		writeJSONResponse(w, http.StatusOK, collectDebugMetrics())
+	}))
+}
+
+type runtimeMetrics struct {
+	Goroutines   int    `json:"goroutines"`
+	HeapAlloc    uint64 `json:"heap_alloc"`
+	HeapInuse    uint64 `json:"heap_inuse"`
+	Sys          uint64 `json:"sys"`
+	NumGC        uint32 `json:"num_gc"`
+	PauseTotalNs uint64 `json:"pause_total_ns"`
+}
+
+type debugMetrics struct {
+	QueuedDownloads int                    `json:"queued_downloads"` // Waiting for a download slot
+	Engine          concurrent.EngineStats `json:"engine"`
+	Runtime         runtimeMetrics         `json:"runtime"`
+}
+
+func collectDebugMetrics() debugMetrics {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	m := debugMetrics{
+		Engine: concurrent.Stats(),
+		Runtime: runtimeMetrics{
+			Goroutines:   runtime.NumGoroutine(),
+			HeapAlloc:    mem.HeapAlloc,
+			HeapInuse:    mem.HeapInuse,
+			Sys:          mem.Sys,
+			NumGC:        mem.NumGC,
+			PauseTotalNs: mem.PauseTotalNs,
+		},
+	}
+	if GlobalPool != nil {
+		m.QueuedDownloads = GlobalPool.QueuedCount()
+	}
+	return m
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestDebugRoutes_RequireAuth(t *testing.T) {
+	baseURL := startAuthedTestServer(t, &fakeRemoteDownloadService{}, "debug-token")
+
+	for _, path := range []string{"/debug/metrics", "/debug/pprof/"} {
+		resp, err := http.Get(baseURL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("%s: expected 401 without token, got %d", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestDebugRoutes_Metrics(t *testing.T) {
+	baseURL := startAuthedTestServer(t, &fakeRemoteDownloadService{}, "debug-token")
+
+	get := func(path string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, baseURL+path, nil)
+		req.Header.Set("Authorization", "Bearer debug-token")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := get("/debug/metrics")
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var metrics debugMetrics
+	if err := json.NewDecoder(resp.Body).Decode(&metrics); err != nil {
+		t.Fatalf("invalid metrics JSON: %v", err)
+	}
+	if metrics.Runtime.Goroutines == 0 || metrics.Runtime.HeapAlloc == 0 {
+		t.Errorf("expected runtime metrics to be populated, got %+v", metrics.Runtime)
+	}
+
+	pprofResp := get("/debug/pprof/goroutine?debug=1")
+	_ = pprofResp.Body.Close()
+	if pprofResp.StatusCode != http.StatusOK {
+		t.Errorf("expected pprof goroutine profile, got %d", pprofResp.StatusCode)
+	}
+}
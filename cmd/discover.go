@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/discovery"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// discoverTimeout is how long clients listen for mDNS answers by default
+const discoverTimeout = 3 * time.Second
+
+var discoverCmd = &cobra.Command{
+	Use:   "discover",
+	Short: "Find Surge daemons on the local network",
+	Long:  `Listen for Surge daemons advertising themselves over mDNS (the "mdns" server setting) and print where to reach them.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		jsonOutput := globalJSON
+
+		daemons, err := browseDaemons(timeout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if jsonOutput {
+			type daemonJSON struct {
+				discovery.Daemon
+				URL string `json:"url"`
+			}
+			out := make([]daemonJSON, 0, len(daemons))
+			for _, d := range daemons {
+				out = append(out, daemonJSON{Daemon: d, URL: d.URL()})
+			}
+			data, _ := json.MarshalIndent(out, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+		if len(daemons) == 0 {
+			fmt.Println("No Surge daemons found.")
+			return
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "INSTANCE\tURL\tVERSION")
+		for _, d := range daemons {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", d.Instance, d.URL(), d.Version)
+		}
+		_ = w.Flush()
+	},
+}
+
+func init() {
+	discoverCmd.Flags().Duration("timeout", discoverTimeout, "How long to listen for daemons")
+	rootCmd.AddCommand(discoverCmd)
+}
+
+func browseDaemons(timeout time.Duration) ([]discovery.Daemon, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return discovery.Browse(ctx)
+}
+
+// startMDNS advertises the API on port when the mdns setting is on. It
+// returns a function withdrawing the advertisement, a no-op when there is
+// none. A daemon bound to loopback only is not advertised, as no other
+// machine could reach it.
+func startMDNS(port int, tls bool) func() {
+	if !getSettings().Server.MDNS || port <= 0 {
+		return func() {}
+	}
+	if ip := net.ParseIP(serverBindAddress()); (ip != nil && ip.IsLoopback()) || serverBindAddress() == "localhost" {
+		fmt.Fprintln(os.Stderr, "Warning: not advertising over mDNS, the server only listens on loopback")
+		return func() {}
+	}
+
+	instance := "Surge"
+	if host, err := os.Hostname(); err == nil && host != "" {
+		instance = "Surge on " + strings.Split(host, ".")[0]
+	}
+	ad, err := discovery.Advertise(instance, port, discovery.Info{Version: Version, APIVersion: apiVersion, TLS: tls})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return func() {}
+	}
+	utils.Debug("Advertising %s as %q over mDNS", discovery.ServiceType, instance)
+	return ad.Stop
+}
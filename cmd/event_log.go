@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// eventLogSize is how many encoded events the server keeps. Progress is
+// flattened to one event per download per tick, so this covers a poll
+// interval comfortably even with many active downloads.
+const eventLogSize = 4096
+
+// loggedEvent is one encoded event and its position in the log
+type loggedEvent struct {
+	Seq  uint64          `json:"seq"`
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// eventLog numbers every event the service emits and keeps the most recent
+// ones in a ring, so SSE and long-poll clients read the same sequence and a
+// poller can pick up where its last request ended.
+type eventLog struct {
+	mu      sync.Mutex
+	ring    []loggedEvent
+	head    int    // Index of the oldest event
+	count   int    // Events held, at most len(ring)
+	lastSeq uint64 // Seq of the newest event; 0 before the first
+	changed chan struct{}
+	started bool
+	stopped chan struct{} // Closed when the service's event stream ends
+}
+
+func newEventLog(size int) *eventLog {
+	return &eventLog{
+		ring:    make([]loggedEvent, size),
+		changed: make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// start subscribes the log to service the first time it is needed. The
+// subscription lasts until the service closes its event stream, which closes
+// stopped.
+func (l *eventLog) start(service core.DownloadService) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.started {
+		return nil
+	}
+	stream, _, err := service.StreamEvents(context.Background())
+	if err != nil {
+		return err
+	}
+	l.started = true
+
+	go func() {
+		for msg := range stream {
+			frames, err := events.EncodeSSEMessages(msg)
+			if err != nil {
+				utils.Debug("Error encoding event: %v", err)
+				continue
+			}
+			if len(frames) > 0 {
+				l.append(frames)
+			}
+		}
+		close(l.stopped)
+	}()
+	return nil
+}
+
+func (l *eventLog) append(frames []events.SSEMessage) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, f := range frames {
+		l.lastSeq++
+		ev := loggedEvent{Seq: l.lastSeq, Type: f.Event, Data: f.Data}
+		if l.count < len(l.ring) {
+			l.ring[(l.head+l.count)%len(l.ring)] = ev
+			l.count++
+		} else {
+			l.ring[l.head] = ev
+			l.head = (l.head + 1) % len(l.ring)
+		}
+	}
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// last returns the seq of the newest event
+func (l *eventLog) last() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lastSeq
+}
+
+// since returns up to limit events after seq (all of them when limit <= 0),
+// the newest seq, whether events after seq have already been dropped, and a
+// channel that is closed when the next event arrives.
+func (l *eventLog) since(seq uint64, limit int) ([]loggedEvent, uint64, bool, <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	oldest := l.lastSeq - uint64(l.count) + 1
+	// A seq ahead of the log comes from before a restart
+	missed := seq > l.lastSeq || (l.count > 0 && seq+1 < oldest)
+	if seq > l.lastSeq || seq+1 < oldest {
+		seq = oldest - 1
+	}
+
+	n := int(l.lastSeq - seq)
+	if limit > 0 && n > limit {
+		n = limit
+	}
+	out := make([]loggedEvent, n)
+	skip := l.count - int(l.lastSeq-seq)
+	for i := range out {
+		out[i] = l.ring[(l.head+skip+i)%len(l.ring)]
+	}
+	return out, l.lastSeq, missed, l.changed
+}
@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/events"
+)
+
+func appendTestEvents(l *eventLog, n int) {
+	for i := 0; i < n; i++ {
+		l.append([]events.SSEMessage{{Event: events.EventTypeSystem, Data: []byte(`{}`)}})
+	}
+}
+
+func TestEventLog_Since(t *testing.T) {
+	l := newEventLog(4)
+
+	evs, last, missed, _ := l.since(0, 0)
+	if len(evs) != 0 || last != 0 || missed {
+		t.Fatalf("empty log: %v, %d, %v", evs, last, missed)
+	}
+
+	appendTestEvents(l, 3)
+	evs, last, missed, _ = l.since(1, 0)
+	if len(evs) != 2 || evs[0].Seq != 2 || evs[1].Seq != 3 || last != 3 || missed {
+		t.Fatalf("since(1) = %v, %d, %v", evs, last, missed)
+	}
+	if evs, _, _, _ := l.since(0, 2); len(evs) != 2 || evs[1].Seq != 2 {
+		t.Fatalf("since(0, limit 2) = %v", evs)
+	}
+
+	// Wrap the ring: seqs 3..6 are kept, so 2 is the oldest resumable point
+	appendTestEvents(l, 3)
+	if evs, _, missed, _ := l.since(2, 0); missed || len(evs) != 4 || evs[0].Seq != 3 {
+		t.Fatalf("since(2) after wrap = %v, %v", evs, missed)
+	}
+	if evs, _, missed, _ := l.since(1, 0); !missed || len(evs) != 4 {
+		t.Fatalf("since(1) after wrap = %v, %v; want missed", evs, missed)
+	}
+	// A seq from before a restart is ahead of the log
+	if _, last, missed, _ := l.since(99, 0); !missed || last != 6 {
+		t.Fatalf("since(99) = %d, %v; want missed", last, missed)
+	}
+}
+
+func TestEventLog_ChangedWakesWaiters(t *testing.T) {
+	l := newEventLog(4)
+	_, _, _, changed := l.since(0, 0)
+	appendTestEvents(l, 1)
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("changed was not closed by append")
+	}
+}
+
+type streamingService struct {
+	fakeRemoteDownloadService
+	ch chan interface{}
+}
+
+func (s *streamingService) StreamEvents(context.Context) (<-chan interface{}, func(), error) {
+	return s.ch, func() {}, nil
+}
+
+func TestEventsPollEndpoint(t *testing.T) {
+	svc := &streamingService{ch: make(chan interface{})}
+	const token = "poll-token"
+	baseURL := startAuthedTestServer(t, svc, token)
+
+	poll := func(query string) eventsPollResponse {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, baseURL+"/events/poll?"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET /events/poll?%s: %v", query, err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET /events/poll?%s: status %d", query, resp.StatusCode)
+		}
+		var out eventsPollResponse
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return out
+	}
+
+	start := poll("")
+	if start.Seq != 0 || len(start.Events) != 0 {
+		t.Fatalf("bootstrap = %+v", start)
+	}
+
+	// A waiting poll returns as soon as an event arrives
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		svc.ch <- events.DownloadPausedMsg{DownloadID: "a", Filename: "a.iso"}
+		svc.ch <- events.BatchProgressMsg{{DownloadID: "a"}, {DownloadID: "b"}}
+	}()
+	got := poll(fmt.Sprintf("since=%d&wait=5", start.Seq))
+	if len(got.Events) == 0 || got.Events[0].Type != events.EventTypePaused || got.Events[0].Seq != 1 {
+		t.Fatalf("first batch = %+v", got)
+	}
+
+	// Batched progress is flattened to one event per download
+	deadline := time.Now().Add(time.Second)
+	for got.Seq < 3 && time.Now().Before(deadline) {
+		next := poll(fmt.Sprintf("since=%d&wait=1", got.Seq))
+		got.Seq, got.Events = next.Seq, append(got.Events, next.Events...)
+	}
+	if got.Seq != 3 || got.Events[2].Type != events.EventTypeProgress {
+		t.Fatalf("events = %+v", got)
+	}
+
+	if empty := poll("since=3&wait=0"); empty.Seq != 3 || len(empty.Events) != 0 {
+		t.Errorf("timed-out poll = %+v", empty)
+	}
+	if reset := poll("since=50&wait=0"); !reset.Missed || reset.Seq != 3 {
+		t.Errorf("poll past the log = %+v, want missed", reset)
+	}
+}
+
+func TestEventsEndpoint_SharesSequence(t *testing.T) {
+	svc := &streamingService{ch: make(chan interface{})}
+	const token = "sse-token"
+	baseURL := startAuthedTestServer(t, svc, token)
+
+	req, _ := http.NewRequest(http.MethodGet, baseURL+"/events", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /events: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	svc.ch <- events.DownloadResumedMsg{DownloadID: "a"}
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	var frame []string
+	for len(frame) < 3 {
+		select {
+		case line := <-lines:
+			if line != "" {
+				frame = append(frame, line)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no SSE frame, got %q", frame)
+		}
+	}
+	if frame[0] != "id: 1" || frame[1] != "event: resumed" || !strings.HasPrefix(frame[2], "data: ") {
+		t.Errorf("frame = %q", frame)
+	}
+}
+
+func TestWSEndpoint_StreamsEvents(t *testing.T) {
+	svc := &streamingService{ch: make(chan interface{})}
+	const token = "ws-token"
+	baseURL := startAuthedTestServer(t, svc, token)
+
+	dial := func(protocols string) (net.Conn, *bufio.Reader, string) {
+		t.Helper()
+		conn, err := net.Dial("tcp", strings.TrimPrefix(baseURL, "http://"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = conn.Close() })
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+		_, _ = fmt.Fprintf(conn, "GET /ws HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+			"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Protocol: %s\r\n\r\n", protocols)
+		br := bufio.NewReader(conn)
+		status, _ := br.ReadString('\n')
+		for {
+			line, err := br.ReadString('\n')
+			if err != nil || line == "\r\n" {
+				break
+			}
+		}
+		return conn, br, status
+	}
+
+	if _, _, status := dial("surge.events, bearer.wrong"); !strings.Contains(status, "401") {
+		t.Fatalf("wrong token got %q, want 401", status)
+	}
+
+	_, br, status := dial("surge.events, bearer." + token)
+	if !strings.Contains(status, "101") {
+		t.Fatalf("upgrade got %q", status)
+	}
+	svc.ch <- events.DownloadResumedMsg{DownloadID: "a"}
+
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(br, head); err != nil {
+		t.Fatalf("reading frame: %v", err)
+	}
+	if head[0] != 0x81 {
+		t.Fatalf("frame header %x, want a final text frame", head[0])
+	}
+	payload := make([]byte, head[1]&0x7F)
+	if _, err := io.ReadFull(br, payload); err != nil {
+		t.Fatalf("reading payload: %v", err)
+	}
+	var ev loggedEvent
+	if err := json.Unmarshal(payload, &ev); err != nil {
+		t.Fatalf("invalid message %q: %v", payload, err)
+	}
+	if ev.Seq != 1 || ev.Type != events.EventTypeResumed {
+		t.Errorf("message = %+v, want the resumed event as seq 1", ev)
+	}
+}
+
+// readSSELines returns the first n non-empty lines of an SSE stream
+func readSSELines(t *testing.T, body io.Reader, n int) []string {
+	t.Helper()
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	var out []string
+	for len(out) < n {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatalf("stream ended after %q", out)
+			}
+			if line != "" {
+				out = append(out, line)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out after %q", out)
+		}
+	}
+	return out
+}
+
+func TestEventsEndpoint_ResumesFromLastEventID(t *testing.T) {
+	svc := &streamingService{ch: make(chan interface{})}
+	el := newEventLog(4)
+	if err := el.start(svc); err != nil {
+		t.Fatal(err)
+	}
+	appendTestEvents(el, 3)
+	server := httptest.NewServer(eventsHandler(el, svc))
+	t.Cleanup(server.Close) // After the streams are closed
+
+	get := func(lastEventID string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		req.Header.Set("Last-Event-ID", lastEventID)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET /events: %v", err)
+		}
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+
+	lines := readSSELines(t, get("1").Body, 6)
+	if lines[0] != "id: 2" || lines[3] != "id: 3" {
+		t.Errorf("resumed stream = %q, want events 2 and 3", lines)
+	}
+
+	// An ID from before a restart can't be resumed
+	lines = readSSELines(t, get("99").Body, 2)
+	if lines[0] != "id: 3" || lines[1] != "event: missed" {
+		t.Errorf("stale resume = %q, want missed", lines)
+	}
+}
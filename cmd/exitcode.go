@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"syscall"
+
+	"github.com/surge-downloader/surge/internal/processing"
+)
+
+// Exit codes, so scripts and cron jobs can tell failures apart
+const (
+	exitOK        = 0
+	exitFailure   = 1   // Anything not covered below, including bad usage
+	exitNetwork   = 2   // Surge, or the server of a download, could not be reached
+	exitVerify    = 3   // A download failed verification
+	exitDuplicate = 4   // A URL was skipped or rejected as already downloaded or queued
+	exitCancelled = 130 // Interrupted by Ctrl+C or a signal, as shells report SIGINT
+)
+
+// errServerNotRunning is returned when a command needs a running Surge and
+// none was found
+var errServerNotRunning = errors.New("surge is not running locally. start it or pass --host (or set SURGE_HOST)")
+
+// exitCodeFor returns the exit code for a command failing with err
+func exitCodeFor(err error) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, context.Canceled):
+		return exitCancelled
+	case errors.Is(err, errAlreadyDownloaded), errors.Is(err, errDuplicateRejected), errors.Is(err, processing.ErrAlreadyDownloaded):
+		return exitDuplicate
+	case isNetworkError(err):
+		return exitNetwork
+	}
+	return exitFailure
+}
+
+// isNetworkError reports whether err comes from failing to reach a server,
+// as opposed to one answering with an error
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, errServerNotRunning) || errors.As(err, &netErr) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// runExitCode is the exit code of a command that ran to the end but should
+// still report a failure, such as surge server --exit-when-done after a
+// download failed. Execute exits with it once the command has cleaned up.
+var runExitCode = struct {
+	sync.Mutex
+	code int
+}{}
+
+// setExitCode records code for Execute to exit with. The first failure
+// wins, except that a cancellation outranks everything.
+func setExitCode(code int) {
+	runExitCode.Lock()
+	defer runExitCode.Unlock()
+	if runExitCode.code == exitOK || code == exitCancelled {
+		runExitCode.code = code
+	}
+}
+
+// pendingExitCode returns the code recorded by setExitCode
+func pendingExitCode() int {
+	runExitCode.Lock()
+	defer runExitCode.Unlock()
+	return runExitCode.code
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/processing"
+)
+
+func TestExitCodeFor(t *testing.T) {
+	_, dialErr := http.Get("http://127.0.0.1:1") // Nothing listens on port 1
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, exitOK},
+		{"other", errors.New("disk full"), exitFailure},
+		{"not running", errServerNotRunning, exitNetwork},
+		{"refused", dialErr, exitNetwork},
+		{"dns", &net.DNSError{Err: "no such host", Name: "nowhere.invalid"}, exitNetwork},
+		{"reset", fmt.Errorf("chunk 3: %w", syscall.ECONNRESET), exitNetwork},
+		{"archived", fmt.Errorf("adding: %w", errAlreadyDownloaded), exitDuplicate},
+		{"rejected", fmt.Errorf("%w: already queued", errDuplicateRejected), exitDuplicate},
+		{"archived locally", processing.ErrAlreadyDownloaded, exitDuplicate},
+		{"cancelled", fmt.Errorf("probe: %w", context.Canceled), exitCancelled},
+	}
+	for _, tt := range tests {
+		if got := exitCodeFor(tt.err); got != tt.want {
+			t.Errorf("%s: exitCodeFor(%v) = %d, want %d", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestSetExitCode(t *testing.T) {
+	t.Cleanup(func() { runExitCode.code = exitOK })
+
+	runExitCode.code = exitOK
+	setExitCode(exitNetwork)
+	setExitCode(exitDuplicate)
+	if got := pendingExitCode(); got != exitNetwork {
+		t.Errorf("exit code = %d, want the first failure", got)
+	}
+	setExitCode(exitCancelled)
+	if got := pendingExitCode(); got != exitCancelled {
+		t.Errorf("exit code = %d, want a cancellation to win", got)
+	}
+}
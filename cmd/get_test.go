@@ -22,7 +22,7 @@ func startAuthedTestServer(t *testing.T, service core.DownloadService, token str
 
 	mux := http.NewServeMux()
 	registerHTTPRoutes(mux, 0, "", service)
-	handler := corsMiddleware(authMiddleware(token, mux))
+	handler := corsMiddleware(versionMiddleware(authMiddleware(token, mux)))
 
 	server := httptest.NewServer(handler)
 	t.Cleanup(server.Close)
@@ -57,6 +57,7 @@ func TestCLI_DeleteEndpoint_CleansPausedStateAndPartialFile(t *testing.T) {
 	t.Cleanup(func() {
 		streamCleanup()
 		<-workerDone
+		lifecycle.WaitPostProcessing()
 	})
 
 	const authToken = "test-token-delete-endpoint"
@@ -99,7 +100,7 @@ func TestCLI_DeleteEndpoint_CleansPausedStateAndPartialFile(t *testing.T) {
 		t.Fatalf("failed to seed paused state: %v", err)
 	}
 
-	resp, err := doRequest(http.MethodDelete, baseURL+"/delete?id="+id)
+	resp, err := doRequest(http.MethodDelete, baseURL+"/delete?permanent=true&id="+id)
 	if err != nil {
 		t.Fatalf("Failed to request delete: %v", err)
 	}
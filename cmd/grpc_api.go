@@ -0,0 +1,290 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/processing"
+	"github.com/surge-downloader/surge/internal/rpc/surgepb"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// grpcServer serves surgepb.DownloadService from a download service, the gRPC
+// counterpart of registerHTTPRoutes
+type grpcServer struct {
+	surgepb.UnimplementedDownloadServiceServer
+	service          core.DownloadService
+	defaultOutputDir string
+}
+
+// newGRPCServer returns a gRPC server for service that rejects calls without
+// token
+func newGRPCServer(service core.DownloadService, defaultOutputDir, token string) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if err := checkGRPCToken(ctx, token); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := checkGRPCToken(ss.Context(), token); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	)
+	surgepb.RegisterDownloadServiceServer(server, &grpcServer{service: service, defaultOutputDir: defaultOutputDir})
+	return server
+}
+
+// checkGRPCToken accepts a call whose "authorization" metadata is
+// "Bearer <token>", like the HTTP API's header
+func checkGRPCToken(ctx context.Context, token string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		if provided, ok := strings.CutPrefix(v, "Bearer "); ok && tokenMatches(provided, token) {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "missing or invalid token")
+}
+
+// startGRPCServer serves the gRPC API on port, if > 0, with the same token as
+// the HTTP API. It returns the port served, or 0 when gRPC is off.
+func startGRPCServer(port int, defaultOutputDir string, service core.DownloadService, tokenOverride string) (int, error) {
+	if port <= 0 {
+		return 0, nil
+	}
+	ln, err := net.Listen("tcp", net.JoinHostPort(serverBindAddress(), strconv.Itoa(port)))
+	if err != nil {
+		return 0, fmt.Errorf("could not bind gRPC API to port %d: %w", port, err)
+	}
+
+	token := strings.TrimSpace(tokenOverride)
+	if token == "" {
+		token = ensureAuthToken()
+	}
+	go func() {
+		if err := newGRPCServer(service, defaultOutputDir, token).Serve(ln); err != nil {
+			utils.Debug("gRPC server error: %v", err)
+		}
+	}()
+	return port, nil
+}
+
+// grpcError maps a service error to a gRPC status
+func grpcError(err error) error {
+	if errors.Is(err, types.ErrNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+func (s *grpcServer) Add(ctx context.Context, req *surgepb.AddRequest) (*surgepb.AddResponse, error) {
+	if req.GetUrl() == "" {
+		return nil, status.Error(codes.InvalidArgument, "url is required")
+	}
+	if draining.Load() {
+		return nil, status.Error(codes.Unavailable, "surge is shutting down")
+	}
+	if strings.Contains(req.GetPath(), "..") || strings.Contains(req.GetFilename(), "..") {
+		return nil, status.Error(codes.InvalidArgument, "invalid path")
+	}
+	if strings.ContainsAny(req.GetFilename(), `/\`) {
+		return nil, status.Error(codes.InvalidArgument, "invalid filename")
+	}
+
+	settings := getSettings()
+	category := req.GetCategory()
+	if category != "" {
+		cat := config.FindCategory(category, settings.General.Categories)
+		if cat == nil {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown category: %s", category)
+		}
+		category = cat.Name
+	}
+
+	// gRPC has no pending-approval answer, so Add skips the TUI's prompt
+	// like extension requests that set skip_approval
+	addReq := &processing.DownloadRequest{
+		URL:                req.GetUrl(),
+		Filename:           req.GetFilename(),
+		Path:               utils.EnsureAbsPath(resolveOutputDir(req.GetPath(), false, s.defaultOutputDir, settings)),
+		Mirrors:            req.GetMirrors(),
+		Headers:            req.GetHeaders(),
+		Tags:               req.GetTags(),
+		Category:           category,
+		IsExplicitCategory: category != "",
+		SkipApproval:       true,
+		Connections:        int(req.GetConnections()),
+		SpeedLimit:         req.GetSpeedLimit(),
+	}
+
+	lifecycle, err := lifecycleForLocalService(s.service)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to initialize lifecycle manager: %v", err)
+	}
+	var id string
+	if lifecycle != nil {
+		id, err = lifecycle.Enqueue(ctx, addReq)
+	} else {
+		id, err = s.service.Add(addReq)
+	}
+	if errors.Is(err, processing.ErrAlreadyDownloaded) {
+		return &surgepb.AddResponse{AlreadyDownloaded: true}, nil
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to add download: %v", err)
+	}
+
+	atomic.AddInt32(&activeDownloads, 1)
+	return &surgepb.AddResponse{Id: id}, nil
+}
+
+func (s *grpcServer) Pause(_ context.Context, req *surgepb.PauseRequest) (*surgepb.PauseResponse, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	if err := s.service.Pause(req.GetId()); err != nil {
+		return nil, grpcError(err)
+	}
+	return &surgepb.PauseResponse{}, nil
+}
+
+func (s *grpcServer) Resume(_ context.Context, req *surgepb.ResumeRequest) (*surgepb.ResumeResponse, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	if err := s.service.Resume(req.GetId()); err != nil {
+		return nil, grpcError(err)
+	}
+	return &surgepb.ResumeResponse{}, nil
+}
+
+func (s *grpcServer) Delete(_ context.Context, req *surgepb.DeleteRequest) (*surgepb.DeleteResponse, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	if err := s.service.Delete(req.GetId()); err != nil {
+		return nil, grpcError(err)
+	}
+	return &surgepb.DeleteResponse{}, nil
+}
+
+func (s *grpcServer) List(context.Context, *surgepb.ListRequest) (*surgepb.ListResponse, error) {
+	statuses, err := s.service.List()
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	resp := &surgepb.ListResponse{Downloads: make([]*surgepb.Download, 0, len(statuses))}
+	for _, st := range statuses {
+		resp.Downloads = append(resp.Downloads, downloadToProto(st))
+	}
+	return resp, nil
+}
+
+func (s *grpcServer) StreamEvents(_ *surgepb.StreamEventsRequest, stream grpc.ServerStreamingServer[surgepb.Event]) error {
+	ctx := stream.Context()
+	ch, cleanup, err := s.service.StreamEvents(ctx)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to subscribe to events: %v", err)
+	}
+	defer cleanup()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			evs, err := eventsToProto(msg)
+			if err != nil {
+				utils.Debug("Error encoding event: %v", err)
+				continue
+			}
+			for _, ev := range evs {
+				if err := stream.Send(ev); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func downloadToProto(st types.DownloadStatus) *surgepb.Download {
+	return &surgepb.Download{
+		Id:            st.ID,
+		Url:           st.URL,
+		Filename:      st.Filename,
+		DestPath:      st.DestPath,
+		TotalSize:     st.TotalSize,
+		Downloaded:    st.Downloaded,
+		Progress:      st.Progress,
+		Speed:         st.Speed,
+		Status:        st.Status,
+		PauseReason:   st.PauseReason,
+		Error:         st.Error,
+		Eta:           st.ETA,
+		Connections:   int32(st.Connections),
+		AddedAt:       st.AddedAt,
+		Tags:          st.Tags,
+		Category:      st.Category,
+		QueuePosition: st.QueuePosition,
+	}
+}
+
+func progressToProto(p events.ProgressMsg) *surgepb.Event {
+	return &surgepb.Event{
+		Type: events.EventTypeProgress,
+		Payload: &surgepb.Event_Progress{Progress: &surgepb.Progress{
+			DownloadId:        p.DownloadID,
+			Downloaded:        p.Downloaded,
+			Total:             p.Total,
+			Speed:             p.Speed,
+			ElapsedMs:         p.Elapsed.Milliseconds(),
+			ActiveConnections: int32(p.ActiveConnections),
+		}},
+	}
+}
+
+// eventsToProto encodes one service event. Progress leaves out the chunk
+// bitmap that only the TUI draws; other events carry their SSE JSON.
+func eventsToProto(msg interface{}) ([]*surgepb.Event, error) {
+	switch m := msg.(type) {
+	case events.ProgressMsg:
+		return []*surgepb.Event{progressToProto(m)}, nil
+	case events.BatchProgressMsg:
+		out := make([]*surgepb.Event, 0, len(m))
+		for _, p := range m {
+			out = append(out, progressToProto(p))
+		}
+		return out, nil
+	}
+
+	frames, err := events.EncodeSSEMessages(msg)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*surgepb.Event, 0, len(frames))
+	for _, f := range frames {
+		out = append(out, &surgepb.Event{Type: f.Event, Payload: &surgepb.Event_Json{Json: f.Data}})
+	}
+	return out, nil
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/rpc/surgepb"
+)
+
+type grpcTestService struct {
+	streamingService
+	paused string
+}
+
+func (s *grpcTestService) Pause(id string) error {
+	if id != "a" {
+		return types.ErrNotFound
+	}
+	s.paused = id
+	return nil
+}
+
+func (s *grpcTestService) List() ([]types.DownloadStatus, error) {
+	return []types.DownloadStatus{{ID: "a", URL: "https://example.com/a.bin", Status: "downloading", Connections: 4}}, nil
+}
+
+func dialTestGRPC(t *testing.T, svc *grpcTestService, token string) surgepb.DownloadServiceClient {
+	t.Helper()
+	ln := bufconn.Listen(1 << 20)
+	server := newGRPCServer(svc, t.TempDir(), token)
+	go func() { _ = server.Serve(ln) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return surgepb.NewDownloadServiceClient(conn)
+}
+
+func TestGRPCAPI(t *testing.T) {
+	svc := &grpcTestService{streamingService: streamingService{ch: make(chan interface{})}}
+	const token = "grpc-token"
+	client := dialTestGRPC(t, svc, token)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := client.List(ctx, &surgepb.ListRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("List without token = %v, want Unauthenticated", err)
+	}
+	bad := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer wrong")
+	if _, err := client.List(bad, &surgepb.ListRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("List with wrong token = %v, want Unauthenticated", err)
+	}
+
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+	list, err := client.List(ctx, &surgepb.ListRequest{})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list.Downloads) != 1 || list.Downloads[0].Id != "a" || list.Downloads[0].Connections != 4 {
+		t.Fatalf("List = %v", list.Downloads)
+	}
+
+	if _, err := client.Pause(ctx, &surgepb.PauseRequest{Id: "a"}); err != nil || svc.paused != "a" {
+		t.Fatalf("Pause: err %v, paused %q", err, svc.paused)
+	}
+	if _, err := client.Pause(ctx, &surgepb.PauseRequest{Id: "missing"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("Pause unknown id = %v, want NotFound", err)
+	}
+	if _, err := client.Add(ctx, &surgepb.AddRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Add without url = %v, want InvalidArgument", err)
+	}
+
+	stream, err := client.StreamEvents(ctx, &surgepb.StreamEventsRequest{})
+	if err != nil {
+		t.Fatalf("StreamEvents: %v", err)
+	}
+	go func() {
+		svc.ch <- events.BatchProgressMsg{{DownloadID: "a", Downloaded: 10, Total: 100, Elapsed: 2 * time.Second}}
+		svc.ch <- events.DownloadPausedMsg{DownloadID: "a", Filename: "a.bin"}
+	}()
+
+	ev, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	if p := ev.GetProgress(); ev.Type != events.EventTypeProgress || p.GetDownloadId() != "a" || p.GetDownloaded() != 10 || p.GetElapsedMs() != 2000 {
+		t.Fatalf("progress event = %v", ev)
+	}
+	ev, err = stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	if ev.Type != events.EventTypePaused || len(ev.GetJson()) == 0 {
+		t.Fatalf("paused event = %v", ev)
+	}
+}
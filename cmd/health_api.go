@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/processing"
+)
+
+// healthMinFreeSpace is the free space at the default download directory
+// below which the daemon reports itself unhealthy
+const healthMinFreeSpace = 100 * types.MB
+
+var (
+	// Swapped out in tests
+	pingStateDB   = state.Ping
+	freeSpaceAtFn = processing.FreeSpace
+)
+
+// healthResponse is /health. Status is "ok" whenever the server answers, as
+// clients look for it to find the daemon; Healthy says whether it can also
+// do its work.
+type healthResponse struct {
+	Status    string         `json:"status"`
+	Healthy   bool           `json:"healthy"`
+	Port      int            `json:"port"`
+	Version   string         `json:"version"`
+	Uptime    int64          `json:"uptime_seconds"`
+	Database  healthCheck    `json:"database"`
+	Disk      diskHealth     `json:"disk"`
+	Downloads downloadCounts `json:"downloads"`
+}
+
+type healthCheck struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// diskHealth is the free space at the default download directory
+type diskHealth struct {
+	healthCheck
+	Free uint64 `json:"free"` // Bytes
+}
+
+type downloadCounts struct {
+	Active int `json:"active"`
+	Queued int `json:"queued"`
+	Paused int `json:"paused"`
+}
+
+// publicHealthResponse is /health for requests without the token, which
+// learn only that the daemon is up
+type publicHealthResponse struct {
+	Status string `json:"status"`
+}
+
+// healthHandler reports whether the daemon is up and healthy. With ?ready it
+// answers 503 when it is not healthy, for probes that only read the status.
+// The checks and details are only run and shown for requests with API
+// access; without it, ?ready only fails while the daemon is shutting down.
+func healthHandler(port int, defaultOutputDir string, service core.DownloadService) http.HandlerFunc {
+	started := time.Now()
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, ready := r.URL.Query()["ready"]
+		if !hasAPIAccess(r) {
+			status := http.StatusOK
+			if ready && draining.Load() {
+				status = http.StatusServiceUnavailable
+			}
+			writeJSONResponse(w, status, publicHealthResponse{Status: "ok"})
+			return
+		}
+
+		resp := healthResponse{
+			Status:  "ok",
+			Port:    port,
+			Version: Version,
+			Uptime:  int64(time.Since(started).Seconds()),
+		}
+
+		if err := pingStateDB(); err != nil {
+			resp.Database.Error = err.Error()
+		} else {
+			resp.Database.OK = true
+		}
+
+		dir := defaultOutputDir
+		if dir == "" {
+			dir = getSettings().General.DefaultDownloadDir
+		}
+		if dir == "" {
+			dir = "."
+		}
+		if free, err := freeSpaceAtFn(dir); err != nil {
+			resp.Disk.Error = err.Error()
+		} else {
+			resp.Disk.Free = free
+			resp.Disk.OK = free >= healthMinFreeSpace
+			if !resp.Disk.OK {
+				resp.Disk.Error = "low disk space"
+			}
+		}
+
+		listOK := true
+		if service != nil {
+			statuses, err := service.List()
+			listOK = err == nil
+			for _, s := range statuses {
+				switch s.Status {
+				case "downloading":
+					resp.Downloads.Active++
+				case "queued":
+					resp.Downloads.Queued++
+				case "paused":
+					resp.Downloads.Paused++
+				}
+			}
+		}
+
+		resp.Healthy = resp.Database.OK && resp.Disk.OK && listOK && !draining.Load()
+		status := http.StatusOK
+		if ready && !resp.Healthy {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSONResponse(w, status, resp)
+	}
+}
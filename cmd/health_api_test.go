@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// listingService lists a fixed set of downloads
+type listingService struct {
+	fakeRemoteDownloadService
+	statuses []types.DownloadStatus
+}
+
+func (s *listingService) List() ([]types.DownloadStatus, error) {
+	return s.statuses, nil
+}
+
+func TestHealthEndpoint_ReportsChecks(t *testing.T) {
+	origPing, origFree := pingStateDB, freeSpaceAtFn
+	t.Cleanup(func() { pingStateDB, freeSpaceAtFn = origPing, origFree })
+	dbErr := error(nil)
+	free := uint64(10 * types.GB)
+	pingStateDB = func() error { return dbErr }
+	freeSpaceAtFn = func(string) (uint64, error) { return free, nil }
+
+	service := &listingService{statuses: []types.DownloadStatus{
+		{ID: "a", Status: "downloading"}, {ID: "b", Status: "queued"}, {ID: "c", Status: "queued"}, {ID: "d", Status: "paused"},
+	}}
+	handler := healthHandler(1700, t.TempDir(), service)
+	get := func(target string) (int, healthResponse) {
+		rec := httptest.NewRecorder()
+		handler(rec, markAuthorized(httptest.NewRequest(http.MethodGet, target, nil)))
+		var resp healthResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode %s: %v", rec.Body.String(), err)
+		}
+		return rec.Code, resp
+	}
+
+	code, resp := get("/health")
+	if code != http.StatusOK || resp.Status != "ok" || !resp.Healthy || resp.Port != 1700 || resp.Version != Version {
+		t.Fatalf("healthy = %d %+v", code, resp)
+	}
+	if resp.Downloads != (downloadCounts{Active: 1, Queued: 2, Paused: 1}) || resp.Disk.Free != free || !resp.Database.OK {
+		t.Errorf("details = %+v", resp)
+	}
+
+	dbErr = errors.New("database is locked")
+	free = 10 * types.MB
+	code, resp = get("/health")
+	if code != http.StatusOK || resp.Status != "ok" || resp.Healthy {
+		t.Fatalf("unhealthy = %d %+v", code, resp)
+	}
+	if resp.Database.Error != "database is locked" || resp.Disk.OK || resp.Disk.Error == "" {
+		t.Errorf("checks = %+v %+v", resp.Database, resp.Disk)
+	}
+	if code, _ := get("/health?ready"); code != http.StatusServiceUnavailable {
+		t.Errorf("ready probe = %d, want 503", code)
+	}
+}
+
+func TestHealthEndpoint_PublicWithoutToken(t *testing.T) {
+	origPing, origFree := pingStateDB, freeSpaceAtFn
+	t.Cleanup(func() { pingStateDB, freeSpaceAtFn = origPing, origFree })
+	pingStateDB = func() error { t.Error("database pinged without the token"); return nil }
+	freeSpaceAtFn = func(string) (uint64, error) { t.Error("disk checked without the token"); return 0, nil }
+
+	const token = "health-token"
+	baseURL := startAuthedTestServer(t, &listingService{}, token)
+	get := func(target, auth string) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, baseURL+target, nil)
+		if auth != "" {
+			req.Header.Set("Authorization", "Bearer "+auth)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, strings.TrimSpace(string(data))
+	}
+
+	for _, auth := range []string{"", "wrong-token"} {
+		if code, body := get("/health", auth); code != http.StatusOK || body != `{"status":"ok"}` {
+			t.Errorf("/health with %q = %d %s, want only the status", auth, code, body)
+		}
+		if code, _ := get("/health?ready", auth); code != http.StatusOK {
+			t.Errorf("/health?ready with %q = %d, want 200", auth, code)
+		}
+	}
+
+	pingStateDB = func() error { return nil }
+	freeSpaceAtFn = func(string) (uint64, error) { return uint64(types.GB), nil }
+	code, body := get("/health", token)
+	var resp healthResponse
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if code != http.StatusOK || !resp.Healthy || resp.Version != Version {
+		t.Errorf("/health with the token = %d %s", code, body)
+	}
+}
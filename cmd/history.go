@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List finished downloads, or work with the download history",
+	Long: `List completed downloads, most recently completed first, from the running
+server or the local database. Use "surge history export" for other statuses
+and formats.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		tag, _ := cmd.Flags().GetString("tag")
+
+		mustInitializeGlobalState()
+		baseURL, token, err := resolveAPIConnection(false)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		var entries []types.DownloadEntry
+		if baseURL != "" {
+			entries, err = getRemoteHistory(baseURL, token, tag)
+		} else {
+			entries, err = state.LoadCompletedDownloads()
+			entries = core.FilterHistoryByTag(entries, tag)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing history: %v\n", err)
+			os.Exit(1)
+		}
+		printHistory(os.Stdout, entries, globalJSON)
+	},
+}
+
+// getRemoteHistory fetches the completed downloads from the running server
+func getRemoteHistory(baseURL, token, tag string) ([]types.DownloadEntry, error) {
+	path := "/history"
+	if tag != "" {
+		path += "?tag=" + url.QueryEscape(tag)
+	}
+	resp, err := doAPIRequest(http.MethodGet, baseURL, token, path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to server: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			utils.Debug("Error closing response body: %v", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %s: %s", resp.Status, core.ReadAPIError(resp).Detail)
+	}
+	var entries []types.DownloadEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// printHistory writes the completed downloads as a table, or as a JSON array
+func printHistory(w io.Writer, entries []types.DownloadEntry, jsonOutput bool) {
+	if jsonOutput {
+		if entries == nil {
+			entries = []types.DownloadEntry{}
+		}
+		data, _ := json.MarshalIndent(entries, "", "  ")
+		_, _ = fmt.Fprintln(w, string(data))
+		return
+	}
+	if len(entries) == 0 {
+		_, _ = fmt.Fprintln(w, "No completed downloads.")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ID\tFILENAME\tSIZE\tAVG SPEED\tCOMPLETED")
+	_, _ = fmt.Fprintln(tw, "--\t--------\t----\t---------\t---------")
+	for _, e := range entries {
+		id := e.ID
+		if len(id) > 8 {
+			id = id[:8]
+		}
+		filename := e.Filename
+		if len(filename) > 25 {
+			filename = filename[:22] + "..."
+		}
+		speed := "-"
+		if e.AvgSpeed > 0 {
+			speed = utils.ConvertBytesToHumanReadable(int64(e.AvgSpeed)) + "/s"
+		}
+		completed := "-"
+		if e.CompletedAt > 0 {
+			completed = time.Unix(e.CompletedAt, 0).Format("2006-01-02 15:04")
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", id, filename, utils.ConvertBytesToHumanReadable(e.TotalSize), speed, completed)
+	}
+	_ = tw.Flush()
+}
+
+var historyExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export downloads as CSV, JSON or an aria2 session",
+	Long: `Write every tracked download, optionally filtered by status and by when it was
+added, as CSV, a JSON array or an aria2 session file. The aria2 format exports only
+unfinished downloads unless --status says otherwise; "surge add --aria2" reads it
+back. Reads from the running server when there is one, otherwise from the local
+database.`,
+	Example: `  surge history export --format csv --status completed --since 2026-01-01 -o january.csv
+  surge history export --status error,paused
+  surge history export --format aria2 -o surge.session`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+		statuses, _ := cmd.Flags().GetStringSlice("status")
+		since, _ := cmd.Flags().GetString("since")
+		until, _ := cmd.Flags().GetString("until")
+		output, _ := cmd.Flags().GetString("output")
+
+		format = strings.ToLower(format)
+		if !core.IsExportFormat(format) {
+			fmt.Fprintf(os.Stderr, "Error: unknown format %q (want csv, json or aria2)\n", format)
+			os.Exit(1)
+		}
+		status := strings.Join(statuses, ",")
+		filter, err := core.ParseExportFilter(status, since, until)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		mustInitializeGlobalState()
+		baseURL, token, err := resolveAPIConnection(false)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		var w io.Writer = os.Stdout
+		if output != "" {
+			f, err := os.Create(output)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			defer func() {
+				if err := f.Close(); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				}
+			}()
+			w = f
+		}
+
+		if baseURL != "" {
+			q := url.Values{}
+			q.Set("format", format)
+			q.Set("status", status)
+			q.Set("since", since)
+			q.Set("until", until)
+			err = exportFromServer(w, baseURL, token, "/history/export?"+q.Encode())
+		} else {
+			err = core.ExportDownloads(w, format, filter)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error exporting history: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// exportFromServer copies the server's export response to w
+func exportFromServer(w io.Writer, baseURL, token, path string) error {
+	resp, err := doAPIRequest(http.MethodGet, baseURL, token, path, nil)
+	if err != nil {
+		return fmt.Errorf("connecting to server: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			utils.Debug("Error closing response body: %v", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server returned %s: %s", resp.Status, core.ReadAPIError(resp).Detail)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.Flags().String("tag", "", "Only list downloads with this tag")
+	historyCmd.AddCommand(historyExportCmd)
+	historyExportCmd.Flags().String("format", core.ExportJSON, "Output format: csv, json or aria2")
+	historyExportCmd.Flags().StringSlice("status", nil, "Only export downloads with these statuses (e.g. completed,error)")
+	historyExportCmd.Flags().String("since", "", "Only downloads added on or after this date (YYYY-MM-DD or RFC 3339)")
+	historyExportCmd.Flags().String("until", "", "Only downloads added before this time, or on or before this date")
+	historyExportCmd.Flags().StringP("output", "o", "", "Write to this file instead of stdout")
+}
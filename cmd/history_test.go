@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestPrintHistory(t *testing.T) {
+	entries := []types.DownloadEntry{{
+		ID:          "12345678-aaaa-bbbb-cccc-1234567890ab",
+		Filename:    "ubuntu.iso",
+		Status:      "completed",
+		TotalSize:   2 * types.MB,
+		AvgSpeed:    float64(types.MB),
+		CompletedAt: 1700000000,
+		Overrides:   &types.DownloadOverrides{Headers: map[string]string{"Cookie": "secret"}},
+	}}
+
+	var table bytes.Buffer
+	printHistory(&table, entries, false)
+	if out := table.String(); !strings.Contains(out, "12345678 ") || !strings.Contains(out, "ubuntu.iso") || !strings.Contains(out, "/s") {
+		t.Errorf("table output = %q", out)
+	}
+
+	var js bytes.Buffer
+	printHistory(&js, entries, true)
+	var decoded []types.DownloadEntry
+	if err := json.Unmarshal(js.Bytes(), &decoded); err != nil {
+		t.Fatalf("JSON output %q: %v", js.String(), err)
+	}
+	if len(decoded) != 1 || decoded[0].ID != entries[0].ID || decoded[0].CompletedAt != 1700000000 {
+		t.Errorf("decoded = %+v", decoded)
+	}
+	if strings.Contains(js.String(), "secret") {
+		t.Errorf("JSON output leaks stored headers: %s", js.String())
+	}
+
+	js.Reset()
+	printHistory(&js, nil, true)
+	if strings.TrimSpace(js.String()) != "[]" {
+		t.Errorf("empty JSON output = %q, want []", js.String())
+	}
+}
+
+func TestJSONFlagIsGlobal(t *testing.T) {
+	for _, c := range []string{"ls", "history", "verify", "discover", "calibrate"} {
+		cmd, _, err := rootCmd.Find([]string{c})
+		if err != nil {
+			t.Fatalf("find %s: %v", c, err)
+		}
+		if cmd.InheritedFlags().Lookup("json") == nil {
+			t.Errorf("surge %s does not take the global --json flag", c)
+		}
+		if cmd.LocalNonPersistentFlags().Lookup("json") != nil {
+			t.Errorf("surge %s shadows the global --json flag with its own", c)
+		}
+	}
+}
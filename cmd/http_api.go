@@ -1,138 +1,677 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/surge-downloader/surge/internal/core"
-	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/processing"
 	"github.com/surge-downloader/surge/internal/utils"
+	"github.com/surge-downloader/surge/internal/websocket"
 )
 
 func registerHTTPRoutes(mux *http.ServeMux, port int, defaultOutputDir string, service core.DownloadService) {
-	mux.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {
-		writeJSONResponse(w, http.StatusOK, map[string]interface{}{
-			"status": "ok",
-			"port":   port,
-		})
-	})
+	mux.HandleFunc("/health", healthHandler(port, defaultOutputDir, service))
 
-	mux.HandleFunc("/events", eventsHandler(service))
+	eventLog := newEventLog(eventLogSize)
+	mux.HandleFunc("/events", eventsHandler(eventLog, service))
+	mux.HandleFunc("/events/poll", requireMethod(http.MethodGet, eventsPollHandler(eventLog, service)))
+	mux.HandleFunc("/status/poll", requireMethod(http.MethodGet, statusPollHandler(eventLog, service)))
+	mux.HandleFunc("/ws", requireMethod(http.MethodGet, wsEventsHandler(eventLog, service)))
 
-	mux.HandleFunc("/download", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/download", withIdempotency(newIdempotencyCache(), func(w http.ResponseWriter, r *http.Request) {
 		handleDownload(w, r, defaultOutputDir, service)
-	})
+	}))
+	mux.HandleFunc("/downloads", requireMethod(http.MethodPost, batchHandler(defaultOutputDir, service)))
 
-	mux.HandleFunc("/pause", requireMethod(http.MethodPost, withRequiredID(func(w http.ResponseWriter, _ *http.Request, id string) {
+	mux.HandleFunc("/pause", requireMethod(http.MethodPost, withIDOrHost(func(w http.ResponseWriter, _ *http.Request, id string) {
 		if err := service.Pause(id); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			httpError(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 		writeJSONResponse(w, http.StatusOK, map[string]string{"status": "paused", "id": id})
+	}, func(w http.ResponseWriter, _ *http.Request, host string) {
+		ids, err := core.PauseHost(service, host)
+		writeHostActionResponse(w, "paused", host, ids, err)
 	})))
 
-	mux.HandleFunc("/resume", requireMethod(http.MethodPost, withRequiredID(func(w http.ResponseWriter, _ *http.Request, id string) {
+	mux.HandleFunc("/resume", requireMethod(http.MethodPost, withIDOrHost(func(w http.ResponseWriter, _ *http.Request, id string) {
 		if err := service.Resume(id); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			httpError(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 		writeJSONResponse(w, http.StatusOK, map[string]string{"status": "resumed", "id": id})
+	}, func(w http.ResponseWriter, _ *http.Request, host string) {
+		ids, err := core.ResumeHost(service, host)
+		writeHostActionResponse(w, "resumed", host, ids, err)
 	})))
 
-	mux.HandleFunc("/delete", requireMethods(withRequiredID(func(w http.ResponseWriter, _ *http.Request, id string) {
-		if err := service.Delete(id); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+	mux.HandleFunc("/delete", requireMethods(withRequiredID(func(w http.ResponseWriter, r *http.Request, id string) {
+		del := service.Delete
+		if permanent, _ := strconv.ParseBool(r.URL.Query().Get("permanent")); permanent {
+			if trasher, ok := service.(core.Trasher); ok {
+				del = trasher.DeletePermanently
+			}
+		}
+		if err := del(id); err != nil {
+			httpError(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 		writeJSONResponse(w, http.StatusOK, map[string]string{"status": "deleted", "id": id})
 	}), http.MethodDelete, http.MethodPost))
 
-	mux.HandleFunc("/list", requireMethod(http.MethodGet, func(w http.ResponseWriter, _ *http.Request) {
-		statuses, err := service.List()
+	mux.HandleFunc("/archive", requireMethod(http.MethodPost, withRequiredID(func(w http.ResponseWriter, _ *http.Request, id string) {
+		archiver, ok := service.(core.Archiver)
+		if !ok {
+			httpError(w, "Archiving is not supported", http.StatusNotImplemented)
+			return
+		}
+		if err := archiver.Archive(id); err != nil {
+			if errors.Is(err, types.ErrNotFound) {
+				httpError(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			httpError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]string{"status": "archived", "id": id})
+	})))
+
+	mux.HandleFunc("/restore-partial", requireMethod(http.MethodPost, withRequiredID(func(w http.ResponseWriter, _ *http.Request, id string) {
+		archiver, ok := service.(core.Archiver)
+		if !ok {
+			httpError(w, "Archiving is not supported", http.StatusNotImplemented)
+			return
+		}
+		if err := archiver.RestorePartial(id); err != nil {
+			httpError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]string{"status": "restored", "id": id})
+	})))
+
+	mux.HandleFunc("/retry", requireMethod(http.MethodPost, withRequiredID(func(w http.ResponseWriter, r *http.Request, id string) {
+		retrier, ok := service.(core.Retrier)
+		if !ok {
+			httpError(w, "Retrying is not supported", http.StatusNotImplemented)
+			return
+		}
+		probe := r.URL.Query().Get("probe") == "true"
+		if err := retrier.Retry(id, probe); err != nil {
+			switch {
+			case errors.Is(err, types.ErrNotFound):
+				httpError(w, err.Error(), http.StatusNotFound)
+			case errors.Is(err, state.ErrNotFailed):
+				httpError(w, err.Error(), http.StatusConflict)
+			case errors.Is(err, processing.ErrStillFailing):
+				httpError(w, err.Error(), http.StatusBadGateway)
+			default:
+				httpError(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]string{"status": "retrying", "id": id})
+	})))
+
+	mux.HandleFunc("/restore", requireMethod(http.MethodPost, withRequiredID(func(w http.ResponseWriter, _ *http.Request, id string) {
+		trasher, ok := service.(core.Trasher)
+		if !ok {
+			httpError(w, "Trash is not supported", http.StatusNotImplemented)
+			return
+		}
+		if err := trasher.RestoreTrashed(id); err != nil {
+			if errors.Is(err, types.ErrNotFound) {
+				httpError(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			if errors.Is(err, state.ErrNotTrashed) {
+				httpError(w, err.Error(), http.StatusConflict)
+				return
+			}
+			httpError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]string{"status": "restored", "id": id})
+	})))
+
+	mux.HandleFunc("/move", requireMethod(http.MethodPost, withRequiredID(func(w http.ResponseWriter, r *http.Request, id string) {
+		q := r.URL.Query()
+		if q.Has("dir") {
+			dir := q.Get("dir")
+			if strings.TrimSpace(dir) == "" {
+				httpError(w, "dir cannot be empty", http.StatusBadRequest)
+				return
+			}
+			relocateHandler(w, service, id, func(rel core.Relocator) (string, error) { return rel.Relocate(id, dir) })
+			return
+		}
+		if q.Has("position") == q.Has("by") {
+			httpError(w, "Pass either position, by or dir", http.StatusBadRequest)
+			return
+		}
+		key := "position"
+		if q.Has("by") {
+			key = "by"
+		}
+		n, err := strconv.Atoi(q.Get(key))
 		if err != nil {
-			http.Error(w, "Failed to list downloads: "+err.Error(), http.StatusInternalServerError)
+			httpError(w, key+" must be a number", http.StatusBadRequest)
 			return
 		}
-		writeJSONResponse(w, http.StatusOK, statuses)
-	}))
+		reorderer, ok := service.(core.Reorderer)
+		if !ok {
+			httpError(w, "Reordering is not supported", http.StatusNotImplemented)
+			return
+		}
+		move := reorderer.Move
+		if key == "by" {
+			move = reorderer.MoveBy
+		}
+		if err := move(id, n); err != nil {
+			if errors.Is(err, types.ErrNotFound) {
+				httpError(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			if errors.Is(err, state.ErrNotWaiting) {
+				httpError(w, err.Error(), http.StatusConflict)
+				return
+			}
+			httpError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]any{"status": "moved", "id": id, key: n})
+	})))
 
-	mux.HandleFunc("/history", requireMethod(http.MethodGet, func(w http.ResponseWriter, _ *http.Request) {
-		history, err := service.History()
+	mux.HandleFunc("/priority", requireMethod(http.MethodPost, withRequiredID(func(w http.ResponseWriter, r *http.Request, id string) {
+		q := r.URL.Query()
+		priority, err := types.ParsePriority(q.Get("level"))
 		if err != nil {
-			http.Error(w, "Failed to retrieve history: "+err.Error(), http.StatusInternalServerError)
+			httpError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		place := q.Get("place")
+		if place != "" && place != state.PlaceTop && place != state.PlaceBottom {
+			httpError(w, "place must be top or bottom", http.StatusBadRequest)
 			return
 		}
-		writeJSONResponse(w, http.StatusOK, history)
+		prioritizer, ok := service.(core.Prioritizer)
+		if !ok {
+			httpError(w, "Priorities are not supported", http.StatusNotImplemented)
+			return
+		}
+		if err := prioritizer.SetPriority(id, priority, place); err != nil {
+			if errors.Is(err, types.ErrNotFound) {
+				httpError(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			if errors.Is(err, state.ErrNotWaiting) {
+				httpError(w, err.Error(), http.StatusConflict)
+				return
+			}
+			httpError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]string{"status": "prioritized", "id": id, "level": types.PriorityName(priority)})
+	})))
+
+	mux.HandleFunc("/rename", requireMethod(http.MethodPost, withRequiredID(func(w http.ResponseWriter, r *http.Request, id string) {
+		name := r.URL.Query().Get("name")
+		relocateHandler(w, service, id, func(rel core.Relocator) (string, error) { return rel.Rename(id, name) })
+	})))
+
+	mux.HandleFunc("/list", requireMethod(http.MethodGet, withPage(func(w http.ResponseWriter, r *http.Request, cursor string, limit int) {
+		q := r.URL.Query()
+		query := core.ListQuery{
+			Tag:      q.Get("tag"),
+			Statuses: core.ParseStatusList(q.Get("status")),
+			Search:   q.Get("search"),
+			Sort:     strings.ToLower(q.Get("sort")),
+			Cursor:   cursor,
+			Limit:    limit,
+		}
+		if v := q.Get("offset"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				httpError(w, "Invalid offset parameter", http.StatusBadRequest)
+				return
+			}
+			query.Offset = n
+		}
+
+		var page []types.DownloadStatus
+		var next string
+		var err error
+		if pager, ok := service.(core.Pager); ok {
+			page, next, err = pager.ListPage(query)
+		} else {
+			var statuses []types.DownloadStatus
+			statuses, err = service.List()
+			if err == nil {
+				page, next, err = core.QueryStatuses(statuses, query)
+			}
+		}
+		if errors.Is(err, core.ErrInvalidCursor) || errors.Is(err, core.ErrInvalidListQuery) {
+			httpError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			httpError(w, "Failed to list downloads: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		setNextCursor(w, next)
+		writeJSONResponse(w, http.StatusOK, page)
+	})))
+
+	mux.HandleFunc("/history", requireMethod(http.MethodGet, withPage(func(w http.ResponseWriter, r *http.Request, cursor string, limit int) {
+		tag := r.URL.Query().Get("tag")
+		var page []types.DownloadEntry
+		var next string
+		var err error
+		if pager, ok := service.(core.Pager); ok {
+			page, next, err = pager.HistoryPage(tag, cursor, limit)
+		} else {
+			var history []types.DownloadEntry
+			history, err = service.History()
+			if err == nil {
+				page, next, err = core.PageHistory(core.FilterHistoryByTag(history, tag), cursor, limit)
+			}
+		}
+		if errors.Is(err, core.ErrInvalidCursor) {
+			httpError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			httpError(w, "Failed to retrieve history: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		setNextCursor(w, next)
+		writeJSONResponse(w, http.StatusOK, page)
+	})))
+
+	mux.HandleFunc("/history/export", requireMethod(http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		format := q.Get("format")
+		if format == "" {
+			format = core.ExportJSON
+		}
+		if !core.IsExportFormat(format) {
+			httpError(w, "Invalid format parameter (want csv, json or aria2)", http.StatusBadRequest)
+			return
+		}
+		filter, err := core.ParseExportFilter(q.Get("status"), q.Get("since"), q.Get("until"))
+		if err != nil {
+			httpError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", core.ExportContentType(format))
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "surge-history."+format))
+		// Headers are already sent, so a failure can only cut the body short
+		if err := core.ExportDownloads(w, format, filter); err != nil {
+			utils.Debug("History export failed: %v", err)
+		}
 	}))
 
 	mux.HandleFunc("/update-url", requireMethod(http.MethodPut, withRequiredID(func(w http.ResponseWriter, r *http.Request, id string) {
 		var req map[string]string
 		if err := decodeJSONBody(r, &req); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			httpError(w, "Invalid request body", http.StatusBadRequest)
 			return
 		}
 
 		newURL := req["url"]
 		if newURL == "" {
-			http.Error(w, "Missing url parameter in body", http.StatusBadRequest)
+			httpError(w, "Missing url parameter in body", http.StatusBadRequest)
 			return
 		}
 
 		if err := service.UpdateURL(id, newURL); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			httpError(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
 		writeJSONResponse(w, http.StatusOK, map[string]string{"status": "updated", "id": id, "url": newURL})
 	})))
+
+	mux.HandleFunc("/note", requireMethod(http.MethodPut, withRequiredID(func(w http.ResponseWriter, r *http.Request, id string) {
+		var update types.NoteUpdate
+		if err := decodeJSONBody(r, &update); err != nil {
+			httpError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := update.Validate(); err != nil {
+			httpError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := service.UpdateNote(id, update); err != nil {
+			if errors.Is(err, types.ErrNotFound) {
+				httpError(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			httpError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeJSONResponse(w, http.StatusOK, map[string]string{"status": "updated", "id": id})
+	})))
+
+	mux.HandleFunc("/chunks", requireMethod(http.MethodGet, withRequiredID(func(w http.ResponseWriter, _ *http.Request, id string) {
+		mapper, ok := service.(core.ChunkMapper)
+		if !ok {
+			httpError(w, "Chunk maps are not supported", http.StatusNotImplemented)
+			return
+		}
+		m, err := mapper.ChunkMap(id)
+		if err != nil {
+			if errors.Is(err, types.ErrNotFound) {
+				httpError(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			httpError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, m)
+	})))
+
+	mux.HandleFunc("/verify", requireMethod(http.MethodPost, withRequiredID(func(w http.ResponseWriter, _ *http.Request, id string) {
+		verifier, ok := service.(core.Verifier)
+		if !ok {
+			httpError(w, "Verification is not supported", http.StatusNotImplemented)
+			return
+		}
+		if err := verifier.Verify(id); err != nil {
+			if errors.Is(err, types.ErrNotFound) {
+				httpError(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			if errors.Is(err, types.ErrNotCompleted) {
+				httpError(w, err.Error(), http.StatusConflict)
+				return
+			}
+			httpError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSONResponse(w, http.StatusAccepted, map[string]string{"status": "verifying", "id": id})
+	})))
+
+	mux.HandleFunc("/speed-limit", requireMethods(speedLimitHandler(service), http.MethodGet, http.MethodPut))
+
+	mux.HandleFunc("/settings", requireMethods(settingsHandler(service), http.MethodGet, http.MethodPut))
+
+	mux.HandleFunc("/webhooks", requireMethods(webhooksHandler(service), http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete))
+
+	mux.HandleFunc("/upload", requireMethod(http.MethodPost, uploadHandler(defaultOutputDir, service)))
+
+	mux.HandleFunc("/shutdown", requireMethod(http.MethodPost, shutdownHandler(service)))
+
+	registerDebugRoutes(mux)
+	registerAPIDocRoutes(mux)
+	registerDashboardRoutes(mux)
 }
 
-func eventsHandler(service core.DownloadService) http.HandlerFunc {
+func eventsHandler(el *eventLog, service core.DownloadService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/event-stream")
 		w.Header().Set("Cache-Control", "no-cache")
 		w.Header().Set("Connection", "keep-alive")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
 
-		stream, cleanup, err := service.StreamEvents(r.Context())
-		if err != nil {
-			http.Error(w, "Failed to subscribe to events", http.StatusInternalServerError)
+		if err := el.start(service); err != nil {
+			httpError(w, "Failed to subscribe to events", http.StatusInternalServerError)
 			return
 		}
-		defer cleanup()
+		// Start from now, or after the last event a reconnecting client saw
+		cursor := el.last()
+		if v := r.Header.Get("Last-Event-ID"); v != "" {
+			if seq, err := strconv.ParseUint(v, 10, 64); err == nil {
+				cursor = seq
+			}
+		}
 
 		flusher, ok := w.(http.Flusher)
 		if !ok {
-			http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+			httpError(w, "Streaming unsupported", http.StatusInternalServerError)
 			return
 		}
 		flusher.Flush()
 
 		done := r.Context().Done()
 		for {
-			select {
-			case <-done:
+			evs, last, missed, changed := el.since(cursor, 0)
+			if missed {
+				// Events after the cursor were dropped, or Last-Event-ID
+				// predates a restart
+				_, _ = fmt.Fprintf(w, "id: %d\nevent: missed\ndata: {}\n\n", last)
+				flusher.Flush()
+				cursor = last
+				continue
+			}
+			if len(evs) == 0 {
+				select {
+				case <-done:
+					return
+				case <-el.stopped:
+					return
+				case <-changed:
+					continue
+				}
+			}
+
+			for _, ev := range evs {
+				_, _ = fmt.Fprintf(w, "id: %d\n", ev.Seq)
+				_, _ = fmt.Fprintf(w, "event: %s\n", ev.Type)
+				_, _ = fmt.Fprintf(w, "data: %s\n\n", ev.Data)
+			}
+			flusher.Flush()
+			cursor = evs[len(evs)-1].Seq
+		}
+	}
+}
+
+// Long-poll limits: how long a poll waits for events by default and at most,
+// and how many events one response carries
+const (
+	defaultPollWait = 25 * time.Second
+	maxPollWait     = 60 * time.Second
+	maxPollEvents   = 500
+)
+
+// eventsPollResponse is one long-poll batch. Seq is the since value for the
+// next poll; Missed means events after since were dropped from the log (or
+// the server restarted), so the client should refetch /list.
+type eventsPollResponse struct {
+	Seq    uint64        `json:"seq"`
+	Missed bool          `json:"missed,omitempty"`
+	Events []loggedEvent `json:"events"`
+}
+
+// eventsPollHandler serves the events after ?since= as one JSON batch, waiting
+// up to ?wait= seconds for the first one. Without since it returns the current
+// seq at once, for a client to start polling from.
+func eventsPollHandler(el *eventLog, service core.DownloadService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		wait, ok := pollWait(q)
+		if !ok {
+			httpError(w, "Invalid wait parameter", http.StatusBadRequest)
+			return
+		}
+
+		if err := el.start(service); err != nil {
+			httpError(w, "Failed to subscribe to events", http.StatusInternalServerError)
+			return
+		}
+
+		v := q.Get("since")
+		if v == "" {
+			writeJSONResponse(w, http.StatusOK, eventsPollResponse{Seq: el.last(), Events: []loggedEvent{}})
+			return
+		}
+		since, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			httpError(w, "Invalid since parameter", http.StatusBadRequest)
+			return
+		}
+
+		evs, seq, missed, ok := waitForEvents(r.Context(), el, since, wait)
+		if !ok {
+			return
+		}
+		if missed {
+			// Buffered events predate the client's resync, so send none
+			evs = []loggedEvent{}
+		}
+		writeJSONResponse(w, http.StatusOK, eventsPollResponse{Seq: seq, Missed: missed, Events: evs})
+	}
+}
+
+// pollWait returns how long a long poll waits, from ?wait= in seconds, and
+// false when it is not a valid number
+func pollWait(q url.Values) (time.Duration, bool) {
+	v := q.Get("wait")
+	if v == "" {
+		return defaultPollWait, true
+	}
+	secs, err := strconv.Atoi(v)
+	if err != nil || secs < 0 {
+		return 0, false
+	}
+	return min(time.Duration(secs)*time.Second, maxPollWait), true
+}
+
+// waitForEvents returns up to maxPollEvents events after since, waiting up to
+// wait for the first one, and the seq to poll from next. missed means events
+// after since were dropped, and ok is false when the client went away.
+func waitForEvents(ctx context.Context, el *eventLog, since uint64, wait time.Duration) (evs []loggedEvent, seq uint64, missed, ok bool) {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		evs, last, missed, changed := el.since(since, maxPollEvents)
+		if missed {
+			return evs, last, true, true
+		}
+		if len(evs) > 0 {
+			return evs, evs[len(evs)-1].Seq, false, true
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			return evs, since, false, true
+		case <-el.stopped:
+			return evs, since, false, true
+		case <-ctx.Done():
+			return nil, since, false, false
+		}
+	}
+}
+
+// WebSocket event stream: the subprotocol clients may ask for, how often the
+// server pings, and how long a write may take before the client is dropped
+const (
+	wsEventsProtocol = "surge.events"
+	wsPingInterval   = 30 * time.Second
+	wsWriteTimeout   = 10 * time.Second
+)
+
+// wsEventsHandler streams the same events as /events over WebSocket, one JSON
+// text message per event shaped like a /events/poll entry. With ?since= the
+// stream picks up after that seq; a message of type "missed" says events were
+// dropped first. The server pings every wsPingInterval and drops a client
+// that has not answered by the next one.
+func wsEventsHandler(el *eventLog, service core.DownloadService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := el.start(service); err != nil {
+			httpError(w, "Failed to subscribe to events", http.StatusInternalServerError)
+			return
+		}
+		cursor := el.last()
+		if v := r.URL.Query().Get("since"); v != "" {
+			since, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				httpError(w, "Invalid since parameter", http.StatusBadRequest)
 				return
-			case msg, ok := <-stream:
-				if !ok {
+			}
+			cursor = since
+		}
+
+		conn, err := websocket.Upgrade(w, r, wsEventsProtocol)
+		if err != nil {
+			utils.Debug("WebSocket upgrade failed: %v", err)
+			return
+		}
+		var lastPong atomic.Int64
+		conn.OnPong = func([]byte) { lastPong.Store(time.Now().UnixNano()) }
+
+		// Clients only send control frames; reading answers pings and
+		// notices the close
+		readDone := make(chan struct{})
+		go func() {
+			defer close(readDone)
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
 					return
 				}
+			}
+		}()
 
-				frames, err := events.EncodeSSEMessages(msg)
-				if err != nil {
-					utils.Debug("Error encoding SSE event: %v", err)
-					continue
+		ping := time.NewTicker(wsPingInterval)
+		defer ping.Stop()
+		var pingSent int64 // When the last ping went out, 0 before the first
+		for {
+			evs, last, missed, changed := el.since(cursor, maxPollEvents)
+			if missed {
+				data, _ := json.Marshal(loggedEvent{Seq: last, Type: "missed"})
+				if err := conn.WriteMessage(websocket.TextMessage, data, wsWriteTimeout); err != nil {
+					_ = conn.Close(websocket.CloseGoingAway)
+					return
 				}
-				if len(frames) == 0 {
-					continue
+				cursor = last
+				continue
+			}
+			for _, ev := range evs {
+				data, err := json.Marshal(ev)
+				if err == nil {
+					err = conn.WriteMessage(websocket.TextMessage, data, wsWriteTimeout)
 				}
+				if err != nil {
+					_ = conn.Close(websocket.CloseGoingAway)
+					return
+				}
+				cursor = ev.Seq
+			}
+			if len(evs) > 0 {
+				continue
+			}
 
-				for _, frame := range frames {
-					_, _ = fmt.Fprintf(w, "event: %s\n", frame.Event)
-					_, _ = fmt.Fprintf(w, "data: %s\n\n", frame.Data)
+			select {
+			case <-changed:
+			case <-ping.C:
+				if pingSent != 0 && lastPong.Load() < pingSent {
+					utils.Debug("WebSocket client stopped answering pings")
+					_ = conn.Close(websocket.CloseGoingAway)
+					return
 				}
-				flusher.Flush()
+				pingSent = time.Now().UnixNano()
+				if err := conn.WriteMessage(websocket.PingMessage, nil, wsWriteTimeout); err != nil {
+					_ = conn.Close(websocket.CloseGoingAway)
+					return
+				}
+			case <-readDone:
+				_ = conn.Close(websocket.CloseNormal)
+				return
+			case <-el.stopped:
+				_ = conn.Close(websocket.CloseGoingAway)
+				return
 			}
 		}
 	}
@@ -150,7 +689,7 @@ func requireMethods(next http.HandlerFunc, methods ...string) http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		if _, ok := allowed[r.Method]; !ok {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			httpError(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 		next(w, r)
@@ -161,13 +700,103 @@ func withRequiredID(next func(http.ResponseWriter, *http.Request, string)) http.
 	return func(w http.ResponseWriter, r *http.Request) {
 		id := r.URL.Query().Get("id")
 		if id == "" {
-			http.Error(w, "Missing id parameter", http.StatusBadRequest)
+			httpError(w, "Missing id parameter", http.StatusBadRequest)
 			return
 		}
 		next(w, r, id)
 	}
 }
 
+// withIDOrHost routes a request for one download (?id=) to byID and one for
+// every download from a host (?host=) to byHost
+func withIDOrHost(byID func(http.ResponseWriter, *http.Request, string), byHost func(http.ResponseWriter, *http.Request, string)) http.HandlerFunc {
+	single := withRequiredID(byID)
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		host := q.Get("host")
+		if host == "" {
+			single(w, r)
+			return
+		}
+		if q.Get("id") != "" {
+			httpError(w, "Use either id or host, not both", http.StatusBadRequest)
+			return
+		}
+		byHost(w, r, host)
+	}
+}
+
+// writeHostActionResponse reports a pause or resume of every download from
+// host. The IDs that were acted on are listed even when others failed.
+func writeHostActionResponse(w http.ResponseWriter, status, host string, ids []string, err error) {
+	if ids == nil {
+		ids = []string{}
+	}
+	resp := map[string]interface{}{"status": status, "host": host, "ids": ids}
+	if err != nil {
+		resp["error"] = err.Error()
+		writeJSONResponse(w, http.StatusInternalServerError, resp)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, resp)
+}
+
+// relocateHandler answers /move with dir, and /rename: move moves or
+// renames the download's file on this machine, and the new path is reported
+func relocateHandler(w http.ResponseWriter, service core.DownloadService, id string, move func(core.Relocator) (string, error)) {
+	relocator, ok := service.(core.Relocator)
+	if !ok {
+		httpError(w, "Moving files is not supported", http.StatusNotImplemented)
+		return
+	}
+	path, err := move(relocator)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrNotFound):
+			httpError(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, processing.ErrInvalidFilename):
+			httpError(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, processing.ErrNotRelocatable), errors.Is(err, processing.ErrWorkingFileMissing):
+			httpError(w, err.Error(), http.StatusConflict)
+		default:
+			httpError(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]string{"status": "moved", "id": id, "path": path})
+}
+
+// maxPageLimit caps the limit parameter of paged endpoints
+const maxPageLimit = 1000
+
+// nextCursorHeader carries the cursor of the following page; it is absent on
+// the last page
+const nextCursorHeader = "X-Next-Cursor"
+
+// withPage parses the optional cursor and limit query parameters. Without a
+// limit the whole result after cursor is returned, as before paging existed.
+func withPage(next func(http.ResponseWriter, *http.Request, string, int)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		limit := 0
+		if v := q.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				httpError(w, "Invalid limit parameter", http.StatusBadRequest)
+				return
+			}
+			limit = min(n, maxPageLimit)
+		}
+		next(w, r, q.Get("cursor"), limit)
+	}
+}
+
+func setNextCursor(w http.ResponseWriter, cursor string) {
+	if cursor != "" {
+		w.Header().Set(nextCursorHeader, cursor)
+	}
+}
+
 func writeJSONResponse(w http.ResponseWriter, status int, payload interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -176,6 +805,38 @@ func writeJSONResponse(w http.ResponseWriter, status int, payload interface{}) {
 	}
 }
 
+// writeProblem answers with an RFC 7807 problem+json body carrying code
+func writeProblem(w http.ResponseWriter, status int, code, detail string) {
+	w.Header().Set("Content-Type", core.ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(core.NewProblem(status, code, detail)); err != nil {
+		utils.Debug("Failed to encode response: %v", err)
+	}
+}
+
+// httpError is the API's http.Error: it answers with a problem whose code
+// follows from status
+func httpError(w http.ResponseWriter, detail string, status int) {
+	writeProblem(w, status, core.CodeForStatus(status), detail)
+}
+
+// apiError returns an error to be answered as the problem it describes
+func apiError(status int, code, detail string) error {
+	return &core.APIError{Problem: core.NewProblem(status, code, detail)}
+}
+
+// writeAPIError answers err as a problem: a *core.APIError as it describes,
+// anything else as an internal error
+func writeAPIError(w http.ResponseWriter, err error) {
+	var apiErr *core.APIError
+	if errors.As(err, &apiErr) {
+		writeProblem(w, apiErr.Status, apiErr.Code, apiErr.Detail)
+		return
+	}
+	httpError(w, err.Error(), http.StatusInternalServerError)
+}
+
 func decodeJSONBody(r *http.Request, dst interface{}) error {
 	defer func() {
 		_ = r.Body.Close()
@@ -3,6 +3,7 @@ package cmd
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -17,6 +18,7 @@ import (
 	"github.com/surge-downloader/surge/internal/engine/state"
 	"github.com/surge-downloader/surge/internal/engine/types"
 	"github.com/surge-downloader/surge/internal/processing"
+	"github.com/surge-downloader/surge/internal/trace"
 )
 
 func TestHandleDownload_PathResolution(t *testing.T) {
@@ -226,31 +228,31 @@ func TestHandleDownload_SkipApprovalUsesLifecycleEnqueue(t *testing.T) {
 	expectedFile := "from-extension.bin"
 
 	var addCalls int
-	GlobalLifecycle = processing.NewLifecycleManager(func(url, path, filename string, _ []string, headers map[string]string, explicit bool, totalSize int64, supportsRange bool) (string, error) {
+	GlobalLifecycle = processing.NewLifecycleManager(func(req *processing.DownloadRequest) (string, error) {
 		addCalls++
-		if url != probeServer.URL {
-			t.Fatalf("url = %q, want %q", url, probeServer.URL)
+		if req.URL != probeServer.URL {
+			t.Fatalf("url = %q, want %q", req.URL, probeServer.URL)
 		}
-		if path != tempDir {
-			t.Fatalf("path = %q, want %q", path, tempDir)
+		if req.Path != tempDir {
+			t.Fatalf("path = %q, want %q", req.Path, tempDir)
 		}
-		if filename != expectedFile {
-			t.Fatalf("filename = %q, want %q", filename, expectedFile)
+		if req.Filename != expectedFile {
+			t.Fatalf("filename = %q, want %q", req.Filename, expectedFile)
 		}
-		if !explicit {
+		if !req.IsExplicitCategory {
 			t.Fatal("expected explicit category flag to be preserved")
 		}
-		if totalSize != 7 {
-			t.Fatalf("totalSize = %d, want 7", totalSize)
+		if req.TotalSize != 7 {
+			t.Fatalf("totalSize = %d, want 7", req.TotalSize)
 		}
-		if !supportsRange {
+		if !req.SupportsRange {
 			t.Fatal("expected probe to preserve range support")
 		}
-		if headers["Authorization"] != "Bearer test" {
+		if req.Headers["Authorization"] != "Bearer test" {
 			t.Fatalf("headers were not forwarded to lifecycle addFunc")
 		}
 
-		surgePath := filepath.Join(path, filename) + types.IncompleteSuffix
+		surgePath := filepath.Join(req.Path, req.Filename) + types.IncompleteSuffix
 		if _, err := os.Stat(surgePath); err != nil {
 			t.Fatalf("expected pre-created working file before addFunc: %v", err)
 		}
@@ -293,3 +295,447 @@ func TestHandleDownload_SkipApprovalUsesLifecycleEnqueue(t *testing.T) {
 		t.Fatalf("response id = %q, want queued-id", resp["id"])
 	}
 }
+
+type pagedListService struct {
+	fakeRemoteDownloadService
+	statuses []types.DownloadStatus
+	history  []types.DownloadEntry
+}
+
+func (s *pagedListService) List() ([]types.DownloadStatus, error) { return s.statuses, nil }
+
+func (s *pagedListService) History() ([]types.DownloadEntry, error) { return s.history, nil }
+
+func TestListEndpoint_CursorPaging(t *testing.T) {
+	svc := &pagedListService{}
+	for i := 0; i < 5; i++ {
+		svc.statuses = append(svc.statuses, types.DownloadStatus{ID: fmt.Sprintf("id-%d", i), AddedAt: int64(i)})
+	}
+	const token = "paging-token"
+	baseURL := startAuthedTestServer(t, svc, token)
+
+	get := func(path string) (*http.Response, []types.DownloadStatus) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, baseURL+path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		var page []types.DownloadStatus
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+		}
+		return resp, page
+	}
+
+	var ids []string
+	path := "/list?limit=2"
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("paging did not terminate")
+		}
+		resp, page := get(path)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s: status %d", path, resp.StatusCode)
+		}
+		for _, s := range page {
+			ids = append(ids, s.ID)
+		}
+		next := resp.Header.Get(nextCursorHeader)
+		if next == "" {
+			break
+		}
+		path = "/list?limit=2&cursor=" + next
+	}
+	if strings.Join(ids, ",") != "id-0,id-1,id-2,id-3,id-4" {
+		t.Errorf("paged ids = %v", ids)
+	}
+
+	// Without a limit the whole list comes back, as before paging existed
+	if resp, page := get("/list"); len(page) != 5 || resp.Header.Get(nextCursorHeader) != "" {
+		t.Errorf("unpaged list = %d items, cursor %q", len(page), resp.Header.Get(nextCursorHeader))
+	}
+
+	for _, bad := range []string{"/list?limit=0", "/list?limit=x", "/list?cursor=bogus", "/history?cursor=bogus"} {
+		if resp, _ := get(bad); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("GET %s: status %d, want 400", bad, resp.StatusCode)
+		}
+	}
+}
+
+func TestTraceMiddleware(t *testing.T) {
+	var seen string
+	handler := traceMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = trace.FromContext(r.Context())
+		http.Error(w, "nope", http.StatusNotFound)
+	}))
+
+	t.Run("generates an id", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/download", nil))
+
+		got := rec.Header().Get(trace.Header)
+		if !trace.ValidID(got) {
+			t.Fatalf("%s = %q", trace.Header, got)
+		}
+		if seen != got {
+			t.Errorf("handler saw trace %q, response carried %q", seen, got)
+		}
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("status = %d", rec.Code)
+		}
+	})
+
+	t.Run("honors traceparent", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/list", nil)
+		req.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get(trace.Header); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("%s = %q", trace.Header, got)
+		}
+	})
+
+	t.Run("honors X-Trace-Id", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/list", nil)
+		req.Header.Set(trace.Header, "0AF7651916CD43DD8448EB211C80319C")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get(trace.Header); got != "0af7651916cd43dd8448eb211c80319c" {
+			t.Errorf("%s = %q", trace.Header, got)
+		}
+	})
+
+	t.Run("keeps streaming", func(t *testing.T) {
+		stream := traceMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := w.(http.Flusher); !ok {
+				t.Error("wrapped writer lost http.Flusher")
+			}
+		}))
+		stream.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/events", nil))
+	})
+}
+
+func TestListEndpoint_TagFilter(t *testing.T) {
+	svc := &pagedListService{
+		statuses: []types.DownloadStatus{
+			{ID: "a", Tags: []string{"work"}},
+			{ID: "b", Tags: []string{"media"}},
+		},
+		history: []types.DownloadEntry{
+			{ID: "h1", Tags: []string{"iso"}},
+			{ID: "h2"},
+		},
+	}
+	const token = "tag-token"
+	baseURL := startAuthedTestServer(t, svc, token)
+
+	get := func(path string, out any) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, baseURL+path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s: status %d", path, resp.StatusCode)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+	}
+
+	var list []types.DownloadStatus
+	get("/list?tag=work", &list)
+	if len(list) != 1 || list[0].ID != "a" || list[0].Tags[0] != "work" {
+		t.Errorf("/list?tag=work = %+v", list)
+	}
+
+	var history []types.DownloadEntry
+	get("/history?tag=iso", &history)
+	if len(history) != 1 || history[0].ID != "h1" {
+		t.Errorf("/history?tag=iso = %+v", history)
+	}
+}
+
+func TestHandleDownload_UnknownCategory(t *testing.T) {
+	origSettings := globalSettings
+	t.Cleanup(func() { globalSettings = origSettings })
+	globalSettings = config.DefaultSettings()
+	globalSettings.General.Categories = []config.Category{{Name: "Videos", Pattern: `\.mp4$`, Path: t.TempDir()}}
+
+	req := httptest.NewRequest(http.MethodPost, "/download", strings.NewReader(`{"url":"https://example.com/a.mp4","category":"music"}`))
+	rec := httptest.NewRecorder()
+	handleDownload(rec, req, t.TempDir(), nil)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if !strings.Contains(rec.Body.String(), "Unknown category") {
+		t.Errorf("body = %q", rec.Body.String())
+	}
+}
+
+type noteService struct {
+	fakeRemoteDownloadService
+	updates map[string]types.NoteUpdate
+}
+
+func (s *noteService) UpdateNote(id string, update types.NoteUpdate) error {
+	if id != "known" {
+		return types.ErrNotFound
+	}
+	s.updates[id] = update
+	return nil
+}
+
+func TestNoteEndpoint(t *testing.T) {
+	svc := &noteService{updates: make(map[string]types.NoteUpdate)}
+	const token = "note-token"
+	baseURL := startAuthedTestServer(t, svc, token)
+
+	put := func(id, body string) int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPut, baseURL+"/note?id="+id, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("PUT /note: %v", err)
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := put("known", `{"note":"hi","metadata":{"source":"forum"},"unset":["old"]}`); code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	got := svc.updates["known"]
+	if got.Note == nil || *got.Note != "hi" || got.Metadata["source"] != "forum" || len(got.Unset) != 1 {
+		t.Errorf("update = %+v", got)
+	}
+
+	if code := put("missing", `{"note":"hi"}`); code != http.StatusNotFound {
+		t.Errorf("unknown id: status = %d, want 404", code)
+	}
+	if code := put("known", `{"metadata":{"":"x"}}`); code != http.StatusBadRequest {
+		t.Errorf("blank key: status = %d, want 400", code)
+	}
+}
+
+func TestHistoryExportEndpoint_RejectsBadParameters(t *testing.T) {
+	const token = "export-token"
+	baseURL := startAuthedTestServer(t, &pagedListService{}, token)
+
+	for _, query := range []string{"format=xml", "since=yesterday", "since=2026-02-01&until=2026-01-01"} {
+		req, _ := http.NewRequest(http.MethodGet, baseURL+"/history/export?"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s: %v", query, err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, resp.StatusCode)
+		}
+	}
+}
+
+func TestHandleDownload_SkipsArchivedURL(t *testing.T) {
+	setupIsolatedCmdState(t)
+	GlobalPool = download.NewWorkerPool(make(chan any, 10), 1)
+
+	origSettings := globalSettings
+	origLifecycle := GlobalLifecycle
+	t.Cleanup(func() {
+		globalSettings = origSettings
+		GlobalLifecycle = origLifecycle
+		GlobalPool = nil
+	})
+	globalSettings = config.DefaultSettings()
+	GlobalLifecycle = processing.NewLifecycleManager(func(req *processing.DownloadRequest) (string, error) {
+		t.Errorf("archived URL %s was queued", req.URL)
+		return "", nil
+	}, nil)
+
+	const archivedURL = "https://example.com/feed/episode-1.mp3"
+	if err := state.ArchiveURL(archivedURL); err != nil {
+		t.Fatalf("ArchiveURL: %v", err)
+	}
+
+	const token = "archive-token"
+	baseURL := startAuthedTestServer(t, &fakeRemoteDownloadService{}, token)
+
+	err := sendRequestToServer(DownloadRequest{URL: archivedURL, DownloadArchive: true}, baseURL, token)
+	if !errors.Is(err, errAlreadyDownloaded) {
+		t.Fatalf("per-request archive: err = %v, want errAlreadyDownloaded", err)
+	}
+
+	// The setting turns the check on for every request
+	settings := config.DefaultSettings()
+	settings.General.DownloadArchive = true
+	if err := GlobalLifecycle.SaveSettings(settings); err != nil {
+		t.Fatalf("SaveSettings: %v", err)
+	}
+	globalSettings = settings
+	err = sendRequestToServer(DownloadRequest{URL: archivedURL + "/"}, baseURL, token)
+	if !errors.Is(err, errAlreadyDownloaded) {
+		t.Fatalf("archive setting: err = %v, want errAlreadyDownloaded", err)
+	}
+}
+
+type hostPauseService struct {
+	pagedListService
+	paused  []string
+	resumed []string
+}
+
+func (s *hostPauseService) Pause(id string) error {
+	s.paused = append(s.paused, id)
+	return nil
+}
+
+func (s *hostPauseService) ResumeBatch(ids []string) []error {
+	s.resumed = append(s.resumed, ids...)
+	return make([]error, len(ids))
+}
+
+func TestPauseResumeEndpoints_HostFilter(t *testing.T) {
+	svc := &hostPauseService{pagedListService: pagedListService{statuses: []types.DownloadStatus{
+		{ID: "a", URL: "https://cdn.example.com/a.iso", Status: "downloading"},
+		{ID: "b", URL: "https://cdn.example.com:8443/b.iso", Status: "paused"},
+		{ID: "c", URL: "https://mirror.example.org/c.iso", Status: "downloading"},
+		{ID: "d", URL: "https://CDN.example.com/d.iso", Status: "downloading"},
+	}}}
+	const token = "host-token"
+	baseURL := startAuthedTestServer(t, svc, token)
+
+	post := func(path string) (int, map[string]any) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, baseURL+path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST %s: %v", path, err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		var body map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body
+	}
+
+	if code, body := post("/pause?host=cdn.example.com"); code != http.StatusOK || body["host"] != "cdn.example.com" {
+		t.Fatalf("pause by host: status %d, body %v", code, body)
+	}
+	if strings.Join(svc.paused, ",") != "a,d" {
+		t.Errorf("paused = %v, want a,d", svc.paused)
+	}
+
+	if code, _ := post("/resume?host=cdn.example.com"); code != http.StatusOK {
+		t.Fatalf("resume by host: status %d", code)
+	}
+	if strings.Join(svc.resumed, ",") != "b" {
+		t.Errorf("resumed = %v, want b", svc.resumed)
+	}
+
+	if code, _ := post("/pause?host=cdn.example.com&id=a"); code != http.StatusBadRequest {
+		t.Errorf("id and host: status %d, want 400", code)
+	}
+	if code, _ := post("/pause"); code != http.StatusBadRequest {
+		t.Errorf("neither id nor host: status %d, want 400", code)
+	}
+}
+
+type chunkMapService struct {
+	fakeRemoteDownloadService
+}
+
+func (s *chunkMapService) ChunkMap(id string) (*types.ChunkMap, error) {
+	if id != "known" {
+		return nil, types.ErrNotFound
+	}
+	return &types.ChunkMap{ID: id, TotalSize: 2048, ChunkSize: 1024, Chunks: "21",
+		Workers: []types.WorkerStatus{{Worker: 0, Start: 1024, Offset: 1500, End: 2048, Mirror: "https://example.com/a"}}}, nil
+}
+
+func TestChunksEndpoint(t *testing.T) {
+	mux := http.NewServeMux()
+	registerHTTPRoutes(mux, 0, "", &chunkMapService{})
+
+	get := func(query string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/chunks?"+query, nil))
+		return rec
+	}
+
+	rec := get("id=known")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	var m types.ChunkMap
+	if err := json.Unmarshal(rec.Body.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Chunks != "21" || len(m.Workers) != 1 || m.Workers[0].Offset != 1500 {
+		t.Errorf("map = %+v", m)
+	}
+
+	if rec := get("id=missing"); rec.Code != http.StatusNotFound {
+		t.Errorf("missing download = %d, want 404", rec.Code)
+	}
+	if rec := get(""); rec.Code != http.StatusBadRequest {
+		t.Errorf("no id = %d, want 400", rec.Code)
+	}
+
+	plain := http.NewServeMux()
+	registerHTTPRoutes(plain, 0, "", &fakeRemoteDownloadService{})
+	rec = httptest.NewRecorder()
+	plain.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/chunks?id=known", nil))
+	if rec.Code != http.StatusNotImplemented {
+		t.Errorf("without chunk maps = %d, want 501", rec.Code)
+	}
+}
+
+func TestHandleDownload_ProblemResponses(t *testing.T) {
+	cases := []struct {
+		method, target, body string
+		status               int
+		code                 string
+	}{
+		{http.MethodPost, "/download", `{"url":"https://example.com/a.iso","path":"../etc"}`, http.StatusBadRequest, core.CodePathForbidden},
+		{http.MethodPost, "/download", `{"url":"https://example.com/a.iso","filename":"a/b.iso"}`, http.StatusBadRequest, core.CodePathForbidden},
+		{http.MethodPost, "/download", `{}`, http.StatusBadRequest, core.CodeBadRequest},
+		{http.MethodGet, "/download?id=missing", "", http.StatusNotFound, core.CodeNotFound},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		handleDownload(rec, httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body)), t.TempDir(), &missingStatusService{})
+
+		if rec.Code != tc.status || rec.Header().Get("Content-Type") != core.ProblemContentType {
+			t.Fatalf("%s %s = %d %q, want %d problem", tc.method, tc.target, rec.Code, rec.Header().Get("Content-Type"), tc.status)
+		}
+		var p core.Problem
+		if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+			t.Fatalf("decode problem: %v", err)
+		}
+		if p.Code != tc.code || p.Status != tc.status || p.Title != http.StatusText(tc.status) || p.Detail == "" {
+			t.Errorf("%s %s problem = %+v, want code %s", tc.method, tc.target, p, tc.code)
+		}
+	}
+}
+
+type missingStatusService struct {
+	fakeRemoteDownloadService
+}
+
+func (s *missingStatusService) GetStatus(id string) (*types.DownloadStatus, error) {
+	return nil, errors.New("download not found")
+}
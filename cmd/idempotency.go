@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/core"
+)
+
+// Idempotency keys let a client retry POST /download after a network blip
+// without queueing the URL twice: the key comes as a header or as the
+// idempotency_key field, and a repeated key gets the first response again.
+const (
+	idempotencyKeyHeader   = "Idempotency-Key"
+	idempotentReplayHeader = "Idempotent-Replayed"
+	idempotencyTTL         = 24 * time.Hour
+	maxIdempotencyKeys     = 10000
+	maxIdempotencyKeyLen   = 255
+)
+
+// idempotentResponse is the response first given for a key. done is closed
+// once it is recorded, so a retry racing the original waits for it.
+type idempotentResponse struct {
+	done        chan struct{}
+	fingerprint [sha256.Size]byte // Of the request body, to catch reused keys
+	expires     time.Time
+	status      int
+	contentType string
+	body        []byte
+	failed      bool // Not recorded: the original failed and may be retried
+}
+
+type idempotencyCache struct {
+	mu      sync.Mutex
+	entries map[string]*idempotentResponse
+	now     func() time.Time
+}
+
+func newIdempotencyCache() *idempotencyCache {
+	return &idempotencyCache{entries: make(map[string]*idempotentResponse), now: time.Now}
+}
+
+// claim returns the entry for key and whether the caller created it and so
+// must complete it. Expired entries are dropped first, and the oldest ones
+// when the cache is full.
+func (c *idempotencyCache) claim(key string, fingerprint [sha256.Size]byte) (*idempotentResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if e, ok := c.entries[key]; ok && now.Before(e.expires) {
+		return e, false
+	}
+	if len(c.entries) >= maxIdempotencyKeys {
+		var oldestKey string
+		var oldest time.Time
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			} else if oldestKey == "" || e.expires.Before(oldest) {
+				oldestKey, oldest = k, e.expires
+			}
+		}
+		if len(c.entries) >= maxIdempotencyKeys {
+			delete(c.entries, oldestKey)
+		}
+	}
+	e := &idempotentResponse{done: make(chan struct{}), fingerprint: fingerprint, expires: now.Add(idempotencyTTL)}
+	c.entries[key] = e
+	return e, true
+}
+
+// forget drops key's entry so the request can be retried
+func (c *idempotencyCache) forget(key string, e *idempotentResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries[key] == e {
+		delete(c.entries, key)
+	}
+}
+
+// responseCapture passes a response through while keeping a copy
+type responseCapture struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (c *responseCapture) WriteHeader(code int) {
+	c.status = code
+	c.ResponseWriter.WriteHeader(code)
+}
+
+func (c *responseCapture) Write(p []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.body.Write(p)
+	return c.ResponseWriter.Write(p)
+}
+
+// withIdempotency replays the recorded response for POSTs repeating an
+// idempotency key. Responses to server errors are not kept, so those can be
+// retried; reusing a key for a different body is rejected with 422.
+func withIdempotency(cache *idempotencyCache, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			next(w, r)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		_ = r.Body.Close()
+		if err != nil {
+			httpError(w, "Failed to read request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" {
+			var fields struct {
+				IdempotencyKey string `json:"idempotency_key"`
+			}
+			_ = json.Unmarshal(body, &fields)
+			key = fields.IdempotencyKey
+		}
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			httpError(w, "Idempotency key too long", http.StatusBadRequest)
+			return
+		}
+
+		fingerprint := sha256.Sum256(body)
+		for {
+			entry, owner := cache.claim(key, fingerprint)
+			if owner {
+				capture := &responseCapture{ResponseWriter: w}
+				defer func() {
+					if capture.status == 0 || capture.status >= http.StatusInternalServerError {
+						entry.failed = true
+						cache.forget(key, entry)
+					} else {
+						entry.status = capture.status
+						entry.contentType = capture.Header().Get("Content-Type")
+						entry.body = capture.body.Bytes()
+					}
+					close(entry.done) // Even if next panics, so waiters don't hang
+				}()
+				next(capture, r)
+				return
+			}
+
+			select {
+			case <-entry.done:
+			case <-r.Context().Done():
+				return
+			}
+			if entry.failed {
+				continue // The original failed; this retry goes ahead
+			}
+			if entry.fingerprint != fingerprint {
+				writeProblem(w, http.StatusUnprocessableEntity, core.CodeIdempotencyReuse, "Idempotency key was used for a different request")
+				return
+			}
+			w.Header().Set("Content-Type", entry.contentType)
+			w.Header().Set(idempotentReplayHeader, "true")
+			w.WriteHeader(entry.status)
+			_, _ = w.Write(entry.body)
+			return
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDownloadEndpoint_IdempotencyKey(t *testing.T) {
+	isolateDownloadGlobals(t)
+
+	service := &fakeRemoteDownloadService{}
+	mux := http.NewServeMux()
+	registerHTTPRoutes(mux, 0, t.TempDir(), service)
+	post := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/download", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(idempotencyKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	const body = `{"url": "https://example.com/a.iso", "skip_approval": true}`
+
+	first := post("key-1", body)
+	if first.Code != http.StatusOK || service.addCalls != 1 {
+		t.Fatalf("first = %d %s, %d adds", first.Code, first.Body.String(), service.addCalls)
+	}
+	retry := post("key-1", body)
+	if retry.Code != http.StatusOK || retry.Body.String() != first.Body.String() || retry.Header().Get(idempotentReplayHeader) != "true" {
+		t.Fatalf("retry = %d %s %v", retry.Code, retry.Body.String(), retry.Header())
+	}
+	if service.addCalls != 1 {
+		t.Fatalf("retry queued again: %d adds", service.addCalls)
+	}
+
+	if rec := post("key-1", `{"url": "https://example.com/b.iso", "skip_approval": true}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reused key = %d, want 422", rec.Code)
+	}
+
+	// The key may come in the body instead
+	fieldBody := `{"url": "https://example.com/c.iso", "skip_approval": true, "idempotency_key": "key-2"}`
+	post("", fieldBody)
+	post("", fieldBody)
+	if service.addCalls != 2 {
+		t.Errorf("body key: %d adds, want 2", service.addCalls)
+	}
+
+	// Without a key every request is queued
+	post("", body)
+	post("", body)
+	if service.addCalls != 4 {
+		t.Errorf("no key: %d adds, want 4", service.addCalls)
+	}
+}
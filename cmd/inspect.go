@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect",
+	Short: "Read-only look at the state database",
+	Long: `Inspect the state database for support and debugging. The database is opened
+read-only, so these commands are safe to run while the daemon is using it.`,
+}
+
+var inspectDBCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Show database size, schema version and row counts",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		in := mustOpenInspector(cmd)
+		defer func() { _ = in.Close() }()
+
+		stats, err := in.Stats()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if globalJSON {
+			printInspectJSON(stats)
+			return
+		}
+
+		fmt.Printf("Path:       %s\n", stats.Path)
+		fmt.Printf("Schema:     v%d\n", stats.SchemaVersion)
+		fmt.Printf("Size:       %s (%s free)\n", utils.ConvertBytesToHumanReadable(stats.SizeBytes), utils.ConvertBytesToHumanReadable(stats.FreeBytes))
+		fmt.Printf("WAL:        %s\n", utils.ConvertBytesToHumanReadable(stats.WALBytes))
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "\nTABLE\tROWS")
+		for _, name := range sortedKeys(stats.Tables) {
+			_, _ = fmt.Fprintf(w, "%s\t%d\n", name, stats.Tables[name])
+		}
+		if len(stats.Downloads) > 0 {
+			_, _ = fmt.Fprintln(w, "\nSTATUS\tDOWNLOADS")
+			for _, status := range sortedKeys(stats.Downloads) {
+				_, _ = fmt.Fprintf(w, "%s\t%d\n", status, stats.Downloads[status])
+			}
+		}
+		_ = w.Flush()
+	},
+}
+
+var inspectStateCmd = &cobra.Command{
+	Use:               "state <id>",
+	Short:             "Dump the stored state of a download",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeDownloadIDs(false),
+	Run: func(cmd *cobra.Command, args []string) {
+		in := mustOpenInspector(cmd)
+		defer func() { _ = in.Close() }()
+
+		dump, err := inspectResolve(in, args[0], in.Dump)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if globalJSON {
+			printInspectJSON(dump)
+			return
+		}
+
+		for _, col := range sortedKeys(dump.Download) {
+			fmt.Printf("%-18s %s\n", col+":", formatColumn(dump.Download[col]))
+		}
+		fmt.Printf("\nTasks: %d\n", len(dump.Tasks))
+		for _, task := range dump.Tasks {
+			fmt.Printf("  offset %v length %v\n", task["offset"], task["length"])
+		}
+	},
+}
+
+var inspectBitmapCmd = &cobra.Command{
+	Use:               "bitmap <id>",
+	Short:             "Draw the saved chunk bitmap of a download",
+	Long:              `Draw the chunk bitmap saved at the last pause: '#' is a completed chunk, '>' was in progress and '.' is pending.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeDownloadIDs(false),
+	Run: func(cmd *cobra.Command, args []string) {
+		in := mustOpenInspector(cmd)
+		defer func() { _ = in.Close() }()
+
+		chunks, err := inspectResolve(in, args[0], in.Bitmap)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if globalJSON {
+			printInspectJSON(chunks)
+			return
+		}
+
+		if len(chunks.Chunks) == 0 {
+			fmt.Println("No chunk bitmap saved for this download.")
+			return
+		}
+		width, _ := cmd.Flags().GetInt("width")
+		fmt.Printf("%d chunks of %s: %d done, %d in progress, %d pending\n",
+			len(chunks.Chunks), utils.ConvertBytesToHumanReadable(chunks.ChunkSize),
+			chunks.Count(types.ChunkCompleted), chunks.Count(types.ChunkDownloading), chunks.Count(types.ChunkPending))
+		fmt.Print(renderChunkMap(chunks, width))
+	},
+}
+
+// renderChunkMap draws width chunks per line, each line prefixed with the
+// index of its first chunk
+func renderChunkMap(m *state.ChunkMap, width int) string {
+	if width <= 0 {
+		width = 64
+	}
+	digits := len(fmt.Sprint(len(m.Chunks)))
+	var b strings.Builder
+	for start := 0; start < len(m.Chunks); start += width {
+		end := min(start+width, len(m.Chunks))
+		fmt.Fprintf(&b, "%*d ", digits, start)
+		for _, c := range m.Chunks[start:end] {
+			switch c {
+			case types.ChunkCompleted:
+				b.WriteByte('#')
+			case types.ChunkDownloading:
+				b.WriteByte('>')
+			default:
+				b.WriteByte('.')
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// inspectResolve expands a partial id before calling fn with it
+func inspectResolve[T any](in *state.Inspector, partialID string, fn func(string) (T, error)) (T, error) {
+	id, err := in.ResolveID(partialID)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return fn(id)
+}
+
+// mustOpenInspector opens --db, or the configured SQLite state database
+func mustOpenInspector(cmd *cobra.Command) *state.Inspector {
+	path, _ := cmd.Flags().GetString("db")
+	if path == "" {
+		path = filepath.Join(config.GetStateDir(), "surge.db")
+		if dsn := getSettings().General.StateStore; dsn != "" {
+			store, err := state.NewStore(dsn)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid state_store: %v\n", err)
+				os.Exit(1)
+			}
+			if s, ok := store.(*state.SQLiteStore); ok {
+				path = s.Path
+			}
+		}
+	}
+
+	in, err := state.OpenInspector(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return in
+}
+
+func printInspectJSON(v any) {
+	data, _ := json.MarshalIndent(v, "", "  ")
+	fmt.Println(string(data))
+}
+
+func formatColumn(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return fmt.Sprintf("<%d bytes>", len(v))
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func init() {
+	rootCmd.AddCommand(inspectCmd)
+	inspectCmd.AddCommand(inspectDBCmd, inspectStateCmd, inspectBitmapCmd)
+	inspectCmd.PersistentFlags().String("db", "", "Path to the SQLite state database (default: the configured one)")
+	inspectBitmapCmd.Flags().Int("width", 64, "Chunks per line")
+}
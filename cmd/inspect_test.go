@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestRenderChunkMap(t *testing.T) {
+	m := &state.ChunkMap{Chunks: []types.ChunkStatus{
+		types.ChunkCompleted, types.ChunkCompleted, types.ChunkDownloading,
+		types.ChunkPending, types.ChunkPending, types.ChunkCompleted,
+		types.ChunkPending, types.ChunkPending, types.ChunkPending,
+		types.ChunkPending, types.ChunkCompleted,
+	}}
+
+	got := renderChunkMap(m, 4)
+	want := " 0 ##>.\n 4 .#..\n 8 ..#\n"
+	if got != want {
+		t.Errorf("renderChunkMap =\n%q\nwant\n%q", got, want)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
 	"text/tabwriter"
 	"time"
 
@@ -16,16 +17,18 @@ import (
 )
 
 var lsCmd = &cobra.Command{
-	Use:     "ls [id]",
-	Aliases: []string{"l"},
-	Short:   "List downloads",
-	Long:    `List all downloads from the running server or database. Optionally show details for a specific download by ID.`,
-	Args:    cobra.MaximumNArgs(1),
+	Use:               "ls [id]",
+	Aliases:           []string{"l"},
+	Short:             "List downloads",
+	Long:              `List all downloads from the running server or database. Optionally show details for a specific download by ID.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeDownloadIDs(false),
 	Run: func(cmd *cobra.Command, args []string) {
 		mustInitializeGlobalState()
 
-		jsonOutput, _ := cmd.Flags().GetBool("json")
+		jsonOutput := globalJSON
 		watch, _ := cmd.Flags().GetBool("watch")
+		tag, _ := cmd.Flags().GetString("tag")
 
 		baseURL, token, err := resolveAPIConnection(false)
 		if err != nil {
@@ -45,28 +48,30 @@ var lsCmd = &cobra.Command{
 			for {
 				// Clear screen first for watch mode
 				fmt.Print("\033[H\033[2J")
-				printDownloads(jsonOutput, baseURL, token, strictRemote)
+				printDownloads(jsonOutput, baseURL, token, strictRemote, tag)
 				time.Sleep(1 * time.Second)
 			}
 		} else {
-			printDownloads(jsonOutput, baseURL, token, strictRemote)
+			printDownloads(jsonOutput, baseURL, token, strictRemote, tag)
 		}
 	},
 }
 
 // downloadInfo is a unified structure for display
 type downloadInfo struct {
-	ID         string  `json:"id"`
-	URL        string  `json:"url,omitempty"`
-	Filename   string  `json:"filename"`
-	Status     string  `json:"status"`
-	Progress   float64 `json:"progress"`
-	TotalSize  int64   `json:"total_size"`
-	Downloaded int64   `json:"downloaded"`
-	Speed      float64 `json:"speed,omitempty"`
+	ID         string   `json:"id"`
+	URL        string   `json:"url,omitempty"`
+	Filename   string   `json:"filename"`
+	Status     string   `json:"status"`
+	Progress   float64  `json:"progress"`
+	TotalSize  int64    `json:"total_size"`
+	Downloaded int64    `json:"downloaded"`
+	Speed      float64  `json:"speed,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
 }
 
-func printDownloads(jsonOutput bool, baseURL string, token string, strictRemote bool) {
+// printDownloads lists downloads, only those labeled tag when it is set
+func printDownloads(jsonOutput bool, baseURL string, token string, strictRemote bool, tag string) {
 	var downloads []downloadInfo
 
 	// Try to get from running server first
@@ -87,6 +92,7 @@ func printDownloads(jsonOutput bool, baseURL string, token string, strictRemote
 					TotalSize:  s.TotalSize,
 					Downloaded: s.Downloaded,
 					Speed:      s.Speed,
+					Tags:       s.Tags,
 				})
 			}
 		}
@@ -112,10 +118,21 @@ func printDownloads(jsonOutput bool, baseURL string, token string, strictRemote
 				Progress:   progress,
 				TotalSize:  d.TotalSize,
 				Downloaded: d.Downloaded,
+				Tags:       d.Tags,
 			})
 		}
 	}
 
+	if tag != "" {
+		var tagged []downloadInfo
+		for _, d := range downloads {
+			if utils.HasTag(d.Tags, tag) {
+				tagged = append(tagged, d)
+			}
+		}
+		downloads = tagged
+	}
+
 	if len(downloads) == 0 {
 		if !jsonOutput {
 			fmt.Println("No downloads found.")
@@ -133,8 +150,8 @@ func printDownloads(jsonOutput bool, baseURL string, token string, strictRemote
 
 	// Table output
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	_, _ = fmt.Fprintln(w, "ID\tFILENAME\tSTATUS\tPROGRESS\tSPEED\tSIZE")
-	_, _ = fmt.Fprintln(w, "--\t--------\t------\t--------\t-----\t----")
+	_, _ = fmt.Fprintln(w, "ID\tFILENAME\tSTATUS\tPROGRESS\tSPEED\tSIZE\tTAGS")
+	_, _ = fmt.Fprintln(w, "--\t--------\t------\t--------\t-----\t----\t----")
 
 	for _, d := range downloads {
 		progress := fmt.Sprintf("%.1f%%", d.Progress)
@@ -160,7 +177,7 @@ func printDownloads(jsonOutput bool, baseURL string, token string, strictRemote
 			filename = filename[:22] + "..."
 		}
 
-		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", id, filename, d.Status, progress, speed, size)
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", id, filename, d.Status, progress, speed, size, strings.Join(d.Tags, ","))
 	}
 	_ = w.Flush()
 }
@@ -244,6 +261,14 @@ func showDownloadDetails(partialID string, jsonOutput bool, baseURL string, toke
 		TotalSize:  found.TotalSize,
 		Downloaded: found.Downloaded,
 		Progress:   progress,
+		Tags:       found.Tags,
+		Category:   found.Category,
+		Note:       found.Note,
+		Metadata:   found.Metadata,
+		Response:   found.Response,
+	}
+	if status.Errors, err = state.LoadDownloadFailures(found.ID); err != nil {
+		utils.Debug("Failed to load error history for %s: %v", found.ID, err)
 	}
 	printDownloadDetail(status, jsonOutput)
 }
@@ -264,13 +289,69 @@ func printDownloadDetail(d types.DownloadStatus, jsonOutput bool) {
 	if d.Speed > 0 {
 		fmt.Printf("Speed:      %.1f MB/s\n", d.Speed)
 	}
+	if len(d.Tags) > 0 {
+		fmt.Printf("Tags:       %s\n", strings.Join(d.Tags, ", "))
+	}
+	if d.Category != "" {
+		fmt.Printf("Category:   %s\n", d.Category)
+	}
+	if d.Note != "" {
+		fmt.Printf("Note:       %s\n", d.Note)
+	}
+	if len(d.Metadata) > 0 {
+		fmt.Println("Metadata:")
+		for _, k := range sortedKeys(d.Metadata) {
+			fmt.Printf("  %s: %s\n", k, d.Metadata[k])
+		}
+	}
+	if r := d.Response; r != nil {
+		fmt.Println("Response:")
+		for _, field := range []struct{ name, value string }{
+			{"Content-Type", r.ContentType},
+			{"Final URL", r.FinalURL},
+			{"Server", r.Server},
+			{"ETag", r.ETag},
+			{"Last-Modified", r.LastModified},
+		} {
+			if field.value != "" {
+				fmt.Printf("  %s: %s\n", field.name, field.value)
+			}
+		}
+	}
+	if len(d.Errors) > 0 {
+		fmt.Printf("Errors:     %d recorded\n", len(d.Errors))
+		for _, f := range d.Errors[max(0, len(d.Errors)-lsErrorHistoryLines):] {
+			fmt.Printf("  %s\n", formatFailure(f))
+		}
+	}
 	if d.Error != "" {
 		fmt.Printf("Error:      %s\n", d.Error)
 	}
 }
 
+// lsErrorHistoryLines is how many of the latest failures surge ls <id> prints
+const lsErrorHistoryLines = 10
+
+// formatFailure describes one entry of a download's error history on a line
+func formatFailure(f types.DownloadFailure) string {
+	line := time.Unix(f.At, 0).Format("2006-01-02 15:04:05") + " " + f.Kind
+	if f.Status != 0 {
+		line += fmt.Sprintf(" (HTTP %d)", f.Status)
+	}
+	if f.Kind != types.FailureFatal {
+		line += fmt.Sprintf(" at offset %d", f.Offset)
+	}
+	if f.Mirror != "" {
+		line += " from " + f.Mirror
+	}
+	if f.Message != "" {
+		line += ": " + f.Message
+	}
+	return line
+}
+
 func init() {
 	rootCmd.AddCommand(lsCmd)
-	lsCmd.Flags().Bool("json", false, "Output in JSON format")
 	lsCmd.Flags().Bool("watch", false, "Watch mode: refresh every second")
+	lsCmd.Flags().String("tag", "", "Only list downloads with this tag")
 }
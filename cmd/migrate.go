@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/core"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export downloads and settings to move them to another machine",
+	Long: `Write the pending and completed downloads of this machine, with their
+per-download overrides and the settings, to a JSON file that "surge import"
+reads on another machine. Progress is not carried: pending downloads start over
+there. Request headers, which may be cookies or tokens, are left out, and so
+are the settings tied to this machine: the state store, the API server and
+distributed peers.`,
+	Example: `  surge export -o surge-export.json
+  surge export --no-settings > downloads.json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		output, _ := cmd.Flags().GetString("output")
+		noSettings, _ := cmd.Flags().GetBool("no-settings")
+
+		mustInitializeGlobalState()
+
+		var settings *config.Settings
+		if !noSettings {
+			settings = getSettings()
+		}
+
+		var w io.Writer = os.Stdout
+		if output != "" {
+			f, err := os.Create(output)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			defer func() {
+				if err := f.Close(); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				}
+			}()
+			w = f
+		}
+
+		if err := core.ExportBundle(w, settings, time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error exporting: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import <FILE>",
+	Short: "Import downloads and settings exported on another machine",
+	Long: `Add the downloads of a "surge export" file to this machine, and apply its
+settings unless --no-settings is given. --map OLD=NEW (repeatable) moves the
+downloads, categories, watch folders and default directory under OLD to NEW.
+
+Completed downloads join the history, their files expected at the new path.
+Queued downloads start over when Surge next starts; paused and failed ones
+wait for "surge resume". Downloads already known here are skipped. Quit Surge
+before importing.`,
+	Example: `  surge import surge-export.json --map /home/alice/Downloads=/data/downloads
+  surge import downloads.json --no-settings`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		mappings, _ := cmd.Flags().GetStringArray("map")
+		noSettings, _ := cmd.Flags().GetBool("no-settings")
+
+		dirs, err := core.ParseDirMap(mappings)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		mustInitializeGlobalState()
+		if readActivePort() > 0 || readActiveSocket() != "" {
+			fmt.Fprintln(os.Stderr, "Error: Surge is running; quit it before importing, and it picks the downloads up when it starts")
+			os.Exit(1)
+		}
+
+		f, err := os.Open(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		bundle, err := core.ReadBundle(f)
+		_ = f.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		res, err := core.ImportBundle(bundle, dirs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error importing downloads: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Imported %d pending and %d completed downloads", res.Pending, res.Completed)
+		if res.Skipped > 0 {
+			fmt.Printf(", skipped %d already here", res.Skipped)
+		}
+		fmt.Println(".")
+
+		if noSettings || bundle.Settings == nil {
+			return
+		}
+		if err := config.SaveSettings(core.ImportSettings(bundle.Settings, getSettings(), dirs)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving settings: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("Imported settings.")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().StringP("output", "o", "", "Write to this file instead of stdout")
+	exportCmd.Flags().Bool("no-settings", false, "Export the downloads only")
+
+	rootCmd.AddCommand(importCmd)
+	importCmd.Flags().StringArray("map", nil, "Move what was under directory OLD to NEW, as OLD=NEW (repeatable)")
+	importCmd.Flags().Bool("no-settings", false, "Import the downloads only, keeping the settings of this machine")
+}
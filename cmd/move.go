@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/processing"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+var moveCmd = &cobra.Command{
+	Use:   "move <ID> <POSITION|DIR>",
+	Short: "Move a waiting download in the queue, or a download's file to another directory",
+	Long: `Move a queued or paused download to POSITION in the queue, counted from 1.
+POSITION may also be "top" or "bottom", or "up" or "down" to move it one
+place. The order is kept across restarts.
+
+Given a directory instead, move the file of a finished or paused download
+into DIR, renaming it if the name is taken there. A paused download's
+partial file moves with it and resumes where it left off. Files are moved
+across drives by copying. DIR is anything with a path separator, starting
+with ~ or ., or an existing directory; write ./1 for a directory named 1.
+Works whether Surge is running or not.`,
+	Example: `  surge move a1b2 1
+  surge move a1b2 bottom
+  surge move a1b2 up
+  surge move a1b2 ~/Videos
+  surge move a1b2 /mnt/archive/isos`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeDownloadIDs(false, "queued", "paused", "completed"),
+	Run: func(cmd *cobra.Command, args []string) {
+		mustInitializeGlobalState()
+
+		if isMoveDir(args[1]) {
+			relocateDownload(args[0], args[1])
+			return
+		}
+		query, err := queueMoveQuery(args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v (or a directory; write ./%s for one here)\n", err, args[1])
+			os.Exit(1)
+		}
+
+		ExecuteAPIAction(args[0], "/move?"+query, http.MethodPost, "Moved download")
+	},
+}
+
+// isMoveDir reports whether a move argument names a directory rather than a
+// queue position. Bare words are positions unless such a directory exists,
+// so a mistyped "botom" isn't taken for a new directory.
+func isMoveDir(s string) bool {
+	if _, err := queueMoveQuery(s); err == nil {
+		return false
+	}
+	if strings.ContainsAny(s, `/\`) || strings.HasPrefix(s, "~") || strings.HasPrefix(s, ".") {
+		return true
+	}
+	info, err := os.Stat(s)
+	return err == nil && info.IsDir()
+}
+
+// relocateDownload moves a download's file into dir and prints the new path
+func relocateDownload(rawID, dir string) {
+	if !filepath.IsAbs(dir) && !strings.HasPrefix(dir, "~") {
+		dir = utils.EnsureAbsPath(dir)
+	}
+	changeDownloadPath(rawID, "/move?dir="+url.QueryEscape(dir), "Moved to", func(id string) (string, error) {
+		return processing.RelocateDownload(id, dir)
+	})
+}
+
+// changeDownloadPath moves or renames a download's file through the running
+// Surge's endpoint when there is one, otherwise with local, and prints the
+// new path after verb
+func changeDownloadPath(rawID, endpoint, verb string, local func(id string) (string, error)) {
+	id, err := resolveDownloadID(rawID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to resolve download ID: %v\n", err)
+		os.Exit(1)
+	}
+
+	baseURL, token, err := resolveAPIConnection(false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	var path string
+	if baseURL != "" {
+		path, err = relocateRemote(baseURL, token, endpoint+"&id="+url.QueryEscape(id))
+	} else {
+		path, err = local(id)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if globalJSON {
+		data, _ := json.Marshal(map[string]string{"id": id, "path": path})
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Printf("%s %s\n", verb, path)
+}
+
+// relocateRemote sends a move or rename to the server and returns the new
+// path it reports
+func relocateRemote(baseURL, token, endpoint string) (string, error) {
+	resp, err := doAPIRequest(http.MethodPost, baseURL, token, endpoint, nil)
+	if err != nil {
+		return "", fmt.Errorf("connecting to server: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			utils.Debug("Error closing response body: %v", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New(core.ReadAPIError(resp).Detail)
+	}
+	var result struct {
+		Path string `json:"path"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	return result.Path, nil
+}
+
+// queueMoveQuery returns the /move parameters for a move argument: up or
+// down by one place, otherwise a position
+func queueMoveQuery(s string) (string, error) {
+	switch s {
+	case "up":
+		return "by=-1", nil
+	case "down":
+		return "by=1", nil
+	}
+	position, err := parseQueuePosition(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid position %q, want a number from 1, top, bottom, up or down", s)
+	}
+	return "position=" + strconv.Itoa(position), nil
+}
+
+// parseQueuePosition reads a queue position counted from 1, or top or bottom
+func parseQueuePosition(s string) (int, error) {
+	switch s {
+	case "top":
+		return 1, nil
+	case "bottom":
+		return math.MaxInt32, nil // Clamped to the end by the server
+	}
+	position, err := strconv.Atoi(s)
+	if err != nil || position < 1 {
+		return 0, fmt.Errorf("invalid position %q, want a number from 1, top or bottom", s)
+	}
+	return position, nil
+}
+
+func init() {
+	rootCmd.AddCommand(moveCmd)
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/processing"
+)
+
+func TestParseQueuePosition(t *testing.T) {
+	for in, want := range map[string]int{"1": 1, "7": 7, "top": 1, "bottom": math.MaxInt32} {
+		if got, err := parseQueuePosition(in); err != nil || got != want {
+			t.Errorf("parseQueuePosition(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"0", "-2", "first", ""} {
+		if _, err := parseQueuePosition(in); err == nil {
+			t.Errorf("parseQueuePosition(%q): expected an error", in)
+		}
+	}
+}
+
+func TestQueueMoveQuery(t *testing.T) {
+	for in, want := range map[string]string{"up": "by=-1", "down": "by=1", "3": "position=3", "top": "position=1"} {
+		if got, err := queueMoveQuery(in); err != nil || got != want {
+			t.Errorf("queueMoveQuery(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := queueMoveQuery("sideways"); err == nil {
+		t.Error("queueMoveQuery(sideways): expected an error")
+	}
+}
+
+func TestIsMoveDir(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.Mkdir("isos", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for in, want := range map[string]bool{
+		"3": false, "top": false, "up": false,
+		"isos": true, "./1": true, "~/Videos": true, "/mnt/archive": true, "new/dir": true,
+		"botom": false,
+	} {
+		if got := isMoveDir(in); got != want {
+			t.Errorf("isMoveDir(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+type moveService struct {
+	fakeRemoteDownloadService
+	moves []string
+}
+
+func (s *moveService) Move(id string, position int) error {
+	s.moves = append(s.moves, fmt.Sprintf("%s to %d", id, position))
+	return nil
+}
+
+func (s *moveService) Relocate(id, dir string) (string, error) {
+	if id == "running" {
+		return "", processing.ErrNotRelocatable
+	}
+	s.moves = append(s.moves, fmt.Sprintf("%s into %s", id, dir))
+	return filepath.Join(dir, id), nil
+}
+
+func (s *moveService) Rename(id, name string) (string, error) {
+	if name == "" {
+		return "", processing.ErrInvalidFilename
+	}
+	s.moves = append(s.moves, fmt.Sprintf("%s renamed %s", id, name))
+	return filepath.Join("/srv", name), nil
+}
+
+func (s *moveService) MoveBy(id string, offset int) error {
+	if id == "done" {
+		return state.ErrNotWaiting
+	}
+	s.moves = append(s.moves, fmt.Sprintf("%s by %d", id, offset))
+	return nil
+}
+
+func TestMoveEndpoint(t *testing.T) {
+	svc := &moveService{}
+	mux := http.NewServeMux()
+	registerHTTPRoutes(mux, 0, "", svc)
+
+	post := func(query string) int {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/move?"+query, nil))
+		return rec.Code
+	}
+
+	for query, want := range map[string]int{
+		"id=a&position=2":      http.StatusOK,
+		"id=a&by=-1":           http.StatusOK,
+		"id=a":                 http.StatusBadRequest,
+		"id=a&position=1&by=1": http.StatusBadRequest,
+		"id=a&by=up":           http.StatusBadRequest,
+		"id=done&by=1":         http.StatusConflict,
+		"id=a&dir=/srv/isos":   http.StatusOK,
+		"id=a&dir=":            http.StatusBadRequest,
+		"id=running&dir=/srv":  http.StatusConflict,
+	} {
+		if got := post(query); got != want {
+			t.Errorf("POST /move?%s = %d, want %d", query, got, want)
+		}
+	}
+	slices.Sort(svc.moves)
+	if want := []string{"a by -1", "a into /srv/isos", "a to 2"}; !slices.Equal(svc.moves, want) {
+		t.Errorf("moves = %v, want %v", svc.moves, want)
+	}
+}
+
+func TestRenameEndpoint(t *testing.T) {
+	svc := &moveService{}
+	mux := http.NewServeMux()
+	registerHTTPRoutes(mux, 0, "", svc)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/rename?id=a&name=new.iso", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"path":"/srv/new.iso"`) {
+		t.Fatalf("POST /rename = %d %s", rec.Code, rec.Body)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/rename?id=a", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("POST /rename without a name = %d, want 400", rec.Code)
+	}
+}
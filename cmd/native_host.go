@@ -0,0 +1,343 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/nativemsg"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// Native messaging lets the browser extension reach Surge by starting
+// "surge native-host" and talking to it over stdio, so the daemon needs no
+// TCP port and the extension no token. The host forwards each message to
+// the local daemon over its unix socket or loopback port.
+const (
+	nativeHostName        = "com.surge_downloader.surge"
+	firefoxExtensionID    = "surge@surge-downloader.com"
+	maxNativeResponseBody = 512 << 10 // Keeps replies under the browser's 1 MiB limit
+)
+
+// nativeRequest is a message from the extension. ID is echoed back so it can
+// match replies to requests.
+type nativeRequest struct {
+	ID         json.RawMessage  `json:"id,omitempty"`
+	Type       string           `json:"type"` // ping, download, list, pause or resume
+	Download   *DownloadRequest `json:"download,omitempty"`
+	DownloadID string           `json:"download_id,omitempty"` // For pause and resume
+}
+
+// nativeResponse answers a nativeRequest. Data is the daemon's JSON reply;
+// on failure Error and Code are those of its problem details.
+type nativeResponse struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	OK     bool            `json:"ok"`
+	Status int             `json:"status,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	Error  string          `json:"error,omitempty"`
+	Code   string          `json:"code,omitempty"`
+}
+
+// nativeForwardFunc sends one API request to the daemon
+type nativeForwardFunc func(method, path string, body io.Reader) (*http.Response, error)
+
+// forwardToLocalDaemon looks the daemon up for every request, so the host
+// keeps working across daemon restarts
+func forwardToLocalDaemon(method, path string, body io.Reader) (*http.Response, error) {
+	baseURL, token, err := resolveAPIConnection(true)
+	if err != nil {
+		return nil, err
+	}
+	return doAPIRequest(method, baseURL, token, path, body)
+}
+
+// handleNativeMessage turns one message into an API request and its reply
+func handleNativeMessage(msg []byte, forward nativeForwardFunc) nativeResponse {
+	var req nativeRequest
+	if err := json.Unmarshal(msg, &req); err != nil {
+		return nativeResponse{Error: "Invalid JSON: " + err.Error(), Code: core.CodeBadRequest}
+	}
+	resp := nativeResponse{ID: req.ID}
+
+	var method, path string
+	var body io.Reader
+	switch req.Type {
+	case "ping":
+		method, path = http.MethodGet, "/health"
+	case "list":
+		method, path = http.MethodGet, "/list"
+	case "download":
+		if req.Download == nil {
+			resp.Error, resp.Code = "Missing download", core.CodeBadRequest
+			return resp
+		}
+		data, err := json.Marshal(req.Download)
+		if err != nil {
+			resp.Error, resp.Code = err.Error(), core.CodeBadRequest
+			return resp
+		}
+		method, path, body = http.MethodPost, "/download", bytes.NewReader(data)
+	case "pause", "resume":
+		if req.DownloadID == "" {
+			resp.Error, resp.Code = "Missing download_id", core.CodeBadRequest
+			return resp
+		}
+		method, path = http.MethodPost, "/"+req.Type+"?id="+url.QueryEscape(req.DownloadID)
+	default:
+		resp.Error, resp.Code = fmt.Sprintf("Unknown message type %q", req.Type), core.CodeBadRequest
+		return resp
+	}
+
+	httpResp, err := forward(method, path, body)
+	if err != nil {
+		resp.Error, resp.Code = err.Error(), core.CodeUnavailable
+		return resp
+	}
+	defer func() {
+		if err := httpResp.Body.Close(); err != nil {
+			utils.Debug("Error closing response body: %v", err)
+		}
+	}()
+
+	resp.Status = httpResp.StatusCode
+	if httpResp.StatusCode >= http.StatusBadRequest {
+		apiErr := core.ReadAPIError(httpResp)
+		resp.Error, resp.Code = apiErr.Detail, apiErr.Code
+		return resp
+	}
+	data, err := io.ReadAll(io.LimitReader(httpResp.Body, maxNativeResponseBody+1))
+	if err != nil {
+		resp.Error, resp.Code = err.Error(), core.CodeUnavailable
+		return resp
+	}
+	if len(data) > maxNativeResponseBody {
+		resp.Error, resp.Code = "Response too large for native messaging", core.CodeTooLarge
+		return resp
+	}
+	resp.OK = true
+	if json.Valid(data) {
+		resp.Data = data
+	}
+	return resp
+}
+
+// serveNativeHost answers messages from r on w until the browser closes the
+// pipe
+func serveNativeHost(r io.Reader, w io.Writer, forward nativeForwardFunc) error {
+	for {
+		msg, err := nativemsg.Read(r)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := nativemsg.Write(w, handleNativeMessage(msg, forward)); err != nil {
+			return err
+		}
+	}
+}
+
+var nativeHostCmd = &cobra.Command{
+	Use:   "native-host",
+	Short: "Serve the browser extension over native messaging",
+	Long: `Run as the native messaging host of the browser extension. The browser starts
+this itself once "surge native-host install" has registered it; it is not meant
+to be run by hand.`,
+	// Browsers pass the calling extension, and on Windows a --parent-window flag
+	Args:               cobra.ArbitraryArgs,
+	FParseErrWhitelist: cobra.FParseErrWhitelist{UnknownFlags: true},
+	Run: func(cmd *cobra.Command, args []string) {
+		// stdout carries the protocol, so nothing else may be printed there
+		if err := serveNativeHost(os.Stdin, os.Stdout, forwardToLocalDaemon); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var nativeHostInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Register the native messaging host with your browsers",
+	Long: `Write the native messaging manifests that let the browser extension start
+"surge native-host". Chrome and Chromium only allow the extension IDs given with
+--chrome-extension-id; they are skipped without one. On Windows the manifests
+also need registering, and the registry commands to do so are printed.`,
+	Example: `  surge native-host install --browser firefox
+  surge native-host install --chrome-extension-id abcdefghijklmnopabcdefghijklmnop`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		browser, _ := cmd.Flags().GetString("browser")
+		chromeIDs, _ := cmd.Flags().GetStringSlice("chrome-extension-id")
+
+		if err := installNativeHost(browser, chromeIDs); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// nativeHostManifest is the manifest browsers read to find and vet the host
+type nativeHostManifest struct {
+	Name              string   `json:"name"`
+	Description       string   `json:"description"`
+	Path              string   `json:"path"`
+	Type              string   `json:"type"`
+	AllowedOrigins    []string `json:"allowed_origins,omitempty"`    // Chrome
+	AllowedExtensions []string `json:"allowed_extensions,omitempty"` // Firefox
+}
+
+func newNativeHostManifest(launcher, browser string, chromeIDs []string) nativeHostManifest {
+	m := nativeHostManifest{
+		Name:        nativeHostName,
+		Description: "Surge Download Manager",
+		Path:        launcher,
+		Type:        "stdio",
+	}
+	if browser == "firefox" {
+		m.AllowedExtensions = []string{firefoxExtensionID}
+	} else {
+		for _, id := range chromeIDs {
+			m.AllowedOrigins = append(m.AllowedOrigins, "chrome-extension://"+id+"/")
+		}
+	}
+	return m
+}
+
+// nativeHostDir returns the per-user folder browser reads manifests from.
+// Windows has none: manifests go in Surge's folder and the registry points
+// at them.
+func nativeHostDir(browser string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	switch runtime.GOOS {
+	case "windows":
+		return filepath.Join(config.GetSurgeDir(), "native-messaging", browser), nil
+	case "darwin":
+		base := filepath.Join(home, "Library", "Application Support")
+		switch browser {
+		case "chrome":
+			return filepath.Join(base, "Google", "Chrome", "NativeMessagingHosts"), nil
+		case "chromium":
+			return filepath.Join(base, "Chromium", "NativeMessagingHosts"), nil
+		default:
+			return filepath.Join(base, "Mozilla", "NativeMessagingHosts"), nil
+		}
+	default:
+		switch browser {
+		case "chrome":
+			return filepath.Join(home, ".config", "google-chrome", "NativeMessagingHosts"), nil
+		case "chromium":
+			return filepath.Join(home, ".config", "chromium", "NativeMessagingHosts"), nil
+		default:
+			return filepath.Join(home, ".mozilla", "native-messaging-hosts"), nil
+		}
+	}
+}
+
+// nativeHostRegistryKey is where Windows browsers look the manifest up
+func nativeHostRegistryKey(browser string) string {
+	switch browser {
+	case "chrome":
+		return `HKCU\Software\Google\Chrome\NativeMessagingHosts\` + nativeHostName
+	case "chromium":
+		return `HKCU\Software\Chromium\NativeMessagingHosts\` + nativeHostName
+	default:
+		return `HKCU\Software\Mozilla\NativeMessagingHosts\` + nativeHostName
+	}
+}
+
+// writeNativeHostLauncher writes the script manifests point at, as a
+// manifest can name an executable but not its arguments. The profile is
+// carried over so the host talks to the same daemon.
+func writeNativeHostLauncher(exe string) (string, error) {
+	dir := config.GetSurgeDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	var profileArg string
+	if p := config.GetProfile(); p != config.DefaultProfile {
+		profileArg = " --profile " + p
+	}
+
+	if runtime.GOOS == "windows" {
+		path := filepath.Join(dir, "surge-native-host.bat")
+		script := fmt.Sprintf("@echo off\r\n\"%s\" native-host%s %%*\r\n", exe, profileArg)
+		return path, os.WriteFile(path, []byte(script), 0o644)
+	}
+	path := filepath.Join(dir, "surge-native-host.sh")
+	script := fmt.Sprintf("#!/bin/sh\nexec '%s' native-host%s \"$@\"\n", strings.ReplaceAll(exe, "'", `'\''`), profileArg)
+	return path, os.WriteFile(path, []byte(script), 0o755)
+}
+
+func installNativeHost(browser string, chromeIDs []string) error {
+	var browsers []string
+	switch browser {
+	case "all":
+		browsers = []string{"chrome", "chromium", "firefox"}
+	case "chrome", "chromium", "firefox":
+		browsers = []string{browser}
+	default:
+		return fmt.Errorf("unknown browser %q: use chrome, chromium, firefox or all", browser)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("finding the surge executable: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	launcher, err := writeNativeHostLauncher(exe)
+	if err != nil {
+		return fmt.Errorf("writing launcher: %w", err)
+	}
+
+	for _, b := range browsers {
+		if b != "firefox" && len(chromeIDs) == 0 {
+			fmt.Printf("Skipped %s: pass --chrome-extension-id to allow the extension\n", b)
+			continue
+		}
+		dir, err := nativeHostDir(b)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(newNativeHostManifest(launcher, b, chromeIDs), "", "  ")
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(dir, nativeHostName+".json")
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("Installed for %s: %s\n", b, path)
+		if runtime.GOOS == "windows" {
+			fmt.Printf("  Register it with: reg add \"%s\" /ve /t REG_SZ /d \"%s\" /f\n", nativeHostRegistryKey(b), path)
+		}
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(nativeHostCmd)
+	nativeHostCmd.AddCommand(nativeHostInstallCmd)
+	nativeHostInstallCmd.Flags().String("browser", "all", "Browser to register with: chrome, chromium, firefox or all")
+	nativeHostInstallCmd.Flags().StringSlice("chrome-extension-id", nil, "ID of the extension in Chrome or Chromium (repeatable)")
+}
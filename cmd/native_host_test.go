@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/nativemsg"
+)
+
+func TestServeNativeHost_ForwardsMessages(t *testing.T) {
+	var gotDownload DownloadRequest
+	mux := http.NewServeMux()
+	mux.HandleFunc("/download", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&gotDownload)
+		writeJSONResponse(w, http.StatusOK, map[string]string{"status": "queued", "id": "abc"})
+	})
+	mux.HandleFunc("/pause", func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, http.StatusNotFound, core.CodeNotFound, "Download not found: "+r.URL.Query().Get("id"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	forward := func(method, path string, body io.Reader) (*http.Response, error) {
+		return doAPIRequest(method, server.URL, "", path, body)
+	}
+
+	var in bytes.Buffer
+	for _, msg := range []string{
+		`{"id":1,"type":"download","download":{"url":"https://example.com/a.zip","filename":"a.zip"}}`,
+		`{"id":"two","type":"pause","download_id":"missing"}`,
+		`{"id":3,"type":"bogus"}`,
+	} {
+		if err := nativemsg.Write(&in, json.RawMessage(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Write only sends valid JSON, so frame the broken message by hand
+	_ = binary.Write(&in, binary.NativeEndian, uint32(len("not json")))
+	in.WriteString("not json")
+	var out bytes.Buffer
+	if err := serveNativeHost(&in, &out, forward); err != nil {
+		t.Fatalf("serveNativeHost: %v", err)
+	}
+
+	var replies []nativeResponse
+	for {
+		msg, err := nativemsg.Read(&out)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var resp nativeResponse
+		if err := json.Unmarshal(msg, &resp); err != nil {
+			t.Fatal(err)
+		}
+		replies = append(replies, resp)
+	}
+	if len(replies) != 4 {
+		t.Fatalf("got %d replies, want 4", len(replies))
+	}
+
+	if r := replies[0]; !r.OK || string(r.ID) != "1" || !strings.Contains(string(r.Data), `"abc"`) {
+		t.Fatalf("download reply = %+v", r)
+	}
+	if gotDownload.URL != "https://example.com/a.zip" || gotDownload.Filename != "a.zip" {
+		t.Fatalf("daemon got %+v", gotDownload)
+	}
+	if r := replies[1]; r.OK || string(r.ID) != `"two"` || r.Status != http.StatusNotFound || r.Code != core.CodeNotFound || r.Error != "Download not found: missing" {
+		t.Fatalf("pause reply = %+v", r)
+	}
+	if r := replies[2]; r.OK || r.Code != core.CodeBadRequest {
+		t.Fatalf("unknown type reply = %+v", r)
+	}
+	if r := replies[3]; r.OK || r.Code != core.CodeBadRequest || r.ID != nil {
+		t.Fatalf("invalid JSON reply = %+v", r)
+	}
+}
+
+func TestNewNativeHostManifest(t *testing.T) {
+	firefox := newNativeHostManifest("/bin/launcher", "firefox", []string{"ignored"})
+	if len(firefox.AllowedExtensions) != 1 || firefox.AllowedExtensions[0] != firefoxExtensionID || firefox.AllowedOrigins != nil {
+		t.Fatalf("firefox manifest = %+v", firefox)
+	}
+	chrome := newNativeHostManifest("/bin/launcher", "chrome", []string{"abcdef"})
+	if len(chrome.AllowedOrigins) != 1 || chrome.AllowedOrigins[0] != "chrome-extension://abcdef/" || chrome.Type != "stdio" || chrome.Name != nativeHostName {
+		t.Fatalf("chrome manifest = %+v", chrome)
+	}
+}
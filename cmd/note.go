@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+var noteCmd = &cobra.Command{
+	Use:   "note <ID> [TEXT]",
+	Short: "Attach a note and metadata to a download",
+	Long: `Set the free-text note of a download, and add or remove key/value metadata.
+Metadata is merged into what the download already has. Use "surge ls <ID>" to see both.`,
+	Example: `  surge note a1b2 "mirror of the conference talks"
+  surge note a1b2 --set source=forum --set ticket=42
+  surge note a1b2 --unset ticket --clear`,
+	Args:              cobra.RangeArgs(1, 2),
+	ValidArgsFunction: completeDownloadIDs(false),
+	Run: func(cmd *cobra.Command, args []string) {
+		mustInitializeGlobalState()
+
+		set, _ := cmd.Flags().GetStringArray("set")
+		unset, _ := cmd.Flags().GetStringSlice("unset")
+		clearNote, _ := cmd.Flags().GetBool("clear")
+
+		update, err := buildNoteUpdate(args[1:], set, unset, clearNote)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		baseURL, token, err := resolveAPIConnection(true)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		id, err := resolveDownloadID(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		jsonData, err := json.Marshal(update)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating request: %v\n", err)
+			os.Exit(1)
+		}
+
+		path := fmt.Sprintf("/note?id=%s", url.QueryEscape(id))
+		resp, err := doAPIRequest(http.MethodPut, baseURL, token, path, bytes.NewBuffer(jsonData))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error connecting to server: %v\n", err)
+			os.Exit(1)
+		}
+		defer func() {
+			if err := resp.Body.Close(); err != nil {
+				utils.Debug("Error closing response body: %v", err)
+			}
+		}()
+
+		if resp.StatusCode != http.StatusOK {
+			fmt.Fprintf(os.Stderr, "Error: server returned %s: %s\n", resp.Status, core.ReadAPIError(resp).Detail)
+			os.Exit(1)
+		}
+		fmt.Printf("Updated note for download %s\n", id[:8])
+	},
+}
+
+// buildNoteUpdate turns the command line into an update, refusing one that
+// would change nothing
+func buildNoteUpdate(text []string, set, unset []string, clearNote bool) (types.NoteUpdate, error) {
+	var update types.NoteUpdate
+	if len(text) > 0 && clearNote {
+		return update, fmt.Errorf("cannot give a note and --clear together")
+	}
+	if len(text) > 0 {
+		update.Note = &text[0]
+	} else if clearNote {
+		update.Note = new(string)
+	}
+
+	metadata, err := utils.ParseMetadata(set)
+	if err != nil {
+		return update, err
+	}
+	update.Metadata = metadata
+	update.Unset = unset
+
+	if update.Note == nil && len(update.Metadata) == 0 && len(update.Unset) == 0 {
+		return update, fmt.Errorf("nothing to change: give a note, --set, --unset or --clear")
+	}
+	return update, update.Validate()
+}
+
+func init() {
+	rootCmd.AddCommand(noteCmd)
+	noteCmd.Flags().StringArray("set", nil, "Set metadata key=value (repeatable)")
+	noteCmd.Flags().StringSlice("unset", nil, "Remove metadata keys")
+	noteCmd.Flags().Bool("clear", false, "Remove the note")
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import "testing"
+
+func TestBuildNoteUpdate(t *testing.T) {
+	update, err := buildNoteUpdate([]string{"hello"}, []string{"a=1"}, []string{"b"}, false)
+	if err != nil {
+		t.Fatalf("buildNoteUpdate: %v", err)
+	}
+	if update.Note == nil || *update.Note != "hello" || update.Metadata["a"] != "1" || update.Unset[0] != "b" {
+		t.Errorf("update = %+v", update)
+	}
+
+	update, err = buildNoteUpdate(nil, nil, nil, true)
+	if err != nil || update.Note == nil || *update.Note != "" {
+		t.Errorf("--clear = %+v, %v; want an empty note", update, err)
+	}
+
+	for name, tc := range map[string]struct {
+		text  []string
+		set   []string
+		clear bool
+	}{
+		"nothing":        {},
+		"note and clear": {text: []string{"x"}, clear: true},
+		"bad pair":       {set: []string{"novalue"}},
+	} {
+		if _, err := buildNoteUpdate(tc.text, tc.set, nil, tc.clear); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+var openCmd = &cobra.Command{
+	Use:   "open <ID>",
+	Short: "Open a finished download, or the folder holding it",
+	Long: `Open the file of a completed download with its default application, using
+xdg-open on Linux, open on macOS and the shell on Windows. With --dir, open
+the folder holding the file instead, with the file selected where the file
+manager supports it; this works for unfinished downloads too.
+
+Files are opened on this machine, so --host is not supported.`,
+	Example: `  surge open a1b2
+  surge open --dir a1b2`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeDownloadIDs(false, "completed", "paused", "queued", "downloading"),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, _ := cmd.Flags().GetBool("dir")
+
+		if resolveHostTarget() != "" {
+			fmt.Fprintln(os.Stderr, "Error: surge open opens files on this machine and can't be used with --host")
+			os.Exit(1)
+		}
+
+		mustInitializeGlobalState()
+
+		id, err := resolveDownloadID(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		entry, err := state.GetDownload(id)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if entry == nil {
+			fmt.Fprintf(os.Stderr, "Error: download %s not found\n", args[0])
+			os.Exit(1)
+		}
+
+		path, err := openTarget(entry, dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		open := utils.OpenWithSystem
+		if dir {
+			open = utils.RevealInFolder
+		}
+		if err := open(path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening %s: %v\n", path, err)
+			os.Exit(1)
+		}
+		infof("Opened %s\n", path)
+	},
+}
+
+// openTarget returns the path surge open hands to the opener for a
+// download: its file when it has finished, and with dir, whichever of its
+// file or working file exists, for the folder holding it to be opened
+func openTarget(e *types.DownloadEntry, dir bool) (string, error) {
+	if e.DestPath == "" {
+		return "", errors.New("download has no destination yet")
+	}
+	if !dir {
+		if e.Status != "completed" {
+			return "", fmt.Errorf("download is %s, not completed; use --dir to open its folder", e.Status)
+		}
+		if _, err := os.Stat(e.DestPath); err != nil {
+			return "", fmt.Errorf("file is missing: %s", e.DestPath)
+		}
+		return e.DestPath, nil
+	}
+	if e.Status != "completed" {
+		working := e.DestPath + types.IncompleteSuffix
+		if _, err := os.Stat(working); err == nil {
+			return working, nil
+		}
+	}
+	return e.DestPath, nil
+}
+
+func init() {
+	rootCmd.AddCommand(openCmd)
+	openCmd.Flags().Bool("dir", false, "Open the folder holding the file instead")
+}
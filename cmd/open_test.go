@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestOpenTarget(t *testing.T) {
+	dir := t.TempDir()
+	done := filepath.Join(dir, "done.iso")
+	partial := filepath.Join(dir, "partial.iso")
+	for _, f := range []string{done, partial + types.IncompleteSuffix} {
+		if err := os.WriteFile(f, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, c := range []struct {
+		entry types.DownloadEntry
+		dir   bool
+		want  string
+	}{
+		{types.DownloadEntry{DestPath: done, Status: "completed"}, false, done},
+		{types.DownloadEntry{DestPath: done, Status: "completed"}, true, done},
+		{types.DownloadEntry{DestPath: partial, Status: "paused"}, true, partial + types.IncompleteSuffix},
+		{types.DownloadEntry{DestPath: filepath.Join(dir, "queued.iso"), Status: "queued"}, true, filepath.Join(dir, "queued.iso")},
+	} {
+		if got, err := openTarget(&c.entry, c.dir); err != nil || got != c.want {
+			t.Errorf("openTarget(%s, dir=%v) = %q, %v; want %q", c.entry.Status, c.dir, got, err, c.want)
+		}
+	}
+
+	for _, e := range []types.DownloadEntry{
+		{DestPath: partial, Status: "paused"},
+		{DestPath: filepath.Join(dir, "gone.iso"), Status: "completed"},
+		{Status: "queued"},
+	} {
+		if _, err := openTarget(&e, false); err == nil {
+			t.Errorf("openTarget(%+v): expected an error", e)
+		}
+	}
+}
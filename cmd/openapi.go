@@ -0,0 +1,415 @@
+package cmd
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	swaggerFiles "github.com/swaggo/files/v2"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// Where the OpenAPI document and its Swagger UI are served. Neither needs the
+// token: they describe the API but expose nothing about downloads.
+const (
+	openAPIPath = "/openapi.json"
+	apiDocsPath = "/docs/"
+)
+
+// apiParam is one query parameter of an API route
+type apiParam struct {
+	Name        string
+	Type        string // OpenAPI type, "string" when empty
+	Description string
+	Required    bool
+}
+
+// apiRoute documents one method of an HTTP API path. Body and Response are
+// zero values of the JSON request and response types, nil for none.
+type apiRoute struct {
+	Method      string
+	Path        string
+	Summary     string
+	Params      []apiParam
+	Body        any
+	Response    any
+	OrResponse  any    // Another shape the response may take, nil for none
+	ContentType string // Response content type when it is not JSON
+	Errors      []int  // Statuses besides 200 and 401 it answers with
+	Public      bool   // Served without the token
+}
+
+// actionResponse is what id actions answer with; fields an action does not
+// report are left out
+type actionResponse struct {
+	Status   string `json:"status"`
+	ID       string `json:"id,omitempty"`
+	Message  string `json:"message,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Position int    `json:"position,omitempty"`
+	By       int    `json:"by,omitempty"`
+	Path     string `json:"path,omitempty"`
+}
+
+// hostActionResponse is what pause and resume answer with for ?host=
+type hostActionResponse struct {
+	Status string   `json:"status"`
+	Host   string   `json:"host"`
+	IDs    []string `json:"ids"`
+	Error  string   `json:"error,omitempty"`
+}
+
+type updateURLRequest struct {
+	URL string `json:"url"`
+}
+
+var (
+	idParam     = apiParam{Name: "id", Description: "Download ID", Required: true}
+	idOrHost    = []apiParam{{Name: "id", Description: "Download ID; required unless host is given"}, {Name: "host", Description: "Act on every download from this host instead of one id"}}
+	tagParam    = apiParam{Name: "tag", Description: "Only downloads with this tag"}
+	cursorParam = apiParam{Name: "cursor", Description: "Cursor from the previous page's " + nextCursorHeader + " header"}
+	limitParam  = apiParam{Name: "limit", Type: "integer", Description: "Page size, at most " + strconv.Itoa(maxPageLimit) + "; everything when absent"}
+	sinceParam  = apiParam{Name: "since", Type: "integer", Description: "Seq of the last event already seen"}
+)
+
+// apiRoutes lists every route registerHTTPRoutes serves, in the order they
+// appear there
+var apiRoutes = []apiRoute{
+	{Method: http.MethodGet, Path: "/health", Summary: "Check that the server is up; with the token, also whether it is healthy", Response: healthResponse{}, Public: true,
+		Params: []apiParam{{Name: "ready", Description: "Answer 503 when not healthy, or without the token when shutting down"}}, Errors: []int{http.StatusServiceUnavailable}},
+	{Method: http.MethodGet, Path: "/events", Summary: "Stream download events as server-sent events", ContentType: "text/event-stream"},
+	{Method: http.MethodGet, Path: "/events/poll", Summary: "Long-poll for download events",
+		Params:   []apiParam{sinceParam, {Name: "wait", Type: "integer", Description: "Seconds to wait for an event, default 25, at most 60"}},
+		Response: eventsPollResponse{}, Errors: []int{http.StatusBadRequest}},
+	{Method: http.MethodGet, Path: "/status/poll", Summary: "Long-poll for the downloads that changed",
+		Params:   []apiParam{sinceParam, {Name: "wait", Type: "integer", Description: "Seconds to wait for a change, default 25, at most 60"}},
+		Response: statusPollResponse{}, Errors: []int{http.StatusBadRequest}},
+	{Method: http.MethodGet, Path: "/ws", Summary: "Stream download events over WebSocket", Params: []apiParam{sinceParam},
+		Errors: []int{http.StatusBadRequest}},
+	{Method: http.MethodGet, Path: "/download", Summary: "Get the status of one download", Params: []apiParam{idParam},
+		Response: types.DownloadStatus{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+	{Method: http.MethodPost, Path: "/download", Summary: "Queue a download", Body: DownloadRequest{}, Response: downloadOutcome{},
+		Errors: []int{http.StatusAccepted, http.StatusBadRequest, http.StatusConflict, http.StatusServiceUnavailable}},
+	{Method: http.MethodPost, Path: "/downloads", Summary: "Queue several downloads, optionally tagged with a shared group name", Body: batchRequest{},
+		Response: batchResponse{}, Errors: []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusServiceUnavailable}},
+	{Method: http.MethodPost, Path: "/upload", Summary: "Queue the files of an uploaded metalink or torrent (multipart field \"file\")",
+		Params: []apiParam{
+			{Name: "path", Description: "Form field: directory to save into"},
+			{Name: "category", Description: "Form field: category to sort into"},
+			{Name: "tags", Description: "Form field: comma-separated tags"}},
+		Response: uploadResponse{}, Errors: []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity, http.StatusServiceUnavailable}},
+	{Method: http.MethodPost, Path: "/pause", Summary: "Pause a download, or every download from a host", Params: idOrHost,
+		Response: actionResponse{}, OrResponse: hostActionResponse{}, Errors: []int{http.StatusBadRequest}},
+	{Method: http.MethodPost, Path: "/resume", Summary: "Resume a download, or every download from a host", Params: idOrHost,
+		Response: actionResponse{}, OrResponse: hostActionResponse{}, Errors: []int{http.StatusBadRequest}},
+	{Method: http.MethodPost, Path: "/delete", Summary: "Delete a download", Params: []apiParam{idParam, {Name: "permanent", Type: "boolean", Description: "Bypass the trash"}},
+		Response: actionResponse{}, Errors: []int{http.StatusBadRequest}},
+	{Method: http.MethodDelete, Path: "/delete", Summary: "Delete a download", Params: []apiParam{idParam, {Name: "permanent", Type: "boolean", Description: "Bypass the trash"}},
+		Response: actionResponse{}, Errors: []int{http.StatusBadRequest}},
+	{Method: http.MethodPost, Path: "/archive", Summary: "Remove a download but keep its partial data", Params: []apiParam{idParam},
+		Response: actionResponse{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusNotImplemented}},
+	{Method: http.MethodPost, Path: "/restore-partial", Summary: "Restore an archived download and resume it", Params: []apiParam{idParam},
+		Response: actionResponse{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusNotImplemented}},
+	{Method: http.MethodPost, Path: "/retry", Summary: "Start a failed download over from the beginning",
+		Params:   []apiParam{idParam, {Name: "probe", Type: "boolean", Description: "Probe the URL first and leave the download failed if it still fails"}},
+		Response: actionResponse{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusBadGateway, http.StatusNotImplemented}},
+	{Method: http.MethodPost, Path: "/restore", Summary: "Restore a download from the trash", Params: []apiParam{idParam},
+		Response: actionResponse{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusNotImplemented}},
+	{Method: http.MethodPost, Path: "/move", Summary: "Move a waiting download in the queue, or the file of a finished or paused one to another directory",
+		Params: []apiParam{idParam,
+			{Name: "position", Type: "integer", Description: "New position, counted from 1"},
+			{Name: "by", Type: "integer", Description: "Places to move instead, toward the front when negative"},
+			{Name: "dir", Description: "Directory on the server to move the file into instead; the new path is returned"}},
+		Response: actionResponse{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusNotImplemented}},
+	{Method: http.MethodPost, Path: "/priority", Summary: "Set the priority a waiting download starts with",
+		Params: []apiParam{idParam,
+			{Name: "level", Required: true, Description: "high, normal or low"},
+			{Name: "place", Description: "top or bottom among the downloads of that priority; by default it moves to the back only when its priority changes"}},
+		Response: actionResponse{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusNotImplemented}},
+	{Method: http.MethodPost, Path: "/rename", Summary: "Rename the file of a finished or paused download",
+		Params:   []apiParam{idParam, {Name: "name", Required: true, Description: "New file name; a number is added if it is taken"}},
+		Response: actionResponse{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusNotImplemented}},
+	{Method: http.MethodGet, Path: "/list", Summary: "List downloads",
+		Params: []apiParam{tagParam,
+			{Name: "status", Description: "Comma-separated statuses to include"},
+			{Name: "search", Description: "Case-insensitive text to find in the filename or URL"},
+			{Name: "sort", Description: "added (default), speed or eta; speed and eta page with offset"},
+			cursorParam,
+			{Name: "offset", Type: "integer", Description: "Downloads to skip; not with cursor"},
+			limitParam},
+		Response: []types.DownloadStatus{}, Errors: []int{http.StatusBadRequest}},
+	{Method: http.MethodGet, Path: "/history", Summary: "List completed downloads, newest first", Params: []apiParam{tagParam, cursorParam, limitParam},
+		Response: []types.DownloadEntry{}, Errors: []int{http.StatusBadRequest}},
+	{Method: http.MethodGet, Path: "/history/export", Summary: "Export downloads as CSV, JSON or an aria2 session",
+		Params: []apiParam{
+			{Name: "format", Description: "csv, json (default) or aria2"},
+			{Name: "status", Description: "Comma-separated statuses to include"},
+			{Name: "since", Description: "RFC 3339 time or YYYY-MM-DD date to start from"},
+			{Name: "until", Description: "RFC 3339 time or YYYY-MM-DD date to end at, a date covering that whole day"},
+		},
+		ContentType: "application/octet-stream", Errors: []int{http.StatusBadRequest}},
+	{Method: http.MethodPut, Path: "/update-url", Summary: "Change the URL of a paused or failed download", Params: []apiParam{idParam},
+		Body: updateURLRequest{}, Response: actionResponse{}, Errors: []int{http.StatusBadRequest}},
+	{Method: http.MethodPut, Path: "/note", Summary: "Change the note and metadata of a download", Params: []apiParam{idParam},
+		Body: types.NoteUpdate{}, Response: actionResponse{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+	{Method: http.MethodGet, Path: "/chunks", Summary: "Get the chunk map and per-worker ranges of a download", Params: []apiParam{idParam},
+		Response: types.ChunkMap{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusNotImplemented}},
+	{Method: http.MethodPost, Path: "/verify", Summary: "Re-hash a completed download; the result comes as a verified event", Params: []apiParam{idParam},
+		Response: actionResponse{}, Errors: []int{http.StatusAccepted, http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusNotImplemented}},
+	{Method: http.MethodGet, Path: "/speed-limit", Summary: "Get the global speed limit, or a download's own with id",
+		Params:   []apiParam{{Name: "id", Description: "Download ID; leave out for the global limit"}},
+		Response: speedLimit{}, Errors: []int{http.StatusNotFound, http.StatusNotImplemented}},
+	{Method: http.MethodPut, Path: "/speed-limit", Summary: "Set the global speed limit, or a download's own with id; running downloads follow at once",
+		Params: []apiParam{{Name: "id", Description: "Download ID; leave out for the global limit"}},
+		Body:   speedLimit{}, Response: speedLimit{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusNotImplemented}},
+	{Method: http.MethodGet, Path: "/webhooks", Summary: "List webhooks", Response: []config.Webhook{}},
+	{Method: http.MethodPost, Path: "/webhooks", Summary: "Add a webhook; its id is assigned",
+		Body: config.Webhook{}, Response: config.Webhook{}, Errors: []int{http.StatusBadRequest}},
+	{Method: http.MethodPut, Path: "/webhooks", Summary: "Replace a webhook", Params: []apiParam{idParam},
+		Body: config.Webhook{}, Response: config.Webhook{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+	{Method: http.MethodDelete, Path: "/webhooks", Summary: "Remove a webhook", Params: []apiParam{idParam},
+		Response: actionResponse{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+	{Method: http.MethodGet, Path: "/settings", Summary: "Get the settings", Response: config.Settings{}},
+	{Method: http.MethodPut, Path: "/settings", Summary: "Change settings; only the fields sent are changed, lists are replaced whole",
+		Body: config.Settings{}, Response: config.Settings{}, Errors: []int{http.StatusBadRequest}},
+	{Method: http.MethodPost, Path: "/shutdown", Summary: "Stop accepting downloads and shut the daemon down",
+		Params: []apiParam{
+			{Name: "mode", Description: "pause (default) pauses running downloads; finish waits for them"},
+			{Name: "timeout", Description: "With mode=finish, pause what still runs after this duration, e.g. 30m"}},
+		Response: shutdownResponse{}, Errors: []int{http.StatusBadRequest, http.StatusConflict}},
+	{Method: http.MethodGet, Path: "/debug/pprof/", Summary: "pprof profile index", ContentType: "text/html"},
+	{Method: http.MethodGet, Path: "/debug/pprof/cmdline", Summary: "pprof command line", ContentType: "text/plain"},
+	{Method: http.MethodGet, Path: "/debug/pprof/profile", Summary: "pprof CPU profile", ContentType: "application/octet-stream"},
+	{Method: http.MethodGet, Path: "/debug/pprof/symbol", Summary: "pprof symbol lookup", ContentType: "text/plain"},
+	{Method: http.MethodGet, Path: "/debug/pprof/trace", Summary: "pprof execution trace", ContentType: "application/octet-stream"},
+	{Method: http.MethodGet, Path: "/debug/metrics", Summary: "Engine and runtime gauges", Response: debugMetrics{}},
+	{Method: http.MethodGet, Path: openAPIPath, Summary: "This document", ContentType: "application/json", Public: true},
+}
+
+// registerAPIDocRoutes serves the OpenAPI document and Swagger UI for it
+func registerAPIDocRoutes(mux *http.ServeMux) {
+	mux.HandleFunc(openAPIPath, requireMethod(http.MethodGet, func(w http.ResponseWriter, _ *http.Request) {
+		writeJSONResponse(w, http.StatusOK, openAPIDocument())
+	}))
+
+	files := http.StripPrefix(apiDocsPath, http.FileServerFS(swaggerFiles.FS))
+	mux.HandleFunc(apiDocsPath, requireMethod(http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+		// The bundled initializer loads the Petstore example; point it here
+		if r.URL.Path == apiDocsPath+"swagger-initializer.js" {
+			w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+			_, _ = w.Write([]byte(swaggerInitializer))
+			return
+		}
+		files.ServeHTTP(w, r)
+	}))
+}
+
+const swaggerInitializer = `window.onload = function() {
+  window.ui = SwaggerUIBundle({
+    url: "` + openAPIPath + `",
+    dom_id: '#swagger-ui',
+    deepLinking: true,
+    persistAuthorization: true,
+    presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+    layout: "StandaloneLayout"
+  });
+};
+`
+
+// openAPIDocument is the OpenAPI 3 description of apiRoutes, built once
+var openAPIDocument = sync.OnceValue(func() map[string]any {
+	b := &schemaBuilder{components: map[string]any{}}
+	paths := map[string]any{}
+	for _, route := range apiRoutes {
+		ops, _ := paths[route.Path].(map[string]any)
+		if ops == nil {
+			ops = map[string]any{}
+			paths[route.Path] = ops
+		}
+		ops[strings.ToLower(route.Method)] = b.operation(route)
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "Surge API",
+			"version": Version,
+			"description": "HTTP API of the Surge daemon. Send the token from `surge token` as `Authorization: Bearer <token>`. " +
+				"Every path is also served without the `" + apiVersionPrefix + "` prefix, and `" + apiVersionHeader + "` may pin the version a client expects.",
+		},
+		"servers": []any{
+			map[string]any{"url": apiVersionPrefix},
+		},
+		"paths":    paths,
+		"security": []any{map[string]any{"bearer": []any{}}},
+		"components": map[string]any{
+			"schemas": b.components,
+			"securitySchemes": map[string]any{
+				"bearer": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+})
+
+// schemaBuilder turns Go types into OpenAPI schemas, collecting named structs
+// as components
+type schemaBuilder struct {
+	components map[string]any
+}
+
+func (b *schemaBuilder) operation(route apiRoute) map[string]any {
+	op := map[string]any{"summary": route.Summary}
+	if route.Public {
+		op["security"] = []any{}
+	}
+
+	if len(route.Params) > 0 {
+		params := make([]any, 0, len(route.Params))
+		for _, p := range route.Params {
+			typ := p.Type
+			if typ == "" {
+				typ = "string"
+			}
+			params = append(params, map[string]any{
+				"name":        p.Name,
+				"in":          "query",
+				"required":    p.Required,
+				"description": p.Description,
+				"schema":      map[string]any{"type": typ},
+			})
+		}
+		op["parameters"] = params
+	}
+
+	if route.Body != nil {
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": b.schema(reflect.TypeOf(route.Body))},
+			},
+		}
+	}
+
+	ok := map[string]any{"description": "OK"}
+	switch {
+	case route.Response != nil:
+		schema := b.schema(reflect.TypeOf(route.Response))
+		if route.OrResponse != nil {
+			schema = map[string]any{"oneOf": []any{schema, b.schema(reflect.TypeOf(route.OrResponse))}}
+		}
+		ok["content"] = map[string]any{"application/json": map[string]any{"schema": schema}}
+	case route.ContentType != "":
+		ok["content"] = map[string]any{route.ContentType: map[string]any{}}
+	}
+	responses := map[string]any{"200": ok}
+	// Errors are answered with problem details
+	problem := map[string]any{core.ProblemContentType: map[string]any{"schema": b.schema(reflect.TypeOf(core.Problem{}))}}
+	if !route.Public {
+		responses["401"] = map[string]any{"description": http.StatusText(http.StatusUnauthorized), "content": problem}
+	}
+	for _, code := range route.Errors {
+		resp := map[string]any{"description": http.StatusText(code)}
+		if code >= http.StatusBadRequest {
+			resp["content"] = problem
+		}
+		responses[strconv.Itoa(code)] = resp
+	}
+	op["responses"] = responses
+	return op
+}
+
+var (
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	durationType   = reflect.TypeOf(time.Duration(0))
+)
+
+// schema returns the schema of t as encoding/json marshals it, a $ref for
+// named structs
+func (b *schemaBuilder) schema(t reflect.Type) map[string]any {
+	switch t {
+	case rawMessageType:
+		return map[string]any{}
+	case durationType:
+		return map[string]any{"type": "integer", "format": "int64", "description": "Nanoseconds"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return b.schema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		// Unexported response types still get capitalized schema names
+		name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+		if _, ok := b.components[name]; !ok {
+			b.components[name] = map[string]any{} // Placeholder for recursive types
+			b.components[name] = b.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	default:
+		return map[string]any{}
+	}
+}
+
+func (b *schemaBuilder) object(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	var required []string
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			// encoding/json promotes embedded structs' fields
+			embedded := b.object(f.Type)
+			maps.Copy(props, embedded["properties"].(map[string]any))
+			if req, ok := embedded["required"].([]string); ok {
+				required = append(required, req...)
+			}
+			continue
+		}
+		if !f.IsExported() || tag == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = b.schema(f.Type)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	out := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		out["required"] = required
+	}
+	return out
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPI_DocumentsEveryRoute(t *testing.T) {
+	documented := map[string]bool{}
+	for _, route := range apiRoutes {
+		documented[route.Path] = true
+	}
+
+	// Routes are registered with literal paths, so the source lists them all
+	pattern := regexp.MustCompile(`mux\.HandleFunc\("([^"]+)"`)
+	for _, file := range []string{"http_api.go", "debug_api.go"} {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range pattern.FindAllStringSubmatch(string(src), -1) {
+			if !documented[m[1]] {
+				t.Errorf("route %s in %s is missing from apiRoutes", m[1], file)
+			}
+		}
+	}
+
+	mux := http.NewServeMux()
+	registerHTTPRoutes(mux, 0, "", &fakeRemoteDownloadService{})
+	for _, route := range apiRoutes {
+		req := httptest.NewRequest(route.Method, route.Path, nil)
+		if _, p := mux.Handler(req); p == "" {
+			t.Errorf("documented route %s %s is not served", route.Method, route.Path)
+		}
+	}
+}
+
+func TestOpenAPI_RefsResolve(t *testing.T) {
+	data, err := json.Marshal(openAPIDocument())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		OpenAPI    string `json:"openapi"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Fatalf("openapi = %q", doc.OpenAPI)
+	}
+	if _, ok := doc.Components.Schemas["DownloadStatus"]; !ok {
+		t.Fatal("DownloadStatus schema missing")
+	}
+
+	for _, m := range regexp.MustCompile(`"\$ref":"#/components/schemas/([^"]+)"`).FindAllStringSubmatch(string(data), -1) {
+		if _, ok := doc.Components.Schemas[m[1]]; !ok {
+			t.Errorf("$ref to missing schema %s", m[1])
+		}
+	}
+}
+
+func TestOpenAPI_ServedWithoutToken(t *testing.T) {
+	baseURL := startAuthedTestServer(t, &fakeRemoteDownloadService{}, "docs-token")
+
+	get := func(path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(baseURL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if code, body := get(openAPIPath); code != http.StatusOK || !strings.Contains(body, `"openapi"`) {
+		t.Fatalf("GET %s = %d %.100s", openAPIPath, code, body)
+	}
+	if code, body := get(apiDocsPath); code != http.StatusOK || !strings.Contains(body, "swagger-ui") {
+		t.Fatalf("GET %s = %d %.100s", apiDocsPath, code, body)
+	}
+	if code, body := get(apiDocsPath + "swagger-initializer.js"); code != http.StatusOK || !strings.Contains(body, openAPIPath) {
+		t.Fatalf("initializer = %d %.200s", code, body)
+	}
+	if code, _ := get("/list"); code != http.StatusUnauthorized {
+		t.Fatalf("GET /list without token = %d, want 401", code)
+	}
+}
@@ -11,15 +11,27 @@ import (
 var pauseCmd = &cobra.Command{
 	Use:   "pause <ID>",
 	Short: "Pause a download",
-	Long:  `Pause a download by its ID. Use --all to pause all downloads.`,
-	Args:  cobra.MaximumNArgs(1),
+	Long: `Pause a download by its ID. Use --all to pause all downloads, or --from-host
+to pause every running download from one host, e.g. a misbehaving mirror.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeDownloadIDs(false, "downloading", "queued"),
 	Run: func(cmd *cobra.Command, args []string) {
 		mustInitializeGlobalState()
 
 		all, _ := cmd.Flags().GetBool("all")
+		fromHost, _ := cmd.Flags().GetString("from-host")
+
+		if fromHost != "" {
+			if all || len(args) > 0 {
+				fmt.Fprintln(os.Stderr, "Error: --from-host cannot be combined with an ID or --all")
+				os.Exit(1)
+			}
+			ExecuteHostAction(fromHost, "/pause", "Paused")
+			return
+		}
 
 		if !all && len(args) == 0 {
-			fmt.Fprintln(os.Stderr, "Error: provide a download ID or use --all")
+			fmt.Fprintln(os.Stderr, "Error: provide a download ID, --from-host or --all")
 			os.Exit(1)
 		}
 
@@ -36,4 +48,5 @@ var pauseCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(pauseCmd)
 	pauseCmd.Flags().Bool("all", false, "Pause all downloads")
+	pauseCmd.Flags().String("from-host", "", "Pause every running download from this host")
 }
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+var priorityCmd = &cobra.Command{
+	Use:   "priority <ID> [high|normal|low]",
+	Short: "Make a waiting download start before or after others",
+	Long: `Set the priority of a queued or paused download. Waiting downloads start
+highest priority first, and in queue order among the same priority, so a
+high priority download starts as soon as a slot frees up, ahead of every
+normal and low one. A download given a new priority goes to the back of the
+downloads with that priority; --top puts it at the front instead, and
+--bottom at the back. Without a priority, --top and --bottom move it within
+its current one.
+
+The priority is kept across restarts. Works whether Surge is running or not.`,
+	Example: `  surge priority a1b2 high
+  surge priority a1b2 high --top
+  surge priority a1b2 low
+  surge priority a1b2 --top`,
+	Args:              cobra.RangeArgs(1, 2),
+	ValidArgsFunction: completeDownloadIDs(false, "queued", "paused"),
+	Run: func(cmd *cobra.Command, args []string) {
+		top, _ := cmd.Flags().GetBool("top")
+		bottom, _ := cmd.Flags().GetBool("bottom")
+
+		place, err := priorityPlace(top, bottom)
+		if err == nil && len(args) == 1 && place == "" {
+			err = errors.New("give a priority, --top or --bottom")
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		mustInitializeGlobalState()
+
+		if len(args) == 1 {
+			// Within its current priority, which is what a move does
+			position := "1"
+			if place == state.PlaceBottom {
+				position = "bottom"
+			}
+			query, _ := queueMoveQuery(position)
+			ExecuteAPIAction(args[0], "/move?"+query, http.MethodPost, "Moved download")
+			return
+		}
+
+		priority, err := types.ParsePriority(args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := setDownloadPriority(args[0], priority, place); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCodeFor(err))
+		}
+		infof("Set priority to %s\n", args[1])
+	},
+}
+
+// priorityPlace returns where --top or --bottom puts a download among those
+// of its priority, or "" to leave that to SetPriority
+func priorityPlace(top, bottom bool) (string, error) {
+	switch {
+	case top && bottom:
+		return "", errors.New("--top and --bottom cannot be used together")
+	case top:
+		return state.PlaceTop, nil
+	case bottom:
+		return state.PlaceBottom, nil
+	}
+	return "", nil
+}
+
+// setDownloadPriority sets a download's priority through the running
+// Surge's endpoint when there is one, so the pool follows, otherwise in the
+// state database for when Surge next starts
+func setDownloadPriority(rawID string, priority int, place string) error {
+	id, err := resolveDownloadID(rawID)
+	if err != nil {
+		return fmt.Errorf("failed to resolve download ID: %w", err)
+	}
+	baseURL, token, err := resolveAPIConnection(false)
+	if err != nil {
+		return err
+	}
+	if baseURL == "" {
+		_, err := state.SetPriority(id, priority, place)
+		return err
+	}
+
+	endpoint := "/priority?id=" + url.QueryEscape(id) + "&level=" + types.PriorityName(priority)
+	if place != "" {
+		endpoint += "&place=" + place
+	}
+	resp, err := doAPIRequest(http.MethodPost, baseURL, token, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("connecting to server: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			utils.Debug("Error closing response body: %v", err)
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(core.ReadAPIError(resp).Detail)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(priorityCmd)
+	priorityCmd.Flags().Bool("top", false, "Put it at the front of the downloads with its priority")
+	priorityCmd.Flags().Bool("bottom", false, "Put it at the back of the downloads with its priority")
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestPriorityPlace(t *testing.T) {
+	for _, c := range []struct {
+		top, bottom bool
+		want        string
+	}{{false, false, ""}, {true, false, state.PlaceTop}, {false, true, state.PlaceBottom}} {
+		if got, err := priorityPlace(c.top, c.bottom); err != nil || got != c.want {
+			t.Errorf("priorityPlace(%v, %v) = %q, %v; want %q", c.top, c.bottom, got, err, c.want)
+		}
+	}
+	if _, err := priorityPlace(true, true); err == nil {
+		t.Error("priorityPlace(true, true): expected an error")
+	}
+}
+
+type priorityService struct {
+	fakeRemoteDownloadService
+	set []string
+}
+
+func (s *priorityService) SetPriority(id string, priority int, place string) error {
+	if id == "done" {
+		return state.ErrNotWaiting
+	}
+	s.set = append(s.set, id+" "+types.PriorityName(priority)+" "+place)
+	return nil
+}
+
+func TestPriorityEndpoint(t *testing.T) {
+	svc := &priorityService{}
+	mux := http.NewServeMux()
+	registerHTTPRoutes(mux, 0, "", svc)
+
+	for query, want := range map[string]int{
+		"id=a&level=high":           http.StatusOK,
+		"id=b&level=low&place=top":  http.StatusOK,
+		"id=a&level=urgent":         http.StatusBadRequest,
+		"id=a":                      http.StatusBadRequest,
+		"id=a&level=high&place=mid": http.StatusBadRequest,
+		"id=done&level=high":        http.StatusConflict,
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/priority?"+query, nil))
+		if rec.Code != want {
+			t.Errorf("POST /priority?%s = %d, want %d", query, rec.Code, want)
+		}
+	}
+	slices.Sort(svc.set)
+	if want := []string{"a high ", "b low top"}; !slices.Equal(svc.set, want) {
+		t.Errorf("priorities set = %q, want %q", svc.set, want)
+	}
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/processing"
+)
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Clean up finished, failed and orphaned downloads",
+	Long: `Remove downloads from the list, and leftovers from the disk, picked by flags:
+
+  --completed  finished downloads; their files stay where they are
+  --failed     downloads that failed, with their working files
+  --missing    finished downloads whose file has been moved or deleted
+  --orphans    working files (` + types.IncompleteSuffix + `) that no unfinished download claims, and
+               paused or queued downloads whose working file is gone and so
+               can no longer be resumed
+
+Download folders and the folders of known downloads are searched on this
+machine. Use --dry-run to list what would be removed.`,
+	Example: `  surge prune --orphans --dry-run
+  surge prune --failed --missing`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		orphans, _ := cmd.Flags().GetBool("orphans")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		var filter state.PruneFilter
+		filter.Completed, _ = cmd.Flags().GetBool("completed")
+		filter.Failed, _ = cmd.Flags().GetBool("failed")
+		filter.Missing, _ = cmd.Flags().GetBool("missing")
+		byStatus := filter.Completed || filter.Failed || filter.Missing
+		if !orphans && !byStatus {
+			fmt.Fprintln(os.Stderr, "Error: say what to prune: --completed, --failed, --missing or --orphans")
+			os.Exit(1)
+		}
+
+		mustInitializeGlobalState()
+
+		var candidates []state.PruneCandidate
+		if byStatus {
+			var err error
+			if candidates, err = state.FindPrunable(filter); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		found := &state.Orphans{}
+		if orphans {
+			var err error
+			if found, err = processing.FindOrphans(getSettings()); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if len(candidates) == 0 && found.Empty() {
+			fmt.Println("Nothing to prune.")
+			return
+		}
+		printPrunable(candidates)
+		printOrphans(found)
+		if dryRun {
+			return
+		}
+
+		removed, err := state.RemovePrunable(filter, candidates)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		files, entries, err := state.RemoveOrphans(found)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if orphans {
+			fmt.Printf("\nRemoved %d downloads, %d orphaned files and %d orphaned downloads.\n", removed, files, entries)
+		} else {
+			fmt.Printf("\nRemoved %d downloads.\n", removed)
+		}
+	},
+}
+
+func printPrunable(candidates []state.PruneCandidate) {
+	for _, c := range candidates {
+		id := c.ID
+		if len(id) > 8 {
+			id = id[:8]
+		}
+		fmt.Printf("%-9s %s  %s\n", c.Reason, id, c.DestPath)
+	}
+}
+
+func printOrphans(o *state.Orphans) {
+	for _, path := range o.Files {
+		fmt.Printf("file      %s\n", path)
+	}
+	for _, e := range o.Entries {
+		id := e.ID
+		if len(id) > 8 {
+			id = id[:8]
+		}
+		fmt.Printf("download  %s  %s (working file missing)\n", id, e.DestPath)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+	pruneCmd.Flags().Bool("completed", false, "Remove finished downloads from the list, keeping their files")
+	pruneCmd.Flags().Bool("failed", false, "Remove failed downloads and their working files")
+	pruneCmd.Flags().Bool("missing", false, "Remove finished downloads whose file no longer exists")
+	pruneCmd.Flags().Bool("orphans", false, "Remove unclaimed working files and downloads whose working file is gone")
+	pruneCmd.Flags().Bool("dry-run", false, "List what would be removed without removing it")
+}
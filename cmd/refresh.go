@@ -13,10 +13,11 @@ import (
 )
 
 var refreshCmd = &cobra.Command{
-	Use:   "refresh <ID> <NEW_URL>",
-	Short: "Update the URL of a paused or errored download",
-	Long:  `Update the source URL of a download by its ID. It must be paused or in an error state to be refreshed.`,
-	Args:  cobra.ExactArgs(2),
+	Use:               "refresh <ID> <NEW_URL>",
+	Short:             "Update the URL of a paused or errored download",
+	Long:              `Update the source URL of a download by its ID. It must be paused or in an error state to be refreshed.`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeDownloadIDs(false, "paused", "error"),
 	Run: func(cmd *cobra.Command, args []string) {
 		mustInitializeGlobalState()
 
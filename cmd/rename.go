@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"net/url"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/processing"
+)
+
+var renameCmd = &cobra.Command{
+	Use:   "rename <ID> <NEW-NAME>",
+	Short: "Rename the file of a finished or paused download",
+	Long: `Rename the file of a finished or paused download in its directory. If
+NEW-NAME is taken there a number is added, as for new downloads. A paused
+download's partial file is renamed and it resumes under the new name. Works
+whether Surge is running or not.`,
+	Example:           `  surge rename a1b2 ubuntu-24.04.iso`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeDownloadIDs(false, "paused", "completed"),
+	Run: func(cmd *cobra.Command, args []string) {
+		mustInitializeGlobalState()
+
+		name := args[1]
+		changeDownloadPath(args[0], "/rename?name="+url.QueryEscape(name), "Renamed to", func(id string) (string, error) {
+			return processing.RenameDownload(id, name)
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(renameCmd)
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/engine/state"
+)
+
+var restorePartialCmd = &cobra.Command{
+	Use:   "restore-partial [ID]",
+	Short: "Bring back a download removed with --keep-partial",
+	Long: `Restore a download that was removed with 'surge rm --keep-partial' and resume
+it from its partial data. Without an ID, list the downloads that can be restored.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		mustInitializeGlobalState()
+
+		if len(args) == 0 {
+			printArchivedDownloads()
+			return
+		}
+
+		ExecuteAPIAction(args[0], "/restore-partial", http.MethodPost, "Restored download")
+	},
+}
+
+// printArchivedDownloads lists the downloads restore-partial can bring back
+func printArchivedDownloads() {
+	downloads, err := state.ListAllDownloads()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing downloads: %v\n", err)
+		os.Exit(1)
+	}
+
+	found := false
+	for _, d := range downloads {
+		if d.Status != "archived" {
+			continue
+		}
+		found = true
+		var progress float64
+		if d.TotalSize > 0 {
+			progress = float64(d.Downloaded) * 100 / float64(d.TotalSize)
+		}
+		fmt.Printf("%s  %5.1f%%  %s\n", truncateID(d.ID), progress, d.DestPath)
+	}
+	if !found {
+		fmt.Println("No archived downloads.")
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(restorePartialCmd)
+}
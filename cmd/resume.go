@@ -4,35 +4,154 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/engine/types"
 )
 
 var resumeCmd = &cobra.Command{
 	Use:   "resume <ID>",
 	Short: "Resume a paused download",
-	Long:  `Resume a paused download by its ID. Use --all to resume all paused downloads.`,
-	Args:  cobra.MaximumNArgs(1),
+	Long: `Resume a paused download by its ID. Use --all to resume all paused downloads,
+or --from-host to resume every paused download from one host.
+
+--all takes filters to resume only part of the backlog: --status picks paused
+or failed downloads (or both), --from-host the ones from one host and --since
+the ones added recently.`,
+	Example: `  surge resume 1a2b3c4d
+  surge resume --all
+  surge resume --all --status failed --from-host example.com --since 24h`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeDownloadIDs(false, "paused", "queued", "error"),
 	Run: func(cmd *cobra.Command, args []string) {
 		mustInitializeGlobalState()
 
 		all, _ := cmd.Flags().GetBool("all")
+		fromHost, _ := cmd.Flags().GetString("from-host")
+		statuses, _ := cmd.Flags().GetStringSlice("status")
+		since, _ := cmd.Flags().GetString("since")
 
-		if !all && len(args) == 0 {
-			fmt.Fprintln(os.Stderr, "Error: provide a download ID or use --all")
+		if all {
+			if len(args) > 0 {
+				fmt.Fprintln(os.Stderr, "Error: --all cannot be combined with an ID")
+				os.Exit(1)
+			}
+			filter, err := newResumeFilter(statuses, fromHost, since, time.Now())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			resumeAll(filter)
+			return
+		}
+		if len(statuses) > 0 || since != "" {
+			fmt.Fprintln(os.Stderr, "Error: --status and --since need --all")
 			os.Exit(1)
 		}
 
-		if all {
-			fmt.Println("Resuming all downloads is not yet implemented for running server.")
+		if fromHost != "" {
+			if len(args) > 0 {
+				fmt.Fprintln(os.Stderr, "Error: --from-host cannot be combined with an ID")
+				os.Exit(1)
+			}
+			ExecuteHostAction(fromHost, "/resume", "Resumed")
 			return
 		}
 
+		if len(args) == 0 {
+			fmt.Fprintln(os.Stderr, "Error: provide a download ID, --from-host or --all")
+			os.Exit(1)
+		}
+
 		ExecuteAPIAction(args[0], "/resume", http.MethodPost, "Resumed download")
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(resumeCmd)
-	resumeCmd.Flags().Bool("all", false, "Resume all paused downloads")
+	resumeCmd.Flags().Bool("all", false, "Resume all paused downloads, or those matching the filters")
+	resumeCmd.Flags().String("from-host", "", "Resume every paused download from this host")
+	resumeCmd.Flags().StringSlice("status", nil, "With --all, resume downloads with these statuses: paused, failed (default paused)")
+	resumeCmd.Flags().String("since", "", "With --all, only resume downloads added within this age (e.g. 24h, 7d) or since a date")
+}
+
+// resumeFilter picks the downloads surge resume --all resumes
+type resumeFilter struct {
+	Statuses []string // Download statuses, "paused" and/or "error"
+	Host     string   // Only downloads from this host, "" = any
+	Since    int64    // Only downloads added at or after this Unix time, 0 = any
+}
+
+// newResumeFilter builds the filter of surge resume --all from its flags.
+// Failed downloads may be given as failed or error.
+func newResumeFilter(statuses []string, host, since string, now time.Time) (resumeFilter, error) {
+	f := resumeFilter{Host: host}
+	for _, s := range statuses {
+		switch s = strings.ToLower(strings.TrimSpace(s)); s {
+		case "paused":
+		case "failed", "error":
+			s = "error"
+		default:
+			return f, fmt.Errorf("--status takes paused or failed, got %q", s)
+		}
+		if !slices.Contains(f.Statuses, s) {
+			f.Statuses = append(f.Statuses, s)
+		}
+	}
+	if len(f.Statuses) == 0 {
+		f.Statuses = []string{"paused"}
+	}
+	if since != "" {
+		t, err := core.ParseSince(since, now)
+		if err != nil {
+			return f, fmt.Errorf("--since: %w", err)
+		}
+		f.Since = t
+	}
+	return f, nil
+}
+
+// match reports whether d is one for surge resume --all to resume
+func (f resumeFilter) match(d types.DownloadStatus) bool {
+	if !slices.Contains(f.Statuses, d.Status) {
+		return false
+	}
+	if f.Host != "" && !core.FromHost(d.URL, f.Host) {
+		return false
+	}
+	return f.Since == 0 || d.AddedAt >= f.Since
+}
+
+// resumeAll resumes the downloads of the running server that match filter.
+// Any that fail to resume make the command exit non-zero.
+func resumeAll(filter resumeFilter) {
+	baseURL, token, err := resolveAPIConnection(true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to Surge server: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	downloads, err := GetRemoteDownloads(baseURL, token)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing downloads: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	resumed := 0
+	for _, d := range downloads {
+		if !filter.match(d) {
+			continue
+		}
+		if err := resumeOnServer(baseURL, token, d.ID); err != nil {
+			fmt.Fprintf(os.Stderr, "Error resuming %s: %v\n", d.ID, err)
+			setExitCode(exitCodeFor(err))
+			continue
+		}
+		resumed++
+	}
+
+	infof("Resumed %d downloads\n", resumed)
 }
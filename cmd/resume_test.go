@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestResumeFilter(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	recent, old := now.Add(-2*time.Hour).Unix(), now.AddDate(0, 0, -3).Unix()
+	downloads := []types.DownloadStatus{
+		{ID: "paused-new", URL: "https://example.com/a", Status: "paused", AddedAt: recent},
+		{ID: "failed-new", URL: "https://example.com:8443/b", Status: "error", AddedAt: recent},
+		{ID: "failed-old", URL: "https://example.com/c", Status: "error", AddedAt: old},
+		{ID: "failed-other", URL: "https://other.org/d", Status: "error", AddedAt: recent},
+		{ID: "running", URL: "https://example.com/e", Status: "downloading", AddedAt: recent},
+	}
+	matching := func(f resumeFilter) []string {
+		var ids []string
+		for _, d := range downloads {
+			if f.match(d) {
+				ids = append(ids, d.ID)
+			}
+		}
+		return ids
+	}
+
+	tests := []struct {
+		statuses []string
+		host     string
+		since    string
+		want     []string
+	}{
+		{want: []string{"paused-new"}},
+		{statuses: []string{"failed"}, host: "example.com", since: "24h", want: []string{"failed-new"}},
+		{statuses: []string{"paused", "error"}, since: "7d", want: []string{"paused-new", "failed-new", "failed-old", "failed-other"}},
+		{statuses: []string{"failed"}, host: "https://other.org/", want: []string{"failed-other"}},
+	}
+	for _, tt := range tests {
+		f, err := newResumeFilter(tt.statuses, tt.host, tt.since, now)
+		if err != nil {
+			t.Fatalf("newResumeFilter(%v, %q, %q): %v", tt.statuses, tt.host, tt.since, err)
+		}
+		if got := matching(f); !slices.Equal(got, tt.want) {
+			t.Errorf("filter %+v matched %v, want %v", f, got, tt.want)
+		}
+	}
+
+	if _, err := newResumeFilter([]string{"completed"}, "", "", now); err == nil {
+		t.Error("--status completed accepted, want an error")
+	}
+	if _, err := newResumeFilter(nil, "", "yesterday", now); err == nil {
+		t.Error("--since yesterday accepted, want an error")
+	}
+}
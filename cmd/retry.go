@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/processing"
+)
+
+var retryCmd = &cobra.Command{
+	Use:   "retry <ID>",
+	Short: "Start a failed download over",
+	Long: `Start a failed download over from the beginning, in place: it keeps its ID,
+destination, tags and per-download overrides, and joins the end of the queue.
+Use --failed to retry every failed download.
+
+With --probe the URL is checked first, and a download whose server still
+fails is left failed; otherwise it starts from what the new probe found.
+Without it, the download starts from what was found when it was added.`,
+	Example: `  surge retry 1a2b3c4d
+  surge retry --probe 1a2b3c4d
+  surge retry --failed`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeDownloadIDs(false, "error"),
+	Run: func(cmd *cobra.Command, args []string) {
+		failed, _ := cmd.Flags().GetBool("failed")
+		probe, _ := cmd.Flags().GetBool("probe")
+
+		if failed == (len(args) > 0) {
+			fmt.Fprintln(os.Stderr, "Error: give a download ID or --failed")
+			os.Exit(1)
+		}
+
+		mustInitializeGlobalState()
+
+		baseURL, token, err := resolveAPIConnection(true)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to connect to Surge server: %v\n", err)
+			os.Exit(exitCodeFor(err))
+		}
+
+		if !failed {
+			id, err := resolveDownloadID(args[0])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to resolve download ID: %v\n", err)
+				os.Exit(1)
+			}
+			if err := retryOnServer(baseURL, token, id, probe); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitCodeFor(err))
+			}
+			infof("Retrying download\n")
+			return
+		}
+
+		downloads, err := GetRemoteDownloads(baseURL, token)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing downloads: %v\n", err)
+			os.Exit(exitCodeFor(err))
+		}
+		retried := 0
+		for _, d := range downloads {
+			if d.Status != "error" {
+				continue
+			}
+			if err := retryOnServer(baseURL, token, d.ID, probe); err != nil {
+				fmt.Fprintf(os.Stderr, "Error retrying %s: %v\n", truncateID(d.ID), err)
+				setExitCode(exitCodeFor(err))
+				continue
+			}
+			retried++
+		}
+		infof("Retrying %d downloads\n", retried)
+	},
+}
+
+// retryOnServer starts failed download id over on the running server. A
+// probe that fails is reported as processing.ErrStillFailing.
+func retryOnServer(baseURL, token, id string, probe bool) error {
+	endpoint := "/retry?id=" + url.QueryEscape(id) + "&probe=" + strconv.FormatBool(probe)
+	resp, err := doAPIRequest(http.MethodPost, baseURL, token, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusBadGateway:
+		return fmt.Errorf("%w (%s)", processing.ErrStillFailing, core.ReadAPIError(resp).Detail)
+	}
+	return fmt.Errorf("server error: %s - %s", resp.Status, core.ReadAPIError(resp).Detail)
+}
+
+func init() {
+	rootCmd.AddCommand(retryCmd)
+	retryCmd.Flags().Bool("failed", false, "Retry every failed download")
+	retryCmd.Flags().Bool("probe", false, "Check the URL first and leave the download failed if it still fails")
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/processing"
+)
+
+type retryService struct {
+	fakeRemoteDownloadService
+	retried []string
+}
+
+func (s *retryService) Retry(id string, probe bool) error {
+	switch id {
+	case "running":
+		return state.ErrNotFailed
+	case "dead":
+		return fmt.Errorf("%w: unexpected status code: 404", processing.ErrStillFailing)
+	}
+	s.retried = append(s.retried, fmt.Sprintf("%s probe=%v", id, probe))
+	return nil
+}
+
+func TestRetryEndpoint(t *testing.T) {
+	svc := &retryService{}
+	mux := http.NewServeMux()
+	registerHTTPRoutes(mux, 0, "", svc)
+
+	for query, want := range map[string]int{
+		"id=a":               http.StatusOK,
+		"id=b&probe=true":    http.StatusOK,
+		"":                   http.StatusBadRequest,
+		"id=running":         http.StatusConflict,
+		"id=dead&probe=true": http.StatusBadGateway,
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/retry?"+query, nil))
+		if rec.Code != want {
+			t.Errorf("POST /retry?%s = %d, want %d", query, rec.Code, want)
+		}
+	}
+	slices.Sort(svc.retried)
+	if want := []string{"a probe=false", "b probe=true"}; !slices.Equal(svc.retried, want) {
+		t.Errorf("retried = %q, want %q", svc.retried, want)
+	}
+}
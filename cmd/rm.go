@@ -13,8 +13,16 @@ var rmCmd = &cobra.Command{
 	Use:     "rm <ID>",
 	Aliases: []string{"kill"},
 	Short:   "Remove a download",
-	Long:    `Remove a download by its ID. Use --clean to remove all completed downloads.`,
-	Args:    cobra.MaximumNArgs(1),
+	Long: `Remove a download by its ID. Use --clean to remove all completed downloads.
+
+Removed downloads go to the trash for trash_retention_days, from where
+'surge trash restore <ID>' brings them back. --permanent skips the trash.
+
+With --keep-partial the download's partial data is kept and it is archived
+instead: it leaves the list, and 'surge restore-partial <ID>' brings it back
+and resumes it from where it stopped.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeDownloadIDs(false),
 	Run: func(cmd *cobra.Command, args []string) {
 		mustInitializeGlobalState()
 
@@ -36,6 +44,14 @@ var rmCmd = &cobra.Command{
 			return
 		}
 
+		if keepPartial, _ := cmd.Flags().GetBool("keep-partial"); keepPartial {
+			ExecuteAPIAction(args[0], "/archive", http.MethodPost, "Removed download, kept its partial data")
+			return
+		}
+		if permanent, _ := cmd.Flags().GetBool("permanent"); permanent {
+			ExecuteAPIAction(args[0], "/delete?permanent=true", http.MethodPost, "Deleted download")
+			return
+		}
 		ExecuteAPIAction(args[0], "/delete", http.MethodPost, "Removed download")
 	},
 }
@@ -43,4 +59,6 @@ var rmCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(rmCmd)
 	rmCmd.Flags().Bool("clean", false, "Remove all completed downloads")
+	rmCmd.Flags().Bool("keep-partial", false, "Keep the partial data so the download can be restored later")
+	rmCmd.Flags().Bool("permanent", false, "Delete at once instead of moving the download to the trash")
 }
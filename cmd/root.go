@@ -3,12 +3,16 @@ package cmd
 import (
 	"context"
 	"crypto/subtle"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -19,11 +23,14 @@ import (
 	"github.com/surge-downloader/surge/internal/core"
 	"github.com/surge-downloader/surge/internal/download"
 	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/relay"
 	"github.com/surge-downloader/surge/internal/engine/state"
 	"github.com/surge-downloader/surge/internal/engine/types"
 	"github.com/surge-downloader/surge/internal/processing"
+	"github.com/surge-downloader/surge/internal/trace"
 	"github.com/surge-downloader/surge/internal/tui"
 	"github.com/surge-downloader/surge/internal/utils"
+	"github.com/surge-downloader/surge/internal/websocket"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/google/uuid"
@@ -44,9 +51,14 @@ var pendingEnqueue int32
 
 // Command line flags
 var (
-	verbose     bool
-	globalHost  string
-	globalToken string
+	verbose       bool
+	globalHost    string
+	globalToken   string
+	globalProfile string
+	globalCACert  string
+	globalJSON    bool // Print JSON instead of tables and messages
+	globalQuiet   bool // Print errors and requested data only
+	noProgress    bool // Leave out the started, queued, paused and resumed lines
 )
 
 // Globals for Unified Backend
@@ -128,8 +140,18 @@ func startLifecycleEventWorker(service core.DownloadService, mgr *processing.Lif
 	if err != nil {
 		return nil, err
 	}
-	go mgr.StartEventWorker(managerStream)
-	return managerCleanup, nil
+	workerDone := make(chan struct{})
+	go func() {
+		defer close(workerDone)
+		mgr.StartEventWorker(managerStream)
+	}()
+	// Let the worker persist what is left in the stream and finish hashing
+	// and post-processing before the caller moves on to closing the database
+	return func() {
+		managerCleanup()
+		<-workerDone
+		mgr.WaitPostProcessing()
+	}, nil
 }
 
 func currentLifecycle() *processing.LifecycleManager {
@@ -218,6 +240,10 @@ func ensureGlobalLocalServiceAndLifecycle() error {
 		})
 
 		localService.SetLifecycleHooks(lifecycle.Pause, lifecycle.Resume, lifecycle.ResumeBatch)
+		localService.SetRestorePartialHook(lifecycle.RestorePartial)
+		localService.SetRestoreTrashedHook(lifecycle.RestoreTrashed)
+		localService.SetRetryHook(lifecycle.Retry)
+		startWebhooks(localService)
 	} else {
 		_, err := ensureLocalLifecycle(GlobalService, currentPoolConfigs)
 		return err
@@ -251,6 +277,7 @@ func recordPreflightDownloadError(url, outPath string, err error) {
 		DestPath: destPath,
 		Filename: filename,
 		Status:   "error",
+		TraceID:  trace.NewID(),
 	}
 	if addErr := state.AddToMasterList(entry); addErr != nil {
 		utils.Debug("Failed to persist preflight download error for %s: %v", url, addErr)
@@ -261,6 +288,7 @@ func recordPreflightDownloadError(url, outPath string, err error) {
 			Filename:   filename,
 			DestPath:   destPath,
 			Err:        err,
+			TraceID:    entry.TraceID,
 		})
 	}
 }
@@ -297,6 +325,12 @@ var rootCmd = &cobra.Command{
 		// Set global verbose mode
 		utils.SetVerbose(verbose)
 
+		// Select the profile before anything reads settings or state
+		if err := config.SetProfile(resolveProfile()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
 		// Initialize Global Progress Channel
 		GlobalProgressCh = make(chan any, 100)
 
@@ -333,6 +367,7 @@ var rootCmd = &cobra.Command{
 		}()
 
 		mustInitializeGlobalState()
+		mustConfigureStateSecrets()
 		resetGlobalEnqueueContext()
 
 		startupIntegrityMessage = runStartupIntegrityCheck()
@@ -353,35 +388,52 @@ var rootCmd = &cobra.Command{
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
+		listener, certFile, err := enableServerTLS(listener)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer removeActiveTLSCert()
 
 		// Save port for browser extension AND CLI discovery
 		saveActivePort(port)
 		defer removeActivePort()
 
 		// Start HTTP server in background (reuse the listener)
-		go startHTTPServer(listener, port, outputDir, GlobalService, "")
+		go startHTTPServer(listener, nil, port, outputDir, GlobalService, "")
+		defer startMDNS(port, certFile != "")()
+		defer startWatchFolders(GlobalService)()
+
+		statusPort, _ := cmd.Flags().GetInt("status-port")
+		if _, err := startStatusPage(GlobalService, statusPort); err != nil {
+			utils.Debug("Status page disabled: %v", err)
+		}
+
+		grpcPort, _ := cmd.Flags().GetInt("grpc-port")
+		if _, err := startGRPCServer(grpcPort, outputDir, GlobalService, ""); err != nil {
+			utils.Debug("gRPC API disabled: %v", err)
+		}
 
 		// Queue initial downloads if any
 		atomic.AddInt32(&pendingEnqueue, 1)
 		go func() {
 			defer atomic.AddInt32(&pendingEnqueue, -1)
-			var urls []string
-			urls = append(urls, args...)
+			if len(args) > 0 {
+				processDownloads(args, outputDir, 0) // 0 port = internal direct add
+			}
 
 			if batchFile != "" {
 				fileURLs, err := utils.ReadURLsFromFile(batchFile)
 				if err != nil {
 					fmt.Fprintf(os.Stderr, "Error reading batch file: %v\n", err)
-				} else {
-					urls = append(urls, fileURLs...)
+				} else if len(fileURLs) > 0 {
+					processBatch(fileURLs, outputDir)
 				}
 			}
-
-			if len(urls) > 0 {
-				processDownloads(urls, outputDir, 0) // 0 port = internal direct add
-			}
 		}()
 
+		startFirstRunCalibration(downloadsStarting(args, batchFile, noResume))
+
 		// Start TUI (default mode)
 		startTUI(port, exitWhenDone, noResume)
 	},
@@ -399,15 +451,29 @@ func runStartupIntegrityCheck() string {
 	}
 
 	// Validate integrity of paused/queued downloads before auto-resume.
-	// This removes entries whose .surge files are missing/tampered and
-	// also cleans orphan .surge files that no longer have DB entries.
+	// This removes entries whose .surge files were tampered with.
 	if removed, err := state.ValidateIntegrity(); err != nil {
 		msg := fmt.Sprintf("Startup integrity check failed: %v", err)
 		return msg
 	} else if removed > 0 {
-		msg := fmt.Sprintf("Startup integrity check: removed %d corrupted/orphaned downloads", removed)
+		msg := fmt.Sprintf("Startup integrity check: removed %d corrupted downloads", removed)
 		return msg
 	}
+
+	// Downloads past the trash retention are deleted for good
+	if purged, err := processing.PurgeTrash(getSettings()); err != nil {
+		utils.Debug("Startup: trash purge failed: %v", err)
+	} else if purged > 0 {
+		utils.Debug("Startup: purged %d expired downloads from the trash", purged)
+	}
+
+	// Orphans are only reported; removing them is up to the user
+	if orphans, err := processing.FindOrphans(getSettings()); err != nil {
+		utils.Debug("Startup: orphan scan failed: %v", err)
+	} else if !orphans.Empty() {
+		return fmt.Sprintf("Startup integrity check: found %d orphaned files and %d downloads with missing files; run 'surge prune --orphans' to clean up",
+			len(orphans.Files), len(orphans.Entries))
+	}
 	msg := "Startup integrity check: no issues found"
 	utils.Debug("%s", msg)
 	return msg
@@ -420,7 +486,7 @@ func startTUI(port int, exitWhenDone bool, noResume bool) {
 
 	m := tui.InitialRootModel(port, Version, GlobalService, currentLifecycle(), noResume)
 	m = m.WithEnqueueContext(currentEnqueueContext(), currentEnqueueCancel())
-	m.ServerHost = serverBindHost
+	m.ServerHost = serverBindAddress()
 	if m.ServerHost == "" {
 		m.ServerHost = "127.0.0.1"
 	}
@@ -497,6 +563,21 @@ func startTUI(port int, exitWhenDone bool, noResume bool) {
 
 const serverBindHost = "0.0.0.0"
 
+// bindAddressFlag is --bind, which overrides the bind_address setting
+var bindAddressFlag string
+
+// serverBindAddress returns the address the API, gRPC and status page listen
+// on: --bind, else the bind_address setting, else every interface
+func serverBindAddress() string {
+	if addr := strings.TrimSpace(bindAddressFlag); addr != "" {
+		return addr
+	}
+	if addr := getSettings().Server.BindAddress; addr != "" {
+		return addr
+	}
+	return serverBindHost
+}
+
 // StartHeadlessConsumer starts a goroutine to consume progress messages and log to stdout
 func StartHeadlessConsumer() {
 	go func() {
@@ -510,29 +591,49 @@ func StartHeadlessConsumer() {
 		}
 		defer cleanup()
 
+		// Progress lines are left out with --no-progress, and all but
+		// errors with --quiet
+		progress := !noProgress && !globalQuiet
 		for msg := range stream {
 			switch m := msg.(type) {
 			case events.DownloadStartedMsg:
-				fmt.Printf("Started: %s [%s]\n", m.Filename, truncateID(m.DownloadID))
+				if progress {
+					fmt.Printf("Started: %s [%s]\n", m.Filename, truncateID(m.DownloadID))
+				}
 			case events.DownloadCompleteMsg:
 				atomic.AddInt32(&activeDownloads, -1)
-				fmt.Printf("Completed: %s [%s] (in %s)\n", m.Filename, truncateID(m.DownloadID), m.Elapsed)
+				infof("Completed: %s [%s] (in %s)\n", m.Filename, truncateID(m.DownloadID), m.Elapsed)
 			case events.DownloadErrorMsg:
 				atomic.AddInt32(&activeDownloads, -1)
-				fmt.Printf("Error: %s [%s]: %v\n", m.Filename, truncateID(m.DownloadID), m.Err)
+				setExitCode(exitCodeFor(m.Err))
+				fmt.Fprintf(os.Stderr, "Error: %s [%s]: %v\n", m.Filename, truncateID(m.DownloadID), m.Err)
 			case events.DownloadQueuedMsg:
-				fmt.Printf("Queued: %s [%s]\n", m.Filename, truncateID(m.DownloadID))
+				if progress {
+					fmt.Printf("Queued: %s [%s]\n", m.Filename, truncateID(m.DownloadID))
+				}
 			case events.DownloadPausedMsg:
-				fmt.Printf("Paused: %s [%s]\n", m.Filename, truncateID(m.DownloadID))
+				if progress {
+					fmt.Printf("Paused: %s [%s]\n", m.Filename, truncateID(m.DownloadID))
+				}
 			case events.DownloadResumedMsg:
-				fmt.Printf("Resumed: %s [%s]\n", m.Filename, truncateID(m.DownloadID))
+				if progress {
+					fmt.Printf("Resumed: %s [%s]\n", m.Filename, truncateID(m.DownloadID))
+				}
 			case events.DownloadRemovedMsg:
-				fmt.Printf("Removed: %s [%s]\n", m.Filename, truncateID(m.DownloadID))
+				infof("Removed: %s [%s]\n", m.Filename, truncateID(m.DownloadID))
 			}
 		}
 	}()
 }
 
+// resolveProfile returns the profile selected by --profile or SURGE_PROFILE
+func resolveProfile() string {
+	if p := strings.TrimSpace(globalProfile); p != "" {
+		return p
+	}
+	return strings.TrimSpace(os.Getenv("SURGE_PROFILE"))
+}
+
 // truncateID shortens a UUID to its first 8 characters for display
 func truncateID(id string) string {
 	if len(id) > 8 {
@@ -543,9 +644,9 @@ func truncateID(id string) string {
 
 // findAvailablePort tries ports starting from 'start' until one is available
 func findAvailablePort(start int) (int, net.Listener) {
-	bindHost := serverBindHost
+	bindHost := serverBindAddress()
 	for port := start; port < start+100; port++ {
-		ln, err := net.Listen("tcp", fmt.Sprintf("%s:%d", bindHost, port))
+		ln, err := net.Listen("tcp", net.JoinHostPort(bindHost, strconv.Itoa(port)))
 		if err == nil {
 			return port, ln
 		}
@@ -554,9 +655,12 @@ func findAvailablePort(start int) (int, net.Listener) {
 }
 
 func bindServerListener(portFlag int) (int, net.Listener, error) {
-	bindHost := serverBindHost
+	bindHost := serverBindAddress()
+	if err := config.ValidateBindAddress(bindHost); err != nil {
+		return 0, nil, err
+	}
 	if portFlag > 0 {
-		ln, err := net.Listen("tcp", fmt.Sprintf("%s:%d", bindHost, portFlag))
+		ln, err := net.Listen("tcp", net.JoinHostPort(bindHost, strconv.Itoa(portFlag)))
 		if err != nil {
 			return 0, nil, fmt.Errorf("could not bind to port %d: %w", portFlag, err)
 		}
@@ -587,7 +691,9 @@ func removeActivePort() {
 }
 
 // startHTTPServer starts the HTTP server using an existing listener
-func startHTTPServer(ln net.Listener, port int, defaultOutputDir string, service core.DownloadService, tokenOverride string) {
+// startHTTPServer serves the API on ln with the token and, when socketLn is
+// set, on that unix socket without one. Either listener may be nil.
+func startHTTPServer(ln, socketLn net.Listener, port int, defaultOutputDir string, service core.DownloadService, tokenOverride string) {
 	authToken := strings.TrimSpace(tokenOverride)
 	if authToken == "" {
 		authToken = ensureAuthToken()
@@ -597,23 +703,77 @@ func startHTTPServer(ln net.Listener, port int, defaultOutputDir string, service
 
 	mux := http.NewServeMux()
 	registerHTTPRoutes(mux, port, defaultOutputDir, service)
+	if settings := getSettings(); settings.Distributed.ServeRelay {
+		client := relay.NewClient(settings.Network.ProxyURL, settings.Distributed.RelayPrivateTargets)
+		mux.Handle(relay.Path, relay.Handler(authToken, client))
+	}
+
+	// Wrap mux with Auth, versioning, tracing and CORS (CORS outermost to
+	// ensure 401/403 include headers; tracing outside auth so rejected requests
+	// get an ID too; versioning outside auth so /v1 paths are checked like the
+	// bare ones)
+	handler := corsMiddleware(traceMiddleware(versionMiddleware(authMiddleware(authToken, mux))))
+
+	if socketLn != nil {
+		socketServer := &http.Server{Handler: traceMiddleware(versionMiddleware(markSocketRequests(mux)))}
+		if ln == nil {
+			serveHTTP(socketServer, socketLn)
+			return
+		}
+		go serveHTTP(socketServer, socketLn)
+	}
+	serveHTTP(&http.Server{Handler: handler}, ln)
+}
+
+// socketRequestKey marks the context of requests that came over the unix socket
+type socketRequestKey struct{}
 
-	// Wrap mux with Auth and CORS (CORS outermost to ensure 401/403 include headers)
-	handler := corsMiddleware(authMiddleware(authToken, mux))
+// markSocketRequests records on each request that it came over the unix
+// socket, for handlers that allow more to local users than to token holders
+func markSocketRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), socketRequestKey{}, true)))
+	})
+}
 
-	server := &http.Server{Handler: handler}
+// viaUnixSocket reports whether r came over the unix socket
+func viaUnixSocket(r *http.Request) bool {
+	socket, _ := r.Context().Value(socketRequestKey{}).(bool)
+	return socket
+}
+
+func serveHTTP(server *http.Server, ln net.Listener) {
 	if err := server.Serve(ln); err != nil && err != http.ErrServerClosed {
 		utils.Debug("HTTP server error: %v", err)
 	}
 }
 
+// corsMiddleware lets browsers call the API from any origin, or only from the
+// allowed_origins setting and the built-in extension and localhost origins
+// when it lists some; requests from other origins are refused. Requests
+// without an Origin, such as the CLI's, and the dashboard's own same-origin
+// requests always pass.
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		allowed := getSettings().Server.AllowedOrigins
+		switch {
+		case len(allowed) == 0 || slices.Contains(allowed, "*"):
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		case origin == "" || isSameOrigin(origin, r):
+		case config.OriginAllowed(origin, allowed):
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+		default:
+			httpError(w, "Origin not allowed", http.StatusForbidden)
+			return
+		}
+
 		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS, PUT, PATCH")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, Access-Control-Allow-Private-Network")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, Access-Control-Allow-Private-Network, Traceparent, "+trace.Header+", "+apiVersionHeader+", "+idempotencyKeyHeader)
 		w.Header().Set("Access-Control-Allow-Private-Network", "true")
+		w.Header().Set("Access-Control-Expose-Headers", nextCursorHeader+", "+trace.Header+", "+apiVersionHeader+", "+idempotentReplayHeader)
 
 		// Handle preflight requests
 		if r.Method == "OPTIONS" {
@@ -625,10 +785,73 @@ func corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+func isSameOrigin(origin string, r *http.Request) bool {
+	u, err := url.Parse(origin)
+	return err == nil && u.Host != "" && u.Host == r.Host
+}
+
+// traceMiddleware assigns every request a trace ID, honoring an incoming
+// traceparent or X-Trace-Id header, and returns it in the X-Trace-Id response
+// header so a failed call can be matched to the daemon's debug log
+func traceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if traceID, spanID, ok := trace.ParseTraceparent(r.Header.Get("Traceparent")); ok {
+			ctx = trace.WithParent(ctx, traceID, spanID)
+		} else if id := strings.ToLower(r.Header.Get(trace.Header)); trace.ValidID(id) {
+			ctx = trace.WithID(ctx, id)
+		}
+		ctx, span := trace.Start(ctx, r.Method+" "+r.URL.Path)
+		span.SetAttr("http.method", r.Method)
+		span.SetAttr("url.path", r.URL.Path)
+		w.Header().Set(trace.Header, span.TraceID())
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		span.SetAttr("http.status_code", strconv.Itoa(rec.status))
+		var err error
+		if rec.status >= http.StatusInternalServerError {
+			err = errors.New(http.StatusText(rec.status))
+		}
+		if rec.status >= http.StatusBadRequest {
+			trace.Debug(ctx, "HTTP %s %s -> %d", r.Method, r.URL.Path, rec.status)
+		}
+		span.End(err)
+	})
+}
+
+// statusRecorder captures the response status while still letting handlers
+// stream (SSE) through the underlying writer
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
 func authMiddleware(token string, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Allow health check without auth
-		if r.URL.Path == "/health" {
+		// Allow health check, API docs and the dashboard page without auth;
+		// relay requests carry their own signature. /health tells more to
+		// requests that do carry the token.
+		if r.URL.Path == "/health" || r.URL.Path == relay.Path || r.URL.Path == openAPIPath || strings.HasPrefix(r.URL.Path, apiDocsPath) || r.URL.Path == dashboardPath {
+			if bearerTokenMatches(r, token) {
+				r = markAuthorized(r)
+			}
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -639,22 +862,53 @@ func authMiddleware(token string, next http.Handler) http.Handler {
 			return
 		}
 
-		// Check for Authorization header
-		authHeader := r.Header.Get("Authorization")
-		if authHeader != "" {
-			if strings.HasPrefix(authHeader, "Bearer ") {
-				providedToken := strings.TrimPrefix(authHeader, "Bearer ")
-				if len(providedToken) == len(token) && subtle.ConstantTimeCompare([]byte(providedToken), []byte(token)) == 1 {
-					next.ServeHTTP(w, r)
+		if bearerTokenMatches(r, token) {
+			next.ServeHTTP(w, markAuthorized(r))
+			return
+		}
+
+		// Browsers can't set headers on a WebSocket, so the token may come
+		// as a "bearer.<token>" subprotocol instead
+		if websocket.IsUpgrade(r) {
+			for _, p := range websocket.Subprotocols(r) {
+				if providedToken, ok := strings.CutPrefix(p, wsTokenProtocolPrefix); ok && tokenMatches(providedToken, token) {
+					next.ServeHTTP(w, markAuthorized(r))
 					return
 				}
 			}
 		}
 
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		httpError(w, "Unauthorized", http.StatusUnauthorized)
 	})
 }
 
+// bearerTokenMatches reports whether r's Authorization header carries token
+func bearerTokenMatches(r *http.Request, token string) bool {
+	providedToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && tokenMatches(providedToken, token)
+}
+
+// authorizedKey marks the context of requests that carried the token
+type authorizedKey struct{}
+
+func markAuthorized(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), authorizedKey{}, true))
+}
+
+// hasAPIAccess reports whether r carried the token or came over the unix
+// socket, for routes open to anyone that tell more to those who may use the API
+func hasAPIAccess(r *http.Request) bool {
+	authorized, _ := r.Context().Value(authorizedKey{}).(bool)
+	return authorized || viaUnixSocket(r)
+}
+
+// wsTokenProtocolPrefix marks the WebSocket subprotocol that carries the token
+const wsTokenProtocolPrefix = "bearer."
+
+func tokenMatches(provided, token string) bool {
+	return len(provided) == len(token) && subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1
+}
+
 func ensureAuthToken() string {
 	stateTokenFile := filepath.Join(config.GetStateDir(), "token")
 	if token, err := readTokenFromFile(stateTokenFile); err == nil {
@@ -704,7 +958,16 @@ type DownloadRequest struct {
 	Mirrors              []string          `json:"mirrors,omitempty"`
 	SkipApproval         bool              `json:"skip_approval,omitempty"` // Extension validated request, skip TUI prompt
 	Headers              map[string]string `json:"headers,omitempty"`       // Custom HTTP headers from browser (cookies, auth, etc.)
+	Tags                 []string          `json:"tags,omitempty"`          // Labels such as "work" or "iso"
+	Category             string            `json:"category,omitempty"`      // Sort into this category's folder instead of matching rules
 	IsExplicitCategory   bool              `json:"is_explicit_category,omitempty"`
+	DownloadArchive      bool              `json:"download_archive,omitempty"` // Skip the URL if it has completed before
+	Connections          int               `json:"connections,omitempty"`      // Connections per host for this download
+	SpeedLimit           int64             `json:"speed_limit,omitempty"`      // Bytes/sec for this download, 0 = unlimited
+	ChunkSize            int64             `json:"chunk_size,omitempty"`       // Minimum bytes per chunk for this download
+	UserAgent            string            `json:"user_agent,omitempty"`       // User-Agent for this download
+	Tries                int               `json:"tries,omitempty"`            // Attempts per chunk for this download
+	IdempotencyKey       string            `json:"idempotency_key,omitempty"`  // Alternative to the Idempotency-Key header
 }
 
 func handleDownload(w http.ResponseWriter, r *http.Request, defaultOutputDir string, service core.DownloadService) {
@@ -712,18 +975,18 @@ func handleDownload(w http.ResponseWriter, r *http.Request, defaultOutputDir str
 	if r.Method == http.MethodGet {
 		id := r.URL.Query().Get("id")
 		if id == "" {
-			http.Error(w, "Missing id parameter", http.StatusBadRequest)
+			httpError(w, "Missing id parameter", http.StatusBadRequest)
 			return
 		}
 
 		if service == nil {
-			http.Error(w, "Service unavailable", http.StatusInternalServerError)
+			httpError(w, "Service unavailable", http.StatusInternalServerError)
 			return
 		}
 
 		status, err := service.GetStatus(id)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusNotFound)
+			httpError(w, err.Error(), http.StatusNotFound)
 			return
 		}
 
@@ -732,37 +995,68 @@ func handleDownload(w http.ResponseWriter, r *http.Request, defaultOutputDir str
 	}
 
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		httpError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if draining.Load() {
+		httpError(w, "Surge is shutting down", http.StatusServiceUnavailable)
 		return
 	}
-
-	settings := getSettings()
 
 	var req DownloadRequest
 	if err := decodeJSONBody(r, &req); err != nil {
-		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		httpError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	if req.URL == "" {
-		http.Error(w, "URL is required", http.StatusBadRequest)
+	outcome, err := addDownload(r.Context(), req, defaultOutputDir, service)
+	if err != nil {
+		writeAPIError(w, err)
 		return
 	}
+	status := http.StatusOK
+	if outcome.Status == "pending_approval" {
+		// Return 202 Accepted to indicate it's pending approval
+		status = http.StatusAccepted
+	}
+	writeJSONResponse(w, status, outcome)
+}
+
+// downloadOutcome is what became of a download request
+type downloadOutcome struct {
+	Status  string `json:"status"` // queued, archived or pending_approval
+	Message string `json:"message"`
+	ID      string `json:"id,omitempty"`
+	URL     string `json:"url,omitempty"` // Of an archived download
+}
+
+// addDownload queues req, or hands it to the TUI for approval. Errors are
+// *core.APIError naming the status and code to answer with.
+func addDownload(ctx context.Context, req DownloadRequest, defaultOutputDir string, service core.DownloadService) (downloadOutcome, error) {
+	settings := getSettings()
+
+	if req.URL == "" {
+		return downloadOutcome{}, apiError(http.StatusBadRequest, core.CodeBadRequest, "URL is required")
+	}
 
 	if strings.Contains(req.Path, "..") || strings.Contains(req.Filename, "..") {
-		http.Error(w, "Invalid path", http.StatusBadRequest)
-		return
+		return downloadOutcome{}, apiError(http.StatusBadRequest, core.CodePathForbidden, "Invalid path")
 	}
 	if strings.Contains(req.Filename, "/") || strings.Contains(req.Filename, "\\") {
-		http.Error(w, "Invalid filename", http.StatusBadRequest)
-		return
+		return downloadOutcome{}, apiError(http.StatusBadRequest, core.CodePathForbidden, "Invalid filename")
+	}
+	if req.Category != "" {
+		cat := config.FindCategory(req.Category, settings.General.Categories)
+		if cat == nil {
+			return downloadOutcome{}, apiError(http.StatusBadRequest, core.CodeBadRequest, "Unknown category: "+req.Category)
+		}
+		req.Category = cat.Name
 	}
 
 	utils.Debug("Received download request: URL=%s, Path=%s", req.URL, req.Path)
 
 	if service == nil {
-		http.Error(w, "Service unavailable", http.StatusInternalServerError)
-		return
+		return downloadOutcome{}, apiError(http.StatusInternalServerError, core.CodeInternal, "Service unavailable")
 	}
 
 	// Prepare output path
@@ -824,62 +1118,68 @@ func handleDownload(w http.ResponseWriter, r *http.Request, defaultOutputDir str
 					Path:     outPath, // Use the path we resolved (default or requested)
 					Mirrors:  mirrorsForAdd,
 					Headers:  req.Headers,
+					Tags:     req.Tags,
+					Category: req.Category,
 				}); err != nil {
-					http.Error(w, "Failed to notify TUI: "+err.Error(), http.StatusInternalServerError)
-					return
+					return downloadOutcome{}, apiError(http.StatusInternalServerError, core.CodeInternal, "Failed to notify TUI: "+err.Error())
 				}
 
-				// Return 202 Accepted to indicate it's pending approval
-				writeJSONResponse(w, http.StatusAccepted, map[string]string{
-					"status":  "pending_approval",
-					"message": "Download request sent to TUI for confirmation",
-					"id":      downloadID, // ID might change if user modifies it, but useful for tracking
-				})
-				return
+				return downloadOutcome{
+					Status:  "pending_approval",
+					Message: "Download request sent to TUI for confirmation",
+					ID:      downloadID, // ID might change if user modifies it, but useful for tracking
+				}, nil
 			} else {
 				// Headless mode check
-				writeJSONResponse(w, http.StatusConflict, map[string]string{
-					"status":  "error",
-					"message": "Download rejected: Duplicate download or approval required (Headless mode)",
-				})
-				return
+				if isDuplicate {
+					return downloadOutcome{}, apiError(http.StatusConflict, core.CodeDuplicate, "Download rejected: Duplicate download (Headless mode)")
+				}
+				return downloadOutcome{}, apiError(http.StatusConflict, core.CodeApprovalRequired, "Download rejected: approval required (Headless mode)")
 			}
 		}
 	}
 
 	lifecycle, err := lifecycleForLocalService(service)
 	if err != nil {
-		http.Error(w, "Failed to initialize lifecycle manager: "+err.Error(), http.StatusInternalServerError)
-		return
+		return downloadOutcome{}, apiError(http.StatusInternalServerError, core.CodeInternal, "Failed to initialize lifecycle manager: "+err.Error())
+	}
+
+	addReq := &processing.DownloadRequest{
+		URL:                urlForAdd,
+		Filename:           req.Filename,
+		Path:               outPath,
+		Mirrors:            mirrorsForAdd,
+		Headers:            req.Headers,
+		Tags:               req.Tags,
+		Category:           req.Category,
+		IsExplicitCategory: req.IsExplicitCategory,
+		SkipApproval:       req.SkipApproval,
+		DownloadArchive:    req.DownloadArchive,
+		Connections:        req.Connections,
+		SpeedLimit:         req.SpeedLimit,
+		ChunkSize:          req.ChunkSize,
+		UserAgent:          req.UserAgent,
+		Tries:              req.Tries,
 	}
-
 	var newID string
 	if lifecycle != nil {
-		newID, err = lifecycle.Enqueue(r.Context(), &processing.DownloadRequest{
-			URL:                urlForAdd,
-			Filename:           req.Filename,
-			Path:               outPath,
-			Mirrors:            mirrorsForAdd,
-			Headers:            req.Headers,
-			IsExplicitCategory: req.IsExplicitCategory,
-			SkipApproval:       req.SkipApproval,
-		})
+		newID, err = lifecycle.Enqueue(ctx, addReq)
 	} else {
-		newID, err = service.Add(urlForAdd, outPath, req.Filename, mirrorsForAdd, req.Headers, req.IsExplicitCategory, 0, false)
+		newID, err = service.Add(addReq)
+	}
+	if errors.Is(err, processing.ErrAlreadyDownloaded) {
+		return downloadOutcome{Status: "archived", Message: "Already downloaded; skipped", URL: urlForAdd}, nil
 	}
 	if err != nil {
-		http.Error(w, "Failed to add download: "+err.Error(), http.StatusInternalServerError)
-		return
+		trace.Debug(ctx, "Failed to add %s: %v", urlForAdd, err)
+		return downloadOutcome{}, apiError(http.StatusInternalServerError, core.CodeInternal, "Failed to add download: "+err.Error())
 	}
+	trace.Debug(ctx, "Queued download %s", newID)
 
 	// Increment active downloads counter
 	atomic.AddInt32(&activeDownloads, 1)
 
-	writeJSONResponse(w, http.StatusOK, map[string]string{
-		"status":  "queued",
-		"message": "Download queued successfully",
-		"id":      newID,
-	})
+	return downloadOutcome{Status: "queued", Message: "Download queued successfully", ID: newID}, nil
 }
 
 // processDownloads handles the logic of adding downloads either to local pool or remote server
@@ -889,16 +1189,21 @@ func processDownloads(urls []string, outputDir string, port int) int {
 
 	// If port > 0, we are sending to a remote server
 	if port > 0 {
-		baseURL := fmt.Sprintf("http://127.0.0.1:%d", port)
+		baseURL := localBaseURL(port)
 		token := resolveLocalToken()
 		for _, arg := range urls {
 			url, mirrors := ParseURLArg(arg)
 			if url == "" {
 				continue
 			}
-			err := sendToServer(url, mirrors, outputDir, baseURL, token)
+			err := sendToServer(url, mirrors, nil, outputDir, baseURL, token)
 			if err != nil {
-				fmt.Printf("Error adding %s: %v\n", url, err)
+				setExitCode(exitCodeFor(err))
+			}
+			if errors.Is(err, errAlreadyDownloaded) {
+				infof("Skipped %s: already downloaded\n", url)
+			} else if err != nil {
+				fmt.Fprintf(os.Stderr, "Error adding %s: %v\n", url, err)
 			} else {
 				successCount++
 			}
@@ -958,7 +1263,13 @@ func processDownloads(urls []string, outputDir string, port int) int {
 			Mirrors:            mirrors,
 			IsExplicitCategory: isExplicit,
 		})
+		if errors.Is(err, processing.ErrAlreadyDownloaded) {
+			setExitCode(exitDuplicate)
+			publishSystemLog(fmt.Sprintf("Skipped %s: already downloaded", url))
+			continue
+		}
 		if err != nil {
+			setExitCode(exitCodeFor(err))
 			recordPreflightDownloadError(url, outPath, err)
 			publishSystemLog(fmt.Sprintf("Error adding %s: %v", url, err))
 			continue
@@ -969,6 +1280,63 @@ func processDownloads(urls []string, outputDir string, port int) int {
 	return successCount
 }
 
+// processBatch queues the URLs of a batch file as one unit: either every
+// download is queued or none is, and each failing URL is logged
+func processBatch(urls []string, outputDir string) int {
+	if GlobalService == nil {
+		fmt.Fprintln(os.Stderr, "Error: GlobalService not initialized")
+		return 0
+	}
+
+	settings := getSettings()
+
+	lifecycle, err := lifecycleForLocalService(GlobalService)
+	if err != nil || lifecycle == nil {
+		fmt.Fprintln(os.Stderr, "Error: unable to initialize lifecycle manager:", err)
+		return 0
+	}
+
+	outPath := utils.EnsureAbsPath(resolveOutputDir(outputDir, false, "", settings))
+	isExplicit := isExplicitOutputPath(outPath, settings.General.DefaultDownloadDir)
+
+	var reqs []*processing.DownloadRequest
+	for _, arg := range urls {
+		url, mirrors := ParseURLArg(arg)
+		if url == "" {
+			continue
+		}
+		reqs = append(reqs, &processing.DownloadRequest{
+			URL:                url,
+			Path:               outPath,
+			Mirrors:            mirrors,
+			IsExplicitCategory: isExplicit,
+		})
+	}
+	if len(reqs) == 0 {
+		return 0
+	}
+
+	report, err := lifecycle.EnqueueBatch(currentEnqueueContext(), reqs)
+	if report != nil {
+		for _, item := range report.Items {
+			switch item.Status {
+			case processing.BatchItemFailed:
+				publishSystemLog(fmt.Sprintf("Error adding %s: %s", item.URL, item.Error))
+			case processing.BatchItemSkipped:
+				publishSystemLog(fmt.Sprintf("Skipped %s: already downloaded", item.URL))
+			}
+		}
+	}
+	if err != nil {
+		publishSystemLog(fmt.Sprintf("Batch of %d downloads not queued: %v", len(reqs), err))
+		return 0
+	}
+
+	queued := report.Count(processing.BatchItemQueued)
+	atomic.AddInt32(&activeDownloads, int32(queued))
+	return queued
+}
+
 func resolveOutputDir(reqPath string, relativeToDefaultDir bool, defaultOutputDir string, settings *config.Settings) string {
 	outPath := reqPath
 
@@ -998,7 +1366,10 @@ func resolveOutputDir(reqPath string, relativeToDefaultDir bool, defaultOutputDi
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(exitFailure)
+	}
+	if code := pendingExitCode(); code != exitOK {
+		os.Exit(code)
 	}
 }
 
@@ -1006,11 +1377,19 @@ func init() {
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose logging")
 	rootCmd.PersistentFlags().StringVar(&globalHost, "host", "", "Server host to connect/control (or set SURGE_HOST), e.g. 127.0.0.1:1700")
 	rootCmd.PersistentFlags().StringVar(&globalToken, "token", "", "Bearer token (or set SURGE_TOKEN)")
+	rootCmd.PersistentFlags().StringVar(&globalCACert, "ca-cert", "", "PEM certificate to trust for https servers, e.g. a daemon's self-signed one (or set SURGE_CA_CERT)")
+	rootCmd.PersistentFlags().BoolVar(&globalJSON, "json", false, "Print machine-readable JSON instead of tables (ls, history, server status, verify, ...)")
+	rootCmd.PersistentFlags().BoolVarP(&globalQuiet, "quiet", "q", false, "Print errors and requested output only, for scripts and cron jobs")
+	rootCmd.PersistentFlags().BoolVar(&noProgress, "no-progress", false, "Leave out per-download progress lines of the headless server")
+	rootCmd.PersistentFlags().StringVar(&globalProfile, "profile", "", "Profile with its own settings, database, token and download folder (or set SURGE_PROFILE)")
 	rootCmd.Flags().StringP("batch", "b", "", "File containing URLs to download (one per line)")
 	rootCmd.Flags().IntP("port", "p", 0, "Port to listen on (default: 8080 or first available)")
 	rootCmd.Flags().StringP("output", "o", "", "Default output directory")
 	rootCmd.Flags().Bool("no-resume", false, "Do not auto-resume paused downloads on startup")
 	rootCmd.Flags().Bool("exit-when-done", false, "Exit when all downloads complete")
+	rootCmd.Flags().Int("status-port", 0, "Serve the read-only status page on this port")
+	rootCmd.Flags().Int("grpc-port", 0, "Serve the gRPC API on this port")
+	rootCmd.Flags().StringVar(&bindAddressFlag, "bind", "", "Address to listen on, e.g. 127.0.0.1 for this machine only (default: bind_address setting or all interfaces)")
 	rootCmd.SetVersionTemplate("Surge v{{.Version}}\n")
 }
 
@@ -1033,14 +1412,31 @@ func initializeGlobalState() error {
 	_ = os.MkdirAll(logsDir, 0o755)
 
 	// Config engine state
-	state.Configure(stateDBPath)
+	settings := getSettings()
+	dsn := settings.General.StateStore
+	if dsn == "" {
+		dsn = stateDBPath
+	}
+	store, err := state.NewStore(dsn)
+	if err != nil {
+		return fmt.Errorf("invalid state_store: %w", err)
+	}
+	if s, ok := store.(*state.SQLiteStore); ok {
+		s.BusyTimeout = settings.Performance.DBBusyTimeout
+	}
+	state.ConfigureStore(store)
 
 	// Config logging
 	utils.ConfigureDebug(logsDir)
 
 	// Clean up old logs
-	retention := getSettings().General.LogRetentionCount
+	retention := settings.General.LogRetentionCount
 	utils.CleanupLogs(retention)
+
+	// Export spans when an OpenTelemetry collector is configured
+	if trace.ConfigureFromEnv() {
+		utils.Debug("Exporting traces via OTLP")
+	}
 	return nil
 }
 
@@ -1056,24 +1452,39 @@ func getSettings() *config.Settings {
 }
 
 func resumePausedDownloads() {
-	settings := getSettings()
+	for _, entry := range resumableDownloads(getSettings()) {
+		if GlobalService == nil {
+			continue
+		}
+		if err := GlobalService.Resume(entry.ID); err == nil {
+			atomic.AddInt32(&activeDownloads, 1)
+		}
+	}
+}
 
+// resumableDownloads returns the downloads auto-resume starts on launch
+func resumableDownloads(settings *config.Settings) []types.DownloadEntry {
 	pausedEntries, err := state.LoadPausedDownloads()
 	if err != nil {
-		return
+		return nil
 	}
 
+	var out []types.DownloadEntry
 	for _, entry := range pausedEntries {
 		// If entry is explicitly queued, we should start it regardless of AutoResume setting
-		// If entry is paused, we only start it if AutoResume is enabled
-		if entry.Status == "paused" && !settings.General.AutoResume {
+		// If entry is paused, we only start it if AutoResume is enabled and
+		// the user didn't pause it themselves
+		if entry.Status == "paused" && (!settings.General.AutoResume || !types.AutoResumable(entry.PauseReason)) {
 			continue
 		}
-		if GlobalService == nil || entry.ID == "" {
+		if entry.ID == "" {
 			continue
 		}
-		if err := GlobalService.Resume(entry.ID); err == nil {
-			atomic.AddInt32(&activeDownloads, 1)
+		// Left for surge prune --orphans; resuming would not fetch the lost bytes again
+		if state.WorkingFileMissing(entry.DestPath, entry.Downloaded) {
+			continue
 		}
+		out = append(out, entry)
 	}
+	return out
 }
@@ -34,17 +34,18 @@ var _ core.DownloadService = (*countingLifecycleService)(nil)
 
 func (s *countingLifecycleService) List() ([]types.DownloadStatus, error)   { return nil, nil }
 func (s *countingLifecycleService) History() ([]types.DownloadEntry, error) { return nil, nil }
-func (s *countingLifecycleService) Add(string, string, string, []string, map[string]string, bool, int64, bool) (string, error) {
+func (s *countingLifecycleService) Add(*processing.DownloadRequest) (string, error) {
 	return "", nil
 }
-func (s *countingLifecycleService) AddWithID(string, string, string, []string, map[string]string, string, int64, bool) (string, error) {
+func (s *countingLifecycleService) AddWithID(*processing.DownloadRequest, string) (string, error) {
 	return "", nil
 }
-func (s *countingLifecycleService) Pause(string) error             { return nil }
-func (s *countingLifecycleService) Resume(string) error            { return nil }
-func (s *countingLifecycleService) ResumeBatch([]string) []error   { return nil }
-func (s *countingLifecycleService) UpdateURL(string, string) error { return nil }
-func (s *countingLifecycleService) Delete(string) error            { return nil }
+func (s *countingLifecycleService) Pause(string) error                        { return nil }
+func (s *countingLifecycleService) Resume(string) error                       { return nil }
+func (s *countingLifecycleService) ResumeBatch([]string) []error              { return nil }
+func (s *countingLifecycleService) UpdateURL(string, string) error            { return nil }
+func (s *countingLifecycleService) UpdateNote(string, types.NoteUpdate) error { return nil }
+func (s *countingLifecycleService) Delete(string) error                       { return nil }
 func (s *countingLifecycleService) Publish(msg interface{}) error {
 	if log, ok := msg.(events.SystemLogMsg); ok {
 		s.cleanupMu.Lock()
@@ -464,7 +465,7 @@ func TestProcessDownloads_UsesSharedEnqueueContext(t *testing.T) {
 
 	dispatchCalled := false
 	GlobalLifecycle = processing.NewLifecycleManager(
-		func(string, string, string, []string, map[string]string, bool, int64, bool) (string, error) {
+		func(*processing.DownloadRequest) (string, error) {
 			dispatchCalled = true
 			return "", nil
 		},
@@ -1,8 +1,9 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
-
+	"net"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -48,10 +49,11 @@ var serverStartCmd = &cobra.Command{
 		}()
 
 		mustInitializeGlobalState()
+		mustConfigureStateSecrets()
 
 		msg := runStartupIntegrityCheck()
 		utils.Debug("%s", msg)
-		fmt.Println(msg)
+		infof("%s\n", msg)
 
 		portFlag, _ := cmd.Flags().GetInt("port")
 		batchFile, _ := cmd.Flags().GetString("batch")
@@ -100,32 +102,53 @@ var serverStatusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Check the status of the Surge server",
 	Run: func(cmd *cobra.Command, args []string) {
-		pid := readPID()
-		if pid == 0 {
-			fmt.Println("Surge server is NOT running.")
+		status, detail := checkServerStatus()
+		if globalJSON {
+			data, _ := json.MarshalIndent(status, "", "  ")
+			fmt.Println(string(data))
 			return
 		}
-
-		// Check if process exists
-		process, err := os.FindProcess(pid)
-		if err != nil {
-			fmt.Printf("Surge server is NOT running (Process %d not found).\n", pid)
-			// Cleanup stale pid file?
+		if !status.Running {
+			fmt.Printf("Surge server is NOT running%s.\n", detail)
 			return
 		}
-
-		// Sending signal 0 to check existence
-		err = process.Signal(syscall.Signal(0))
-		if err != nil {
-			fmt.Printf("Surge server is NOT running (Process %d dead).\n", pid)
-			return
+		fmt.Printf("Surge server is running (PID: %d, Port: %d).\n", status.PID, status.Port)
+		if status.Socket != "" {
+			fmt.Printf("Socket: %s\n", status.Socket)
 		}
-
-		port := readActivePort()
-		fmt.Printf("Surge server is running (PID: %d, Port: %d).\n", pid, port)
 	},
 }
 
+// serverStatus is what surge server status reports
+type serverStatus struct {
+	Running bool   `json:"running"`
+	PID     int    `json:"pid,omitempty"`
+	Port    int    `json:"port,omitempty"`
+	Socket  string `json:"socket,omitempty"`
+}
+
+// checkServerStatus reports whether the server in the PID file is alive.
+// When it isn't, detail says why for the message, e.g. " (Process 12 dead)".
+func checkServerStatus() (serverStatus, string) {
+	pid := readPID()
+	if pid == 0 {
+		return serverStatus{}, ""
+	}
+
+	// Check if process exists
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return serverStatus{}, fmt.Sprintf(" (Process %d not found)", pid)
+	}
+
+	// Sending signal 0 to check existence
+	if err := process.Signal(syscall.Signal(0)); err != nil {
+		return serverStatus{}, fmt.Sprintf(" (Process %d dead)", pid)
+	}
+
+	return serverStatus{Running: true, PID: pid, Port: readActivePort(), Socket: readActiveSocket()}, ""
+}
+
 func init() {
 	rootCmd.AddCommand(serverCmd)
 	serverCmd.AddCommand(serverStartCmd)
@@ -138,20 +161,40 @@ func init() {
 	serverCmd.PersistentFlags().Bool("exit-when-done", false, "Exit when all downloads complete")
 	serverCmd.PersistentFlags().Bool("no-resume", false, "Do not auto-resume paused downloads on startup")
 	serverCmd.PersistentFlags().String("token", "", "Auth token for API clients (or set SURGE_TOKEN)")
+	serverCmd.PersistentFlags().Int("status-port", 0, "Serve the read-only status page on this port")
+	serverCmd.PersistentFlags().Int("grpc-port", 0, "Serve the gRPC API on this port")
+	serverCmd.PersistentFlags().StringVar(&bindAddressFlag, "bind", "", "Address to listen on, e.g. 127.0.0.1 for this machine only (default: bind_address setting or all interfaces)")
+	serverCmd.PersistentFlags().String("socket", "", "Also serve the API on this unix socket, without a token")
+	serverCmd.PersistentFlags().Bool("no-tcp", false, "Serve the API only on --socket, not on a port")
+
+	// surge daemon runs the same server, so it takes the same flags
+	daemonCmd.Flags().AddFlagSet(serverCmd.PersistentFlags())
 }
 
+// extraPIDFile is another file savePID writes the PID to, set by
+// surge daemon --pid-file for init systems that watch one
+var extraPIDFile string
+
 func savePID() {
-	pid := os.Getpid()
-	pidFile := filepath.Join(config.GetRuntimeDir(), "pid")
-	if err := os.WriteFile(pidFile, []byte(fmt.Sprintf("%d", pid)), 0o644); err != nil {
-		utils.Debug("Error writing PID file: %v", err)
+	pid := []byte(fmt.Sprintf("%d", os.Getpid()))
+	for _, pidFile := range []string{filepath.Join(config.GetRuntimeDir(), "pid"), extraPIDFile} {
+		if pidFile == "" {
+			continue
+		}
+		if err := os.WriteFile(pidFile, pid, 0o644); err != nil {
+			utils.Debug("Error writing PID file: %v", err)
+		}
 	}
 }
 
 func removePID() {
-	pidFile := filepath.Join(config.GetRuntimeDir(), "pid")
-	if err := os.Remove(pidFile); err != nil && !os.IsNotExist(err) {
-		utils.Debug("Error removing PID file: %v", err)
+	for _, pidFile := range []string{filepath.Join(config.GetRuntimeDir(), "pid"), extraPIDFile} {
+		if pidFile == "" {
+			continue
+		}
+		if err := os.Remove(pidFile); err != nil && !os.IsNotExist(err) {
+			utils.Debug("Error removing PID file: %v", err)
+		}
 	}
 }
 
@@ -166,11 +209,39 @@ func readPID() int {
 }
 
 func startServerLogic(cmd *cobra.Command, args []string, portFlag int, batchFile string, outputDir string, exitWhenDone bool, noResume bool, tokenOverride string) {
-	port, listener, err := bindServerListener(portFlag)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	socketPath, _ := cmd.Flags().GetString("socket")
+	noTCP, _ := cmd.Flags().GetBool("no-tcp")
+	if noTCP && socketPath == "" {
+		fmt.Fprintln(os.Stderr, "Error: --no-tcp needs --socket")
 		os.Exit(1)
 	}
+
+	var port int
+	var listener, socketListener net.Listener
+	var certFile string
+	var err error
+	if !noTCP {
+		port, listener, err = bindServerListener(portFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		listener, certFile, err = enableServerTLS(listener)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer removeActiveTLSCert()
+	}
+	if socketPath != "" {
+		socketListener, err = listenUnixSocket(socketPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer func() { _ = socketListener.Close() }()
+		socketPath = socketListener.Addr().String()
+	}
 	resetGlobalEnqueueContext()
 
 	if err := ensureGlobalLocalServiceAndLifecycle(); err != nil {
@@ -178,36 +249,67 @@ func startServerLogic(cmd *cobra.Command, args []string, portFlag int, batchFile
 		os.Exit(1)
 	}
 
-	saveActivePort(port)
-	defer removeActivePort()
+	if listener != nil {
+		saveActivePort(port)
+		defer removeActivePort()
+	}
+	if socketListener != nil {
+		saveActiveSocket(socketPath)
+		defer removeActiveSocket()
+	}
+
+	go startHTTPServer(listener, socketListener, port, outputDir, GlobalService, strings.TrimSpace(tokenOverride))
+	if listener != nil {
+		defer startMDNS(port, certFile != "")()
+	}
+
+	defer startWatchFolders(GlobalService)()
 
-	go startHTTPServer(listener, port, outputDir, GlobalService, strings.TrimSpace(tokenOverride))
+	statusPort, _ := cmd.Flags().GetInt("status-port")
+	if served, err := startStatusPage(GlobalService, statusPort); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	} else if served > 0 {
+		infof("Status page available on port %d\n", served)
+	}
+
+	grpcPort, _ := cmd.Flags().GetInt("grpc-port")
+	if served, err := startGRPCServer(grpcPort, outputDir, GlobalService, strings.TrimSpace(tokenOverride)); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	} else if served > 0 {
+		infof("gRPC API available on port %d\n", served)
+	}
 
 	// Queue initial downloads
 	go func() {
-		var urls []string
-		urls = append(urls, args...)
+		if len(args) > 0 {
+			processDownloads(args, outputDir, 0)
+		}
 
 		if batchFile != "" {
 			fileURLs, err := utils.ReadURLsFromFile(batchFile)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error reading batch file: %v\n", err)
-			} else {
-				urls = append(urls, fileURLs...)
+			} else if len(fileURLs) > 0 {
+				processBatch(fileURLs, outputDir)
 			}
 		}
-
-		if len(urls) > 0 {
-			processDownloads(urls, outputDir, 0)
-		}
 	}()
 
-	fmt.Printf("Surge %s running in server mode.\n", Version)
-	host := serverBindHost
-	fmt.Printf("Serving on %s:%d\n", host, port)
-	fmt.Println("Press Ctrl+C to exit.")
+	infof("Surge %s running in server mode.\n", Version)
+	if listener != nil {
+		host := serverBindAddress()
+		infof("Serving on %s\n", net.JoinHostPort(host, strconv.Itoa(port)))
+	}
+	if certFile != "" {
+		infof("Serving HTTPS with %s (SHA-256 %s)\n", certFile, certFingerprint(certFile))
+	}
+	if socketListener != nil {
+		infof("Serving on unix socket %s\n", socketPath)
+	}
+	infof("Press Ctrl+C to exit.\n")
 
 	StartHeadlessConsumer()
+	startFirstRunCalibration(downloadsStarting(args, batchFile, noResume))
 
 	// Auto-resume paused downloads (unless --no-resume)
 	if !noResume {
@@ -239,11 +341,16 @@ func startServerLogic(cmd *cobra.Command, args []string, portFlag int, batchFile
 
 		select {
 		case sig := <-sigChan:
+			// Stopped before the downloads finished
+			setExitCode(exitCancelled)
 			fmt.Printf("\nReceived %s. Shutting down...\n", sig)
 			_ = executeGlobalShutdown(fmt.Sprintf("server signal: %s", sig))
 		case <-exitWhenDoneCh:
-			fmt.Println("All downloads finished. Exiting...")
+			infof("All downloads finished. Exiting...\n")
 			_ = executeGlobalShutdown("server: exit when done")
+		case reason := <-shutdownRequests:
+			fmt.Println("Shutdown requested. Exiting...")
+			_ = executeGlobalShutdown(reason)
 		}
 		return
 	}
@@ -251,10 +358,15 @@ func startServerLogic(cmd *cobra.Command, args []string, portFlag int, batchFile
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
 	defer signal.Stop(sigChan)
-	sig := <-sigChan
 
-	fmt.Printf("\nReceived %s. Shutting down...\n", sig)
-	_ = executeGlobalShutdown(fmt.Sprintf("server signal: %s", sig))
+	select {
+	case sig := <-sigChan:
+		fmt.Printf("\nReceived %s. Shutting down...\n", sig)
+		_ = executeGlobalShutdown(fmt.Sprintf("server signal: %s", sig))
+	case reason := <-shutdownRequests:
+		fmt.Println("Shutdown requested. Exiting...")
+		_ = executeGlobalShutdown(reason)
+	}
 }
 
 func resolveServerToken(cmd *cobra.Command) string {
@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/core"
+)
+
+// settingsMu serializes /settings updates so concurrent partial updates don't
+// drop each other's changes
+var settingsMu sync.Mutex
+
+// settingsHandler serves settings.json. A PUT body is merged onto the current
+// settings, so it carries only the fields to change; lists such as categories
+// are replaced whole. The result is validated before it is saved and applied.
+// Secrets are redacted from responses, and only requests over the unix socket
+// may add scripts or change peers and server settings.
+func settingsHandler(service core.DownloadService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		settingsMu.Lock()
+		defer settingsMu.Unlock()
+
+		settings, err := config.LoadSettings()
+		if err != nil {
+			httpError(w, "Failed to load settings: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if r.Method == http.MethodGet {
+			writeJSONResponse(w, http.StatusOK, redactSettings(settings))
+			return
+		}
+		// Decoding reuses settings' lists, so compare against a fresh copy
+		before, err := config.LoadSettings()
+		if err != nil {
+			httpError(w, "Failed to load settings: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		defer func() {
+			_ = r.Body.Close()
+		}()
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(settings); err != nil {
+			httpError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		restoreSecrets(settings, before)
+		if !viaUnixSocket(r) {
+			if err := checkRemoteSettingsChange(before, settings); err != nil {
+				httpError(w, err.Error(), http.StatusForbidden)
+				return
+			}
+		}
+		if err := settings.Validate(); err != nil {
+			httpError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := applySettings(service, settings); err != nil {
+			httpError(w, "Failed to save settings: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, redactSettings(settings))
+	}
+}
+
+// redactedSecret replaces peer tokens and webhook headers in /settings
+// responses. Sent back unchanged, it keeps the saved value.
+const redactedSecret = "<redacted>"
+
+// redactSettings returns a copy of s without the secrets it holds
+func redactSettings(s *config.Settings) *config.Settings {
+	redacted := *s
+	redacted.Distributed.Peers = slices.Clone(s.Distributed.Peers)
+	for i := range redacted.Distributed.Peers {
+		if redacted.Distributed.Peers[i].Token != "" {
+			redacted.Distributed.Peers[i].Token = redactedSecret
+		}
+	}
+	redacted.Webhooks = slices.Clone(s.Webhooks)
+	for i, hook := range redacted.Webhooks {
+		if len(hook.Headers) == 0 {
+			continue
+		}
+		headers := make(map[string]string, len(hook.Headers))
+		for name := range hook.Headers {
+			headers[name] = redactedSecret
+		}
+		redacted.Webhooks[i].Headers = headers
+	}
+	return &redacted
+}
+
+// restoreSecrets puts the values from before back where s still holds
+// redactedSecret, matching peers by URL and webhooks by ID
+func restoreSecrets(s, before *config.Settings) {
+	for i, peer := range s.Distributed.Peers {
+		if peer.Token != redactedSecret {
+			continue
+		}
+		s.Distributed.Peers[i].Token = ""
+		for _, old := range before.Distributed.Peers {
+			if old.URL == peer.URL {
+				s.Distributed.Peers[i].Token = old.Token
+				break
+			}
+		}
+	}
+	for i, hook := range s.Webhooks {
+		var oldHeaders map[string]string
+		for _, old := range before.Webhooks {
+			if old.ID == hook.ID {
+				oldHeaders = old.Headers
+				break
+			}
+		}
+		headers := maps.Clone(hook.Headers)
+		for name, value := range headers {
+			if value != redactedSecret {
+				continue
+			}
+			if oldValue, ok := oldHeaders[name]; ok {
+				headers[name] = oldValue
+			} else {
+				delete(headers, name)
+			}
+		}
+		s.Webhooks[i].Headers = headers
+	}
+}
+
+// checkRemoteSettingsChange refuses changes that could run programs or
+// redirect the daemon's traffic and credentials when they don't come over the
+// unix socket, whose file permissions limit them to this machine's user: new
+// post-processing scripts, distributed peers and server settings
+func checkRemoteSettingsChange(before, after *config.Settings) error {
+	known := map[string]bool{"": true}
+	for _, cat := range before.General.Categories {
+		for _, step := range cat.PostProcess {
+			known[scriptKey(step)] = true
+		}
+	}
+	for _, cat := range after.General.Categories {
+		for _, step := range cat.PostProcess {
+			if !known[scriptKey(step)] {
+				return errors.New("post-processing scripts can only be changed over the unix socket or in settings.json")
+			}
+		}
+	}
+	if !sameJSON(before.Distributed.Peers, after.Distributed.Peers) {
+		return errors.New("distributed peers can only be changed over the unix socket or in settings.json")
+	}
+	if !sameJSON(before.Server, after.Server) {
+		return errors.New("server settings can only be changed over the unix socket or in settings.json")
+	}
+	return nil
+}
+
+// scriptKey identifies the program a post-processing step runs; steps that
+// run none share the empty key
+func scriptKey(step config.PostStep) string {
+	if step.Command == "" {
+		return ""
+	}
+	key, _ := json.Marshal(append([]string{step.Command}, step.Args...))
+	return string(key)
+}
+
+// sameJSON reports whether a and b encode the same, so empty and nil lists
+// compare equal
+func sameJSON(a, b any) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+// applySettings saves settings and hands them to the running service and
+// lifecycle, like saving from the TUI settings screen. Settings marked
+// "Requires restart" are saved but take effect on the next start.
+func applySettings(service core.DownloadService, settings *config.Settings) error {
+	if err := config.SaveSettings(settings); err != nil {
+		return err
+	}
+	if globalSettings != nil {
+		globalSettings = settings
+	}
+	if reloader, ok := service.(interface{ ReloadSettings() error }); ok {
+		if err := reloader.ReloadSettings(); err != nil {
+			return err
+		}
+	}
+	if lifecycle := currentLifecycle(); lifecycle != nil {
+		lifecycle.ApplySettings(settings)
+	}
+	return nil
+}
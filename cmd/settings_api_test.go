@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/config"
+)
+
+func TestSettingsEndpoint(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	origSettings := globalSettings
+	t.Cleanup(func() { globalSettings = origSettings })
+	globalSettings = config.DefaultSettings()
+
+	const token = "settings-token"
+	baseURL := startAuthedTestServer(t, &fakeRemoteDownloadService{}, token)
+
+	do := func(method, body string) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequest(method, baseURL+"/settings", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s /settings: %v", method, err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+
+	code, body := do(http.MethodGet, "")
+	if code != http.StatusOK {
+		t.Fatalf("GET = %d %s", code, body)
+	}
+	var got config.Settings
+	if err := json.Unmarshal([]byte(body), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Network.MaxConnectionsPerHost != 32 {
+		t.Fatalf("max_connections_per_host = %d, want the default 32", got.Network.MaxConnectionsPerHost)
+	}
+
+	// Only the fields sent change
+	code, body = do(http.MethodPut, `{"network":{"max_connections_per_host":8},"general":{"auto_resume":true}}`)
+	if code != http.StatusOK {
+		t.Fatalf("PUT = %d %s", code, body)
+	}
+	saved, err := config.LoadSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Network.MaxConnectionsPerHost != 8 || !saved.General.AutoResume {
+		t.Errorf("saved = %+v %+v", saved.Network, saved.General)
+	}
+	if saved.Network.MinChunkSize != 2*config.MB || saved.Performance.MaxTaskRetries != 3 {
+		t.Errorf("untouched settings changed: %+v %+v", saved.Network, saved.Performance)
+	}
+	if globalSettings.Network.MaxConnectionsPerHost != 8 {
+		t.Errorf("running settings not updated")
+	}
+
+	for _, bad := range []string{
+		`{"network":{"max_connections_per_host":100}}`,
+		`{"network":{"max_conections_per_host":8}}`,
+		`{"network":{"max_connections_per_host":"8"}}`,
+	} {
+		if code, body := do(http.MethodPut, bad); code != http.StatusBadRequest {
+			t.Errorf("PUT %s = %d %s, want 400", bad, code, body)
+		}
+	}
+	if saved, _ := config.LoadSettings(); saved.Network.MaxConnectionsPerHost != 8 {
+		t.Errorf("rejected update was saved: %d", saved.Network.MaxConnectionsPerHost)
+	}
+
+	if code, _ := do(http.MethodPost, `{}`); code != http.StatusMethodNotAllowed {
+		t.Errorf("POST = %d, want 405", code)
+	}
+}
+
+func TestSettingsEndpoint_SecretsAndRemoteChanges(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	origSettings := globalSettings
+	t.Cleanup(func() { globalSettings = origSettings })
+	globalSettings = config.DefaultSettings()
+
+	initial := config.DefaultSettings()
+	initial.Distributed.Peers = []config.PeerDaemon{{URL: "http://10.0.0.5:1700", Token: "peer-secret"}}
+	initial.Webhooks = []config.Webhook{{ID: "hook", URL: "https://example.com/hook", Headers: map[string]string{"Authorization": "Bearer hook-secret"}}}
+	initial.General.Categories = []config.Category{{Name: "Scripts", Pattern: `\.sh$`, Path: t.TempDir(),
+		PostProcess: []config.PostStep{{Type: "script", Command: "/usr/bin/true"}}}}
+	if err := config.SaveSettings(initial); err != nil {
+		t.Fatal(err)
+	}
+
+	const token = "settings-token"
+	baseURL := startAuthedTestServer(t, &fakeRemoteDownloadService{}, token)
+	mux := http.NewServeMux()
+	registerHTTPRoutes(mux, 0, "", &fakeRemoteDownloadService{})
+	socket := httptest.NewServer(markSocketRequests(mux))
+	t.Cleanup(socket.Close)
+
+	do := func(base, method, body string) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequest(method, base+"/settings", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s /settings: %v", method, err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+
+	code, body := do(baseURL, http.MethodGet, "")
+	if code != http.StatusOK {
+		t.Fatalf("GET = %d %s", code, body)
+	}
+	if strings.Contains(body, "peer-secret") || strings.Contains(body, "hook-secret") {
+		t.Fatalf("GET leaks secrets: %s", body)
+	}
+
+	// Sending the redacted settings back, as surge config set does, keeps the secrets
+	if code, body := do(baseURL, http.MethodPut, body); code != http.StatusOK {
+		t.Fatalf("PUT of GET body = %d %s", code, body)
+	}
+	saved, err := config.LoadSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Distributed.Peers[0].Token != "peer-secret" || saved.Webhooks[0].Headers["Authorization"] != "Bearer hook-secret" {
+		t.Errorf("secrets not kept: %+v %+v", saved.Distributed.Peers, saved.Webhooks)
+	}
+
+	addScript := `{"general":{"categories":[{"name":"Scripts","pattern":"\\.sh$","path":"` + saved.General.Categories[0].Path + `",` +
+		`"post_process":[{"type":"script","command":"/usr/bin/true"},{"type":"script","command":"/bin/sh","args":["-c","id"]}]}]}}`
+	for _, change := range []string{
+		addScript,
+		`{"distributed":{"peers":[{"url":"http://attacker.example:1700","token":"x"}]}}`,
+		`{"server":{"bind_address":"0.0.0.0"}}`,
+	} {
+		if code, body := do(baseURL, http.MethodPut, change); code != http.StatusForbidden {
+			t.Errorf("PUT %s with the token = %d %s, want 403", change, code, body)
+		}
+		if code, body := do(socket.URL, http.MethodPut, change); code != http.StatusOK {
+			t.Errorf("PUT %s over the socket = %d %s, want 200", change, code, body)
+		}
+	}
+}
@@ -1,9 +1,14 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/surge-downloader/surge/internal/trace"
 	"github.com/surge-downloader/surge/internal/utils"
 )
 
@@ -13,6 +18,28 @@ var (
 	globalShutdownFn   = defaultGlobalShutdown
 )
 
+// draining is set once a shutdown was requested over the API; new downloads
+// are refused from then on
+var draining atomic.Bool
+
+// shutdownRequests tells a headless server to shut down, with the reason
+var shutdownRequests = make(chan string, 1)
+
+// requestShutdownFn ends the running server or TUI; tests replace it
+var requestShutdownFn = requestShutdown
+
+// requestShutdown ends the TUI, or the headless server as SIGTERM would
+func requestShutdown(reason string) {
+	if serverProgram != nil {
+		serverProgram.Send(tea.Quit())
+		return
+	}
+	select {
+	case shutdownRequests <- reason:
+	default:
+	}
+}
+
 func defaultGlobalShutdown() error {
 	cancelGlobalEnqueue()
 
@@ -31,6 +58,11 @@ func defaultGlobalShutdown() error {
 		cleanup()
 	}
 
+	// Flush spans queued for export, without holding up exit on a dead collector
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	trace.Shutdown(ctx)
+
 	return err
 }
 
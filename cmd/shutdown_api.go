@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// Shutdown modes: pause running downloads to resume them on the next start,
+// or let them finish first
+const (
+	shutdownPause  = "pause"
+	shutdownFinish = "finish"
+)
+
+// drainPollInterval is how often a finishing shutdown checks for downloads
+// still running
+var drainPollInterval = time.Second
+
+type shutdownResponse struct {
+	Status  string `json:"status"`
+	Mode    string `json:"mode"`
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// shutdownHandler stops accepting downloads and shuts the daemon down in the
+// background, pausing running downloads or, in finish mode, waiting for them
+// up to an optional timeout. State is persisted as on SIGTERM.
+func shutdownHandler(service core.DownloadService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		mode := q.Get("mode")
+		if mode == "" {
+			mode = shutdownPause
+		}
+		if mode != shutdownPause && mode != shutdownFinish {
+			httpError(w, "mode must be pause or finish", http.StatusBadRequest)
+			return
+		}
+		var timeout time.Duration
+		if v := q.Get("timeout"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				httpError(w, "Invalid timeout", http.StatusBadRequest)
+				return
+			}
+			if mode != shutdownFinish {
+				httpError(w, "timeout only applies to mode=finish", http.StatusBadRequest)
+				return
+			}
+			timeout = d
+		}
+		if !draining.CompareAndSwap(false, true) {
+			httpError(w, "Already shutting down", http.StatusConflict)
+			return
+		}
+		cancelGlobalEnqueue()
+
+		go func() {
+			if mode == shutdownFinish {
+				drainDownloads(service, timeout)
+			}
+			requestShutdownFn(fmt.Sprintf("api shutdown (%s)", mode))
+		}()
+
+		resp := shutdownResponse{Status: "shutting_down", Mode: mode}
+		if timeout > 0 {
+			resp.Timeout = timeout.String()
+		}
+		writeJSONResponse(w, http.StatusOK, resp)
+	}
+}
+
+// drainDownloads pauses waiting downloads so they don't start, then waits for
+// the running ones to finish, or for timeout when it is set. Whatever still
+// runs then is paused by the shutdown itself.
+func drainDownloads(service core.DownloadService, timeout time.Duration) {
+	statuses, err := service.List()
+	if err != nil {
+		utils.Debug("Drain: listing downloads failed: %v", err)
+		return
+	}
+	for _, s := range statuses {
+		if s.Status == "queued" {
+			if err := service.Pause(s.ID); err != nil {
+				utils.Debug("Drain: pausing queued %s failed: %v", s.ID, err)
+			}
+		}
+	}
+
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	for {
+		statuses, err := service.List()
+		if err != nil {
+			utils.Debug("Drain: listing downloads failed: %v", err)
+			return
+		}
+		running := 0
+		for _, s := range statuses {
+			if s.Status == "downloading" {
+				running++
+			}
+		}
+		if running == 0 {
+			return
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			utils.Debug("Drain: timed out with %d download(s) running", running)
+			return
+		}
+		time.Sleep(drainPollInterval)
+	}
+}
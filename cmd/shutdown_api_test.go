@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// drainService reports a running download that finishes after a few lists
+type drainService struct {
+	fakeRemoteDownloadService
+	mu     sync.Mutex
+	lists  int
+	paused []string
+}
+
+func (s *drainService) List() ([]types.DownloadStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lists++
+	running := "downloading"
+	if s.lists > 3 {
+		running = "completed"
+	}
+	return []types.DownloadStatus{{ID: "a", Status: running}, {ID: "b", Status: "queued"}}, nil
+}
+
+func (s *drainService) Pause(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = append(s.paused, id)
+	return nil
+}
+
+func TestShutdownEndpoint(t *testing.T) {
+	origFn, origPoll := requestShutdownFn, drainPollInterval
+	t.Cleanup(func() {
+		requestShutdownFn, drainPollInterval = origFn, origPoll
+		draining.Store(false)
+		resetGlobalEnqueueContext()
+	})
+	requested := make(chan string, 1)
+	requestShutdownFn = func(reason string) { requested <- reason }
+	drainPollInterval = time.Millisecond
+
+	service := &drainService{}
+	mux := http.NewServeMux()
+	registerHTTPRoutes(mux, 0, "", service)
+	post := func(path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return rec
+	}
+
+	for _, path := range []string{"/shutdown?mode=later", "/shutdown?timeout=1m", "/shutdown?mode=finish&timeout=soon"} {
+		if rec := post(path, ""); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s = %d, want 400", path, rec.Code)
+		}
+	}
+	if draining.Load() {
+		t.Fatal("rejected request started draining")
+	}
+
+	rec := post("/shutdown?mode=finish&timeout=1m", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"mode":"finish"`) {
+		t.Fatalf("shutdown = %d %s", rec.Code, rec.Body.String())
+	}
+	select {
+	case reason := <-requested:
+		if reason != "api shutdown (finish)" {
+			t.Fatalf("reason = %q", reason)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown not requested after downloads finished")
+	}
+	service.mu.Lock()
+	if len(service.paused) != 1 || service.paused[0] != "b" || service.lists < 4 {
+		t.Fatalf("paused %v after %d lists, want only the queued one paused and the running one awaited", service.paused, service.lists)
+	}
+	service.mu.Unlock()
+
+	if rec := post("/shutdown", ""); rec.Code != http.StatusConflict {
+		t.Fatalf("second shutdown = %d, want 409", rec.Code)
+	}
+	if rec := post("/download", `{"url":"https://example.com/file"}`); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("download while draining = %d, want 503", rec.Code)
+	}
+}
+
+func TestShutdownQuery(t *testing.T) {
+	if q, _ := shutdownQuery(false, 0); q != "mode=pause" {
+		t.Fatalf("default = %q", q)
+	}
+	if q, _ := shutdownQuery(true, 90*time.Second); q != "mode=finish&timeout=1m30s" {
+		t.Fatalf("finish = %q", q)
+	}
+	if _, err := shutdownQuery(false, time.Minute); err == nil {
+		t.Fatal("timeout without finish accepted")
+	}
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// listenUnixSocket serves the API on a unix socket at path that only the
+// current user can connect to. The socket's permissions stand in for the
+// token, so requests over it are not asked for one. A socket left behind by a
+// server that is gone is replaced; one still answering is an error.
+func listenUnixSocket(path string) (net.Listener, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("%s is in use by another server", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("could not remove stale socket %s: %w", path, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	restore := restrictUmask()
+	ln, err := net.Listen("unix", path)
+	restore()
+	if err != nil {
+		return nil, fmt.Errorf("could not listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		_ = ln.Close()
+		return nil, fmt.Errorf("could not restrict %s: %w", path, err)
+	}
+	return ln, nil
+}
+
+// saveActiveSocket records the socket path for CLI discovery, next to the
+// port file
+func saveActiveSocket(path string) {
+	socketFile := filepath.Join(config.GetRuntimeDir(), "socket")
+	if err := os.WriteFile(socketFile, []byte(path), 0o644); err != nil {
+		utils.Debug("Error writing socket file: %v", err)
+	}
+	utils.Debug("HTTP server listening on %s", path)
+}
+
+// removeActiveSocket cleans up the socket file on exit
+func removeActiveSocket() {
+	socketFile := filepath.Join(config.GetRuntimeDir(), "socket")
+	if err := os.Remove(socketFile); err != nil && !os.IsNotExist(err) {
+		utils.Debug("Error removing socket file: %v", err)
+	}
+}
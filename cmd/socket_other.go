@@ -0,0 +1,12 @@
+//go:build !windows
+
+package cmd
+
+import "syscall"
+
+// restrictUmask sets a 077 umask, so a socket created before it is chmodded
+// is never reachable by other users, and returns a func restoring the old one
+func restrictUmask() func() {
+	old := syscall.Umask(0o077)
+	return func() { syscall.Umask(old) }
+}
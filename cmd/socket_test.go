@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/core"
+)
+
+func TestListenUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "surge.sock")
+
+	ln, err := listenUnixSocket(path)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Fatalf("socket mode = %o, want 600", perm)
+	}
+	if _, err := listenUnixSocket(path); err == nil {
+		t.Fatal("listened on a socket another server is using")
+	}
+
+	// Leave the socket file behind, as a crashed server would
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = ln.Close()
+	ln, err = listenUnixSocket(path)
+	if err != nil {
+		t.Fatalf("stale socket not replaced: %v", err)
+	}
+	_ = ln.Close()
+
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := listenUnixSocket(file); err == nil {
+		t.Fatal("replaced a regular file")
+	}
+}
+
+func TestStartHTTPServer_SocketSkipsToken(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "surge.sock")
+	socketLn, err := listenUnixSocket(path)
+	if err != nil {
+		t.Fatalf("listen socket: %v", err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen tcp: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = ln.Close()
+		_ = socketLn.Close()
+	})
+	go startHTTPServer(ln, socketLn, 0, "", &fakeRemoteDownloadService{}, "socket-token")
+
+	resp, err := doAPIRequest(http.MethodGet, core.UnixScheme+path, "", "/list", nil)
+	if err != nil {
+		t.Fatalf("list over socket: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("socket status = %d, want 200", resp.StatusCode)
+	}
+
+	resp, err = doAPIRequest(http.MethodGet, "http://"+ln.Addr().String(), "", "/list", nil)
+	if err != nil {
+		t.Fatalf("list over tcp: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("tcp status = %d, want 401", resp.StatusCode)
+	}
+}
+
+func TestResolveConnectBaseURL_UnixSocket(t *testing.T) {
+	t.Setenv("SURGE_TOKEN", "")
+	got, err := resolveConnectBaseURL("unix:///run/surge.sock", false)
+	if err != nil || got != "unix:///run/surge.sock" {
+		t.Fatalf("got %q, %v", got, err)
+	}
+	if _, err := resolveConnectBaseURL("unix://surge.sock", false); err == nil {
+		t.Fatal("relative socket path accepted")
+	}
+	token, err := resolveTokenForTarget("unix:///run/surge.sock")
+	if err != nil || token != "" {
+		t.Fatalf("socket token = %q, %v", token, err)
+	}
+}
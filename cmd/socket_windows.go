@@ -0,0 +1,8 @@
+//go:build windows
+
+package cmd
+
+// restrictUmask does nothing on Windows, which has no umask
+func restrictUmask() func() {
+	return func() {}
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// speedLimit is the body and answer of /speed-limit, in bytes/sec with 0 for
+// unlimited. ID is set for a download's own limit and empty for the global
+// one.
+type speedLimit struct {
+	ID         string `json:"id,omitempty"`
+	SpeedLimit int64  `json:"speed_limit"`
+}
+
+// speedLimitHandler gets or sets the global speed limit, or with ?id= a
+// download's own, so clients can offer a bandwidth slider. Running downloads
+// follow a change at once. The global limit is saved to the settings.
+func speedLimitHandler(service core.DownloadService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Query().Get("id")
+		var req speedLimit
+		if r.Method == http.MethodPut {
+			if err := decodeJSONBody(r, &req); err != nil {
+				httpError(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if req.SpeedLimit < 0 {
+				httpError(w, "Speed limit cannot be negative", http.StatusBadRequest)
+				return
+			}
+		}
+
+		if id == "" {
+			settingsMu.Lock()
+			defer settingsMu.Unlock()
+			settings, err := config.LoadSettings()
+			if err != nil {
+				httpError(w, "Failed to load settings: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if r.Method == http.MethodPut && settings.Network.GlobalRateLimit != req.SpeedLimit {
+				settings.Network.GlobalRateLimit = req.SpeedLimit
+				if err := applySettings(service, settings); err != nil {
+					httpError(w, "Failed to save settings: "+err.Error(), http.StatusInternalServerError)
+					return
+				}
+			}
+			writeJSONResponse(w, http.StatusOK, speedLimit{SpeedLimit: settings.Network.GlobalRateLimit})
+			return
+		}
+
+		limiter, ok := service.(core.SpeedLimiter)
+		if !ok {
+			httpError(w, "Per-download speed limits are not supported", http.StatusNotImplemented)
+			return
+		}
+		if r.Method == http.MethodPut {
+			if err := limiter.SetSpeedLimit(id, req.SpeedLimit); err != nil {
+				writeSpeedLimitError(w, err)
+				return
+			}
+			writeJSONResponse(w, http.StatusOK, speedLimit{ID: id, SpeedLimit: req.SpeedLimit})
+			return
+		}
+		rate, err := limiter.SpeedLimit(id)
+		if err != nil {
+			writeSpeedLimitError(w, err)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, speedLimit{ID: id, SpeedLimit: rate})
+	}
+}
+
+func writeSpeedLimitError(w http.ResponseWriter, err error) {
+	if errors.Is(err, types.ErrNotFound) {
+		httpError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	httpError(w, err.Error(), http.StatusInternalServerError)
+}
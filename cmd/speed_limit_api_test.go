@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+type speedLimitService struct {
+	fakeRemoteDownloadService
+	limits map[string]int64
+}
+
+func (s *speedLimitService) SpeedLimit(id string) (int64, error) {
+	rate, ok := s.limits[id]
+	if !ok {
+		return 0, types.ErrNotFound
+	}
+	return rate, nil
+}
+
+func (s *speedLimitService) SetSpeedLimit(id string, rate int64) error {
+	if _, ok := s.limits[id]; !ok {
+		return types.ErrNotFound
+	}
+	s.limits[id] = rate
+	return nil
+}
+
+func TestSpeedLimitEndpoint(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	origSettings := globalSettings
+	t.Cleanup(func() { globalSettings = origSettings })
+	globalSettings = config.DefaultSettings()
+
+	const token = "limit-token"
+	service := &speedLimitService{limits: map[string]int64{"dl-1": 0}}
+	baseURL := startAuthedTestServer(t, service, token)
+
+	do := func(method, query, body string) (int, speedLimit) {
+		t.Helper()
+		req, _ := http.NewRequest(method, baseURL+"/speed-limit"+query, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s /speed-limit%s: %v", method, query, err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		var got speedLimit
+		data, _ := io.ReadAll(resp.Body)
+		_ = json.Unmarshal(data, &got)
+		return resp.StatusCode, got
+	}
+
+	if code, got := do(http.MethodGet, "", ""); code != http.StatusOK || got.SpeedLimit != 0 || got.ID != "" {
+		t.Fatalf("GET global = %d %+v", code, got)
+	}
+	if code, got := do(http.MethodPut, "", `{"speed_limit":1048576}`); code != http.StatusOK || got.SpeedLimit != 1048576 {
+		t.Fatalf("PUT global = %d %+v", code, got)
+	}
+	saved, err := config.LoadSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Network.GlobalRateLimit != 1048576 {
+		t.Fatalf("saved global_rate_limit = %d", saved.Network.GlobalRateLimit)
+	}
+	if code, got := do(http.MethodGet, "", ""); code != http.StatusOK || got.SpeedLimit != 1048576 {
+		t.Fatalf("GET global after PUT = %d %+v", code, got)
+	}
+
+	if code, got := do(http.MethodPut, "?id=dl-1", `{"speed_limit":4096}`); code != http.StatusOK || got.ID != "dl-1" || got.SpeedLimit != 4096 {
+		t.Fatalf("PUT download = %d %+v", code, got)
+	}
+	if code, got := do(http.MethodGet, "?id=dl-1", ""); code != http.StatusOK || got.SpeedLimit != 4096 {
+		t.Fatalf("GET download = %d %+v", code, got)
+	}
+	if code, _ := do(http.MethodGet, "?id=missing", ""); code != http.StatusNotFound {
+		t.Fatalf("GET missing download = %d, want 404", code)
+	}
+	if code, _ := do(http.MethodPut, "?id=dl-1", `{"speed_limit":-1}`); code != http.StatusBadRequest {
+		t.Fatalf("negative limit = %d, want 400", code)
+	}
+	if service.limits["dl-1"] != 4096 {
+		t.Fatalf("rejected PUT changed the limit to %d", service.limits["dl-1"])
+	}
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -12,7 +13,6 @@ import (
 	"github.com/surge-downloader/surge/internal/engine/state"
 	"github.com/surge-downloader/surge/internal/engine/types"
 	"github.com/surge-downloader/surge/internal/processing"
-	"github.com/surge-downloader/surge/internal/utils"
 )
 
 // TestServer_Startup_HandlesResume verifies that resumePausedDownloads() works for server mode
@@ -69,7 +69,7 @@ func TestServer_Startup_HandlesResume(t *testing.T) {
 	}
 }
 
-func TestStartupIntegrityCheck_RemovesMissingPausedEntry(t *testing.T) {
+func TestStartupIntegrityCheck_ReportsMissingPausedEntry(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "surge-startup-integrity-test")
 	if err != nil {
 		t.Fatal(err)
@@ -81,22 +81,36 @@ func TestStartupIntegrityCheck_RemovesMissingPausedEntry(t *testing.T) {
 	testID := "startup-integrity-missing-id"
 	testURL := "http://example.com/startup-integrity.bin"
 	testDest := filepath.Join(tmpDir, "startup-integrity.bin")
-	seedDownload(t, testID, testURL, testDest, "paused")
-
-	// Ensure .surge file is missing to simulate an orphaned paused DB entry.
-	if err := os.Remove(testDest + types.IncompleteSuffix); err != nil && !os.IsNotExist(err) {
-		t.Fatalf("failed to remove test .surge file: %v", err)
+	// A paused download whose .surge file is gone
+	if err := state.AddToMasterList(types.DownloadEntry{
+		ID:         testID,
+		URL:        testURL,
+		DestPath:   testDest,
+		Filename:   filepath.Base(testDest),
+		Status:     "paused",
+		TotalSize:  1000,
+		Downloaded: 500,
+	}); err != nil {
+		t.Fatal(err)
 	}
 
 	msg := runStartupIntegrityCheck()
-	utils.Debug("%s", msg)
+	if !strings.Contains(msg, "surge prune --orphans") {
+		t.Errorf("message = %q, want a pointer to surge prune --orphans", msg)
+	}
 
+	// Only the user removes orphans
 	entry, err := state.GetDownload(testID)
 	if err != nil {
 		t.Fatalf("GetDownload failed: %v", err)
 	}
-	if entry != nil {
-		t.Fatalf("expected missing paused entry to be removed, got %+v", entry)
+	if entry == nil {
+		t.Fatal("expected the paused entry to be kept")
+	}
+	settings := config.DefaultSettings()
+	settings.General.AutoResume = true
+	if len(resumableDownloads(settings)) != 0 {
+		t.Error("a download without its working file must not be auto-resumed")
 	}
 }
 
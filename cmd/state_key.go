@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/keychain"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// stateKeyAccount names the state key in the OS keychain
+const stateKeyAccount = "state-key"
+
+// stateKeyEnv holds a base64 state key, for machines without a keychain
+const stateKeyEnv = "SURGE_STATE_KEY"
+
+// mustConfigureStateSecrets is configureStateSecrets for the commands that
+// run the engine; storing headers in plain text when asked not to is fatal
+func mustConfigureStateSecrets() {
+	if err := configureStateSecrets(getSettings()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// configureStateSecrets loads the key encrypted state values are read with.
+// With encrypt_state on, a key is created in the keychain on first use and
+// every stored secret is encrypted. With it off, a database that still holds
+// encrypted values is decrypted, if the key can be found.
+func configureStateSecrets(settings *config.Settings) error {
+	encrypt := settings.General.EncryptState
+	if !encrypt {
+		sealed, err := state.HasSealedSecrets()
+		if err != nil || !sealed {
+			return err
+		}
+	}
+
+	key, err := loadStateKey(encrypt)
+	if err != nil {
+		if !encrypt {
+			utils.Debug("State: encrypted headers stay unreadable: %v", err)
+			return nil
+		}
+		return fmt.Errorf("encrypt_state: %w", err)
+	}
+	if err := state.ConfigureSecrets(key, encrypt); err != nil {
+		return err
+	}
+
+	changed, err := state.ResealSecrets()
+	if err != nil {
+		return err
+	}
+	if changed > 0 {
+		action := "decrypted"
+		if encrypt {
+			action = "encrypted"
+		}
+		utils.Debug("State: %s %d stored values", action, changed)
+	}
+	return nil
+}
+
+// loadStateKey returns the state key from SURGE_STATE_KEY or the keychain,
+// creating one in the keychain when create is set and none is there yet
+func loadStateKey(create bool) ([]byte, error) {
+	if v := strings.TrimSpace(os.Getenv(stateKeyEnv)); v != "" {
+		key, err := base64.StdEncoding.DecodeString(v)
+		if err != nil || len(key) != state.SecretKeySize {
+			return nil, fmt.Errorf("%s must be %d base64-encoded bytes", stateKeyEnv, state.SecretKeySize)
+		}
+		return key, nil
+	}
+
+	key, err := keychain.Get(stateKeyAccount)
+	if !errors.Is(err, keychain.ErrNotFound) || !create {
+		return key, err
+	}
+	if key, err = state.NewSecretKey(); err != nil {
+		return nil, err
+	}
+	if err := keychain.Set(stateKeyAccount, key); err != nil {
+		return nil, fmt.Errorf("failed to store the state key: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestConfigureStateSecrets_EncryptsAndDecryptsWithEnvKey(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "surge-state-key-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	setupTestEnv(t, tmpDir)
+	defer state.CloseDB()
+	defer func() { _ = state.ConfigureSecrets(nil, false) }()
+
+	key, err := state.NewSecretKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(stateKeyEnv, base64.StdEncoding.EncodeToString(key))
+
+	if err := state.AddToMasterList(types.DownloadEntry{
+		ID:        "secret-id",
+		URL:       "https://example.com/private.bin",
+		DestPath:  tmpDir + "/private.bin",
+		Status:    "queued",
+		Overrides: &types.DownloadOverrides{Headers: map[string]string{"Authorization": "Bearer abc"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	settings := config.DefaultSettings()
+	settings.General.EncryptState = true
+	if err := configureStateSecrets(settings); err != nil {
+		t.Fatalf("configureStateSecrets failed: %v", err)
+	}
+	if sealed, err := state.HasSealedSecrets(); err != nil || !sealed {
+		t.Fatalf("HasSealedSecrets = %v, %v; want the headers encrypted", sealed, err)
+	}
+	if o, _ := state.GetOverrides("secret-id"); o == nil || o.Headers["Authorization"] != "Bearer abc" {
+		t.Fatalf("overrides = %+v, want them readable with the key", o)
+	}
+
+	// Turning the setting off decrypts what was encrypted
+	_ = state.ConfigureSecrets(nil, false)
+	settings.General.EncryptState = false
+	if err := configureStateSecrets(settings); err != nil {
+		t.Fatalf("configureStateSecrets failed: %v", err)
+	}
+	if sealed, _ := state.HasSealedSecrets(); sealed {
+		t.Error("expected the headers to be decrypted")
+	}
+
+	t.Setenv(stateKeyEnv, "not-a-key")
+	settings.General.EncryptState = true
+	if err := configureStateSecrets(settings); err == nil || !strings.Contains(err.Error(), stateKeyEnv) {
+		t.Errorf("expected an invalid %s to be reported, got %v", stateKeyEnv, err)
+	}
+}
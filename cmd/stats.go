@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show download statistics",
+	Long: `Show how much has been downloaded, how fast and from where: completed and
+failed downloads, bytes, average speed and failure rate over all time and over
+the recent window given by --since, and the hosts that served the most in that
+window. Reads the state database on this machine, so it works with or without
+Surge running.`,
+	Example: `  surge stats
+  surge stats --since 24h --hosts 10
+  surge stats --since 2026-01-01 --json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		mustInitializeGlobalState()
+
+		sinceFlag, _ := cmd.Flags().GetString("since")
+		topHosts, _ := cmd.Flags().GetInt("hosts")
+		since, err := core.ParseSince(sinceFlag, time.Now())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --since: %v\n", err)
+			os.Exit(1)
+		}
+
+		lifetime, err := state.LoadDownloadStats(0, topHosts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		recent, err := state.LoadDownloadStats(since, topHosts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		printStats(os.Stdout, sinceFlag, lifetime, recent, globalJSON)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+	statsCmd.Flags().String("since", "7d", "Recent window: an age like 24h or 7d, or a date (YYYY-MM-DD)")
+	statsCmd.Flags().Int("hosts", 5, "How many of the busiest hosts to list")
+}
+
+// printStats writes lifetime and recent statistics side by side, then the
+// busiest hosts of the recent window, or both as one JSON object
+func printStats(w io.Writer, window string, lifetime, recent *state.DownloadStats, jsonOutput bool) {
+	if jsonOutput {
+		data, _ := json.MarshalIndent(map[string]*state.DownloadStats{"lifetime": lifetime, "recent": recent}, "", "  ")
+		_, _ = fmt.Fprintln(w, string(data))
+		return
+	}
+
+	speed := func(s *state.DownloadStats) string {
+		if s.AvgSpeed <= 0 {
+			return "-"
+		}
+		return utils.ConvertBytesToHumanReadable(int64(s.AvgSpeed)) + "/s"
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "\tSINCE %s\tALL TIME\n", window)
+	_, _ = fmt.Fprintf(tw, "Completed\t%d\t%d\n", recent.Completed, lifetime.Completed)
+	_, _ = fmt.Fprintf(tw, "Failed\t%d\t%d\n", recent.Failed, lifetime.Failed)
+	_, _ = fmt.Fprintf(tw, "Failure rate\t%.1f%%\t%.1f%%\n", recent.FailureRate*100, lifetime.FailureRate*100)
+	_, _ = fmt.Fprintf(tw, "Downloaded\t%s\t%s\n", utils.ConvertBytesToHumanReadable(recent.TotalBytes), utils.ConvertBytesToHumanReadable(lifetime.TotalBytes))
+	_, _ = fmt.Fprintf(tw, "Average speed\t%s\t%s\n", speed(recent), speed(lifetime))
+	_ = tw.Flush()
+
+	if len(recent.Hosts) == 0 {
+		return
+	}
+	_, _ = fmt.Fprintf(w, "\nBusiest hosts since %s\n", window)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "HOST\tCOMPLETED\tFAILED\tDOWNLOADED")
+	for _, h := range recent.Hosts {
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", h.Host, h.Completed, h.Failed, utils.ConvertBytesToHumanReadable(h.TotalBytes))
+	}
+	_ = tw.Flush()
+}
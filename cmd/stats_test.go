@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+)
+
+func TestPrintStats(t *testing.T) {
+	lifetime := &state.DownloadStats{Completed: 4, Failed: 1, TotalBytes: 4 << 20, AvgSpeed: 1 << 20, FailureRate: 0.2,
+		Hosts: []state.HostTotal{{Host: "cdn.example.com", Completed: 4, TotalBytes: 4 << 20}}}
+	recent := &state.DownloadStats{Completed: 1, TotalBytes: 1 << 20, Hosts: []state.HostTotal{{Host: "cdn.example.com", Completed: 1, TotalBytes: 1 << 20}}}
+
+	var buf bytes.Buffer
+	printStats(&buf, "7d", lifetime, recent, false)
+	out := buf.String()
+	for _, want := range []string{"SINCE 7d", "ALL TIME", "20.0%", "Busiest hosts since 7d", "cdn.example.com"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	printStats(&buf, "7d", lifetime, recent, true)
+	var decoded map[string]state.DownloadStats
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if decoded["lifetime"].Completed != 4 || decoded["recent"].TotalBytes != 1<<20 {
+		t.Errorf("decoded = %+v", decoded)
+	}
+}
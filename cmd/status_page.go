@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// statusPageRefresh is how often the HTML status page reloads itself
+const statusPageRefresh = 5 * time.Second
+
+// publicDownload is the redacted view of a download shown on the status page.
+// It must never carry URLs, paths or IDs since the page is unauthenticated.
+type publicDownload struct {
+	Name       string  `json:"name,omitempty"`
+	Status     string  `json:"status"`
+	Progress   float64 `json:"progress"`
+	TotalSize  int64   `json:"total_size,omitempty"`
+	Downloaded int64   `json:"downloaded,omitempty"`
+	Speed      float64 `json:"speed,omitempty"` // MB/s
+	ETA        int64   `json:"eta,omitempty"`   // Seconds remaining
+}
+
+type publicStatus struct {
+	Active     int              `json:"active"`
+	TotalSpeed float64          `json:"total_speed,omitempty"` // MB/s
+	Downloads  []publicDownload `json:"downloads"`
+}
+
+// buildPublicStatus lists unfinished downloads with only the fields the
+// privacy settings allow
+func buildPublicStatus(service core.DownloadService, opts config.StatusPageSettings) (publicStatus, error) {
+	statuses, err := service.List()
+	if err != nil {
+		return publicStatus{}, err
+	}
+
+	out := publicStatus{Downloads: []publicDownload{}}
+	for _, s := range statuses {
+		if s.Status == "completed" || s.Status == "error" {
+			continue
+		}
+
+		d := publicDownload{
+			Status:   s.Status,
+			Progress: s.Progress,
+		}
+		if opts.ShowFilenames {
+			d.Name = s.Filename
+		}
+		if opts.ShowSizes {
+			d.TotalSize = s.TotalSize
+			d.Downloaded = s.Downloaded
+		}
+		if opts.ShowSpeed {
+			d.Speed = s.Speed
+			out.TotalSpeed += s.Speed
+		}
+		if opts.ShowETA {
+			d.ETA = s.ETA
+		}
+
+		if s.Status == "downloading" {
+			out.Active++
+		}
+		out.Downloads = append(out.Downloads, d)
+	}
+	return out, nil
+}
+
+var statusPageTemplate = template.Must(template.New("status").Funcs(template.FuncMap{
+	"bytes": utils.ConvertBytesToHumanReadable,
+	"eta": func(seconds int64) string {
+		return (time.Duration(seconds) * time.Second).String()
+	},
+	"inc": func(i int) int { return i + 1 },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="{{.Refresh}}">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Surge</title>
+<style>
+body { font-family: sans-serif; background: #111; color: #eee; margin: 2em; }
+.dl { margin-bottom: 1.5em; }
+.bar { background: #333; height: 1em; border-radius: 4px; overflow: hidden; }
+.fill { background: #7d56f4; height: 100%; }
+.meta { color: #aaa; font-size: 0.9em; margin-top: 0.3em; }
+</style>
+</head>
+<body>
+<h1>Surge · {{.Status.Active}} active{{if .Status.TotalSpeed}} · {{printf "%.1f" .Status.TotalSpeed}} MB/s{{end}}</h1>
+{{range $i, $d := .Status.Downloads}}
+<div class="dl">
+<div>{{if $d.Name}}{{$d.Name}}{{else}}Download {{inc $i}}{{end}} · {{$d.Status}} · {{printf "%.1f" $d.Progress}}%</div>
+<div class="bar"><div class="fill" style="width: {{printf "%.1f" $d.Progress}}%"></div></div>
+<div class="meta">{{if $d.TotalSize}}{{bytes $d.Downloaded}} / {{bytes $d.TotalSize}} {{end}}{{if $d.Speed}}· {{printf "%.1f" $d.Speed}} MB/s {{end}}{{if $d.ETA}}· {{eta $d.ETA}} left{{end}}</div>
+</div>
+{{else}}
+<p>No active downloads.</p>
+{{end}}
+</body>
+</html>
+`))
+
+// newStatusPageHandler serves the read-only status page and its JSON feed
+func newStatusPageHandler(service core.DownloadService, opts config.StatusPageSettings) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/status.json", requireMethod(http.MethodGet, func(w http.ResponseWriter, _ *http.Request) {
+		status, err := buildPublicStatus(service, opts)
+		if err != nil {
+			httpError(w, "Status unavailable", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSONResponse(w, http.StatusOK, status)
+	}))
+
+	mux.HandleFunc("/", requireMethod(http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		status, err := buildPublicStatus(service, opts)
+		if err != nil {
+			http.Error(w, "Status unavailable", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		data := struct {
+			Refresh int
+			Status  publicStatus
+		}{int(statusPageRefresh.Seconds()), status}
+		if err := statusPageTemplate.Execute(w, data); err != nil {
+			utils.Debug("Failed to render status page: %v", err)
+		}
+	}))
+
+	return mux
+}
+
+// startStatusPage starts the status page on its own port if enabled in
+// settings or by portOverride (> 0). It returns the port served, or 0 when
+// the page is disabled.
+func startStatusPage(service core.DownloadService, portOverride int) (int, error) {
+	settings, err := config.LoadSettings()
+	if err != nil {
+		settings = config.DefaultSettings()
+	}
+	opts := settings.StatusPage
+	if portOverride > 0 {
+		opts.Enabled = true
+		opts.Port = portOverride
+	}
+	if !opts.Enabled || opts.Port <= 0 {
+		return 0, nil
+	}
+
+	ln, err := net.Listen("tcp", net.JoinHostPort(serverBindAddress(), strconv.Itoa(opts.Port)))
+	if err != nil {
+		return 0, fmt.Errorf("could not bind status page to port %d: %w", opts.Port, err)
+	}
+
+	go func() {
+		server := &http.Server{Handler: newStatusPageHandler(service, opts), ReadHeaderTimeout: 10 * time.Second}
+		if err := server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			utils.Debug("Status page server error: %v", err)
+		}
+	}()
+	return opts.Port, nil
+}
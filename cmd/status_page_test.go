@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+type statusPageTestService struct {
+	fakeRemoteDownloadService
+	statuses []types.DownloadStatus
+}
+
+func (s *statusPageTestService) List() ([]types.DownloadStatus, error) {
+	return s.statuses, nil
+}
+
+func newStatusPageTestService() *statusPageTestService {
+	return &statusPageTestService{statuses: []types.DownloadStatus{
+		{
+			ID:         "secret-id-1",
+			URL:        "https://private.example/secret.iso",
+			Filename:   "secret.iso",
+			DestPath:   "/home/user/secret.iso",
+			TotalSize:  1000,
+			Downloaded: 500,
+			Progress:   50,
+			Speed:      2.5,
+			Status:     "downloading",
+			ETA:        200,
+		},
+		{
+			ID:       "secret-id-2",
+			URL:      "https://private.example/done.zip",
+			Filename: "done.zip",
+			Progress: 100,
+			Status:   "completed",
+		},
+	}}
+}
+
+func TestStatusPage_JSONRedactsPrivateFields(t *testing.T) {
+	svc := newStatusPageTestService()
+	handler := newStatusPageHandler(svc, config.DefaultSettings().StatusPage)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 without auth, got %d", rec.Code)
+	}
+
+	body := rec.Body.String()
+	for _, leak := range []string{"private.example", "secret-id", "/home/user", "secret.iso"} {
+		if strings.Contains(body, leak) {
+			t.Errorf("status page leaked %q: %s", leak, body)
+		}
+	}
+
+	var status publicStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if status.Active != 1 || len(status.Downloads) != 1 {
+		t.Fatalf("expected only the active download, got %+v", status)
+	}
+	d := status.Downloads[0]
+	if d.Progress != 50 || d.Speed != 2.5 || d.ETA != 200 || d.TotalSize != 1000 {
+		t.Errorf("expected default-visible fields, got %+v", d)
+	}
+}
+
+func TestStatusPage_PrivacyToggles(t *testing.T) {
+	svc := newStatusPageTestService()
+	opts := config.StatusPageSettings{ShowFilenames: true}
+	status, err := buildPublicStatus(svc, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := status.Downloads[0]
+	if d.Name != "secret.iso" {
+		t.Errorf("expected filename when enabled, got %q", d.Name)
+	}
+	if d.Speed != 0 || d.ETA != 0 || d.TotalSize != 0 || d.Downloaded != 0 || status.TotalSpeed != 0 {
+		t.Errorf("expected hidden fields to be zeroed, got %+v", status)
+	}
+	if d.Progress != 50 {
+		t.Errorf("expected progress to always be shown, got %v", d.Progress)
+	}
+}
+
+func TestStatusPage_HTMLIsReadOnly(t *testing.T) {
+	server := httptest.NewServer(newStatusPageHandler(newStatusPageTestService(), config.DefaultSettings().StatusPage))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if strings.Contains(string(body), "private.example") || !strings.Contains(string(body), "50.0%") {
+		t.Errorf("unexpected page body: %s", body)
+	}
+
+	resp, err = http.Post(server.URL+"/status.json", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected writes to be rejected, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(server.URL + "/list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected API routes to be absent, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// statusPollResponse is one /status/poll batch: the current status of every
+// download that changed, once each however many events it had. Full means
+// Downloads holds every download and replaces what the client has, as on
+// the first poll or after events were missed. Removed lists downloads that
+// changed but are gone.
+type statusPollResponse struct {
+	Seq       uint64                 `json:"seq"`
+	Full      bool                   `json:"full,omitempty"`
+	Downloads []types.DownloadStatus `json:"downloads"`
+	Removed   []string               `json:"removed,omitempty"`
+}
+
+// statusPollHandler long-polls like /events/poll but answers with download
+// statuses instead of events, for clients that would rather not apply
+// events themselves. Without since it returns every download and the seq
+// to poll from.
+func statusPollHandler(el *eventLog, service core.DownloadService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		wait, ok := pollWait(q)
+		if !ok {
+			httpError(w, "Invalid wait parameter", http.StatusBadRequest)
+			return
+		}
+		var since uint64
+		v := q.Get("since")
+		if v != "" {
+			var err error
+			if since, err = strconv.ParseUint(v, 10, 64); err != nil {
+				httpError(w, "Invalid since parameter", http.StatusBadRequest)
+				return
+			}
+		}
+
+		if err := el.start(service); err != nil {
+			httpError(w, "Failed to subscribe to events", http.StatusInternalServerError)
+			return
+		}
+
+		var evs []loggedEvent
+		seq, missed := el.last(), true
+		if v != "" {
+			if evs, seq, missed, ok = waitForEvents(r.Context(), el, since, wait); !ok {
+				return
+			}
+		}
+		if missed {
+			// The seq is taken first, so changes made while listing come again
+			statuses, err := service.List()
+			if err != nil {
+				httpError(w, "Failed to list downloads", http.StatusInternalServerError)
+				return
+			}
+			if statuses == nil {
+				statuses = []types.DownloadStatus{}
+			}
+			writeJSONResponse(w, http.StatusOK, statusPollResponse{Seq: seq, Full: true, Downloads: statuses})
+			return
+		}
+
+		resp := statusPollResponse{Seq: seq, Downloads: []types.DownloadStatus{}}
+		seen := make(map[string]bool)
+		for _, ev := range evs {
+			var ref struct {
+				DownloadID string
+			}
+			if json.Unmarshal(ev.Data, &ref) != nil || ref.DownloadID == "" || seen[ref.DownloadID] {
+				continue
+			}
+			seen[ref.DownloadID] = true
+			status, err := service.GetStatus(ref.DownloadID)
+			if err != nil || status == nil {
+				resp.Removed = append(resp.Removed, ref.DownloadID)
+				continue
+			}
+			resp.Downloads = append(resp.Downloads, *status)
+		}
+		writeJSONResponse(w, http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+type statusPollService struct {
+	streamingService
+	statuses map[string]types.DownloadStatus
+}
+
+func (s *statusPollService) List() ([]types.DownloadStatus, error) {
+	var out []types.DownloadStatus
+	for _, st := range s.statuses {
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+func (s *statusPollService) GetStatus(id string) (*types.DownloadStatus, error) {
+	st, ok := s.statuses[id]
+	if !ok {
+		return nil, errors.New("download not found")
+	}
+	return &st, nil
+}
+
+func TestStatusPollEndpoint(t *testing.T) {
+	svc := &statusPollService{
+		streamingService: streamingService{ch: make(chan interface{})},
+		statuses: map[string]types.DownloadStatus{
+			"a": {ID: "a", Status: "downloading"},
+			"b": {ID: "b", Status: "queued"},
+		},
+	}
+	const token = "status-poll-token"
+	baseURL := startAuthedTestServer(t, svc, token)
+
+	poll := func(query string) statusPollResponse {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, baseURL+"/status/poll?"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET /status/poll?%s: %v", query, err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET /status/poll?%s: status %d", query, resp.StatusCode)
+		}
+		var out statusPollResponse
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return out
+	}
+
+	start := poll("")
+	if !start.Full || start.Seq != 0 || len(start.Downloads) != 2 {
+		t.Fatalf("bootstrap = %+v", start)
+	}
+
+	// Several events for a download come back as its one current status
+	svc.statuses["a"] = types.DownloadStatus{ID: "a", Status: "paused"}
+	delete(svc.statuses, "b")
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		svc.ch <- events.BatchProgressMsg{{DownloadID: "a"}, {DownloadID: "b"}}
+		svc.ch <- events.DownloadPausedMsg{DownloadID: "a"}
+	}()
+	var got statusPollResponse
+	deadline := time.Now().Add(2 * time.Second)
+	for got.Seq < 3 && time.Now().Before(deadline) {
+		next := poll(fmt.Sprintf("since=%d&wait=5", got.Seq))
+		got.Seq = next.Seq
+		got.Downloads = append(got.Downloads, next.Downloads...)
+		got.Removed = append(got.Removed, next.Removed...)
+		if next.Full {
+			t.Fatalf("incremental poll returned a full list: %+v", next)
+		}
+	}
+	if got.Seq != 3 {
+		t.Fatalf("seq = %d, want 3", got.Seq)
+	}
+	if len(got.Removed) != 1 || got.Removed[0] != "b" {
+		t.Errorf("removed = %v, want [b]", got.Removed)
+	}
+	last := got.Downloads[len(got.Downloads)-1]
+	if last.ID != "a" || last.Status != "paused" {
+		t.Errorf("last status = %+v, want a paused", last)
+	}
+
+	if empty := poll("since=3&wait=0"); empty.Seq != 3 || empty.Full || len(empty.Downloads) != 0 {
+		t.Errorf("timed-out poll = %+v", empty)
+	}
+	if reset := poll("since=50&wait=0"); !reset.Full || reset.Seq != 3 || len(reset.Downloads) != 1 {
+		t.Errorf("poll past the log = %+v, want the full list", reset)
+	}
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/core"
+)
+
+var stopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Shut down the running Surge daemon gracefully",
+	Long: `Ask the running daemon to stop accepting downloads, pause the running ones
+and persist their progress before exiting. With --finish, running downloads
+complete first and waiting ones stay paused for the next start.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		mustInitializeGlobalState()
+
+		finish, _ := cmd.Flags().GetBool("finish")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		query, err := shutdownQuery(finish, timeout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		baseURL, token, err := resolveAPIConnection(true)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to connect to Surge server: %v\n", err)
+			os.Exit(1)
+		}
+		resp, err := doAPIRequest(http.MethodPost, baseURL, token, "/shutdown?"+query, nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to send request to server: %v\n", err)
+			os.Exit(1)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		if resp.StatusCode != http.StatusOK {
+			fmt.Fprintf(os.Stderr, "Server error: %s - %s\n", resp.Status, core.ReadAPIError(resp).Detail)
+			os.Exit(1)
+		}
+
+		if finish {
+			fmt.Println("Surge stops once running downloads finish.")
+		} else {
+			fmt.Println("Surge is pausing downloads and shutting down.")
+		}
+	},
+}
+
+func init() {
+	stopCmd.Flags().Bool("finish", false, "Let running downloads finish before exiting")
+	stopCmd.Flags().Duration("timeout", 0, "With --finish, pause what still runs after this long")
+	rootCmd.AddCommand(stopCmd)
+}
+
+// shutdownQuery returns the /shutdown query for surge stop's flags
+func shutdownQuery(finish bool, timeout time.Duration) (string, error) {
+	q := url.Values{}
+	q.Set("mode", shutdownPause)
+	if finish {
+		q.Set("mode", shutdownFinish)
+	}
+	if timeout > 0 {
+		if !finish {
+			return "", fmt.Errorf("--timeout needs --finish")
+		}
+		q.Set("timeout", timeout.String())
+	}
+	return q.Encode(), nil
+}
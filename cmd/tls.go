@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// selfSignedValidity is how long a generated certificate lasts; an expired
+// one is replaced on the next start
+const selfSignedValidity = 10 * 365 * 24 * time.Hour
+
+// selfSignedPaths returns where the generated certificate and key are kept
+func selfSignedPaths() (string, string) {
+	dir := filepath.Join(config.GetSurgeDir(), "tls")
+	return filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+}
+
+// serverTLSConfig returns the TLS config the API is served with and the
+// certificate file clients can trust, or nil when settings leave TLS off.
+// Without a configured certificate a self-signed one is generated and reused.
+func serverTLSConfig(s config.ServerSettings) (*tls.Config, string, error) {
+	if !s.TLS {
+		return nil, "", nil
+	}
+	certFile, keyFile := s.TLSCert, s.TLSKey
+	if certFile == "" {
+		certFile, keyFile = selfSignedPaths()
+		if err := ensureSelfSignedCert(certFile, keyFile); err != nil {
+			return nil, "", fmt.Errorf("could not generate TLS certificate: %w", err)
+		}
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, "", fmt.Errorf("could not load TLS certificate: %w", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, certFile, nil
+}
+
+// ensureSelfSignedCert writes a self-signed certificate for this machine's
+// names and addresses unless a current one is already there
+func ensureSelfSignedCert(certFile, keyFile string) error {
+	if cert, err := tls.LoadX509KeyPair(certFile, keyFile); err == nil {
+		if leaf, err := x509.ParseCertificate(cert.Certificate[0]); err == nil && time.Now().Before(leaf.NotAfter) {
+			return nil
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+	names, ips := certificateHosts()
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "Surge"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              names,
+		IPAddresses:           ips,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(certFile), 0o700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		return err
+	}
+	return os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644)
+}
+
+// certificateHosts lists the names and addresses a generated certificate
+// covers: localhost, the hostname and every interface address
+func certificateHosts() ([]string, []net.IP) {
+	names := []string{"localhost"}
+	if host, err := os.Hostname(); err == nil && host != "" && host != "localhost" {
+		names = append(names, host)
+	}
+	ips := []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() {
+				ips = append(ips, ipNet.IP)
+			}
+		}
+	}
+	return names, ips
+}
+
+// certFingerprint returns the SHA-256 fingerprint of the PEM certificate in
+// certFile, for checking it on another machine
+func certFingerprint(certFile string) string {
+	data, err := os.ReadFile(certFile)
+	if err != nil {
+		return ""
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return ""
+	}
+	sum := sha256.Sum256(block.Bytes)
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// enableServerTLS wraps the API listener in TLS when settings ask for it and
+// records the certificate so local clients switch to https and trust it
+func enableServerTLS(ln net.Listener) (net.Listener, string, error) {
+	cfg, certFile, err := serverTLSConfig(getSettings().Server)
+	if err != nil || cfg == nil {
+		return ln, "", err
+	}
+	saveActiveTLSCert(certFile)
+	return tls.NewListener(ln, cfg), certFile, nil
+}
+
+// saveActiveTLSCert records the certificate of a server serving https, next
+// to the port file
+func saveActiveTLSCert(certFile string) {
+	if abs, err := filepath.Abs(certFile); err == nil {
+		certFile = abs
+	}
+	tlsFile := filepath.Join(config.GetRuntimeDir(), "tls")
+	if err := os.WriteFile(tlsFile, []byte(certFile), 0o644); err != nil {
+		utils.Debug("Error writing TLS file: %v", err)
+	}
+}
+
+// removeActiveTLSCert cleans up the TLS file on exit
+func removeActiveTLSCert() {
+	tlsFile := filepath.Join(config.GetRuntimeDir(), "tls")
+	if err := os.Remove(tlsFile); err != nil && !os.IsNotExist(err) {
+		utils.Debug("Error removing TLS file: %v", err)
+	}
+}
+
+// readActiveTLSCert returns the certificate file of a local server serving
+// https, or "" when it serves plain http
+func readActiveTLSCert() string {
+	data, err := os.ReadFile(filepath.Join(config.GetRuntimeDir(), "tls"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// localBaseURL returns the URL of the local server on port
+func localBaseURL(port int) string {
+	if readActiveTLSCert() != "" {
+		return fmt.Sprintf("https://127.0.0.1:%d", port)
+	}
+	return fmt.Sprintf("http://127.0.0.1:%d", port)
+}
+
+// clientTLSConfig returns the TLS config API clients verify https servers
+// with: the system roots plus the local server's certificate and the one
+// given by --ca-cert or SURGE_CA_CERT. It is nil when there is nothing to add.
+func clientTLSConfig() (*tls.Config, error) {
+	var files []string
+	if local := readActiveTLSCert(); local != "" {
+		files = append(files, local)
+	}
+	caCert := strings.TrimSpace(globalCACert)
+	if caCert == "" {
+		caCert = strings.TrimSpace(os.Getenv("SURGE_CA_CERT"))
+	}
+	if caCert != "" {
+		files = append(files, caCert)
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			if f != caCert {
+				continue // A stale record of a server that is gone
+			}
+			return nil, fmt.Errorf("could not read CA certificate: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no PEM certificate in %s", f)
+		}
+	}
+	return &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}, nil
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/config"
+)
+
+func TestServerTLSConfig_SelfSigned(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	if cfg, _, err := serverTLSConfig(config.ServerSettings{}); cfg != nil || err != nil {
+		t.Fatalf("TLS off: got %v, %v", cfg, err)
+	}
+
+	cfg, certFile, err := serverTLSConfig(config.ServerSettings{TLS: true})
+	if err != nil || cfg == nil {
+		t.Fatalf("self-signed: %v", err)
+	}
+	first := certFingerprint(certFile)
+	if first == "" {
+		t.Fatalf("no certificate written to %s", certFile)
+	}
+	if _, _, err := serverTLSConfig(config.ServerSettings{TLS: true}); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if again := certFingerprint(certFile); again != first {
+		t.Fatal("certificate regenerated although still valid")
+	}
+
+	if _, _, err := serverTLSConfig(config.ServerSettings{TLS: true, TLSCert: "missing.pem", TLSKey: "missing.key"}); err == nil {
+		t.Fatal("missing certificate accepted")
+	}
+}
+
+func TestStartHTTPServer_TLS(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	t.Setenv("SURGE_CA_CERT", "")
+	if err := os.MkdirAll(config.GetRuntimeDir(), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	origSettings := globalSettings
+	t.Cleanup(func() { globalSettings = origSettings })
+	globalSettings = config.DefaultSettings()
+	globalSettings.Server.TLS = true
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	tlsLn, certFile, err := enableServerTLS(ln)
+	if err != nil {
+		t.Fatalf("enable TLS: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = tlsLn.Close()
+		removeActiveTLSCert()
+	})
+	if readActiveTLSCert() == "" || certFile == "" {
+		t.Fatal("active certificate not recorded")
+	}
+	go startHTTPServer(tlsLn, nil, port, "", &fakeRemoteDownloadService{}, "tls-token")
+
+	baseURL := localBaseURL(port)
+	if !strings.HasPrefix(baseURL, "https://") {
+		t.Fatalf("local base URL = %q, want https", baseURL)
+	}
+	resp, err := doAPIRequest(http.MethodGet, baseURL, "tls-token", "/list", nil)
+	if err != nil {
+		t.Fatalf("list over https: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+
+	// A client that doesn't trust the self-signed certificate is refused
+	if resp, err := http.Get(baseURL + "/health"); err == nil {
+		_ = resp.Body.Close()
+		t.Fatal("untrusted client connected")
+	}
+}
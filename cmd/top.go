@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// topActiveStatuses are the downloads surge top lists without --all
+var topActiveStatuses = []string{"downloading", "pausing", "queued"}
+
+var topCmd = &cobra.Command{
+	Use:   "top",
+	Short: "Watch running downloads live",
+	Long: `Show the running downloads of Surge with their speed, ETA and connections,
+refreshed in place every --interval until Ctrl+C, like docker stats. Plain
+terminal output, so it suits SSH sessions and small terminals where the TUI
+is too much. Needs Surge to be running.`,
+	Example: `  surge top
+  surge top --all --interval 5s
+  surge top --host nas:1700`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		interval, _ := cmd.Flags().GetDuration("interval")
+		all, _ := cmd.Flags().GetBool("all")
+		if interval < 100*time.Millisecond {
+			fmt.Fprintln(os.Stderr, "Error: --interval must be at least 100ms")
+			os.Exit(1)
+		}
+
+		baseURL, token, err := resolveAPIConnection(true)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			downloads, err := GetRemoteDownloads(baseURL, token)
+			// Home the cursor and clear the screen, as ls --watch does
+			fmt.Print("\033[H\033[2J")
+			if err != nil {
+				fmt.Printf("surge top - %s\n\nCannot reach Surge: %v\n", time.Now().Format("15:04:05"), err)
+			} else {
+				renderTop(os.Stdout, downloads, all, time.Now())
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(topCmd)
+	topCmd.Flags().Duration("interval", time.Second, "Time between refreshes")
+	topCmd.Flags().Bool("all", false, "Also list paused, failed and completed downloads")
+}
+
+// renderTop writes one frame of surge top: a summary line, then the
+// downloads running first, fastest first, and the queue in its order
+func renderTop(w io.Writer, downloads []types.DownloadStatus, all bool, now time.Time) {
+	var shown []types.DownloadStatus
+	var totalSpeed float64
+	active, queued := 0, 0
+	for _, d := range downloads {
+		switch d.Status {
+		case "downloading", "pausing":
+			active++
+			totalSpeed += d.Speed
+		case "queued":
+			queued++
+		}
+		if all || slices.Contains(topActiveStatuses, d.Status) {
+			shown = append(shown, d)
+		}
+	}
+	sort.SliceStable(shown, func(i, j int) bool {
+		a, b := topRank(shown[i].Status), topRank(shown[j].Status)
+		if a != b {
+			return a < b
+		}
+		if shown[i].Speed != shown[j].Speed {
+			return shown[i].Speed > shown[j].Speed
+		}
+		return shown[i].QueuePosition < shown[j].QueuePosition
+	})
+
+	_, _ = fmt.Fprintf(w, "surge top - %s - %d downloading, %d queued, %s total\n\n",
+		now.Format("15:04:05"), active, queued, formatTopSpeed(totalSpeed))
+	if len(shown) == 0 {
+		_, _ = fmt.Fprintln(w, "Nothing is downloading.")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ID\tFILENAME\tSTATUS\tPROGRESS\tDOWNLOADED\tSPEED\tETA\tCONNS")
+	for _, d := range shown {
+		id := d.ID
+		if len(id) > 8 {
+			id = id[:8]
+		}
+		filename := d.Filename
+		if len(filename) > 30 {
+			filename = filename[:27] + "..."
+		}
+		size := "?"
+		if d.TotalSize > 0 {
+			size = utils.ConvertBytesToHumanReadable(d.TotalSize)
+		}
+		speed, eta, conns := "-", "-", "-"
+		if d.Status == "downloading" {
+			speed = formatTopSpeed(d.Speed)
+			eta = formatETA(d.ETA)
+			conns = fmt.Sprint(d.Connections)
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%.1f%%\t%s / %s\t%s\t%s\t%s\n",
+			id, filename, d.Status, d.Progress, utils.ConvertBytesToHumanReadable(d.Downloaded), size, speed, eta, conns)
+	}
+	_ = tw.Flush()
+}
+
+// topRank orders statuses in surge top: running, then waiting, then the rest
+func topRank(status string) int {
+	switch status {
+	case "downloading", "pausing":
+		return 0
+	case "queued":
+		return 1
+	}
+	return 2
+}
+
+// formatTopSpeed formats a speed in MB/s, as the API reports it
+func formatTopSpeed(mbps float64) string {
+	if mbps <= 0 {
+		return "0 B/s"
+	}
+	return utils.ConvertBytesToHumanReadable(int64(mbps*float64(types.MB))) + "/s"
+}
+
+// formatETA formats seconds remaining as e.g. 1h02m, 3m05s or 42s, and "-"
+// when unknown
+func formatETA(seconds int64) string {
+	if seconds <= 0 {
+		return "-"
+	}
+	d := time.Duration(seconds) * time.Second
+	switch {
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	}
+	return fmt.Sprintf("%ds", seconds)
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestRenderTop(t *testing.T) {
+	downloads := []types.DownloadStatus{
+		{ID: "done0000", Filename: "old.iso", Status: "completed", Progress: 100},
+		{ID: "queued00", Filename: "next.iso", Status: "queued", QueuePosition: 3},
+		{ID: "slow0000", Filename: "slow.iso", Status: "downloading", Speed: 1, ETA: 90, Connections: 2, TotalSize: 100 * types.MB, Downloaded: 10 * types.MB, Progress: 10},
+		{ID: "fast0000", Filename: "fast.iso", Status: "downloading", Speed: 8, ETA: 7300, Connections: 16, TotalSize: 4 * 1024 * types.MB},
+	}
+	now := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	var out bytes.Buffer
+	renderTop(&out, downloads, false, now)
+	got := out.String()
+	if !strings.HasPrefix(got, "surge top - 15:04:05 - 2 downloading, 1 queued, 9.4 MB/s total") {
+		t.Errorf("summary line wrong:\n%s", got)
+	}
+	if strings.Contains(got, "old.iso") {
+		t.Errorf("completed download listed without --all:\n%s", got)
+	}
+	fast, slow, next := strings.Index(got, "fast.iso"), strings.Index(got, "slow.iso"), strings.Index(got, "next.iso")
+	if fast < 0 || !(fast < slow && slow < next) {
+		t.Errorf("want fastest first, then the queue:\n%s", got)
+	}
+	for _, want := range []string{"2h01m", "1m30s", "16"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output is missing %q:\n%s", want, got)
+		}
+	}
+
+	out.Reset()
+	renderTop(&out, downloads, true, now)
+	if !strings.Contains(out.String(), "old.iso") {
+		t.Errorf("--all should list completed downloads:\n%s", out.String())
+	}
+
+	out.Reset()
+	renderTop(&out, downloads[:1], false, now)
+	if !strings.Contains(out.String(), "Nothing is downloading.") {
+		t.Errorf("want an idle message:\n%s", out.String())
+	}
+}
+
+func TestFormatETA(t *testing.T) {
+	for in, want := range map[int64]string{0: "-", -1: "-", 42: "42s", 185: "3m05s", 3720: "1h02m"} {
+		if got := formatETA(in); got != want {
+			t.Errorf("formatETA(%d) = %q, want %q", in, got, want)
+		}
+	}
+}
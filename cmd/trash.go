@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/engine/state"
+)
+
+var trashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "List deleted downloads that can still be restored",
+	Long: `Deleted downloads stay in the trash, with their data, for trash_retention_days
+(7 by default) before they are deleted for good. Use 'surge trash restore <ID>'
+to bring one back, and 'surge rm --permanent <ID>' to skip the trash.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		mustInitializeGlobalState()
+
+		trashed, err := state.LoadTrash()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing the trash: %v\n", err)
+			os.Exit(1)
+		}
+		if len(trashed) == 0 {
+			fmt.Println("The trash is empty.")
+			return
+		}
+
+		retention := time.Duration(getSettings().General.TrashRetentionDays) * 24 * time.Hour
+		for _, d := range trashed {
+			var progress float64
+			if d.TotalSize > 0 {
+				progress = float64(d.Downloaded) * 100 / float64(d.TotalSize)
+			} else if d.CompletedAt > 0 {
+				progress = 100
+			}
+			expires := time.Unix(d.TrashedAt, 0).Add(retention).Format("2006-01-02 15:04")
+			fmt.Printf("%s  %5.1f%%  expires %s  %s\n", truncateID(d.ID), progress, expires, d.DestPath)
+		}
+	},
+}
+
+var trashRestoreCmd = &cobra.Command{
+	Use:   "restore <ID>",
+	Short: "Bring a download back from the trash",
+	Long: `Take a download out of the trash. A completed download is listed as completed
+again; any other comes back paused, to be resumed with 'surge resume <ID>'.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		mustInitializeGlobalState()
+		ExecuteAPIAction(args[0], "/restore", http.MethodPost, "Restored download")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(trashCmd)
+	trashCmd.AddCommand(trashRestoreCmd)
+}
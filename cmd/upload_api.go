@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/metafile"
+	"github.com/surge-downloader/surge/internal/processing"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// maxUploadSize bounds an uploaded metalink or torrent
+const maxUploadSize = 10 << 20
+
+// uploadedFile reports what became of one file an upload lists
+type uploadedFile struct {
+	Filename string `json:"filename"`
+	Status   string `json:"status"` // queued, archived or error
+	ID       string `json:"id,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type uploadResponse struct {
+	Status string         `json:"status"` // queued, or error when no file was
+	Kind   string         `json:"kind"`   // metalink or torrent
+	Files  []uploadedFile `json:"files"`
+}
+
+// uploadHandler queues the files of a metalink or torrent posted as the
+// "file" field of a multipart form, as the browser extension does when it
+// intercepts one. Optional "path", "category" and "tags" fields apply to
+// every file. Uploads skip the TUI's approval prompt like extension requests.
+func uploadHandler(defaultOutputDir string, service core.DownloadService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if draining.Load() {
+			httpError(w, "Surge is shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+		if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+			var tooBig *http.MaxBytesError
+			if errors.As(err, &tooBig) {
+				httpError(w, "Upload too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			httpError(w, "Invalid multipart form: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			httpError(w, "Missing file field", http.StatusBadRequest)
+			return
+		}
+		defer func() { _ = file.Close() }()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			httpError(w, "Failed to read upload: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		kind := metafile.Detect(header.Filename, data)
+		files, err := metafile.Parse(kind, data)
+		if errors.Is(err, metafile.ErrUnsupported) {
+			httpError(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		if err != nil {
+			httpError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		settings := getSettings()
+		reqPath := r.FormValue("path")
+		if strings.Contains(reqPath, "..") {
+			writeProblem(w, http.StatusBadRequest, core.CodePathForbidden, "Invalid path")
+			return
+		}
+		category := r.FormValue("category")
+		if category != "" {
+			cat := config.FindCategory(category, settings.General.Categories)
+			if cat == nil {
+				httpError(w, "Unknown category: "+category, http.StatusBadRequest)
+				return
+			}
+			category = cat.Name
+		}
+		tags := utils.NormalizeTags([]string{r.FormValue("tags")})
+
+		lifecycle, err := lifecycleForLocalService(service)
+		if err != nil {
+			httpError(w, "Failed to initialize lifecycle manager: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		outDir := utils.EnsureAbsPath(resolveOutputDir(reqPath, false, defaultOutputDir, settings))
+		resp := uploadResponse{Status: "error", Kind: kind, Files: make([]uploadedFile, 0, len(files))}
+		for _, f := range files {
+			addReq := &processing.DownloadRequest{
+				URL:                f.URLs[0],
+				Filename:           f.Name,
+				Path:               filepath.Join(outDir, filepath.FromSlash(f.Dir)),
+				Mirrors:            f.URLs,
+				Tags:               tags,
+				Category:           category,
+				IsExplicitCategory: category != "",
+				SkipApproval:       true,
+			}
+			var id string
+			if lifecycle != nil {
+				id, err = lifecycle.Enqueue(r.Context(), addReq)
+			} else {
+				id, err = service.Add(addReq)
+			}
+
+			result := uploadedFile{Filename: filepath.ToSlash(filepath.Join(f.Dir, f.Name))}
+			switch {
+			case errors.Is(err, processing.ErrAlreadyDownloaded):
+				result.Status = "archived"
+			case err != nil:
+				result.Status, result.Error = "error", err.Error()
+				utils.Debug("Upload: failed to add %s: %v", f.URLs[0], err)
+			default:
+				result.Status, result.ID = "queued", id
+				resp.Status = "queued"
+				atomic.AddInt32(&activeDownloads, 1)
+			}
+			resp.Files = append(resp.Files, result)
+		}
+		writeJSONResponse(w, http.StatusOK, resp)
+	}
+}
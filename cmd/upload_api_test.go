@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func postUpload(t *testing.T, mux *http.ServeMux, name, content string) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = fw.Write([]byte(content))
+	_ = mw.WriteField("tags", "iso,linux")
+	_ = mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestUploadEndpoint(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	isolateDownloadGlobals(t)
+	outDir := t.TempDir()
+	service := &fakeRemoteDownloadService{}
+	mux := http.NewServeMux()
+	registerHTTPRoutes(mux, 0, outDir, service)
+
+	metalink := `<?xml version="1.0" encoding="UTF-8"?>
+<metalink xmlns="urn:ietf:params:xml:ns:metalink">
+  <file name="distro/disc.iso">
+    <url priority="2">https://mirror.example.com/disc.iso</url>
+    <url priority="1">https://example.com/disc.iso</url>
+  </file>
+</metalink>`
+	rec := postUpload(t, mux, "disc.meta4", metalink)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"status":"queued"`) {
+		t.Fatalf("upload = %d %s", rec.Code, rec.Body.String())
+	}
+	if service.addCalls != 1 || service.lastURL != "https://example.com/disc.iso" || service.lastFile != "disc.iso" {
+		t.Fatalf("added %d, url %q, file %q", service.addCalls, service.lastURL, service.lastFile)
+	}
+	if want := filepath.Join(outDir, "distro"); service.lastPath != want {
+		t.Fatalf("path = %q, want %q", service.lastPath, want)
+	}
+
+	if rec := postUpload(t, mux, "links.dlc", "opaque"); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("dlc upload = %d, want 422", rec.Code)
+	}
+	if rec := postUpload(t, mux, "broken.torrent", "d4:infoi"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("broken torrent = %d, want 400", rec.Code)
+	}
+	if service.addCalls != 1 {
+		t.Fatalf("rejected uploads added downloads: %d", service.addCalls)
+	}
+}
@@ -7,11 +7,13 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/core"
 	"github.com/surge-downloader/surge/internal/engine/state"
 	"github.com/surge-downloader/surge/internal/engine/types"
 	"github.com/surge-downloader/surge/internal/utils"
@@ -29,6 +31,16 @@ func readActivePort() int {
 	return port
 }
 
+// readActiveSocket reads the unix socket path of a running server, if it
+// serves one
+func readActiveSocket() string {
+	data, err := os.ReadFile(filepath.Join(config.GetRuntimeDir(), "socket"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
 // ParseURLArg parses a command line argument that might contain comma-separated mirrors
 // Returns the primary URL and a list of all mirrors (including the primary)
 func ParseURLArg(arg string) (string, []string) {
@@ -67,12 +79,15 @@ func resolveAPIConnection(requireServer bool) (string, string, error) {
 	if target == "" {
 		port := readActivePort()
 		if port > 0 {
-			return fmt.Sprintf("http://127.0.0.1:%d", port), resolveLocalToken(), nil
+			return localBaseURL(port), resolveLocalToken(), nil
+		}
+		if socket := readActiveSocket(); socket != "" {
+			return core.UnixScheme + socket, "", nil
 		}
 		if !requireServer {
 			return "", "", nil
 		}
-		return "", "", errors.New("surge is not running locally. start it or pass --host (or set SURGE_HOST)")
+		return "", "", errServerNotRunning
 	}
 
 	baseURL, err := resolveConnectBaseURL(target, false)
@@ -87,6 +102,11 @@ func resolveAPIConnection(requireServer bool) (string, string, error) {
 }
 
 func doAPIRequest(method string, baseURL string, token string, path string, body io.Reader) (*http.Response, error) {
+	tlsConfig, err := clientTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, baseURL := core.NewAPIClient(baseURL, 0, tlsConfig)
 	reqURL := fmt.Sprintf("%s%s", strings.TrimRight(baseURL, "/"), path)
 	req, err := http.NewRequest(method, reqURL, body)
 	if err != nil {
@@ -100,16 +120,28 @@ func doAPIRequest(method string, baseURL string, token string, path string, body
 		req.Header.Set("Content-Type", "application/json")
 	}
 
-	client := &http.Client{}
 	return client.Do(req)
 }
 
-func sendToServer(url string, mirrors []string, outPath string, baseURL string, token string) error {
-	reqBody := DownloadRequest{
+func sendToServer(url string, mirrors []string, tags []string, outPath string, baseURL string, token string) error {
+	return sendRequestToServer(DownloadRequest{
 		URL:     url,
 		Mirrors: mirrors,
+		Tags:    tags,
 		Path:    outPath,
-	}
+	}, baseURL, token)
+}
+
+// errAlreadyDownloaded is returned when the server skipped a URL found in
+// its download archive
+var errAlreadyDownloaded = errors.New("already downloaded")
+
+// errDuplicateRejected is returned when the server turns a URL down as one
+// it already has
+var errDuplicateRejected = errors.New("duplicate rejected")
+
+// sendRequestToServer posts reqBody to the server's /download endpoint
+func sendRequestToServer(reqBody DownloadRequest, baseURL string, token string) error {
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
 		return fmt.Errorf("failed to marshal request: %w", err)
@@ -126,10 +158,19 @@ func sendToServer(url string, mirrors []string, outPath string, baseURL string,
 	}()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("server error: %s - %s", resp.Status, string(body))
+		apiErr := core.ReadAPIError(resp)
+		if apiErr.Code == core.CodeDuplicate {
+			return fmt.Errorf("%w: %s", errDuplicateRejected, apiErr.Detail)
+		}
+		return fmt.Errorf("server error: %s - %s", resp.Status, apiErr.Detail)
 	}
 
+	var result struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err == nil && result.Status == "archived" {
+		return errAlreadyDownloaded
+	}
 	return nil
 }
 
@@ -157,13 +198,21 @@ func GetRemoteDownloads(baseURL string, token string) ([]types.DownloadStatus, e
 	return statuses, nil
 }
 
+// infof prints a status message unless --quiet is given. Errors and what a
+// command was asked for, such as tables and JSON, are printed regardless.
+func infof(format string, a ...any) {
+	if !globalQuiet {
+		fmt.Printf(format, a...)
+	}
+}
+
 // ExecuteAPIAction connects to the server, resolves the ID, and sends a request.
 // It prints a success message and then exits if successful, or prints an error and exits on failure.
 func ExecuteAPIAction(rawID, endpoint, method, successMsg string) {
 	baseURL, token, err := resolveAPIConnection(true)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to connect to Surge server: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitCodeFor(err))
 	}
 
 	id, err := resolveDownloadID(rawID)
@@ -172,11 +221,44 @@ func ExecuteAPIAction(rawID, endpoint, method, successMsg string) {
 		os.Exit(1)
 	}
 
-	resp, err := doAPIRequest(method, baseURL, token, fmt.Sprintf("%s/%s", endpoint, id), nil)
+	sep := "?"
+	if strings.Contains(endpoint, "?") {
+		sep = "&" // The endpoint carries its own options
+	}
+	resp, err := doAPIRequest(method, baseURL, token, endpoint+sep+"id="+url.QueryEscape(id), nil)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to send request to server: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			utils.Debug("Error closing response body: %v", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "Server error: %s - %s\n", resp.Status, core.ReadAPIError(resp).Detail)
 		os.Exit(1)
 	}
+
+	infof("%s\n", successMsg)
+	os.Exit(0)
+}
+
+// ExecuteHostAction pauses or resumes every download from host on the
+// server: endpoint is /pause or /resume and verb describes it for the summary.
+func ExecuteHostAction(host, endpoint, verb string) {
+	baseURL, token, err := resolveAPIConnection(true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to Surge server: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	resp, err := doAPIRequest(http.MethodPost, baseURL, token, fmt.Sprintf("%s?host=%s", endpoint, url.QueryEscape(host)), nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to send request to server: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
 			utils.Debug("Error closing response body: %v", err)
@@ -184,12 +266,23 @@ func ExecuteAPIAction(rawID, endpoint, method, successMsg string) {
 	}()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		fmt.Fprintf(os.Stderr, "Server error: %s - %s\n", resp.Status, string(body))
+		fmt.Fprintf(os.Stderr, "Server error: %s - %s\n", resp.Status, core.ReadAPIError(resp).Detail)
+		os.Exit(1)
+	}
+	var result struct {
+		IDs   []string `json:"ids"`
+		Error string   `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid server response: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Println(successMsg)
+	infof("%s %d downloads from %s\n", verb, len(result.IDs), host)
+	if result.Error != "" {
+		fmt.Fprintf(os.Stderr, "Some downloads failed: %s\n", result.Error)
+		os.Exit(1)
+	}
 	os.Exit(0)
 }
 
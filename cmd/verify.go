@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/processing"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify [id]...",
+	Short: "Re-hash completed downloads and check them against their checksums",
+	Long: `Re-hash the files of completed downloads and compare them with the SHA-256
+recorded when they finished. Files that changed are reported as corrupted and
+files that are gone as missing; downloads finished before checksums were kept
+get one stored. Files are read on this machine. Exits with status 3 if any
+download fails verification; with --quiet only the failures are listed.`,
+	Example: `  surge verify 3f2a
+  surge verify --all`,
+	ValidArgsFunction: completeDownloadIDs(true, "completed"),
+	Run: func(cmd *cobra.Command, args []string) {
+		all, _ := cmd.Flags().GetBool("all")
+		jsonOutput := globalJSON
+		if all == (len(args) > 0) {
+			fmt.Fprintln(os.Stderr, "Error: give download IDs or --all")
+			os.Exit(1)
+		}
+
+		mustInitializeGlobalState()
+
+		var entries []types.DownloadEntry
+		if all {
+			downloads, err := state.ListAllDownloads()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			for _, d := range downloads {
+				if d.Status == "completed" {
+					entries = append(entries, d)
+				}
+			}
+		} else {
+			for _, arg := range args {
+				id, err := resolveDownloadID(arg)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+				entry, err := state.GetDownload(id)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+				if entry == nil {
+					fmt.Fprintf(os.Stderr, "Error: download %s not found\n", arg)
+					os.Exit(1)
+				}
+				entries = append(entries, *entry)
+			}
+		}
+
+		results := make([]types.VerifyResult, 0, len(entries))
+		for _, e := range entries {
+			results = append(results, processing.VerifyDownload(e))
+		}
+
+		var failed []types.VerifyResult
+		for _, r := range results {
+			if r.Failed() {
+				failed = append(failed, r)
+			}
+		}
+
+		switch {
+		case jsonOutput:
+			data, _ := json.MarshalIndent(results, "", "  ")
+			fmt.Println(string(data))
+		case globalQuiet:
+			if len(failed) > 0 {
+				printVerifyResults(os.Stdout, failed)
+			}
+		default:
+			printVerifyResults(os.Stdout, results)
+		}
+		if len(failed) > 0 {
+			os.Exit(exitVerify)
+		}
+	},
+}
+
+// printVerifyResults writes a table of the results and a summary counting
+// the corrupted, missing and unreadable files apart, so what failed is clear
+// at a glance
+func printVerifyResults(out io.Writer, results []types.VerifyResult) {
+	if len(results) == 0 {
+		_, _ = fmt.Fprintln(out, "No completed downloads to verify.")
+		return
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tSTATUS\tFILE")
+	counts := make(map[string]int)
+	for _, r := range results {
+		status := r.Status
+		if r.Error != "" {
+			status = "error: " + r.Error
+			counts["unreadable"]++
+		} else {
+			counts[r.Status]++
+		}
+		id := r.ID
+		if len(id) > 8 {
+			id = id[:8]
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", id, status, r.Path)
+	}
+	_ = w.Flush()
+
+	summary := []string{fmt.Sprintf("%d verified", counts[types.VerifyOK]+counts[types.VerifyHashed])}
+	for _, c := range []struct{ key, label string }{
+		{types.VerifyUnverifiable, "not verifiable"},
+		{types.VerifyCorrupted, "corrupted"},
+		{types.VerifyMissing, "missing"},
+		{"unreadable", "unreadable"},
+	} {
+		if counts[c.key] > 0 {
+			summary = append(summary, fmt.Sprintf("%d %s", counts[c.key], c.label))
+		}
+	}
+	_, _ = fmt.Fprintf(out, "\n%s\n", strings.Join(summary, ", "))
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+	verifyCmd.Flags().Bool("all", false, "Verify every completed download")
+}
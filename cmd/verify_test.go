@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestPrintVerifyResults(t *testing.T) {
+	var buf bytes.Buffer
+	printVerifyResults(&buf, []types.VerifyResult{
+		{ID: "aaaaaaaa-1", Path: "/d/ok.iso", Status: types.VerifyOK},
+		{ID: "bbbbbbbb-2", Path: "/d/new.iso", Status: types.VerifyHashed},
+		{ID: "cccccccc-3", Path: "/d/bad.iso", Status: types.VerifyCorrupted},
+		{ID: "dddddddd-4", Path: "/d/gone.iso", Status: types.VerifyMissing},
+		{ID: "eeeeeeee-5", Path: "/d/locked.iso", Error: "permission denied"},
+	})
+	out := buf.String()
+	if !strings.Contains(out, "2 verified, 1 corrupted, 1 missing, 1 unreadable") {
+		t.Errorf("summary missing:\n%s", out)
+	}
+	if !strings.Contains(out, "error: permission denied") || !strings.Contains(out, "cccccccc") {
+		t.Errorf("table missing rows:\n%s", out)
+	}
+
+	buf.Reset()
+	printVerifyResults(&buf, nil)
+	if !strings.Contains(buf.String(), "No completed downloads") {
+		t.Errorf("empty output = %q", buf.String())
+	}
+}
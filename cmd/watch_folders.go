@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/metafile"
+	"github.com/surge-downloader/surge/internal/processing"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// watchFolderInterval is how often watch folders are scanned. A file is
+// only read once its size is the same on two scans in a row, so one still
+// being written isn't queued half-done.
+const watchFolderInterval = 3 * time.Second
+
+// Suffixes given to trigger files once handled, so they aren't picked up again
+const (
+	watchQueuedSuffix = ".queued"
+	watchFailedSuffix = ".failed"
+)
+
+// isWatchedFile reports whether a file in a watch folder is one to queue
+func isWatchedFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".torrent", ".metalink", ".meta4", ".txt":
+		return true
+	}
+	return false
+}
+
+// folderWatcher polls the watch folders in settings and queues the files
+// dropped into them
+type folderWatcher struct {
+	service core.DownloadService
+	sizes   map[string]int64 // Sizes at the last scan of files not yet handled
+}
+
+func newFolderWatcher(service core.DownloadService) *folderWatcher {
+	return &folderWatcher{service: service, sizes: make(map[string]int64)}
+}
+
+// startWatchFolders scans the watch folders every watchFolderInterval until
+// the returned function is called. Folders are read from settings on each
+// scan, so changes made through the settings API apply at once.
+func startWatchFolders(service core.DownloadService) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := newFolderWatcher(service)
+	go func() {
+		ticker := time.NewTicker(watchFolderInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if !draining.Load() {
+					w.scan(ctx, getSettings())
+				}
+			}
+		}
+	}()
+	return cancel
+}
+
+// scan queues the files in every enabled watch folder whose size has
+// settled since the previous scan
+func (w *folderWatcher) scan(ctx context.Context, settings *config.Settings) {
+	seen := make(map[string]bool)
+	for _, folder := range settings.WatchFolders {
+		if folder.Disabled {
+			continue
+		}
+		entries, err := os.ReadDir(folder.Path)
+		if err != nil {
+			utils.Debug("Watch folder %s: %v", folder.Path, err)
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !isWatchedFile(entry.Name()) {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			path := filepath.Join(folder.Path, entry.Name())
+			seen[path] = true
+			if last, ok := w.sizes[path]; !ok || last != info.Size() {
+				w.sizes[path] = info.Size()
+				continue
+			}
+			delete(w.sizes, path)
+			w.handle(ctx, folder, path, settings)
+		}
+	}
+	for path := range w.sizes {
+		if !seen[path] {
+			delete(w.sizes, path)
+		}
+	}
+}
+
+// handle queues the downloads a trigger file lists and renames it
+// <name>.queued, or <name>.failed when none could be queued
+func (w *folderWatcher) handle(ctx context.Context, folder config.WatchFolder, path string, settings *config.Settings) {
+	queued, err := w.queueFile(ctx, folder, path, settings)
+	suffix := watchQueuedSuffix
+	if err != nil {
+		suffix = watchFailedSuffix
+		utils.Debug("Watch folder: %s: %v", path, err)
+	} else {
+		utils.Debug("Watch folder: queued %d downloads from %s", queued, path)
+	}
+	if err := os.Rename(path, path+suffix); err != nil {
+		utils.Debug("Watch folder: failed to rename %s: %v", path, err)
+	}
+}
+
+// queueFile queues every download in the file at path and returns how many
+// were queued. Downloads the archive already has count as handled; the file
+// fails when it can't be read or nothing in it could be queued.
+func (w *folderWatcher) queueFile(ctx context.Context, folder config.WatchFolder, path string, settings *config.Settings) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	outDir := utils.EnsureAbsPath(resolveOutputDir(folder.OutputDir, false, "", settings))
+	category := ""
+	if cat := config.FindCategory(folder.Category, settings.General.Categories); cat != nil {
+		category = cat.Name
+	}
+	newRequest := func(urls []string, name, dir string) *processing.DownloadRequest {
+		return &processing.DownloadRequest{
+			URL:                urls[0],
+			Filename:           name,
+			Path:               dir,
+			Mirrors:            urls,
+			Tags:               utils.NormalizeTags(folder.Tags),
+			Category:           category,
+			IsExplicitCategory: category != "" || isExplicitOutputPath(dir, settings.General.DefaultDownloadDir),
+			SkipApproval:       true,
+		}
+	}
+
+	var reqs []*processing.DownloadRequest
+	if strings.EqualFold(filepath.Ext(path), ".txt") {
+		entries, err := parseBatch(bytes.NewReader(data))
+		if err != nil {
+			return 0, err
+		}
+		for _, e := range entries {
+			reqs = append(reqs, newRequest(e.Mirrors, e.Filename, outDir))
+		}
+	} else {
+		files, err := metafile.Parse(metafile.Detect(filepath.Base(path), data), data)
+		if err != nil {
+			return 0, err
+		}
+		for _, f := range files {
+			reqs = append(reqs, newRequest(f.URLs, f.Name, filepath.Join(outDir, filepath.FromSlash(f.Dir))))
+		}
+	}
+	if len(reqs) == 0 {
+		return 0, errors.New("no downloads in file")
+	}
+
+	lifecycle, err := lifecycleForLocalService(w.service)
+	if err != nil {
+		return 0, err
+	}
+	queued, failed := 0, 0
+	for _, req := range reqs {
+		if lifecycle != nil {
+			_, err = lifecycle.Enqueue(ctx, req)
+		} else {
+			_, err = w.service.Add(req)
+		}
+		switch {
+		case errors.Is(err, processing.ErrAlreadyDownloaded):
+		case err != nil:
+			failed++
+			utils.Debug("Watch folder: failed to add %s: %v", req.URL, err)
+		default:
+			queued++
+			atomic.AddInt32(&activeDownloads, 1)
+		}
+	}
+	if queued == 0 && failed > 0 {
+		return 0, fmt.Errorf("%d of %d downloads failed to queue", failed, len(reqs))
+	}
+	return queued, nil
+}
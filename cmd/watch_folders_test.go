@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/config"
+)
+
+func TestFolderWatcher_Scan(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	isolateDownloadGlobals(t)
+	dir, outDir := t.TempDir(), t.TempDir()
+	service := &fakeRemoteDownloadService{}
+	w := newFolderWatcher(service)
+	settings := config.DefaultSettings()
+	settings.WatchFolders = []config.WatchFolder{{Path: dir, OutputDir: outDir}}
+
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("links.txt", "https://example.com/a.iso https://mirror.example.com/a.iso a.iso\n")
+	write("broken.txt", "not-a-url\n")
+	write("notes.md", "https://example.com/ignored\n")
+
+	// The first scan only notes the sizes, in case the files are still being written
+	w.scan(context.Background(), settings)
+	if service.addCalls != 0 {
+		t.Fatalf("first scan added %d downloads", service.addCalls)
+	}
+
+	w.scan(context.Background(), settings)
+	if service.addCalls != 1 || service.lastURL != "https://example.com/a.iso" || service.lastFile != "a.iso" || service.lastPath != outDir {
+		t.Fatalf("added %d, url %q, file %q, path %q", service.addCalls, service.lastURL, service.lastFile, service.lastPath)
+	}
+	for _, name := range []string{"links.txt.queued", "broken.txt.failed", "notes.md"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	w.scan(context.Background(), settings)
+	if service.addCalls != 1 {
+		t.Fatalf("handled files were queued again: %d", service.addCalls)
+	}
+}
+
+func TestFolderWatcher_WaitsForWrites(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	isolateDownloadGlobals(t)
+	dir := t.TempDir()
+	service := &fakeRemoteDownloadService{}
+	w := newFolderWatcher(service)
+	settings := config.DefaultSettings()
+	settings.WatchFolders = []config.WatchFolder{{Path: dir, OutputDir: t.TempDir()}}
+
+	path := filepath.Join(dir, "links.txt")
+	_ = os.WriteFile(path, []byte("https://example.com/a.iso\n"), 0o644)
+	w.scan(context.Background(), settings)
+	_ = os.WriteFile(path, []byte("https://example.com/a.iso\nhttps://example.com/b.iso\n"), 0o644)
+	w.scan(context.Background(), settings)
+	if service.addCalls != 0 {
+		t.Fatalf("a file still growing was queued")
+	}
+	w.scan(context.Background(), settings)
+	if service.addCalls != 2 {
+		t.Fatalf("added %d downloads, want 2", service.addCalls)
+	}
+}
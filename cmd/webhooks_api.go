@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/utils"
+	"github.com/surge-downloader/surge/internal/webhook"
+)
+
+// startWebhooks delivers the service's download events to the webhooks in the
+// current settings for as long as the process runs
+func startWebhooks(service core.DownloadService) {
+	stream, _, err := service.StreamEvents(context.Background())
+	if err != nil {
+		utils.Debug("Webhooks disabled, failed to subscribe to events: %v", err)
+		return
+	}
+	dispatcher := webhook.NewDispatcher(func() []config.Webhook { return getSettings().Webhooks }, service.GetStatus)
+	go dispatcher.Run(stream)
+}
+
+// errWebhookNotFound is answered with 404
+var errWebhookNotFound = errors.New("webhook not found")
+
+// webhooksHandler lists webhooks and adds (POST), replaces (PUT ?id=) and
+// removes (DELETE ?id=) them, saving each change to the settings
+func webhooksHandler(service core.DownloadService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			settings, err := config.LoadSettings()
+			if err != nil {
+				httpError(w, "Failed to load settings: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			hooks := settings.Webhooks
+			if hooks == nil {
+				hooks = []config.Webhook{}
+			}
+			writeJSONResponse(w, http.StatusOK, hooks)
+			return
+		}
+
+		id := r.URL.Query().Get("id")
+		if r.Method != http.MethodPost && id == "" {
+			httpError(w, "Missing id parameter", http.StatusBadRequest)
+			return
+		}
+		var hook config.Webhook
+		if r.Method != http.MethodDelete {
+			if err := decodeJSONBody(r, &hook); err != nil {
+				httpError(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			hook.ID = id
+			if r.Method == http.MethodPost {
+				hook.ID = uuid.New().String()
+			}
+			if err := validateWebhook(hook); err != nil {
+				httpError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		err := updateWebhooks(service, func(hooks []config.Webhook) ([]config.Webhook, error) {
+			if r.Method == http.MethodPost {
+				return append(hooks, hook), nil
+			}
+			i := slices.IndexFunc(hooks, func(h config.Webhook) bool { return h.ID == id })
+			if i < 0 {
+				return nil, errWebhookNotFound
+			}
+			if r.Method == http.MethodDelete {
+				return slices.Delete(hooks, i, i+1), nil
+			}
+			hooks[i] = hook
+			return hooks, nil
+		})
+		if err != nil {
+			if errors.Is(err, errWebhookNotFound) {
+				httpError(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			httpError(w, "Failed to save webhooks: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if r.Method == http.MethodDelete {
+			writeJSONResponse(w, http.StatusOK, map[string]string{"status": "deleted", "id": id})
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, hook)
+	}
+}
+
+// validateWebhook checks hook and that its template renders valid JSON
+func validateWebhook(hook config.Webhook) error {
+	if err := hook.Validate(); err != nil {
+		return err
+	}
+	sample := webhook.Payload{Event: "download." + config.WebhookCompleted, ID: "sample", Filename: "file.bin", Time: time.Now().Unix()}
+	_, err := webhook.Render(hook, sample)
+	return err
+}
+
+// updateWebhooks saves the webhooks change returns for the current ones
+func updateWebhooks(service core.DownloadService, change func([]config.Webhook) ([]config.Webhook, error)) error {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+
+	settings, err := config.LoadSettings()
+	if err != nil {
+		return err
+	}
+	hooks, err := change(slices.Clone(settings.Webhooks))
+	if err != nil {
+		return err
+	}
+	settings.Webhooks = hooks
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	return applySettings(service, settings)
+}
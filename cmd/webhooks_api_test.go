@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/config"
+)
+
+func TestWebhooksEndpoint(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	origSettings := globalSettings
+	t.Cleanup(func() { globalSettings = origSettings })
+	globalSettings = config.DefaultSettings()
+
+	mux := http.NewServeMux()
+	registerHTTPRoutes(mux, 0, "", &fakeRemoteDownloadService{})
+	do := func(method, query, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, "/webhooks"+query, strings.NewReader(body)))
+		return rec
+	}
+	list := func() []config.Webhook {
+		t.Helper()
+		rec := do(http.MethodGet, "", "")
+		var hooks []config.Webhook
+		if err := json.Unmarshal(rec.Body.Bytes(), &hooks); err != nil {
+			t.Fatalf("GET /webhooks = %d %s", rec.Code, rec.Body.String())
+		}
+		return hooks
+	}
+
+	if hooks := list(); len(hooks) != 0 {
+		t.Fatalf("webhooks = %+v, want none", hooks)
+	}
+
+	rec := do(http.MethodPost, "", `{"url":"https://example.com/hook","events":["completed"]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST = %d %s", rec.Code, rec.Body.String())
+	}
+	var created config.Webhook
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil || created.ID == "" {
+		t.Fatalf("created = %+v, %v", created, err)
+	}
+	if hooks := list(); len(hooks) != 1 || hooks[0].URL != "https://example.com/hook" {
+		t.Fatalf("webhooks after add = %+v", hooks)
+	}
+	if len(globalSettings.Webhooks) != 1 {
+		t.Errorf("running settings not updated")
+	}
+
+	if rec := do(http.MethodPut, "?id="+created.ID, `{"url":"https://example.com/other","template":"{\"text\": {{json .Filename}}}"}`); rec.Code != http.StatusOK {
+		t.Fatalf("PUT = %d %s", rec.Code, rec.Body.String())
+	}
+	if hooks := list(); len(hooks) != 1 || hooks[0].URL != "https://example.com/other" || hooks[0].ID != created.ID {
+		t.Fatalf("webhooks after update = %+v", hooks)
+	}
+
+	for _, tc := range []struct {
+		method, query, body string
+		want                int
+	}{
+		{http.MethodPost, "", `{"url":"ftp://example.com/"}`, http.StatusBadRequest},
+		{http.MethodPost, "", `{"url":"https://example.com/","events":["paused"]}`, http.StatusBadRequest},
+		{http.MethodPost, "", `{"url":"https://example.com/","template":"{{.Filename}}"}`, http.StatusBadRequest},
+		{http.MethodPut, "", `{"url":"https://example.com/"}`, http.StatusBadRequest},
+		{http.MethodPut, "?id=missing", `{"url":"https://example.com/"}`, http.StatusNotFound},
+		{http.MethodDelete, "?id=missing", "", http.StatusNotFound},
+		{http.MethodPatch, "", "", http.StatusMethodNotAllowed},
+	} {
+		if rec := do(tc.method, tc.query, tc.body); rec.Code != tc.want {
+			t.Errorf("%s /webhooks%s %s = %d %s, want %d", tc.method, tc.query, tc.body, rec.Code, rec.Body.String(), tc.want)
+		}
+	}
+
+	if rec := do(http.MethodDelete, "?id="+created.ID, ""); rec.Code != http.StatusOK {
+		t.Fatalf("DELETE = %d %s", rec.Code, rec.Body.String())
+	}
+	if hooks := list(); len(hooks) != 0 {
+		t.Fatalf("webhooks after delete = %+v", hooks)
+	}
+}
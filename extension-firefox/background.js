@@ -340,42 +340,76 @@ async function validateAuthToken() {
   }
 }
 
+// === Native Messaging ===
+
+const NATIVE_HOST_NAME = 'com.surge_downloader.surge';
+
+// Reaches Surge through "surge native-host", registered with
+// "surge native-host install", for daemons without a TCP port
+async function sendNative(message) {
+  try {
+    return await browser.runtime.sendNativeMessage(NATIVE_HOST_NAME, message);
+  } catch (error) {
+    console.debug('[Surge] Native host unavailable:', error);
+    return null;
+  }
+}
+
 // === Download Sending ===
 
 async function sendToSurge(url, filename, absolutePath) {
+  const body = {
+    url: url,
+    filename: filename || '',
+  };
+
+  // Use absolute path directly if provided
+  if (absolutePath) {
+    body.path = absolutePath;
+  }
+
+  // Include captured headers for authenticated downloads
+  const headers = getCapturedHeaders(url);
+  if (headers) {
+    body.headers = headers;
+    console.log('[Surge] Forwarding captured headers to Surge');
+  }
+
   const baseUrl = await findSurgeUrl();
   if (!baseUrl) {
+    // Without a reachable port, try the native messaging host
+    const reply = await sendNative({ type: 'download', download: body });
+    if (reply) {
+      return reply.ok
+        ? { success: true, data: reply.data }
+        : { success: false, error: reply.error };
+    }
     console.error('[Surge] No server found');
     return { success: false, error: 'Server not running' };
   }
 
   try {
-    const body = {
-      url: url,
-      filename: filename || '',
-    };
-
-    // Use absolute path directly if provided
-    if (absolutePath) {
-      body.path = absolutePath;
-    }
-
-    // Include captured headers for authenticated downloads
-    const headers = getCapturedHeaders(url);
-    if (headers) {
-      body.headers = headers;
-      console.log('[Surge] Forwarding captured headers to Surge');
-    }
-
     const auth = await authHeaders();
-    const response = await fetch(`${baseUrl}/download`, {
-      method: 'POST',
-      headers: {
-        'Content-Type': 'application/json',
-        ...auth,
-      },
-      body: JSON.stringify(body),
-    });
+    // The same key on a retry lets Surge answer it without queueing twice
+    const idempotencyKey = crypto.randomUUID();
+    const post = () =>
+      fetch(`${baseUrl}/download`, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+          'Idempotency-Key': idempotencyKey,
+          ...auth,
+        },
+        body: JSON.stringify(body),
+      });
+    let response;
+    try {
+      response = await post();
+    } catch (error) {
+      // A network blip may have lost only the response; retry once
+      console.warn('[Surge] Retrying download request:', error);
+      response = await post();
+    }
 
     if (response.ok) {
       const data = await response.json();
@@ -386,11 +420,15 @@ async function sendToSurge(url, filename, absolutePath) {
       let msg = "Download rejected: duplicate or approval required (headless mode)";
       try {
         const json = JSON.parse(errorText);
-        if (json.message) msg = json.message;
+        // Problem details carry the reason in detail
+        if (json.detail || json.message) msg = json.detail || json.message;
       } catch (e) {}
       return { success: false, error: msg };
     } else {
-      const error = await response.text();
+      let error = await response.text();
+      try {
+        error = JSON.parse(error).detail || error;
+      } catch (e) {}
       console.error('[Surge] Failed to queue download:', response.status, error);
       return { success: false, error };
     }
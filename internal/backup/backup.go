@@ -0,0 +1,366 @@
+// Package backup bundles the state database, settings and API token into a
+// single archive and restores them from one.
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/state"
+)
+
+// FormatVersion is the archive layout this build writes. Archives with a
+// higher format are refused.
+const FormatVersion = 1
+
+// Names of the members of an archive
+const (
+	manifestName = "manifest.json"
+	dbName       = "surge.db"
+	settingsName = "settings.json"
+	tokenName    = "token"
+)
+
+// maxMemberSize bounds a single archive member so a corrupt or hostile
+// archive can't fill the disk
+const maxMemberSize = 4 << 30
+
+// Manifest describes an archive
+type Manifest struct {
+	Format        int               `json:"format"`
+	SurgeVersion  string            `json:"surge_version"`
+	SchemaVersion int               `json:"schema_version"`
+	CreatedAt     int64             `json:"created_at"`
+	Files         map[string]string `json:"files"` // Member name to SHA-256
+}
+
+// Paths are where the settings and token live. The database is always the
+// configured state database.
+type Paths struct {
+	Settings string
+	Token    string
+}
+
+// DefaultPaths returns the locations Surge uses
+func DefaultPaths() Paths {
+	return Paths{
+		Settings: config.GetSettingsPath(),
+		Token:    filepath.Join(config.GetStateDir(), "token"),
+	}
+}
+
+// Create writes an archive of the state database, settings and token to
+// archivePath. Settings and token are skipped when they don't exist.
+func Create(archivePath, surgeVersion string, paths Paths) (*Manifest, error) {
+	tmp, err := os.MkdirTemp("", "surge-backup-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(tmp) }()
+
+	dbCopy := filepath.Join(tmp, dbName)
+	if err := state.Backup(dbCopy); err != nil {
+		return nil, fmt.Errorf("failed to back up state database: %w", err)
+	}
+	schema, err := state.SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	members := map[string]string{dbName: dbCopy}
+	for name, path := range map[string]string{settingsName: paths.Settings, tokenName: paths.Token} {
+		if _, err := os.Stat(path); err == nil {
+			members[name] = path
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	m := &Manifest{
+		Format:        FormatVersion,
+		SurgeVersion:  surgeVersion,
+		SchemaVersion: schema,
+		CreatedAt:     time.Now().Unix(),
+		Files:         make(map[string]string, len(members)),
+	}
+	for name, path := range members {
+		if m.Files[name], err = hashFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	// The archive holds the API token, so it is private like the token file
+	partial := archivePath + ".partial"
+	f, err := os.OpenFile(partial, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeArchive(f, m, members); err != nil {
+		_ = f.Close()
+		_ = os.Remove(partial)
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(partial)
+		return nil, err
+	}
+	if err := os.Rename(partial, archivePath); err != nil {
+		_ = os.Remove(partial)
+		return nil, err
+	}
+	return m, nil
+}
+
+func writeArchive(w io.Writer, m *Manifest, members map[string]string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeMember(tw, manifestName, 0o644, int64(len(manifest)), bytes.NewReader(manifest)); err != nil {
+		return err
+	}
+	for _, name := range []string{dbName, settingsName, tokenName} {
+		path, ok := members[name]
+		if !ok {
+			continue
+		}
+		if err := addFile(tw, name, path); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func addFile(tw *tar.Writer, name, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return writeMember(tw, name, 0o600, fi.Size(), f)
+}
+
+func writeMember(tw *tar.Writer, name string, mode int64, size int64, r io.Reader) error {
+	hdr := &tar.Header{Name: name, Mode: mode, Size: size, ModTime: time.Now(), Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := io.Copy(tw, r)
+	return err
+}
+
+// RestoreOptions selects what Restore puts back
+type RestoreOptions struct {
+	SkipSettings bool
+	SkipToken    bool
+}
+
+// Restore checks archivePath and puts its contents back. Everything is
+// verified first: the format and schema versions, and every checksum; nothing
+// is written unless all of it passes. The caller must make sure no Surge
+// instance is running.
+func Restore(archivePath string, paths Paths, opts RestoreOptions) (*Manifest, error) {
+	tmp, err := os.MkdirTemp("", "surge-restore-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(tmp) }()
+
+	m, err := Inspect(archivePath, tmp)
+	if err != nil {
+		return nil, err
+	}
+	if m.SchemaVersion > state.LatestSchemaVersion() {
+		return nil, fmt.Errorf("backup has schema version %d, newer than this build supports (%d); upgrade Surge first", m.SchemaVersion, state.LatestSchemaVersion())
+	}
+	if _, ok := m.Files[settingsName]; ok && !opts.SkipSettings {
+		if err := checkSettings(filepath.Join(tmp, settingsName)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := state.Restore(filepath.Join(tmp, dbName)); err != nil {
+		return nil, fmt.Errorf("failed to restore state database: %w", err)
+	}
+	if _, ok := m.Files[settingsName]; ok && !opts.SkipSettings {
+		if err := replaceFile(paths.Settings, filepath.Join(tmp, settingsName), 0o644); err != nil {
+			return nil, fmt.Errorf("failed to restore settings: %w", err)
+		}
+	}
+	if _, ok := m.Files[tokenName]; ok && !opts.SkipToken {
+		if err := replaceFile(paths.Token, filepath.Join(tmp, tokenName), 0o600); err != nil {
+			return nil, fmt.Errorf("failed to restore token: %w", err)
+		}
+	}
+	return m, nil
+}
+
+// Inspect extracts archivePath into dir and verifies it against its
+// manifest, which it returns
+func Inspect(archivePath, dir string) (*Manifest, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("not a Surge backup: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	var m *Manifest
+	seen := make(map[string]bool)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("corrupt backup: %w", err)
+		}
+		switch hdr.Name {
+		case manifestName:
+			m = &Manifest{}
+			if err := json.NewDecoder(io.LimitReader(tr, 1<<20)).Decode(m); err != nil {
+				return nil, fmt.Errorf("corrupt backup manifest: %w", err)
+			}
+			if m.Format > FormatVersion {
+				return nil, fmt.Errorf("backup format %d is newer than this build supports (%d); upgrade Surge first", m.Format, FormatVersion)
+			}
+		case dbName, settingsName, tokenName:
+			if hdr.Typeflag != tar.TypeReg || hdr.Size > maxMemberSize {
+				return nil, fmt.Errorf("corrupt backup: bad member %s", hdr.Name)
+			}
+			if err := extract(tr, filepath.Join(dir, hdr.Name)); err != nil {
+				return nil, err
+			}
+			seen[hdr.Name] = true
+		default:
+			return nil, fmt.Errorf("corrupt backup: unexpected member %q", hdr.Name)
+		}
+	}
+
+	if m == nil {
+		return nil, errors.New("not a Surge backup: no manifest")
+	}
+	if _, ok := m.Files[dbName]; !ok {
+		return nil, errors.New("corrupt backup: no state database")
+	}
+	for name, want := range m.Files {
+		if !seen[name] {
+			return nil, fmt.Errorf("corrupt backup: %s is missing", name)
+		}
+		got, err := hashFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		if got != want {
+			return nil, fmt.Errorf("corrupt backup: checksum mismatch for %s", name)
+		}
+	}
+	for name := range seen {
+		if _, ok := m.Files[name]; !ok {
+			return nil, fmt.Errorf("corrupt backup: %s is not in the manifest", name)
+		}
+	}
+	return m, nil
+}
+
+func extract(r io.Reader, path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, io.LimitReader(r, maxMemberSize)); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// checkSettings makes sure a settings file from an archive parses
+func checkSettings(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var s config.Settings
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("backup settings are invalid: %w", err)
+	}
+	return nil
+}
+
+// replaceFile copies src over dst through a temporary file in dst's
+// directory, so dst is never left half-written
+func replaceFile(dst, src string, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".restore-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, in); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,194 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func setupState(t *testing.T) (string, Paths) {
+	t.Helper()
+	dir := t.TempDir()
+	state.CloseDB()
+	state.Configure(filepath.Join(dir, "surge.db"))
+	if _, err := state.GetDB(); err != nil {
+		t.Fatalf("GetDB: %v", err)
+	}
+	t.Cleanup(state.CloseDB)
+
+	paths := Paths{Settings: filepath.Join(dir, "settings.json"), Token: filepath.Join(dir, "token")}
+	if err := os.WriteFile(paths.Settings, []byte(`{"general":{}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(paths.Token, []byte("old-token"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return dir, paths
+}
+
+func TestCreateAndRestore(t *testing.T) {
+	dir, paths := setupState(t)
+	if err := state.AddToMasterList(types.DownloadEntry{ID: "a", URL: "https://example.com/a", DestPath: "/tmp/a", Status: "completed"}); err != nil {
+		t.Fatalf("AddToMasterList: %v", err)
+	}
+
+	archive := filepath.Join(dir, "backup.tar.gz")
+	m, err := Create(archive, "1.2.3", paths)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if len(m.Files) != 3 || m.SchemaVersion != state.LatestSchemaVersion() {
+		t.Fatalf("manifest = %+v", m)
+	}
+	if fi, err := os.Stat(archive); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Fatalf("archive mode = %v, %v", fi, err)
+	}
+
+	// Change everything, then restore
+	if err := state.RemoveFromMasterList("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(paths.Token, []byte("new-token"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(paths.Settings, []byte(`{}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Restore(archive, paths, RestoreOptions{SkipSettings: true}); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if entry, err := state.GetDownload("a"); err != nil || entry == nil {
+		t.Errorf("download not restored: %v, %v", entry, err)
+	}
+	if got, _ := os.ReadFile(paths.Token); string(got) != "old-token" {
+		t.Errorf("token = %q, want old-token", got)
+	}
+	if got, _ := os.ReadFile(paths.Settings); string(got) != `{}` {
+		t.Errorf("settings restored despite SkipSettings: %q", got)
+	}
+}
+
+func TestCreate_SkipsMissingFiles(t *testing.T) {
+	dir, paths := setupState(t)
+	_ = os.Remove(paths.Token)
+
+	m, err := Create(filepath.Join(dir, "backup.tar.gz"), "dev", paths)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, ok := m.Files[tokenName]; ok || len(m.Files) != 2 {
+		t.Errorf("files = %v", m.Files)
+	}
+}
+
+// rewrite copies archive to a new one, letting edit change each member
+func rewrite(t *testing.T, src, dst string, edit func(name string, data []byte) []byte) {
+	t.Helper()
+	tmp := t.TempDir()
+	if _, err := Inspect(src, tmp); err != nil {
+		t.Fatalf("Inspect: %v", err)
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	in, _ := os.Open(src)
+	defer func() { _ = in.Close() }()
+	gr, _ := gzip.NewReader(in)
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		var data []byte
+		if hdr.Name == manifestName {
+			if data, err = io.ReadAll(tr); err != nil {
+				t.Fatal(err)
+			}
+		} else {
+			data, _ = os.ReadFile(filepath.Join(tmp, hdr.Name))
+		}
+		data = edit(hdr.Name, data)
+		if data == nil {
+			continue
+		}
+		_ = tw.WriteHeader(&tar.Header{Name: hdr.Name, Mode: 0o600, Size: int64(len(data)), Typeflag: tar.TypeReg})
+		_, _ = tw.Write(data)
+	}
+	_ = tw.Close()
+	_ = gz.Close()
+}
+
+func TestRestore_RejectsBadArchives(t *testing.T) {
+	dir, paths := setupState(t)
+	archive := filepath.Join(dir, "backup.tar.gz")
+	if _, err := Create(archive, "dev", paths); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	editManifest := func(change func(*Manifest)) func(string, []byte) []byte {
+		return func(name string, data []byte) []byte {
+			if name != manifestName {
+				return data
+			}
+			var m Manifest
+			if err := json.Unmarshal(data, &m); err != nil {
+				t.Fatal(err)
+			}
+			change(&m)
+			out, _ := json.Marshal(m)
+			return out
+		}
+	}
+
+	tests := []struct {
+		name string
+		edit func(string, []byte) []byte
+		want string
+	}{
+		{"newer format", editManifest(func(m *Manifest) { m.Format = FormatVersion + 1 }), "format"},
+		{"newer schema", editManifest(func(m *Manifest) { m.SchemaVersion = state.LatestSchemaVersion() + 1 }), "schema version"},
+		{"tampered token", func(name string, data []byte) []byte {
+			if name == tokenName {
+				return []byte("evil")
+			}
+			return data
+		}, "checksum"},
+		{"missing database", func(name string, data []byte) []byte {
+			if name == dbName {
+				return nil
+			}
+			return data
+		}, "missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bad := filepath.Join(t.TempDir(), "bad.tar.gz")
+			rewrite(t, archive, bad, tt.edit)
+			before, _ := os.ReadFile(paths.Token)
+
+			_, err := Restore(bad, paths, RestoreOptions{})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Restore error = %v, want it to mention %q", err, tt.want)
+			}
+			if after, _ := os.ReadFile(paths.Token); string(after) != string(before) {
+				t.Error("token changed by a rejected restore")
+			}
+		})
+	}
+}
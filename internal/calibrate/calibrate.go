@@ -0,0 +1,267 @@
+// Package calibrate measures the link's bandwidth and latency and derives
+// connection, chunk and buffer settings from the bandwidth-delay product.
+package calibrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// DefaultEndpoints serve large files from well-connected networks
+var DefaultEndpoints = []string{
+	"https://speed.cloudflare.com/__down?bytes=200000000",
+	"https://proof.ovh.net/files/100Mb.dat",
+}
+
+const (
+	defaultConnections = 4
+	defaultDuration    = 5 * time.Second
+	rttSamples         = 3
+)
+
+// Options configures a measurement. Zero values use the defaults.
+type Options struct {
+	Endpoints   []string
+	Connections int           // Parallel streams used to fill the link
+	Duration    time.Duration // How long each endpoint is downloaded from
+	Client      *http.Client
+}
+
+// Result is what one endpoint measured
+type Result struct {
+	Endpoint  string        `json:"endpoint"`
+	RTT       time.Duration `json:"rtt"`
+	Bandwidth float64       `json:"bandwidth"` // Bytes per second
+}
+
+// BDP is the bandwidth-delay product: the bytes in flight needed to keep
+// the link busy
+func (r *Result) BDP() int64 {
+	return int64(r.Bandwidth * r.RTT.Seconds())
+}
+
+// Measure downloads from each endpoint in turn and returns the fastest
+// result. An endpoint that fails is skipped; Measure fails only when all do.
+func Measure(ctx context.Context, opts Options) (*Result, error) {
+	endpoints := opts.Endpoints
+	if len(endpoints) == 0 {
+		endpoints = DefaultEndpoints
+	}
+	if opts.Connections <= 0 {
+		opts.Connections = defaultConnections
+	}
+	if opts.Duration <= 0 {
+		opts.Duration = defaultDuration
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Transport: &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			MaxIdleConnsPerHost: opts.Connections,
+		}}
+	}
+
+	var best *Result
+	var errs []error
+	for _, endpoint := range endpoints {
+		r, err := measureEndpoint(ctx, opts, endpoint)
+		if err != nil {
+			utils.Debug("Calibrate: %s failed: %v", endpoint, err)
+			errs = append(errs, fmt.Errorf("%s: %w", endpoint, err))
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		utils.Debug("Calibrate: %s rtt=%v bandwidth=%.0f B/s", endpoint, r.RTT, r.Bandwidth)
+		if best == nil || r.Bandwidth > best.Bandwidth {
+			best = r
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("calibration failed: %w", errors.Join(errs...))
+	}
+	return best, nil
+}
+
+func measureEndpoint(ctx context.Context, opts Options, endpoint string) (*Result, error) {
+	rtt, err := measureRTT(ctx, opts.Client, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	bandwidth, err := measureBandwidth(ctx, opts.Client, endpoint, opts.Connections, opts.Duration)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Endpoint: endpoint, RTT: rtt, Bandwidth: bandwidth}, nil
+}
+
+// measureRTT times one-byte range requests. The first one pays for DNS and
+// the handshakes, later ones reuse its connection, so the fastest is the
+// closest to a round trip.
+func measureRTT(ctx context.Context, client *http.Client, endpoint string) (time.Duration, error) {
+	best := time.Duration(math.MaxInt64)
+	for i := 0; i < rttSamples; i++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Set("Range", "bytes=0-0")
+		start := time.Now()
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		elapsed := time.Since(start)
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+		_ = resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			return 0, fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		best = min(best, elapsed)
+	}
+	return best, nil
+}
+
+// measureBandwidth downloads over conns parallel streams for d and returns
+// the combined rate
+func measureBandwidth(ctx context.Context, client *http.Client, endpoint string, conns int, d time.Duration) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+
+	var total atomic.Int64
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var firstErr error
+	start := time.Now()
+	for i := 0; i < conns; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := stream(ctx, client, endpoint, &total); err != nil && ctx.Err() == nil {
+				errOnce.Do(func() { firstErr = err })
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	if total.Load() == 0 {
+		if firstErr == nil {
+			firstErr = errors.New("no data received")
+		}
+		return 0, firstErr
+	}
+	return float64(total.Load()) / elapsed.Seconds(), nil
+}
+
+// stream downloads endpoint repeatedly until ctx ends, adding to total
+func stream(ctx context.Context, client *http.Client, endpoint string, total *atomic.Int64) error {
+	buf := make([]byte, 64*1024)
+	for ctx.Err() == nil {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode >= 400 {
+			_ = resp.Body.Close()
+			return fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		for {
+			n, err := resp.Body.Read(buf)
+			total.Add(int64(n))
+			if err != nil {
+				break
+			}
+		}
+		_ = resp.Body.Close()
+	}
+	return nil
+}
+
+// Tuning is the settings a measurement suggests
+type Tuning struct {
+	MaxConnectionsPerHost int   `json:"max_connections_per_host"`
+	MinChunkSize          int64 `json:"min_chunk_size"`
+	WorkerBufferSize      int   `json:"worker_buffer_size"`
+}
+
+const (
+	// assumedWindow is the receive window one connection is expected to
+	// sustain, so a connection carries about assumedWindow per round trip
+	assumedWindow = 256 * config.KB
+	// chunkSeconds is how long one connection should spend on a chunk
+	chunkSeconds = 2
+
+	minConnections = 4
+	maxConnections = 64 // max_connections_per_host's upper bound
+	minChunk       = 1 * config.MB
+	maxChunk       = 64 * config.MB
+	minBuffer      = 32 * config.KB
+	maxBuffer      = 4 * config.MB
+)
+
+// Recommend derives settings from r: enough connections to cover the
+// bandwidth-delay product, a buffer of each connection's share of it, and
+// chunks that take a connection a couple of seconds.
+func Recommend(r *Result) Tuning {
+	bdp := r.BDP()
+	conns := int((bdp + assumedWindow - 1) / assumedWindow)
+	conns = max(minConnections, min(conns, maxConnections))
+
+	perConn := r.Bandwidth / float64(conns)
+	return Tuning{
+		MaxConnectionsPerHost: conns,
+		MinChunkSize:          clampPow2(int64(perConn*chunkSeconds), minChunk, maxChunk),
+		WorkerBufferSize:      int(clampPow2(bdp/int64(conns), minBuffer, maxBuffer)),
+	}
+}
+
+// clampPow2 rounds n down to a power of two within [lo, hi]
+func clampPow2(n, lo, hi int64) int64 {
+	if n <= lo {
+		return lo
+	}
+	if n >= hi {
+		return hi
+	}
+	p := lo
+	for p*2 <= n {
+		p *= 2
+	}
+	return p
+}
+
+// Apply writes t into s and marks s as calibrated
+func (t Tuning) Apply(s *config.Settings, now time.Time) {
+	s.Network.MaxConnectionsPerHost = t.MaxConnectionsPerHost
+	s.Network.MinChunkSize = t.MinChunkSize
+	s.Network.WorkerBufferSize = t.WorkerBufferSize
+	s.Network.CalibratedAt = now.Unix()
+}
+
+// NeedsFirstRun reports whether s should be calibrated automatically: it is
+// enabled, has never been calibrated, and the values calibration sets are
+// still the defaults, so nothing the user chose is overwritten.
+func NeedsFirstRun(s *config.Settings) bool {
+	if !s.Network.AutoCalibrate || s.Network.CalibratedAt != 0 {
+		return false
+	}
+	d := config.DefaultSettings().Network
+	return s.Network.MaxConnectionsPerHost == d.MaxConnectionsPerHost &&
+		s.Network.MinChunkSize == d.MinChunkSize &&
+		s.Network.WorkerBufferSize == d.WorkerBufferSize
+}
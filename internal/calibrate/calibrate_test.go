@@ -0,0 +1,116 @@
+package calibrate
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+)
+
+func newStreamServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	chunk := make([]byte, 32*1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") == "bytes=0-0" {
+			w.Header().Set("Content-Range", "bytes 0-0/1048576")
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write([]byte{0})
+			return
+		}
+		for i := 0; i < 32; i++ {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestMeasure(t *testing.T) {
+	srv := newStreamServer(t)
+
+	r, err := Measure(context.Background(), Options{
+		Endpoints:   []string{"http://127.0.0.1:1/unreachable", srv.URL},
+		Connections: 2,
+		Duration:    200 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Measure: %v", err)
+	}
+	if r.Endpoint != srv.URL {
+		t.Errorf("Endpoint = %q, want the reachable one", r.Endpoint)
+	}
+	if r.RTT <= 0 || r.Bandwidth <= 0 {
+		t.Errorf("RTT = %v, Bandwidth = %v", r.RTT, r.Bandwidth)
+	}
+}
+
+func TestMeasure_AllEndpointsFail(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	if _, err := Measure(context.Background(), Options{Endpoints: []string{srv.URL}, Duration: 50 * time.Millisecond}); err == nil {
+		t.Fatal("Measure succeeded against a failing endpoint")
+	}
+}
+
+func TestRecommend(t *testing.T) {
+	tests := []struct {
+		name string
+		r    Result
+		want Tuning
+	}{
+		{
+			name: "slow link floors everything",
+			r:    Result{RTT: 20 * time.Millisecond, Bandwidth: 1 * config.MB},
+			want: Tuning{MaxConnectionsPerHost: 4, MinChunkSize: 1 * config.MB, WorkerBufferSize: 32 * config.KB},
+		},
+		{
+			name: "gigabit at 20ms",
+			r:    Result{RTT: 20 * time.Millisecond, Bandwidth: 125 * config.MB},
+			want: Tuning{MaxConnectionsPerHost: 10, MinChunkSize: 16 * config.MB, WorkerBufferSize: 256 * config.KB},
+		},
+		{
+			name: "long fat pipe caps connections",
+			r:    Result{RTT: 300 * time.Millisecond, Bandwidth: 1250 * config.MB},
+			want: Tuning{MaxConnectionsPerHost: 64, MinChunkSize: 32 * config.MB, WorkerBufferSize: 4 * config.MB},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Recommend(&tt.r); got != tt.want {
+				t.Errorf("Recommend = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNeedsFirstRun(t *testing.T) {
+	s := config.DefaultSettings()
+	if NeedsFirstRun(s) {
+		t.Fatal("auto_calibrate is opt-in, so defaults must not be calibrated")
+	}
+
+	s.Network.AutoCalibrate = true
+	if !NeedsFirstRun(s) {
+		t.Fatal("fresh settings with auto_calibrate on should be calibrated")
+	}
+
+	Tuning{MaxConnectionsPerHost: 8, MinChunkSize: 4 * config.MB, WorkerBufferSize: 256 * config.KB}.Apply(s, time.Unix(100, 0))
+	if s.Network.CalibratedAt != 100 || NeedsFirstRun(s) {
+		t.Fatalf("calibrated settings: CalibratedAt = %d, NeedsFirstRun = %v", s.Network.CalibratedAt, NeedsFirstRun(s))
+	}
+
+	s = config.DefaultSettings()
+	s.Network.AutoCalibrate = true
+	s.Network.MaxConnectionsPerHost = 16
+	if NeedsFirstRun(s) {
+		t.Error("user-tuned settings must not be overwritten")
+	}
+}
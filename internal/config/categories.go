@@ -2,6 +2,7 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"regexp"
 	"strings"
@@ -10,14 +11,24 @@ import (
 	"github.com/surge-downloader/surge/internal/utils"
 )
 
-// Category defines a download category for auto-sorting.
+// Category defines a download category for auto-sorting. A download matches
+// when its filename matches Pattern, its URL matches URLPattern, or its
+// Content-Type is one of MimeTypes; requests can also name a category outright.
 type Category struct {
-	Name        string `json:"name"`
-	Description string `json:"description,omitempty"`
-	Pattern     string `json:"pattern"`
-	Path        string `json:"path"`
+	Name           string   `json:"name"`
+	Description    string   `json:"description,omitempty"`
+	Pattern        string   `json:"pattern"`
+	URLPattern     string   `json:"url_pattern,omitempty"` // Regexp matched against the download URL
+	MimeTypes      []string `json:"mime_types,omitempty"`  // e.g. "application/pdf" or "video/*"
+	Path           string   `json:"path"`
+	MaxConnections int      `json:"max_connections,omitempty"` // Overrides max_connections_per_host, 0 = no override
+
+	PostProcess []PostStep `json:"post_process,omitempty"` // Run in order once a download completes
 }
 
+// MaxCategoryConnections bounds Category.MaxConnections like the global setting
+const MaxCategoryConnections = 64
+
 func (c *Category) Validate() error {
 	if c == nil {
 		return errors.New("category cannot be nil")
@@ -25,16 +36,63 @@ func (c *Category) Validate() error {
 	if strings.TrimSpace(c.Name) == "" {
 		return errors.New("category name cannot be empty")
 	}
-	if strings.TrimSpace(c.Pattern) == "" {
+	if strings.TrimSpace(c.Pattern) == "" && strings.TrimSpace(c.URLPattern) == "" && len(c.MimeTypes) == 0 {
 		return errors.New("category pattern cannot be empty")
 	}
-	if _, err := regexp.Compile(strings.TrimSpace(c.Pattern)); err != nil {
-		return err
+	if p := strings.TrimSpace(c.Pattern); p != "" {
+		if _, err := regexp.Compile(p); err != nil {
+			return err
+		}
+	}
+	if p := strings.TrimSpace(c.URLPattern); p != "" {
+		if _, err := regexp.Compile(p); err != nil {
+			return fmt.Errorf("category url_pattern: %w", err)
+		}
 	}
 	if strings.TrimSpace(c.Path) == "" {
 		return errors.New("category path cannot be empty")
 	}
-	return nil
+	if c.MaxConnections < 0 || c.MaxConnections > MaxCategoryConnections {
+		return fmt.Errorf("category max_connections must be between 0 and %d", MaxCategoryConnections)
+	}
+	return validatePostProcess(c.PostProcess)
+}
+
+// matches reports whether any of the category's rules match the download
+func (c *Category) matches(filename, rawurl, contentType string) bool {
+	if filename != "" && c.Pattern != "" {
+		if re := getCompiledPattern(c.Pattern); re != nil && re.MatchString(filename) {
+			return true
+		}
+	}
+	if rawurl != "" && c.URLPattern != "" {
+		if re := getCompiledPattern(c.URLPattern); re != nil && re.MatchString(rawurl) {
+			return true
+		}
+	}
+	if contentType != "" {
+		for _, m := range c.MimeTypes {
+			if mimeMatches(m, contentType) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mimeMatches compares a rule such as "video/*" with a Content-Type header,
+// ignoring case and parameters like charset
+func mimeMatches(rule, contentType string) bool {
+	rule = strings.ToLower(strings.TrimSpace(rule))
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if rule == "" || mediaType == "" {
+		return false
+	}
+	if prefix, ok := strings.CutSuffix(rule, "*"); ok {
+		return strings.HasPrefix(mediaType, prefix)
+	}
+	return mediaType == rule
 }
 
 func existingDirOrFallback(dir, fallback string) string {
@@ -157,6 +215,32 @@ func GetCategoryForFile(filename string, categories []Category) (*Category, erro
 	return matched, nil
 }
 
+// MatchCategory returns the last category whose filename, URL or MIME rules
+// match, like GetCategoryForFile, or nil
+func MatchCategory(filename, rawurl, contentType string, categories []Category) *Category {
+	var matched *Category
+	for i := range categories {
+		if cat := &categories[i]; cat.matches(filename, rawurl, contentType) {
+			matched = cat
+		}
+	}
+	return matched
+}
+
+// FindCategory returns the category called name, ignoring case, or nil
+func FindCategory(name string, categories []Category) *Category {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil
+	}
+	for i := range categories {
+		if strings.EqualFold(strings.TrimSpace(categories[i].Name), name) {
+			return &categories[i]
+		}
+	}
+	return nil
+}
+
 // ResolveCategoryPath returns the Path of a category.
 func ResolveCategoryPath(cat *Category, defaultDownloadDir string) string {
 	defaultPath := strings.TrimSpace(defaultDownloadDir)
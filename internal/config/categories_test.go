@@ -288,3 +288,63 @@ func TestGetCompiledPattern_Concurrent(t *testing.T) {
 		t.Error("Expected non-nil for valid pattern")
 	}
 }
+
+func TestMatchCategory_URLAndMimeRules(t *testing.T) {
+	cats := []Category{
+		{Name: "Videos", Pattern: `(?i)\.mp4$`, MimeTypes: []string{"video/*"}, Path: "/videos"},
+		{Name: "Docs", MimeTypes: []string{"application/pdf"}, Path: "/docs"},
+		{Name: "Releases", URLPattern: `^https://github\.com/.*/releases/`, Path: "/releases"},
+	}
+
+	tests := []struct {
+		filename, url, contentType string
+		want                       string
+	}{
+		{"clip.mp4", "https://example.com/clip.mp4", "", "Videos"},
+		{"stream", "https://example.com/stream", "video/webm", "Videos"},
+		{"paper", "https://example.com/paper", "Application/PDF; charset=binary", "Docs"},
+		{"app.mp4", "https://github.com/o/r/releases/download/v1/app.mp4", "", "Releases"},
+		{"page.html", "https://example.com/", "text/html", ""},
+	}
+	for _, tt := range tests {
+		got := MatchCategory(tt.filename, tt.url, tt.contentType, cats)
+		name := ""
+		if got != nil {
+			name = got.Name
+		}
+		if name != tt.want {
+			t.Errorf("MatchCategory(%q, %q, %q) = %q, want %q", tt.filename, tt.url, tt.contentType, name, tt.want)
+		}
+	}
+}
+
+func TestFindCategory(t *testing.T) {
+	cats := []Category{{Name: "Videos", Pattern: `\.mp4$`, Path: "/videos"}}
+
+	if got := FindCategory(" videos ", cats); got == nil || got.Name != "Videos" {
+		t.Fatalf("FindCategory = %#v, want Videos", got)
+	}
+	if got := FindCategory("music", cats); got != nil {
+		t.Fatalf("FindCategory(music) = %#v, want nil", got)
+	}
+	if got := FindCategory("", cats); got != nil {
+		t.Fatalf("FindCategory(\"\") = %#v, want nil", got)
+	}
+}
+
+func TestCategoryValidate_RuleFields(t *testing.T) {
+	cat := Category{Name: "Docs", MimeTypes: []string{"application/pdf"}, Path: "/tmp"}
+	if err := cat.Validate(); err != nil {
+		t.Fatalf("MIME-only category should be valid, got %v", err)
+	}
+
+	cat = Category{Name: "Docs", URLPattern: "[", Path: "/tmp"}
+	if err := cat.Validate(); err == nil {
+		t.Fatal("expected invalid url_pattern validation error, got nil")
+	}
+
+	cat = Category{Name: "Docs", Pattern: `\.pdf$`, Path: "/tmp", MaxConnections: MaxCategoryConnections + 1}
+	if err := cat.Validate(); err == nil {
+		t.Fatal("expected max_connections validation error, got nil")
+	}
+}
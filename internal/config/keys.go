@@ -0,0 +1,171 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LookupSetting returns the metadata of the setting with the JSON key key and
+// the settings editor tab it is on
+func LookupSetting(key string) (string, SettingMeta, bool) {
+	metadata := GetSettingsMetadata()
+	for _, category := range CategoryOrder() {
+		for _, m := range metadata[category] {
+			if m.Key == key {
+				return category, m, true
+			}
+		}
+	}
+	return "", SettingMeta{}, false
+}
+
+// SettingKeys lists the keys LookupSetting knows, sorted
+func SettingKeys() []string {
+	var keys []string
+	for _, metas := range GetSettingsMetadata() {
+		for _, m := range metas {
+			keys = append(keys, m.Key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// section returns the struct holding the settings of an editor tab
+func (s *Settings) section(category string) any {
+	switch category {
+	case "General", "Categories":
+		return &s.General
+	case "Network":
+		return &s.Network
+	case "Performance":
+		return &s.Performance
+	}
+	return nil
+}
+
+// sectionValues returns a section's fields by JSON key
+func sectionValues(section any) (map[string]any, error) {
+	data, err := json.Marshal(section)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]any)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// GetValue returns a setting as the settings editor shows it: sizes in the
+// setting's Unit and durations in seconds
+func (s *Settings) GetValue(key string) (string, error) {
+	category, m, ok := LookupSetting(key)
+	if !ok {
+		return "", fmt.Errorf("unknown setting %q", key)
+	}
+	values, err := sectionValues(s.section(category))
+	if err != nil {
+		return "", err
+	}
+	switch v := values[key].(type) {
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return "", err
+		}
+		return formatRangeBound(f / m.unitScale()), nil
+	case string:
+		return v, nil
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// SetValue sets a setting from input typed as in the settings editor, after
+// checking it against the setting's type and range. Choices are accepted by
+// name, e.g. "dark" for the theme, and bools as true/false, on/off or yes/no.
+// Callers run Validate on the result before saving it.
+func (s *Settings) SetValue(key, input string) error {
+	category, m, ok := LookupSetting(key)
+	if !ok {
+		return fmt.Errorf("unknown setting %q", key)
+	}
+	input = strings.TrimSpace(input)
+
+	var value any
+	switch m.Type {
+	case "string":
+		value = input
+	case "bool":
+		b, err := parseBoolSetting(input)
+		if err != nil {
+			return err
+		}
+		value = b
+	default:
+		if err := m.Validate(input); err != nil {
+			return err
+		}
+		f, err := parseNumberSetting(m, input)
+		if err != nil {
+			return err
+		}
+		f *= m.unitScale()
+		if m.Type == "float64" {
+			value = f
+		} else {
+			value = int64(math.Round(f))
+		}
+	}
+
+	section := s.section(category)
+	values, err := sectionValues(section)
+	if err != nil {
+		return err
+	}
+	values[key] = value
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, section)
+}
+
+// parseBoolSetting parses the ways a bool setting may be typed
+func parseBoolSetting(input string) (bool, error) {
+	switch strings.ToLower(input) {
+	case "true", "on", "yes", "1":
+		return true, nil
+	case "false", "off", "no", "0":
+		return false, nil
+	}
+	return false, errors.New("enter true or false")
+}
+
+// parseNumberSetting parses input, which m.Validate accepted, as a number in
+// the setting's Unit
+func parseNumberSetting(m SettingMeta, input string) (float64, error) {
+	for i, choice := range m.Choices {
+		if strings.EqualFold(input, choice) {
+			return float64(i), nil
+		}
+	}
+	if f, err := strconv.ParseFloat(input, 64); err == nil {
+		return f, nil
+	}
+	d, err := time.ParseDuration(input)
+	if err != nil {
+		return 0, errors.New("enter a number")
+	}
+	return d.Seconds(), nil
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSettings_SetValue(t *testing.T) {
+	s := DefaultSettings()
+	set := func(key, input string) {
+		t.Helper()
+		if err := s.SetValue(key, input); err != nil {
+			t.Fatalf("%s = %q: %v", key, input, err)
+		}
+	}
+	set("max_connections_per_host", "16")
+	set("min_chunk_size", "4")
+	set("global_rate_limit", "512")
+	set("stall_timeout", "2.5")
+	set("theme", "dark")
+	set("auto_resume", "on")
+	set("user_agent", "curl/8.0")
+
+	if s.Network.MaxConnectionsPerHost != 16 || s.Network.MinChunkSize != 4*MB || s.Network.GlobalRateLimit != 512*KB {
+		t.Errorf("network = %+v", s.Network)
+	}
+	if s.Performance.StallTimeout != 2500*time.Millisecond {
+		t.Errorf("stall_timeout = %v", s.Performance.StallTimeout)
+	}
+	if s.General.Theme != ThemeDark || !s.General.AutoResume || s.Network.UserAgent != "curl/8.0" {
+		t.Errorf("general = %+v, user agent %q", s.General, s.Network.UserAgent)
+	}
+	if s.Network.MaxConcurrentDownloads != DefaultSettings().Network.MaxConcurrentDownloads {
+		t.Error("setting one key changed another")
+	}
+
+	for key, input := range map[string]string{
+		"max_connections_per_host": "100",
+		"auto_resume":              "maybe",
+		"stall_timeout":            "soon",
+		"no_such_setting":          "1",
+	} {
+		if err := s.SetValue(key, input); err == nil {
+			t.Errorf("%s = %q: expected an error", key, input)
+		}
+	}
+}
+
+func TestSettings_GetValue(t *testing.T) {
+	s := DefaultSettings()
+	for key, want := range map[string]string{
+		"min_chunk_size":      "2",
+		"worker_buffer_size":  "512",
+		"stall_timeout":       "3",
+		"speed_ema_alpha":     "0.3",
+		"warn_on_duplicate":   "true",
+		"log_retention_count": "5",
+	} {
+		got, err := s.GetValue(key)
+		if err != nil || got != want {
+			t.Errorf("%s = %q, %v; want %q", key, got, err, want)
+		}
+	}
+	if _, err := s.GetValue("no_such_setting"); err == nil {
+		t.Error("expected an error for an unknown key")
+	}
+}
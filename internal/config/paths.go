@@ -1,14 +1,58 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
 
 	"github.com/adrg/xdg"
 )
 
+// DefaultProfile names the profile used when none is selected. Its files stay
+// where they were before profiles existed.
+const DefaultProfile = "default"
+
+// profile is the selected profile; empty means DefaultProfile
+var profile string
+
+var profileNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// SetProfile selects the profile whose settings, database, token and runtime
+// files are used, so downloads of different profiles stay apart. Each profile
+// keeps them in a profiles/<name> folder of the usual directories. Names are
+// lowercased and must be letters, digits, '-' or '_'.
+func SetProfile(name string) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || name == DefaultProfile {
+		profile = ""
+		return nil
+	}
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use letters, digits, '-' and '_'", name)
+	}
+	profile = name
+	return nil
+}
+
+// GetProfile returns the selected profile
+func GetProfile() string {
+	if profile == "" {
+		return DefaultProfile
+	}
+	return profile
+}
+
+// profileDir returns the folder of the selected profile within base
+func profileDir(base string) string {
+	if profile == "" {
+		return base
+	}
+	return filepath.Join(base, "profiles", profile)
+}
+
 func getXDGBaseDir(envKey, fallback string) string {
 	if dir := strings.TrimSpace(os.Getenv(envKey)); dir != "" {
 		if filepath.IsAbs(dir) {
@@ -22,7 +66,12 @@ func getXDGBaseDir(envKey, fallback string) string {
 // Linux: $XDG_CONFIG_HOME/surge or ~/.config/surge
 // macOS: ~/Library/Application Support/surge
 // Windows: %APPDATA%/surge
+// A profile other than the default one uses its profiles/<name> subfolder.
 func GetSurgeDir() string {
+	return profileDir(surgeBaseDir())
+}
+
+func surgeBaseDir() string {
 	if runtime.GOOS == "windows" {
 		// Preserve legacy location for existing Windows installs.
 		if appData := strings.TrimSpace(os.Getenv("APPDATA")); appData != "" {
@@ -39,7 +88,7 @@ func GetStateDir() string {
 	if runtime.GOOS == "windows" {
 		return GetSurgeDir()
 	}
-	return filepath.Join(getXDGBaseDir("XDG_STATE_HOME", xdg.StateHome), "surge")
+	return profileDir(filepath.Join(getXDGBaseDir("XDG_STATE_HOME", xdg.StateHome), "surge"))
 }
 
 func GetDownloadsDir() string {
@@ -62,6 +111,20 @@ func GetDownloadsDir() string {
 	return ""
 }
 
+// GetProfileDownloadsDir returns where downloads of the selected profile go
+// by default: GetDownloadsDir, in a subfolder named after any profile other
+// than the default one.
+func GetProfileDownloadsDir() string {
+	dir := GetDownloadsDir()
+	if profile == "" {
+		return dir
+	}
+	if dir == "" {
+		dir = "."
+	}
+	return filepath.Join(dir, profile)
+}
+
 func GetRuntimeDir() string {
 	runtimeEnv := strings.TrimSpace(os.Getenv("XDG_RUNTIME_DIR"))
 	if runtimeEnv != "" && !filepath.IsAbs(runtimeEnv) {
@@ -87,7 +150,7 @@ func GetRuntimeDir() string {
 		return filepath.Join(GetStateDir(), "runtime")
 	}
 
-	return filepath.Join(runtimeBase, "surge")
+	return profileDir(filepath.Join(runtimeBase, "surge"))
 }
 
 func GetDocumentsDir() string {
@@ -202,3 +202,47 @@ func TestWindowsPathsIgnoreRelativeAppData(t *testing.T) {
 		t.Fatalf("GetSurgeDir() = %q, want %q", got, want)
 	}
 }
+
+func TestSetProfile_SeparatesSettingsAndState(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("non-windows behavior")
+	}
+
+	configHome := t.TempDir()
+	stateHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	t.Setenv("XDG_STATE_HOME", stateHome)
+	t.Cleanup(func() { _ = SetProfile("") })
+
+	if err := SetProfile("Work"); err != nil {
+		t.Fatalf("SetProfile failed: %v", err)
+	}
+	if got := GetProfile(); got != "work" {
+		t.Errorf("GetProfile() = %q, want work", got)
+	}
+	if got, want := GetSettingsPath(), filepath.Join(configHome, "surge", "profiles", "work", "settings.json"); got != want {
+		t.Errorf("GetSettingsPath() = %q, want %q", got, want)
+	}
+	if got, want := GetStateDir(), filepath.Join(stateHome, "surge", "profiles", "work"); got != want {
+		t.Errorf("GetStateDir() = %q, want %q", got, want)
+	}
+	if got := GetProfileDownloadsDir(); filepath.Base(got) != "work" {
+		t.Errorf("GetProfileDownloadsDir() = %q, want a work folder", got)
+	}
+
+	if err := SetProfile(DefaultProfile); err != nil {
+		t.Fatalf("SetProfile(default) failed: %v", err)
+	}
+	if got, want := GetStateDir(), filepath.Join(stateHome, "surge"); got != want {
+		t.Errorf("default GetStateDir() = %q, want %q", got, want)
+	}
+
+	for _, bad := range []string{"../work", "a/b", "-x", "work space"} {
+		if err := SetProfile(bad); err == nil {
+			t.Errorf("SetProfile(%q) should fail", bad)
+		}
+	}
+	if got := GetProfile(); got != DefaultProfile {
+		t.Errorf("a rejected name changed the profile to %q", got)
+	}
+}
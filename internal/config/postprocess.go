@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Post-processing step types, run in the order a category lists them
+const (
+	StepVerify  = "verify"  // Check the file is complete
+	StepExtract = "extract" // Unpack .zip, .tar, .tar.gz and .tgz files
+	StepMove    = "move"    // Move the file to Target
+	StepScript  = "script"  // Run Command with the download in its environment
+	StepNotify  = "notify"  // POST to URL, or show a desktop notification
+)
+
+// What happens when a step fails
+const (
+	OnFailureStop     = "stop"     // Skip the remaining steps (the default)
+	OnFailureContinue = "continue" // Log it and run the next step
+	OnFailureFail     = "fail"     // Skip the remaining steps and mark the download as failed
+)
+
+// MaxPostProcessSteps bounds the steps of one category
+const MaxPostProcessSteps = 16
+
+// PostStep is one step of a category's post-processing pipeline
+type PostStep struct {
+	Type           string   `json:"type"`
+	Disabled       bool     `json:"disabled,omitempty"`
+	OnFailure      string   `json:"on_failure,omitempty"`
+	Target         string   `json:"target,omitempty"`          // move: directory; extract: directory, default next to the file
+	Command        string   `json:"command,omitempty"`         // script: program to run
+	Args           []string `json:"args,omitempty"`            // script: its arguments
+	URL            string   `json:"url,omitempty"`             // notify: webhook, desktop notification when empty
+	DeleteArchive  bool     `json:"delete_archive,omitempty"`  // extract: remove the archive afterwards
+	TimeoutSeconds int      `json:"timeout_seconds,omitempty"` // script and notify, 0 = default
+}
+
+// FailurePolicy returns the step's on_failure, defaulting to stop
+func (s PostStep) FailurePolicy() string {
+	if s.OnFailure == "" {
+		return OnFailureStop
+	}
+	return s.OnFailure
+}
+
+// Validate checks the step has what its type needs
+func (s PostStep) Validate() error {
+	switch s.Type {
+	case StepVerify, StepExtract:
+	case StepMove:
+		if strings.TrimSpace(s.Target) == "" {
+			return fmt.Errorf("move step needs a target")
+		}
+	case StepScript:
+		if strings.TrimSpace(s.Command) == "" {
+			return fmt.Errorf("script step needs a command")
+		}
+	case StepNotify:
+		if s.URL != "" {
+			u, err := url.Parse(s.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("notify step url must be an http(s) URL")
+			}
+		}
+	default:
+		return fmt.Errorf("unknown post-processing step %q", s.Type)
+	}
+
+	switch s.OnFailure {
+	case "", OnFailureStop, OnFailureContinue, OnFailureFail:
+	default:
+		return fmt.Errorf("unknown on_failure %q (want stop, continue or fail)", s.OnFailure)
+	}
+	if s.TimeoutSeconds < 0 {
+		return fmt.Errorf("timeout_seconds cannot be negative")
+	}
+	return nil
+}
+
+func validatePostProcess(steps []PostStep) error {
+	if len(steps) > MaxPostProcessSteps {
+		return fmt.Errorf("category post_process has more than %d steps", MaxPostProcessSteps)
+	}
+	for i, step := range steps {
+		if err := step.Validate(); err != nil {
+			return fmt.Errorf("category post_process step %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
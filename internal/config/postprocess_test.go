@@ -0,0 +1,21 @@
+package config
+
+import "testing"
+
+func TestPostStepValidate(t *testing.T) {
+	bad := []PostStep{
+		{Type: "upload"},
+		{Type: StepMove},
+		{Type: StepScript},
+		{Type: StepNotify, URL: "ftp://example.com"},
+		{Type: StepVerify, OnFailure: "retry"},
+	}
+	for _, step := range bad {
+		if err := step.Validate(); err == nil {
+			t.Errorf("%+v: expected an error", step)
+		}
+	}
+	if err := (PostStep{Type: StepNotify}).Validate(); err != nil {
+		t.Errorf("desktop notify step: %v", err)
+	}
+}
@@ -2,31 +2,178 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // Settings holds all user-configurable application settings organized by category.
 type Settings struct {
-	General     GeneralSettings     `json:"general"`
-	Network     NetworkSettings     `json:"network"`
-	Performance PerformanceSettings `json:"performance"`
+	General         GeneralSettings     `json:"general"`
+	Network         NetworkSettings     `json:"network"`
+	Performance     PerformanceSettings `json:"performance"`
+	DomainOverrides []DomainOverride    `json:"domain_overrides,omitempty"`
+	StatusPage      StatusPageSettings  `json:"status_page"`
+	Server          ServerSettings      `json:"server"`
+	Distributed     DistributedSettings `json:"distributed"`
+	Webhooks        []Webhook           `json:"webhooks,omitempty"`
+	WatchFolders    []WatchFolder       `json:"watch_folders,omitempty"`
+}
+
+// ServerSettings configures how the daemon serves its HTTP API
+type ServerSettings struct {
+	BindAddress    string   `json:"bind_address,omitempty"`    // Address the API, gRPC and status page listen on; every interface when empty
+	AllowedOrigins []string `json:"allowed_origins,omitempty"` // Browser origins allowed to call the API besides BuiltinAllowedOrigins; any when empty
+	MDNS           bool     `json:"mdns"`                      // Advertise the API on the local network as _surge._tcp
+	TLS            bool     `json:"tls"`                       // Serve HTTPS instead of HTTP
+	TLSCert        string   `json:"tls_cert,omitempty"`        // PEM certificate file; a self-signed one is generated when empty
+	TLSKey         string   `json:"tls_key,omitempty"`         // PEM private key file of TLSCert
+}
+
+// ValidateBindAddress checks that addr is empty, localhost or an IP address
+func ValidateBindAddress(addr string) error {
+	if addr == "" || addr == "localhost" || net.ParseIP(addr) != nil {
+		return nil
+	}
+	return fmt.Errorf("bind_address %q must be an IP address or localhost", addr)
+}
+
+// BuiltinAllowedOrigins are allowed to call the API besides the
+// allowed_origins setting: browser extensions, whose Firefox origins differ
+// per install, and web UIs served from this machine. They still need the
+// token.
+var BuiltinAllowedOrigins = []string{
+	"chrome-extension://*",
+	"moz-extension://*",
+	"http://localhost:*",
+	"http://127.0.0.1:*",
+	"http://[::1]:*",
+}
+
+// ValidateOrigin checks that origin is "*" or a scheme://host[:port] origin
+// as browsers send it, such as chrome-extension://<id>. The host may be "*"
+// to allow any, and the port "*" to allow any including none.
+func ValidateOrigin(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+	if _, _, _, ok := splitOrigin(origin); !ok {
+		return fmt.Errorf("allowed origin %q must look like https://host[:port]", origin)
+	}
+	return nil
+}
+
+// OriginAllowed reports whether origin matches one of allowed or of
+// BuiltinAllowedOrigins
+func OriginAllowed(origin string, allowed []string) bool {
+	scheme, host, port, ok := splitOrigin(origin)
+	if !ok {
+		return false
+	}
+	for _, list := range [][]string{allowed, BuiltinAllowedOrigins} {
+		for _, pattern := range list {
+			pScheme, pHost, pPort, ok := splitOrigin(pattern)
+			if ok && strings.EqualFold(pScheme, scheme) &&
+				(pHost == "*" || strings.EqualFold(pHost, host)) &&
+				(pPort == "*" || pPort == port) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// splitOrigin splits a scheme://host[:port] origin, or a pattern of one
+func splitOrigin(origin string) (scheme, host, port string, ok bool) {
+	origin = strings.TrimSuffix(origin, "/")
+	wildPort := strings.HasSuffix(origin, ":*")
+	origin = strings.TrimSuffix(origin, ":*")
+	wildHost := false
+	if before, after, found := strings.Cut(origin, "://*"); found && (after == "" || after[0] == ':') {
+		// url.Parse rejects * as a host; check the rest with a stand-in
+		origin, wildHost = before+"://wildcard"+after, true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return "", "", "", false
+	}
+	host, port = u.Hostname(), u.Port()
+	if strings.Contains(host, "*") {
+		return "", "", "", false // Only a whole host may be a wildcard
+	}
+	if wildHost {
+		host = "*"
+	}
+	if wildPort {
+		if port != "" {
+			return "", "", "", false
+		}
+		port = "*"
+	}
+	return u.Scheme, host, port, true
+}
+
+// DistributedSettings configures the experimental coordinator mode: ranges
+// of multi-connection downloads are also fetched through peer daemons, each
+// over its own link, and streamed back to this instance. Enabled and Peers
+// are set on the coordinator; ServeRelay on each peer.
+type DistributedSettings struct {
+	Enabled             bool         `json:"enabled"`
+	Peers               []PeerDaemon `json:"peers,omitempty"`
+	ServeRelay          bool         `json:"serve_relay"`           // Act as a peer: serve /relay for coordinators holding this daemon's token
+	RelayPrivateTargets bool         `json:"relay_private_targets"` // Also relay upstreams on loopback, private and link-local addresses
+}
+
+// PeerDaemon is a remote Surge daemon that relays ranges for the coordinator
+type PeerDaemon struct {
+	URL   string `json:"url"`   // Base URL of the peer's API, e.g. http://10.0.0.5:1700
+	Token string `json:"token"` // The peer's API token
+}
+
+// StatusPageSettings configures the optional read-only status page. It needs
+// no token, so it never shows URLs, paths or IDs; the Show* fields control what
+// else is visible.
+type StatusPageSettings struct {
+	Enabled       bool `json:"enabled"`
+	Port          int  `json:"port"`
+	ShowFilenames bool `json:"show_filenames"`
+	ShowSizes     bool `json:"show_sizes"`
+	ShowSpeed     bool `json:"show_speed"`
+	ShowETA       bool `json:"show_eta"`
+}
+
+// DomainOverride customizes behavior for matching hosts. Host accepts a hostname
+// (also matching its subdomains), "*.example.com", an IP literal, or a CIDR range.
+type DomainOverride struct {
+	Host                string `json:"host"`
+	ExemptFromRateLimit bool   `json:"exempt_from_rate_limit"` // Never throttle (e.g. LAN NAS)
 }
 
 // GeneralSettings contains application behavior settings.
 type GeneralSettings struct {
 	DefaultDownloadDir string     `json:"default_download_dir"`
 	WarnOnDuplicate    bool       `json:"warn_on_duplicate"`
+	DownloadArchive    bool       `json:"download_archive"` // Skip URLs that have completed before
 	ExtensionPrompt    bool       `json:"extension_prompt"`
 	AutoResume         bool       `json:"auto_resume"`
 	SkipUpdateCheck    bool       `json:"skip_update_check"`
 	CategoryEnabled    bool       `json:"category_enabled"`
 	Categories         []Category `json:"categories"`
 
-	ClipboardMonitor  bool `json:"clipboard_monitor"`
-	Theme             int  `json:"theme"`
-	LogRetentionCount int  `json:"log_retention_count"`
+	ClipboardMonitor   bool `json:"clipboard_monitor"`
+	Theme              int  `json:"theme"`
+	LogRetentionCount  int  `json:"log_retention_count"`
+	ReverifyAfterDays  int  `json:"reverify_after_days"`  // Re-check the remote file when resuming downloads paused this long, 0 = never
+	TrashRetentionDays int  `json:"trash_retention_days"` // Keep deleted downloads restorable this long, 0 = delete at once
+
+	StateStore   string `json:"state_store"`   // SQLite file holding the queue, empty = surge.db in the state directory
+	EncryptState bool   `json:"encrypt_state"` // Encrypt stored request headers and URLs with a key kept in the OS keychain
 }
 
 const (
@@ -44,6 +191,11 @@ type NetworkSettings struct {
 	SequentialDownload     bool   `json:"sequential_download"`
 	MinChunkSize           int64  `json:"min_chunk_size"`
 	WorkerBufferSize       int    `json:"worker_buffer_size"`
+	GlobalRateLimit        int64  `json:"global_rate_limit"` // Bytes/sec across all downloads, 0 = unlimited
+	KeepCompressed         bool   `json:"keep_compressed_responses"`
+	PrewarmConnections     bool   `json:"prewarm_connections"`
+	AutoCalibrate          bool   `json:"auto_calibrate"` // Measure the link once at an idle start and tune the values above
+	CalibratedAt           int64  `json:"calibrated_at"`  // Unix time of the last calibration, 0 = never
 }
 
 // PerformanceSettings contains performance tuning parameters.
@@ -53,48 +205,235 @@ type PerformanceSettings struct {
 	SlowWorkerGracePeriod time.Duration `json:"slow_worker_grace_period"`
 	StallTimeout          time.Duration `json:"stall_timeout"`
 	SpeedEmaAlpha         float64       `json:"speed_ema_alpha"`
+	MaxBufferMemory       int64         `json:"max_buffer_memory"`   // Bytes of worker buffers across all downloads, 0 = unlimited
+	DensePreallocation    bool          `json:"dense_preallocation"` // Reserve the whole file on disk up front instead of leaving it sparse
+	DBBusyTimeout         time.Duration `json:"db_busy_timeout"`     // How long a state write waits for another writer's lock
 }
 
 // SettingMeta provides metadata for a single setting (for UI rendering).
 type SettingMeta struct {
-	Key         string // JSON key name
-	Label       string // Human-readable label
-	Description string // Help text displayed in right pane
-	Type        string // "string", "int", "int64", "bool", "duration", "float64"
+	Key         string        // JSON key name
+	Label       string        // Human-readable label
+	Description string        // Help text displayed in right pane
+	Type        string        // "string", "int", "int64", "bool", "duration", "float64"
+	Unit        string        // Unit the value is entered in, e.g. "MB" (empty = unitless)
+	Range       *SettingRange // Allowed numeric range in Unit (nil = unchecked)
+	Choices     []string      // Named values accepted besides numbers
+	Example     string        // Sample input shown as a hint
+}
+
+// SettingRange bounds a numeric setting. Max <= Min means no upper bound.
+type SettingRange struct {
+	Min float64
+	Max float64
+}
+
+func (r SettingRange) String() string {
+	if r.Max <= r.Min {
+		return fmt.Sprintf("%s or more", formatRangeBound(r.Min))
+	}
+	return fmt.Sprintf("%s-%s", formatRangeBound(r.Min), formatRangeBound(r.Max))
+}
+
+func formatRangeBound(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// Validate checks input as typed in the settings editor, i.e. in the setting's
+// Unit. Bool settings are toggled rather than typed and always pass.
+func (m SettingMeta) Validate(input string) error {
+	input = strings.TrimSpace(input)
+	for _, choice := range m.Choices {
+		if strings.EqualFold(input, choice) {
+			return nil
+		}
+	}
+
+	var v float64
+	switch m.Type {
+	case "string", "bool":
+		return nil
+	case "int":
+		n, err := strconv.Atoi(input)
+		if err != nil {
+			if len(m.Choices) > 0 {
+				return fmt.Errorf("enter a whole number or one of: %s", strings.Join(m.Choices, ", "))
+			}
+			return errors.New("enter a whole number")
+		}
+		v = float64(n)
+	case "int64", "float64":
+		f, err := strconv.ParseFloat(input, 64)
+		if err != nil {
+			return errors.New("enter a number")
+		}
+		v = f
+	case "duration":
+		if f, err := strconv.ParseFloat(input, 64); err == nil {
+			v = f
+		} else if d, err := time.ParseDuration(input); err == nil {
+			v = d.Seconds()
+		} else {
+			return errors.New("enter seconds or a duration like 5s")
+		}
+	}
+
+	return m.checkRange(v)
+}
+
+// checkRange checks v, in the setting's Unit, against Range
+func (m SettingMeta) checkRange(v float64) error {
+	if m.Range == nil {
+		return nil
+	}
+	if v < m.Range.Min || (m.Range.Max > m.Range.Min && v > m.Range.Max) {
+		msg := fmt.Sprintf("must be between %s and %s", formatRangeBound(m.Range.Min), formatRangeBound(m.Range.Max))
+		if m.Range.Max <= m.Range.Min {
+			msg = "must be at least " + formatRangeBound(m.Range.Min)
+		}
+		if m.Unit != "" {
+			msg += " " + m.Unit
+		}
+		return errors.New(msg)
+	}
+	return nil
+}
+
+// unitScale is how many stored units make one of the setting's Unit: settings
+// entered in MB or KB are stored in bytes, durations in nanoseconds
+func (m SettingMeta) unitScale() float64 {
+	switch {
+	case m.Type == "duration":
+		return float64(time.Second)
+	case m.Unit == "MB":
+		return MB
+	case m.Unit == "KB", m.Unit == "KB/s":
+		return KB
+	}
+	return 1
+}
+
+// Validate checks stored values against the ranges the settings editor
+// enforces on input, and that categories, domain overrides and webhooks are
+// complete. Settings written by hand or through the API go through it before
+// saving.
+func (s *Settings) Validate() error {
+	sections := map[string]any{
+		"General":     s.General,
+		"Network":     s.Network,
+		"Performance": s.Performance,
+	}
+	metadata := GetSettingsMetadata()
+	for _, category := range CategoryOrder() {
+		section, ok := sections[category]
+		if !ok {
+			continue
+		}
+		data, err := json.Marshal(section)
+		if err != nil {
+			return err
+		}
+		var values map[string]any
+		if err := json.Unmarshal(data, &values); err != nil {
+			return err
+		}
+		for _, m := range metadata[category] {
+			v, ok := values[m.Key].(float64)
+			if !ok {
+				continue
+			}
+			if err := m.checkRange(v / m.unitScale()); err != nil {
+				return fmt.Errorf("%s %w", m.Key, err)
+			}
+		}
+	}
+
+	for i := range s.General.Categories {
+		if err := s.General.Categories[i].Validate(); err != nil {
+			return fmt.Errorf("categories[%d]: %w", i, err)
+		}
+	}
+	for i, o := range s.DomainOverrides {
+		if strings.TrimSpace(o.Host) == "" {
+			return fmt.Errorf("domain_overrides[%d]: host cannot be empty", i)
+		}
+	}
+	ids := make(map[string]bool, len(s.Webhooks))
+	for i, w := range s.Webhooks {
+		if err := w.Validate(); err != nil {
+			return fmt.Errorf("webhooks[%d]: %w", i, err)
+		}
+		if w.ID == "" || ids[w.ID] {
+			return fmt.Errorf("webhooks[%d]: id must be set and unique", i)
+		}
+		ids[w.ID] = true
+	}
+	for i, w := range s.WatchFolders {
+		if err := w.Validate(s.General.Categories); err != nil {
+			return fmt.Errorf("watch_folders[%d]: %w", i, err)
+		}
+	}
+	if s.StatusPage.Enabled && (s.StatusPage.Port < 1 || s.StatusPage.Port > 65535) {
+		return errors.New("status_page port must be between 1 and 65535")
+	}
+	if err := ValidateBindAddress(s.Server.BindAddress); err != nil {
+		return fmt.Errorf("server %w", err)
+	}
+	for _, o := range s.Server.AllowedOrigins {
+		if err := ValidateOrigin(o); err != nil {
+			return fmt.Errorf("server %w", err)
+		}
+	}
+	if (s.Server.TLSCert == "") != (s.Server.TLSKey == "") {
+		return errors.New("server tls_cert and tls_key must be set together")
+	}
+	return nil
 }
 
 // GetSettingsMetadata returns metadata for all settings organized by category.
 func GetSettingsMetadata() map[string][]SettingMeta {
 	return map[string][]SettingMeta{
 		"General": {
-			{Key: "default_download_dir", Label: "Default Download Dir", Description: "Default directory for new downloads. Leave empty to use current directory.", Type: "string"},
+			{Key: "default_download_dir", Label: "Default Download Dir", Description: "Default directory for new downloads. Leave empty to use current directory.", Type: "string", Example: "~/Downloads"},
 			{Key: "warn_on_duplicate", Label: "Warn on Duplicate", Description: "Show warning when adding a download that already exists.", Type: "bool"},
+			{Key: "download_archive", Label: "Download Archive", Description: "Skip URLs that have been downloaded before, even after they are removed from the list. Useful for recurring feed jobs.", Type: "bool"},
 			{Key: "extension_prompt", Label: "Extension Prompt", Description: "Prompt for confirmation when adding downloads via browser extension.", Type: "bool"},
-			{Key: "auto_resume", Label: "Auto Resume", Description: "Automatically resume paused downloads on startup.", Type: "bool"},
+			{Key: "auto_resume", Label: "Auto Resume", Description: "Resume downloads stopped by shutdown or a crash on startup. Downloads you paused stay paused.", Type: "bool"},
 			{Key: "skip_update_check", Label: "Skip Update Check", Description: "Disable automatic check for new versions on startup.", Type: "bool"},
 
 			{Key: "clipboard_monitor", Label: "Clipboard Monitor", Description: "Watch clipboard for URLs and prompt to download them.", Type: "bool"},
-			{Key: "theme", Label: "App Theme", Description: "UI Theme (System, Light, Dark).", Type: "int"},
-			{Key: "log_retention_count", Label: "Log Retention Count", Description: "Number of recent log files to keep.", Type: "int"},
+			{Key: "theme", Label: "App Theme", Description: "UI Theme (System, Light, Dark).", Type: "int", Range: &SettingRange{Min: 0, Max: 2}, Choices: []string{"System", "Light", "Dark"}, Example: "dark"},
+			{Key: "log_retention_count", Label: "Log Retention Count", Description: "Number of recent log files to keep.", Type: "int", Unit: "files", Range: &SettingRange{Min: 0}, Example: "5"},
+			{Key: "reverify_after_days", Label: "Re-verify After", Description: "Before resuming a download paused this long, re-check the remote size and ETag and compare samples of the downloaded data (0 = never).", Type: "int", Unit: "days", Range: &SettingRange{Min: 0, Max: 365}, Example: "7"},
+			{Key: "trash_retention_days", Label: "Trash Retention", Description: "Deleted downloads stay in the trash, with their data, this long and can be restored with 'surge trash restore' (0 = delete at once).", Type: "int", Unit: "days", Range: &SettingRange{Min: 0, Max: 365}, Example: "7"},
+			{Key: "state_store", Label: "State Store", Description: "SQLite database file holding the download queue. Leave empty for surge.db in the state directory. Requires restart.", Type: "string", Example: "/srv/surge/surge.db"},
+			{Key: "encrypt_state", Label: "Encrypt State", Description: "Encrypt the request headers and URLs stored with downloads, such as cookies and tokens, with a key kept in the OS keychain (or SURGE_STATE_KEY). Requires restart.", Type: "bool"},
 		},
 		"Categories": {
 			{Key: "category_enabled", Label: "Manage Categories", Description: "Sort downloads into subfolders by file type. Press Enter to open Category Manager.", Type: "bool"},
 		},
 		"Network": {
-			{Key: "max_connections_per_host", Label: "Max Connections/Host", Description: "Maximum concurrent connections per host (1-64).", Type: "int"},
-			{Key: "max_concurrent_downloads", Label: "Max Concurrent Downloads", Description: "Maximum number of downloads running at once (1-10). Requires restart.", Type: "int"},
-			{Key: "user_agent", Label: "User Agent", Description: "Custom User-Agent string for HTTP requests. Leave empty for default.", Type: "string"},
-			{Key: "proxy_url", Label: "Proxy URL", Description: "HTTP/HTTPS proxy URL (e.g. http://127.0.0.1:1700). Leave empty to use system default.", Type: "string"},
+			{Key: "max_connections_per_host", Label: "Max Connections/Host", Description: "Maximum concurrent connections per host.", Type: "int", Unit: "connections", Range: &SettingRange{Min: 1, Max: 64}, Example: "32"},
+			{Key: "max_concurrent_downloads", Label: "Max Concurrent Downloads", Description: "Maximum number of downloads running at once. Requires restart.", Type: "int", Unit: "downloads", Range: &SettingRange{Min: 1, Max: 10}, Example: "3"},
+			{Key: "user_agent", Label: "User Agent", Description: "Custom User-Agent string for HTTP requests. Leave empty for default.", Type: "string", Example: "Mozilla/5.0 (X11; Linux x86_64)"},
+			{Key: "proxy_url", Label: "Proxy URL", Description: "HTTP/HTTPS proxy URL. Leave empty to use system default.", Type: "string", Example: "http://127.0.0.1:1700"},
 			{Key: "sequential_download", Label: "Sequential Download", Description: "Download pieces in order (Streaming Mode). May be slower.", Type: "bool"},
-			{Key: "min_chunk_size", Label: "Min Chunk Size", Description: "Minimum download chunk size in MB (e.g., 2).", Type: "int64"},
-			{Key: "worker_buffer_size", Label: "Worker Buffer Size", Description: "I/O buffer size per worker in KB (e.g., 512).", Type: "int"},
+			{Key: "min_chunk_size", Label: "Min Chunk Size", Description: "Minimum download chunk size. Entered in megabytes, not bytes.", Type: "int64", Unit: "MB", Range: &SettingRange{Min: 0.1, Max: 1024}, Example: "2"},
+			{Key: "worker_buffer_size", Label: "Worker Buffer Size", Description: "I/O buffer size per worker. Entered in kilobytes, not bytes.", Type: "int", Unit: "KB", Range: &SettingRange{Min: 4, Max: 65536}, Example: "512"},
+			{Key: "global_rate_limit", Label: "Global Speed Limit", Description: "Maximum combined download speed (0 = unlimited). Hosts in domain_overrides with exempt_from_rate_limit are never throttled.", Type: "int64", Unit: "KB/s", Range: &SettingRange{Min: 0}, Example: "2048"},
+			{Key: "keep_compressed_responses", Label: "Keep Compressed Responses", Description: "Save gzip-encoded responses as received instead of decoding them. Such downloads always use a single connection.", Type: "bool"},
+			{Key: "prewarm_connections", Label: "Prewarm Connections", Description: "Shortly before a download slot frees, resolve the next queued download's host and open a TLS session to it so it starts without the usual connection delay. Not used with a proxy.", Type: "bool"},
+			{Key: "auto_calibrate", Label: "Auto Calibrate", Description: "Measure bandwidth and latency once, at the first start with no downloads to run, and tune connections, chunk and buffer size. Run 'surge calibrate' to redo it.", Type: "bool"},
 		},
 		"Performance": {
-			{Key: "max_task_retries", Label: "Max Task Retries", Description: "Number of times to retry a failed chunk before giving up.", Type: "int"},
-			{Key: "slow_worker_threshold", Label: "Slow Worker Threshold", Description: "Restart workers slower than this fraction of mean speed (0.0-1.0).", Type: "float64"},
-			{Key: "slow_worker_grace_period", Label: "Slow Worker Grace", Description: "Grace period before checking worker speed (e.g., 5s).", Type: "duration"},
-			{Key: "stall_timeout", Label: "Stall Timeout", Description: "Restart workers with no data for this duration (e.g., 5s).", Type: "duration"},
-			{Key: "speed_ema_alpha", Label: "Speed EMA Alpha", Description: "Exponential moving average smoothing factor (0.0-1.0).", Type: "float64"},
+			{Key: "max_task_retries", Label: "Max Task Retries", Description: "Number of times to retry a failed chunk before giving up.", Type: "int", Unit: "retries", Range: &SettingRange{Min: 0, Max: 100}, Example: "3"},
+			{Key: "slow_worker_threshold", Label: "Slow Worker Threshold", Description: "Restart workers slower than this fraction of mean speed.", Type: "float64", Range: &SettingRange{Min: 0, Max: 1}, Example: "0.3"},
+			{Key: "slow_worker_grace_period", Label: "Slow Worker Grace", Description: "Grace period before checking worker speed.", Type: "duration", Unit: "seconds", Range: &SettingRange{Min: 0}, Example: "5"},
+			{Key: "stall_timeout", Label: "Stall Timeout", Description: "Restart workers with no data for this duration.", Type: "duration", Unit: "seconds", Range: &SettingRange{Min: 0.1}, Example: "5"},
+			{Key: "speed_ema_alpha", Label: "Speed EMA Alpha", Description: "Exponential moving average smoothing factor.", Type: "float64", Range: &SettingRange{Min: 0, Max: 1}, Example: "0.3"},
+			{Key: "max_buffer_memory", Label: "Buffer Memory Cap", Description: "Memory all downloads may use for worker buffers (0 = unlimited). Workers wait for a free buffer once the cap is reached.", Type: "int64", Unit: "MB", Range: &SettingRange{Min: 0}, Example: "256"},
+			{Key: "dense_preallocation", Label: "Dense Preallocation", Description: "Reserve the full file size on disk when a download starts. When off, incomplete files are sparse and only use space for bytes already fetched.", Type: "bool"},
+			{Key: "db_busy_timeout", Label: "DB Busy Timeout", Description: "How long a state database write waits for another writer before failing. Raise it if many active downloads report 'database is locked'. Requires restart.", Type: "duration", Unit: "seconds", Range: &SettingRange{Min: 1, Max: 300}, Example: "5"},
 		},
 	}
 }
@@ -112,20 +451,23 @@ const (
 // DefaultSettings returns a new Settings instance with sensible defaults.
 func DefaultSettings() *Settings {
 
-	defaultDir := GetDownloadsDir()
+	defaultDir := GetProfileDownloadsDir()
 
 	return &Settings{
 		General: GeneralSettings{
 			DefaultDownloadDir: defaultDir,
 			WarnOnDuplicate:    true,
+			DownloadArchive:    false,
 			ExtensionPrompt:    false,
 			AutoResume:         false,
 			CategoryEnabled:    false,
 			Categories:         DefaultCategories(),
 
-			ClipboardMonitor:  true,
-			Theme:             ThemeAdaptive,
-			LogRetentionCount: 5,
+			ClipboardMonitor:   true,
+			Theme:              ThemeAdaptive,
+			LogRetentionCount:  5,
+			ReverifyAfterDays:  7,
+			TrashRetentionDays: 7,
 		},
 		Network: NetworkSettings{
 			MaxConnectionsPerHost:  32,
@@ -134,6 +476,7 @@ func DefaultSettings() *Settings {
 			SequentialDownload:     false,
 			MinChunkSize:           2 * MB,
 			WorkerBufferSize:       512 * KB,
+			AutoCalibrate:          false,
 		},
 		Performance: PerformanceSettings{
 			MaxTaskRetries:        3,
@@ -141,6 +484,17 @@ func DefaultSettings() *Settings {
 			SlowWorkerGracePeriod: 5 * time.Second,
 			StallTimeout:          3 * time.Second,
 			SpeedEmaAlpha:         0.3,
+			MaxBufferMemory:       256 * MB,
+			DensePreallocation:    false,
+			DBBusyTimeout:         5 * time.Second,
+		},
+		StatusPage: StatusPageSettings{
+			Enabled:       false,
+			Port:          1790,
+			ShowFilenames: false,
+			ShowSizes:     true,
+			ShowSpeed:     true,
+			ShowETA:       true,
 		},
 	}
 }
@@ -208,6 +562,14 @@ type RuntimeConfig struct {
 	SlowWorkerGracePeriod time.Duration
 	StallTimeout          time.Duration
 	SpeedEmaAlpha         float64
+	GlobalRateLimit       int64
+	RateLimitExemptHosts  []string
+	KeepCompressed        bool
+	PrewarmConnections    bool
+	MaxBufferMemory       int64
+	ReverifyAfter         time.Duration
+	DensePreallocation    bool
+	RelayPeers            []PeerDaemon
 }
 
 // ToRuntimeConfig creates a RuntimeConfig from user Settings
@@ -224,5 +586,49 @@ func (s *Settings) ToRuntimeConfig() *RuntimeConfig {
 		SlowWorkerGracePeriod: s.Performance.SlowWorkerGracePeriod,
 		StallTimeout:          s.Performance.StallTimeout,
 		SpeedEmaAlpha:         s.Performance.SpeedEmaAlpha,
+		GlobalRateLimit:       s.Network.GlobalRateLimit,
+		RateLimitExemptHosts:  s.RateLimitExemptHosts(),
+		KeepCompressed:        s.Network.KeepCompressed,
+		PrewarmConnections:    s.Network.PrewarmConnections,
+		MaxBufferMemory:       s.Performance.MaxBufferMemory,
+		ReverifyAfter:         time.Duration(s.General.ReverifyAfterDays) * 24 * time.Hour,
+		DensePreallocation:    s.Performance.DensePreallocation,
+		RelayPeers:            s.RelayPeers(),
+	}
+}
+
+// ToRuntimeConfigForCategory is ToRuntimeConfig with the named category's
+// connection limit applied, if it sets one
+func (s *Settings) ToRuntimeConfigForCategory(category string) *RuntimeConfig {
+	rc := s.ToRuntimeConfig()
+	if cat := FindCategory(category, s.General.Categories); cat != nil && cat.MaxConnections > 0 {
+		rc.MaxConnectionsPerHost = cat.MaxConnections
+	}
+	return rc
+}
+
+// RelayPeers returns the peer daemons downloads are split across, or nil when
+// coordinator mode is off
+func (s *Settings) RelayPeers() []PeerDaemon {
+	if !s.Distributed.Enabled {
+		return nil
+	}
+	var peers []PeerDaemon
+	for _, p := range s.Distributed.Peers {
+		if strings.TrimSpace(p.URL) != "" && p.Token != "" {
+			peers = append(peers, p)
+		}
+	}
+	return peers
+}
+
+// RateLimitExemptHosts returns the domain override hosts that bypass the global speed limit
+func (s *Settings) RateLimitExemptHosts() []string {
+	var hosts []string
+	for _, o := range s.DomainOverrides {
+		if o.ExemptFromRateLimit && o.Host != "" {
+			hosts = append(hosts, o.Host)
+		}
 	}
+	return hosts
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -304,6 +305,78 @@ func TestToRuntimeConfig(t *testing.T) {
 	if runtime.SpeedEmaAlpha != settings.Performance.SpeedEmaAlpha {
 		t.Error("SpeedEmaAlpha not correctly mapped")
 	}
+	if runtime.MaxBufferMemory != settings.Performance.MaxBufferMemory || runtime.MaxBufferMemory == 0 {
+		t.Error("MaxBufferMemory not correctly mapped")
+	}
+	if runtime.ReverifyAfter != time.Duration(settings.General.ReverifyAfterDays)*24*time.Hour || runtime.ReverifyAfter == 0 {
+		t.Error("ReverifyAfter not correctly mapped")
+	}
+	settings.Performance.DensePreallocation = true
+	if !settings.ToRuntimeConfig().DensePreallocation {
+		t.Error("DensePreallocation not correctly mapped")
+	}
+}
+
+func TestToRuntimeConfig_RateLimitExemptions(t *testing.T) {
+	settings := DefaultSettings()
+	settings.Network.GlobalRateLimit = 512 * KB
+	settings.DomainOverrides = []DomainOverride{
+		{Host: "nas.local", ExemptFromRateLimit: true},
+		{Host: "example.com", ExemptFromRateLimit: false},
+		{Host: "192.168.0.0/16", ExemptFromRateLimit: true},
+	}
+
+	runtime := settings.ToRuntimeConfig()
+	if runtime.GlobalRateLimit != 512*KB {
+		t.Errorf("GlobalRateLimit = %d, want %d", runtime.GlobalRateLimit, 512*KB)
+	}
+	want := []string{"nas.local", "192.168.0.0/16"}
+	if len(runtime.RateLimitExemptHosts) != len(want) {
+		t.Fatalf("RateLimitExemptHosts = %v, want %v", runtime.RateLimitExemptHosts, want)
+	}
+	for i, h := range want {
+		if runtime.RateLimitExemptHosts[i] != h {
+			t.Errorf("RateLimitExemptHosts[%d] = %q, want %q", i, runtime.RateLimitExemptHosts[i], h)
+		}
+	}
+}
+
+func TestToRuntimeConfig_RelayPeers(t *testing.T) {
+	settings := DefaultSettings()
+	settings.Distributed.Peers = []PeerDaemon{
+		{URL: "http://10.0.0.5:1700", Token: "a"},
+		{URL: "http://10.0.0.6:1700"}, // no token, can't be used
+		{URL: " ", Token: "b"},
+	}
+
+	if peers := settings.ToRuntimeConfig().RelayPeers; peers != nil {
+		t.Fatalf("RelayPeers = %v while coordinator mode is off", peers)
+	}
+
+	settings.Distributed.Enabled = true
+	peers := settings.ToRuntimeConfig().RelayPeers
+	if len(peers) != 1 || peers[0].URL != "http://10.0.0.5:1700" || peers[0].Token != "a" {
+		t.Errorf("RelayPeers = %v, want only the complete peer", peers)
+	}
+}
+
+func TestToRuntimeConfigForCategory(t *testing.T) {
+	settings := DefaultSettings()
+	settings.Network.MaxConnectionsPerHost = 32
+	settings.General.Categories = []Category{
+		{Name: "Releases", URLPattern: `github\.com`, Path: "/releases", MaxConnections: 4},
+		{Name: "Docs", Pattern: `\.pdf$`, Path: "/docs"},
+	}
+
+	if got := settings.ToRuntimeConfigForCategory("releases").MaxConnectionsPerHost; got != 4 {
+		t.Errorf("Releases MaxConnectionsPerHost = %d, want 4", got)
+	}
+	if got := settings.ToRuntimeConfigForCategory("Docs").MaxConnectionsPerHost; got != 32 {
+		t.Errorf("Docs MaxConnectionsPerHost = %d, want the global 32", got)
+	}
+	if got := settings.ToRuntimeConfigForCategory("").MaxConnectionsPerHost; got != 32 {
+		t.Errorf("uncategorized MaxConnectionsPerHost = %d, want the global 32", got)
+	}
 }
 
 func TestGetSettingsMetadata(t *testing.T) {
@@ -581,3 +654,154 @@ func TestDefaultSettings_Fallback(t *testing.T) {
 		}
 	}
 }
+
+func TestSettingMeta_Validate(t *testing.T) {
+	metas := map[string]SettingMeta{}
+	for _, settings := range GetSettingsMetadata() {
+		for _, s := range settings {
+			metas[s.Key] = s
+		}
+	}
+
+	tests := []struct {
+		key     string
+		input   string
+		wantErr string
+	}{
+		{"min_chunk_size", "2", ""},
+		{"min_chunk_size", "0.5", ""},
+		{"min_chunk_size", "2097152", "must be between 0.1 and 1024 MB"},
+		{"min_chunk_size", "two", "enter a number"},
+		{"max_connections_per_host", "0", "must be between 1 and 64 connections"},
+		{"max_connections_per_host", "8.5", "enter a whole number"},
+		{"global_rate_limit", "-1", "must be at least 0 KB/s"},
+		{"global_rate_limit", "0", ""},
+		{"stall_timeout", "5s", ""},
+		{"stall_timeout", "1m", ""},
+		{"stall_timeout", "soon", "enter seconds or a duration like 5s"},
+		{"speed_ema_alpha", "1.5", "must be between 0 and 1"},
+		{"theme", "Dark", ""},
+		{"theme", "3", "must be between 0 and 2"},
+		{"user_agent", "anything", ""},
+	}
+
+	for _, tt := range tests {
+		meta, ok := metas[tt.key]
+		if !ok {
+			t.Fatalf("missing metadata for %s", tt.key)
+		}
+		err := meta.Validate(tt.input)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s=%q: unexpected error %v", tt.key, tt.input, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != tt.wantErr {
+			t.Errorf("%s=%q: expected %q, got %v", tt.key, tt.input, tt.wantErr, err)
+		}
+	}
+}
+
+func TestGetSettingsMetadata_DefaultsWithinRange(t *testing.T) {
+	// The TUI pre-fills the editor with the current value, so defaults must
+	// validate in their display unit.
+	defaults := DefaultSettings()
+	displayed := map[string]float64{
+		"max_connections_per_host": float64(defaults.Network.MaxConnectionsPerHost),
+		"max_concurrent_downloads": float64(defaults.Network.MaxConcurrentDownloads),
+		"min_chunk_size":           float64(defaults.Network.MinChunkSize) / float64(MB),
+		"worker_buffer_size":       float64(defaults.Network.WorkerBufferSize) / float64(KB),
+		"max_task_retries":         float64(defaults.Performance.MaxTaskRetries),
+		"stall_timeout":            defaults.Performance.StallTimeout.Seconds(),
+		"speed_ema_alpha":          defaults.Performance.SpeedEmaAlpha,
+		"max_buffer_memory":        float64(defaults.Performance.MaxBufferMemory) / float64(MB),
+		"reverify_after_days":      float64(defaults.General.ReverifyAfterDays),
+		"trash_retention_days":     float64(defaults.General.TrashRetentionDays),
+	}
+
+	for _, settings := range GetSettingsMetadata() {
+		for _, s := range settings {
+			v, ok := displayed[s.Key]
+			if !ok {
+				continue
+			}
+			if err := s.Validate(strconv.FormatFloat(v, 'f', -1, 64)); err != nil {
+				t.Errorf("default for %s fails validation: %v", s.Key, err)
+			}
+		}
+	}
+}
+
+func TestOriginAllowed(t *testing.T) {
+	allowed := []string{"https://nas.lan:8443", "http://media.lan:*"}
+	for _, o := range append(allowed, "*", "https://*", "http://localhost:*") {
+		if err := ValidateOrigin(o); err != nil {
+			t.Errorf("ValidateOrigin(%q) = %v", o, err)
+		}
+	}
+	for _, o := range []string{"https://*.invalid", "https://host:80:*", "nas.lan", "https://user@nas.lan"} {
+		if err := ValidateOrigin(o); err == nil {
+			t.Errorf("ValidateOrigin(%q) accepted", o)
+		}
+	}
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://nas.lan:8443", true},
+		{"https://NAS.lan:8443", true},
+		{"https://nas.lan", false},
+		{"http://media.lan", true},
+		{"http://media.lan:8080", true},
+		{"https://media.lan", false},
+		{"moz-extension://6b1e0a5c-0c0e-4a5e-9d5e-1f1f1f1f1f1f", true},
+		{"chrome-extension://abcdefghijklmnopabcdefghijklmnop", true},
+		{"http://localhost:3000", true},
+		{"http://127.0.0.1", true},
+		{"https://evil.example", false},
+		{"null", false},
+	}
+	for _, tt := range tests {
+		if got := OriginAllowed(tt.origin, allowed); got != tt.want {
+			t.Errorf("OriginAllowed(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestSettings_Validate(t *testing.T) {
+	if err := DefaultSettings().Validate(); err != nil {
+		t.Fatalf("defaults: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		change  func(*Settings)
+		wantErr string
+	}{
+		{"connections above range", func(s *Settings) { s.Network.MaxConnectionsPerHost = 65 }, "max_connections_per_host must be between 1 and 64 connections"},
+		{"chunk size in bytes", func(s *Settings) { s.Network.MinChunkSize = 1024 }, "min_chunk_size must be between 0.1 and 1024 MB"},
+		{"stall timeout in nanoseconds", func(s *Settings) { s.Performance.StallTimeout = time.Millisecond }, "stall_timeout must be at least 0.1 seconds"},
+		{"unnamed category", func(s *Settings) { s.General.Categories = []Category{{Pattern: "x", Path: "/tmp"}} }, "categories[0]: category name cannot be empty"},
+		{"empty override host", func(s *Settings) { s.DomainOverrides = []DomainOverride{{ExemptFromRateLimit: true}} }, "domain_overrides[0]: host cannot be empty"},
+		{"status page port", func(s *Settings) { s.StatusPage.Enabled = true; s.StatusPage.Port = 0 }, "status_page port must be between 1 and 65535"},
+		{"bind address", func(s *Settings) { s.Server.BindAddress = "lan" }, `server bind_address "lan" must be an IP address or localhost`},
+		{"origin with path", func(s *Settings) { s.Server.AllowedOrigins = []string{"https://example.com/app"} }, `server allowed origin "https://example.com/app" must look like https://host[:port]`},
+		{"tls cert without key", func(s *Settings) { s.Server.TLSCert = "cert.pem" }, "server tls_cert and tls_key must be set together"},
+	}
+	for _, tt := range tests {
+		s := DefaultSettings()
+		tt.change(s)
+		if err := s.Validate(); err == nil || err.Error() != tt.wantErr {
+			t.Errorf("%s: got %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+
+	s := DefaultSettings()
+	s.Network.MinChunkSize = 4 * MB
+	s.Performance.StallTimeout = 10 * time.Second
+	if err := s.Validate(); err != nil {
+		t.Errorf("in-range values: %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"errors"
+	"strings"
+)
+
+// WatchFolder is a drop folder: .torrent, .metalink, .meta4 and .txt link
+// files saved into Path are queued, then renamed with a .queued suffix, or
+// .failed when nothing in them could be.
+type WatchFolder struct {
+	Path      string   `json:"path"`
+	OutputDir string   `json:"output_dir,omitempty"` // Where the downloads go, the default download dir when empty
+	Category  string   `json:"category,omitempty"`   // Category given to the downloads
+	Tags      []string `json:"tags,omitempty"`
+	Disabled  bool     `json:"disabled,omitempty"`
+}
+
+// Validate checks that the folder has a path and any category exists
+func (w WatchFolder) Validate(categories []Category) error {
+	if strings.TrimSpace(w.Path) == "" {
+		return errors.New("path cannot be empty")
+	}
+	if w.Category != "" && FindCategory(w.Category, categories) == nil {
+		return errors.New("unknown category " + w.Category)
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package config
+
+import "testing"
+
+func TestWatchFolder_Validate(t *testing.T) {
+	categories := DefaultCategories()
+	if err := (WatchFolder{Path: "/srv/drop"}).Validate(categories); err != nil {
+		t.Errorf("valid folder: %v", err)
+	}
+	if err := (WatchFolder{Path: "  "}).Validate(categories); err == nil {
+		t.Error("expected an error for an empty path")
+	}
+	if err := (WatchFolder{Path: "/srv/drop", Category: "Nope"}).Validate(categories); err == nil {
+		t.Error("expected an error for an unknown category")
+	}
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"text/template"
+)
+
+// Download events a webhook can subscribe to
+const (
+	WebhookStarted   = "started"
+	WebhookCompleted = "completed"
+	WebhookError     = "error"
+)
+
+// WebhookEvents lists every event a webhook can subscribe to
+var WebhookEvents = []string{WebhookStarted, WebhookCompleted, WebhookError}
+
+// Webhook posts a JSON body to URL when a download starts, completes or
+// fails. Failed deliveries are retried with backoff.
+type Webhook struct {
+	ID       string            `json:"id"`
+	URL      string            `json:"url"`
+	Events   []string          `json:"events,omitempty"`   // Some of WebhookEvents, all when empty
+	Template string            `json:"template,omitempty"` // text/template for the body, the default payload when empty
+	Headers  map[string]string `json:"headers,omitempty"`  // Sent with every request, e.g. Authorization
+	Disabled bool              `json:"disabled,omitempty"`
+}
+
+// WebhookTemplateFuncs are the functions webhook templates may call. json
+// quotes a value for use inside the body.
+var WebhookTemplateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// Wants reports whether the webhook fires on event
+func (w Webhook) Wants(event string) bool {
+	return !w.Disabled && (len(w.Events) == 0 || slices.Contains(w.Events, event))
+}
+
+// ParseTemplate parses the webhook's body template, nil when it has none
+func (w Webhook) ParseTemplate() (*template.Template, error) {
+	if w.Template == "" {
+		return nil, nil
+	}
+	return template.New("webhook").Funcs(WebhookTemplateFuncs).Option("missingkey=error").Parse(w.Template)
+}
+
+// Validate checks the URL, events and template of a webhook
+func (w Webhook) Validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook url must be an http(s) URL")
+	}
+	for _, event := range w.Events {
+		if !slices.Contains(WebhookEvents, event) {
+			return fmt.Errorf("unknown webhook event %q (want started, completed or error)", event)
+		}
+	}
+	if _, err := w.ParseTemplate(); err != nil {
+		return fmt.Errorf("webhook template: %w", err)
+	}
+	return nil
+}
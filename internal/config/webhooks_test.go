@@ -0,0 +1,44 @@
+package config
+
+import "testing"
+
+func TestWebhook_Validate(t *testing.T) {
+	valid := []Webhook{
+		{ID: "a", URL: "https://example.com/hook"},
+		{ID: "b", URL: "http://127.0.0.1:9000/", Events: []string{WebhookCompleted, WebhookError}},
+		{ID: "c", URL: "https://example.com/hook", Template: `{"text": {{json .Filename}}}`},
+	}
+	for _, w := range valid {
+		if err := w.Validate(); err != nil {
+			t.Errorf("%s: %v", w.ID, err)
+		}
+	}
+
+	invalid := map[string]Webhook{
+		"no url":       {ID: "a"},
+		"ftp url":      {ID: "a", URL: "ftp://example.com/"},
+		"bad event":    {ID: "a", URL: "https://example.com/", Events: []string{"paused"}},
+		"bad template": {ID: "a", URL: "https://example.com/", Template: `{"text": {{.Filename}`},
+	}
+	for name, w := range invalid {
+		if err := w.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestWebhook_Wants(t *testing.T) {
+	all := Webhook{URL: "https://example.com/"}
+	errorsOnly := Webhook{URL: "https://example.com/", Events: []string{WebhookError}}
+	disabled := Webhook{URL: "https://example.com/", Disabled: true}
+
+	if !all.Wants(WebhookStarted) || !all.Wants(WebhookError) {
+		t.Error("a webhook without events should want every event")
+	}
+	if errorsOnly.Wants(WebhookCompleted) || !errorsOnly.Wants(WebhookError) {
+		t.Error("events should limit what a webhook wants")
+	}
+	if disabled.Wants(WebhookError) {
+		t.Error("a disabled webhook should want nothing")
+	}
+}
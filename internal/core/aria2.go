@@ -0,0 +1,190 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// ExportAria2 writes downloads as an aria2 session (input) file
+const ExportAria2 = "aria2"
+
+// aria2UnfinishedStatuses are exported when no status filter is given: what
+// is left to download, which is what a session file carries
+var aria2UnfinishedStatuses = []string{"queued", "paused", "downloading", "error"}
+
+// Aria2Download is one entry of an aria2 session file, limited to what Surge
+// can use
+type Aria2Download struct {
+	URIs        []string // The first is the URL, the rest mirrors
+	Dir         string
+	Out         string // Filename
+	Headers     map[string]string
+	Connections int   // max-connection-per-server, or split when that is missing
+	SpeedLimit  int64 // Bytes/sec, 0 = unlimited
+	ChunkSize   int64 // min-split-size, 0 = use settings
+	UserAgent   string
+	Paused      bool
+}
+
+// exportAria2 writes one session entry per download. URLs and mirrors go on
+// the entry's line and the rest as indented options, as aria2 saves them.
+func exportAria2(w io.Writer, filter state.ExportFilter) error {
+	if len(filter.Statuses) == 0 {
+		filter.Statuses = aria2UnfinishedStatuses
+	}
+	bw := bufio.NewWriter(w)
+	err := state.ExportDownloads(filter, func(e types.DownloadEntry) error {
+		uris := append([]string{e.URL}, e.Mirrors...)
+		fmt.Fprintln(bw, strings.Join(uris, "\t"))
+		if e.DestPath != "" {
+			fmt.Fprintf(bw, " dir=%s\n", filepath.Dir(e.DestPath))
+		}
+		if e.Filename != "" {
+			fmt.Fprintf(bw, " out=%s\n", e.Filename)
+		}
+		// Headers are left out: they may be credentials, which no export carries
+		if o := e.Overrides; o != nil {
+			if o.Connections > 0 {
+				fmt.Fprintf(bw, " max-connection-per-server=%d\n", o.Connections)
+			}
+			if o.SpeedLimit > 0 {
+				fmt.Fprintf(bw, " max-download-limit=%d\n", o.SpeedLimit)
+			}
+			if o.ChunkSize > 0 {
+				fmt.Fprintf(bw, " min-split-size=%d\n", o.ChunkSize)
+			}
+			if o.UserAgent != "" {
+				fmt.Fprintf(bw, " user-agent=%s\n", o.UserAgent)
+			}
+		}
+		if e.Status == "paused" || e.Status == "error" {
+			fmt.Fprintln(bw, " pause=true")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// ParseAria2Session reads an aria2 session or input file. Options Surge has
+// no use for are skipped; lines that can't be read are errors, with their
+// line number.
+func ParseAria2Session(r io.Reader) ([]Aria2Download, error) {
+	var downloads []Aria2Download
+	var current *Aria2Download
+	split := 0
+
+	finish := func() {
+		if current == nil {
+			return
+		}
+		if current.Connections == 0 {
+			current.Connections = split
+		}
+		downloads = append(downloads, *current)
+		current, split = nil, 0
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		// Unindented lines start an entry with its tab-separated URIs
+		if line[0] != ' ' && line[0] != '\t' {
+			finish()
+			current = &Aria2Download{URIs: strings.Split(trimmed, "\t")}
+			continue
+		}
+		if current == nil {
+			return nil, fmt.Errorf("line %d: option before any URI", n)
+		}
+
+		name, value, ok := strings.Cut(trimmed, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected name=value, got %q", n, trimmed)
+		}
+		var err error
+		switch name {
+		case "dir":
+			current.Dir = value
+		case "out":
+			current.Out = value
+		case "header":
+			var h map[string]string
+			if h, err = utils.ParseHeaders([]string{value}); err == nil {
+				if current.Headers == nil {
+					current.Headers = make(map[string]string)
+				}
+				for k, v := range h {
+					current.Headers[k] = v
+				}
+			}
+		case "max-connection-per-server":
+			current.Connections, err = strconv.Atoi(value)
+		case "split":
+			split, err = strconv.Atoi(value)
+		case "max-download-limit":
+			current.SpeedLimit, err = parseAria2Bytes(value)
+		case "min-split-size":
+			current.ChunkSize, err = parseAria2Bytes(value)
+		case "user-agent":
+			current.UserAgent = value
+		case "pause":
+			current.Paused = value == "true"
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid %s: %w", n, name, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	finish()
+	return downloads, nil
+}
+
+// parseAria2Bytes reads an aria2 size such as 512, 100K or 2M
+func parseAria2Bytes(s string) (int64, error) {
+	mult := int64(1)
+	switch {
+	case strings.HasSuffix(s, "K"), strings.HasSuffix(s, "k"):
+		mult, s = types.KB, s[:len(s)-1]
+	case strings.HasSuffix(s, "M"), strings.HasSuffix(s, "m"):
+		mult, s = types.MB, s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q is not a size", s)
+	}
+	return n * mult, nil
+}
+
+// Aria2Supported reports whether Surge can download d: aria2 also takes
+// magnet links, torrents and metalinks, which Surge does not
+func Aria2Supported(d Aria2Download) bool {
+	if len(d.URIs) == 0 {
+		return false
+	}
+	for _, uri := range d.URIs {
+		scheme, _, _ := strings.Cut(strings.ToLower(uri), "://")
+		if scheme != "http" && scheme != "https" {
+			return false
+		}
+	}
+	return true
+}
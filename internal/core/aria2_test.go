@@ -0,0 +1,95 @@
+package core
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestExportAria2RoundTrip(t *testing.T) {
+	state.CloseDB()
+	state.Configure(filepath.Join(t.TempDir(), "surge.db"))
+	defer state.CloseDB()
+
+	for _, e := range []types.DownloadEntry{
+		{ID: "done", URL: "https://example.com/done.iso", DestPath: "/data/done.iso", Filename: "done.iso", Status: "completed"},
+		{
+			ID: "big", URL: "https://example.com/big.iso", Mirrors: []string{"https://mirror.example.org/big.iso"},
+			DestPath: "/data/isos/big.iso", Filename: "big.iso", Status: "paused",
+			Overrides: &types.DownloadOverrides{Headers: map[string]string{"Cookie": "id=1"}, Connections: 4, SpeedLimit: 512 * types.KB, ChunkSize: 8 * types.MB, UserAgent: "Wget/1.21"},
+		},
+		{ID: "next", URL: "https://example.com/next.zip", DestPath: "/data/next.zip", Filename: "next.zip", Status: "queued"},
+	} {
+		if err := state.AddToMasterList(e); err != nil {
+			t.Fatalf("AddToMasterList: %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := ExportDownloads(&buf, ExportAria2, state.ExportFilter{}); err != nil {
+		t.Fatalf("ExportDownloads aria2: %v", err)
+	}
+	if strings.Contains(buf.String(), "done.iso") {
+		t.Errorf("completed downloads should not be exported:\n%s", buf.String())
+	}
+
+	got, err := ParseAria2Session(&buf)
+	if err != nil {
+		t.Fatalf("ParseAria2Session: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("parsed %d entries, want 2: %+v", len(got), got)
+	}
+	big := got[0]
+	if len(big.URIs) != 2 || big.URIs[1] != "https://mirror.example.org/big.iso" {
+		t.Errorf("URIs = %q", big.URIs)
+	}
+	if big.Dir != "/data/isos" || big.Out != "big.iso" || !big.Paused {
+		t.Errorf("entry = %+v", big)
+	}
+	if big.Connections != 4 || big.SpeedLimit != 512*types.KB || big.ChunkSize != 8*types.MB || big.UserAgent != "Wget/1.21" {
+		t.Errorf("overrides lost: %+v", big)
+	}
+	if big.Headers != nil {
+		t.Errorf("stored headers must not be exported: %+v", big.Headers)
+	}
+	if got[1].URIs[0] != "https://example.com/next.zip" || got[1].Paused {
+		t.Errorf("queued entry = %+v", got[1])
+	}
+}
+
+func TestParseAria2Session(t *testing.T) {
+	session := `# saved by aria2
+https://example.com/a.bin
+  gid=2089b05ecca3d829
+  split=8
+  max-download-limit=2M
+  header=Authorization: Bearer abc
+magnet:?xt=urn:btih:abc
+ dir=/tmp
+`
+	got, err := ParseAria2Session(strings.NewReader(session))
+	if err != nil {
+		t.Fatalf("ParseAria2Session: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("parsed %+v, want 2 entries", got)
+	}
+	a := got[0]
+	if a.Connections != 8 || a.SpeedLimit != 2*types.MB || a.Headers["Authorization"] != "Bearer abc" {
+		t.Errorf("entry = %+v", a)
+	}
+	if !Aria2Supported(a) || Aria2Supported(got[1]) {
+		t.Error("only the http entry should be supported")
+	}
+
+	for _, bad := range []string{" dir=/tmp\n", "https://example.com/a\n split=many\n", "https://example.com/a\n novalue\n"} {
+		if _, err := ParseAria2Session(strings.NewReader(bad)); err == nil {
+			t.Errorf("ParseAria2Session(%q): expected an error", bad)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package core
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// Export formats
+const (
+	ExportCSV  = "csv"
+	ExportJSON = "json"
+)
+
+var exportCSVHeader = []string{
+	"id", "url", "filename", "dest_path", "status", "total_size", "downloaded",
+	"created_at", "completed_at", "time_taken_ms", "avg_speed", "tags", "category", "note",
+}
+
+// ParseExportFilter builds a filter from user input: a comma-separated status
+// list and since/until bounds given as RFC 3339 times or YYYY-MM-DD dates.
+// A date-only until covers that whole day.
+func ParseExportFilter(statuses, since, until string) (state.ExportFilter, error) {
+	f := state.ExportFilter{Statuses: ParseStatusList(statuses)}
+
+	var err error
+	if f.Since, err = parseExportTime(since, false); err != nil {
+		return f, fmt.Errorf("invalid since: %w", err)
+	}
+	if f.Until, err = parseExportTime(until, true); err != nil {
+		return f, fmt.Errorf("invalid until: %w", err)
+	}
+	if f.Since > 0 && f.Until > 0 && f.Until <= f.Since {
+		return f, fmt.Errorf("until must be after since")
+	}
+	return f, nil
+}
+
+func parseExportTime(s string, endOfDay bool) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.Unix(), nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, s, time.Local)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a date (YYYY-MM-DD) or RFC 3339 time", s)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t.Unix(), nil
+}
+
+// ParseSince parses a --since value into a Unix time: an age counted back
+// from now, such as 90m, 24h or 7d, or a date or time as ParseExportFilter
+// takes them. An empty s is 0, all time.
+func ParseSince(s string, now time.Time) (int64, error) {
+	s = strings.TrimSpace(s)
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n).Unix(), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return now.Add(-d).Unix(), nil
+	}
+	t, err := parseExportTime(s, false)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not an age like 24h or 7d, a date (YYYY-MM-DD) or an RFC 3339 time", s)
+	}
+	return t, nil
+}
+
+// IsExportFormat reports whether format is one ExportDownloads writes
+func IsExportFormat(format string) bool {
+	return format == ExportCSV || format == ExportJSON || format == ExportAria2
+}
+
+// ExportContentType returns the MIME type of an export format
+func ExportContentType(format string) string {
+	switch format {
+	case ExportCSV:
+		return "text/csv; charset=utf-8"
+	case ExportAria2:
+		return "text/plain; charset=utf-8"
+	}
+	return "application/json"
+}
+
+// ExportDownloads writes the downloads matching filter to w as CSV, a JSON
+// array or an aria2 session, one row at a time
+func ExportDownloads(w io.Writer, format string, filter state.ExportFilter) error {
+	switch format {
+	case ExportCSV:
+		return exportCSV(w, filter)
+	case ExportJSON:
+		return exportJSON(w, filter)
+	case ExportAria2:
+		return exportAria2(w, filter)
+	default:
+		return fmt.Errorf("unknown export format %q (want csv, json or aria2)", format)
+	}
+}
+
+func exportCSV(w io.Writer, filter state.ExportFilter) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(exportCSVHeader); err != nil {
+		return err
+	}
+	err := state.ExportDownloads(filter, func(e types.DownloadEntry) error {
+		return cw.Write([]string{
+			e.ID, e.URL, e.Filename, e.DestPath, e.Status,
+			strconv.FormatInt(e.TotalSize, 10), strconv.FormatInt(e.Downloaded, 10),
+			formatExportTime(e.CreatedAt), formatExportTime(e.CompletedAt),
+			strconv.FormatInt(e.TimeTaken, 10), strconv.FormatFloat(e.AvgSpeed, 'f', 0, 64),
+			strings.Join(e.Tags, ","), e.Category, e.Note,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func exportJSON(w io.Writer, filter state.ExportFilter) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	first := true
+	err := state.ExportDownloads(filter, func(e types.DownloadEntry) error {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		sep := ",\n"
+		if first {
+			sep, first = "\n", false
+		}
+		_, err = io.WriteString(w, sep+string(data))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n]\n")
+	return err
+}
+
+// formatExportTime renders a Unix timestamp as RFC 3339, or empty when unset
+func formatExportTime(unix int64) string {
+	if unix <= 0 {
+		return ""
+	}
+	return time.Unix(unix, 0).UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestExportDownloads(t *testing.T) {
+	state.CloseDB()
+	state.Configure(filepath.Join(t.TempDir(), "surge.db"))
+	defer state.CloseDB()
+
+	day := func(d int) int64 { return time.Date(2026, 3, d, 12, 0, 0, 0, time.Local).Unix() }
+	for _, e := range []types.DownloadEntry{
+		{ID: "a", URL: "https://example.com/a", DestPath: "/tmp/a", Filename: "a", Status: "completed", CreatedAt: day(1), CompletedAt: day(1), Tags: []string{"work", "iso"}},
+		{ID: "b", URL: "https://example.com/b", DestPath: "/tmp/b", Filename: "b", Status: "error", CreatedAt: day(2)},
+		{ID: "c", URL: "https://example.com/c", DestPath: "/tmp/c", Filename: "c, with comma", Status: "completed", CreatedAt: day(3), CompletedAt: day(3)},
+	} {
+		if err := state.AddToMasterList(e); err != nil {
+			t.Fatalf("AddToMasterList: %v", err)
+		}
+	}
+
+	filter, err := ParseExportFilter("completed", "2026-03-01", "2026-03-02")
+	if err != nil {
+		t.Fatalf("ParseExportFilter: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := ExportDownloads(&buf, ExportCSV, filter); err != nil {
+		t.Fatalf("ExportDownloads csv: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("reading csv: %v", err)
+	}
+	if len(records) != 2 || records[1][0] != "a" || records[1][11] != "work,iso" {
+		t.Fatalf("csv = %q", records)
+	}
+
+	// A date-only until includes that whole day
+	filter, _ = ParseExportFilter("", "", "2026-03-03")
+	buf.Reset()
+	if err := ExportDownloads(&buf, ExportJSON, filter); err != nil {
+		t.Fatalf("ExportDownloads json: %v", err)
+	}
+	var entries []types.DownloadEntry
+	if err := json.Unmarshal(buf.Bytes(), &entries); err != nil {
+		t.Fatalf("invalid json %q: %v", buf.String(), err)
+	}
+	if len(entries) != 3 || entries[2].Filename != "c, with comma" {
+		t.Fatalf("json = %+v", entries)
+	}
+
+	// An empty result is still a valid array
+	filter, _ = ParseExportFilter("paused", "", "")
+	buf.Reset()
+	if err := ExportDownloads(&buf, ExportJSON, filter); err != nil {
+		t.Fatalf("ExportDownloads json: %v", err)
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entries); err != nil || len(entries) != 0 {
+		t.Errorf("empty export = %q, %v", buf.String(), err)
+	}
+
+	if err := ExportDownloads(&buf, "xml", state.ExportFilter{}); err == nil {
+		t.Error("unknown format accepted")
+	}
+}
+
+func TestParseExportFilter(t *testing.T) {
+	f, err := ParseExportFilter(" Completed, error ,", "2026-01-01T00:00:00Z", "")
+	if err != nil {
+		t.Fatalf("ParseExportFilter: %v", err)
+	}
+	if len(f.Statuses) != 2 || f.Statuses[0] != "completed" || f.Since != 1767225600 || f.Until != 0 {
+		t.Errorf("filter = %+v", f)
+	}
+
+	for _, tc := range [][2]string{{"yesterday", ""}, {"", "03/01/2026"}, {"2026-02-01", "2026-01-01"}} {
+		if _, err := ParseExportFilter("", tc[0], tc[1]); err == nil {
+			t.Errorf("ParseExportFilter(%q, %q) succeeded", tc[0], tc[1])
+		}
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	for input, want := range map[string]time.Time{
+		"24h":                  now.Add(-24 * time.Hour),
+		"90m":                  now.Add(-90 * time.Minute),
+		"7d":                   now.AddDate(0, 0, -7),
+		"2026-01-01T00:00:00Z": time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		got, err := ParseSince(input, now)
+		if err != nil || got != want.Unix() {
+			t.Errorf("ParseSince(%q) = %d, %v; want %d", input, got, err, want.Unix())
+		}
+	}
+	if got, err := ParseSince("", now); err != nil || got != 0 {
+		t.Errorf("ParseSince(\"\") = %d, %v; want 0", got, err)
+	}
+	for _, input := range []string{"soon", "-3h", "3w"} {
+		if _, err := ParseSince(input, now); err == nil {
+			t.Errorf("ParseSince(%q) succeeded", input)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// normalizeHost accepts a host, host:port or URL and returns it lowercased
+// without the scheme or path
+func normalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if strings.Contains(host, "://") {
+		if u, err := url.Parse(host); err == nil {
+			return u.Host
+		}
+	}
+	return strings.TrimSuffix(host, "/")
+}
+
+// FromHost reports whether rawURL is served by host. A host without a port
+// matches any port.
+func FromHost(rawURL, host string) bool {
+	host = normalizeHost(host)
+	if host == "" {
+		return false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, host) || strings.EqualFold(u.Hostname(), host)
+}
+
+// FilterStatusesByHost keeps the statuses downloading from host, preserving
+// order
+func FilterStatusesByHost(statuses []types.DownloadStatus, host string) []types.DownloadStatus {
+	out := make([]types.DownloadStatus, 0, len(statuses))
+	for _, s := range statuses {
+		if FromHost(s.URL, host) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// PauseHost pauses every running download from host in one go and returns
+// the IDs it paused. Downloads that fail to pause are reported together and
+// do not stop the rest.
+func PauseHost(service DownloadService, host string) ([]string, error) {
+	ids, err := hostDownloads(service, host, "downloading")
+	if err != nil {
+		return nil, err
+	}
+
+	var paused []string
+	var errs []error
+	for _, id := range ids {
+		if err := service.Pause(id); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", id, err))
+			continue
+		}
+		paused = append(paused, id)
+	}
+	return paused, errors.Join(errs...)
+}
+
+// ResumeHost resumes every paused download from host in one batch and returns
+// the IDs it resumed
+func ResumeHost(service DownloadService, host string) ([]string, error) {
+	ids, err := hostDownloads(service, host, "paused")
+	if err != nil {
+		return nil, err
+	}
+
+	var resumed []string
+	var errs []error
+	for i, err := range service.ResumeBatch(ids) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ids[i], err))
+			continue
+		}
+		resumed = append(resumed, ids[i])
+	}
+	return resumed, errors.Join(errs...)
+}
+
+// hostDownloads returns the IDs of the downloads from host in status
+func hostDownloads(service DownloadService, host, status string) ([]string, error) {
+	if normalizeHost(host) == "" {
+		return nil, fmt.Errorf("missing host")
+	}
+	statuses, err := service.List()
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, s := range FilterStatusesByHost(statuses, host) {
+		if s.Status == status {
+			ids = append(ids, s.ID)
+		}
+	}
+	return ids, nil
+}
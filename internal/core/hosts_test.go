@@ -0,0 +1,40 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestFromHost(t *testing.T) {
+	tests := []struct {
+		url, host string
+		want      bool
+	}{
+		{"https://cdn.example.com/a.iso", "cdn.example.com", true},
+		{"https://cdn.example.com/a.iso", "CDN.Example.com", true},
+		{"https://cdn.example.com:8443/a.iso", "cdn.example.com", true},
+		{"https://cdn.example.com:8443/a.iso", "cdn.example.com:8443", true},
+		{"https://cdn.example.com/a.iso", "cdn.example.com:8443", false},
+		{"https://cdn.example.com/a.iso", "https://cdn.example.com/", true},
+		{"https://cdn2.example.com/a.iso", "cdn.example.com", false},
+		{"https://cdn.example.com/a.iso", "", false},
+	}
+	for _, tt := range tests {
+		if got := FromHost(tt.url, tt.host); got != tt.want {
+			t.Errorf("FromHost(%q, %q) = %v, want %v", tt.url, tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestFilterStatusesByHost(t *testing.T) {
+	statuses := []types.DownloadStatus{
+		{ID: "a", URL: "https://cdn.example.com/a"},
+		{ID: "b", URL: "https://other.example.com/b"},
+		{ID: "c", URL: "https://cdn.example.com/c"},
+	}
+	got := FilterStatusesByHost(statuses, "cdn.example.com")
+	if len(got) != 2 || got[0].ID != "a" || got[1].ID != "c" {
+		t.Fatalf("cdn.example.com = %+v", got)
+	}
+}
@@ -4,23 +4,25 @@ import (
 	"context"
 
 	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/processing"
 )
 
 // DownloadService defines the interface for interacting with the download engine.
 // This abstraction allows the TUI to switch between a local embedded backend
 // and a remote daemon connection.
 type DownloadService interface {
-	// List returns the status of all active and completed downloads.
+	// List returns the status of all active and completed downloads, ordered
+	// by when they were added and then by ID.
 	List() ([]types.DownloadStatus, error)
 
-	// History returns completed downloads
+	// History returns completed downloads, most recently completed first.
 	History() ([]types.DownloadEntry, error)
 
-	// Add queues a new download.
-	Add(url string, path string, filename string, mirrors []string, headers map[string]string, isExplicitCategory bool, totalSize int64, supportsRange bool) (string, error)
+	// Add queues a new download, labeled with req.Tags.
+	Add(req *processing.DownloadRequest) (string, error)
 
 	// AddWithID queues a new download with a caller-provided ID.
-	AddWithID(url string, path string, filename string, mirrors []string, headers map[string]string, id string, totalSize int64, supportsRange bool) (string, error)
+	AddWithID(req *processing.DownloadRequest, id string) (string, error)
 
 	// Pause pauses an active download.
 	Pause(id string) error
@@ -34,6 +36,9 @@ type DownloadService interface {
 	// UpdateURL updates the URL of a paused or errored download
 	UpdateURL(id string, newURL string) error
 
+	// UpdateNote changes the note and metadata attached to a download.
+	UpdateNote(id string, update types.NoteUpdate) error
+
 	// Delete cancels and removes a download.
 	Delete(id string) error
 
@@ -51,3 +56,103 @@ type DownloadService interface {
 	// Shutdown handles graceful shutdown of the service
 	Shutdown() error
 }
+
+// Pager is implemented by services that can page List and History in
+// storage, reading only the requested page instead of every download.
+type Pager interface {
+	// ListPage returns the List downloads q selects, in its order, and the
+	// cursor of the next page ("" on the last, or when not ordered by when
+	// they were added).
+	ListPage(q ListQuery) ([]types.DownloadStatus, string, error)
+
+	// HistoryPage pages History like ListPage.
+	HistoryPage(tag, cursor string, limit int) ([]types.DownloadEntry, string, error)
+}
+
+// Archiver is implemented by services that can remove a download while keeping
+// its partial data, so it can be restored and resumed later.
+type Archiver interface {
+	// Archive removes a download from the list like Delete, but keeps its
+	// working file and resume state.
+	Archive(id string) error
+
+	// RestorePartial brings back an archived download and resumes it.
+	RestorePartial(id string) error
+}
+
+// Trasher is implemented by services whose Delete moves downloads to a trash
+// they can be restored from until it expires.
+type Trasher interface {
+	// RestoreTrashed brings a download back from the trash.
+	RestoreTrashed(id string) error
+
+	// DeletePermanently deletes a download at once, bypassing the trash.
+	DeletePermanently(id string) error
+}
+
+// Retrier is implemented by services that can start a failed download over
+// in place, without removing and adding it again.
+type Retrier interface {
+	// Retry starts a failed download over from the beginning, probing its URL
+	// first when probe is set.
+	Retry(id string, probe bool) error
+}
+
+// Reorderer is implemented by services that keep the order waiting downloads
+// start in, across restarts.
+type Reorderer interface {
+	// Move puts a waiting download at position, counted from 1, in the queue.
+	Move(id string, position int) error
+	// MoveBy moves a waiting download offset places, toward the front when
+	// negative.
+	MoveBy(id string, offset int) error
+}
+
+// Prioritizer is implemented by services that start higher priority
+// waiting downloads first, whatever their place in the queue.
+type Prioritizer interface {
+	// SetPriority gives a waiting download priority and moves it to place,
+	// state.PlaceTop or state.PlaceBottom, among the downloads of that
+	// priority; "" keeps its place unless the priority changed.
+	SetPriority(id string, priority int, place string) error
+}
+
+// Relocator is implemented by services that can move a download's file to
+// another directory or rename it.
+type Relocator interface {
+	// Relocate moves the file of a finished or paused download into dir and
+	// returns its new path, renamed if the name was taken there.
+	Relocate(id, dir string) (string, error)
+	// Rename renames the file of a finished or paused download in its
+	// directory and returns its new path, numbered if name was taken.
+	Rename(id, name string) (string, error)
+}
+
+// ChunkMapper is implemented by services that can show which parts of a
+// download are done and what each of its connections is fetching.
+type ChunkMapper interface {
+	// ChunkMap returns the chunk map of a download, types.ErrNotFound when
+	// there is no such download.
+	ChunkMap(id string) (*types.ChunkMap, error)
+}
+
+// Verifier is implemented by services that can re-hash a completed download
+// on request.
+type Verifier interface {
+	// Verify starts checking a completed download's file against its
+	// checksum and returns; the outcome comes as an events.DownloadVerifiedMsg.
+	// It returns types.ErrNotFound when there is no such download and
+	// types.ErrNotCompleted when it hasn't finished.
+	Verify(id string) error
+}
+
+// SpeedLimiter is implemented by services that can change a download's own
+// speed limit while it runs.
+type SpeedLimiter interface {
+	// SpeedLimit returns a download's own speed limit in bytes/sec, 0 when it
+	// has none, and types.ErrNotFound when there is no such download.
+	SpeedLimit(id string) (int64, error)
+	// SetSpeedLimit changes it, at once when the download is running, and
+	// keeps it for later resumes.
+	SetSpeedLimit(id string, rate int64) error
+}
@@ -0,0 +1,132 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// List orders
+const (
+	SortAdded = "added" // Oldest first, the default
+	SortSpeed = "speed" // Fastest first
+	SortETA   = "eta"   // Soonest done first, unknown last
+)
+
+// ErrInvalidListQuery is returned for list parameters that can't be parsed or
+// combined
+var ErrInvalidListQuery = errors.New("invalid list query")
+
+// ListQuery selects and orders a page of List. Speed and ETA are live values,
+// so those orders are paged with Offset rather than Cursor.
+type ListQuery struct {
+	Tag      string
+	Statuses []string // Empty for any status
+	Search   string   // Case-insensitive substring of the filename or URL
+	Sort     string   // One of the Sort* orders, SortAdded when empty
+	Cursor   string   // Continue after a page in SortAdded order
+	Offset   int      // Skip this many downloads first
+	Limit    int      // At most this many, everything when 0
+}
+
+// Validate checks the sort and that the paging parameters can be combined
+func (q ListQuery) Validate() error {
+	switch q.Sort {
+	case "", SortAdded, SortSpeed, SortETA:
+	default:
+		return fmt.Errorf("%w: unknown sort %q (want added, speed or eta)", ErrInvalidListQuery, q.Sort)
+	}
+	if q.Offset < 0 {
+		return fmt.Errorf("%w: negative offset", ErrInvalidListQuery)
+	}
+	if q.Cursor != "" && q.Offset > 0 {
+		return fmt.Errorf("%w: use either cursor or offset", ErrInvalidListQuery)
+	}
+	if q.Cursor != "" && !q.byAdded() {
+		return fmt.Errorf("%w: cursor only pages the added order, use offset", ErrInvalidListQuery)
+	}
+	return nil
+}
+
+func (q ListQuery) byAdded() bool {
+	return q.Sort == "" || q.Sort == SortAdded
+}
+
+// Matches reports whether s passes the tag, status and search filters
+func (q ListQuery) Matches(s types.DownloadStatus) bool {
+	if q.Tag != "" && !utils.HasTag(s.Tags, q.Tag) {
+		return false
+	}
+	if len(q.Statuses) > 0 && !containsFold(q.Statuses, s.Status) {
+		return false
+	}
+	if search := strings.ToLower(strings.TrimSpace(q.Search)); search != "" {
+		return strings.Contains(strings.ToLower(s.Filename+"\n"+s.URL), search)
+	}
+	return true
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseStatusList splits a comma-separated status list, lowercased, dropping
+// empty entries
+func ParseStatusList(list string) []string {
+	var out []string
+	for _, s := range strings.Split(list, ",") {
+		if s = strings.ToLower(strings.TrimSpace(s)); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// SortStatusesBy reorders statuses already in SortStatuses order by one of
+// the Sort* orders; ties keep their order
+func SortStatusesBy(statuses []types.DownloadStatus, order string) {
+	switch order {
+	case SortSpeed:
+		sort.SliceStable(statuses, func(i, j int) bool {
+			return statuses[i].Speed > statuses[j].Speed
+		})
+	case SortETA:
+		sort.SliceStable(statuses, func(i, j int) bool {
+			a, b := statuses[i].ETA, statuses[j].ETA
+			if a <= 0 || b <= 0 {
+				return a > 0 && b <= 0
+			}
+			return a < b
+		})
+	}
+}
+
+// QueryStatuses applies q to every download as listed by List, in memory, for
+// services that can't page in storage
+func QueryStatuses(statuses []types.DownloadStatus, q ListQuery) ([]types.DownloadStatus, string, error) {
+	if err := q.Validate(); err != nil {
+		return nil, "", err
+	}
+	filtered := make([]types.DownloadStatus, 0, len(statuses))
+	for _, s := range statuses {
+		if q.Matches(s) {
+			filtered = append(filtered, s)
+		}
+	}
+	if !q.byAdded() {
+		SortStatusesBy(filtered, q.Sort)
+		start := min(q.Offset, len(filtered))
+		end, _ := window(len(filtered), start, q.Limit)
+		return filtered[start:end], "", nil
+	}
+	return PageStatuses(filtered[min(q.Offset, len(filtered)):], q.Cursor, q.Limit)
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func statusIDs(statuses []types.DownloadStatus) []string {
+	ids := make([]string, len(statuses))
+	for i, s := range statuses {
+		ids[i] = s.ID
+	}
+	return ids
+}
+
+func TestQueryStatuses(t *testing.T) {
+	statuses := []types.DownloadStatus{
+		{ID: "a", Filename: "ubuntu.iso", Status: "downloading", AddedAt: 10, Speed: 2, ETA: 30},
+		{ID: "b", Filename: "notes.txt", Status: "paused", AddedAt: 20},
+		{ID: "c", Filename: "Fedora.ISO", Status: "downloading", AddedAt: 30, Speed: 5, ETA: 10},
+		{ID: "d", URL: "https://example.com/debian.iso", Status: "completed", AddedAt: 40},
+	}
+
+	for _, tc := range []struct {
+		q    ListQuery
+		want string
+	}{
+		{ListQuery{}, "[a b c d]"},
+		{ListQuery{Statuses: []string{"downloading"}}, "[a c]"},
+		{ListQuery{Search: "iso"}, "[a c d]"},
+		{ListQuery{Sort: SortSpeed}, "[c a b d]"},
+		{ListQuery{Sort: SortETA}, "[c a b d]"},
+		{ListQuery{Sort: SortSpeed, Offset: 1, Limit: 2}, "[a b]"},
+		{ListQuery{Offset: 3}, "[d]"},
+		{ListQuery{Offset: 9}, "[]"},
+	} {
+		page, _, err := QueryStatuses(statuses, tc.q)
+		if err != nil {
+			t.Fatalf("%+v: %v", tc.q, err)
+		}
+		if got := fmt.Sprint(statusIDs(page)); got != tc.want {
+			t.Errorf("%+v = %s, want %s", tc.q, got, tc.want)
+		}
+	}
+
+	for _, q := range []ListQuery{
+		{Sort: "size"},
+		{Offset: -1},
+		{Cursor: "x", Offset: 1},
+		{Cursor: "x", Sort: SortSpeed},
+	} {
+		if _, _, err := QueryStatuses(statuses, q); !errors.Is(err, ErrInvalidListQuery) {
+			t.Errorf("%+v: err = %v, want ErrInvalidListQuery", q, err)
+		}
+	}
+}
+
+func TestLocalPager_FiltersInStorage(t *testing.T) {
+	state.CloseDB()
+	state.Configure(filepath.Join(t.TempDir(), "surge.db"))
+	defer state.CloseDB()
+
+	for _, e := range []types.DownloadEntry{
+		{ID: "a", URL: "https://example.com/ubuntu.iso", DestPath: "/tmp/a", Status: "completed", CreatedAt: 10},
+		{ID: "b", URL: "https://example.com/b", Filename: "notes.txt", DestPath: "/tmp/b", Status: "paused", CreatedAt: 20},
+		{ID: "c", URL: "https://example.com/fedora.iso", DestPath: "/tmp/c", Status: "error", CreatedAt: 30},
+		{ID: "d", URL: "https://example.com/debian.iso", DestPath: "/tmp/d", Status: "paused", CreatedAt: 40},
+	} {
+		if err := state.AddToMasterList(e); err != nil {
+			t.Fatalf("AddToMasterList: %v", err)
+		}
+	}
+	svc := &LocalDownloadService{}
+	all, err := svc.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+
+	for _, q := range []ListQuery{
+		{Statuses: []string{"paused", "error"}},
+		{Search: "ISO"},
+		{Search: "iso", Statuses: []string{"paused"}},
+		{Offset: 1, Limit: 2},
+		{Sort: SortETA, Offset: 2},
+	} {
+		got, _, err := svc.ListPage(q)
+		if err != nil {
+			t.Fatalf("ListPage(%+v): %v", q, err)
+		}
+		want, _, _ := QueryStatuses(all, q)
+		if fmt.Sprint(statusIDs(got)) != fmt.Sprint(statusIDs(want)) {
+			t.Errorf("ListPage(%+v) = %v, want %v", q, statusIDs(got), statusIDs(want))
+		}
+	}
+
+	// Offset pages carry a cursor that continues them
+	page, next, err := svc.ListPage(ListQuery{Offset: 1, Limit: 1})
+	if err != nil || next == "" {
+		t.Fatalf("ListPage: page %v, next %q, err %v", statusIDs(page), next, err)
+	}
+	rest, _, err := svc.ListPage(ListQuery{Cursor: next})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(statusIDs(rest)); got != "[c d]" {
+		t.Fatalf("after cursor = %s, want [c d]", got)
+	}
+}
@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -12,8 +13,10 @@ import (
 	"github.com/surge-downloader/surge/internal/config"
 	"github.com/surge-downloader/surge/internal/download"
 	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/ratelimit"
 	"github.com/surge-downloader/surge/internal/engine/state"
 	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/processing"
 	"github.com/surge-downloader/surge/internal/utils"
 )
 
@@ -39,9 +42,16 @@ func (s *LocalDownloadService) ReloadSettings() error {
 	s.settingsMu.Lock()
 	s.settings = settings
 	s.settingsMu.Unlock()
+	applyRateLimit(settings)
 	return nil
 }
 
+// applyRateLimit pushes the speed limit to the shared limiter so running
+// downloads pick it up immediately instead of on their next start.
+func applyRateLimit(settings *config.Settings) {
+	ratelimit.Global.Configure(settings.Network.GlobalRateLimit, settings.RateLimitExemptHosts())
+}
+
 // LocalDownloadService implements DownloadService for the local embedded engine.
 type LocalDownloadService struct {
 	Pool    *download.WorkerPool
@@ -62,13 +72,29 @@ type LocalDownloadService struct {
 	shutdownOnce sync.Once
 	shutdownErr  error
 
+	// inputMu guards InputCh against Publish racing the close in Shutdown,
+	// since lifecycle hooks may still publish while final events drain.
+	inputMu     sync.RWMutex
+	inputClosed bool
+
 	// Settings Cache
 	settings   *config.Settings
 	settingsMu sync.RWMutex
 
-	pauseFunc       func(id string) error
-	resumeFunc      func(id string) error
-	resumeBatchFunc func(ids []string) []error
+	pauseFunc          func(id string) error
+	resumeFunc         func(id string) error
+	resumeBatchFunc    func(ids []string) []error
+	restorePartialFunc func(id string) error
+	restoreTrashedFunc func(id string) error
+	retryFunc          func(id string, probe bool) error
+
+	// Latest post-download phase per download, until it completes
+	phases  map[string]events.DownloadPhaseMsg
+	phaseMu sync.RWMutex
+
+	// Downloads whose files Verify is re-hashing
+	verifying map[string]bool
+	verifyMu  sync.Mutex
 }
 
 const (
@@ -91,12 +117,14 @@ func NewLocalDownloadServiceWithInput(pool *download.WorkerPool, inputCh chan in
 		Pool:      pool,
 		InputCh:   inputCh,
 		listeners: make([]chan interface{}, 0),
+		phases:    make(map[string]events.DownloadPhaseMsg),
 	}
 
 	// Load initial settings
 	if s.settings, _ = config.LoadSettings(); s.settings == nil {
 		s.settings = config.DefaultSettings()
 	}
+	applyRateLimit(s.settings)
 
 	// Lifecycle
 	ctx, cancel := context.WithCancel(context.Background())
@@ -125,15 +153,20 @@ func NewLocalDownloadServiceWithInput(pool *download.WorkerPool, inputCh chan in
 
 func (s *LocalDownloadService) broadcastLoop() {
 	for msg := range s.InputCh {
+		s.trackPhase(msg)
+
 		s.listenerMu.Lock()
 		for _, ch := range s.listeners {
 			// Check message type
 			isProgress := false
-			switch msg.(type) {
+			switch m := msg.(type) {
 			case events.ProgressMsg:
 				isProgress = true
 			case events.BatchProgressMsg:
 				isProgress = true
+			case events.DownloadPhaseMsg:
+				// Intermediate phase ticks are droppable like progress; the end is not
+				isProgress = m.Phase != types.PhaseComplete
 			}
 
 			if isProgress {
@@ -168,6 +201,53 @@ func (s *LocalDownloadService) broadcastLoop() {
 	}
 }
 
+// trackPhase remembers running post-download phases so List and GetStatus
+// can report them
+func (s *LocalDownloadService) trackPhase(msg interface{}) {
+	var id string
+	switch m := msg.(type) {
+	case events.DownloadPhaseMsg:
+		if types.IsFinalizingPhase(m.Phase) {
+			s.phaseMu.Lock()
+			s.phases[m.DownloadID] = m
+			s.phaseMu.Unlock()
+			return
+		}
+		id = m.DownloadID
+	case events.DownloadErrorMsg:
+		id = m.DownloadID
+	case events.DownloadRemovedMsg:
+		id = m.DownloadID
+	default:
+		return
+	}
+	s.phaseMu.Lock()
+	delete(s.phases, id)
+	s.phaseMu.Unlock()
+}
+
+// applyPhase fills in status.Phase. A download whose bytes are all in but
+// whose finalize phases are still running is reported as downloading.
+func (s *LocalDownloadService) applyPhase(status *types.DownloadStatus) {
+	s.phaseMu.RLock()
+	p, ok := s.phases[status.ID]
+	s.phaseMu.RUnlock()
+
+	if ok && status.Status != "error" {
+		status.Status = "downloading"
+		status.Phase = p.Phase
+		status.PhaseProgress = p.Progress
+		return
+	}
+
+	switch status.Status {
+	case "downloading":
+		status.Phase = types.PhaseDownloading
+	case "completed":
+		status.Phase = types.PhaseComplete
+	}
+}
+
 func (s *LocalDownloadService) reportProgressLoop() {
 	lastSpeeds := make(map[string]float64)
 	lastChunkSnapshot := make(map[string]time.Time)
@@ -313,6 +393,11 @@ func (s *LocalDownloadService) Publish(msg interface{}) error {
 	if s.InputCh == nil {
 		return fmt.Errorf("input channel not initialized")
 	}
+	s.inputMu.RLock()
+	defer s.inputMu.RUnlock()
+	if s.inputClosed {
+		return fmt.Errorf("service is shut down")
+	}
 	select {
 	case s.InputCh <- msg:
 		return nil
@@ -337,18 +422,55 @@ func (s *LocalDownloadService) Shutdown() error {
 
 		// Close input channel to stop broadcaster
 		if s.InputCh != nil {
+			s.inputMu.Lock()
+			s.inputClosed = true
 			close(s.InputCh)
+			s.inputMu.Unlock()
 		}
 		s.broadcastWG.Wait()
 	})
 	return s.shutdownErr
 }
 
-// List returns the status of all active and completed downloads.
+// List returns the status of all active and completed downloads, oldest
+// first (see SortStatuses).
 func (s *LocalDownloadService) List() ([]types.DownloadStatus, error) {
-	var statuses []types.DownloadStatus
-
 	// 1. Get active downloads from pool
+	statuses := s.activeStatuses()
+
+	// 2. Fetch from database for history/paused/completed
+	dbDownloads, err := state.ListAllDownloads()
+	if err == nil {
+		// Create a map of existing IDs to avoid duplicates
+		existingIDs := make(map[string]int)
+		for i, s := range statuses {
+			existingIDs[s.ID] = i
+		}
+
+		for _, d := range dbDownloads {
+			// Archived and trashed downloads were removed from the list
+			if d.Status == "archived" || d.Status == "trashed" {
+				continue
+			}
+			// Skip if already present (active), but keep its place in the order
+			if i, ok := existingIDs[d.ID]; ok {
+				mergeEntryInto(&statuses[i], d)
+				continue
+			}
+			statuses = append(statuses, entryStatus(d))
+		}
+	}
+
+	for i := range statuses {
+		s.applyPhase(&statuses[i])
+	}
+	SortStatuses(statuses)
+	return statuses, nil
+}
+
+// activeStatuses reports the downloads held by the pool from their live state
+func (s *LocalDownloadService) activeStatuses() []types.DownloadStatus {
+	var statuses []types.DownloadStatus
 	if s.Pool != nil {
 		activeConfigs := s.Pool.GetAll()
 		for _, cfg := range activeConfigs {
@@ -357,6 +479,9 @@ func (s *LocalDownloadService) List() ([]types.DownloadStatus, error) {
 				URL:      cfg.URL,
 				Filename: cfg.Filename,
 				Status:   "downloading",
+				Tags:     cfg.Tags,
+				Category: cfg.Category,
+				TraceID:  cfg.TraceID,
 			}
 
 			if cfg.State != nil {
@@ -405,60 +530,66 @@ func (s *LocalDownloadService) List() ([]types.DownloadStatus, error) {
 		}
 	}
 
-	// 2. Fetch from database for history/paused/completed
-	dbDownloads, err := state.ListAllDownloads()
-	if err == nil {
-		// Create a map of existing IDs to avoid duplicates
-		existingIDs := make(map[string]bool)
-		for _, s := range statuses {
-			existingIDs[s.ID] = true
-		}
-
-		for _, d := range dbDownloads {
-			// Skip if already present (active)
-			if existingIDs[d.ID] {
-				continue
-			}
+	return statuses
+}
 
-			var progress float64
-			if d.TotalSize > 0 {
-				progress = float64(d.Downloaded) * 100 / float64(d.TotalSize)
-			} else if d.Status == "completed" {
-				progress = 100.0
-			}
+// mergeEntryInto copies the persisted fields the pool doesn't track onto an
+// active download's status
+func mergeEntryInto(status *types.DownloadStatus, d types.DownloadEntry) {
+	status.AddedAt = d.CreatedAt
+	status.Note = d.Note
+	status.Metadata = d.Metadata
+	status.Response = d.Response
+	status.QueuePosition = d.QueuePosition
+}
 
-			statuses = append(statuses, types.DownloadStatus{
-				ID:          d.ID,
-				URL:         d.URL,
-				Filename:    d.Filename,
-				DestPath:    d.DestPath,
-				Status:      d.Status,
-				TotalSize:   d.TotalSize,
-				Downloaded:  d.Downloaded,
-				Progress:    progress,
-				Speed:       completedSpeedMBps(d),
-				Connections: 0,
-				TimeTaken:   d.TimeTaken,
-				AvgSpeed:    d.AvgSpeed,
-			})
-		}
+// entryStatus reports a download that isn't in the pool from its database row
+func entryStatus(d types.DownloadEntry) types.DownloadStatus {
+	var progress float64
+	if d.TotalSize > 0 {
+		progress = float64(d.Downloaded) * 100 / float64(d.TotalSize)
+	} else if d.Status == "completed" {
+		progress = 100.0
 	}
 
-	return statuses, nil
+	return types.DownloadStatus{
+		ID:          d.ID,
+		URL:         d.URL,
+		Filename:    d.Filename,
+		DestPath:    d.DestPath,
+		Status:      d.Status,
+		PauseReason: d.PauseReason,
+		TotalSize:   d.TotalSize,
+		Downloaded:  d.Downloaded,
+		Progress:    progress,
+		Speed:       completedSpeedMBps(d),
+		Connections: 0,
+		TimeTaken:   d.TimeTaken,
+		AvgSpeed:    d.AvgSpeed,
+		AddedAt:     d.CreatedAt,
+		Tags:        d.Tags,
+		Category:    d.Category,
+		TraceID:     d.TraceID,
+		Note:        d.Note,
+		Metadata:    d.Metadata,
+		Response:    d.Response,
+
+		QueuePosition: d.QueuePosition,
+	}
 }
 
 // Add queues a new download on the local pool without TUI confirmation.
-func (s *LocalDownloadService) Add(url string, path string, filename string, mirrors []string, headers map[string]string, isExplicitCategory bool, totalSize int64, supportsRange bool) (string, error) {
-	return s.add(url, path, filename, mirrors, headers, "", isExplicitCategory, totalSize, supportsRange)
+func (s *LocalDownloadService) Add(req *processing.DownloadRequest) (string, error) {
+	return s.add(req, "", req.IsExplicitCategory)
 }
 
 // AddWithID queues a new download using a caller-provided id when non-empty.
-func (s *LocalDownloadService) AddWithID(url string, path string, filename string, mirrors []string, headers map[string]string, id string, totalSize int64, supportsRange bool) (string, error) {
+func (s *LocalDownloadService) AddWithID(req *processing.DownloadRequest, id string) (string, error) {
 	// Remote or RPC-driven calls use preset IDs and should bypass interactive category routing.
-	return s.add(url, path, filename, mirrors, headers, id, false, totalSize, supportsRange)
+	return s.add(req, id, false)
 }
 
-func (s *LocalDownloadService) add(url string, path string, filename string, mirrors []string, headers map[string]string, requestedID string, isExplicitCategory bool, totalSize int64, supportsRange bool) (string, error) {
+func (s *LocalDownloadService) add(req *processing.DownloadRequest, requestedID string, isExplicitCategory bool) (string, error) {
 	if s.Pool == nil {
 		return "", fmt.Errorf("worker pool not initialized")
 	}
@@ -467,7 +598,7 @@ func (s *LocalDownloadService) add(url string, path string, filename string, mir
 	settings := s.settings
 	s.settingsMu.RUnlock()
 
-	outPath := path
+	outPath := req.Path
 	if outPath == "" {
 		if settings.General.DefaultDownloadDir != "" {
 			outPath = settings.General.DefaultDownloadDir
@@ -486,33 +617,44 @@ func (s *LocalDownloadService) add(url string, path string, filename string, mir
 	}
 	if entry, err := state.GetDownload(id); err != nil {
 		return "", fmt.Errorf("failed to query download state: %w", err)
-	} else if entry != nil {
+	} else if entry != nil && !isPendingDispatch(entry, req.URL, filepath.Join(outPath, req.Filename)) {
 		return "", fmt.Errorf("download id already exists")
 	}
 
 	state := types.NewProgressState(id, 0)
-	state.DestPath = filepath.Join(outPath, filename) // Best guess until download starts
+	state.DestPath = filepath.Join(outPath, req.Filename) // Best guess until download starts
 
 	cfg := types.DownloadConfig{
-		URL:                url,
-		Mirrors:            mirrors,
+		URL:                req.URL,
+		Mirrors:            req.Mirrors,
 		OutputPath:         outPath,
 		ID:                 id,
-		Filename:           filename, // If empty, will be auto-detected
+		Filename:           req.Filename, // If empty, will be auto-detected
 		ProgressCh:         s.InputCh,
 		State:              state,
-		Runtime:            types.ConvertRuntimeConfig(settings.ToRuntimeConfig()),
-		Headers:            headers,
+		Runtime:            types.ConvertRuntimeConfig(settings.ToRuntimeConfigForCategory(req.Category)),
+		Headers:            req.Headers,
+		Tags:               utils.NormalizeTags(req.Tags),
+		Category:           req.Category,
 		IsExplicitCategory: isExplicitCategory,
-		TotalSize:          totalSize,
-		SupportsRange:      supportsRange,
+		TotalSize:          req.TotalSize,
+		SupportsRange:      req.SupportsRange,
+		Overrides:          req.Overrides(),
 	}
+	cfg.Overrides.Apply(&cfg)
 
 	s.Pool.Add(cfg)
 
 	return id, nil
 }
 
+// isPendingDispatch reports whether entry is a queued row for this exact
+// download that is waiting to be handed to the pool, as batch adds write
+// their rows before dispatching
+func isPendingDispatch(entry *types.DownloadEntry, url, destPath string) bool {
+	return entry.Status == "queued" && entry.URL == url && entry.DestPath == destPath
+}
+
 // Pause pauses an active download.
 func (s *LocalDownloadService) Pause(id string) error {
 	if s.pauseFunc != nil {
@@ -549,6 +691,32 @@ func (s *LocalDownloadService) SetLifecycleHooks(pause func(string) error, resum
 	s.resumeBatchFunc = resumeBatch
 }
 
+// SetRestorePartialHook routes RestorePartial through the event-worker
+// lifecycle, like SetLifecycleHooks does for pause and resume.
+func (s *LocalDownloadService) SetRestorePartialHook(restore func(string) error) {
+	s.restorePartialFunc = restore
+}
+
+// SetRestoreTrashedHook routes RestoreTrashed through the event-worker
+// lifecycle, so clients hear about the download coming back.
+func (s *LocalDownloadService) SetRestoreTrashedHook(restore func(string) error) {
+	s.restoreTrashedFunc = restore
+}
+
+// SetRetryHook routes Retry through the event-worker lifecycle, which
+// queues the download again.
+func (s *LocalDownloadService) SetRetryHook(retry func(id string, probe bool) error) {
+	s.retryFunc = retry
+}
+
+// Retry starts a failed download over from the beginning.
+func (s *LocalDownloadService) Retry(id string, probe bool) error {
+	if s.retryFunc != nil {
+		return s.retryFunc(id, probe)
+	}
+	return fmt.Errorf("RetryFunc not initialized")
+}
+
 // UpdateURL updates the URL of a paused or errored download
 func (s *LocalDownloadService) UpdateURL(id string, newURL string) error {
 	if s.Pool == nil {
@@ -558,12 +726,69 @@ func (s *LocalDownloadService) UpdateURL(id string, newURL string) error {
 	return s.Pool.UpdateURL(id, newURL)
 }
 
-// Delete cancels and removes a download.
+// UpdateNote changes the note and metadata of a download and broadcasts the
+// result so every client's view follows.
+func (s *LocalDownloadService) UpdateNote(id string, update types.NoteUpdate) error {
+	if err := update.Validate(); err != nil {
+		return err
+	}
+	note, metadata, err := state.UpdateNote(id, update)
+	if err != nil {
+		return err
+	}
+	if err := s.Publish(events.DownloadNoteMsg{DownloadID: id, Note: note, Metadata: metadata}); err != nil {
+		utils.Debug("Failed to publish note update for %s: %v", id, err)
+	}
+	return nil
+}
+
+// Delete moves a download to the trash when settings keep one, pausing it
+// if it runs. Otherwise, and for downloads already in the trash, it deletes
+// them at once like DeletePermanently.
 func (s *LocalDownloadService) Delete(id string) error {
 	if s.Pool == nil {
 		return fmt.Errorf("worker pool not initialized")
 	}
 
+	s.settingsMu.RLock()
+	settings := s.settings
+	s.settingsMu.RUnlock()
+
+	// Downloads not written to the database yet have nothing to restore from;
+	// deleting one already in the trash empties it from there
+	entry, _ := state.GetDownload(id)
+	if settings == nil || settings.General.TrashRetentionDays <= 0 || entry == nil || entry.Status == "trashed" {
+		return s.DeletePermanently(id)
+	}
+
+	if st := s.Pool.GetStatus(id); st != nil {
+		// The pool announces the removal once the download is paused
+		s.Pool.Trash(id)
+	} else if s.InputCh != nil {
+		s.InputCh <- events.DownloadRemovedMsg{
+			DownloadID: id,
+			Filename:   entry.Filename,
+			DestPath:   entry.DestPath,
+			Completed:  entry.Status == "completed",
+			Trashed:    true,
+		}
+	}
+
+	if purged, err := processing.PurgeTrash(settings); err != nil {
+		utils.Debug("Trash: failed to purge expired downloads: %v", err)
+	} else if purged > 0 {
+		utils.Debug("Trash: purged %d expired downloads", purged)
+	}
+	return nil
+}
+
+// DeletePermanently removes a download and its working file at once,
+// bypassing the trash.
+func (s *LocalDownloadService) DeletePermanently(id string) error {
+	if s.Pool == nil {
+		return fmt.Errorf("worker pool not initialized")
+	}
+
 	removedFilename := ""
 	removedDestPath := ""
 	removedCompleted := false
@@ -603,6 +828,228 @@ func (s *LocalDownloadService) Delete(id string) error {
 	return nil
 }
 
+// Archive removes a download from the list like Delete, but keeps its working
+// file and resume state so RestorePartial can bring it back. A running
+// download is paused first.
+func (s *LocalDownloadService) Archive(id string) error {
+	if s.Pool == nil {
+		return fmt.Errorf("worker pool not initialized")
+	}
+
+	st := s.Pool.GetStatus(id)
+	entry, _ := state.GetDownload(id)
+	if st == nil && entry == nil {
+		return types.ErrNotFound
+	}
+	if (st != nil && st.Status == "completed") || (entry != nil && entry.Status == "completed") {
+		return fmt.Errorf("download already completed")
+	}
+	if entry != nil && entry.Status == "archived" {
+		return nil
+	}
+
+	if st != nil {
+		// The pool announces the removal once the download is paused
+		s.Pool.Archive(id)
+		return nil
+	}
+
+	// Not in the pool, so nobody else will tell clients or the lifecycle
+	if s.InputCh != nil {
+		s.InputCh <- events.DownloadRemovedMsg{
+			DownloadID: id,
+			Filename:   entry.Filename,
+			DestPath:   entry.DestPath,
+			Archived:   true,
+		}
+	}
+	return nil
+}
+
+// RestorePartial brings back an archived download and resumes it.
+func (s *LocalDownloadService) RestorePartial(id string) error {
+	if s.restorePartialFunc != nil {
+		return s.restorePartialFunc(id)
+	}
+	return fmt.Errorf("RestorePartialFunc not initialized")
+}
+
+// Move puts a waiting download at position in the queue. Downloads still
+// waiting in the pool are dispatched in the new order right away.
+func (s *LocalDownloadService) Move(id string, position int) error {
+	order, err := state.MoveInQueue(id, position)
+	if err != nil {
+		return err
+	}
+	if s.Pool != nil {
+		s.Pool.Reorder(order)
+	}
+	return nil
+}
+
+// MoveBy moves a waiting download offset places in the queue, like Move
+func (s *LocalDownloadService) MoveBy(id string, offset int) error {
+	order, err := state.MoveInQueueBy(id, offset)
+	if err != nil {
+		return err
+	}
+	if s.Pool != nil {
+		s.Pool.Reorder(order)
+	}
+	return nil
+}
+
+// SetPriority gives a waiting download priority and moves it among the
+// downloads of that priority, like Move
+func (s *LocalDownloadService) SetPriority(id string, priority int, place string) error {
+	order, err := state.SetPriority(id, priority, place)
+	if err != nil {
+		return err
+	}
+	if s.Pool != nil {
+		s.Pool.SetPriority(id, priority)
+		s.Pool.Reorder(order)
+	}
+	return nil
+}
+
+// Relocate moves the file of a finished or paused download into dir. A
+// paused download the pool still holds resumes in the new place.
+func (s *LocalDownloadService) Relocate(id, dir string) (string, error) {
+	return s.relocate(id, func() (string, error) { return processing.RelocateDownload(id, dir) })
+}
+
+// Rename renames the file of a finished or paused download, like Relocate
+func (s *LocalDownloadService) Rename(id, name string) (string, error) {
+	return s.relocate(id, func() (string, error) { return processing.RenameDownload(id, name) })
+}
+
+// relocate runs move on a download the pool isn't running, then points the
+// pool and clients at the path it returns
+func (s *LocalDownloadService) relocate(id string, move func() (string, error)) (string, error) {
+	if s.Pool != nil {
+		if st := s.Pool.GetStatus(id); st != nil && st.Status != "paused" && st.Status != "completed" {
+			return "", processing.ErrNotRelocatable
+		}
+	}
+	destPath, err := move()
+	if err != nil {
+		return "", err
+	}
+	filename := filepath.Base(destPath)
+	if s.Pool != nil {
+		s.Pool.Relocate(id, destPath, filename)
+	}
+	if err := s.Publish(events.DownloadMovedMsg{DownloadID: id, Filename: filename, DestPath: destPath}); err != nil {
+		utils.Debug("Failed to publish move of %s: %v", id, err)
+	}
+	return destPath, nil
+}
+
+// ChunkMap returns the chunk map of a download: live while it is in the pool,
+// otherwise as saved when it was paused. Downloads with no saved chunks, such
+// as finished ones, have none.
+func (s *LocalDownloadService) ChunkMap(id string) (*types.ChunkMap, error) {
+	if s.Pool != nil {
+		if m := s.Pool.GetChunkMap(id); m != nil {
+			return m, nil
+		}
+	}
+
+	entry, err := state.GetDownload(id)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, types.ErrNotFound
+	}
+	m := &types.ChunkMap{ID: id, TotalSize: entry.TotalSize, Workers: []types.WorkerStatus{}}
+	states, err := state.LoadStates([]string{id})
+	if err != nil {
+		return nil, err
+	}
+	if saved := states[id]; saved != nil && saved.ActualChunkSize > 0 && saved.TotalSize > 0 {
+		m.TotalSize = saved.TotalSize
+		m.ChunkSize = saved.ActualChunkSize
+		width := int((saved.TotalSize + saved.ActualChunkSize - 1) / saved.ActualChunkSize)
+		m.Chunks = types.FormatChunks(saved.ChunkBitmap, width)
+	}
+	return m, nil
+}
+
+// Verify re-hashes a completed download's file in the background and
+// publishes the outcome. A request for a download already being verified
+// leaves that run to answer it.
+func (s *LocalDownloadService) Verify(id string) error {
+	entry, err := state.GetDownload(id)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return types.ErrNotFound
+	}
+	if entry.Status != "completed" {
+		return fmt.Errorf("%w: it is %s", types.ErrNotCompleted, entry.Status)
+	}
+
+	s.verifyMu.Lock()
+	defer s.verifyMu.Unlock()
+	if s.verifying[id] {
+		return nil
+	}
+	if s.verifying == nil {
+		s.verifying = make(map[string]bool)
+	}
+	s.verifying[id] = true
+
+	go func() {
+		result := processing.VerifyDownload(*entry)
+		s.verifyMu.Lock()
+		delete(s.verifying, id)
+		s.verifyMu.Unlock()
+		if err := s.Publish(events.DownloadVerifiedMsg{DownloadID: id, Result: result}); err != nil {
+			utils.Debug("Failed to publish verification of %s: %v", id, err)
+		}
+	}()
+	return nil
+}
+
+// SpeedLimit returns the speed limit a download keeps of its own
+func (s *LocalDownloadService) SpeedLimit(id string) (int64, error) {
+	entry, err := state.GetDownload(id)
+	if err != nil {
+		return 0, err
+	}
+	if entry == nil {
+		return 0, types.ErrNotFound
+	}
+	if entry.Overrides == nil {
+		return 0, nil
+	}
+	return entry.Overrides.SpeedLimit, nil
+}
+
+// SetSpeedLimit changes a download's own speed limit, at once when it runs,
+// and stores it for its resumes
+func (s *LocalDownloadService) SetSpeedLimit(id string, rate int64) error {
+	if rate < 0 {
+		return fmt.Errorf("speed limit cannot be negative")
+	}
+	inPool := s.Pool != nil && s.Pool.SetSpeedLimit(id, rate)
+	if err := state.SetSpeedLimit(id, rate); err != nil && !(inPool && errors.Is(err, types.ErrNotFound)) {
+		return err
+	}
+	return nil
+}
+
+// RestoreTrashed brings a download back from the trash.
+func (s *LocalDownloadService) RestoreTrashed(id string) error {
+	if s.restoreTrashedFunc != nil {
+		return s.restoreTrashedFunc(id)
+	}
+	return fmt.Errorf("RestoreTrashedFunc not initialized")
+}
+
 // GetStatus returns a status for a single download by id.
 func (s *LocalDownloadService) GetStatus(id string) (*types.DownloadStatus, error) {
 	if id == "" {
@@ -613,6 +1060,13 @@ func (s *LocalDownloadService) GetStatus(id string) (*types.DownloadStatus, erro
 	if s.Pool != nil {
 		status := s.Pool.GetStatus(id)
 		if status != nil {
+			if entry, err := state.GetDownload(id); err == nil && entry != nil {
+				status.Note = entry.Note
+				status.Metadata = entry.Metadata
+				status.Response = entry.Response
+			}
+			status.Errors = loadFailures(id)
+			s.applyPhase(status)
 			return status, nil
 		}
 	}
@@ -638,13 +1092,30 @@ func (s *LocalDownloadService) GetStatus(id string) (*types.DownloadStatus, erro
 			Status:     entry.Status,
 			TimeTaken:  entry.TimeTaken,
 			AvgSpeed:   entry.AvgSpeed,
+			Tags:       entry.Tags,
+			Category:   entry.Category,
+			TraceID:    entry.TraceID,
+			Note:       entry.Note,
+			Metadata:   entry.Metadata,
+			Response:   entry.Response,
+			Errors:     loadFailures(id),
 		}
+		s.applyPhase(&status)
 		return &status, nil
 	}
 
 	return nil, fmt.Errorf("download not found")
 }
 
+// loadFailures returns the error history of a download for its status
+func loadFailures(id string) []types.DownloadFailure {
+	failures, err := state.LoadDownloadFailures(id)
+	if err != nil {
+		utils.Debug("Failed to load error history for %s: %v", id, err)
+	}
+	return failures
+}
+
 // History returns completed downloads
 func (s *LocalDownloadService) History() ([]types.DownloadEntry, error) {
 	// For local service, we can directly access the state DB
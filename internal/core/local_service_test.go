@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -15,6 +16,7 @@ import (
 	"github.com/surge-downloader/surge/internal/engine/events"
 	"github.com/surge-downloader/surge/internal/engine/state"
 	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/processing"
 	"github.com/surge-downloader/surge/internal/testutil"
 )
 
@@ -59,6 +61,8 @@ func TestLocalDownloadService_Delete_DBOnlyBroadcastsRemoved(t *testing.T) {
 		t.Fatalf("failed to seed state: %v", err)
 	}
 
+	// Without a trash, delete removes the download at once
+	svc.settings.General.TrashRetentionDays = 0
 	if err := svc.Delete(id); err != nil {
 		t.Fatalf("delete failed: %v", err)
 	}
@@ -95,6 +99,166 @@ func TestLocalDownloadService_Delete_DBOnlyBroadcastsRemoved(t *testing.T) {
 	}
 }
 
+func TestLocalDownloadService_Archive_KeepsPartialData(t *testing.T) {
+	tempDir := t.TempDir()
+	state.CloseDB()
+	state.Configure(filepath.Join(tempDir, fmt.Sprintf("%s-surge.db", t.Name())))
+	defer state.CloseDB()
+
+	ch := make(chan interface{}, 20)
+	pool := download.NewWorkerPool(ch, 1)
+	svc := NewLocalDownloadServiceWithInput(pool, ch)
+	defer func() { _ = svc.Shutdown() }()
+	evCleanup := startEventWorkerForTest(t, svc)
+	defer evCleanup()
+
+	id := "archive-db-only-id"
+	url := "https://example.com/file.bin"
+	destPath := filepath.Join(tempDir, "file.bin")
+	incompletePath := destPath + types.IncompleteSuffix
+
+	if err := os.WriteFile(incompletePath, []byte("partial"), 0o644); err != nil {
+		t.Fatalf("failed to create partial file: %v", err)
+	}
+	if err := state.SaveState(url, destPath, &types.DownloadState{
+		ID:         id,
+		URL:        url,
+		DestPath:   destPath,
+		Filename:   "file.bin",
+		TotalSize:  1000,
+		Downloaded: 200,
+		Tasks:      []types.Task{{Offset: 200, Length: 800}},
+	}); err != nil {
+		t.Fatalf("failed to seed state: %v", err)
+	}
+
+	if err := svc.Archive(id); err != nil {
+		t.Fatalf("archive failed: %v", err)
+	}
+
+	deadline := time.Now().Add(500 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		if entry, _ := state.GetDownload(id); entry != nil && entry.Status == "archived" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	entry, err := state.GetDownload(id)
+	if err != nil || entry == nil || entry.Status != "archived" {
+		t.Fatalf("expected archived entry, got %+v, %v", entry, err)
+	}
+	if _, err := os.Stat(incompletePath); err != nil {
+		t.Fatalf("partial file should be kept: %v", err)
+	}
+
+	statuses, err := svc.List()
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	for _, st := range statuses {
+		if st.ID == id {
+			t.Fatalf("archived download should not be listed, got %+v", st)
+		}
+	}
+
+	if err := svc.Archive("missing-id"); err != types.ErrNotFound {
+		t.Errorf("archive of unknown download = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLocalDownloadService_Delete_MovesToTrashUntilRestored(t *testing.T) {
+	tempDir := t.TempDir()
+	state.CloseDB()
+	state.Configure(filepath.Join(tempDir, fmt.Sprintf("%s-surge.db", t.Name())))
+	defer state.CloseDB()
+
+	ch := make(chan interface{}, 20)
+	pool := download.NewWorkerPool(ch, 1)
+	svc := NewLocalDownloadServiceWithInput(pool, ch)
+	defer func() { _ = svc.Shutdown() }()
+	svc.settings.General.TrashRetentionDays = 7
+	evCleanup := startEventWorkerForTest(t, svc)
+	defer evCleanup()
+	streamCh, cleanup, err := svc.StreamEvents(context.Background())
+	if err != nil {
+		t.Fatalf("failed to stream events: %v", err)
+	}
+	defer cleanup()
+
+	id := "trash-db-only-id"
+	url := "https://example.com/file.bin"
+	destPath := filepath.Join(tempDir, "file.bin")
+	incompletePath := destPath + types.IncompleteSuffix
+
+	if err := os.WriteFile(incompletePath, []byte("partial"), 0o644); err != nil {
+		t.Fatalf("failed to create partial file: %v", err)
+	}
+	if err := state.SaveState(url, destPath, &types.DownloadState{
+		ID:         id,
+		URL:        url,
+		DestPath:   destPath,
+		Filename:   "file.bin",
+		TotalSize:  1000,
+		Downloaded: 200,
+		Tasks:      []types.Task{{Offset: 200, Length: 800}},
+	}); err != nil {
+		t.Fatalf("failed to seed state: %v", err)
+	}
+
+	if err := svc.Delete(id); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+
+	deadline := time.Now().Add(500 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		if entry, _ := state.GetDownload(id); entry != nil && entry.Status == "trashed" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	entry, err := state.GetDownload(id)
+	if err != nil || entry == nil || entry.Status != "trashed" || entry.TrashedAt == 0 {
+		t.Fatalf("expected trashed entry, got %+v, %v", entry, err)
+	}
+	if _, err := os.Stat(incompletePath); err != nil {
+		t.Fatalf("partial file should be kept in the trash: %v", err)
+	}
+	statuses, err := svc.List()
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	for _, st := range statuses {
+		if st.ID == id {
+			t.Fatalf("trashed download should not be listed, got %+v", st)
+		}
+	}
+
+	if err := svc.RestoreTrashed(id); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+	entry, _ = state.GetDownload(id)
+	if entry == nil || entry.Status != "paused" || entry.TrashedAt != 0 {
+		t.Fatalf("expected paused entry after restore, got %+v", entry)
+	}
+
+	gotRestored := false
+	timeout := time.After(500 * time.Millisecond)
+	for !gotRestored {
+		select {
+		case msg := <-streamCh:
+			if m, ok := msg.(events.DownloadRestoredMsg); ok && m.DownloadID == id {
+				gotRestored = m.Status == "paused"
+			}
+		case <-timeout:
+			t.Fatal("expected a restored event")
+		}
+	}
+
+	if err := svc.RestoreTrashed(id); !errors.Is(err, state.ErrNotTrashed) {
+		t.Errorf("restoring a download not in the trash = %v, want ErrNotTrashed", err)
+	}
+}
+
 func TestLocalDownloadService_Delete_ActiveWithoutDB_RemovesPartialFile(t *testing.T) {
 	tempDir := t.TempDir()
 	state.CloseDB()
@@ -120,7 +284,7 @@ func TestLocalDownloadService_Delete_ActiveWithoutDB_RemovesPartialFile(t *testi
 	if f, err := os.Create(filepath.Join(outputDir, filename) + ".surge"); err == nil {
 		_ = f.Close()
 	}
-	id, err := svc.Add(server.URL(), outputDir, filename, nil, nil, false, 0, false)
+	id, err := svc.Add(&processing.DownloadRequest{URL: server.URL(), Path: outputDir, Filename: filename})
 	if err != nil {
 		t.Fatalf("failed to add download: %v", err)
 	}
@@ -277,7 +441,7 @@ func TestLocalDownloadService_AddWithID_UsesProvidedID(t *testing.T) {
 
 	requestID := "provided-id-001"
 	outputDir := t.TempDir()
-	gotID, err := svc.AddWithID("https://example.com/file.bin", outputDir, "file.bin", nil, nil, requestID, 0, false)
+	gotID, err := svc.AddWithID(&processing.DownloadRequest{URL: "https://example.com/file.bin", Path: outputDir, Filename: "file.bin"}, requestID)
 	if err != nil {
 		t.Fatalf("AddWithID failed: %v", err)
 	}
@@ -290,6 +454,26 @@ func TestLocalDownloadService_AddWithID_UsesProvidedID(t *testing.T) {
 	}
 }
 
+func TestLocalDownloadService_AddWithID_NormalizesTags(t *testing.T) {
+	ch := make(chan interface{}, 8)
+	pool := download.NewWorkerPool(ch, 1)
+	svc := NewLocalDownloadServiceWithInput(pool, ch)
+	defer func() { _ = svc.Shutdown() }()
+
+	id, err := svc.AddWithID(&processing.DownloadRequest{URL: "https://example.com/file.bin", Path: t.TempDir(), Filename: "file.bin", Tags: []string{" Work", "iso,work"}}, "tagged-id")
+	if err != nil {
+		t.Fatalf("AddWithID failed: %v", err)
+	}
+
+	st := pool.GetStatus(id)
+	if st == nil {
+		t.Fatalf("expected pool status for %q", id)
+	}
+	if strings.Join(st.Tags, ",") != "work,iso" {
+		t.Fatalf("tags = %v, want [work iso]", st.Tags)
+	}
+}
+
 func TestLocalDownloadService_Shutdown_PersistsPausedState(t *testing.T) {
 	tempDir := t.TempDir()
 	state.CloseDB()
@@ -314,7 +498,7 @@ func TestLocalDownloadService_Shutdown_PersistsPausedState(t *testing.T) {
 	if f, err := os.Create(filepath.Join(outputDir, filename) + ".surge"); err == nil {
 		_ = f.Close()
 	}
-	id, err := svc.Add(server.URL(), outputDir, filename, nil, nil, false, fileSize, true)
+	id, err := svc.Add(&processing.DownloadRequest{URL: server.URL(), Path: outputDir, Filename: filename, TotalSize: fileSize, SupportsRange: true})
 	if err != nil {
 		t.Fatalf("failed to add download: %v", err)
 	}
@@ -416,14 +600,14 @@ func TestLocalDownloadService_Shutdown_PersistsQueuedState(t *testing.T) {
 	if f, err := os.Create(filepath.Join(outputDir, "first.bin") + ".surge"); err == nil {
 		_ = f.Close()
 	}
-	firstID, err := svc.Add(server.URL()+"?id=1", outputDir, "first.bin", nil, nil, false, 0, false)
+	firstID, err := svc.Add(&processing.DownloadRequest{URL: server.URL() + "?id=1", Path: outputDir, Filename: "first.bin"})
 	if err != nil {
 		t.Fatalf("failed to add first download: %v", err)
 	}
 	if f, err := os.Create(filepath.Join(outputDir, "second.bin") + ".surge"); err == nil {
 		_ = f.Close()
 	}
-	secondID, err := svc.Add(server.URL()+"?id=2", outputDir, "second.bin", nil, nil, false, 0, false)
+	secondID, err := svc.Add(&processing.DownloadRequest{URL: server.URL() + "?id=2", Path: outputDir, Filename: "second.bin"})
 	if err != nil {
 		t.Fatalf("failed to add second download: %v", err)
 	}
@@ -518,7 +702,7 @@ func TestLocalDownloadService_BatchProgress(t *testing.T) {
 	if f, err := os.Create(filepath.Join(tempDir, "test-file") + ".surge"); err == nil {
 		_ = f.Close()
 	}
-	_, err = svc.Add(ts.URL, tempDir, "test-file", nil, nil, false, 0, false)
+	_, err = svc.Add(&processing.DownloadRequest{URL: ts.URL, Path: tempDir, Filename: "test-file"})
 	if err != nil {
 		t.Fatalf("failed to add download: %v", err)
 	}
@@ -564,7 +748,7 @@ func TestLocalDownloadService_ResumeRejectedWhilePausing(t *testing.T) {
 	if f, err := os.Create(filepath.Join(outputDir, "resume-race.bin") + ".surge"); err == nil {
 		_ = f.Close()
 	}
-	id, err := svc.Add(server.URL(), outputDir, "resume-race.bin", nil, nil, false, 0, false)
+	id, err := svc.Add(&processing.DownloadRequest{URL: server.URL(), Path: outputDir, Filename: "resume-race.bin"})
 	if err != nil {
 		t.Fatalf("failed to add download: %v", err)
 	}
@@ -593,3 +777,135 @@ func TestLocalDownloadService_ResumeRejectedWhilePausing(t *testing.T) {
 		t.Fatal("expected resume to fail while download is still pausing")
 	}
 }
+
+func TestLocalDownloadService_ReportsFinalizePhase(t *testing.T) {
+	tempDir := t.TempDir()
+	state.CloseDB()
+	state.Configure(filepath.Join(tempDir, fmt.Sprintf("%s-surge.db", t.Name())))
+	defer state.CloseDB()
+
+	ch := make(chan interface{}, 20)
+	svc := NewLocalDownloadServiceWithInput(download.NewWorkerPool(ch, 1), ch)
+	defer func() { _ = svc.Shutdown() }()
+
+	id := "phase-id"
+	if err := state.AddToMasterList(types.DownloadEntry{
+		ID:         id,
+		URL:        "https://example.com/big.iso",
+		DestPath:   filepath.Join(tempDir, "big.iso"),
+		Filename:   "big.iso",
+		Status:     "downloading",
+		TotalSize:  100,
+		Downloaded: 100,
+	}); err != nil {
+		t.Fatalf("failed to seed entry: %v", err)
+	}
+
+	waitForPhase := func(want string) *types.DownloadStatus {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for {
+			st, err := svc.GetStatus(id)
+			if err != nil {
+				t.Fatalf("GetStatus failed: %v", err)
+			}
+			if st.Phase == want || time.Now().After(deadline) {
+				return st
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	if st := waitForPhase(types.PhaseDownloading); st.Phase != types.PhaseDownloading {
+		t.Fatalf("expected downloading phase before finalize, got %q", st.Phase)
+	}
+
+	if err := svc.Publish(events.DownloadPhaseMsg{DownloadID: id, Phase: types.PhaseMoving, Progress: 40}); err != nil {
+		t.Fatal(err)
+	}
+	st := waitForPhase(types.PhaseMoving)
+	if st.Phase != types.PhaseMoving || st.PhaseProgress != 40 || st.Status != "downloading" {
+		t.Fatalf("expected moving at 40%% while finalizing, got %+v", st)
+	}
+
+	statuses, err := svc.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 1 || statuses[0].Phase != types.PhaseMoving {
+		t.Fatalf("expected List to report the moving phase, got %+v", statuses)
+	}
+
+	if err := state.UpdateStatus(id, "completed"); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Publish(events.DownloadPhaseMsg{DownloadID: id, Phase: types.PhaseComplete, Progress: 100}); err != nil {
+		t.Fatal(err)
+	}
+	st = waitForPhase(types.PhaseComplete)
+	if st.Phase != types.PhaseComplete || st.Status != "completed" || st.PhaseProgress != 0 {
+		t.Fatalf("expected complete phase after finalize, got %+v", st)
+	}
+}
+
+func TestLocalDownloadService_Verify_PublishesResult(t *testing.T) {
+	tempDir := t.TempDir()
+	state.CloseDB()
+	state.Configure(filepath.Join(tempDir, "surge.db"))
+	defer state.CloseDB()
+
+	svc := NewLocalDownloadServiceWithInput(nil, nil)
+	defer func() { _ = svc.Shutdown() }()
+	streamCh, cleanup, err := svc.StreamEvents(context.Background())
+	if err != nil {
+		t.Fatalf("failed to stream events: %v", err)
+	}
+	defer cleanup()
+
+	destPath := filepath.Join(tempDir, "done.bin")
+	if err := os.WriteFile(destPath, []byte("complete file"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sum, err := processing.HashFile(destPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []types.DownloadEntry{
+		{ID: "done", URL: "https://example.com/done.bin", DestPath: destPath, Filename: "done.bin", Status: "completed"},
+		{ID: "running", URL: "https://example.com/running.bin", DestPath: filepath.Join(tempDir, "running.bin"), Filename: "running.bin", Status: "downloading"},
+	} {
+		if err := state.AddToMasterList(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := state.SetChecksum("done", sum); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.Verify("missing"); !errors.Is(err, types.ErrNotFound) {
+		t.Fatalf("Verify(missing) = %v, want ErrNotFound", err)
+	}
+	if err := svc.Verify("running"); !errors.Is(err, types.ErrNotCompleted) {
+		t.Fatalf("Verify(running) = %v, want ErrNotCompleted", err)
+	}
+	if err := svc.Verify("done"); err != nil {
+		t.Fatalf("Verify(done) = %v", err)
+	}
+
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case msg := <-streamCh:
+			m, ok := msg.(events.DownloadVerifiedMsg)
+			if !ok {
+				continue
+			}
+			if m.DownloadID != "done" || m.Result.Status != types.VerifyOK || m.Result.Actual != sum {
+				t.Fatalf("verified = %+v", m)
+			}
+			return
+		case <-deadline:
+			t.Fatal("expected DownloadVerifiedMsg")
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// BundleVersion is the version of the bundle format ExportBundle writes
+const BundleVersion = 1
+
+// bundleStatuses are the downloads a bundle carries: the pending ones and
+// the completed ones
+var bundleStatuses = []string{"queued", "downloading", "paused", "error", "completed"}
+
+// Bundle is what surge export writes: the pending and completed downloads of
+// one machine, with its settings, for surge import to read on another
+type Bundle struct {
+	Version    int              `json:"version"`
+	ExportedAt string           `json:"exported_at"` // RFC 3339
+	Settings   *config.Settings `json:"settings,omitempty"`
+	Downloads  []BundleDownload `json:"downloads"`
+}
+
+// BundleDownload is one download of a Bundle. Progress is not carried:
+// pending downloads start over on the machine they are imported on.
+type BundleDownload struct {
+	ID            string                   `json:"id"`
+	URL           string                   `json:"url"`
+	Mirrors       []string                 `json:"mirrors,omitempty"`
+	DestPath      string                   `json:"dest_path"`
+	Filename      string                   `json:"filename"`
+	Status        string                   `json:"status"`
+	TotalSize     int64                    `json:"total_size"`
+	CreatedAt     int64                    `json:"created_at,omitempty"`
+	CompletedAt   int64                    `json:"completed_at,omitempty"`
+	TimeTaken     int64                    `json:"time_taken,omitempty"`
+	AvgSpeed      float64                  `json:"avg_speed,omitempty"`
+	Tags          []string                 `json:"tags,omitempty"`
+	Category      string                   `json:"category,omitempty"`
+	Note          string                   `json:"note,omitempty"`
+	Metadata      map[string]string        `json:"metadata,omitempty"`
+	Checksum      string                   `json:"checksum,omitempty"`
+	QueuePosition int64                    `json:"queue_position,omitempty"`
+	Overrides     *types.DownloadOverrides `json:"overrides,omitempty"` // Without headers, which may be credentials
+}
+
+// ExportBundle writes the pending and completed downloads in the state
+// database as a Bundle, with settings unless nil. Request headers are left
+// out, of the downloads and of webhooks, as no export carries credentials,
+// and so are the settings that only make sense on this machine: the state
+// store, the API server and distributed peers.
+func ExportBundle(w io.Writer, settings *config.Settings, now time.Time) error {
+	b := Bundle{
+		Version:    BundleVersion,
+		ExportedAt: now.UTC().Format(time.RFC3339),
+		Settings:   portableSettings(settings),
+		Downloads:  []BundleDownload{},
+	}
+	err := state.ExportDownloads(state.ExportFilter{Statuses: bundleStatuses}, func(e types.DownloadEntry) error {
+		d := BundleDownload{
+			ID:            e.ID,
+			URL:           e.URL,
+			Mirrors:       e.Mirrors,
+			DestPath:      e.DestPath,
+			Filename:      e.Filename,
+			Status:        e.Status,
+			TotalSize:     e.TotalSize,
+			CreatedAt:     e.CreatedAt,
+			CompletedAt:   e.CompletedAt,
+			TimeTaken:     e.TimeTaken,
+			AvgSpeed:      e.AvgSpeed,
+			Tags:          e.Tags,
+			Category:      e.Category,
+			Note:          e.Note,
+			Metadata:      e.Metadata,
+			Checksum:      e.Checksum,
+			QueuePosition: e.QueuePosition,
+		}
+		if o := e.Overrides; o != nil {
+			stripped := *o
+			stripped.Headers = nil
+			if !stripped.IsZero() {
+				d.Overrides = &stripped
+			}
+		}
+		b.Downloads = append(b.Downloads, d)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b)
+}
+
+// portableSettings returns a copy of s without what ExportBundle leaves out
+func portableSettings(s *config.Settings) *config.Settings {
+	if s == nil {
+		return nil
+	}
+	out := *s
+	out.General.StateStore = ""
+	out.General.EncryptState = false
+	out.Server = config.ServerSettings{}
+	out.Distributed = config.DistributedSettings{}
+	out.Webhooks = slices.Clone(s.Webhooks)
+	for i := range out.Webhooks {
+		out.Webhooks[i].Headers = nil
+	}
+	return &out
+}
+
+// ReadBundle reads a bundle written by ExportBundle
+func ReadBundle(r io.Reader) (*Bundle, error) {
+	var b Bundle
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return nil, fmt.Errorf("not a Surge export: %w", err)
+	}
+	if b.Version < 1 || b.Version > BundleVersion {
+		return nil, fmt.Errorf("unsupported export version %d (this Surge reads up to %d)", b.Version, BundleVersion)
+	}
+	return &b, nil
+}
+
+// DirMap rewrites directories of another machine to ones of this machine:
+// each key becomes its value, along with everything under it. The longest
+// matching key wins; paths no key matches are kept.
+type DirMap map[string]string
+
+// ParseDirMap reads OLD=NEW pairs into a DirMap
+func ParseDirMap(pairs []string) (DirMap, error) {
+	m := make(DirMap, len(pairs))
+	for _, pair := range pairs {
+		from, to, ok := strings.Cut(pair, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid directory mapping %q, want OLD=NEW", pair)
+		}
+		m[trimSeparators(from)] = to
+	}
+	return m, nil
+}
+
+// Apply returns path with its directory remapped
+func (m DirMap) Apply(path string) string {
+	if path == "" || len(m) == 0 {
+		return path
+	}
+	keys := make([]string, 0, len(m))
+	for from := range m {
+		keys = append(keys, from)
+	}
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+
+	for _, from := range keys {
+		rest, ok := strings.CutPrefix(path, from)
+		if !ok || (rest != "" && rest[0] != '/' && rest[0] != '\\') {
+			continue
+		}
+		// The exporting machine may use the other kind of separator
+		rest = strings.ReplaceAll(rest, `\`, "/")
+		return filepath.Join(m[from], filepath.FromSlash(rest))
+	}
+	return path
+}
+
+// trimSeparators drops trailing separators, but keeps a root as it is
+func trimSeparators(dir string) string {
+	trimmed := strings.TrimRight(dir, `/\`)
+	if trimmed == "" || strings.HasSuffix(trimmed, ":") {
+		return dir
+	}
+	return trimmed
+}
+
+// ImportResult counts what ImportBundle did
+type ImportResult struct {
+	Pending   int // Added to the queue
+	Completed int // Added to the history
+	Skipped   int // Already in the state database
+}
+
+// ImportBundle adds the downloads of b to the state database, their paths
+// remapped by dirs. Completed downloads join the history, with their files
+// expected at the new path. Pending ones start over from the beginning:
+// queued and running downloads are queued and start when Surge does, paused
+// and failed ones wait paused for surge resume. Downloads whose ID is
+// already known are skipped, so importing a bundle twice changes nothing.
+func ImportBundle(b *Bundle, dirs DirMap) (ImportResult, error) {
+	var res ImportResult
+	// Pending downloads join the queue in the order they waited in
+	downloads := slices.Clone(b.Downloads)
+	sort.SliceStable(downloads, func(i, j int) bool {
+		return downloads[i].QueuePosition < downloads[j].QueuePosition
+	})
+	for _, d := range downloads {
+		if d.ID == "" || d.URL == "" {
+			continue
+		}
+		existing, err := state.GetDownload(d.ID)
+		if err != nil {
+			return res, err
+		}
+		if existing != nil {
+			res.Skipped++
+			continue
+		}
+
+		entry := types.DownloadEntry{
+			ID:        d.ID,
+			URLHash:   state.URLHash(d.URL),
+			URL:       d.URL,
+			Mirrors:   d.Mirrors,
+			DestPath:  dirs.Apply(d.DestPath),
+			Filename:  d.Filename,
+			TotalSize: d.TotalSize,
+			CreatedAt: d.CreatedAt,
+			Tags:      d.Tags,
+			Category:  d.Category,
+			Overrides: d.Overrides,
+		}
+		switch d.Status {
+		case "completed":
+			entry.Status = "completed"
+			entry.Downloaded = d.TotalSize
+			entry.CompletedAt, entry.TimeTaken, entry.AvgSpeed = d.CompletedAt, d.TimeTaken, d.AvgSpeed
+		case "queued", "downloading":
+			entry.Status = "queued"
+		case "paused", "error":
+			entry.Status, entry.PauseReason = "paused", types.PauseUser
+		default:
+			continue
+		}
+		if err := state.AddToMasterList(entry); err != nil {
+			return res, fmt.Errorf("importing %s: %w", d.ID, err)
+		}
+
+		if d.Note != "" || len(d.Metadata) > 0 {
+			update := types.NoteUpdate{Metadata: maps.Clone(d.Metadata)}
+			if d.Note != "" {
+				update.Note = &d.Note
+			}
+			if _, _, err := state.UpdateNote(d.ID, update); err != nil {
+				return res, fmt.Errorf("importing the note of %s: %w", d.ID, err)
+			}
+		}
+		if d.Checksum != "" && entry.Status == "completed" {
+			if err := state.SetChecksum(d.ID, d.Checksum); err != nil {
+				return res, fmt.Errorf("importing the checksum of %s: %w", d.ID, err)
+			}
+		}
+
+		if entry.Status == "completed" {
+			res.Completed++
+		} else {
+			res.Pending++
+		}
+	}
+	return res, nil
+}
+
+// ImportSettings returns the settings of a bundle for this machine: the
+// directories in them remapped by dirs, and what ExportBundle leaves out
+// taken from current
+func ImportSettings(imported, current *config.Settings, dirs DirMap) *config.Settings {
+	out := *imported
+	out.General.DefaultDownloadDir = dirs.Apply(out.General.DefaultDownloadDir)
+	out.General.Categories = slices.Clone(imported.General.Categories)
+	for i := range out.General.Categories {
+		c := &out.General.Categories[i]
+		c.Path = dirs.Apply(c.Path)
+	}
+	out.WatchFolders = slices.Clone(imported.WatchFolders)
+	for i := range out.WatchFolders {
+		w := &out.WatchFolders[i]
+		w.Path, w.OutputDir = dirs.Apply(w.Path), dirs.Apply(w.OutputDir)
+	}
+
+	if current != nil {
+		out.General.StateStore = current.General.StateStore
+		out.General.EncryptState = current.General.EncryptState
+		out.Server = current.Server
+		out.Distributed = current.Distributed
+		// Keep this machine's webhook headers for the hooks it already has
+		headers := make(map[string]map[string]string, len(current.Webhooks))
+		for _, hook := range current.Webhooks {
+			headers[hook.ID] = hook.Headers
+		}
+		out.Webhooks = slices.Clone(imported.Webhooks)
+		for i := range out.Webhooks {
+			if hook := &out.Webhooks[i]; hook.Headers == nil {
+				hook.Headers = headers[hook.ID]
+			}
+		}
+	}
+	return &out
+}
@@ -0,0 +1,120 @@
+package core
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+	state.CloseDB()
+	state.Configure(filepath.Join(t.TempDir(), "old.db"))
+	defer state.CloseDB()
+
+	for _, e := range []types.DownloadEntry{
+		{ID: "done", URL: "https://example.com/a.iso", DestPath: "/home/alice/Downloads/isos/a.iso", Filename: "a.iso", Status: "completed", TotalSize: 10, CompletedAt: 100, Tags: []string{"iso"}},
+		{ID: "waiting", URL: "https://example.com/b.bin", DestPath: "/home/alice/Downloads/b.bin", Filename: "b.bin", Status: "paused", Downloaded: 5,
+			Overrides: &types.DownloadOverrides{Headers: map[string]string{"Cookie": "secret"}, Connections: 4, Tries: 9}},
+		{ID: "failed", URL: "https://example.com/c.bin", DestPath: "/srv/c.bin", Filename: "c.bin", Status: "error"},
+		{ID: "gone", URL: "https://example.com/d.bin", DestPath: "/srv/d.bin", Filename: "d.bin", Status: "trashed"},
+	} {
+		if err := state.AddToMasterList(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := state.SetChecksum("done", "abc123"); err != nil {
+		t.Fatal(err)
+	}
+
+	settings := config.DefaultSettings()
+	settings.General.DefaultDownloadDir = "/home/alice/Downloads"
+	settings.General.StateStore = "/home/alice/surge.db"
+	settings.Webhooks = []config.Webhook{{ID: "hook", URL: "https://hooks.example.com", Headers: map[string]string{"Authorization": "Bearer x"}}}
+
+	var buf bytes.Buffer
+	if err := ExportBundle(&buf, settings, time.Unix(0, 0)); err != nil {
+		t.Fatalf("ExportBundle: %v", err)
+	}
+	for _, secret := range []string{"secret", "Bearer x", "/home/alice/surge.db"} {
+		if strings.Contains(buf.String(), secret) {
+			t.Errorf("export contains %q:\n%s", secret, buf.String())
+		}
+	}
+
+	bundle, err := ReadBundle(&buf)
+	if err != nil {
+		t.Fatalf("ReadBundle: %v", err)
+	}
+	if len(bundle.Downloads) != 3 {
+		t.Fatalf("downloads = %+v, want the pending and completed ones", bundle.Downloads)
+	}
+
+	// Import on a new machine
+	state.CloseDB()
+	state.Configure(filepath.Join(t.TempDir(), "new.db"))
+	dirs, err := ParseDirMap([]string{"/home/alice/Downloads/=/data"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := ImportBundle(bundle, dirs)
+	if err != nil {
+		t.Fatalf("ImportBundle: %v", err)
+	}
+	if res.Pending != 2 || res.Completed != 1 {
+		t.Errorf("result = %+v", res)
+	}
+
+	done, _ := state.GetDownload("done")
+	if done == nil || done.DestPath != filepath.Join("/data", "isos", "a.iso") || done.Status != "completed" || done.Checksum != "abc123" || done.Tags[0] != "iso" {
+		t.Errorf("completed download = %+v", done)
+	}
+	waiting, _ := state.GetDownload("waiting")
+	if waiting == nil || waiting.Status != "paused" || waiting.Downloaded != 0 || waiting.DestPath != filepath.Join("/data", "b.bin") {
+		t.Errorf("paused download = %+v", waiting)
+	}
+	if o := waiting.Overrides; o == nil || o.Connections != 4 || o.Tries != 9 || o.Headers != nil {
+		t.Errorf("overrides = %+v, want them without headers", o)
+	}
+	failed, _ := state.GetDownload("failed")
+	if failed == nil || failed.Status != "paused" || failed.DestPath != "/srv/c.bin" {
+		t.Errorf("failed download = %+v, want it paused where it was", failed)
+	}
+
+	if res, err := ImportBundle(bundle, dirs); err != nil || res.Skipped != 3 {
+		t.Errorf("second import = %+v, %v; want everything skipped", res, err)
+	}
+
+	current := config.DefaultSettings()
+	current.General.StateStore = "/data/surge.db"
+	imported := ImportSettings(bundle.Settings, current, dirs)
+	if imported.General.DefaultDownloadDir != "/data" || imported.General.StateStore != "/data/surge.db" {
+		t.Errorf("settings general = %+v", imported.General)
+	}
+}
+
+func TestDirMapApply(t *testing.T) {
+	dirs, err := ParseDirMap([]string{"/home/alice=/home/bob", "/home/alice/Videos=/media/videos", `C:\Users\alice\Downloads=/data`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for in, want := range map[string]string{
+		"/home/alice/a.iso":           filepath.Join("/home/bob", "a.iso"),
+		"/home/alice/Videos/film.mkv": filepath.Join("/media/videos", "film.mkv"),
+		"/home/alicexyz/a.iso":        "/home/alicexyz/a.iso",
+		`C:\Users\alice\Downloads\x`:  filepath.Join("/data", "x"),
+		"/home/alice":                 "/home/bob",
+	} {
+		if got := dirs.Apply(in); got != want {
+			t.Errorf("Apply(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if _, err := ParseDirMap([]string{"/nowhere"}); err == nil {
+		t.Error("a mapping without = was accepted")
+	}
+}
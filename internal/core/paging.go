@@ -0,0 +1,229 @@
+package core
+
+import (
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// ErrInvalidCursor is returned for a page token this server did not issue
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursors are keyset positions: the sort key and id of the last item served.
+// Paging by position rather than offset means downloads added or removed
+// between requests don't shift later pages, so nothing is repeated or skipped.
+const (
+	listCursorKind    = "l"
+	historyCursorKind = "h"
+)
+
+type pageCursor struct {
+	key int64
+	id  string
+}
+
+func encodeCursor(kind string, key int64, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(kind + ":" + strconv.FormatInt(key, 10) + ":" + id))
+}
+
+func decodeCursor(kind, token string) (*pageCursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	parts := strings.SplitN(string(raw), ":", 3)
+	if len(parts) != 3 || parts[0] != kind {
+		return nil, ErrInvalidCursor
+	}
+	key, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &pageCursor{key: key, id: parts[2]}, nil
+}
+
+// SortStatuses orders downloads by when they were added, then by id
+func SortStatuses(statuses []types.DownloadStatus) {
+	sort.SliceStable(statuses, func(i, j int) bool {
+		if statuses[i].AddedAt != statuses[j].AddedAt {
+			return statuses[i].AddedAt < statuses[j].AddedAt
+		}
+		return statuses[i].ID < statuses[j].ID
+	})
+}
+
+// PageStatuses returns up to limit statuses following cursor from a list
+// sorted by SortStatuses, and the cursor of the next page ("" on the last
+// one). A limit of 0 or less returns everything after cursor.
+func PageStatuses(statuses []types.DownloadStatus, cursor string, limit int) ([]types.DownloadStatus, string, error) {
+	after, err := decodeCursor(listCursorKind, cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	start := 0
+	if after != nil {
+		start = sort.Search(len(statuses), func(i int) bool {
+			s := statuses[i]
+			return s.AddedAt > after.key || (s.AddedAt == after.key && s.ID > after.id)
+		})
+	}
+	page, more := window(len(statuses), start, limit)
+	out := statuses[start:page]
+	if !more {
+		return out, "", nil
+	}
+	last := out[len(out)-1]
+	return out, encodeCursor(listCursorKind, last.AddedAt, last.ID), nil
+}
+
+// PageHistory pages completed downloads ordered by state.SortHistory, like
+// PageStatuses
+func PageHistory(entries []types.DownloadEntry, cursor string, limit int) ([]types.DownloadEntry, string, error) {
+	after, err := decodeCursor(historyCursorKind, cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	start := 0
+	if after != nil {
+		start = sort.Search(len(entries), func(i int) bool {
+			e := entries[i]
+			return e.CompletedAt < after.key || (e.CompletedAt == after.key && e.ID > after.id)
+		})
+	}
+	page, more := window(len(entries), start, limit)
+	out := entries[start:page]
+	if !more {
+		return out, "", nil
+	}
+	last := out[len(out)-1]
+	return out, encodeCursor(historyCursorKind, last.CompletedAt, last.ID), nil
+}
+
+// window returns the end of a page starting at start and whether items remain
+// after it
+func window(n, start, limit int) (end int, more bool) {
+	if limit <= 0 || start+limit >= n {
+		return n, false
+	}
+	return start + limit, true
+}
+
+// ListPage pages List in the database: only the requested rows are read, and
+// active downloads on the page report their live status. Ordering by speed or
+// ETA reads every matching row, since those are only known live.
+func (s *LocalDownloadService) ListPage(q ListQuery) ([]types.DownloadStatus, string, error) {
+	if err := q.Validate(); err != nil {
+		return nil, "", err
+	}
+	after, err := decodeCursor(listCursorKind, q.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	active := make(map[string]types.DownloadStatus)
+	for _, st := range s.activeStatuses() {
+		active[st.ID] = st
+	}
+	sq := pageQuery(after, q.Tag, 0)
+	sq.Statuses, sq.Search = q.Statuses, q.Search
+	if len(q.Statuses) > 0 {
+		// A stored status can lag the live one, so filter those after merging
+		for id := range active {
+			sq.Include = append(sq.Include, id)
+		}
+	}
+	if q.byAdded() && q.Limit > 0 {
+		sq.Limit = q.Offset + q.Limit + 1
+	}
+	entries, err := state.ListDownloadsPage(sq)
+	if err != nil {
+		return nil, "", err
+	}
+
+	statuses := make([]types.DownloadStatus, 0, len(entries))
+	for _, d := range entries {
+		st, ok := active[d.ID]
+		if ok {
+			mergeEntryInto(&st, d)
+			delete(active, d.ID)
+		} else {
+			st = entryStatus(d)
+		}
+		if q.Matches(st) {
+			statuses = append(statuses, st)
+		}
+	}
+	// Queued downloads have no row until they start, so List sorts them
+	// first with an AddedAt of 0
+	for id, st := range active {
+		if after != nil && (after.key > 0 || (after.key == 0 && id <= after.id)) {
+			continue
+		}
+		if !q.Matches(st) {
+			continue
+		}
+		if d, _ := state.GetDownload(id); d != nil {
+			continue
+		}
+		statuses = append(statuses, st)
+	}
+	SortStatuses(statuses)
+	if !q.byAdded() {
+		SortStatusesBy(statuses, q.Sort)
+	}
+
+	start := min(q.Offset, len(statuses))
+	end, more := window(len(statuses), start, q.Limit)
+	// Rows dropped for their live status can leave the page short of the
+	// rows read, so a full read also means more may follow
+	more = more || (sq.Limit > 0 && len(entries) == sq.Limit)
+	out := statuses[start:end]
+	for i := range out {
+		s.applyPhase(&out[i])
+	}
+	if !more || !q.byAdded() || len(out) == 0 {
+		return out, "", nil
+	}
+	last := out[len(out)-1]
+	return out, encodeCursor(listCursorKind, last.AddedAt, last.ID), nil
+}
+
+// HistoryPage pages History in the database, like ListPage
+func (s *LocalDownloadService) HistoryPage(tag, cursor string, limit int) ([]types.DownloadEntry, string, error) {
+	after, err := decodeCursor(historyCursorKind, cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	entries, err := state.ListCompletedPage(pageQuery(after, tag, limit))
+	if err != nil {
+		return nil, "", err
+	}
+	end, more := window(len(entries), 0, limit)
+	out := entries[:end]
+	if !more {
+		return out, "", nil
+	}
+	last := out[len(out)-1]
+	return out, encodeCursor(historyCursorKind, last.CompletedAt, last.ID), nil
+}
+
+// pageQuery asks for one row past the page so a full page knows whether
+// another follows it
+func pageQuery(after *pageCursor, tag string, limit int) state.PageQuery {
+	q := state.PageQuery{Tag: tag}
+	if after != nil {
+		q.After, q.AfterKey, q.AfterID = true, after.key, after.id
+	}
+	if limit > 0 {
+		q.Limit = limit + 1
+	}
+	return q
+}
@@ -0,0 +1,210 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func pageAllStatuses(t *testing.T, statuses []types.DownloadStatus, limit int) []string {
+	t.Helper()
+	var ids []string
+	cursor := ""
+	for i := 0; ; i++ {
+		if i > len(statuses)+1 {
+			t.Fatal("paging did not terminate")
+		}
+		page, next, err := PageStatuses(statuses, cursor, limit)
+		if err != nil {
+			t.Fatalf("PageStatuses: %v", err)
+		}
+		if limit > 0 && len(page) > limit {
+			t.Fatalf("page of %d exceeds limit %d", len(page), limit)
+		}
+		for _, s := range page {
+			ids = append(ids, s.ID)
+		}
+		if next == "" {
+			return ids
+		}
+		cursor = next
+	}
+}
+
+func TestSortStatuses(t *testing.T) {
+	statuses := []types.DownloadStatus{
+		{ID: "c", AddedAt: 20},
+		{ID: "b", AddedAt: 10},
+		{ID: "a", AddedAt: 20},
+		{ID: "d", AddedAt: 0},
+	}
+	SortStatuses(statuses)
+	want := []string{"d", "b", "a", "c"}
+	for i, s := range statuses {
+		if s.ID != want[i] {
+			t.Fatalf("order = %v, want %v", statuses, want)
+		}
+	}
+}
+
+func TestPageStatuses_VisitsEveryItemOnce(t *testing.T) {
+	var statuses []types.DownloadStatus
+	for i := 0; i < 23; i++ {
+		// Several downloads share each timestamp, as batch adds do
+		statuses = append(statuses, types.DownloadStatus{ID: fmt.Sprintf("id-%02d", i), AddedAt: int64(i / 4)})
+	}
+	SortStatuses(statuses)
+
+	for _, limit := range []int{0, 1, 4, 5, 23, 50} {
+		ids := pageAllStatuses(t, statuses, limit)
+		if len(ids) != len(statuses) {
+			t.Fatalf("limit %d: got %d items, want %d", limit, len(ids), len(statuses))
+		}
+		for i, id := range ids {
+			if id != statuses[i].ID {
+				t.Fatalf("limit %d: item %d = %s, want %s", limit, i, id, statuses[i].ID)
+			}
+		}
+	}
+}
+
+func TestPageStatuses_StableAcrossChanges(t *testing.T) {
+	statuses := []types.DownloadStatus{
+		{ID: "a", AddedAt: 1}, {ID: "b", AddedAt: 2}, {ID: "c", AddedAt: 3}, {ID: "d", AddedAt: 4},
+	}
+	page, next, err := PageStatuses(statuses, "", 2)
+	if err != nil || len(page) != 2 || next == "" {
+		t.Fatalf("first page = %v, %q, %v", page, next, err)
+	}
+
+	// "a" is removed and "e" is added before the client asks for page two
+	changed := []types.DownloadStatus{
+		{ID: "b", AddedAt: 2}, {ID: "c", AddedAt: 3}, {ID: "d", AddedAt: 4}, {ID: "e", AddedAt: 5},
+	}
+	page, next, err = PageStatuses(changed, next, 2)
+	if err != nil {
+		t.Fatalf("second page: %v", err)
+	}
+	if len(page) != 2 || page[0].ID != "c" || page[1].ID != "d" {
+		t.Fatalf("second page = %v, want c, d", page)
+	}
+	page, next, err = PageStatuses(changed, next, 2)
+	if err != nil || len(page) != 1 || page[0].ID != "e" || next != "" {
+		t.Fatalf("last page = %v, %q, %v", page, next, err)
+	}
+}
+
+func TestPageHistory_NewestFirst(t *testing.T) {
+	entries := []types.DownloadEntry{
+		{ID: "old", CompletedAt: 100},
+		{ID: "new-b", CompletedAt: 300},
+		{ID: "mid", CompletedAt: 200},
+		{ID: "new-a", CompletedAt: 300},
+	}
+	state.SortHistory(entries)
+
+	var ids []string
+	cursor := ""
+	for {
+		page, next, err := PageHistory(entries, cursor, 3)
+		if err != nil {
+			t.Fatalf("PageHistory: %v", err)
+		}
+		for _, e := range page {
+			ids = append(ids, e.ID)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	want := []string{"new-a", "new-b", "mid", "old"}
+	if fmt.Sprint(ids) != fmt.Sprint(want) {
+		t.Errorf("history order = %v, want %v", ids, want)
+	}
+}
+
+func TestPageCursor_Invalid(t *testing.T) {
+	statuses := []types.DownloadStatus{{ID: "a", AddedAt: 1}, {ID: "b", AddedAt: 2}}
+	_, historyCursor, _ := PageHistory([]types.DownloadEntry{{ID: "x", CompletedAt: 2}, {ID: "y", CompletedAt: 1}}, "", 1)
+
+	for _, cursor := range []string{"not base64!", "Zm9v", historyCursor} {
+		if _, _, err := PageStatuses(statuses, cursor, 1); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("cursor %q: err = %v, want ErrInvalidCursor", cursor, err)
+		}
+	}
+}
+
+func TestLocalPager_MatchesInMemoryPaging(t *testing.T) {
+	state.CloseDB()
+	state.Configure(filepath.Join(t.TempDir(), "surge.db"))
+	defer state.CloseDB()
+
+	for _, e := range []types.DownloadEntry{
+		{ID: "a", URL: "https://example.com/a", DestPath: "/tmp/a", Status: "completed", CreatedAt: 10, CompletedAt: 50, Tags: []string{"iso"}},
+		{ID: "b", URL: "https://example.com/b", DestPath: "/tmp/b", Status: "paused", CreatedAt: 10},
+		{ID: "c", URL: "https://example.com/c", DestPath: "/tmp/c", Status: "completed", CreatedAt: 20, CompletedAt: 50, Tags: []string{"iso"}},
+		{ID: "d", URL: "https://example.com/d", DestPath: "/tmp/d", Status: "completed", CreatedAt: 30, CompletedAt: 40},
+		{ID: "e", URL: "https://example.com/e", DestPath: "/tmp/e", Status: "error", CreatedAt: 40, Tags: []string{"iso"}},
+	} {
+		if err := state.AddToMasterList(e); err != nil {
+			t.Fatalf("AddToMasterList: %v", err)
+		}
+	}
+	svc := &LocalDownloadService{}
+
+	for _, tag := range []string{"", "iso"} {
+		var got []string
+		cursor := ""
+		for i := 0; ; i++ {
+			if i > 10 {
+				t.Fatal("paging did not terminate")
+			}
+			page, next, err := svc.ListPage(ListQuery{Tag: tag, Cursor: cursor, Limit: 2})
+			if err != nil {
+				t.Fatalf("ListPage: %v", err)
+			}
+			for _, s := range page {
+				got = append(got, s.ID)
+			}
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+		all, err := svc.List()
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if want := pageAllStatuses(t, FilterStatusesByTag(all, tag), 2); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("tag %q: ListPage ids = %v, want %v", tag, got, want)
+		}
+	}
+
+	var history []string
+	cursor := ""
+	for {
+		page, next, err := svc.HistoryPage("", cursor, 2)
+		if err != nil {
+			t.Fatalf("HistoryPage: %v", err)
+		}
+		for _, e := range page {
+			history = append(history, e.ID)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if want := []string{"a", "c", "d"}; fmt.Sprint(history) != fmt.Sprint(want) {
+		t.Errorf("HistoryPage ids = %v, want %v", history, want)
+	}
+
+	if _, _, err := svc.HistoryPage("", "Zm9v", 2); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("invalid cursor: err = %v, want ErrInvalidCursor", err)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/surge-downloader/surge/internal/download"
 	"github.com/surge-downloader/surge/internal/engine/state"
 	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/processing"
 	"github.com/surge-downloader/surge/internal/testutil"
 )
 
@@ -177,7 +178,7 @@ func TestIntegration_PauseResume_HotPath_Aggregates(t *testing.T) {
 	if f, err := os.Create(destPath + ".surge"); err == nil {
 		_ = f.Close()
 	}
-	id, err := svc.Add(server.URL(), outputDir, filename, nil, nil, false, fileSize, true)
+	id, err := svc.Add(&processing.DownloadRequest{URL: server.URL(), Path: outputDir, Filename: filename, TotalSize: fileSize, SupportsRange: true})
 	if err != nil {
 		t.Fatalf("add failed: %v", err)
 	}
@@ -337,7 +338,7 @@ func TestIntegration_PauseResume_ColdPath_StateContinuity(t *testing.T) {
 	if f, err := os.Create(destPath + ".surge"); err == nil {
 		_ = f.Close()
 	}
-	id, err := svc1.Add(server.URL(), outputDir, filename, nil, nil, false, fileSize, true)
+	id, err := svc1.Add(&processing.DownloadRequest{URL: server.URL(), Path: outputDir, Filename: filename, TotalSize: fileSize, SupportsRange: true})
 	if err != nil {
 		t.Fatalf("add failed: %v", err)
 	}
@@ -565,7 +566,7 @@ func TestIntegration_PauseResume_StatusFormulaInvariants(t *testing.T) {
 	if f, err := os.Create(destPath + ".surge"); err == nil {
 		_ = f.Close()
 	}
-	id, err := svc.Add(server.URL(), outputDir, filename, nil, nil, false, fileSize, true)
+	id, err := svc.Add(&processing.DownloadRequest{URL: server.URL(), Path: outputDir, Filename: filename, TotalSize: fileSize, SupportsRange: true})
 	if err != nil {
 		t.Fatalf("add failed: %v", err)
 	}
@@ -663,7 +664,7 @@ func TestIntegration_PauseResume_ConcreteSnapshotDebugString(t *testing.T) {
 	if f, err := os.Create(destPath + ".surge"); err == nil {
 		_ = f.Close()
 	}
-	id, err := svc.Add(server.URL(), outputDir, filename, nil, nil, false, fileSize, true)
+	id, err := svc.Add(&processing.DownloadRequest{URL: server.URL(), Path: outputDir, Filename: filename, TotalSize: fileSize, SupportsRange: true})
 	if err != nil {
 		t.Fatalf("add failed: %v", err)
 	}
@@ -738,7 +739,7 @@ func TestIntegration_PauseResumeBatch_ColdPath(t *testing.T) {
 	if f, err := os.Create(destPath1 + ".surge"); err == nil {
 		_ = f.Close()
 	}
-	id1, err := svc1.Add(server.URL(), outputDir, "cold1.bin", nil, nil, false, fileSize, true)
+	id1, err := svc1.Add(&processing.DownloadRequest{URL: server.URL(), Path: outputDir, Filename: "cold1.bin", TotalSize: fileSize, SupportsRange: true})
 	if err != nil {
 		t.Fatalf("add 1 failed: %v", err)
 	}
@@ -747,7 +748,7 @@ func TestIntegration_PauseResumeBatch_ColdPath(t *testing.T) {
 	if f, err := os.Create(destPath2 + ".surge"); err == nil {
 		_ = f.Close()
 	}
-	id2, err := svc1.Add(server.URL(), outputDir, "cold2.bin", nil, nil, false, fileSize, true)
+	id2, err := svc1.Add(&processing.DownloadRequest{URL: server.URL(), Path: outputDir, Filename: "cold2.bin", TotalSize: fileSize, SupportsRange: true})
 	if err != nil {
 		t.Fatalf("add 2 failed: %v", err)
 	}
@@ -788,7 +789,7 @@ func TestIntegration_PauseResumeBatch_ColdPath(t *testing.T) {
 	if f, err := os.Create(destPathHot + ".surge"); err == nil {
 		_ = f.Close()
 	}
-	idHot, err := svc2.Add(server.URL(), outputDir, "hot1.bin", nil, nil, false, fileSize, true)
+	idHot, err := svc2.Add(&processing.DownloadRequest{URL: server.URL(), Path: outputDir, Filename: "hot1.bin", TotalSize: fileSize, SupportsRange: true})
 	if err != nil {
 		t.Fatalf("add hot failed: %v", err)
 	}
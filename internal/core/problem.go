@@ -0,0 +1,104 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// ProblemContentType is the media type of the API's error bodies (RFC 7807)
+const ProblemContentType = "application/problem+json"
+
+// Error codes carried by a Problem, for clients to branch on
+const (
+	CodeBadRequest       = "bad_request"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodeDuplicate        = "duplicate"              // The URL is already queued or downloaded
+	CodePathForbidden    = "path_forbidden"         // A path or filename would leave its directory
+	CodeApprovalRequired = "approval_required"      // Headless daemons can't prompt for approval
+	CodeIdempotencyReuse = "idempotency_key_reused" // An idempotency key came with a different request
+	CodeTooLarge         = "too_large"
+	CodeUnsupported      = "unsupported"
+	CodeInternal         = "internal"
+	CodeNotImplemented   = "not_implemented"
+	CodeUnavailable      = "unavailable"
+)
+
+// Problem is an RFC 7807 problem details body, which the API answers every
+// error with. Type is always about:blank; Code tells errors of the same
+// status apart.
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Code   string `json:"code"`
+}
+
+// NewProblem returns the problem for an error with status, code and detail
+func NewProblem(status int, code, detail string) Problem {
+	return Problem{Type: "about:blank", Title: http.StatusText(status), Status: status, Detail: detail, Code: code}
+}
+
+// CodeForStatus returns the code for an error that has no more specific one
+func CodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodeTooLarge
+	case http.StatusUnprocessableEntity:
+		return CodeUnsupported
+	case http.StatusNotImplemented:
+		return CodeNotImplemented
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
+
+// APIError is an error response from the daemon
+type APIError struct {
+	Problem
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error %d: %s", e.Status, e.Detail)
+}
+
+// ReadAPIError reads the error response resp. Bodies that aren't problems,
+// such as those of daemons predating them, become the detail as text.
+func ReadAPIError(resp *http.Response) *APIError {
+	// Limit error body read to 1KB to prevent DoS
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, types.KB))
+	apiErr := &APIError{Problem: NewProblem(resp.StatusCode, CodeForStatus(resp.StatusCode), strings.TrimSpace(string(body)))}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType == ProblemContentType {
+		var p Problem
+		if json.Unmarshal(body, &p) == nil && p.Code != "" {
+			apiErr.Problem = p
+		}
+	}
+	return apiErr
+}
@@ -0,0 +1,47 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadAPIError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Content-Type", ProblemContentType+"; charset=utf-8")
+	rec.WriteHeader(http.StatusConflict)
+	_, _ = rec.WriteString(`{"type":"about:blank","title":"Conflict","status":409,"detail":"already queued","code":"duplicate"}`)
+	apiErr := ReadAPIError(rec.Result())
+	if apiErr.Code != CodeDuplicate || apiErr.Detail != "already queued" || apiErr.Status != http.StatusConflict {
+		t.Fatalf("problem = %+v", apiErr.Problem)
+	}
+
+	// Plain-text bodies of older daemons keep their text and get a code by status
+	rec = httptest.NewRecorder()
+	http.Error(rec, "download not found", http.StatusNotFound)
+	apiErr = ReadAPIError(rec.Result())
+	if apiErr.Code != CodeNotFound || apiErr.Detail != "download not found" {
+		t.Fatalf("problem = %+v", apiErr.Problem)
+	}
+	if apiErr.Error() != "API error 404: download not found" {
+		t.Errorf("Error() = %q", apiErr.Error())
+	}
+}
+
+func TestRemoteServiceReturnsAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ProblemContentType)
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid path","code":"path_forbidden"}`))
+	}))
+	defer server.Close()
+
+	svc := NewRemoteDownloadService(server.URL, "token")
+	defer func() { _ = svc.Shutdown() }()
+	_, err := svc.List()
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != CodePathForbidden {
+		t.Fatalf("List() error = %v, want path_forbidden API error", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/surge-downloader/surge/internal/engine/events"
 	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/processing"
 	"github.com/surge-downloader/surge/internal/utils"
 )
 
@@ -27,14 +29,23 @@ type RemoteDownloadService struct {
 	cancel    context.CancelFunc
 }
 
-// NewRemoteDownloadService creates a new remote service instance.
+// NewRemoteDownloadService creates a new remote service instance. baseURL may
+// be a unix:// socket path.
 func NewRemoteDownloadService(baseURL string, token string) *RemoteDownloadService {
+	return NewRemoteDownloadServiceTLS(baseURL, token, nil)
+}
+
+// NewRemoteDownloadServiceTLS is NewRemoteDownloadService verifying an https
+// daemon with tlsConfig, e.g. one trusting its self-signed certificate
+func NewRemoteDownloadServiceTLS(baseURL string, token string, tlsConfig *tls.Config) *RemoteDownloadService {
 	ctx, cancel := context.WithCancel(context.Background())
+	sseClient, _ := NewAPIClient(baseURL, 0, tlsConfig)
+	client, baseURL := NewAPIClient(baseURL, 30*time.Second, tlsConfig)
 	return &RemoteDownloadService{
 		BaseURL:   baseURL,
 		Token:     token,
-		Client:    &http.Client{Timeout: 30 * time.Second},
-		SSEClient: &http.Client{},
+		Client:    client,
+		SSEClient: sseClient,
 		ctx:       ctx,
 		cancel:    cancel,
 	}
@@ -56,6 +67,7 @@ func (s *RemoteDownloadService) doRequest(method, path string, body interface{})
 	}
 
 	req.Header.Set("Authorization", "Bearer "+s.Token)
+	req.Header.Set("X-Surge-API-Version", "1") // The API version this client was written for
 	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
@@ -67,9 +79,7 @@ func (s *RemoteDownloadService) doRequest(method, path string, body interface{})
 
 	if resp.StatusCode >= 400 {
 		defer func() { _ = resp.Body.Close() }()
-		// Limit error body read to 1KB to prevent DoS
-		bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, types.KB))
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, ReadAPIError(resp)
 	}
 
 	return resp, nil
@@ -120,48 +130,67 @@ func (s *RemoteDownloadService) GetStatus(id string) (*types.DownloadStatus, err
 	return &status, nil
 }
 
-// Add queues a new download.
-func (s *RemoteDownloadService) Add(url string, path string, filename string, mirrors []string, headers map[string]string, isExplicitCategory bool, totalSize int64, supportsRange bool) (string, error) {
-	req := map[string]interface{}{
-		"url":                  url,
-		"path":                 path,
-		"filename":             filename,
-		"mirrors":              mirrors,
-		"headers":              headers,
-		"skip_approval":        true,
-		"is_explicit_category": isExplicitCategory,
-		"total_size":           totalSize,
-		"supports_range":       supportsRange,
-	}
-
-	resp, err := s.doRequest("POST", "/download", req)
+// ChunkMap returns the chunk map of a download.
+func (s *RemoteDownloadService) ChunkMap(id string) (*types.ChunkMap, error) {
+	resp, err := s.doRequest("GET", "/chunks?id="+url.QueryEscape(id), nil)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	var result map[string]string
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
+	var m types.ChunkMap
+	if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
+		return nil, err
 	}
-	return result["id"], nil
+	return &m, nil
+}
+
+// Add queues a new download.
+func (s *RemoteDownloadService) Add(req *processing.DownloadRequest) (string, error) {
+	return s.add(map[string]interface{}{
+		"url":                  req.URL,
+		"path":                 req.Path,
+		"filename":             req.Filename,
+		"mirrors":              req.Mirrors,
+		"headers":              req.Headers,
+		"tags":                 req.Tags,
+		"category":             req.Category,
+		"skip_approval":        true,
+		"is_explicit_category": req.IsExplicitCategory,
+		"total_size":           req.TotalSize,
+		"supports_range":       req.SupportsRange,
+		"connections":          req.Connections,
+		"speed_limit":          req.SpeedLimit,
+		"chunk_size":           req.ChunkSize,
+		"user_agent":           req.UserAgent,
+		"tries":                req.Tries,
+	})
 }
 
 // AddWithID queues a new download with a caller-provided id.
-func (s *RemoteDownloadService) AddWithID(url string, path string, filename string, mirrors []string, headers map[string]string, id string, totalSize int64, supportsRange bool) (string, error) {
-	req := map[string]interface{}{
-		"url":            url,
-		"path":           path,
-		"filename":       filename,
-		"mirrors":        mirrors,
-		"headers":        headers,
+func (s *RemoteDownloadService) AddWithID(req *processing.DownloadRequest, id string) (string, error) {
+	return s.add(map[string]interface{}{
+		"url":            req.URL,
+		"path":           req.Path,
+		"filename":       req.Filename,
+		"mirrors":        req.Mirrors,
+		"headers":        req.Headers,
+		"tags":           req.Tags,
+		"category":       req.Category,
 		"skip_approval":  true,
 		"id":             id,
-		"total_size":     totalSize,
-		"supports_range": supportsRange,
-	}
+		"total_size":     req.TotalSize,
+		"supports_range": req.SupportsRange,
+		"connections":    req.Connections,
+		"speed_limit":    req.SpeedLimit,
+		"chunk_size":     req.ChunkSize,
+		"user_agent":     req.UserAgent,
+		"tries":          req.Tries,
+	})
+}
 
-	resp, err := s.doRequest("POST", "/download", req)
+func (s *RemoteDownloadService) add(body map[string]interface{}) (string, error) {
+	resp, err := s.doRequest("POST", "/download", body)
 	if err != nil {
 		return "", err
 	}
@@ -216,6 +245,16 @@ func (s *RemoteDownloadService) UpdateURL(id string, newURL string) error {
 	return nil
 }
 
+// UpdateNote changes the note and metadata of a download via the remote API.
+func (s *RemoteDownloadService) UpdateNote(id string, update types.NoteUpdate) error {
+	resp, err := s.doRequest("PUT", "/note?id="+url.QueryEscape(id), update)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	return nil
+}
+
 // Delete cancels and removes a download.
 func (s *RemoteDownloadService) Delete(id string) error {
 	resp, err := s.doRequest("POST", "/delete?id="+url.QueryEscape(id), nil)
@@ -228,6 +267,157 @@ func (s *RemoteDownloadService) Delete(id string) error {
 	return nil
 }
 
+// Archive removes a download but keeps its partial data.
+func (s *RemoteDownloadService) Archive(id string) error {
+	resp, err := s.doRequest("POST", "/archive?id="+url.QueryEscape(id), nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	return nil
+}
+
+// RestorePartial brings back an archived download and resumes it.
+func (s *RemoteDownloadService) RestorePartial(id string) error {
+	resp, err := s.doRequest("POST", "/restore-partial?id="+url.QueryEscape(id), nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	return nil
+}
+
+// Verify asks the daemon to re-hash a completed download; the outcome comes
+// over the event stream.
+func (s *RemoteDownloadService) Verify(id string) error {
+	resp, err := s.doRequest("POST", "/verify?id="+url.QueryEscape(id), nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	return nil
+}
+
+// SpeedLimit returns the speed limit a download keeps of its own.
+func (s *RemoteDownloadService) SpeedLimit(id string) (int64, error) {
+	resp, err := s.doRequest("GET", "/speed-limit?id="+url.QueryEscape(id), nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var limit struct {
+		SpeedLimit int64 `json:"speed_limit"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&limit); err != nil {
+		return 0, err
+	}
+	return limit.SpeedLimit, nil
+}
+
+// SetSpeedLimit changes a download's own speed limit.
+func (s *RemoteDownloadService) SetSpeedLimit(id string, rate int64) error {
+	resp, err := s.doRequest("PUT", "/speed-limit?id="+url.QueryEscape(id), map[string]int64{"speed_limit": rate})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	return nil
+}
+
+// RestoreTrashed brings a download back from the trash.
+func (s *RemoteDownloadService) RestoreTrashed(id string) error {
+	resp, err := s.doRequest("POST", "/restore?id="+url.QueryEscape(id), nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	return nil
+}
+
+// Retry starts a failed download over from the beginning.
+func (s *RemoteDownloadService) Retry(id string, probe bool) error {
+	resp, err := s.doRequest("POST", fmt.Sprintf("/retry?id=%s&probe=%t", url.QueryEscape(id), probe), nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	return nil
+}
+
+// Move puts a waiting download at position in the queue.
+func (s *RemoteDownloadService) Move(id string, position int) error {
+	resp, err := s.doRequest("POST", fmt.Sprintf("/move?id=%s&position=%d", url.QueryEscape(id), position), nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	return nil
+}
+
+// MoveBy moves a waiting download offset places in the queue.
+func (s *RemoteDownloadService) MoveBy(id string, offset int) error {
+	resp, err := s.doRequest("POST", fmt.Sprintf("/move?id=%s&by=%d", url.QueryEscape(id), offset), nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	return nil
+}
+
+// SetPriority gives a waiting download priority and moves it among the
+// downloads of that priority.
+func (s *RemoteDownloadService) SetPriority(id string, priority int, place string) error {
+	endpoint := "/priority?id=" + url.QueryEscape(id) + "&level=" + types.PriorityName(priority)
+	if place != "" {
+		endpoint += "&place=" + url.QueryEscape(place)
+	}
+	resp, err := s.doRequest("POST", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	return nil
+}
+
+// Relocate moves the file of a finished or paused download into dir on the
+// server's machine.
+func (s *RemoteDownloadService) Relocate(id, dir string) (string, error) {
+	return s.relocate("/move?id=" + url.QueryEscape(id) + "&dir=" + url.QueryEscape(dir))
+}
+
+// Rename renames the file of a finished or paused download.
+func (s *RemoteDownloadService) Rename(id, name string) (string, error) {
+	return s.relocate("/rename?id=" + url.QueryEscape(id) + "&name=" + url.QueryEscape(name))
+}
+
+// relocate posts a move or rename and returns the path the server reports
+func (s *RemoteDownloadService) relocate(path string) (string, error) {
+	resp, err := s.doRequest("POST", path, nil)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var result struct {
+		Path string `json:"path"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	return result.Path, nil
+}
+
+// DeletePermanently deletes a download, bypassing the trash.
+func (s *RemoteDownloadService) DeletePermanently(id string) error {
+	resp, err := s.doRequest("POST", "/delete?permanent=true&id="+url.QueryEscape(id), nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	return nil
+}
+
 // Shutdown stops the service.
 func (s *RemoteDownloadService) Shutdown() error {
 	s.cancel()
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// UnixScheme prefixes a base URL that reaches the daemon over a unix socket,
+// as in unix:///run/user/1000/surge.sock
+const UnixScheme = "unix://"
+
+// socketBaseURL is the URL requests over a socket are sent to; the socket
+// picks the server, so the host is only a placeholder
+const socketBaseURL = "http://surge"
+
+// SocketPath returns the socket path of a unix:// base URL
+func SocketPath(baseURL string) (string, bool) {
+	path, ok := strings.CutPrefix(baseURL, UnixScheme)
+	if !ok || path == "" {
+		return "", false
+	}
+	return path, true
+}
+
+// NewAPIClient returns a client for the API at baseURL and the base URL its
+// requests use. An http(s) base URL is returned as is, and https verified with
+// tlsConfig when set; a unix:// one gets a client that dials the socket.
+func NewAPIClient(baseURL string, timeout time.Duration, tlsConfig *tls.Config) (*http.Client, string) {
+	path, ok := SocketPath(baseURL)
+	if !ok {
+		client := &http.Client{Timeout: timeout}
+		if tlsConfig != nil {
+			transport := http.DefaultTransport.(*http.Transport).Clone()
+			transport.TLSClientConfig = tlsConfig
+			client.Transport = transport
+		}
+		return client, baseURL
+	}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}, socketBaseURL
+}
@@ -0,0 +1,41 @@
+package core
+
+import (
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewAPIClient_UnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "surge.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Path))
+	})}
+	go func() { _ = server.Serve(ln) }()
+	t.Cleanup(func() { _ = server.Close() })
+
+	client, baseURL := NewAPIClient(UnixScheme+path, 0, nil)
+	if baseURL != socketBaseURL {
+		t.Fatalf("baseURL = %q, want %q", baseURL, socketBaseURL)
+	}
+	resp, err := client.Get(baseURL + "/health")
+	if err != nil {
+		t.Fatalf("get over socket: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+
+	if _, baseURL := NewAPIClient("http://127.0.0.1:1700", 0, nil); baseURL != "http://127.0.0.1:1700" {
+		t.Fatalf("http baseURL rewritten to %q", baseURL)
+	}
+	if _, ok := SocketPath("unix://"); ok {
+		t.Fatal("empty socket path accepted")
+	}
+}
@@ -0,0 +1,36 @@
+package core
+
+import (
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// FilterStatusesByTag keeps the statuses labeled tag, preserving order. An
+// empty tag keeps everything.
+func FilterStatusesByTag(statuses []types.DownloadStatus, tag string) []types.DownloadStatus {
+	if tag == "" {
+		return statuses
+	}
+	out := make([]types.DownloadStatus, 0, len(statuses))
+	for _, s := range statuses {
+		if utils.HasTag(s.Tags, tag) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// FilterHistoryByTag keeps the history entries labeled tag, preserving order.
+// An empty tag keeps everything.
+func FilterHistoryByTag(entries []types.DownloadEntry, tag string) []types.DownloadEntry {
+	if tag == "" {
+		return entries
+	}
+	out := make([]types.DownloadEntry, 0, len(entries))
+	for _, e := range entries {
+		if utils.HasTag(e.Tags, tag) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
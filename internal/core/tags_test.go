@@ -0,0 +1,37 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestFilterStatusesByTag(t *testing.T) {
+	statuses := []types.DownloadStatus{
+		{ID: "a", Tags: []string{"work"}},
+		{ID: "b"},
+		{ID: "c", Tags: []string{"iso", "work"}},
+	}
+
+	if got := FilterStatusesByTag(statuses, ""); len(got) != 3 {
+		t.Fatalf("empty tag kept %d, want 3", len(got))
+	}
+	got := FilterStatusesByTag(statuses, "Work")
+	if len(got) != 2 || got[0].ID != "a" || got[1].ID != "c" {
+		t.Fatalf("work = %+v", got)
+	}
+	if got := FilterStatusesByTag(statuses, "media"); len(got) != 0 {
+		t.Fatalf("media = %+v", got)
+	}
+}
+
+func TestFilterHistoryByTag(t *testing.T) {
+	history := []types.DownloadEntry{
+		{ID: "a", Tags: []string{"media"}},
+		{ID: "b", Tags: []string{"iso"}},
+	}
+	got := FilterHistoryByTag(history, "iso")
+	if len(got) != 1 || got[0].ID != "b" {
+		t.Fatalf("iso = %+v", got)
+	}
+}
@@ -32,6 +32,7 @@ func startEventWorkerForTest(t *testing.T, svc *LocalDownloadService) func() {
 	}()
 
 	svc.SetLifecycleHooks(mgr.Pause, mgr.Resume, mgr.ResumeBatch)
+	svc.SetRestoreTrashedHook(mgr.RestoreTrashed)
 	mgr.SetEngineHooks(processing.EngineHooks{
 		Pause:        svc.Pool.Pause,
 		Resume:       svc.Pool.Resume,
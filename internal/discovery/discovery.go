@@ -0,0 +1,131 @@
+// Package discovery advertises a running daemon on the local network over
+// mDNS/DNS-SD and finds the daemons others advertise, so clients can connect
+// without being told a host and port.
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/grandcat/zeroconf"
+)
+
+// ServiceType is the DNS-SD service daemons are advertised as, in the
+// local. domain
+const ServiceType = "_surge._tcp"
+
+const domain = "local."
+
+// Info is what a daemon advertises besides its address
+type Info struct {
+	Version    string // Surge version
+	APIVersion string // HTTP API version, as in X-Surge-API-Version
+	TLS        bool   // The API is served over https
+}
+
+// Daemon is a daemon found on the network
+type Daemon struct {
+	Instance string   `json:"instance"`
+	Host     string   `json:"host"` // mDNS host name, e.g. nas.local.
+	Addrs    []string `json:"addrs"`
+	Port     int      `json:"port"`
+	Info
+}
+
+// URL returns the base URL of the daemon's API, preferring an IPv4 address
+// over the host name
+func (d Daemon) URL() string {
+	scheme := "http"
+	if d.TLS {
+		scheme = "https"
+	}
+	host := strings.TrimSuffix(d.Host, ".")
+	if len(d.Addrs) > 0 {
+		host = d.Addrs[0]
+	}
+	return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, fmt.Sprint(d.Port)))
+}
+
+// Advertisement is a running advertisement
+type Advertisement struct {
+	server *zeroconf.Server
+}
+
+// Stop withdraws the advertisement
+func (a *Advertisement) Stop() {
+	a.server.Shutdown()
+}
+
+// Advertise announces a daemon listening on port as instance until Stop is
+// called
+func Advertise(instance string, port int, info Info) (*Advertisement, error) {
+	server, err := zeroconf.Register(instance, ServiceType, domain, port, encodeTXT(info), nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not advertise over mDNS: %w", err)
+	}
+	return &Advertisement{server: server}, nil
+}
+
+// Browse returns the daemons that answer before ctx is done, sorted by
+// instance name
+func Browse(ctx context.Context) ([]Daemon, error) {
+	resolver, err := zeroconf.NewResolver(nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not start mDNS resolver: %w", err)
+	}
+	entries := make(chan *zeroconf.ServiceEntry)
+	if err := resolver.Browse(ctx, ServiceType, domain, entries); err != nil {
+		return nil, fmt.Errorf("could not browse for daemons: %w", err)
+	}
+
+	found := make(map[string]Daemon)
+	for entry := range entries {
+		d := Daemon{
+			Instance: entry.Instance,
+			Host:     entry.HostName,
+			Port:     entry.Port,
+			Info:     decodeTXT(entry.Text),
+		}
+		for _, ip := range entry.AddrIPv4 {
+			d.Addrs = append(d.Addrs, ip.String())
+		}
+		for _, ip := range entry.AddrIPv6 {
+			d.Addrs = append(d.Addrs, ip.String())
+		}
+		found[d.Instance] = d
+	}
+
+	daemons := make([]Daemon, 0, len(found))
+	for _, d := range found {
+		daemons = append(daemons, d)
+	}
+	sort.Slice(daemons, func(i, j int) bool { return daemons[i].Instance < daemons[j].Instance })
+	return daemons, nil
+}
+
+func encodeTXT(info Info) []string {
+	txt := []string{"version=" + info.Version, "api=" + info.APIVersion}
+	if info.TLS {
+		txt = append(txt, "tls=1")
+	}
+	return txt
+}
+
+func decodeTXT(txt []string) Info {
+	var info Info
+	for _, kv := range txt {
+		key, value, _ := strings.Cut(kv, "=")
+		switch key {
+		case "version":
+			info.Version = value
+		case "api":
+			info.APIVersion = value
+		case "tls":
+			info.TLS = value == "1"
+		}
+	}
+	return info
+}
@@ -0,0 +1,29 @@
+package discovery
+
+import "testing"
+
+func TestTXTRoundTrip(t *testing.T) {
+	info := Info{Version: "1.2.3", APIVersion: "1", TLS: true}
+	if got := decodeTXT(encodeTXT(info)); got != info {
+		t.Fatalf("decodeTXT(encodeTXT(%+v)) = %+v", info, got)
+	}
+	if got := decodeTXT([]string{"version=2", "junk", "tls=0"}); got != (Info{Version: "2"}) {
+		t.Fatalf("decodeTXT with junk = %+v", got)
+	}
+}
+
+func TestDaemonURL(t *testing.T) {
+	tests := []struct {
+		d    Daemon
+		want string
+	}{
+		{Daemon{Host: "nas.local.", Addrs: []string{"192.168.1.10"}, Port: 1700}, "http://192.168.1.10:1700"},
+		{Daemon{Host: "nas.local.", Addrs: []string{"fe80::1"}, Port: 1700, Info: Info{TLS: true}}, "https://[fe80::1]:1700"},
+		{Daemon{Host: "nas.local.", Port: 1701}, "http://nas.local:1701"},
+	}
+	for _, tt := range tests {
+		if got := tt.d.URL(); got != tt.want {
+			t.Errorf("URL() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -12,10 +13,11 @@ import (
 
 	"github.com/surge-downloader/surge/internal/engine/concurrent"
 	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/relay"
 	"github.com/surge-downloader/surge/internal/engine/single"
 	"github.com/surge-downloader/surge/internal/engine/types"
 	"github.com/surge-downloader/surge/internal/processing"
-	"github.com/surge-downloader/surge/internal/utils"
+	"github.com/surge-downloader/surge/internal/trace"
 )
 
 // safeSendProgress sends msg on ch, recovering from panics caused by sending
@@ -79,9 +81,44 @@ func uniqueFilePath(path string) string {
 	return path
 }
 
+// withRelayMirrors appends a relay mirror of rawurl for every configured peer
+// daemon not already among mirrors
+func withRelayMirrors(mirrors []string, rawurl string, runtime *types.RuntimeConfig) []string {
+	if runtime == nil || len(runtime.RelayPeers) == 0 {
+		return mirrors
+	}
+	existing := make(map[string]bool, len(mirrors))
+	for _, m := range mirrors {
+		existing[m] = true
+	}
+	for _, p := range runtime.RelayPeers {
+		m := relay.URL(p.URL, p.Token, rawurl)
+		if !existing[m] {
+			mirrors = append(mirrors, m)
+			existing[m] = true
+		}
+	}
+	return mirrors
+}
+
 // TUIDownload is the main entry point for downloads executed by the Engine pool
 func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 	start := time.Now()
+
+	// Every log line and span below carries the download's trace ID
+	ctx, span := trace.Start(trace.WithID(ctx, cfg.TraceID), "download")
+	span.SetAttr("download.id", cfg.ID)
+	if u, err := url.Parse(cfg.URL); err == nil {
+		span.SetAttr("server.address", u.Hostname())
+	}
+	var downloadErr error
+	defer func() {
+		if errors.Is(downloadErr, types.ErrPaused) || errors.Is(downloadErr, context.Canceled) {
+			span.End(nil)
+			return
+		}
+		span.End(downloadErr)
+	}()
 	// Engine expects cfg.OutputPath and cfg.Filename to be fully resolved by the processing layer
 	destPath := cfg.OutputPath
 	finalFilename := cfg.Filename
@@ -114,7 +151,7 @@ func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 					existing[m] = true
 				}
 			}
-			utils.Debug("Restored %d mirrors from state", len(savedState.Mirrors))
+			trace.Debug(ctx, "Restored %d mirrors from state", len(savedState.Mirrors))
 		}
 	}
 	isResume := cfg.IsResume && savedState != nil && savedState.DestPath != ""
@@ -123,9 +160,9 @@ func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 		// Resume: use saved destination path directly (don't generate new unique name)
 		finalDestPath = savedState.DestPath
 		finalFilename = filepath.Base(finalDestPath)
-		utils.Debug("Resuming download, using saved destPath: %s", finalDestPath)
+		trace.Debug(ctx, "Resuming download, using saved destPath: %s", finalDestPath)
 	}
-	utils.Debug("Destination path: %s", finalDestPath)
+	trace.Debug(ctx, "Destination path: %s", finalDestPath)
 
 	if cfg.State != nil {
 		cfg.State.SetFilename(finalFilename)
@@ -141,6 +178,9 @@ func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 			Total:      cfg.TotalSize, // Relies on TotalSize from Config
 			DestPath:   finalDestPath,
 			State:      cfg.State,
+			Tags:       cfg.Tags,
+			Category:   cfg.Category,
+			TraceID:    cfg.TraceID,
 		})
 	}
 
@@ -149,40 +189,60 @@ func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 		cfg.State.SetTotalSize(cfg.TotalSize)
 	}
 
-	// Choose downloader based on probe results
-	var downloadErr error
-	if cfg.SupportsRange && cfg.TotalSize > 0 {
-		utils.Debug("Using concurrent downloader")
+	// Bytes from a download paused long ago may no longer match the remote file
+	if isResume && processing.NeedsRevalidation(savedState, cfg.Runtime, time.Now()) {
+		downloadErr = processing.RevalidateResume(ctx, cfg, savedState)
+	}
 
-		// We probe all candidate mirrors (mirrors) to filter out invalid ones
+	// Choose downloader based on probe results
+	if downloadErr != nil {
+		trace.Debug(ctx, "Not resuming %s: %v", cfg.ID, downloadErr)
+	} else if cfg.SupportsRange && cfg.TotalSize > 0 {
+		trace.Debug(ctx, "Using concurrent downloader")
+
+		// Coordinator mode: each peer daemon is one more mirror, relaying
+		// ranges over its own link
+		mirrors = withRelayMirrors(mirrors, cfg.URL, cfg.Runtime)
+
+		// We probe all candidate mirrors (mirrors) to filter out invalid ones.
+		// A resume with cached probe metadata already knows the origin's size and
+		// range support, so it trusts the saved mirrors and lets workers fail over.
 		var activeMirrors []string
-		if len(mirrors) > 0 {
-			utils.Debug("Probing %d mirrors", len(mirrors))
-			// Always check primary + mirrors to ensure we are using the best set
-			allToCheck := append([]string{cfg.URL}, mirrors...)
-			valid, errs := processing.ProbeMirrorsWithProxy(ctx, allToCheck, cfg.Runtime.ProxyURL)
+		if isResume && cfg.Probe != nil {
+			for _, m := range mirrors {
+				if m != cfg.URL {
+					activeMirrors = append(activeMirrors, m)
+				}
+			}
+			trace.Debug(ctx, "Skipping mirror probe on resume, using %d cached mirrors", len(activeMirrors))
+		} else if len(mirrors) > 0 {
+			// The primary was already probed at enqueue; only the extra mirrors
+			// need checking, all at once and against the primary's size
+			var toCheck []string
+			for _, m := range mirrors {
+				if m != cfg.URL {
+					toCheck = append(toCheck, m)
+				}
+			}
+			trace.Debug(ctx, "Probing %d mirrors", len(toCheck))
+			valid, errs := processing.ProbeMirrorsForSize(ctx, toCheck, cfg.Runtime.ProxyURL, cfg.TotalSize)
 
 			// Log errors
 			for u, e := range errs {
-				utils.Debug("Mirror probe failed for %s: %v", u, e)
+				trace.Debug(ctx, "Mirror probe failed for %s: %v", u, e)
 			}
 
-			// Filter valid mirrors (excluding primary as it is handled separately)
-			for _, v := range valid {
-				if v != cfg.URL {
-					activeMirrors = append(activeMirrors, v)
-				}
-			}
-			utils.Debug("Found %d active mirrors from %d candidates", len(activeMirrors), len(mirrors))
+			activeMirrors = valid
+			trace.Debug(ctx, "Found %d active mirrors from %d candidates", len(activeMirrors), len(toCheck))
 		}
 
 		d := concurrent.NewConcurrentDownloader(cfg.ID, cfg.ProgressCh, cfg.State, cfg.Runtime)
 		d.Headers = cfg.Headers // Forward custom headers from browser extension
-		utils.Debug("Calling Download with mirrors: %v", mirrors)
+		trace.Debug(ctx, "Calling Download with mirrors: %v", mirrors)
 		downloadErr = d.Download(ctx, cfg.URL, mirrors, activeMirrors, finalDestPath, cfg.TotalSize)
 	} else {
 		// Fallback to single-threaded downloader
-		utils.Debug("Using single-threaded downloader")
+		trace.Debug(ctx, "Using single-threaded downloader")
 		d := single.NewSingleDownloader(cfg.ID, cfg.ProgressCh, cfg.State, cfg.Runtime)
 		d.Headers = cfg.Headers // Forward custom headers from browser extension
 		downloadErr = d.Download(ctx, cfg.URL, finalDestPath, cfg.TotalSize, finalFilename)
@@ -191,15 +251,23 @@ func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 	// Only send completion if NO error AND not paused
 	// Check specifically for ErrPaused to avoid treating it as error
 	if errors.Is(downloadErr, types.ErrPaused) {
-		utils.Debug("Download paused cleanly")
+		trace.Debug(ctx, "Download paused cleanly")
 		return nil // Return nil so worker can remove it from active map
 	}
 
 	isPaused := cfg.State != nil && cfg.State.IsPaused()
 	if downloadErr == nil && !isPaused {
+		// The downloader may have learned the real size (unknown length or decoded body)
+		totalSize := cfg.TotalSize
+		if cfg.State != nil {
+			if actual := cfg.State.GetTotalSize(); actual > 0 {
+				totalSize = actual
+			}
+		}
+
 		var elapsed time.Duration
 		if cfg.State != nil {
-			_, elapsed = cfg.State.FinalizeSession(cfg.TotalSize)
+			_, elapsed = cfg.State.FinalizeSession(totalSize)
 		} else {
 			elapsed = time.Since(start)
 		}
@@ -208,7 +276,7 @@ func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 		// Compute average download speed in bytes/sec
 		var avgSpeed float64
 		if elapsed.Seconds() > 0 {
-			avgSpeed = float64(cfg.TotalSize) / elapsed.Seconds()
+			avgSpeed = float64(totalSize) / elapsed.Seconds()
 		}
 
 		if cfg.ProgressCh != nil {
@@ -216,14 +284,15 @@ func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 				DownloadID: cfg.ID,
 				Filename:   finalFilename,
 				Elapsed:    elapsed,
-				Total:      cfg.TotalSize,
+				Total:      totalSize,
 				AvgSpeed:   avgSpeed,
+				TraceID:    cfg.TraceID,
 			})
 		}
 	} else if downloadErr != nil && !isPaused {
 		// Verify it's not a cancellation error
 		if errors.Is(downloadErr, context.Canceled) {
-			utils.Debug("Download canceled cleanly")
+			trace.Debug(ctx, "Download canceled cleanly")
 			return nil
 		}
 
@@ -234,6 +303,7 @@ func TUIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 				Filename:   finalFilename,
 				DestPath:   finalDestPath,
 				Err:        downloadErr,
+				TraceID:    cfg.TraceID,
 			})
 		}
 	}
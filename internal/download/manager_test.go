@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/relay"
 	"github.com/surge-downloader/surge/internal/engine/types"
 	"github.com/surge-downloader/surge/internal/processing"
 	"github.com/surge-downloader/surge/internal/testutil"
@@ -749,3 +750,30 @@ func BenchmarkUniqueFilePath_WithConflict(b *testing.B) {
 		uniqueFilePath(path)
 	}
 }
+
+func TestWithRelayMirrors(t *testing.T) {
+	primary := "https://example.com/file.iso"
+	runtime := &types.RuntimeConfig{RelayPeers: []types.RelayPeer{
+		{URL: "http://10.0.0.5:1700", Token: "a"},
+		{URL: "http://10.0.0.6:1700", Token: "b"},
+	}}
+
+	if got := withRelayMirrors([]string{primary}, primary, &types.RuntimeConfig{}); len(got) != 1 {
+		t.Fatalf("without peers got %v", got)
+	}
+
+	got := withRelayMirrors([]string{primary}, primary, runtime)
+	if len(got) != 3 {
+		t.Fatalf("mirrors = %v, want primary plus one relay per peer", got)
+	}
+	for _, m := range got[1:] {
+		if !relay.IsRelayURL(m) {
+			t.Errorf("%s is not a relay URL", m)
+		}
+	}
+
+	// Resumes restore relay mirrors from state; they must not be doubled
+	if again := withRelayMirrors(got, primary, runtime); len(again) != 3 {
+		t.Errorf("mirrors after re-adding = %v", again)
+	}
+}
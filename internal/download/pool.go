@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"path/filepath"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -11,6 +12,8 @@ import (
 	"github.com/surge-downloader/surge/internal/engine/events"
 	"github.com/surge-downloader/surge/internal/engine/state"
 	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/engine/warmup"
+	"github.com/surge-downloader/surge/internal/trace"
 	"github.com/surge-downloader/surge/internal/utils"
 )
 
@@ -28,6 +31,9 @@ type WorkerPool struct {
 	progressDone chan struct{}                   // closed when progressCh must no longer be sent to
 	downloads    map[string]*activeDownload      // Track active downloads for pause/resume
 	queued       map[string]types.DownloadConfig // Track queued downloads
+	queueSeq     map[string]uint64               // Dispatch order of queued downloads
+	nextSeq      uint64
+	prewarmed    map[string]bool // Queued downloads already prewarmed
 	mu           sync.RWMutex
 	wg           sync.WaitGroup // We use this to wait for all active downloads to pause before exiting the program
 	maxDownloads int
@@ -46,6 +52,15 @@ var (
 	cancelStopWaitTimeout = 3 * time.Second
 	// cancelStopPollInterval controls polling cadence while waiting for cancel to take effect.
 	cancelStopPollInterval = 10 * time.Millisecond
+	// prewarmCheckInterval controls how often the pool looks for a slot about to free.
+	prewarmCheckInterval = 1 * time.Second
+	// prewarmLead is how close to finishing a running download must be before
+	// the next queued one is prewarmed.
+	prewarmLead = 5 * time.Second
+	// prewarmTimeout bounds one prewarm of a queued download.
+	prewarmTimeout = 15 * time.Second
+	// prewarmFunc is swapped out by tests
+	prewarmFunc = warmup.Prewarm
 )
 
 func NewWorkerPool(progressCh chan<- any, maxDownloads int) *WorkerPool {
@@ -58,11 +73,14 @@ func NewWorkerPool(progressCh chan<- any, maxDownloads int) *WorkerPool {
 		progressDone: make(chan struct{}),
 		downloads:    make(map[string]*activeDownload),
 		queued:       make(map[string]types.DownloadConfig),
+		queueSeq:     make(map[string]uint64),
+		prewarmed:    make(map[string]bool),
 		maxDownloads: maxDownloads,
 	}
 	for i := 0; i < maxDownloads; i++ {
 		go pool.worker()
 	}
+	go pool.prewarmLoop()
 	return pool
 }
 
@@ -84,6 +102,20 @@ func syncConfigFromState(cfg *types.DownloadConfig) {
 		}
 		cfg.Mirrors = urls
 	}
+	if rate, ok := cfg.State.GetSpeedLimit(); ok {
+		setConfigSpeedLimit(cfg, rate)
+	}
+}
+
+// setConfigSpeedLimit gives cfg its own copy of the runtime config with the
+// speed limit changed, as the old one may be in use by a running download
+func setConfigSpeedLimit(cfg *types.DownloadConfig, rate int64) {
+	runtime := types.RuntimeConfig{}
+	if cfg.Runtime != nil {
+		runtime = *cfg.Runtime
+	}
+	runtime.SpeedLimit = rate
+	cfg.Runtime = &runtime
 }
 
 // resolveDestPath resolves the destination path consistently from config, state, and output bounds.
@@ -106,8 +138,16 @@ func (p *WorkerPool) Add(cfg types.DownloadConfig) {
 	if cfg.ProgressCh == nil {
 		cfg.ProgressCh = p.progressCh
 	}
+	if cfg.TraceID == "" {
+		cfg.TraceID = trace.NewID()
+	}
 	p.mu.Lock()
 	p.queued[cfg.ID] = cfg
+	if p.queueSeq == nil {
+		p.queueSeq = make(map[string]uint64)
+	}
+	p.nextSeq++
+	p.queueSeq[cfg.ID] = p.nextSeq
 	p.mu.Unlock()
 
 	if !cfg.IsResume {
@@ -117,6 +157,10 @@ func (p *WorkerPool) Add(cfg types.DownloadConfig) {
 			URL:        cfg.URL,
 			DestPath:   resolveDestPath(&cfg),
 			Mirrors:    append([]string(nil), cfg.Mirrors...),
+			Tags:       cfg.Tags,
+			Category:   cfg.Category,
+			TraceID:    cfg.TraceID,
+			Overrides:  cfg.Overrides,
 		})
 	}
 
@@ -160,6 +204,13 @@ func (p *WorkerPool) ActiveCount() int {
 	return count
 }
 
+// QueuedCount returns the number of downloads waiting for a free slot
+func (p *WorkerPool) QueuedCount() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.queued)
+}
+
 // GetAll returns all active download configs (for listing)
 func (p *WorkerPool) GetAll() []types.DownloadConfig {
 	p.mu.RLock()
@@ -177,8 +228,13 @@ func (p *WorkerPool) GetAll() []types.DownloadConfig {
 	return configs
 }
 
-// Pause pauses a specific download by ID. Returns true if found and pause initiated (or already paused), false otherwise.
+// Pause pauses a specific download by ID at the user's request. Returns true if found and pause initiated (or already paused), false otherwise.
 func (p *WorkerPool) Pause(downloadID string) bool {
+	return p.PauseFor(downloadID, types.PauseUser)
+}
+
+// PauseFor is Pause for a download stopped for reason, one of the types.Pause* values
+func (p *WorkerPool) PauseFor(downloadID, reason string) bool {
 	p.mu.RLock()
 	ad, exists := p.downloads[downloadID]
 	p.mu.RUnlock()
@@ -201,7 +257,7 @@ func (p *WorkerPool) Pause(downloadID string) bool {
 			return true
 		}
 		ad.config.State.SetPausing(true) // Mark as transitioning to pause
-		ad.config.State.Pause()
+		ad.config.State.PauseFor(reason)
 	}
 	// Always cancel worker context as a safety net (single downloader does not set state cancel itself).
 	if ad.cancel != nil {
@@ -226,12 +282,36 @@ func (p *WorkerPool) PauseAll() {
 	p.mu.RUnlock()
 
 	for _, id := range ids {
-		p.Pause(id)
+		p.PauseFor(id, types.PauseShutdown)
 	}
 }
 
 // Cancel cancels and removes a download by ID
 func (p *WorkerPool) Cancel(downloadID string) {
+	p.remove(downloadID, "")
+}
+
+// Archive removes a download by ID like Cancel, but keeps its partial data so
+// it can be restored later. A running download is paused first so its resume
+// state is saved before it is removed.
+func (p *WorkerPool) Archive(downloadID string) {
+	p.remove(downloadID, types.PauseArchived)
+}
+
+// Trash removes a download by ID into the trash. Like Archive it keeps the
+// data, completed or not, so the download can be restored until it expires.
+func (p *WorkerPool) Trash(downloadID string) {
+	p.remove(downloadID, types.PauseTrashed)
+}
+
+// remove takes a download out of the pool. keep is the pause reason of a
+// download whose data is kept (PauseArchived or PauseTrashed), or empty to
+// cancel it outright.
+func (p *WorkerPool) remove(downloadID string, keep string) {
+	if keep != "" {
+		p.PauseFor(downloadID, keep)
+	}
+
 	p.mu.Lock()
 	ad, activeExists := p.downloads[downloadID]
 	qCfg, queuedExists := p.queued[downloadID]
@@ -239,7 +319,7 @@ func (p *WorkerPool) Cancel(downloadID string) {
 		delete(p.downloads, downloadID)
 	}
 	if queuedExists {
-		delete(p.queued, downloadID)
+		p.dequeueLocked(downloadID)
 	}
 	p.mu.Unlock()
 
@@ -255,8 +335,8 @@ func (p *WorkerPool) Cancel(downloadID string) {
 		removedDestPath = resolveDestPath(&ad.config)
 		removedCompleted = ad.config.State != nil && ad.config.State.Done.Load()
 
-		// Cancel the context to stop workers
-		if ad.cancel != nil {
+		// Cancel the context to stop workers; a download whose data is kept was paused instead
+		if keep == "" && ad.cancel != nil {
 			ad.cancel()
 		}
 
@@ -282,6 +362,8 @@ func (p *WorkerPool) Cancel(downloadID string) {
 		Filename:   removedFilename,
 		DestPath:   removedDestPath,
 		Completed:  removedCompleted,
+		Archived:   keep == types.PauseArchived && !removedCompleted,
+		Trashed:    keep == types.PauseTrashed,
 	})
 }
 
@@ -326,6 +408,11 @@ func (p *WorkerPool) Resume(downloadID string) bool {
 			}
 		}
 	}
+	if ad.config.Probe == nil {
+		if probe, err := state.GetProbeCache(ad.config.ID); err == nil {
+			ad.config.Probe = probe
+		}
+	}
 
 	// Re-queue the download
 	ad.config.IsResume = true
@@ -339,6 +426,26 @@ func (p *WorkerPool) Resume(downloadID string) bool {
 	return true
 }
 
+// SetSpeedLimit changes a download's own speed limit in bytes/sec (0 =
+// unlimited): at once when it is running, and from its next start when it is
+// queued or paused. It returns false when the download is not in the pool.
+func (p *WorkerPool) SetSpeedLimit(downloadID string, rate int64) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cfg, queued := p.queued[downloadID]
+	if queued {
+		setConfigSpeedLimit(&cfg, rate)
+		p.queued[downloadID] = cfg
+	}
+	ad, exists := p.downloads[downloadID]
+	if exists && ad != nil && ad.config.State != nil {
+		// Picked up by syncConfigFromState when it resumes
+		ad.config.State.ApplySpeedLimit(rate)
+	}
+	return queued || exists
+}
+
 // UpdateURL updates the URL of a download by ID.
 // It fails if the download is actively downloading (not paused or errored).
 func (p *WorkerPool) UpdateURL(downloadID string, newURL string) error {
@@ -369,19 +476,40 @@ func (p *WorkerPool) UpdateURL(downloadID string, newURL string) error {
 	return state.UpdateURL(downloadID, newURL)
 }
 
+// Relocate points a paused or finished download held in the pool at the
+// path its file was moved to, so a resume writes there. It does nothing for
+// downloads the pool doesn't hold.
+func (p *WorkerPool) Relocate(downloadID, destPath, filename string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ad, exists := p.downloads[downloadID]
+	if !exists || ad == nil {
+		return
+	}
+	ad.config.DestPath = destPath
+	ad.config.Filename = filename
+	if ad.config.State != nil {
+		ad.config.State.SetDestPath(destPath)
+		ad.config.State.SetFilename(filename)
+	}
+}
+
 func (p *WorkerPool) worker() {
-	for cfg := range p.taskChan {
-		p.mu.RLock()
-		_, stillQueued := p.queued[cfg.ID]
-		p.mu.RUnlock()
-		if !stillQueued {
+	// Every Add sends one config, but what runs is the queued download first
+	// in dispatch order, so a Reorder counts for downloads still waiting
+	for range p.taskChan {
+		p.mu.Lock()
+		cfg, ok := p.nextQueuedLocked()
+		if !ok {
 			// Canceled while waiting in queue.
+			p.mu.Unlock()
 			continue
 		}
 
 		p.wg.Add(1)
 		// Create cancellable context
-		ctx, cancel := context.WithCancel(context.Background())
+		ctx, cancel := context.WithCancel(trace.WithID(context.Background(), cfg.TraceID))
 
 		// Register active download
 		ad := &activeDownload{
@@ -392,8 +520,7 @@ func (p *WorkerPool) worker() {
 			ad.config.State.SetCancelFunc(cancel)
 		}
 		ad.running.Store(true)
-		p.mu.Lock()
-		delete(p.queued, cfg.ID)
+		p.dequeueLocked(cfg.ID)
 		p.downloads[cfg.ID] = ad
 		p.mu.Unlock()
 
@@ -412,9 +539,10 @@ func (p *WorkerPool) worker() {
 		}
 
 		if isPaused {
-			utils.Debug("WorkerPool: Download %s paused cleanly", cfg.ID)
+			trace.Debug(ctx, "WorkerPool: Download %s paused cleanly", cfg.ID)
 			// If paused, we keep it in downloads map for potential resume
 		} else if err != nil {
+			trace.Debug(ctx, "WorkerPool: Download %s failed: %v", cfg.ID, err)
 			if cfg.State != nil {
 				cfg.State.SetError(err)
 			}
@@ -423,6 +551,7 @@ func (p *WorkerPool) worker() {
 				Filename:   cfg.Filename,
 				DestPath:   resolveDestPath(&cfg),
 				Err:        err,
+				TraceID:    cfg.TraceID,
 			})
 			// Clean up errored download from tracking (don't save to .surge)
 			p.mu.Lock()
@@ -446,6 +575,159 @@ func (p *WorkerPool) worker() {
 	}
 }
 
+// dequeueLocked forgets a queued download. Callers hold p.mu.
+func (p *WorkerPool) dequeueLocked(id string) {
+	delete(p.queued, id)
+	delete(p.queueSeq, id)
+	delete(p.prewarmed, id)
+}
+
+// nextQueuedLocked returns the queued download that is dispatched next: the
+// highest priority one, first in dispatch order among those. Callers hold
+// p.mu.
+func (p *WorkerPool) nextQueuedLocked() (types.DownloadConfig, bool) {
+	var next types.DownloadConfig
+	var nextSeq uint64
+	for id, cfg := range p.queued {
+		seq := p.queueSeq[id]
+		if nextSeq == 0 {
+			next, nextSeq = cfg, seq
+			continue
+		}
+		priority, nextPriority := cfg.Overrides.GetPriority(), next.Overrides.GetPriority()
+		if priority > nextPriority || (priority == nextPriority && seq < nextSeq) {
+			next, nextSeq = cfg, seq
+		}
+	}
+	return next, nextSeq != 0
+}
+
+// SetPriority changes the priority a queued download is dispatched with,
+// and a paused one once it is resumed. It returns false when the download
+// is not in the pool.
+func (p *WorkerPool) SetPriority(downloadID string, priority int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cfg, queued := p.queued[downloadID]
+	if queued {
+		setConfigPriority(&cfg, priority)
+		p.queued[downloadID] = cfg
+	}
+	ad, exists := p.downloads[downloadID]
+	if exists && ad != nil {
+		setConfigPriority(&ad.config, priority)
+	}
+	return queued || exists
+}
+
+// setConfigPriority sets priority on a copy of cfg's overrides, which the
+// config it was copied from may share
+func setConfigPriority(cfg *types.DownloadConfig, priority int) {
+	overrides := types.DownloadOverrides{}
+	if cfg.Overrides != nil {
+		overrides = *cfg.Overrides
+	}
+	overrides.Priority = priority
+	cfg.Overrides = &overrides
+}
+
+// Reorder dispatches the queued downloads among ids in the order given. The
+// dispatch slots they held are handed out again in that order, so downloads
+// not listed keep their place.
+func (p *WorkerPool) Reorder(ids []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var listed []string
+	var slots []uint64
+	for _, id := range ids {
+		if _, ok := p.queued[id]; ok {
+			listed = append(listed, id)
+			slots = append(slots, p.queueSeq[id])
+		}
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i] < slots[j] })
+	for i, id := range listed {
+		p.queueSeq[id] = slots[i]
+	}
+}
+
+// prewarmLoop warms up the next queued download once every slot is busy and
+// one of the running downloads is about to finish, so the queued one skips
+// the DNS lookup and full TLS handshake when it is dispatched.
+func (p *WorkerPool) prewarmLoop() {
+	ticker := time.NewTicker(prewarmCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.progressDone:
+			return
+		case <-ticker.C:
+			if cfg, ok := p.nextToPrewarm(); ok {
+				go p.prewarm(cfg)
+			}
+		}
+	}
+}
+
+// nextToPrewarm returns the download that will get the next free slot, if it
+// wants prewarming, hasn't had it yet, and a slot is about to free
+func (p *WorkerPool) nextToPrewarm() (types.DownloadConfig, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	next, ok := p.nextQueuedLocked()
+	if !ok || p.prewarmed[next.ID] || next.Runtime == nil || !next.Runtime.PrewarmConnections {
+		return types.DownloadConfig{}, false
+	}
+
+	running := 0
+	soonest := time.Duration(-1)
+	for _, ad := range p.downloads {
+		if !ad.running.Load() || ad.config.State == nil || ad.config.State.IsPaused() {
+			continue
+		}
+		running++
+		if eta, ok := remainingTime(ad.config.State); ok && (soonest < 0 || eta < soonest) {
+			soonest = eta
+		}
+	}
+	// With a free slot the download is dispatched right away; there is
+	// nothing to overlap the warm-up with.
+	if running < p.maxDownloads || soonest < 0 || soonest > prewarmLead {
+		return types.DownloadConfig{}, false
+	}
+
+	p.prewarmed[next.ID] = true
+	return next, true
+}
+
+func (p *WorkerPool) prewarm(cfg types.DownloadConfig) {
+	ctx, cancel := context.WithTimeout(trace.WithID(context.Background(), cfg.TraceID), prewarmTimeout)
+	defer cancel()
+	if err := prewarmFunc(ctx, cfg.URL, cfg.Runtime); err != nil {
+		trace.Debug(ctx, "WorkerPool: prewarm of %s failed: %v", cfg.ID, err)
+		return
+	}
+	trace.Debug(ctx, "WorkerPool: prewarmed %s", cfg.ID)
+}
+
+// remainingTime estimates how long a running download needs to finish from
+// its speed this session
+func remainingTime(st *types.ProgressState) (time.Duration, bool) {
+	downloaded, total, _, sessionElapsed, _, sessionStart := st.GetProgress()
+	sessionDownloaded := downloaded - sessionStart
+	if total <= 0 || sessionElapsed <= 0 || sessionDownloaded <= 0 {
+		return 0, false
+	}
+	if downloaded >= total {
+		return 0, true
+	}
+	bytesPerSec := float64(sessionDownloaded) / sessionElapsed.Seconds()
+	return time.Duration(float64(total-downloaded) / bytesPerSec * float64(time.Second)), true
+}
+
 // GetStatus returns the status of an active download
 func (p *WorkerPool) GetStatus(id string) *types.DownloadStatus {
 	p.mu.RLock()
@@ -466,6 +748,9 @@ func (p *WorkerPool) GetStatus(id string) *types.DownloadStatus {
 			Status:     "queued",
 			Downloaded: 0,
 			TotalSize:  0, // Metadata not yet fetched
+			Tags:       qCfg.Tags,
+			Category:   qCfg.Category,
+			TraceID:    qCfg.TraceID,
 		}
 	}
 
@@ -490,6 +775,9 @@ func (p *WorkerPool) GetStatus(id string) *types.DownloadStatus {
 		TotalSize:  totalSize,
 		Downloaded: downloaded,
 		Status:     "downloading",
+		Tags:       ad.config.Tags,
+		Category:   ad.config.Category,
+		TraceID:    ad.config.TraceID,
 	}
 	if dp := state.GetDestPath(); dp != "" {
 		status.DestPath = dp
@@ -501,6 +789,7 @@ func (p *WorkerPool) GetStatus(id string) *types.DownloadStatus {
 		status.Status = "pausing"
 	} else if ad.config.State.IsPaused() {
 		status.Status = "paused"
+		status.PauseReason = ad.config.State.PauseReason()
 	} else if state.Done.Load() {
 		status.Status = "completed"
 	}
@@ -527,6 +816,23 @@ func (p *WorkerPool) GetStatus(id string) *types.DownloadStatus {
 	return status
 }
 
+// GetChunkMap returns the chunk map of a download in the pool, nil when it
+// is not in it. Queued downloads have no chunks yet.
+func (p *WorkerPool) GetChunkMap(id string) *types.ChunkMap {
+	p.mu.RLock()
+	ad, exists := p.downloads[id]
+	_, queued := p.queued[id]
+	p.mu.RUnlock()
+
+	if queued {
+		return &types.ChunkMap{ID: id, Workers: []types.WorkerStatus{}}
+	}
+	if !exists || ad.config.State == nil {
+		return nil
+	}
+	return ad.config.State.GetChunkMap()
+}
+
 // trySendProgress sends msg on progressCh unless progressDone has been closed,
 // preventing a panic from sending on a closed channel after shutdown.
 func (p *WorkerPool) trySendProgress(msg any) {
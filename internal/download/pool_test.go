@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
 	"testing"
 	"time"
@@ -1082,3 +1083,173 @@ func TestWorkerPool_UpdateURL_PersistsToDB(t *testing.T) {
 		t.Fatalf("db-only entry not updated in db: %#v", entry)
 	}
 }
+
+func TestWorkerPool_NextToPrewarm_WaitsForSlotAboutToFree(t *testing.T) {
+	warm := &types.RuntimeConfig{PrewarmConnections: true}
+	pool := &WorkerPool{
+		downloads:    make(map[string]*activeDownload),
+		queued:       make(map[string]types.DownloadConfig),
+		prewarmed:    make(map[string]bool),
+		maxDownloads: 1,
+	}
+
+	running := types.NewProgressState("running", 100*types.MB)
+	running.StartTime = time.Now().Add(-time.Second)
+	running.VerifiedProgress.Store(types.MB)
+	ad := &activeDownload{config: types.DownloadConfig{ID: "running", State: running}}
+	ad.running.Store(true)
+	pool.downloads["running"] = ad
+
+	pool.taskChan = make(chan types.DownloadConfig, 10)
+	pool.Add(types.DownloadConfig{ID: "first", URL: "https://a.example/f", Runtime: warm})
+	pool.Add(types.DownloadConfig{ID: "second", URL: "https://b.example/f", Runtime: warm})
+
+	// 99 MB left at 1 MB/s: the slot is far from free
+	if _, ok := pool.nextToPrewarm(); ok {
+		t.Fatal("prewarmed while the running download still has minutes left")
+	}
+
+	running.VerifiedProgress.Store(100*types.MB - types.KB)
+	cfg, ok := pool.nextToPrewarm()
+	if !ok || cfg.ID != "first" {
+		t.Fatalf("nextToPrewarm = %q, %v; want first queued download", cfg.ID, ok)
+	}
+	if _, ok := pool.nextToPrewarm(); ok {
+		t.Fatal("the same queued download was prewarmed twice")
+	}
+}
+
+func TestWorkerPool_NextToPrewarm_RequiresSetting(t *testing.T) {
+	pool := &WorkerPool{
+		taskChan:     make(chan types.DownloadConfig, 10),
+		downloads:    make(map[string]*activeDownload),
+		queued:       make(map[string]types.DownloadConfig),
+		prewarmed:    make(map[string]bool),
+		maxDownloads: 1,
+	}
+	running := types.NewProgressState("running", types.MB)
+	running.StartTime = time.Now().Add(-time.Second)
+	running.VerifiedProgress.Store(types.MB - types.KB)
+	ad := &activeDownload{config: types.DownloadConfig{ID: "running", State: running}}
+	ad.running.Store(true)
+	pool.downloads["running"] = ad
+
+	pool.Add(types.DownloadConfig{ID: "queued", URL: "https://a.example/f", Runtime: &types.RuntimeConfig{}})
+	if _, ok := pool.nextToPrewarm(); ok {
+		t.Fatal("prewarmed a download whose settings leave prewarming off")
+	}
+}
+
+func TestWorkerPool_Reorder_ChangesDispatchOrder(t *testing.T) {
+	pool := &WorkerPool{
+		taskChan:  make(chan types.DownloadConfig, 10),
+		downloads: make(map[string]*activeDownload),
+		queued:    make(map[string]types.DownloadConfig),
+		prewarmed: make(map[string]bool),
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		pool.Add(types.DownloadConfig{ID: id, URL: "https://example.com/" + id})
+	}
+
+	// Not queued ids are skipped; the listed ones swap their slots
+	pool.Reorder([]string{"c", "gone", "a"})
+
+	var order []string
+	for range 3 {
+		pool.mu.Lock()
+		cfg, ok := pool.nextQueuedLocked()
+		if ok {
+			pool.dequeueLocked(cfg.ID)
+		}
+		pool.mu.Unlock()
+		if !ok {
+			t.Fatalf("queue ran out after %v", order)
+		}
+		order = append(order, cfg.ID)
+	}
+	if want := []string{"c", "b", "a"}; !slices.Equal(order, want) {
+		t.Errorf("dispatch order = %v, want %v", order, want)
+	}
+}
+
+func TestWorkerPool_SetSpeedLimit(t *testing.T) {
+	ch := make(chan any, 10)
+	pool := NewWorkerPool(ch, 3)
+
+	sharedRuntime := &types.RuntimeConfig{SpeedLimit: 100}
+	state := types.NewProgressState("active-id", 1000)
+	var applied []int64
+	state.SetSpeedLimiter(func(rate int64) { applied = append(applied, rate) })
+
+	pool.mu.Lock()
+	pool.downloads["active-id"] = &activeDownload{
+		config: types.DownloadConfig{ID: "active-id", State: state, Runtime: sharedRuntime},
+	}
+	pool.queued["queued-id"] = types.DownloadConfig{ID: "queued-id", Runtime: sharedRuntime}
+	pool.mu.Unlock()
+
+	if !pool.SetSpeedLimit("active-id", 2048) {
+		t.Fatal("SetSpeedLimit(active-id) = false")
+	}
+	if len(applied) != 1 || applied[0] != 2048 {
+		t.Fatalf("running limiter got %v, want [2048]", applied)
+	}
+
+	// A resume picks the limit up from the state
+	cfg := pool.downloads["active-id"].config
+	syncConfigFromState(&cfg)
+	if cfg.Runtime.SpeedLimit != 2048 {
+		t.Errorf("resumed speed limit = %d, want 2048", cfg.Runtime.SpeedLimit)
+	}
+
+	if !pool.SetSpeedLimit("queued-id", 4096) {
+		t.Fatal("SetSpeedLimit(queued-id) = false")
+	}
+	if got := pool.queued["queued-id"].Runtime.SpeedLimit; got != 4096 {
+		t.Errorf("queued speed limit = %d, want 4096", got)
+	}
+	if sharedRuntime.SpeedLimit != 100 {
+		t.Errorf("shared runtime config changed to %d", sharedRuntime.SpeedLimit)
+	}
+
+	if pool.SetSpeedLimit("missing", 1) {
+		t.Error("SetSpeedLimit(missing) = true")
+	}
+}
+
+func TestWorkerPool_SetPriority_DispatchesHighFirst(t *testing.T) {
+	pool := &WorkerPool{
+		taskChan:  make(chan types.DownloadConfig, 10),
+		downloads: make(map[string]*activeDownload),
+		queued:    make(map[string]types.DownloadConfig),
+		prewarmed: make(map[string]bool),
+	}
+	low := &types.DownloadOverrides{Priority: types.PriorityLow}
+	pool.Add(types.DownloadConfig{ID: "a", URL: "https://example.com/a", Overrides: low})
+	pool.Add(types.DownloadConfig{ID: "b", URL: "https://example.com/b"})
+	pool.Add(types.DownloadConfig{ID: "c", URL: "https://example.com/c"})
+
+	if !pool.SetPriority("c", types.PriorityHigh) {
+		t.Fatal("SetPriority(c) = false")
+	}
+	if pool.SetPriority("gone", types.PriorityHigh) {
+		t.Error("SetPriority(gone) = true for a download not in the pool")
+	}
+
+	var order []string
+	for range 3 {
+		pool.mu.Lock()
+		cfg, ok := pool.nextQueuedLocked()
+		if ok {
+			pool.dequeueLocked(cfg.ID)
+		}
+		pool.mu.Unlock()
+		if !ok {
+			t.Fatalf("queue ran out after %v", order)
+		}
+		order = append(order, cfg.ID)
+	}
+	if want := []string{"c", "b", "a"}; !slices.Equal(order, want) {
+		t.Errorf("dispatch order = %v, want %v", order, want)
+	}
+}
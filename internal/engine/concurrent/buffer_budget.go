@@ -0,0 +1,107 @@
+package concurrent
+
+import (
+	"context"
+	"sync"
+)
+
+// bufferBudget caps the memory held in worker buffers across every download
+// in the process. Workers that would exceed it wait for a buffer to be freed.
+var bufferBudget = newMemoryBudget()
+
+type memoryBudget struct {
+	mu      sync.Mutex
+	limit   int64 // 0 = unlimited
+	used    int64
+	waiting int
+	wake    chan struct{} // Closed and replaced whenever memory is freed or the limit changes
+}
+
+func newMemoryBudget() *memoryBudget {
+	return &memoryBudget{wake: make(chan struct{})}
+}
+
+// configure sets the budget in bytes (0 = unlimited). Waiters are re-checked
+// against the new limit.
+func (b *memoryBudget) configure(limit int64) {
+	if limit < 0 {
+		limit = 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.limit == limit {
+		return
+	}
+	b.limit = limit
+	b.broadcastLocked()
+}
+
+// acquire reserves n bytes, blocking until they fit or ctx is done. A request
+// larger than the whole budget is let through when nothing else is held so a
+// misconfigured cap cannot stall downloads forever.
+func (b *memoryBudget) acquire(ctx context.Context, n int64) error {
+	for {
+		b.mu.Lock()
+		if b.limit <= 0 || b.used+n <= b.limit || b.used == 0 {
+			b.used += n
+			b.mu.Unlock()
+			return nil
+		}
+		wake := b.wake
+		b.waiting++
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			b.mu.Lock()
+			b.waiting--
+			b.mu.Unlock()
+			return ctx.Err()
+		case <-wake:
+			b.mu.Lock()
+			b.waiting--
+			b.mu.Unlock()
+		}
+	}
+}
+
+// release returns n bytes taken by acquire
+func (b *memoryBudget) release(n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.used -= n
+	if b.used < 0 {
+		b.used = 0
+	}
+	b.broadcastLocked()
+}
+
+func (b *memoryBudget) broadcastLocked() {
+	close(b.wake)
+	b.wake = make(chan struct{})
+}
+
+// snapshot returns the limit, bytes held and number of blocked workers
+func (b *memoryBudget) snapshot() (limit, used int64, waiting int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.limit, b.used, b.waiting
+}
+
+// getBuffer takes a worker buffer from the pool once the shared budget allows it
+func (d *ConcurrentDownloader) getBuffer(ctx context.Context) (*[]byte, error) {
+	size := int64(d.Runtime.GetWorkerBufferSize())
+	if err := bufferBudget.acquire(ctx, size); err != nil {
+		return nil, err
+	}
+	bufPtr := d.bufPool.Get().(*[]byte)
+	buffersInUse.Add(1)
+	return bufPtr, nil
+}
+
+// putBuffer returns a buffer from getBuffer to the pool and the budget
+func (d *ConcurrentDownloader) putBuffer(bufPtr *[]byte) {
+	buffersInUse.Add(-1)
+	d.bufPool.Put(bufPtr)
+	bufferBudget.release(int64(d.Runtime.GetWorkerBufferSize()))
+}
@@ -0,0 +1,136 @@
+package concurrent
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestMemoryBudget_BlocksAtLimit(t *testing.T) {
+	b := newMemoryBudget()
+	b.configure(100)
+
+	if err := b.acquire(context.Background(), 60); err != nil {
+		t.Fatalf("first acquire failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := b.acquire(ctx, 60); err == nil {
+		t.Fatal("expected acquire over the limit to block until the context expired")
+	}
+
+	acquired := make(chan error, 1)
+	go func() { acquired <- b.acquire(context.Background(), 60) }()
+	b.release(60)
+
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatalf("acquire after release failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("release did not wake the waiting acquire")
+	}
+	if _, used, waiting := b.snapshot(); used != 60 || waiting != 0 {
+		t.Errorf("expected 60 bytes held and no waiters, got %d, %d", used, waiting)
+	}
+}
+
+func TestMemoryBudget_OversizeAllowedWhenIdle(t *testing.T) {
+	b := newMemoryBudget()
+	b.configure(10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := b.acquire(ctx, 50); err != nil {
+		t.Fatalf("expected a lone oversize buffer to be allowed, got %v", err)
+	}
+}
+
+func TestMemoryBudget_RaisingLimitWakesWaiters(t *testing.T) {
+	b := newMemoryBudget()
+	b.configure(10)
+	_ = b.acquire(context.Background(), 10)
+
+	acquired := make(chan error, 1)
+	go func() { acquired <- b.acquire(context.Background(), 10) }()
+	time.Sleep(20 * time.Millisecond)
+	b.configure(0)
+
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatalf("acquire failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("lifting the limit did not wake the waiting acquire")
+	}
+}
+
+func TestConcurrentDownloader_BufferBudgetLimitsInFlightRequests(t *testing.T) {
+	tmpDir, cleanup := initTestState(t)
+	defer cleanup()
+	defer bufferBudget.configure(0)
+
+	fileSize := int64(512 * types.KB)
+	content := bytes.Repeat([]byte("budget"), int(fileSize)/6+1)[:fileSize]
+
+	var inFlight, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		http.ServeContent(w, r, "budget.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	destPath := filepath.Join(tmpDir, "budget.bin")
+	if f, err := os.Create(destPath + types.IncompleteSuffix); err == nil {
+		_ = f.Close()
+	}
+
+	// Room for exactly one worker buffer despite four connections
+	runtime := &types.RuntimeConfig{
+		MaxConnectionsPerHost: 4,
+		MinChunkSize:          64 * types.KB,
+		WorkerBufferSize:      32 * types.KB,
+		MaxBufferMemory:       32 * types.KB,
+	}
+	state := types.NewProgressState("budget", fileSize)
+	downloader := NewConcurrentDownloader("budget-id", nil, state, runtime)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := downloader.Download(ctx, server.URL, nil, nil, destPath, fileSize); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+
+	if got := peak.Load(); got != 1 {
+		t.Errorf("expected the budget to serialise requests, saw %d in flight", got)
+	}
+	got, err := os.ReadFile(destPath + types.IncompleteSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Error("downloaded content does not match")
+	}
+	if _, used, _ := bufferBudget.snapshot(); used != 0 {
+		t.Errorf("expected all buffer memory released, %d bytes still held", used)
+	}
+}
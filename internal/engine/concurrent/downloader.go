@@ -10,12 +10,16 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/ratelimit"
 	"github.com/surge-downloader/surge/internal/engine/state"
 	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/engine/warmup"
 	"github.com/surge-downloader/surge/internal/utils"
 )
 
@@ -30,7 +34,10 @@ type ConcurrentDownloader struct {
 	DestPath     string // For pause/resume
 	Runtime      *types.RuntimeConfig
 	bufPool      sync.Pool
-	Headers      map[string]string // Custom HTTP headers from browser (cookies, auth, etc.)
+	Headers      map[string]string  // Custom HTTP headers from browser (cookies, auth, etc.)
+	mirrorScores *mirrorScoreboard  // Per-mirror throughput/error tracking
+	workerErrors *workerErrorBoard  // Per-worker failure counts for the debug heatmap
+	limiter      *ratelimit.Limiter // This download's own speed limit, on top of the global one
 }
 
 // NewConcurrentDownloader creates a new concurrent downloader with all required parameters
@@ -49,11 +56,16 @@ func NewConcurrentDownloader(id string, progressCh chan<- any, progState *types.
 		State:        progState,
 		activeTasks:  make(map[int]*ActiveTask),
 		Runtime:      runtime,
+		mirrorScores: newMirrorScoreboard(),
+		workerErrors: newWorkerErrorBoard(),
+		limiter:      ratelimit.NewLimiter(runtime.GetSpeedLimit()),
 		bufPool: sync.Pool{
 			New: func() any {
 				// Use configured buffer size
 				size := runtime.GetWorkerBufferSize()
 				buf := make([]byte, size)
+				buffersAllocated.Add(1)
+				bufferBytes.Add(int64(size))
 				return &buf
 			},
 		},
@@ -118,6 +130,25 @@ func (d *ConcurrentDownloader) ReportMirrorError(url string) {
 	}
 }
 
+// workerStatuses lists the range each worker is fetching, for the chunk map
+func (d *ConcurrentDownloader) workerStatuses() []types.WorkerStatus {
+	d.activeMu.Lock()
+	defer d.activeMu.Unlock()
+	workers := make([]types.WorkerStatus, 0, len(d.activeTasks))
+	for id, active := range d.activeTasks {
+		workers = append(workers, types.WorkerStatus{
+			Worker: id,
+			Start:  active.Task.Offset,
+			Offset: active.effectiveOffset(),
+			End:    active.StopAt.Load(),
+			Speed:  active.GetSpeed(),
+			Mirror: active.URL,
+		})
+	}
+	sort.Slice(workers, func(i, j int) bool { return workers[i].Worker < workers[j].Worker })
+	return workers
+}
+
 // calculateChunkSize determines optimal chunk size
 func (d *ConcurrentDownloader) calculateChunkSize(fileSize int64, numConns int) int64 {
 	// Safety check
@@ -222,11 +253,14 @@ func (d *ConcurrentDownloader) newConcurrentClient(numConns int) *http.Client {
 		ForceAttemptHTTP2:  false, // FORCE HTTP/1.1 for multiple TCP connections
 		TLSNextProto:       make(map[string]func(authority string, c *tls.Conn) http.RoundTripper),
 
+		// Resume TLS sessions opened by prewarming the queue
+		TLSClientConfig: warmup.TLSConfig(),
+
 		// Dial settings for TCP reliability
-		DialContext: (&net.Dialer{
+		DialContext: warmup.DialContext(&net.Dialer{
 			Timeout:   types.DialTimeout,
 			KeepAlive: types.KeepAliveDuration,
-		}).DialContext,
+		}),
 	}
 
 	return &http.Client{
@@ -262,6 +296,9 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 	d.URL = rawurl
 	d.DestPath = destPath
 
+	ratelimit.Global.Configure(d.Runtime.GetGlobalRateLimit(), d.Runtime.RateLimitExemptHosts)
+	bufferBudget.configure(d.Runtime.GetMaxBufferMemory())
+
 	// Initialize mirror status in state
 	if d.State != nil {
 		d.State.SetURL(rawurl)
@@ -289,6 +326,10 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 		}
 
 		d.State.SetMirrors(statuses)
+		d.State.SetWorkerSource(d.workerStatuses)
+		defer d.State.SetWorkerSource(nil)
+		d.State.SetSpeedLimiter(d.limiter.SetRate)
+		defer d.State.SetSpeedLimiter(nil)
 	}
 
 	// Working file has .surge suffix until download completes
@@ -309,6 +350,8 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 
 	// Determine connections and chunk size
 	numConns := d.getInitialConnections(fileSize)
+	// Respect connection caps these hosts advertised in earlier responses
+	numConns = capConnectionsForHosts(numConns, append([]string{rawurl}, activeMirrors...))
 	chunkSize := d.determineChunkSize(fileSize, numConns)
 
 	// Create tuned HTTP client for concurrent downloads
@@ -375,7 +418,7 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 		utils.Debug("Resuming from saved state: %d tasks, %d bytes downloaded", len(tasks), savedState.Downloaded)
 	} else {
 		// Fresh download: preallocate file and create new tasks
-		if err := outFile.Truncate(fileSize); err != nil {
+		if err := utils.PreallocateFile(outFile, fileSize, d.Runtime.DensePreallocation); err != nil {
 			return fmt.Errorf("failed to preallocate file: %w", err)
 		}
 		// Robustness: ensure state counter starts at 0 for fresh download
@@ -386,6 +429,8 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 	}
 	queue := NewTaskQueue()
 	queue.PushMultiple(tasks)
+	liveQueues.Store(queue, struct{}{})
+	defer liveQueues.Delete(queue)
 
 	// Start balancer goroutine for dynamic chunk splitting
 	balancerCtx, cancelBalancer := context.WithCancel(downloadCtx)
@@ -512,8 +557,14 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 	// Wait for all workers to complete
 	go func() {
 		wg.Wait()
+		// Publish the error summary before the download is reported finished
+		d.workerErrors.logSummary(d.ID)
+		if d.State != nil {
+			d.State.SetWorkerErrors(d.workerErrors.snapshot())
+		}
 		close(workerErrors)
 		queue.Close()
+		d.mirrorScores.logSummary(d.ID)
 	}()
 
 	// Check for errors or pause
@@ -528,8 +579,15 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 	if d.State != nil && d.State.IsPaused() {
 		// 1. Collect active tasks as remaining work FIRST
 		var activeRemaining []types.Task
+		raced := make(map[*atomic.Int64]bool) // Racing workers share one range; save it once
 		d.activeMu.Lock()
 		for _, active := range d.activeTasks {
+			if active.SharedMaxOffset != nil {
+				if raced[active.SharedMaxOffset] {
+					continue
+				}
+				raced[active.SharedMaxOffset] = true
+			}
 			if remaining := active.RemainingTask(); remaining != nil {
 				activeRemaining = append(activeRemaining, *remaining)
 			}
@@ -582,6 +640,7 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 				DownloadID: d.ID,
 				Filename:   filepath.Base(destPath),
 				Downloaded: computedDownloaded,
+				Reason:     d.State.PauseReason(),
 				State:      s,
 			}
 		}
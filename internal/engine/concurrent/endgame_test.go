@@ -0,0 +1,132 @@
+package concurrent
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestActiveTask_RemainingTaskCountsRacerProgress(t *testing.T) {
+	shared := &atomic.Int64{}
+	shared.Store(600)
+
+	at := &ActiveTask{SharedMaxOffset: shared}
+	at.CurrentOffset.Store(400)
+	at.StopAt.Store(1000)
+
+	remaining := at.RemainingTask()
+	if remaining == nil || remaining.Offset != 600 || remaining.Length != 400 {
+		t.Fatalf("expected remaining 600+400, got %+v", remaining)
+	}
+
+	shared.Store(1000)
+	if at.RemainingTask() != nil || at.RemainingBytes() != 0 {
+		t.Error("expected no remaining work once a racer covered the range")
+	}
+}
+
+func TestHedgeWork_PrefersStalledTaskAndOtherMirror(t *testing.T) {
+	d := NewConcurrentDownloader("hedge", nil, nil, &types.RuntimeConfig{})
+
+	fast := &ActiveTask{URL: "http://a.example/file", Speed: 10 * float64(types.MB)}
+	fast.CurrentOffset.Store(0)
+	fast.StopAt.Store(4 * types.MB)
+	fast.LastActivity.Store(time.Now().UnixNano())
+
+	stalled := &ActiveTask{URL: "http://b.example/file"}
+	stalled.CurrentOffset.Store(8 * types.MB)
+	stalled.StopAt.Store(9 * types.MB)
+
+	d.activeTasks[0] = fast
+	d.activeTasks[1] = stalled
+
+	queue := NewTaskQueue()
+	if !d.HedgeWork(queue) {
+		t.Fatal("expected a hedge")
+	}
+	task, _ := queue.Pop()
+	if task.Offset != 8*types.MB || task.AvoidMirror != "http://b.example/file" {
+		t.Errorf("expected hedge of the stalled task avoiding its mirror, got %+v", task)
+	}
+	if task.SharedMaxOffset == nil || task.SharedMaxOffset != stalled.SharedMaxOffset {
+		t.Error("expected hedge to share progress tracking with the original")
+	}
+}
+
+func TestConcurrentDownloader_EndGameRaceBeatsStalledConnection(t *testing.T) {
+	tmpDir, cleanup := initTestState(t)
+	defer cleanup()
+
+	fileSize := int64(3 * types.MB)
+	content := bytes.Repeat([]byte("surge-endgame"), int(fileSize)/13+1)[:fileSize]
+
+	// The first request for the start of the file stalls after a little data;
+	// any later request (the racing copy) is served normally.
+	var stalledOnce atomic.Bool
+	var firstChunkRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.Header.Get("Range"), "bytes=0-") {
+			firstChunkRequests.Add(1)
+			if stalledOnce.CompareAndSwap(false, true) {
+				w.Header().Set("Content-Range", "bytes 0-1048575/3145728")
+				w.Header().Set("Content-Length", "1048576")
+				w.WriteHeader(http.StatusPartialContent)
+				_, _ = w.Write(content[:64*types.KB])
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+				return
+			}
+		}
+		http.ServeContent(w, r, "endgame.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	destPath := filepath.Join(tmpDir, "endgame.bin")
+	if f, err := os.Create(destPath + types.IncompleteSuffix); err == nil {
+		_ = f.Close()
+	}
+
+	// Health checks are pushed far out so only end-game racing can rescue the stall
+	runtime := &types.RuntimeConfig{
+		MaxConnectionsPerHost: 3,
+		MinChunkSize:          1 * types.MB,
+		StallTimeout:          time.Minute,
+		SlowWorkerGracePeriod: time.Minute,
+	}
+	state := types.NewProgressState("endgame", fileSize)
+	downloader := NewConcurrentDownloader("endgame-id", nil, state, runtime)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	if err := downloader.Download(ctx, server.URL, nil, nil, destPath, fileSize); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("expected end-game race to finish well before the stall timeout, took %v", elapsed)
+	}
+	if firstChunkRequests.Load() < 2 {
+		t.Errorf("expected the stalled chunk to be raced, got %d requests", firstChunkRequests.Load())
+	}
+	if got := state.Downloaded.Load(); got != fileSize {
+		t.Errorf("expected %d bytes counted once, got %d", fileSize, got)
+	}
+
+	got, err := os.ReadFile(destPath + types.IncompleteSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Error("downloaded content does not match")
+	}
+}
@@ -0,0 +1,384 @@
+package concurrent
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// hostLimitTTL bounds how long an inferred per-host connection cap is trusted
+const hostLimitTTL = 24 * time.Hour
+
+const (
+	// rateLimitCooldown is the host-wide pause after a first 429; each further
+	// 429 before a successful response doubles it up to rateLimitMaxCooldown
+	rateLimitCooldown    = 1 * time.Second
+	rateLimitMaxCooldown = 60 * time.Second
+
+	// maxRetryAfter caps how long a Retry-After header can pause a host
+	maxRetryAfter = 5 * time.Minute
+
+	// maxRateLimitRetries is how many 429s a task may wait out before they
+	// start counting against MaxTaskRetries
+	maxRateLimitRetries = 10
+)
+
+// concurrencyLimitHeaders are nonstandard headers some servers use to state a
+// connection cap outright
+var concurrencyLimitHeaders = []string{
+	"X-Concurrency-Limit",
+	"X-Connection-Limit",
+	"X-Max-Connections",
+	"X-RateLimit-Concurrency",
+}
+
+// requestQuotaHeaders advertise a per-window request quota. A host that only
+// allows N requests per window cannot usefully serve more than N connections.
+var requestQuotaHeaders = []string{
+	"X-RateLimit-Limit",
+	"X-Rate-Limit-Limit",
+	"RateLimit-Limit",
+}
+
+// hostLimits caps in-flight requests per host for every download in the process
+var hostLimits = newHostConnLimiter()
+
+type hostSlot struct {
+	limit   int // 0 = unlimited
+	hint    string
+	expires time.Time
+	inUse   int
+	loaded  bool          // Persisted stats have been consulted
+	wake    chan struct{} // Closed and replaced whenever a slot frees up
+
+	coolUntil time.Time // No new requests start before this
+	strikes   int       // 429s since the last successful response
+}
+
+// hostConnLimiter is a per-host counting semaphore whose size is inferred from
+// server hints. Limits only ever shrink until they expire.
+type hostConnLimiter struct {
+	mu    sync.Mutex
+	hosts map[string]*hostSlot
+}
+
+func newHostConnLimiter() *hostConnLimiter {
+	return &hostConnLimiter{hosts: make(map[string]*hostSlot)}
+}
+
+func (l *hostConnLimiter) slotLocked(host string) *hostSlot {
+	s, ok := l.hosts[host]
+	if !ok {
+		s = &hostSlot{wake: make(chan struct{})}
+		l.hosts[host] = s
+	}
+	if s.limit > 0 && time.Now().After(s.expires) {
+		s.limit = 0
+		s.hint = ""
+	}
+	return s
+}
+
+// limit returns the current cap for host (0 = unlimited)
+func (l *hostConnLimiter) limit(host string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.slotLocked(host).limit
+}
+
+// inUse returns the number of requests currently holding a slot for host
+func (l *hostConnLimiter) inUse(host string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.slotLocked(host).inUse
+}
+
+// acquire blocks until host is out of its cool-down and has a free slot,
+// or ctx is done
+func (l *hostConnLimiter) acquire(ctx context.Context, host string) error {
+	for {
+		l.mu.Lock()
+		s := l.slotLocked(host)
+		if wait := time.Until(s.coolUntil); wait > 0 {
+			l.mu.Unlock()
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+			continue
+		}
+		if s.limit <= 0 || s.inUse < s.limit {
+			s.inUse++
+			l.mu.Unlock()
+			return nil
+		}
+		wake := s.wake
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wake:
+		}
+	}
+}
+
+// rateLimited records a 429 (or a Retry-After) from host. Every worker waits
+// out the returned cool-down before its next request to host, and the cap
+// drops below inUse (the requests in flight, including the limited one) when
+// that is stricter. retryAfter, when positive, is the delay the server asked
+// for and replaces the exponential cool-down. It returns the new cap, or 0
+// when the cap was unchanged.
+func (l *hostConnLimiter) rateLimited(host string, inUse int, retryAfter time.Duration, hint string) (int, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s := l.slotLocked(host)
+
+	s.strikes++
+	cooldown := rateLimitMaxCooldown
+	if retryAfter > 0 {
+		cooldown = min(retryAfter, maxRetryAfter)
+	} else if s.strikes <= 6 {
+		cooldown = min(rateLimitCooldown<<(s.strikes-1), rateLimitMaxCooldown)
+	}
+	if until := time.Now().Add(cooldown); until.After(s.coolUntil) {
+		s.coolUntil = until
+	}
+
+	limit := max(1, inUse-1)
+	if s.limit > 0 && s.limit <= limit {
+		return 0, cooldown
+	}
+	s.limit = limit
+	s.hint = hint
+	s.expires = time.Now().Add(hostLimitTTL)
+	return limit, cooldown
+}
+
+// coolingDown reports whether host is waiting out a 429 cool-down
+func (l *hostConnLimiter) coolingDown(host string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return time.Now().Before(l.slotLocked(host).coolUntil)
+}
+
+// succeeded clears the 429 streak for host so the next one starts the
+// cool-down from scratch
+func (l *hostConnLimiter) succeeded(host string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.slotLocked(host).strikes = 0
+}
+
+// release frees a slot taken by acquire
+func (l *hostConnLimiter) release(host string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s := l.slotLocked(host)
+	if s.inUse > 0 {
+		s.inUse--
+	}
+	close(s.wake)
+	s.wake = make(chan struct{})
+}
+
+// lower caps host at limit if that is stricter than the current cap.
+// It reports whether the cap changed.
+func (l *hostConnLimiter) lower(host string, limit int, hint string) bool {
+	if limit <= 0 {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s := l.slotLocked(host)
+	if s.limit > 0 && s.limit <= limit {
+		return false
+	}
+	s.limit = limit
+	s.hint = hint
+	s.expires = time.Now().Add(hostLimitTTL)
+	return true
+}
+
+// load applies the cap persisted for host the first time the host is seen
+func (l *hostConnLimiter) load(host string) {
+	l.mu.Lock()
+	s := l.slotLocked(host)
+	if s.loaded {
+		l.mu.Unlock()
+		return
+	}
+	s.loaded = true
+	l.mu.Unlock()
+
+	stats, err := state.GetHostStats(host)
+	if err != nil || stats == nil || stats.MaxConnections <= 0 {
+		return
+	}
+	updated := time.Unix(stats.UpdatedAt, 0)
+	if time.Since(updated) > hostLimitTTL {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s = l.slotLocked(host)
+	if s.limit <= 0 || stats.MaxConnections < s.limit {
+		s.limit = stats.MaxConnections
+		s.hint = stats.LimitHint
+		s.expires = updated.Add(hostLimitTTL)
+		utils.Debug("Host %s: restored connection cap %d (%s)", host, s.limit, s.hint)
+	}
+}
+
+// hostOf returns the host[:port] of rawurl, lowercased
+func hostOf(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Host)
+}
+
+// parseRetryAfter reads a Retry-After value, either delay-seconds or an
+// HTTP date. It returns 0 for a missing, malformed or past value.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs <= 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(v); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// parseHintCount reads the leading integer of a header value such as "10" or
+// "10, 10;w=1"
+func parseHintCount(v string) int {
+	v = strings.TrimSpace(v)
+	if i := strings.IndexAny(v, ",;"); i >= 0 {
+		v = strings.TrimSpace(v[:i])
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0
+	}
+	return n
+}
+
+// inferConnectionLimit derives a connection cap from a response. inUse is
+// the number of requests in flight to the host, including this one.
+// It returns 0 when the response carries no usable hint.
+func inferConnectionLimit(resp *http.Response, inUse int) (int, string) {
+	if resp == nil {
+		return 0, ""
+	}
+
+	// Told to back off while we had inUse connections open: the host tolerates fewer
+	if (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) &&
+		resp.Header.Get("Retry-After") != "" {
+		return max(1, inUse-1), "Retry-After"
+	}
+
+	if strings.EqualFold(strings.TrimSpace(resp.Header.Get("Accept-Ranges")), "none") {
+		return 1, "Accept-Ranges"
+	}
+
+	for _, name := range concurrencyLimitHeaders {
+		if n := parseHintCount(resp.Header.Get(name)); n > 0 {
+			return n, name
+		}
+	}
+	for _, name := range requestQuotaHeaders {
+		if n := parseHintCount(resp.Header.Get(name)); n > 0 {
+			return n, name
+		}
+	}
+	return 0, ""
+}
+
+// toldToBackOff reports whether a response with status code and Retry-After
+// delay retryAfter asks the client to slow down: a 429, or a 503 with a delay
+func toldToBackOff(code int, retryAfter time.Duration) bool {
+	return code == http.StatusTooManyRequests || (code == http.StatusServiceUnavailable && retryAfter > 0)
+}
+
+// observeHostHints tightens the host's connection cap from resp and records
+// any new cap in the per-host stats table. A 429 also starts a host-wide
+// cool-down shared by every worker.
+func (d *ConcurrentDownloader) observeHostHints(host string, resp *http.Response) {
+	retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	if toldToBackOff(resp.StatusCode, retryAfter) {
+		hint := "429"
+		if retryAfter > 0 {
+			hint = "Retry-After"
+		}
+		limit, cooldown := hostLimits.rateLimited(host, hostLimits.inUse(host), retryAfter, hint)
+		utils.Debug("Host %s: told to back off (%d), pausing requests for %v", host, resp.StatusCode, cooldown)
+		if limit > 0 && limit < d.Runtime.GetMaxConnectionsPerHost() {
+			utils.Debug("Host %s: capping connections at %d (%s)", host, limit, hint)
+			if err := state.SaveHostConnectionLimit(host, limit, hint); err != nil {
+				utils.Debug("Failed to record host stats for %s: %v", host, err)
+			}
+		}
+		return
+	}
+	if resp.StatusCode < http.StatusBadRequest {
+		hostLimits.succeeded(host)
+	}
+
+	limit, hint := inferConnectionLimit(resp, hostLimits.inUse(host))
+	if limit <= 0 || limit >= d.Runtime.GetMaxConnectionsPerHost() {
+		return
+	}
+	if !hostLimits.lower(host, limit, hint) {
+		return
+	}
+
+	utils.Debug("Host %s: capping connections at %d (%s)", host, limit, hint)
+	if err := state.SaveHostConnectionLimit(host, limit, hint); err != nil {
+		utils.Debug("Failed to record host stats for %s: %v", host, err)
+	}
+}
+
+// capConnectionsForHosts limits numConns to what the hosts behind urls are
+// known to accept. Hosts without a cap leave numConns unchanged.
+func capConnectionsForHosts(numConns int, urls []string) int {
+	total := 0
+	seen := make(map[string]bool, len(urls))
+	for _, u := range urls {
+		host := hostOf(u)
+		if host == "" || seen[host] {
+			continue
+		}
+		seen[host] = true
+
+		hostLimits.load(host)
+		limit := hostLimits.limit(host)
+		if limit <= 0 {
+			return numConns
+		}
+		total += limit
+	}
+	if total > 0 && total < numConns {
+		return total
+	}
+	return numConns
+}
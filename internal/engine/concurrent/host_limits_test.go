@@ -0,0 +1,295 @@
+package concurrent
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/testutil"
+)
+
+func TestInferConnectionLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		headers   map[string]string
+		inUse     int
+		wantLimit int
+		wantHint  string
+	}{
+		{"no hints", http.StatusPartialContent, nil, 4, 0, ""},
+		{"retry-after on 429", http.StatusTooManyRequests, map[string]string{"Retry-After": "5"}, 4, 3, "Retry-After"},
+		{"retry-after floor", http.StatusServiceUnavailable, map[string]string{"Retry-After": "5"}, 1, 1, "Retry-After"},
+		{"retry-after on success ignored", http.StatusPartialContent, map[string]string{"Retry-After": "5"}, 4, 0, ""},
+		{"accept-ranges none", http.StatusOK, map[string]string{"Accept-Ranges": "none"}, 4, 1, "Accept-Ranges"},
+		{"explicit concurrency", http.StatusPartialContent, map[string]string{"X-Concurrency-Limit": "3"}, 4, 3, "X-Concurrency-Limit"},
+		{"request quota", http.StatusPartialContent, map[string]string{"RateLimit-Limit": "6, 6;w=1"}, 4, 6, "RateLimit-Limit"},
+		{"garbage", http.StatusPartialContent, map[string]string{"X-RateLimit-Limit": "lots"}, 4, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: make(http.Header)}
+			for k, v := range tt.headers {
+				resp.Header.Set(k, v)
+			}
+			limit, hint := inferConnectionLimit(resp, tt.inUse)
+			if limit != tt.wantLimit || hint != tt.wantHint {
+				t.Errorf("got (%d, %q), want (%d, %q)", limit, hint, tt.wantLimit, tt.wantHint)
+			}
+		})
+	}
+}
+
+func TestHostConnLimiter_LowerOnly(t *testing.T) {
+	l := newHostConnLimiter()
+	if !l.lower("a.example", 4, "X-Concurrency-Limit") {
+		t.Fatal("expected first cap to apply")
+	}
+	if l.lower("a.example", 8, "X-RateLimit-Limit") {
+		t.Error("expected looser cap to be ignored")
+	}
+	if !l.lower("a.example", 2, "Retry-After") {
+		t.Error("expected stricter cap to apply")
+	}
+	if got := l.limit("a.example"); got != 2 {
+		t.Errorf("expected cap 2, got %d", got)
+	}
+	if got := l.limit("b.example"); got != 0 {
+		t.Errorf("expected other hosts to stay unlimited, got %d", got)
+	}
+}
+
+func TestHostConnLimiter_AcquireBlocksAtCap(t *testing.T) {
+	l := newHostConnLimiter()
+	l.lower("a.example", 1, "Accept-Ranges")
+
+	if err := l.acquire(context.Background(), "a.example"); err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := l.acquire(ctx, "a.example"); err == nil {
+		t.Fatal("expected second acquire to block until the context expired")
+	}
+
+	acquired := make(chan error, 1)
+	go func() { acquired <- l.acquire(context.Background(), "a.example") }()
+	l.release("a.example")
+
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatalf("acquire after release failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("release did not wake the waiting acquire")
+	}
+}
+
+func TestHostConnLimiter_RateLimitCooldown(t *testing.T) {
+	l := newHostConnLimiter()
+
+	limit, cooldown := l.rateLimited("a.example", 4, 0, "429")
+	if limit != 3 || cooldown != rateLimitCooldown {
+		t.Fatalf("first 429: got cap %d, cool-down %v", limit, cooldown)
+	}
+	if _, cooldown = l.rateLimited("a.example", 3, 0, "429"); cooldown != 2*rateLimitCooldown {
+		t.Errorf("expected cool-down to double, got %v", cooldown)
+	}
+	if l.limit("a.example") != 2 {
+		t.Errorf("expected cap to keep shrinking, got %d", l.limit("a.example"))
+	}
+	if !l.coolingDown("a.example") || l.coolingDown("b.example") {
+		t.Error("expected only the limited host to cool down")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := l.acquire(ctx, "a.example"); err == nil {
+		t.Fatal("expected acquire to wait out the cool-down")
+	}
+
+	l.succeeded("a.example")
+	if _, cooldown = l.rateLimited("a.example", 1, 0, "429"); cooldown != rateLimitCooldown {
+		t.Errorf("expected a success to reset the streak, got %v", cooldown)
+	}
+
+	for range 20 {
+		_, cooldown = l.rateLimited("a.example", 1, 0, "429")
+	}
+	if cooldown != rateLimitMaxCooldown {
+		t.Errorf("expected cool-down to be capped, got %v", cooldown)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"7", 7 * time.Second},
+		{"0", 0},
+		{"-3", 0},
+		{"soon", 0},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestHostConnLimiter_RetryAfterOverridesBackoff(t *testing.T) {
+	l := newHostConnLimiter()
+
+	if _, cooldown := l.rateLimited("a.example", 2, 7*time.Second, "Retry-After"); cooldown != 7*time.Second {
+		t.Errorf("expected the server's delay, got %v", cooldown)
+	}
+	if _, cooldown := l.rateLimited("a.example", 2, time.Hour, "Retry-After"); cooldown != maxRetryAfter {
+		t.Errorf("expected the delay to be capped, got %v", cooldown)
+	}
+}
+
+func TestConcurrentDownloader_HonorsRetryAfter(t *testing.T) {
+	tmpDir, cleanup := initTestState(t)
+	defer cleanup()
+
+	fileSize := int64(256 * types.KB)
+	content := bytes.Repeat([]byte{0xCD}, int(fileSize))
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		http.ServeContent(w, r, "retry.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	destPath := filepath.Join(tmpDir, "retry.bin")
+	if f, err := os.Create(destPath + types.IncompleteSuffix); err == nil {
+		_ = f.Close()
+	}
+
+	runtime := &types.RuntimeConfig{
+		MaxConnectionsPerHost: 1,
+		MaxTaskRetries:        3,
+		MinChunkSize:          64 * types.KB,
+	}
+	downloader := NewConcurrentDownloader("retry-after-id", nil, types.NewProgressState("retry-after", fileSize), runtime)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := downloader.Download(ctx, server.URL, nil, nil, destPath, fileSize); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+
+	// The fixed backoff would have retried after 400ms
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Errorf("expected the retry to wait for Retry-After, took %v", elapsed)
+	}
+}
+
+func TestConcurrentDownloader_BacksOffRateLimitedHost(t *testing.T) {
+	tmpDir, cleanup := initTestState(t)
+	defer cleanup()
+
+	fileSize := int64(16 * types.MB) // Enough for four workers against a limit of two
+	server := testutil.NewMockServerT(t,
+		testutil.WithFileSize(fileSize),
+		testutil.WithRangeSupport(true),
+		testutil.WithMaxConcurrentRequests(2),
+		testutil.WithLatency(20*time.Millisecond),
+	)
+	defer server.Close()
+
+	destPath := filepath.Join(tmpDir, "ratelimited.bin")
+	if f, err := os.Create(destPath + types.IncompleteSuffix); err == nil {
+		_ = f.Close()
+	}
+
+	// One retry would not survive the 429s if they counted as failures
+	runtime := &types.RuntimeConfig{
+		MaxConnectionsPerHost: 8,
+		MaxTaskRetries:        1,
+		MinChunkSize:          256 * types.KB,
+	}
+	downloader := NewConcurrentDownloader("ratelimited-id", nil, types.NewProgressState("ratelimited", fileSize), runtime)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	if err := downloader.Download(ctx, server.URL(), nil, nil, destPath, fileSize); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if err := testutil.VerifyFileSize(destPath+types.IncompleteSuffix, fileSize); err != nil {
+		t.Error(err)
+	}
+
+	host := hostOf(server.URL())
+	if limit := hostLimits.limit(host); limit <= 0 || limit >= 8 {
+		t.Errorf("expected the 429s to lower the host cap, got %d", limit)
+	}
+	stats, err := state.GetHostStats(host)
+	if err != nil || stats == nil || stats.LimitHint != "429" {
+		t.Errorf("expected the cap to be recorded from 429s, got %+v, %v", stats, err)
+	}
+}
+
+func TestConcurrentDownloader_RecordsHostConnectionCap(t *testing.T) {
+	tmpDir, cleanup := initTestState(t)
+	defer cleanup()
+
+	fileSize := int64(512 * types.KB)
+	content := bytes.Repeat([]byte{0xAB}, int(fileSize))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Concurrency-Limit", "2")
+		http.ServeContent(w, r, "capped.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	destPath := filepath.Join(tmpDir, "capped.bin")
+	if f, err := os.Create(destPath + types.IncompleteSuffix); err == nil {
+		_ = f.Close()
+	}
+
+	runtime := &types.RuntimeConfig{
+		MaxConnectionsPerHost: 8,
+		MinChunkSize:          16 * types.KB,
+	}
+	downloader := NewConcurrentDownloader("capped-id", nil, types.NewProgressState("capped", fileSize), runtime)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := downloader.Download(ctx, server.URL, nil, nil, destPath, fileSize); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+
+	host := hostOf(server.URL)
+	stats, err := state.GetHostStats(host)
+	if err != nil {
+		t.Fatalf("GetHostStats failed: %v", err)
+	}
+	if stats == nil || stats.MaxConnections != 2 || stats.LimitHint != "X-Concurrency-Limit" {
+		t.Fatalf("expected recorded cap of 2, got %+v", stats)
+	}
+
+	if got := capConnectionsForHosts(8, []string{server.URL}); got != 2 {
+		t.Errorf("expected later downloads to start with 2 connections, got %d", got)
+	}
+}
@@ -0,0 +1,49 @@
+package concurrent
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Process-wide gauges for diagnosing live daemons. They are cheap atomics
+// updated on the hot path and only read when metrics are requested.
+var (
+	liveQueues       sync.Map // *TaskQueue -> struct{}, one per running download
+	workersRunning   atomic.Int64
+	buffersAllocated atomic.Int64
+	bufferBytes      atomic.Int64
+	buffersInUse     atomic.Int64
+)
+
+// EngineStats is a snapshot of the concurrent engine's gauges
+type EngineStats struct {
+	Downloads        int   `json:"downloads"`         // Concurrent downloads in flight
+	Workers          int64 `json:"workers"`           // Worker goroutines running
+	IdleWorkers      int64 `json:"idle_workers"`      // Workers waiting for a task
+	QueuedTasks      int   `json:"queued_tasks"`      // Chunks waiting for a worker
+	BuffersAllocated int64 `json:"buffers_allocated"` // Worker buffers ever created by the pools
+	BufferBytes      int64 `json:"buffer_bytes"`      // Total size of those buffers
+	BuffersInUse     int64 `json:"buffers_in_use"`    // Buffers currently checked out
+	BufferBudget     int64 `json:"buffer_budget"`     // Memory cap for checked out buffers, 0 = unlimited
+	BufferBudgetUsed int64 `json:"buffer_budget_used"`
+	BufferWaiters    int   `json:"buffer_waiters"` // Workers blocked on the memory cap
+}
+
+// Stats returns the current engine gauges
+func Stats() EngineStats {
+	s := EngineStats{
+		Workers:          workersRunning.Load(),
+		BuffersAllocated: buffersAllocated.Load(),
+		BufferBytes:      bufferBytes.Load(),
+		BuffersInUse:     buffersInUse.Load(),
+	}
+	s.BufferBudget, s.BufferBudgetUsed, s.BufferWaiters = bufferBudget.snapshot()
+	liveQueues.Range(func(k, _ any) bool {
+		q := k.(*TaskQueue)
+		s.Downloads++
+		s.QueuedTasks += q.Len()
+		s.IdleWorkers += q.IdleWorkers()
+		return true
+	})
+	return s
+}
@@ -0,0 +1,64 @@
+package concurrent
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestStats_TracksRunningDownload(t *testing.T) {
+	tmpDir, cleanup := initTestState(t)
+	defer cleanup()
+
+	fileSize := int64(256 * types.KB)
+	content := bytes.Repeat([]byte{0x5A}, int(fileSize))
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		http.ServeContent(w, r, "stats.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	destPath := filepath.Join(tmpDir, "stats.bin")
+	if f, err := os.Create(destPath + types.IncompleteSuffix); err == nil {
+		_ = f.Close()
+	}
+
+	runtime := &types.RuntimeConfig{MaxConnectionsPerHost: 2, MinChunkSize: 64 * types.KB}
+	downloader := NewConcurrentDownloader("stats-id", nil, types.NewProgressState("stats", fileSize), runtime)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- downloader.Download(ctx, server.URL, nil, nil, destPath, fileSize) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	var during EngineStats
+	for time.Now().Before(deadline) {
+		during = Stats()
+		if during.Downloads == 1 && during.Workers > 0 && during.BuffersInUse > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(release)
+
+	if err := <-done; err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if during.Downloads != 1 || during.Workers == 0 || during.BuffersInUse == 0 || during.BufferBytes == 0 {
+		t.Fatalf("expected live download in stats, got %+v", during)
+	}
+
+	after := Stats()
+	if after.Downloads != 0 || after.Workers != 0 || after.BuffersInUse != 0 || after.QueuedTasks != 0 {
+		t.Errorf("expected gauges to drain after download, got %+v", after)
+	}
+}
@@ -0,0 +1,202 @@
+package concurrent
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+const (
+	// mirrorMinScoreFactor keeps a demoted mirror selectable at a low rate so it
+	// can recover if its errors were transient.
+	mirrorMinScoreFactor = 0.05
+	// mirrorMinSampleBytes is the amount of data a mirror must serve before its
+	// measured throughput is trusted over the optimistic default.
+	mirrorMinSampleBytes = 64 * types.KB
+)
+
+// mirrorStats accumulates observed throughput and failures for one mirror.
+type mirrorStats struct {
+	bytes     int64
+	elapsed   time.Duration
+	successes int
+	errors    int
+}
+
+// throughput returns the mirror's observed bytes/sec, or 0 if there is not yet
+// enough data to judge it.
+func (s *mirrorStats) throughput() float64 {
+	if s == nil || s.bytes < mirrorMinSampleBytes || s.elapsed <= 0 {
+		return 0
+	}
+	return float64(s.bytes) / s.elapsed.Seconds()
+}
+
+// reliability returns a multiplier in [mirrorMinScoreFactor, 1] derived from the
+// mirror's error rate. Errors weigh more than successes so a flaky mirror is
+// demoted quickly.
+func (s *mirrorStats) reliability() float64 {
+	if s == nil || s.errors == 0 {
+		return 1
+	}
+	attempts := float64(s.successes + s.errors)
+	errRate := float64(s.errors) / attempts
+	factor := (1 - errRate) * (1 - errRate)
+	if factor < mirrorMinScoreFactor {
+		return mirrorMinScoreFactor
+	}
+	return factor
+}
+
+// mirrorScoreboard tracks per-mirror performance for a single download and
+// biases task assignment toward the fastest, most reliable mirrors.
+type mirrorScoreboard struct {
+	mu    sync.Mutex
+	stats map[string]*mirrorStats
+	rng   *rand.Rand
+}
+
+func newMirrorScoreboard() *mirrorScoreboard {
+	return &mirrorScoreboard{
+		stats: make(map[string]*mirrorStats),
+		rng:   rand.New(rand.NewPCG(uint64(time.Now().UnixNano()), 0)),
+	}
+}
+
+func (s *mirrorScoreboard) statsLocked(url string) *mirrorStats {
+	st, ok := s.stats[url]
+	if !ok {
+		st = &mirrorStats{}
+		s.stats[url] = st
+	}
+	return st
+}
+
+// recordSuccess records a task that completed against url.
+func (s *mirrorScoreboard) recordSuccess(url string, bytes int64, elapsed time.Duration) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.statsLocked(url)
+	st.bytes += bytes
+	st.elapsed += elapsed
+	st.successes++
+}
+
+// recordError records a failed attempt against url. Bytes served before the
+// failure still count toward throughput.
+func (s *mirrorScoreboard) recordError(url string, bytes int64, elapsed time.Duration) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.statsLocked(url)
+	st.bytes += bytes
+	st.elapsed += elapsed
+	st.errors++
+}
+
+// score returns the current weight of url; higher is better.
+func (s *mirrorScoreboard) score(url string) float64 {
+	if s == nil {
+		return 1
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.scoreLocked(url, s.bestThroughputLocked())
+}
+
+// bestThroughputLocked returns the highest measured throughput, used as the
+// optimistic estimate for mirrors that have not been sampled yet.
+func (s *mirrorScoreboard) bestThroughputLocked() float64 {
+	var best float64
+	for _, st := range s.stats {
+		if tp := st.throughput(); tp > best {
+			best = tp
+		}
+	}
+	if best == 0 {
+		best = 1
+	}
+	return best
+}
+
+func (s *mirrorScoreboard) scoreLocked(url string, optimistic float64) float64 {
+	st := s.stats[url]
+	tp := st.throughput()
+	if tp == 0 {
+		tp = optimistic
+	}
+	return tp * st.reliability()
+}
+
+// pick selects the index of the mirror to use for the next task, weighted by
+// score. avoid excludes one mirror (typically the one that just failed) when
+// there is an alternative.
+func (s *mirrorScoreboard) pick(mirrors []string, avoid string) int {
+	if len(mirrors) <= 1 {
+		return 0
+	}
+	if s == nil {
+		for i, m := range mirrors {
+			if m != avoid {
+				return i
+			}
+		}
+		return 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	optimistic := s.bestThroughputLocked()
+	weights := make([]float64, len(mirrors))
+	var total float64
+	for i, m := range mirrors {
+		if m == avoid {
+			continue
+		}
+		weights[i] = s.scoreLocked(m, optimistic)
+		total += weights[i]
+	}
+	if total <= 0 {
+		return 0
+	}
+
+	r := s.rng.Float64() * total
+	last := 0
+	for i, w := range weights {
+		if w <= 0 {
+			continue
+		}
+		last = i
+		if r < w {
+			return i
+		}
+		r -= w
+	}
+	return last
+}
+
+// logSummary writes the per-mirror statistics to the debug log.
+func (s *mirrorScoreboard) logSummary(downloadID string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.stats) < 2 {
+		return
+	}
+	optimistic := s.bestThroughputLocked()
+	for url, st := range s.stats {
+		utils.Debug("Mirror score [%s] %s: %s/s, %d ok, %d errors, score %.0f",
+			downloadID, url, utils.ConvertBytesToHumanReadable(int64(st.throughput())), st.successes, st.errors, s.scoreLocked(url, optimistic))
+	}
+}
@@ -0,0 +1,97 @@
+package concurrent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestMirrorScoreboard_FastMirrorOutscoresSlow(t *testing.T) {
+	s := newMirrorScoreboard()
+	s.recordSuccess("fast", 10*types.MB, time.Second)
+	s.recordSuccess("slow", 1*types.MB, time.Second)
+
+	if s.score("fast") <= s.score("slow") {
+		t.Errorf("expected fast mirror to score higher: fast=%.0f slow=%.0f", s.score("fast"), s.score("slow"))
+	}
+}
+
+func TestMirrorScoreboard_ErrorsDemoteMirror(t *testing.T) {
+	s := newMirrorScoreboard()
+	s.recordSuccess("good", 4*types.MB, time.Second)
+	s.recordSuccess("flaky", 4*types.MB, time.Second)
+	before := s.score("flaky")
+
+	s.recordError("flaky", 0, time.Second)
+	s.recordError("flaky", 0, time.Second)
+
+	after := s.score("flaky")
+	if after >= before {
+		t.Errorf("expected errors to lower score: before=%.0f after=%.0f", before, after)
+	}
+	if after <= 0 {
+		t.Errorf("demoted mirror should remain selectable, got score %.0f", after)
+	}
+}
+
+func TestMirrorScoreboard_UnsampledMirrorIsOptimistic(t *testing.T) {
+	s := newMirrorScoreboard()
+	s.recordSuccess("known", 8*types.MB, time.Second)
+
+	// Too little data to judge: treated like the best known mirror
+	s.recordSuccess("fresh", 1*types.KB, time.Second)
+
+	if s.score("fresh") != s.score("known") {
+		t.Errorf("expected unsampled mirror to use best throughput: fresh=%.0f known=%.0f", s.score("fresh"), s.score("known"))
+	}
+}
+
+func TestMirrorScoreboard_PickAvoidsMirror(t *testing.T) {
+	s := newMirrorScoreboard()
+	mirrors := []string{"a", "b", "c"}
+
+	for i := 0; i < 100; i++ {
+		if idx := s.pick(mirrors, "b"); mirrors[idx] == "b" {
+			t.Fatal("pick returned the avoided mirror")
+		}
+	}
+
+	// A single mirror is always returned even if it is the one to avoid
+	if idx := s.pick([]string{"only"}, "only"); idx != 0 {
+		t.Errorf("expected 0 for single mirror, got %d", idx)
+	}
+}
+
+func TestMirrorScoreboard_PickFavorsFasterMirror(t *testing.T) {
+	s := newMirrorScoreboard()
+	s.recordSuccess("fast", 20*types.MB, time.Second)
+	s.recordSuccess("slow", 1*types.MB, time.Second)
+	mirrors := []string{"slow", "fast"}
+
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		counts[mirrors[s.pick(mirrors, "")]]++
+	}
+
+	if counts["fast"] <= counts["slow"]*5 {
+		t.Errorf("expected fast mirror to dominate selection, got fast=%d slow=%d", counts["fast"], counts["slow"])
+	}
+	if counts["slow"] == 0 {
+		t.Error("expected slow mirror to still be picked occasionally")
+	}
+}
+
+func TestMirrorScoreboard_NilSafe(t *testing.T) {
+	var s *mirrorScoreboard
+	s.recordSuccess("a", 1, time.Second)
+	s.recordError("a", 1, time.Second)
+	s.logSummary("id")
+
+	if got := s.score("a"); got != 1 {
+		t.Errorf("expected neutral score for nil scoreboard, got %f", got)
+	}
+	if idx := s.pick([]string{"a", "b"}, "a"); idx != 1 {
+		t.Errorf("expected nil scoreboard to skip avoided mirror, got %d", idx)
+	}
+}
@@ -0,0 +1,97 @@
+package concurrent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func newStealTestTask(offset, length int64, speed float64) *ActiveTask {
+	at := &ActiveTask{
+		Task:  types.Task{Offset: offset, Length: length},
+		Speed: speed,
+	}
+	at.CurrentOffset.Store(offset)
+	at.StopAt.Store(offset + length)
+	at.LastActivity.Store(time.Now().UnixNano())
+	return at
+}
+
+func TestStealWork_PrefersSlowWorkerOverLargestRange(t *testing.T) {
+	d := NewConcurrentDownloader("steal", nil, nil, nil)
+
+	// Fast worker has more bytes left but finishes sooner (40MB @ 20MB/s = 2s)
+	fast := newStealTestTask(0, 40*types.MB, 20*types.MB)
+	// Slow worker has fewer bytes but is far behind (20MB @ 1MB/s = 20s)
+	slow := newStealTestTask(100*types.MB, 20*types.MB, 1*types.MB)
+	d.activeTasks[0] = fast
+	d.activeTasks[1] = slow
+
+	queue := NewTaskQueue()
+	if !d.StealWork(queue) {
+		t.Fatal("expected StealWork to succeed")
+	}
+
+	if got := slow.StopAt.Load(); got >= 120*types.MB {
+		t.Errorf("expected slow worker's range to be split, StopAt=%d", got)
+	}
+	if got := fast.StopAt.Load(); got != 40*types.MB {
+		t.Errorf("expected fast worker untouched, StopAt=%d", got)
+	}
+
+	stolen, ok := queue.Pop()
+	if !ok || stolen.Offset < 100*types.MB {
+		t.Errorf("expected stolen task from slow worker's range, got %+v", stolen)
+	}
+}
+
+func TestStealWork_SkipsWorkerThatFinishesFirst(t *testing.T) {
+	d := NewConcurrentDownloader("steal", nil, nil, nil)
+
+	// Both workers are fast relative to the mean; a thief would not beat them
+	d.activeTasks[0] = newStealTestTask(0, 8*types.MB, 50*types.MB)
+	d.activeTasks[1] = newStealTestTask(100*types.MB, 8*types.MB, 50*types.MB)
+
+	queue := NewTaskQueue()
+	if d.StealWork(queue) {
+		t.Fatal("expected StealWork to skip workers that finish sooner than a thief")
+	}
+	if queue.Len() != 0 {
+		t.Errorf("expected no stolen tasks, got %d", queue.Len())
+	}
+}
+
+func TestStealWork_FallsBackToRemainingWithoutSpeeds(t *testing.T) {
+	d := NewConcurrentDownloader("steal", nil, nil, nil)
+
+	small := newStealTestTask(0, 10*types.MB, 0)
+	large := newStealTestTask(100*types.MB, 30*types.MB, 0)
+	d.activeTasks[0] = small
+	d.activeTasks[1] = large
+
+	queue := NewTaskQueue()
+	if !d.StealWork(queue) {
+		t.Fatal("expected StealWork to succeed")
+	}
+	if got := large.StopAt.Load(); got >= 130*types.MB {
+		t.Errorf("expected largest range to be split when no speed data, StopAt=%d", got)
+	}
+	if got := small.StopAt.Load(); got != 10*types.MB {
+		t.Errorf("expected small range untouched, StopAt=%d", got)
+	}
+}
+
+func TestStealETA(t *testing.T) {
+	if _, ok := stealETA(10*types.MB, 0, 5*types.MB); !ok {
+		t.Error("expected unmeasured worker to be stealable")
+	}
+	// 10MB @ 1MB/s = 10s vs thief 5MB @ 10MB/s + setup
+	if _, ok := stealETA(10*types.MB, 1*types.MB, 10*types.MB); !ok {
+		t.Error("expected slow worker to be stealable")
+	}
+	// 10MB @ 100MB/s = 0.1s, thief can't win
+	if _, ok := stealETA(10*types.MB, 100*types.MB, 10*types.MB); ok {
+		t.Error("expected fast worker not to be stealable")
+	}
+}
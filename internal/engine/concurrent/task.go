@@ -29,11 +29,25 @@ type ActiveTask struct {
 	// Hedged request tracking
 	Hedged          atomic.Int32  // 1 if an idle worker is already racing this task
 	SharedMaxOffset *atomic.Int64 // Highest offset reached by any racing worker
+	URL             string        // Mirror serving this task
+}
+
+// effectiveOffset returns how far this task's range is known to be written.
+// Racing workers cover the range contiguously from the hedge point, so any
+// progress a racer made past our own offset counts as ours.
+func (at *ActiveTask) effectiveOffset() int64 {
+	current := at.CurrentOffset.Load()
+	if at.SharedMaxOffset != nil {
+		if shared := at.SharedMaxOffset.Load(); shared > current {
+			return shared
+		}
+	}
+	return current
 }
 
 // RemainingBytes returns the number of bytes left for this task
 func (at *ActiveTask) RemainingBytes() int64 {
-	current := at.CurrentOffset.Load()
+	current := at.effectiveOffset()
 	stopAt := at.StopAt.Load()
 	if current >= stopAt {
 		return 0
@@ -43,7 +57,7 @@ func (at *ActiveTask) RemainingBytes() int64 {
 
 // RemainingTask returns a Task representing the remaining work, or nil if complete
 func (at *ActiveTask) RemainingTask() *types.Task {
-	current := at.CurrentOffset.Load()
+	current := at.effectiveOffset()
 	stopAt := at.StopAt.Load()
 	if current >= stopAt {
 		return nil
@@ -76,6 +90,10 @@ func (at *ActiveTask) GetSpeed() float64 {
 	return speed
 }
 
+// stealSetupCost approximates the time a thief spends on a new request before
+// stolen bytes start flowing.
+const stealSetupCost = 500 * time.Millisecond
+
 // alignedSplitSize calculates a split size that is half of remaining, aligned to AlignSize
 // Returns 0 if the split would be smaller than MinChunk
 func alignedSplitSize(remaining int64) int64 {
@@ -171,3 +171,35 @@ func TestActiveTask_GetSpeed_Decay(t *testing.T) {
 		t.Errorf("Extreme decayed speed = %f, want ~100.0", speed)
 	}
 }
+
+func TestWorkerStatuses(t *testing.T) {
+	state := types.NewProgressState("workers-id", 3000)
+	d := NewConcurrentDownloader("workers-id", nil, state, nil)
+
+	for id, offset := range map[int]int64{2: 2000, 0: 0} {
+		at := &ActiveTask{Task: types.Task{Offset: offset, Length: 1000}, URL: "https://mirror/" + string(rune('a'+id)), Speed: 100}
+		at.CurrentOffset.Store(offset + 250)
+		at.StopAt.Store(offset + 1000)
+		d.activeTasks[id] = at
+	}
+	state.SetWorkerSource(d.workerStatuses)
+
+	workers := state.GetWorkers()
+	want := []types.WorkerStatus{
+		{Worker: 0, Start: 0, Offset: 250, End: 1000, Speed: 100, Mirror: "https://mirror/a"},
+		{Worker: 2, Start: 2000, Offset: 2250, End: 3000, Speed: 100, Mirror: "https://mirror/c"},
+	}
+	if len(workers) != len(want) {
+		t.Fatalf("workers = %+v, want %+v", workers, want)
+	}
+	for i := range want {
+		if workers[i] != want[i] {
+			t.Errorf("workers[%d] = %+v, want %+v", i, workers[i], want[i])
+		}
+	}
+
+	state.SetWorkerSource(nil)
+	if workers := state.GetWorkers(); workers != nil {
+		t.Errorf("workers after stop = %+v, want none", workers)
+	}
+}
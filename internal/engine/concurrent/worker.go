@@ -4,27 +4,31 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"os"
 	"sync/atomic"
 	"time"
 
+	"github.com/surge-downloader/surge/internal/engine/ratelimit"
 	"github.com/surge-downloader/surge/internal/engine/types"
 	"github.com/surge-downloader/surge/internal/utils"
 )
 
 // worker downloads tasks from the queue
 func (d *ConcurrentDownloader) worker(ctx context.Context, id int, mirrors []string, file *os.File, queue *TaskQueue, totalSize int64, client *http.Client) error {
-	// Get pooled buffer
-	bufPtr := d.bufPool.Get().(*[]byte)
-	defer d.bufPool.Put(bufPtr)
-	buf := *bufPtr
+	workersRunning.Add(1)
+	defer workersRunning.Add(-1)
+	defer ratelimit.BeginTransfer()()
 
 	utils.Debug("Worker %d started", id)
 	defer utils.Debug("Worker %d finished", id)
 
 	// Initial mirror assignment: Round Robin based on ID
 	currentMirrorIdx := id % len(mirrors)
+	// pinned keeps the current assignment for the next task (initial round robin
+	// or an explicit rotation away from a slow mirror)
+	pinned := true
 
 	for {
 		// Get next task
@@ -34,17 +38,28 @@ func (d *ConcurrentDownloader) worker(ctx context.Context, id int, mirrors []str
 			return nil // Queue closed, no more work
 		}
 
+		// Subsequent tasks go to mirrors weighted by observed performance.
+		// End-game copies race the original from a different mirror when possible.
+		if task.AvoidMirror != "" && len(mirrors) > 1 {
+			currentMirrorIdx = d.mirrorScores.pick(mirrors, task.AvoidMirror)
+		} else if !pinned {
+			currentMirrorIdx = d.mirrorScores.pick(mirrors, "")
+		}
+		pinned = false
+
 		// Update active workers
 		if d.State != nil {
 			d.State.ActiveWorkers.Add(1)
 		}
 
 		var lastErr error
+		rateLimitRetries := 0
 		maxRetries := d.Runtime.GetMaxTaskRetries()
 		for attempt := 0; attempt < maxRetries; attempt++ {
-			if attempt > 0 {
+			if lastErr != nil {
 
-				if len(mirrors) == 1 {
+				// A 429 or Retry-After is waited out by the host cool-down in acquire below
+				if len(mirrors) == 1 && !askedToWait(lastErr) {
 					time.Sleep(time.Duration(1<<attempt) * types.RetryBaseDelay) // Exponential backoff incase of failure
 				}
 
@@ -52,13 +67,40 @@ func (d *ConcurrentDownloader) worker(ctx context.Context, id int, mirrors []str
 				// Report error for the previous mirror
 				d.ReportMirrorError(mirrors[currentMirrorIdx])
 
-				currentMirrorIdx = (currentMirrorIdx + 1) % len(mirrors)
+				currentMirrorIdx = d.mirrorScores.pick(mirrors, mirrors[currentMirrorIdx])
 				utils.Debug("Worker %d: switching to mirror %s (attempt %d)", id, mirrors[currentMirrorIdx], attempt+1)
 			}
 
+			// Don't sit out a rate-limit cool-down while another mirror is free
+			if len(mirrors) > 1 && hostLimits.coolingDown(hostOf(mirrors[currentMirrorIdx])) {
+				currentMirrorIdx = d.mirrorScores.pick(mirrors, mirrors[currentMirrorIdx])
+			}
+
 			// Use current mirror
 			currentURL := mirrors[currentMirrorIdx]
 
+			// Wait for a slot under the host's inferred connection cap
+			host := hostOf(currentURL)
+			if err := hostLimits.acquire(ctx, host); err != nil {
+				queue.Push(task) // Not started yet, keep it for pause/resume
+				if d.State != nil {
+					d.State.ActiveWorkers.Add(-1)
+				}
+				return err
+			}
+
+			// Buffers are only held while a request is in flight so idle
+			// workers don't count against the shared memory budget
+			bufPtr, err := d.getBuffer(ctx)
+			if err != nil {
+				hostLimits.release(host)
+				queue.Push(task)
+				if d.State != nil {
+					d.State.ActiveWorkers.Add(-1)
+				}
+				return err
+			}
+
 			// Register active task with per-task cancellable context
 			taskCtx, taskCancel := context.WithCancel(ctx)
 			now := time.Now()
@@ -68,6 +110,7 @@ func (d *ConcurrentDownloader) worker(ctx context.Context, id int, mirrors []str
 				Cancel:          taskCancel,
 				WindowStart:     now, // Initialize sliding window
 				SharedMaxOffset: task.SharedMaxOffset,
+				URL:             currentURL,
 			}
 			if task.SharedMaxOffset != nil {
 				// Prevent infinite hedging of hedged tasks.
@@ -90,14 +133,38 @@ func (d *ConcurrentDownloader) worker(ctx context.Context, id int, mirrors []str
 			}
 
 			taskStart := time.Now()
-			lastErr = d.downloadTask(taskCtx, currentURL, file, activeTask, buf, client, totalSize)
+			lastErr = d.downloadTask(taskCtx, currentURL, file, activeTask, *bufPtr, client, totalSize)
+			d.putBuffer(bufPtr)
+			hostLimits.release(host)
 
 			// CRITICAL: Capture external cancellation state BEFORE calling taskCancel()
 			// If we call taskCancel() first, taskCtx.Err() will always be non-nil
 			wasExternallyCancelled := taskCtx.Err() != nil
 
 			taskCancel() // Clean up context resources
-			utils.Debug("Worker %d: Task offset=%d length=%d took %v", id, task.Offset, task.Length, time.Since(taskStart))
+			taskElapsed := time.Since(taskStart)
+
+			// Racing end-game copies: the first to cover the range wins and the
+			// rest are cancelled, which is not a failure on their part
+			if activeTask.SharedMaxOffset != nil && activeTask.RemainingTask() == nil {
+				if lastErr == nil {
+					d.cancelRaceLosers(id, activeTask)
+				}
+				lastErr = nil
+			}
+			utils.Debug("Worker %d: Task offset=%d length=%d took %v", id, task.Offset, task.Length, taskElapsed)
+
+			// Feed mirror scoring (pause/shutdown is not the mirror's fault)
+			if ctx.Err() == nil {
+				served := activeTask.CurrentOffset.Load() - task.Offset
+				if lastErr == nil {
+					d.mirrorScores.recordSuccess(currentURL, served, taskElapsed)
+				} else {
+					d.mirrorScores.recordError(currentURL, served, taskElapsed)
+					d.workerErrors.record(id, lastErr, wasExternallyCancelled)
+					d.recordFailure(currentURL, task.Offset, lastErr, wasExternallyCancelled)
+				}
+			}
 
 			// Check for PARENT context cancellation (pause/shutdown)
 			// This preserves active task info for pause handler to collect
@@ -114,9 +181,11 @@ func (d *ConcurrentDownloader) worker(ctx context.Context, id int, mirrors []str
 			if wasExternallyCancelled && lastErr != nil {
 				// Health monitor cancelled this task - re-queue REMAINING work only
 
-				// Force rotation to next mirror to avoid getting stuck on the slow one
-				currentMirrorIdx = (currentMirrorIdx + 1) % len(mirrors)
-				utils.Debug("Worker %d: Health check cancelled task, rotating from mirror %s to %s", id, mirrors[(currentMirrorIdx+len(mirrors)-1)%len(mirrors)], mirrors[currentMirrorIdx])
+				// Force rotation to another mirror to avoid getting stuck on the slow one
+				slowURL := mirrors[currentMirrorIdx]
+				currentMirrorIdx = d.mirrorScores.pick(mirrors, slowURL)
+				pinned = true
+				utils.Debug("Worker %d: Health check cancelled task, rotating from mirror %s to %s", id, slowURL, mirrors[currentMirrorIdx])
 
 				if remaining := activeTask.RemainingTask(); remaining != nil {
 					// Clamp to original task end (don't go past original boundary)
@@ -162,6 +231,12 @@ func (d *ConcurrentDownloader) worker(ctx context.Context, id int, mirrors []str
 			if current > task.Offset {
 				task = types.Task{Offset: current, Length: task.Offset + task.Length - current}
 			}
+
+			// Being told to slow down is not a failure of the task, up to a point
+			if askedToWait(lastErr) && rateLimitRetries < maxRateLimitRetries {
+				rateLimitRetries++
+				attempt--
+			}
 		}
 
 		// Update active workers
@@ -213,9 +288,12 @@ func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string,
 		}
 	}()
 
+	// Tighten the host's connection cap from any parallelism hints it sent
+	d.observeHostHints(hostOf(rawurl), resp)
+
 	// Handle rate limiting explicitly
 	if resp.StatusCode == http.StatusTooManyRequests {
-		return fmt.Errorf("rate limited (429)")
+		return &statusError{code: resp.StatusCode, retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
 	}
 
 	// Validate status code
@@ -226,7 +304,7 @@ func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string,
 			return fmt.Errorf("server indicated success (200) but ignored range request (expected 206)")
 		}
 	} else if resp.StatusCode != http.StatusPartialContent {
-		return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+		return &statusError{code: resp.StatusCode, retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
 	}
 
 	// Batching State
@@ -253,6 +331,12 @@ func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string,
 	// Ensure we flush whatever we have on exit
 	defer flushUpdates()
 
+	// Throttle by the host that actually serves the body (after redirects)
+	host := req.URL.Host
+	if resp.Request != nil {
+		host = resp.Request.URL.Host
+	}
+
 	// Read and write at offset
 	offset := task.Offset
 	for {
@@ -289,6 +373,15 @@ func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string,
 				// to completely fill and hit disk. This prevents the Health Monitor from killing
 				// workers on slightly slower networks during the 500KB buffer acquisition.
 				activeTask.LastActivity.Store(time.Now().UnixNano())
+
+				// Global and per-download speed limits; time spent throttled is not a stall
+				if waitErr := ratelimit.Global.WaitN(ctx, host, n); waitErr != nil {
+					return waitErr
+				}
+				if waitErr := d.limiter.WaitN(ctx, host, n); waitErr != nil {
+					return waitErr
+				}
+				activeTask.LastActivity.Store(time.Now().UnixNano())
 			}
 			if err != nil {
 				readErr = err
@@ -314,6 +407,12 @@ func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string,
 
 			_, writeErr := file.WriteAt(buf[:readSoFar], offset)
 			if writeErr != nil {
+				// Retrying won't free space; pause so the download can be
+				// resumed once there is room
+				if d.State != nil && utils.IsDiskFull(writeErr) {
+					utils.Debug("Worker: disk full writing %s, pausing download", d.ID)
+					d.State.PauseFor(types.PauseDiskFull)
+				}
 				return fmt.Errorf("write error: %w", writeErr)
 			}
 
@@ -366,6 +465,11 @@ func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string,
 				flushUpdates()
 			}
 
+			// A racing worker already finished the rest of this range
+			if activeTask.SharedMaxOffset != nil && activeTask.SharedMaxOffset.Load() >= activeTask.StopAt.Load() {
+				return nil
+			}
+
 			// Update EMA speed using sliding window (2 second window)
 			// This relies on WindowBytes which is updated atomically above, so independent of batching
 			windowElapsed := now.Sub(activeTask.WindowStart).Seconds()
@@ -398,19 +502,52 @@ func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string,
 }
 
 // StealWork tries to split an active task from a busy worker
-// It greedily targets the worker with the MOST remaining work.
+// It targets the worker with the longest estimated time to finish (remaining
+// bytes over its EMA speed), skipping fast workers that would finish their
+// range before a thief could.
 func (d *ConcurrentDownloader) StealWork(queue *TaskQueue) bool {
 	d.activeMu.Lock()
 	defer d.activeMu.Unlock()
 
+	// Mean speed of measured workers approximates how fast a thief would go
+	speeds := make(map[int]float64, len(d.activeTasks))
+	var speedSum float64
+	var measured int
+	for id, active := range d.activeTasks {
+		speed := active.GetSpeed()
+		speeds[id] = speed
+		if speed > 0 {
+			speedSum += speed
+			measured++
+		}
+	}
+	var meanSpeed float64
+	if measured > 0 {
+		meanSpeed = speedSum / float64(measured)
+	}
+
 	bestID := -1
 	var maxRemaining int64 = 0
+	var bestETA time.Duration = -1
 	var bestActive *ActiveTask
 
-	// Find the worker with the MOST remaining work
+	// Find the worker that will take the LONGEST to finish its range.
+	// Remaining bytes break ties (e.g. when no speeds are measured yet).
 	for id, active := range d.activeTasks {
+		// Raced ranges are shared by several workers; splitting one would double count
+		if active.SharedMaxOffset != nil {
+			continue
+		}
 		remaining := active.RemainingBytes()
-		if remaining > types.MinChunk && remaining > maxRemaining {
+		if remaining <= types.MinChunk {
+			continue
+		}
+		eta, worthIt := stealETA(remaining, speeds[id], meanSpeed)
+		if !worthIt {
+			continue
+		}
+		if eta > bestETA || (eta == bestETA && remaining > maxRemaining) {
+			bestETA = eta
 			maxRemaining = remaining
 			bestID = id
 			bestActive = active
@@ -469,11 +606,33 @@ func (d *ConcurrentDownloader) StealWork(queue *TaskQueue) bool {
 	return true
 }
 
-// HedgeWork creates a duplicate task when stealing isn't possible (chunks too small).
-// An idle worker picks up the duplicate and races the original on a fresh HTTP connection.
-// Both workers write identical data to the same file offsets (WriteAt is idempotent),
-// so the file is always correct. Whichever finishes first wins; the other exits
-// naturally when the queue closes or its next read returns data already counted.
+// stealETA estimates how long a worker needs to finish remaining bytes at its
+// current speed. Workers without a speed sample (just started or stalled) sort
+// last-to-finish. worthIt is false when the victim would finish before a fresh
+// connection could download the stolen half at the mean speed.
+func stealETA(remaining int64, speed, meanSpeed float64) (eta time.Duration, worthIt bool) {
+	if speed <= 0 {
+		return time.Duration(math.MaxInt64), true
+	}
+
+	eta = time.Duration(float64(remaining) / speed * float64(time.Second))
+	if meanSpeed > 0 {
+		half := float64(alignedSplitSize(remaining))
+		thiefETA := time.Duration(half/meanSpeed*float64(time.Second)) + stealSetupCost
+		if eta <= thiefETA {
+			return eta, false
+		}
+	}
+	return eta, true
+}
+
+// HedgeWork creates a duplicate task when stealing isn't possible (chunks too small),
+// i.e. in the end game when only the last in-flight chunks remain.
+// An idle worker picks up the duplicate and races the original on a fresh HTTP
+// connection, preferring a different mirror. Both workers write identical data to
+// the same file offsets (WriteAt is idempotent), so the file is always correct.
+// Whichever covers the range first wins and cancels the other, so one stalled
+// connection cannot hold up completion.
 func (d *ConcurrentDownloader) HedgeWork(queue *TaskQueue) bool {
 	d.activeMu.Lock()
 	defer d.activeMu.Unlock()
@@ -482,9 +641,11 @@ func (d *ConcurrentDownloader) HedgeWork(queue *TaskQueue) bool {
 		return false
 	}
 
-	// Find the active task with the most remaining work that hasn't been hedged yet
+	// Race the task that will take the longest to finish (stalled ones first)
+	// that hasn't been hedged yet. Remaining bytes break ties.
 	var bestActive *ActiveTask
 	var maxRemaining int64
+	var bestETA time.Duration = -1
 
 	for _, active := range d.activeTasks {
 		// Skip tasks already being raced
@@ -492,7 +653,12 @@ func (d *ConcurrentDownloader) HedgeWork(queue *TaskQueue) bool {
 			continue
 		}
 		remaining := active.RemainingBytes()
-		if remaining > 0 && remaining > maxRemaining {
+		if remaining <= 0 {
+			continue
+		}
+		eta, _ := stealETA(remaining, active.GetSpeed(), 0)
+		if eta > bestETA || (eta == bestETA && remaining > maxRemaining) {
+			bestETA = eta
 			maxRemaining = remaining
 			bestActive = active
 		}
@@ -526,6 +692,7 @@ func (d *ConcurrentDownloader) HedgeWork(queue *TaskQueue) bool {
 		Offset:          current,
 		Length:          stopAt - current,
 		SharedMaxOffset: bestActive.SharedMaxOffset,
+		AvoidMirror:     bestActive.URL,
 	}
 
 	queue.Push(hedgedTask)
@@ -534,3 +701,20 @@ func (d *ConcurrentDownloader) HedgeWork(queue *TaskQueue) bool {
 
 	return true
 }
+
+// cancelRaceLosers stops the workers still racing a range that winner has
+// just finished
+func (d *ConcurrentDownloader) cancelRaceLosers(winnerID int, winner *ActiveTask) {
+	d.activeMu.Lock()
+	defer d.activeMu.Unlock()
+
+	for id, active := range d.activeTasks {
+		if id == winnerID || active.SharedMaxOffset != winner.SharedMaxOffset || active.Cancel == nil {
+			continue
+		}
+		if active.RemainingTask() == nil {
+			utils.Debug("Worker %d: lost end-game race to worker %d, cancelling", id, winnerID)
+			active.Cancel()
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package concurrent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// statusError is a response status the worker could not use
+type statusError struct {
+	code       int
+	retryAfter time.Duration // Delay the server asked for, 0 if none
+}
+
+func (e *statusError) Error() string {
+	if e.code == http.StatusTooManyRequests {
+		return "rate limited (429)"
+	}
+	return fmt.Sprintf("unexpected status: %d", e.code)
+}
+
+// HTTPStatus returns the response status, for types.HTTPStatus
+func (e *statusError) HTTPStatus() int {
+	return e.code
+}
+
+// workerErrorBoard aggregates failed attempts per worker for a single download
+// so a connection that keeps dying shows up in one summary line.
+type workerErrorBoard struct {
+	mu    sync.Mutex
+	stats map[int]*types.WorkerErrorStats
+}
+
+func newWorkerErrorBoard() *workerErrorBoard {
+	return &workerErrorBoard{stats: make(map[int]*types.WorkerErrorStats)}
+}
+
+// isRateLimited reports whether err is a 429 response
+func isRateLimited(err error) bool {
+	var status *statusError
+	return errors.As(err, &status) && status.code == http.StatusTooManyRequests
+}
+
+// askedToWait reports whether err is the server telling us to slow down,
+// judged like observeHostHints does: a 429, or a 503 carrying Retry-After.
+// The host cool-down handles the wait, so these are not retried like
+// ordinary failures.
+func askedToWait(err error) bool {
+	var status *statusError
+	return errors.As(err, &status) && toldToBackOff(status.code, status.retryAfter)
+}
+
+// record counts a failed attempt. stalled marks attempts the health monitor
+// cancelled, which surface as context errors rather than network failures.
+func (b *workerErrorBoard) record(worker int, err error, stalled bool) {
+	if b == nil || (err == nil && !stalled) {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s, ok := b.stats[worker]
+	if !ok {
+		s = &types.WorkerErrorStats{Worker: worker}
+		b.stats[worker] = s
+	}
+
+	switch workerErrorKind(err, stalled) {
+	case "stall":
+		s.Stalls++
+	case "429":
+		s.RateLimited++
+	case "5xx":
+		s.ServerErrors++
+	case "timeout":
+		s.Timeouts++
+	case "reset":
+		s.Resets++
+	default:
+		s.Other++
+	}
+}
+
+// workerErrorKind sorts a failed attempt into one of types.WorkerErrorKinds
+func workerErrorKind(err error, stalled bool) string {
+	var status *statusError
+	switch {
+	case stalled:
+		return "stall"
+	case isRateLimited(err):
+		return "429"
+	case errors.As(err, &status) && status.code >= 500:
+		return "5xx"
+	case isTimeoutError(err):
+		return "timeout"
+	case isResetError(err):
+		return "reset"
+	}
+	return "other"
+}
+
+// recordFailure adds a failed attempt at the task starting at offset to the
+// download's error history
+func (d *ConcurrentDownloader) recordFailure(url string, offset int64, err error, stalled bool) {
+	if d.ID == "" || (err == nil && !stalled) {
+		return
+	}
+	f := types.DownloadFailure{
+		At:     time.Now().Unix(),
+		Kind:   workerErrorKind(err, stalled),
+		Status: types.HTTPStatus(err),
+		Mirror: url,
+		Offset: offset,
+	}
+	if stalled {
+		f.Message = "stalled, restarted by the health monitor"
+	} else {
+		f.Message = err.Error()
+	}
+	if err := state.RecordDownloadFailure(d.ID, f); err != nil {
+		utils.Debug("Failed to record error history for %s: %v", d.ID, err)
+	}
+}
+
+// snapshot returns the per-worker counts ordered by worker id
+func (b *workerErrorBoard) snapshot() []types.WorkerErrorStats {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	out := make([]types.WorkerErrorStats, 0, len(b.stats))
+	for _, s := range b.stats {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Worker < out[j].Worker })
+	return out
+}
+
+// logSummary writes the error heatmap to the debug log, if any worker failed.
+func (b *workerErrorBoard) logSummary(downloadID string) {
+	lines := types.FormatWorkerErrorHeatmap(b.snapshot())
+	if len(lines) == 0 {
+		return
+	}
+	utils.Debug("Worker errors [%s]:\n  %s", downloadID, strings.Join(lines, "\n  "))
+}
+
+func isTimeoutError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func isResetError(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		strings.Contains(err.Error(), "connection reset")
+}
@@ -0,0 +1,107 @@
+package concurrent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestWorkerErrorBoard_ClassifiesErrors(t *testing.T) {
+	b := newWorkerErrorBoard()
+
+	b.record(2, fmt.Errorf("read error: %w", syscall.ECONNRESET), false)
+	b.record(2, fmt.Errorf("read error: %w", io.ErrUnexpectedEOF), false)
+	b.record(2, context.DeadlineExceeded, false)
+	b.record(0, &statusError{code: 503}, false)
+	b.record(0, &statusError{code: 429}, false)
+	b.record(0, &statusError{code: 404}, false)
+	b.record(1, context.Canceled, true)
+	b.record(1, nil, false) // Successes are not counted
+
+	got := b.snapshot()
+	want := []types.WorkerErrorStats{
+		{Worker: 0, ServerErrors: 1, RateLimited: 1, Other: 1},
+		{Worker: 1, Stalls: 1},
+		{Worker: 2, Timeouts: 1, Resets: 2},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("snapshot = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("worker stats[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestStatusError_KeepsMessages(t *testing.T) {
+	if got := (&statusError{code: 429}).Error(); got != "rate limited (429)" {
+		t.Errorf("429 message = %q", got)
+	}
+	if got := (&statusError{code: 502}).Error(); got != "unexpected status: 502" {
+		t.Errorf("502 message = %q", got)
+	}
+}
+
+func TestStatusError_HTTPStatus(t *testing.T) {
+	if got := types.HTTPStatus(fmt.Errorf("attempt 3: %w", &statusError{code: 503})); got != 503 {
+		t.Errorf("HTTPStatus of a wrapped 503 = %d", got)
+	}
+	if got := types.HTTPStatus(io.ErrUnexpectedEOF); got != 0 {
+		t.Errorf("HTTPStatus without a response = %d, want 0", got)
+	}
+}
+
+func TestFormatWorkerErrorHeatmap(t *testing.T) {
+	if lines := types.FormatWorkerErrorHeatmap([]types.WorkerErrorStats{{Worker: 0}}); lines != nil {
+		t.Fatalf("expected no heatmap without errors, got %q", lines)
+	}
+
+	lines := types.FormatWorkerErrorHeatmap([]types.WorkerErrorStats{
+		{Worker: 0},
+		{Worker: 3, Resets: 8},
+		{Worker: 5, Resets: 1, Timeouts: 2},
+	})
+	if len(lines) != 3 {
+		t.Fatalf("expected header and two failing workers, got %q", lines)
+	}
+	for _, kind := range types.WorkerErrorKinds {
+		if !strings.Contains(lines[0], kind) {
+			t.Errorf("header %q missing %q", lines[0], kind)
+		}
+	}
+	if !strings.HasPrefix(lines[1], "#3") || !strings.Contains(lines[1], "█ 8") {
+		t.Errorf("expected worker 3 to be the hottest row, got %q", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], "#5") || !strings.Contains(lines[2], "░ 1") {
+		t.Errorf("expected worker 5 to be shaded lightly, got %q", lines[2])
+	}
+}
+
+func TestAskedToWait_MatchesHostBackOff(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&statusError{code: http.StatusTooManyRequests}, true},
+		{&statusError{code: http.StatusTooManyRequests, retryAfter: time.Second}, true},
+		{&statusError{code: http.StatusServiceUnavailable, retryAfter: time.Second}, true},
+		{&statusError{code: http.StatusServiceUnavailable}, false},
+		{&statusError{code: http.StatusForbidden, retryAfter: time.Second}, false},
+		{&statusError{code: http.StatusMovedPermanently, retryAfter: time.Second}, false},
+		{fmt.Errorf("wrapped: %w", &statusError{code: http.StatusTooManyRequests}), true},
+		{io.ErrUnexpectedEOF, false},
+	}
+	for _, tt := range tests {
+		if got := askedToWait(tt.err); got != tt.want {
+			t.Errorf("askedToWait(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -21,8 +21,13 @@ func TestEventTypeForMessage(t *testing.T) {
 		{name: "resumed", msg: DownloadResumedMsg{}, wantType: EventTypeResumed, wantFound: true},
 		{name: "queued", msg: DownloadQueuedMsg{}, wantType: EventTypeQueued, wantFound: true},
 		{name: "removed", msg: DownloadRemovedMsg{}, wantType: EventTypeRemoved, wantFound: true},
+		{name: "restored", msg: DownloadRestoredMsg{}, wantType: EventTypeRestored, wantFound: true},
 		{name: "request", msg: DownloadRequestMsg{}, wantType: EventTypeRequest, wantFound: true},
 		{name: "system", msg: SystemLogMsg{}, wantType: EventTypeSystem, wantFound: true},
+		{name: "phase", msg: DownloadPhaseMsg{}, wantType: EventTypePhase, wantFound: true},
+		{name: "note", msg: DownloadNoteMsg{}, wantType: EventTypeNote, wantFound: true},
+		{name: "verified", msg: DownloadVerifiedMsg{}, wantType: EventTypeVerified, wantFound: true},
+		{name: "moved", msg: DownloadMovedMsg{}, wantType: EventTypeMoved, wantFound: true},
 		{name: "unknown", msg: struct{}{}, wantType: "", wantFound: false},
 	}
 
@@ -29,6 +29,7 @@ type DownloadCompleteMsg struct {
 	Elapsed    time.Duration
 	Total      int64
 	AvgSpeed   float64 // Average download speed in bytes/sec
+	TraceID    string  `json:",omitempty"`
 }
 
 // DownloadErrorMsg signals that an error occurred
@@ -37,6 +38,7 @@ type DownloadErrorMsg struct {
 	Filename   string
 	DestPath   string
 	Err        error
+	TraceID    string
 }
 
 func (m DownloadErrorMsg) MarshalJSON() ([]byte, error) {
@@ -45,12 +47,14 @@ func (m DownloadErrorMsg) MarshalJSON() ([]byte, error) {
 		Filename   string `json:"Filename,omitempty"`
 		DestPath   string `json:"DestPath,omitempty"`
 		Err        string `json:"Err,omitempty"`
+		TraceID    string `json:"TraceID,omitempty"`
 	}
 
 	out := encoded{
 		DownloadID: m.DownloadID,
 		Filename:   m.Filename,
 		DestPath:   m.DestPath,
+		TraceID:    m.TraceID,
 	}
 	if m.Err != nil {
 		out.Err = m.Err.Error()
@@ -104,15 +108,28 @@ type DownloadStartedMsg struct {
 	Total      int64
 	DestPath   string               // Full path to the destination file
 	State      *types.ProgressState `json:"-"`
+	Tags       []string             `json:",omitempty"`
+	Category   string               `json:",omitempty"`
+	TraceID    string               `json:",omitempty"`
 }
 
 type DownloadPausedMsg struct {
 	DownloadID string
 	Filename   string
 	Downloaded int64
+	Reason     string               // One of the types.Pause* values
 	State      *types.DownloadState `json:"-"`
 }
 
+// DownloadPhaseMsg reports progress through the post-download phases
+// (verifying, extracting, moving) and their end (PhaseComplete)
+type DownloadPhaseMsg struct {
+	DownloadID string
+	Filename   string
+	Phase      string
+	Progress   float64 // Percentage 0-100 within the phase
+}
+
 type DownloadResumedMsg struct {
 	DownloadID string
 	Filename   string
@@ -124,6 +141,12 @@ type DownloadQueuedMsg struct {
 	URL        string
 	DestPath   string
 	Mirrors    []string
+	Tags       []string `json:",omitempty"`
+	Category   string   `json:",omitempty"`
+	TraceID    string   `json:",omitempty"`
+
+	// Overrides go to the database only: their headers may hold credentials
+	Overrides *types.DownloadOverrides `json:"-"`
 }
 
 type DownloadRemovedMsg struct {
@@ -131,6 +154,42 @@ type DownloadRemovedMsg struct {
 	Filename   string
 	DestPath   string
 	Completed  bool
+	Archived   bool `json:",omitempty"` // Partial data was kept for restore-partial
+	Trashed    bool `json:",omitempty"` // Moved to the trash; data is kept until it expires
+}
+
+// DownloadRestoredMsg brings a download back from the trash into the list
+type DownloadRestoredMsg struct {
+	DownloadID string
+	URL        string
+	Filename   string
+	DestPath   string
+	Status     string // "paused" or "completed"
+	TotalSize  int64
+	Downloaded int64
+	Tags       []string `json:",omitempty"`
+}
+
+// DownloadNoteMsg carries a download's note and metadata after they change
+type DownloadNoteMsg struct {
+	DownloadID string
+	Note       string            `json:",omitempty"`
+	Metadata   map[string]string `json:",omitempty"`
+}
+
+// DownloadMovedMsg is sent when a download's file is moved to another
+// directory or renamed
+type DownloadMovedMsg struct {
+	DownloadID string
+	Filename   string
+	DestPath   string // Full path to the file in its new place
+}
+
+// DownloadVerifiedMsg carries the outcome of re-hashing a completed
+// download's file on request
+type DownloadVerifiedMsg struct {
+	DownloadID string
+	Result     types.VerifyResult
 }
 
 // SystemLogMsg carries informational system-level log messages for clients/UI.
@@ -150,6 +209,8 @@ type DownloadRequestMsg struct {
 	Path     string
 	Mirrors  []string
 	Headers  map[string]string
+	Tags     []string
+	Category string
 }
 
 const (
@@ -161,8 +222,13 @@ const (
 	EventTypeResumed  = "resumed"
 	EventTypeQueued   = "queued"
 	EventTypeRemoved  = "removed"
+	EventTypeRestored = "restored"
 	EventTypeRequest  = "request"
 	EventTypeSystem   = "system"
+	EventTypePhase    = "phase"
+	EventTypeNote     = "note"
+	EventTypeVerified = "verified"
+	EventTypeMoved    = "moved"
 )
 
 // SSEMessage represents one server-sent event frame.
@@ -223,10 +289,20 @@ func EventTypeForMessage(msg interface{}) (string, bool) {
 		return EventTypeQueued, true
 	case DownloadRemovedMsg:
 		return EventTypeRemoved, true
+	case DownloadRestoredMsg:
+		return EventTypeRestored, true
 	case DownloadRequestMsg:
 		return EventTypeRequest, true
 	case SystemLogMsg:
 		return EventTypeSystem, true
+	case DownloadPhaseMsg:
+		return EventTypePhase, true
+	case DownloadNoteMsg:
+		return EventTypeNote, true
+	case DownloadVerifiedMsg:
+		return EventTypeVerified, true
+	case DownloadMovedMsg:
+		return EventTypeMoved, true
 	default:
 		return "", false
 	}
@@ -285,6 +361,12 @@ func DecodeSSEMessage(eventType string, data []byte) (interface{}, bool, error)
 			return nil, true, err
 		}
 		msg = m
+	case EventTypeRestored:
+		var m DownloadRestoredMsg
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, true, err
+		}
+		msg = m
 	case EventTypeRequest:
 		var m DownloadRequestMsg
 		if err := json.Unmarshal(data, &m); err != nil {
@@ -297,6 +379,30 @@ func DecodeSSEMessage(eventType string, data []byte) (interface{}, bool, error)
 			return nil, true, err
 		}
 		msg = m
+	case EventTypePhase:
+		var m DownloadPhaseMsg
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, true, err
+		}
+		msg = m
+	case EventTypeNote:
+		var m DownloadNoteMsg
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, true, err
+		}
+		msg = m
+	case EventTypeVerified:
+		var m DownloadVerifiedMsg
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, true, err
+		}
+		msg = m
+	case EventTypeMoved:
+		var m DownloadMovedMsg
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, true, err
+		}
+		msg = m
 	default:
 		return nil, false, nil
 	}
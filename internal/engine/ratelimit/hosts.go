@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"net"
+	"strings"
+)
+
+// hostPattern is a parsed exemption entry
+type hostPattern struct {
+	cidr *net.IPNet
+	host string // lowercase hostname or IP literal
+}
+
+// parseHostPattern accepts:
+//   - "nas.local" or "*.example.com": the host and any of its subdomains
+//   - "192.168.1.10" or "::1": an exact IP literal
+//   - "192.168.0.0/16": any IP literal in the range
+//
+// CIDR entries only match URLs that use an IP address; hostnames are not resolved.
+func parseHostPattern(pattern string) (hostPattern, bool) {
+	p := strings.ToLower(strings.TrimSpace(pattern))
+	p = strings.TrimPrefix(p, "*.")
+	p = strings.TrimPrefix(p, ".")
+	if p == "" {
+		return hostPattern{}, false
+	}
+	if strings.Contains(p, "/") {
+		_, ipNet, err := net.ParseCIDR(p)
+		if err != nil {
+			return hostPattern{}, false
+		}
+		return hostPattern{cidr: ipNet}, true
+	}
+	return hostPattern{host: stripPort(p)}, true
+}
+
+func (p hostPattern) match(host string) bool {
+	h := strings.ToLower(stripPort(strings.TrimSpace(host)))
+	if h == "" {
+		return false
+	}
+	if p.cidr != nil {
+		ip := net.ParseIP(h)
+		return ip != nil && p.cidr.Contains(ip)
+	}
+	return h == p.host || strings.HasSuffix(h, "."+p.host)
+}
+
+// stripPort removes an optional ":port" and IPv6 brackets
+func stripPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+}
@@ -0,0 +1,153 @@
+// Package ratelimit provides the process-wide bandwidth limiter shared by all
+// downloads, with per-host exemptions, and the pacing of probe requests.
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Global is the limiter applied to every download in this process.
+var Global = NewLimiter(0)
+
+// Limiter is a token bucket measured in bytes. A rate <= 0 disables throttling.
+// Reads larger than the bucket go into debt, so callers may pass any buffer size.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   int64
+	tokens float64
+	last   time.Time
+	exempt []hostPattern
+}
+
+// NewLimiter creates a limiter allowing rate bytes/sec (0 = unlimited)
+func NewLimiter(rate int64) *Limiter {
+	return &Limiter{rate: rate, tokens: float64(max(rate, 0)), last: time.Now()}
+}
+
+// Configure sets the rate and the hosts that bypass it in one step
+func (l *Limiter) Configure(rate int64, exemptHosts []string) {
+	l.SetRate(rate)
+	l.SetExemptHosts(exemptHosts)
+}
+
+// SetRate changes the limit; in-flight waits pick it up on their next call
+func (l *Limiter) SetRate(rate int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if rate == l.rate {
+		return
+	}
+	l.rate = rate
+	l.tokens = float64(max(rate, 0))
+	l.last = time.Now()
+}
+
+// Rate returns the current limit in bytes/sec (0 = unlimited)
+func (l *Limiter) Rate() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return max(l.rate, 0)
+}
+
+// SetExemptHosts replaces the host patterns that are never throttled.
+// See parseHostPattern for the accepted pattern forms.
+func (l *Limiter) SetExemptHosts(patterns []string) {
+	parsed := make([]hostPattern, 0, len(patterns))
+	for _, p := range patterns {
+		if hp, ok := parseHostPattern(p); ok {
+			parsed = append(parsed, hp)
+		}
+	}
+	l.mu.Lock()
+	l.exempt = parsed
+	l.mu.Unlock()
+}
+
+// IsExempt reports whether host (optionally with port) bypasses the limit
+func (l *Limiter) IsExempt(host string) bool {
+	l.mu.Lock()
+	exempt := l.exempt
+	l.mu.Unlock()
+	for _, p := range exempt {
+		if p.match(host) {
+			return true
+		}
+	}
+	return false
+}
+
+// WaitN blocks until n bytes may be consumed from host or ctx is done
+func (l *Limiter) WaitN(ctx context.Context, host string, n int) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+	if l.IsExempt(host) {
+		return nil
+	}
+
+	wait := l.reserve(n)
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// reserve takes n tokens and returns how long the caller must wait for them
+func (l *Limiter) reserve(n int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.rate <= 0 {
+		return 0
+	}
+
+	now := time.Now()
+	burst := float64(l.rate)
+	l.tokens += now.Sub(l.last).Seconds() * float64(l.rate)
+	if l.tokens > burst {
+		l.tokens = burst
+	}
+	l.last = now
+
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / float64(l.rate) * float64(time.Second))
+}
+
+// Reader wraps r so reads from host are throttled. Exempt hosts get r unchanged.
+func (l *Limiter) Reader(ctx context.Context, r io.Reader, host string) io.Reader {
+	if l == nil || l.IsExempt(host) {
+		return r
+	}
+	return &limitedReader{ctx: ctx, r: r, l: l, host: host}
+}
+
+type limitedReader struct {
+	ctx  context.Context
+	r    io.Reader
+	l    *Limiter
+	host string
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	n, err := lr.r.Read(p)
+	if n > 0 {
+		if waitErr := lr.l.WaitN(lr.ctx, lr.host, n); waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestLimiter_UnlimitedDoesNotWait(t *testing.T) {
+	l := NewLimiter(0)
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		if err := l.WaitN(context.Background(), "example.com", 1<<20); err != nil {
+			t.Fatalf("WaitN failed: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("unlimited limiter waited %v", elapsed)
+	}
+}
+
+func TestLimiter_ThrottlesToRate(t *testing.T) {
+	l := NewLimiter(100 * 1024) // 100 KB/s, 100 KB burst
+
+	start := time.Now()
+	// Burst plus 20 KB of debt: ~200ms
+	for i := 0; i < 12; i++ {
+		if err := l.WaitN(context.Background(), "example.com", 10*1024); err != nil {
+			t.Fatalf("WaitN failed: %v", err)
+		}
+	}
+	elapsed := time.Since(start)
+	if elapsed < 150*time.Millisecond {
+		t.Errorf("expected throttling, took %v", elapsed)
+	}
+	if elapsed > 2*time.Second {
+		t.Errorf("throttled far more than expected: %v", elapsed)
+	}
+}
+
+func TestLimiter_ExemptHostBypassesLimit(t *testing.T) {
+	l := NewLimiter(1024)
+	l.SetExemptHosts([]string{"nas.local", "10.0.0.0/8"})
+
+	start := time.Now()
+	for _, host := range []string{"nas.local", "media.nas.local:8080", "10.1.2.3", "10.1.2.3:443"} {
+		if err := l.WaitN(context.Background(), host, 1<<20); err != nil {
+			t.Fatalf("WaitN(%s) failed: %v", host, err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("exempt hosts were throttled: %v", elapsed)
+	}
+}
+
+func TestLimiter_WaitRespectsContext(t *testing.T) {
+	l := NewLimiter(1024)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// Consume the burst then go deep into debt
+	if err := l.WaitN(ctx, "example.com", 1024*100); err == nil {
+		t.Fatal("expected context error while throttled")
+	}
+}
+
+func TestLimiter_SetRateAppliesToLaterWaits(t *testing.T) {
+	l := NewLimiter(1024)
+	l.SetRate(0)
+	if got := l.Rate(); got != 0 {
+		t.Errorf("Rate() = %d, want 0", got)
+	}
+	if err := l.WaitN(context.Background(), "example.com", 1<<20); err != nil {
+		t.Fatalf("WaitN failed: %v", err)
+	}
+}
+
+func TestLimiter_ReaderPassthroughForExemptHost(t *testing.T) {
+	l := NewLimiter(1024)
+	l.SetExemptHosts([]string{"nas.local"})
+
+	src := bytes.NewReader([]byte("hello"))
+	if r := l.Reader(context.Background(), src, "nas.local"); r != io.Reader(src) {
+		t.Error("expected exempt host reader to be returned unwrapped")
+	}
+	if r := l.Reader(context.Background(), src, "example.com"); r == io.Reader(src) {
+		t.Error("expected non-exempt host reader to be wrapped")
+	}
+}
+
+func TestHostPatternMatching(t *testing.T) {
+	tests := []struct {
+		pattern string
+		host    string
+		want    bool
+	}{
+		{"nas.local", "nas.local", true},
+		{"nas.local", "NAS.local:5000", true},
+		{"nas.local", "files.nas.local", true},
+		{"nas.local", "evilnas.local", false},
+		{"*.example.com", "cdn.example.com", true},
+		{"*.example.com", "example.com", true},
+		{"192.168.1.10", "192.168.1.10:8080", true},
+		{"192.168.1.10", "192.168.1.11", false},
+		{"192.168.0.0/16", "192.168.44.2", true},
+		{"192.168.0.0/16", "10.0.0.1", false},
+		{"192.168.0.0/16", "nas.local", false},
+		{"fd00::/8", "[fd00::1]:80", true},
+	}
+
+	for _, tt := range tests {
+		p, ok := parseHostPattern(tt.pattern)
+		if !ok {
+			t.Fatalf("parseHostPattern(%q) failed", tt.pattern)
+		}
+		if got := p.match(tt.host); got != tt.want {
+			t.Errorf("match(%q, %q) = %v, want %v", tt.pattern, tt.host, got, tt.want)
+		}
+	}
+
+	if _, ok := parseHostPattern("  "); ok {
+		t.Error("expected empty pattern to be rejected")
+	}
+	if _, ok := parseHostPattern("300.0.0.0/8"); ok {
+		t.Error("expected invalid CIDR to be rejected")
+	}
+}
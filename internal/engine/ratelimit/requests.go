@@ -0,0 +1,127 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RequestPolicy bounds a class of short control requests
+type RequestPolicy struct {
+	Rate       float64 // Requests started per second, also the burst size
+	Concurrent int     // Requests in flight at once
+}
+
+// Probes paces probe and mirror-check requests. They yield to downloads:
+// while any transfer is running the stricter busy policy applies, so adding a
+// batch with many mirrors does not flood the link that downloads are using.
+var Probes = NewRequestLimiter(
+	RequestPolicy{Rate: 20, Concurrent: 8},
+	RequestPolicy{Rate: 2, Concurrent: 2},
+)
+
+// requestRecheck bounds how long a waiter sleeps before re-reading the policy,
+// which changes when transfers start or stop
+const requestRecheck = 250 * time.Millisecond
+
+// transfers counts download streams currently moving data
+var transfers atomic.Int64
+
+// BeginTransfer marks a download stream as active until the returned func is
+// called. Request limiters switch to their busy policy while any are active.
+func BeginTransfer() (end func()) {
+	transfers.Add(1)
+	var once sync.Once
+	return func() { once.Do(func() { transfers.Add(-1) }) }
+}
+
+// TransfersActive reports whether any download stream is running
+func TransfersActive() bool {
+	return transfers.Load() > 0
+}
+
+// RequestLimiter is a token bucket over request starts combined with a cap on
+// requests in flight
+type RequestLimiter struct {
+	mu       sync.Mutex
+	idle     RequestPolicy
+	busy     RequestPolicy
+	tokens   float64
+	last     time.Time
+	inFlight int
+	wake     chan struct{} // Closed and replaced whenever a request finishes
+}
+
+// NewRequestLimiter creates a limiter using idle normally and busy while
+// transfers are active
+func NewRequestLimiter(idle, busy RequestPolicy) *RequestLimiter {
+	return &RequestLimiter{
+		idle:   idle,
+		busy:   busy,
+		tokens: idle.Rate,
+		last:   time.Now(),
+		wake:   make(chan struct{}),
+	}
+}
+
+func (l *RequestLimiter) policyLocked() RequestPolicy {
+	if TransfersActive() {
+		return l.busy
+	}
+	return l.idle
+}
+
+// Acquire blocks until a request may start or ctx is done. The returned
+// release must be called once the request has finished.
+func (l *RequestLimiter) Acquire(ctx context.Context) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	for {
+		l.mu.Lock()
+		p := l.policyLocked()
+		now := time.Now()
+		l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*p.Rate, max(p.Rate, 1))
+		l.last = now
+
+		if (p.Concurrent <= 0 || l.inFlight < p.Concurrent) && (p.Rate <= 0 || l.tokens >= 1) {
+			if p.Rate > 0 {
+				l.tokens--
+			}
+			l.inFlight++
+			l.mu.Unlock()
+
+			var once sync.Once
+			return func() { once.Do(l.release) }, nil
+		}
+
+		wait := requestRecheck
+		if p.Rate > 0 && l.tokens < 1 {
+			wait = min(wait, time.Duration((1-l.tokens)/p.Rate*float64(time.Second)))
+		}
+		wake := l.wake
+		l.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+func (l *RequestLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inFlight > 0 {
+		l.inFlight--
+	}
+	close(l.wake)
+	l.wake = make(chan struct{})
+}
@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRequestLimiter_CapsInFlight(t *testing.T) {
+	l := NewRequestLimiter(RequestPolicy{Rate: 100, Concurrent: 2}, RequestPolicy{Rate: 100, Concurrent: 1})
+
+	r1, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx); err == nil {
+		t.Fatal("expected a third request to wait for a free slot")
+	}
+
+	acquired := make(chan error, 1)
+	go func() {
+		_, err := l.Acquire(context.Background())
+		acquired <- err
+	}()
+	r1()
+	r1() // Releasing twice must not free a second slot
+
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatalf("acquire after release failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("release did not wake the waiting request")
+	}
+	if l.inFlight != 2 {
+		t.Errorf("expected 2 requests in flight, got %d", l.inFlight)
+	}
+}
+
+func TestRequestLimiter_YieldsToTransfers(t *testing.T) {
+	l := NewRequestLimiter(RequestPolicy{Rate: 1000, Concurrent: 10}, RequestPolicy{Rate: 10, Concurrent: 10})
+
+	// The idle policy lets a burst straight through
+	start := time.Now()
+	for range 10 {
+		release, err := l.Acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("idle burst was throttled: %v", elapsed)
+	}
+
+	end := BeginTransfer()
+	defer end()
+	if !TransfersActive() {
+		t.Fatal("expected a transfer to be active")
+	}
+
+	// Busy: the bucket holds at most 10 tokens at 10/s, so 15 more take ~0.5s
+	start = time.Now()
+	for range 15 {
+		release, err := l.Acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("expected probes to slow down during transfers, took %v", elapsed)
+	}
+
+	end()
+	end()
+	if TransfersActive() {
+		t.Error("expected ending a transfer twice to be harmless")
+	}
+}
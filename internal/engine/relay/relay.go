@@ -0,0 +1,229 @@
+// Package relay lets a Surge daemon fetch byte ranges on behalf of another.
+//
+// In coordinator mode the downloading instance adds each peer daemon as an
+// extra mirror of the file: the mirror URL points at the peer's /relay
+// endpoint, which fetches the requested range from the upstream over the
+// peer's own link and streams it back. The coordinator's workers, progress
+// and failover treat a peer exactly like any other mirror.
+//
+// Relay URLs carry an HMAC of the upstream URL keyed with the peer's API
+// token instead of the token itself, since mirror URLs are persisted and
+// shown in the UI.
+//
+// A daemon only serves relays when it is set up as a peer, and by default
+// refuses upstreams on loopback, private or link-local addresses so a
+// coordinator cannot reach into the peer's network.
+package relay
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/surge-downloader/surge/internal/engine/ratelimit"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// ErrPrivateTarget means a relay upstream resolved to an address the peer
+// does not relay to
+var ErrPrivateTarget = errors.New("relay target is a private or loopback address")
+
+// Path is where daemons serve the relay endpoint
+const Path = "/relay"
+
+// hopHeaders are connection-scoped and never forwarded
+var hopHeaders = []string{
+	"Authorization",
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// Sign returns the signature a peer with token accepts for upstream
+func Sign(token, upstream string) string {
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write([]byte(upstream))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// URL returns the mirror URL that fetches upstream through the peer daemon at
+// peerURL
+func URL(peerURL, token, upstream string) string {
+	q := url.Values{}
+	q.Set("url", upstream)
+	q.Set("sig", Sign(token, upstream))
+	return strings.TrimRight(peerURL, "/") + Path + "?" + q.Encode()
+}
+
+// IsRelayURL reports whether rawurl points at a relay endpoint
+func IsRelayURL(rawurl string) bool {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return false
+	}
+	return strings.HasSuffix(u.Path, Path) && u.Query().Get("sig") != ""
+}
+
+func verify(token, upstream, sig string) bool {
+	if token == "" || sig == "" {
+		return false
+	}
+	want, err := hex.DecodeString(Sign(token, upstream))
+	if err != nil {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(want, got)
+}
+
+// NewClient returns the client relayed ranges are fetched with, honoring
+// proxyURL like local downloads do. Unless allowPrivate is set, connections
+// to loopback, private and link-local addresses are refused; the check runs
+// on the resolved address, so a public name pointing inward is caught too.
+// A configured proxy may itself be on such an address.
+func NewClient(proxyURL string, allowPrivate bool) *http.Client {
+	proxy := http.ProxyFromEnvironment
+	if proxyURL != "" {
+		if parsed, err := url.Parse(proxyURL); err == nil {
+			proxy = http.ProxyURL(parsed)
+		} else {
+			utils.Debug("Invalid proxy URL %s: %v", proxyURL, err)
+		}
+	}
+
+	dialer := &net.Dialer{Timeout: types.DialTimeout, KeepAlive: types.KeepAliveDuration}
+	transport := &http.Transport{
+		Proxy:              proxy,
+		DisableCompression: true,
+		DialContext:        dialer.DialContext,
+	}
+	if !allowPrivate {
+		var proxies sync.Map // host:port of proxies in use, which may be private
+		transport.Proxy = func(r *http.Request) (*url.URL, error) {
+			u, err := proxy(r)
+			if u != nil {
+				proxies.Store(hostPort(u), true)
+			}
+			return u, err
+		}
+		guarded := *dialer
+		guarded.Control = refusePrivate
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if _, ok := proxies.Load(addr); ok {
+				return dialer.DialContext(ctx, network, addr)
+			}
+			return guarded.DialContext(ctx, network, addr)
+		}
+	}
+	return &http.Client{Transport: transport}
+}
+
+// refusePrivate is a net.Dialer Control that rejects non-public addresses
+func refusePrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return fmt.Errorf("%w: %s", ErrPrivateTarget, host)
+	}
+	return nil
+}
+
+func hostPort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return net.JoinHostPort(u.Hostname(), port)
+	}
+	if u.Scheme == "https" {
+		return net.JoinHostPort(u.Hostname(), "443")
+	}
+	return net.JoinHostPort(u.Hostname(), "80")
+}
+
+// Handler serves relay requests signed with token. Request headers (Range,
+// cookies, ...) are passed upstream and the upstream response is streamed
+// back unchanged, so ranged requests keep their 206 and Content-Range.
+// A nil client is NewClient("", false).
+func Handler(token string, client *http.Client) http.Handler {
+	if client == nil {
+		client = NewClient("", false)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		upstream := r.URL.Query().Get("url")
+		if !verify(token, upstream, r.URL.Query().Get("sig")) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		target, err := url.Parse(upstream)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			http.Error(w, "Invalid url parameter", http.StatusBadRequest)
+			return
+		}
+
+		req, err := http.NewRequestWithContext(r.Context(), r.Method, upstream, nil)
+		if err != nil {
+			http.Error(w, "Invalid url parameter", http.StatusBadRequest)
+			return
+		}
+		req.Header = r.Header.Clone()
+		for _, h := range hopHeaders {
+			req.Header.Del(h)
+		}
+
+		// Relayed ranges use this daemon's link like its own downloads do
+		defer ratelimit.BeginTransfer()()
+
+		resp, err := client.Do(req)
+		if err != nil {
+			utils.Debug("Relay: upstream %s failed: %v", target.Host, err)
+			if errors.Is(err, ErrPrivateTarget) {
+				http.Error(w, "Relay target not allowed", http.StatusForbidden)
+				return
+			}
+			http.Error(w, "Upstream request failed", http.StatusBadGateway)
+			return
+		}
+		defer func() { _ = resp.Body.Close() }()
+
+		for k, vv := range resp.Header {
+			for _, v := range vv {
+				w.Header().Add(k, v)
+			}
+		}
+		for _, h := range hopHeaders {
+			w.Header().Del(h)
+		}
+		w.WriteHeader(resp.StatusCode)
+		if _, err := io.Copy(w, resp.Body); err != nil {
+			utils.Debug("Relay: stream from %s ended early: %v", target.Host, err)
+		}
+	})
+}
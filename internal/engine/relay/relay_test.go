@@ -0,0 +1,227 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/concurrent"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/testutil"
+)
+
+const testToken = "peer-token"
+
+func newUpstream(t *testing.T, data []byte, hits *int32) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits != nil {
+			atomic.AddInt32(hits, 1)
+		}
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "file.bin", time.Unix(0, 0), bytes.NewReader(data))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestURL_RoundTrip(t *testing.T) {
+	upstream := "https://example.com/file.iso?x=1&y=2"
+	u := URL("http://10.0.0.5:1700/", testToken, upstream)
+
+	if !strings.HasPrefix(u, "http://10.0.0.5:1700/relay?") {
+		t.Fatalf("URL = %s", u)
+	}
+	if strings.Contains(u, testToken) {
+		t.Fatal("relay URL must not contain the peer token")
+	}
+	if !IsRelayURL(u) {
+		t.Errorf("IsRelayURL(%s) = false", u)
+	}
+	if IsRelayURL(upstream) {
+		t.Errorf("IsRelayURL(%s) = true", upstream)
+	}
+	if !verify(testToken, upstream, Sign(testToken, upstream)) {
+		t.Error("signature does not verify")
+	}
+	if verify("other-token", upstream, Sign(testToken, upstream)) {
+		t.Error("signature verified with the wrong token")
+	}
+	if verify(testToken, upstream+"&z=3", Sign(testToken, upstream)) {
+		t.Error("signature verified for a different URL")
+	}
+}
+
+func TestHandler_RelaysRange(t *testing.T) {
+	data := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	upstream := newUpstream(t, data, nil)
+	peer := httptest.NewServer(Handler(testToken, NewClient("", true)))
+	defer peer.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, URL(peer.URL, testToken, upstream.URL+"/file.bin"), nil)
+	req.Header.Set("Range", "bytes=10-19")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		t.Fatalf("status = %d, want 206", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Content-Range"); got != "bytes 10-19/36" {
+		t.Errorf("Content-Range = %q", got)
+	}
+	if got := resp.Header.Get("ETag"); got != `"v1"` {
+		t.Errorf("ETag = %q", got)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "abcdefghij" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestHandler_RejectsBadRequests(t *testing.T) {
+	var hits int32
+	upstream := newUpstream(t, []byte("data"), &hits)
+	peer := httptest.NewServer(Handler(testToken, NewClient("", true)))
+	defer peer.Close()
+
+	target := upstream.URL + "/file.bin"
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		want   int
+	}{
+		{"wrong token", http.MethodGet, URL(peer.URL, "other", target), http.StatusUnauthorized},
+		{"unsigned", http.MethodGet, peer.URL + Path + "?url=" + target, http.StatusUnauthorized},
+		{"not http", http.MethodGet, URL(peer.URL, testToken, "file:///etc/passwd"), http.StatusBadRequest},
+		{"post", http.MethodPost, URL(peer.URL, testToken, target), http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, tt.url, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, resp.StatusCode, tt.want)
+		}
+	}
+	if n := atomic.LoadInt32(&hits); n != 0 {
+		t.Errorf("upstream contacted %d times by rejected requests", n)
+	}
+
+	// A daemon without a token never relays
+	open := httptest.NewServer(Handler("", NewClient("", true)))
+	defer open.Close()
+	resp, err := http.Get(URL(open.URL, "", target))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("tokenless daemon: status = %d, want 401", resp.StatusCode)
+	}
+}
+
+func TestHandler_RefusesPrivateTargetsByDefault(t *testing.T) {
+	var hits int32
+	upstream := newUpstream(t, []byte("data"), &hits)
+	peer := httptest.NewServer(Handler(testToken, nil))
+	defer peer.Close()
+
+	// The upstream listens on loopback, as would the peer's own API or a LAN host
+	for _, target := range []string{upstream.URL + "/file.bin", strings.Replace(upstream.URL, "127.0.0.1", "localhost", 1) + "/file.bin"} {
+		resp, err := http.Get(URL(peer.URL, testToken, target))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("%s: status = %d, want 403", target, resp.StatusCode)
+		}
+	}
+	if n := atomic.LoadInt32(&hits); n != 0 {
+		t.Errorf("private upstream contacted %d times", n)
+	}
+}
+
+func TestNewClient_AllowsPrivateProxy(t *testing.T) {
+	// A proxy on loopback is still usable when private targets are refused
+	var proxied int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&proxied, 1)
+		_, _ = io.WriteString(w, "via proxy")
+	}))
+	defer proxy.Close()
+
+	resp, err := NewClient(proxy.URL, false).Get("http://example.com/file.bin")
+	if err != nil {
+		t.Fatalf("request through loopback proxy: %v", err)
+	}
+	_ = resp.Body.Close()
+	if atomic.LoadInt32(&proxied) != 1 {
+		t.Fatal("request did not go through the proxy")
+	}
+}
+
+// The coordinator's workers split the file between the upstream and the
+// peer's relay, and the assembled file matches the original.
+func TestCoordinator_SplitsRangesAcrossPeer(t *testing.T) {
+	tmpDir := testutil.SetupStateDB(t)
+
+	data := make([]byte, 8*types.MB)
+	rand.New(rand.NewSource(1)).Read(data)
+	var upstreamHits int32
+	upstream := newUpstream(t, data, &upstreamHits)
+
+	var relayed int32
+	relayHandler := Handler(testToken, NewClient("", true))
+	peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&relayed, 1)
+		relayHandler.ServeHTTP(w, r)
+	}))
+	defer peer.Close()
+
+	primary := upstream.URL + "/file.bin"
+	mirror := URL(peer.URL, testToken, primary)
+	mirrors := []string{primary, mirror}
+
+	destPath := filepath.Join(tmpDir, "file.bin")
+	if f, err := os.Create(destPath + types.IncompleteSuffix); err == nil {
+		_ = f.Close()
+	}
+
+	size := int64(len(data))
+	runtime := &types.RuntimeConfig{MaxConnectionsPerHost: 4, MinChunkSize: 256 * types.KB}
+	d := concurrent.NewConcurrentDownloader("relay-test", nil, types.NewProgressState("relay-test", size), runtime)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := d.Download(ctx, primary, mirrors, mirrors, destPath, size); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+
+	got, err := os.ReadFile(destPath + types.IncompleteSuffix)
+	if err != nil {
+		t.Fatalf("read result: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("downloaded file does not match upstream")
+	}
+	if atomic.LoadInt32(&relayed) == 0 {
+		t.Error("no ranges went through the peer")
+	}
+}
@@ -1,6 +1,7 @@
 package single
 
 import (
+	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
@@ -11,7 +12,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/surge-downloader/surge/internal/engine/ratelimit"
 	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/engine/warmup"
 	"github.com/surge-downloader/surge/internal/utils"
 )
 
@@ -118,10 +121,11 @@ func newSingleTransport(runtime *types.RuntimeConfig) *http.Transport {
 		ExpectContinueTimeout: types.DefaultExpectContinueTimeout,
 
 		DisableCompression: true,
-		DialContext: (&net.Dialer{
+		TLSClientConfig:    warmup.TLSConfig(),
+		DialContext: warmup.DialContext(&net.Dialer{
 			Timeout:   types.DialTimeout,
 			KeepAlive: types.KeepAliveDuration,
-		}).DialContext,
+		}),
 	}
 }
 
@@ -136,28 +140,62 @@ func (d *SingleDownloader) Download(ctx context.Context, rawurl, destPath string
 		d.State.SetDestPath(destPath)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawurl, nil)
+	ratelimit.Global.Configure(d.Runtime.GetGlobalRateLimit(), d.Runtime.RateLimitExemptHosts)
+
+	// Fast path: splice plain-HTTP bodies straight from the socket into the file
+	zc, err := d.openZeroCopy(ctx, rawurl)
 	if err != nil {
 		return err
 	}
 
-	for key, val := range d.Headers {
-		req.Header.Set(key, val)
-	}
-	req.Header.Set("User-Agent", d.Runtime.GetUserAgent())
+	var body io.Reader
+	if zc != nil {
+		defer func() { _ = zc.Close() }()
+	} else {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawurl, nil)
+		if err != nil {
+			return err
+		}
 
-	resp, err := d.Client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			utils.Debug("Error closing response body: %v", err)
+		for key, val := range d.Headers {
+			req.Header.Set(key, val)
+		}
+		req.Header.Set("User-Agent", d.Runtime.GetUserAgent())
+
+		resp, err := d.Client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := resp.Body.Close(); err != nil {
+				utils.Debug("Error closing response body: %v", err)
+			}
+		}()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		}
+		body = ratelimit.Global.Reader(ctx, resp.Body, resp.Request.URL.Host)
+		// Always wrapped, even when unlimited, so a limit can be set while it runs
+		limiter := ratelimit.NewLimiter(d.Runtime.GetSpeedLimit())
+		body = limiter.Reader(ctx, body, resp.Request.URL.Host)
+		if d.State != nil {
+			d.State.SetSpeedLimiter(limiter.SetRate)
+			defer d.State.SetSpeedLimiter(nil)
 		}
-	}()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		// Forced gzip means any length we were given is the compressed one; decode
+		// the stream and let the bytes written define the file size.
+		if !d.Runtime.KeepCompressed && utils.IsTransferGzip(resp, filename) {
+			utils.Debug("Decoding gzip-encoded response for %s", filename)
+			gz, err := gzip.NewReader(body)
+			if err != nil {
+				return fmt.Errorf("gzip decode error: %w", err)
+			}
+			defer func() { _ = gz.Close() }()
+			body = gz
+			fileSize = 0
+		}
 	}
 
 	// Use .surge extension for incomplete file (must be pre-created by processing layer)
@@ -169,7 +207,7 @@ func (d *SingleDownloader) Download(ctx context.Context, rawurl, destPath string
 
 	preallocated := false
 	if fileSize > 0 {
-		if err := preallocateFile(outFile, fileSize); err != nil {
+		if err := utils.PreallocateFile(outFile, fileSize, d.Runtime.DensePreallocation); err != nil {
 			return fmt.Errorf("failed to preallocate file: %w", err)
 		}
 		preallocated = true
@@ -179,19 +217,26 @@ func (d *SingleDownloader) Download(ctx context.Context, rawurl, destPath string
 		_ = outFile.Close()
 	}()
 
+	// Probes back off while data is flowing
+	defer ratelimit.BeginTransfer()()
+
 	start := time.Now()
 	var written int64
 
-	bufPtr := bufPool.Get().(*[]byte)
-	buf := *bufPtr
-	defer bufPool.Put(bufPtr)
-
-	if d.State == nil {
-		written, err = io.CopyBuffer(outFile, resp.Body, buf)
+	if zc != nil {
+		written, err = zc.writeToWithProgress(outFile, d.State)
 	} else {
-		progressReader := newProgressReader(resp.Body, d.State, types.WorkerBatchSize, types.WorkerBatchInterval)
-		written, err = io.CopyBuffer(outFile, progressReader, buf)
-		progressReader.Flush()
+		bufPtr := bufPool.Get().(*[]byte)
+		buf := *bufPtr
+		defer bufPool.Put(bufPtr)
+
+		if d.State == nil {
+			written, err = io.CopyBuffer(outFile, body, buf)
+		} else {
+			progressReader := newProgressReader(body, d.State, types.WorkerBatchSize, types.WorkerBatchInterval)
+			written, err = io.CopyBuffer(outFile, progressReader, buf)
+			progressReader.Flush()
+		}
 	}
 	if err != nil {
 		if ctxErr := ctx.Err(); ctxErr != nil {
@@ -216,6 +261,9 @@ func (d *SingleDownloader) Download(ctx context.Context, rawurl, destPath string
 	if d.State != nil {
 		d.State.Downloaded.Store(written)
 		d.State.VerifiedProgress.Store(written)
+		if written != fileSize {
+			d.State.SetActualSize(written)
+		}
 	}
 
 	elapsed := time.Since(start)
@@ -258,16 +306,21 @@ func newProgressReader(reader io.Reader, state *types.ProgressState, batchSize i
 
 func (w *progressReader) Read(p []byte) (int, error) {
 	n, err := w.reader.Read(p)
-	if n <= 0 || w.state == nil {
-		return n, err
+	w.add(int64(n))
+	return n, err
+}
+
+// add records n bytes written without going through Read (zero-copy path)
+func (w *progressReader) add(written int64) {
+	if w == nil || written <= 0 || w.state == nil {
+		return
 	}
 
-	written := int64(n)
 	w.written += written
 	w.pending += written
 	if w.pending >= w.batchSize {
 		w.flushWithTime(time.Now())
-		return n, err
+		return
 	}
 
 	if w.batchInterval > 0 {
@@ -281,8 +334,6 @@ func (w *progressReader) Read(p []byte) (int, error) {
 			w.readChecks = 0
 		}
 	}
-
-	return n, err
 }
 
 func (w *progressReader) Flush() {
@@ -1,10 +1,15 @@
 package single
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strconv"
 	"testing"
 	"time"
 
@@ -150,8 +155,8 @@ func TestPreallocateFile(t *testing.T) {
 	defer func() { _ = file.Close() }()
 
 	const size = int64(2 * types.MB)
-	if err := preallocateFile(file, size); err != nil {
-		t.Fatalf("preallocateFile failed: %v", err)
+	if err := utils.PreallocateFile(file, size, true); err != nil {
+		t.Fatalf("PreallocateFile failed: %v", err)
 	}
 
 	info, err := file.Stat()
@@ -638,3 +643,90 @@ func BenchmarkSingleDownloader(b *testing.B) {
 		_ = os.Remove(destPath)
 	}
 }
+
+// =============================================================================
+// SingleDownloader - Forced gzip Content-Encoding
+// =============================================================================
+
+func newForcedGzipServer(t *testing.T, payload []byte) *httptest.Server {
+	t.Helper()
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	if _, err := gz.Write(payload); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Sent regardless of Accept-Encoding, with the compressed length
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Length", strconv.Itoa(compressed.Len()))
+		_, _ = w.Write(compressed.Bytes())
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSingleDownloader_DecodesForcedGzip(t *testing.T) {
+	tmpDir, cleanup, _ := testutil.TempDir("surge-gzip-single")
+	defer cleanup()
+
+	payload := bytes.Repeat([]byte("surge gzip payload "), 10000)
+	server := newForcedGzipServer(t, payload)
+
+	destPath := filepath.Join(tmpDir, "report.csv")
+	if f, err := os.Create(destPath + types.IncompleteSuffix); err == nil {
+		_ = f.Close()
+	}
+	state := types.NewProgressState("gzip-single", 0)
+	downloader := NewSingleDownloader("gzip-id", nil, state, &types.RuntimeConfig{})
+
+	if err := downloader.Download(context.Background(), server.URL, destPath, 0, "report.csv"); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+
+	got, err := os.ReadFile(destPath + types.IncompleteSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Errorf("expected decoded payload (%d bytes), got %d bytes", len(payload), len(got))
+	}
+	if total := state.GetTotalSize(); total != int64(len(payload)) {
+		t.Errorf("expected state to record decoded size %d, got %d", len(payload), total)
+	}
+}
+
+func TestSingleDownloader_KeepCompressed(t *testing.T) {
+	tmpDir, cleanup, _ := testutil.TempDir("surge-gzip-keep")
+	defer cleanup()
+
+	payload := bytes.Repeat([]byte("kept as gzip "), 1000)
+	server := newForcedGzipServer(t, payload)
+
+	destPath := filepath.Join(tmpDir, "report.csv")
+	if f, err := os.Create(destPath + types.IncompleteSuffix); err == nil {
+		_ = f.Close()
+	}
+	downloader := NewSingleDownloader("gzip-keep", nil, nil, &types.RuntimeConfig{KeepCompressed: true})
+
+	if err := downloader.Download(context.Background(), server.URL, destPath, 0, "report.csv"); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+
+	f, err := os.Open(destPath + types.IncompleteSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("expected file to stay gzip-compressed: %v", err)
+	}
+	got, err := io.ReadAll(gz)
+	if err != nil || !bytes.Equal(got, payload) {
+		t.Errorf("compressed file does not round-trip: %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package single
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/ratelimit"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// zeroCopyChunk bounds each splice so progress is reported regularly
+const zeroCopyChunk = types.WorkerBatchSize
+
+// zeroCopyBody is a response body read off a connection we own, so the payload
+// can be spliced from the socket into the file without a userspace copy.
+type zeroCopyBody struct {
+	conn      *net.TCPConn
+	br        *bufio.Reader // Holds whatever body bytes arrived with the headers
+	remaining int64
+	stop      func() bool // Detaches the context cancellation hook
+}
+
+// openZeroCopy issues a plain-HTTP GET on a dedicated connection and returns the
+// body if the zero-copy path can take it: 200 OK, known length, identity
+// encoding, no proxy and no throttling. A nil body with a nil error means the
+// regular client should be used instead (e.g. redirects); error statuses are
+// returned as-is so the server is not asked twice. Nothing is written to disk here.
+func (d *SingleDownloader) openZeroCopy(ctx context.Context, rawurl string) (*zeroCopyBody, error) {
+	if !zeroCopySupported {
+		return nil, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawurl, nil)
+	if err != nil || req.URL.Scheme != "http" {
+		return nil, nil
+	}
+	if d.Runtime.ProxyURL != "" {
+		return nil, nil
+	}
+	if proxy, err := http.ProxyFromEnvironment(req); err != nil || proxy != nil {
+		return nil, nil
+	}
+	// Throttled transfers gain nothing from zero-copy
+	if d.Runtime.GetSpeedLimit() > 0 || (ratelimit.Global.Rate() > 0 && !ratelimit.Global.IsExempt(req.URL.Host)) {
+		return nil, nil
+	}
+
+	for key, val := range d.Headers {
+		req.Header.Set(key, val)
+	}
+	req.Header.Set("User-Agent", d.Runtime.GetUserAgent())
+	req.Header.Set("Accept-Encoding", "identity")
+	req.Close = true
+
+	addr := req.URL.Host
+	if req.URL.Port() == "" {
+		addr = net.JoinHostPort(req.URL.Hostname(), "80")
+	}
+	dialer := &net.Dialer{Timeout: types.DialTimeout, KeepAlive: types.KeepAliveDuration}
+	c, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		utils.Debug("Zero-copy dial failed for %s: %v", rawurl, err)
+		return nil, nil
+	}
+	conn, ok := c.(*net.TCPConn)
+	if !ok {
+		_ = c.Close()
+		return nil, nil
+	}
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+
+	fallback := func(reason string, args ...any) (*zeroCopyBody, error) {
+		stop()
+		_ = conn.Close()
+		utils.Debug("Zero-copy unavailable for %s: "+reason, append([]any{rawurl}, args...)...)
+		return nil, nil
+	}
+
+	_ = conn.SetDeadline(time.Now().Add(types.DefaultResponseHeaderTimeout))
+	if err := req.Write(conn); err != nil {
+		return fallback("write request: %v", err)
+	}
+	br := bufio.NewReaderSize(conn, 32*types.KB)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return fallback("read response: %v", err)
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		stop()
+		_ = conn.Close()
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fallback("status %d", resp.StatusCode)
+	}
+	if resp.ContentLength <= 0 || len(resp.TransferEncoding) > 0 {
+		return fallback("unknown or chunked length")
+	}
+	if enc := resp.Header.Get("Content-Encoding"); enc != "" && enc != "identity" {
+		return fallback("content-encoding %s", enc)
+	}
+
+	return &zeroCopyBody{conn: conn, br: br, remaining: resp.ContentLength, stop: stop}, nil
+}
+
+// writeToWithProgress runs writeTo while publishing progress to state (may be nil)
+func (b *zeroCopyBody) writeToWithProgress(file *os.File, state *types.ProgressState) (int64, error) {
+	if state == nil {
+		return b.writeTo(file, nil)
+	}
+	progress := newProgressReader(nil, state, types.WorkerBatchSize, types.WorkerBatchInterval)
+	written, err := b.writeTo(file, progress)
+	progress.Flush()
+	return written, err
+}
+
+// writeTo drains the body bytes buffered with the headers, then splices the
+// rest of the socket into file at its current offset.
+func (b *zeroCopyBody) writeTo(file *os.File, progress *progressReader) (int64, error) {
+	var written int64
+
+	if n := min(int64(b.br.Buffered()), b.remaining); n > 0 {
+		w, err := io.CopyN(file, b.br, n)
+		written += w
+		b.remaining -= w
+		progress.add(w)
+		if err != nil {
+			return written, err
+		}
+	}
+
+	for b.remaining > 0 {
+		n, err := file.ReadFrom(&io.LimitedReader{R: b.conn, N: min(b.remaining, zeroCopyChunk)})
+		written += n
+		b.remaining -= n
+		progress.add(n)
+		if err != nil {
+			return written, err
+		}
+		if n == 0 {
+			return written, io.ErrUnexpectedEOF
+		}
+	}
+
+	return written, nil
+}
+
+// Close releases the connection
+func (b *zeroCopyBody) Close() error {
+	b.stop()
+	return b.conn.Close()
+}
@@ -0,0 +1,7 @@
+//go:build linux
+
+package single
+
+// zeroCopySupported enables the socket-to-file path; os.File.ReadFrom uses
+// splice(2) when the source is a TCP connection.
+const zeroCopySupported = true
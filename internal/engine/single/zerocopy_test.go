@@ -0,0 +1,147 @@
+package single
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func newZeroCopyTestFile(t *testing.T) *os.File {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), "zc.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = f.Close() })
+	return f
+}
+
+func TestZeroCopy_SplicesBody(t *testing.T) {
+	if !zeroCopySupported {
+		t.Skip("zero-copy not supported on this platform")
+	}
+
+	payload := bytes.Repeat([]byte("0123456789abcdef"), 64*1024) // 1MB, several chunks
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+		_, _ = w.Write(payload)
+	}))
+	defer server.Close()
+
+	state := types.NewProgressState("zc", int64(len(payload)))
+	d := NewSingleDownloader("zc", nil, state, &types.RuntimeConfig{})
+
+	zc, err := d.openZeroCopy(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("openZeroCopy failed: %v", err)
+	}
+	if zc == nil {
+		t.Fatal("expected zero-copy path for plain HTTP")
+	}
+	defer func() { _ = zc.Close() }()
+
+	f := newZeroCopyTestFile(t)
+	written, err := zc.writeToWithProgress(f, state)
+	if err != nil {
+		t.Fatalf("writeTo failed: %v", err)
+	}
+	if written != int64(len(payload)) {
+		t.Fatalf("expected %d bytes, got %d", len(payload), written)
+	}
+	if got := state.Downloaded.Load(); got != written {
+		t.Errorf("expected progress %d, got %d", written, got)
+	}
+
+	got, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Error("file contents don't match payload")
+	}
+}
+
+func TestZeroCopy_TruncatedBody(t *testing.T) {
+	if !zeroCopySupported {
+		t.Skip("zero-copy not supported on this platform")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1000")
+		_, _ = w.Write(make([]byte, 100))
+		// Drop the connection before the advertised length is sent
+		if hj, ok := w.(http.Hijacker); ok {
+			conn, _, _ := hj.Hijack()
+			_ = conn.Close()
+		}
+	}))
+	defer server.Close()
+
+	d := NewSingleDownloader("zc-trunc", nil, nil, &types.RuntimeConfig{})
+	zc, err := d.openZeroCopy(context.Background(), server.URL)
+	if err != nil || zc == nil {
+		t.Fatalf("expected zero-copy body, got %v, %v", zc, err)
+	}
+	defer func() { _ = zc.Close() }()
+
+	_, err = zc.writeTo(newZeroCopyTestFile(t), nil)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected unexpected EOF, got %v", err)
+	}
+}
+
+func TestZeroCopy_ErrorStatus(t *testing.T) {
+	if !zeroCopySupported {
+		t.Skip("zero-copy not supported on this platform")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	d := NewSingleDownloader("zc-404", nil, nil, &types.RuntimeConfig{})
+	zc, err := d.openZeroCopy(context.Background(), server.URL)
+	if err == nil || zc != nil {
+		t.Fatalf("expected error status to be returned, got %v, %v", zc, err)
+	}
+}
+
+func TestZeroCopy_Ineligible(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("chunked")) // No Content-Length, so the response is chunked
+		w.(http.Flusher).Flush()
+		_, _ = w.Write([]byte(" body"))
+	}))
+	defer server.Close()
+	tlsServer := httptest.NewTLSServer(http.NotFoundHandler())
+	defer tlsServer.Close()
+
+	tests := []struct {
+		name    string
+		url     string
+		runtime *types.RuntimeConfig
+	}{
+		{"chunked", server.URL, &types.RuntimeConfig{}},
+		{"https", tlsServer.URL, &types.RuntimeConfig{}},
+		{"proxy", server.URL, &types.RuntimeConfig{ProxyURL: "http://127.0.0.1:1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewSingleDownloader("zc-skip", nil, nil, tt.runtime)
+			zc, err := d.openZeroCopy(context.Background(), tt.url)
+			if err != nil || zc != nil {
+				t.Errorf("expected fallback, got %v, %v", zc, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package state
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// keyedArchivePrefix marks download archive hashes keyed with the state key
+const keyedArchivePrefix = "keyed:"
+
+// archiveKeys hashes url for the download archive: the hash new entries are
+// stored under, and every hash an existing entry may have been stored under.
+// Trailing slashes are ignored, as they are for duplicate detection. While
+// state is sealed the hash is keyed, so the archive can't be checked against
+// a guessed URL without the state key.
+func archiveKeys(url string) (store string, match []string) {
+	url = strings.TrimRight(strings.TrimSpace(url), "/")
+	h := sha256.Sum256([]byte(url))
+	plain := hex.EncodeToString(h[:])
+	mac, keyed := urlMAC()
+	if mac == nil {
+		return plain, []string{plain}
+	}
+	sealed := keyedArchivePrefix + hex.EncodeToString(keyedHash(mac, "archive", url))
+	if keyed {
+		return sealed, []string{sealed, plain}
+	}
+	return plain, []string{plain, sealed}
+}
+
+// ArchiveURL records url in the download archive. Only the hash is kept, and
+// the entry outlives the download's row in the master list.
+func ArchiveURL(url string) error {
+	if strings.TrimSpace(url) == "" {
+		return nil
+	}
+	key, _ := archiveKeys(url)
+	return withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			INSERT INTO download_archive (url_hash, completed_at)
+			VALUES (?, ?)
+			ON CONFLICT(url_hash) DO UPDATE SET completed_at = excluded.completed_at
+		`, key, time.Now().Unix())
+		if err != nil {
+			return fmt.Errorf("failed to archive url: %w", err)
+		}
+		return nil
+	})
+}
+
+// IsArchived reports whether url has been downloaded to completion before
+func IsArchived(url string) (bool, error) {
+	db := getDBHelper()
+	if db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+
+	_, keys := archiveKeys(url)
+	args := make([]any, len(keys))
+	for i, k := range keys {
+		args[i] = k
+	}
+	var one int
+	err := db.QueryRow(`SELECT 1 FROM download_archive WHERE url_hash IN (?`+strings.Repeat(", ?", len(keys)-1)+`) LIMIT 1`, args...).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to query download archive: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,45 @@
+package state
+
+import (
+	"os"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestDownloadArchive(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	const url = "https://example.com/feed/episode-1.mp3"
+	if archived, err := IsArchived(url); err != nil || archived {
+		t.Fatalf("IsArchived before archiving = %v, %v", archived, err)
+	}
+
+	if err := ArchiveURL(url); err != nil {
+		t.Fatalf("ArchiveURL: %v", err)
+	}
+	if err := ArchiveURL(url); err != nil {
+		t.Fatalf("ArchiveURL twice: %v", err)
+	}
+	for _, u := range []string{url, url + "/", " " + url} {
+		if archived, err := IsArchived(u); err != nil || !archived {
+			t.Errorf("IsArchived(%q) = %v, %v, want true", u, archived, err)
+		}
+	}
+	if archived, _ := IsArchived("https://example.com/feed/episode-2.mp3"); archived {
+		t.Error("unrelated URL reported as archived")
+	}
+
+	// Removing the download from the list keeps it in the archive
+	if err := AddToMasterList(types.DownloadEntry{ID: "ep1", URL: url, DestPath: "/tmp/ep1", Status: "completed"}); err != nil {
+		t.Fatalf("AddToMasterList: %v", err)
+	}
+	if err := RemoveFromMasterList("ep1"); err != nil {
+		t.Fatalf("RemoveFromMasterList: %v", err)
+	}
+	if archived, _ := IsArchived(url); !archived {
+		t.Error("archive entry lost when the download was removed")
+	}
+}
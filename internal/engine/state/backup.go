@@ -0,0 +1,109 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	sqlite "modernc.org/sqlite"
+)
+
+// sqliteBackuper is what the modernc driver's connections expose for the
+// online backup API
+type sqliteBackuper interface {
+	NewBackup(dstURI string) (*sqlite.Backup, error)
+	NewRestore(srcURI string) (*sqlite.Backup, error)
+}
+
+// LatestSchemaVersion is the schema version this build migrates to
+func LatestSchemaVersion() int {
+	return latestSchemaVersion()
+}
+
+// Backup writes a consistent copy of the state database to dst with SQLite's
+// online backup API, so it is safe while the daemon keeps writing. dst must
+// not exist yet.
+func Backup(dst string) error {
+	if _, err := os.Stat(dst); err == nil {
+		return fmt.Errorf("backup destination %s already exists", dst)
+	}
+	return withBackupConn(func(b sqliteBackuper) (*sqlite.Backup, error) {
+		return b.NewBackup(dst)
+	})
+}
+
+// Restore replaces the contents of the state database with the SQLite
+// database at src, then migrates it to the latest schema. A database from a
+// newer Surge is refused before anything is written.
+func Restore(src string) error {
+	in, err := OpenInspector(src)
+	if err != nil {
+		return err
+	}
+	stats, err := in.Stats()
+	_ = in.Close()
+	if err != nil {
+		return err
+	}
+	if _, ok := stats.Tables["downloads"]; !ok {
+		return fmt.Errorf("%s is not a Surge state database", src)
+	}
+	if stats.SchemaVersion > latestSchemaVersion() {
+		return fmt.Errorf("backup has schema version %d, newer than this build supports (%d); upgrade Surge first", stats.SchemaVersion, latestSchemaVersion())
+	}
+
+	err = withBackupConn(func(b sqliteBackuper) (*sqlite.Backup, error) {
+		return b.NewRestore(src)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Reopen so the restored database goes through the migrations
+	dbMu.Lock()
+	s := store
+	dbMu.Unlock()
+	CloseDB()
+	ConfigureStore(s)
+	_, err = GetDB()
+	return err
+}
+
+// withBackupConn runs a backup or restore started by start on a connection to
+// the configured SQLite database
+func withBackupConn(start func(sqliteBackuper) (*sqlite.Backup, error)) error {
+	d, err := GetDB()
+	if err != nil {
+		return err
+	}
+	dbMu.Lock()
+	_, ok := store.(*SQLiteStore)
+	dbMu.Unlock()
+	if !ok {
+		return fmt.Errorf("backup and restore support SQLite state stores only")
+	}
+
+	c, err := d.Conn(context.Background())
+	if err != nil {
+		return err
+	}
+	defer func() { _ = c.Close() }()
+
+	return c.Raw(func(driverConn any) error {
+		b, ok := driverConn.(sqliteBackuper)
+		if !ok {
+			return fmt.Errorf("sqlite driver does not support online backup")
+		}
+		bk, err := start(b)
+		if err != nil {
+			return fmt.Errorf("failed to start backup: %w", err)
+		}
+		for more := true; more; {
+			if more, err = bk.Step(-1); err != nil {
+				_ = bk.Finish()
+				return fmt.Errorf("backup step failed: %w", err)
+			}
+		}
+		return bk.Finish()
+	})
+}
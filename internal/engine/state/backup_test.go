@@ -0,0 +1,81 @@
+package state
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	if err := AddToMasterList(types.DownloadEntry{ID: "kept", URL: "https://example.com/a", DestPath: "/tmp/a", Status: "completed"}); err != nil {
+		t.Fatalf("AddToMasterList: %v", err)
+	}
+
+	snapshot := filepath.Join(tmpDir, "snapshot.db")
+	if err := Backup(snapshot); err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	if err := Backup(snapshot); err == nil {
+		t.Error("Backup overwrote an existing file")
+	}
+
+	// Changes after the snapshot are undone by restoring it
+	if err := RemoveFromMasterList("kept"); err != nil {
+		t.Fatalf("RemoveFromMasterList: %v", err)
+	}
+	if err := AddToMasterList(types.DownloadEntry{ID: "later", URL: "https://example.com/b", DestPath: "/tmp/b", Status: "queued"}); err != nil {
+		t.Fatalf("AddToMasterList: %v", err)
+	}
+	if err := Restore(snapshot); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	if e, _ := GetDownload("kept"); e == nil {
+		t.Error("restored database is missing the snapshotted download")
+	}
+	if e, _ := GetDownload("later"); e != nil {
+		t.Error("restored database still has a download added after the snapshot")
+	}
+}
+
+func TestRestore_RejectsNewerSchema(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	if err := AddToMasterList(types.DownloadEntry{ID: "live", URL: "https://example.com/a", DestPath: "/tmp/a", Status: "queued"}); err != nil {
+		t.Fatalf("AddToMasterList: %v", err)
+	}
+
+	snapshot := filepath.Join(tmpDir, "future.db")
+	if err := Backup(snapshot); err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	future, err := sql.Open("sqlite", snapshot)
+	if err != nil {
+		t.Fatalf("open snapshot: %v", err)
+	}
+	if _, err := future.Exec("INSERT INTO schema_version (version) VALUES (?)", latestSchemaVersion()+1); err != nil {
+		t.Fatalf("bump schema version: %v", err)
+	}
+	_ = future.Close()
+
+	if err := Restore(snapshot); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Fatalf("Restore = %v, want a newer-schema error", err)
+	}
+	if e, _ := GetDownload("live"); e == nil {
+		t.Error("refused restore changed the live database")
+	}
+
+	if err := Restore(filepath.Join(tmpDir, "missing.db")); err == nil {
+		t.Error("Restore of a missing file succeeded")
+	}
+}
@@ -0,0 +1,70 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// QueuedDownload is one row of a batch add together with the probe result
+// its destination was resolved from
+type QueuedDownload struct {
+	Entry types.DownloadEntry
+	Probe types.CachedProbe
+}
+
+// BatchInsertError reports which download of a batch could not be written
+type BatchInsertError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchInsertError) Error() string {
+	return fmt.Sprintf("batch item %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchInsertError) Unwrap() error { return e.Err }
+
+// InsertQueuedDownloads writes every download of a batch as queued in one
+// transaction. Unlike AddToMasterList an existing id is an error, and any
+// error leaves the table untouched.
+func InsertQueuedDownloads(downloads []QueuedDownload) error {
+	now := time.Now().Unix()
+	return withTx(func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(`
+			INSERT INTO downloads (
+				id, url, dest_path, filename, status, total_size, downloaded, url_hash, mirrors,
+				probe_size, probe_ranges, probe_etag, probe_final_url, probe_content_type, probe_server, probe_last_modified, probed_at,
+				created_at, trace_id, tags, category, overrides, queue_position
+			) VALUES (?, ?, ?, ?, 'queued', ?, 0, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ` + nextQueuePosition + `)
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare batch insert: %w", err)
+		}
+		defer func() { _ = stmt.Close() }()
+
+		for i, d := range downloads {
+			e, p := d.Entry, d.Probe
+			if e.ID == "" {
+				return &BatchInsertError{Index: i, Err: fmt.Errorf("missing id")}
+			}
+			if p.ProbedAt == 0 {
+				p.ProbedAt = now
+			}
+			overrides, err := encodeOverrides(e.Overrides)
+			if err != nil {
+				return &BatchInsertError{Index: i, Err: err}
+			}
+			if _, err := stmt.Exec(
+				e.ID, sealValue(e.URL), e.DestPath, e.Filename, e.TotalSize, URLHash(e.URL), sealValue(strings.Join(e.Mirrors, ",")),
+				p.FileSize, p.SupportsRange, p.ETag, sealValue(p.FinalURL), p.ContentType, p.Server, p.LastModified, p.ProbedAt, now, e.TraceID, strings.Join(e.Tags, ","), e.Category, overrides,
+			); err != nil {
+				return &BatchInsertError{Index: i, Err: err}
+			}
+		}
+		return nil
+	})
+}
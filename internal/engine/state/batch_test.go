@@ -0,0 +1,46 @@
+package state
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestInsertQueuedDownloads_AllOrNothing(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	if err := AddToMasterList(types.DownloadEntry{ID: "taken", URL: "https://example.com/old", Status: "completed"}); err != nil {
+		t.Fatal(err)
+	}
+
+	batch := []QueuedDownload{
+		{Entry: types.DownloadEntry{ID: "a", URL: "https://example.com/a", DestPath: "/tmp/a", Filename: "a"}},
+		{Entry: types.DownloadEntry{ID: "taken", URL: "https://example.com/b", DestPath: "/tmp/b", Filename: "b"}},
+	}
+	err := InsertQueuedDownloads(batch)
+	var itemErr *BatchInsertError
+	if !errors.As(err, &itemErr) || itemErr.Index != 1 {
+		t.Fatalf("expected the colliding item to be reported, got %v", err)
+	}
+	if entry, _ := GetDownload("a"); entry != nil {
+		t.Fatal("expected the first row to be rolled back")
+	}
+
+	batch[1].Entry.ID = "b"
+	batch[1].Probe = types.CachedProbe{FileSize: 10, SupportsRange: true, ETag: `"e"`}
+	if err := InsertQueuedDownloads(batch); err != nil {
+		t.Fatalf("InsertQueuedDownloads failed: %v", err)
+	}
+	entry, err := GetDownload("b")
+	if err != nil || entry == nil || entry.Status != "queued" || entry.URLHash != URLHash("https://example.com/b") {
+		t.Fatalf("expected queued row, got %+v, %v", entry, err)
+	}
+	probe, err := GetProbeCache("b")
+	if err != nil || probe == nil || probe.FileSize != 10 || probe.ETag != `"e"` {
+		t.Fatalf("expected probe cache to be written with the row, got %+v, %v", probe, err)
+	}
+}
@@ -0,0 +1,47 @@
+package state
+
+import (
+	"fmt"
+	"time"
+)
+
+// SetChecksum stores the checksum of a completed download and clears the
+// result of any earlier verification
+func SetChecksum(id string, checksum string) error {
+	db := getDBHelper()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	result, err := db.Exec("UPDATE downloads SET checksum = ?, verify_status = NULL, verified_at = NULL WHERE id = ?", checksum, id)
+	if err != nil {
+		return fmt.Errorf("failed to store checksum: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("download not found: %s", id)
+	}
+
+	return nil
+}
+
+// RecordVerification stores the outcome of verifying a download
+func RecordVerification(id string, status string) error {
+	db := getDBHelper()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	result, err := db.Exec("UPDATE downloads SET verify_status = ?, verified_at = ? WHERE id = ?", status, time.Now().Unix(), id)
+	if err != nil {
+		return fmt.Errorf("failed to record verification: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("download not found: %s", id)
+	}
+
+	return nil
+}
@@ -10,136 +10,44 @@ import (
 )
 
 var (
-	db         *sql.DB
-	dbMu       sync.Mutex
-	dbPath     string
-	configured bool
+	db    *sql.DB
+	dbMu  sync.Mutex
+	store Store
 )
 
 // Configure sets the path for the SQLite database
 func Configure(path string) {
-	dbMu.Lock()
-	defer dbMu.Unlock()
-	dbPath = path
-	configured = true
+	ConfigureStore(&SQLiteStore{Path: path})
 }
 
-// InitDB initializes the SQLite database connection using the configured path
-func initDB() error {
+// ConfigureStore sets the backend the state database is opened with
+func ConfigureStore(s Store) {
 	dbMu.Lock()
 	defer dbMu.Unlock()
+	store = s
+}
 
-	if db != nil {
-		return nil
+// initDB opens and migrates the database using the configured store.
+// dbMu must be held.
+func initDB() error {
+	if store == nil {
+		return fmt.Errorf("state database not configured: call state.Configure() first")
 	}
-
-	if !configured || dbPath == "" {
+	if s, ok := store.(*SQLiteStore); ok && s.Path == "" {
 		return fmt.Errorf("state database not configured: call state.Configure() first")
 	}
 
-	// Ensure directory exists - caller should perhaps do this, but safe to do here if path is provided
-
-	// Open database
-	var err error
-	db, err = sql.Open("sqlite", dbPath)
+	d, err := store.Open()
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
 
-	// Enable WAL mode and busy_timeout for concurrent reader-writer access
-	// (required now that the processing layer's event worker writes from a goroutine)
-	if _, err := db.Exec("PRAGMA journal_mode=WAL"); err != nil {
-		return fmt.Errorf("failed to set WAL mode: %w", err)
-	}
-	if _, err := db.Exec("PRAGMA busy_timeout=5000"); err != nil {
-		return fmt.Errorf("failed to set busy_timeout: %w", err)
-	}
-
-	// Create tables
-	query := `
-	CREATE TABLE IF NOT EXISTS downloads (
-		id TEXT PRIMARY KEY,
-		url TEXT NOT NULL,
-		dest_path TEXT NOT NULL,
-		filename TEXT,
-		status TEXT,
-		total_size INTEGER,
-		downloaded INTEGER,
-		url_hash TEXT,
-		created_at INTEGER,
-		paused_at INTEGER,
-		completed_at INTEGER,
-		time_taken INTEGER,
-		mirrors TEXT,
-		chunk_bitmap BLOB,
-		actual_chunk_size INTEGER,
-		avg_speed REAL,
-		file_hash TEXT
-	);
-
-	CREATE TABLE IF NOT EXISTS tasks (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		download_id TEXT,
-		offset INTEGER,
-		length INTEGER,
-		FOREIGN KEY(download_id) REFERENCES downloads(id) ON DELETE CASCADE
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_tasks_download_id ON tasks(download_id);
-	`
-
-	if _, err := db.Exec(query); err != nil {
-		return fmt.Errorf("failed to create tables: %w", err)
-	}
-
-	if err := ensureDownloadsSchema(); err != nil {
-		return fmt.Errorf("failed to ensure schema: %w", err)
-	}
-
-	return nil
-}
-
-// ensureDownloadsSchema checks if required columns exist in the downloads table and adds them if missing.
-func ensureDownloadsSchema() error {
-	rows, err := db.Query("PRAGMA table_info(downloads)")
-	if err != nil {
-		return err
-	}
-	defer func() { _ = rows.Close() }()
-
-	existingColumns := make(map[string]bool)
-	for rows.Next() {
-		var cid int
-		var name, ctype string
-		var notnull int
-		var dfltValue interface{}
-		var pk int
-		if err := rows.Scan(&cid, &name, &ctype, &notnull, &dfltValue, &pk); err != nil {
-			return err
-		}
-		existingColumns[name] = true
-	}
-
-	columnsToAdd := []struct {
-		name string
-		def  string
-	}{
-		{"mirrors", "TEXT"},
-		{"chunk_bitmap", "BLOB"},
-		{"actual_chunk_size", "INTEGER"},
-		{"avg_speed", "REAL"},
-		{"file_hash", "TEXT"},
-	}
-
-	for _, col := range columnsToAdd {
-		if !existingColumns[col.name] {
-			alterQuery := fmt.Sprintf("ALTER TABLE downloads ADD COLUMN %s %s", col.name, col.def)
-			if _, err := db.Exec(alterQuery); err != nil {
-				log.Printf("Failed to add column %s: %v", col.name, err)
-			}
-		}
+	if err := migrate(d); err != nil {
+		_ = d.Close()
+		return fmt.Errorf("failed to migrate schema: %w", err)
 	}
 
+	db = d
 	return nil
 }
 
@@ -150,12 +58,13 @@ func CloseDB() {
 		_ = db.Close()
 		db = nil
 	}
-	dbPath = ""
-	configured = false
+	store = nil
 }
 
 // GetDB returns the database instance, initializing it if necessary
 func GetDB() (*sql.DB, error) {
+	dbMu.Lock()
+	defer dbMu.Unlock()
 	if db == nil {
 		if err := initDB(); err != nil {
 			return nil, err
@@ -164,6 +73,16 @@ func GetDB() (*sql.DB, error) {
 	return db, nil
 }
 
+// Ping checks that the database opens and answers a query
+func Ping() error {
+	d, err := GetDB()
+	if err != nil {
+		return err
+	}
+	var one int
+	return d.QueryRow("SELECT 1").Scan(&one)
+}
+
 // Helper to ensure DB is initialized and return it
 func getDBHelper() *sql.DB {
 	d, err := GetDB()
@@ -143,7 +143,7 @@ func TestInitDB_createsDir(t *testing.T) {
 		_ = db.Close()
 		db = nil
 	}
-	configured = false
+	store = nil
 	dbMu.Unlock()
 
 	// Configure
@@ -0,0 +1,72 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// RecordDownloadFailure adds f to the error history of download id, dropping
+// the oldest entries past types.MaxDownloadFailures
+func RecordDownloadFailure(id string, f types.DownloadFailure) error {
+	if getDBHelper() == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	return withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			INSERT INTO download_errors (download_id, occurred_at, kind, http_status, mirror, task_offset, message)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, id, f.At, f.Kind, f.Status, sealValue(f.Mirror), f.Offset, sealValue(f.Message))
+		if err != nil {
+			return fmt.Errorf("failed to record download error: %w", err)
+		}
+		_, err = tx.Exec(`
+			DELETE FROM download_errors WHERE download_id = ? AND id NOT IN (
+				SELECT id FROM download_errors WHERE download_id = ? ORDER BY id DESC LIMIT ?
+			)
+		`, id, id, types.MaxDownloadFailures)
+		if err != nil {
+			return fmt.Errorf("failed to trim download errors: %w", err)
+		}
+		return nil
+	})
+}
+
+// LoadDownloadFailures returns the error history of download id, oldest first
+func LoadDownloadFailures(id string) ([]types.DownloadFailure, error) {
+	db := getDBHelper()
+	if db == nil {
+		return nil, nil
+	}
+
+	rows, err := db.Query(`
+		SELECT occurred_at, kind, http_status, mirror, task_offset, message
+		FROM download_errors
+		WHERE download_id = ?
+		ORDER BY id
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query download errors: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var failures []types.DownloadFailure
+	for rows.Next() {
+		var f types.DownloadFailure
+		var at, status, offset sql.NullInt64
+		var kind, mirror, message sql.NullString
+		if err := rows.Scan(&at, &kind, &status, &mirror, &offset, &message); err != nil {
+			return nil, err
+		}
+		f.At = at.Int64
+		f.Kind = kind.String
+		f.Status = int(status.Int64)
+		f.Mirror = openValue(mirror.String)
+		f.Offset = offset.Int64
+		f.Message = openValue(message.String)
+		failures = append(failures, f)
+	}
+	return failures, rows.Err()
+}
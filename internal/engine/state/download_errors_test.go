@@ -0,0 +1,50 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestDownloadFailures(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	if err := AddToMasterList(types.DownloadEntry{ID: "flaky", URL: "https://example.com/flaky", DestPath: filepath.Join(tmpDir, "flaky.bin"), Status: "downloading"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range types.MaxDownloadFailures + 5 {
+		f := types.DownloadFailure{At: int64(1000 + i), Kind: "5xx", Status: 503, Mirror: "https://mirror.example.com/flaky", Offset: int64(i) * types.MB, Message: fmt.Sprintf("attempt %d", i)}
+		if err := RecordDownloadFailure("flaky", f); err != nil {
+			t.Fatalf("RecordDownloadFailure failed: %v", err)
+		}
+	}
+
+	failures, err := LoadDownloadFailures("flaky")
+	if err != nil {
+		t.Fatalf("LoadDownloadFailures failed: %v", err)
+	}
+	if len(failures) != types.MaxDownloadFailures {
+		t.Fatalf("kept %d failures, want %d", len(failures), types.MaxDownloadFailures)
+	}
+	first, last := failures[0], failures[len(failures)-1]
+	if first.Message != "attempt 5" || last.Message != fmt.Sprintf("attempt %d", types.MaxDownloadFailures+4) {
+		t.Errorf("kept %q..%q, want the newest, oldest first", first.Message, last.Message)
+	}
+	if last.Status != 503 || last.Kind != "5xx" || last.Mirror == "" || last.Offset == 0 {
+		t.Errorf("failure = %+v, fields lost", last)
+	}
+
+	// The history goes with the download
+	if err := RemoveFromMasterList("flaky"); err != nil {
+		t.Fatal(err)
+	}
+	if failures, _ := LoadDownloadFailures("flaky"); len(failures) != 0 {
+		t.Errorf("history outlived its download: %+v", failures)
+	}
+}
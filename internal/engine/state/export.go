@@ -0,0 +1,69 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// ExportFilter selects the downloads ExportDownloads visits. Zero fields
+// match everything.
+type ExportFilter struct {
+	Statuses []string // e.g. "completed", "error"
+	Since    int64    // Unix time; only downloads added at or after it
+	Until    int64    // Unix time; only downloads added before it
+}
+
+// ExportDownloads calls fn for every download matching filter, oldest first.
+// Rows are read one at a time so the table is never held in memory.
+func ExportDownloads(filter ExportFilter, fn func(types.DownloadEntry) error) error {
+	db := getDBHelper()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	var where []string
+	var args []any
+	if len(filter.Statuses) > 0 {
+		where = append(where, "status IN (?"+strings.Repeat(", ?", len(filter.Statuses)-1)+")")
+		for _, s := range filter.Statuses {
+			args = append(args, s)
+		}
+	}
+	if filter.Since > 0 {
+		where = append(where, "created_at >= ?")
+		args = append(args, filter.Since)
+	}
+	if filter.Until > 0 {
+		where = append(where, "created_at < ?")
+		args = append(args, filter.Until)
+	}
+	query := "SELECT " + masterListColumns + " FROM downloads"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY COALESCE(created_at, 0), id"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query downloads: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			utils.Debug("Error closing rows: %v", err)
+		}
+	}()
+
+	for rows.Next() {
+		e, err := scanMasterListRow(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
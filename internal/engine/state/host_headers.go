@@ -0,0 +1,89 @@
+package state
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// SaveHostHeaders records the header profile observed for a host
+func SaveHostHeaders(profile types.HostHeaderProfile) error {
+	host := strings.ToLower(strings.TrimSpace(profile.Host))
+	if host == "" {
+		return nil
+	}
+
+	headers, err := json.Marshal(profile.Headers)
+	if err != nil {
+		return fmt.Errorf("failed to encode host headers: %w", err)
+	}
+	sealed, err := sealSecret(string(headers))
+	if err != nil {
+		return fmt.Errorf("failed to encode host headers: %w", err)
+	}
+
+	// Stored as an integer; not every backend converts bools for us
+	worked := 0
+	if profile.Worked {
+		worked = 1
+	}
+
+	return withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			INSERT INTO host_headers (host, headers, redacted, worked, updated_at)
+			VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT(host) DO UPDATE SET
+				headers = excluded.headers,
+				redacted = excluded.redacted,
+				worked = excluded.worked,
+				updated_at = excluded.updated_at
+		`, host, sealed, strings.Join(profile.Redacted, ","), worked, time.Now().UnixNano())
+		if err != nil {
+			return fmt.Errorf("failed to save host headers: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetHostHeaders returns the header profile recorded for a host, or nil if none
+func GetHostHeaders(host string) (*types.HostHeaderProfile, error) {
+	db := getDBHelper()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	var p types.HostHeaderProfile
+	var headers, redacted sql.NullString
+	var worked, updatedAt sql.NullInt64
+	err := db.QueryRow(`
+		SELECT host, headers, redacted, worked, updated_at
+		FROM host_headers
+		WHERE host = ?
+	`, strings.ToLower(strings.TrimSpace(host))).Scan(&p.Host, &headers, &redacted, &worked, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query host headers: %w", err)
+	}
+
+	// Headers that can't be decrypted are dropped; the profile is relearned
+	if plain, err := openSecret(headers.String); err != nil {
+		utils.Debug("Ignoring stored headers for %s: %v", p.Host, err)
+	} else if plain != "" {
+		if err := json.Unmarshal([]byte(plain), &p.Headers); err != nil {
+			return nil, fmt.Errorf("failed to decode host headers: %w", err)
+		}
+	}
+	if redacted.String != "" {
+		p.Redacted = strings.Split(redacted.String, ",")
+	}
+	p.Worked = worked.Int64 != 0
+	p.UpdatedAt = time.Unix(0, updatedAt.Int64).Unix()
+	return &p, nil
+}
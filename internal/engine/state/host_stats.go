@@ -0,0 +1,62 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// SaveHostConnectionLimit records the connection cap inferred for a host
+func SaveHostConnectionLimit(host string, limit int, hint string) error {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if host == "" {
+		return nil
+	}
+
+	return withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			INSERT INTO host_stats (host, max_connections, limit_hint, updated_at)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(host) DO UPDATE SET
+				max_connections = excluded.max_connections,
+				limit_hint = excluded.limit_hint,
+				updated_at = excluded.updated_at
+		`, host, limit, hint, time.Now().UnixNano())
+		if err != nil {
+			return fmt.Errorf("failed to save host stats: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetHostStats returns the stats recorded for a host, or nil if none
+func GetHostStats(host string) (*types.HostStats, error) {
+	db := getDBHelper()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	var s types.HostStats
+	var maxConns sql.NullInt64
+	var hint sql.NullString
+	var updatedAt sql.NullInt64
+	err := db.QueryRow(`
+		SELECT host, max_connections, limit_hint, updated_at
+		FROM host_stats
+		WHERE host = ?
+	`, strings.ToLower(strings.TrimSpace(host))).Scan(&s.Host, &maxConns, &hint, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query host stats: %w", err)
+	}
+
+	s.MaxConnections = int(maxConns.Int64)
+	s.LimitHint = hint.String
+	s.UpdatedAt = time.Unix(0, updatedAt.Int64).Unix()
+	return &s, nil
+}
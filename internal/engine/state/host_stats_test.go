@@ -0,0 +1,39 @@
+package state
+
+import (
+	"os"
+	"testing"
+)
+
+func TestHostStats_SaveAndGet(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	stats, err := GetHostStats("example.com")
+	if err != nil {
+		t.Fatalf("GetHostStats failed: %v", err)
+	}
+	if stats != nil {
+		t.Fatalf("expected nil for unknown host, got %+v", stats)
+	}
+
+	if err := SaveHostConnectionLimit("Example.com", 4, "X-Concurrency-Limit"); err != nil {
+		t.Fatalf("SaveHostConnectionLimit failed: %v", err)
+	}
+	// Later hints replace earlier ones
+	if err := SaveHostConnectionLimit("example.com", 2, "Retry-After"); err != nil {
+		t.Fatalf("SaveHostConnectionLimit failed: %v", err)
+	}
+
+	stats, err = GetHostStats("EXAMPLE.com")
+	if err != nil {
+		t.Fatalf("GetHostStats failed: %v", err)
+	}
+	if stats == nil || stats.MaxConnections != 2 || stats.LimitHint != "Retry-After" {
+		t.Errorf("unexpected host stats: %+v", stats)
+	}
+	if stats != nil && stats.UpdatedAt == 0 {
+		t.Error("expected updated_at to be set")
+	}
+}
@@ -0,0 +1,303 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// Inspector reads a SQLite state database without ever writing to it, so it
+// can be pointed at the file of a running daemon. Unlike the package-level
+// connection it does not migrate the schema, and every read runs in one
+// read-only transaction so it sees a consistent snapshot while the daemon
+// keeps writing through WAL.
+type Inspector struct {
+	db   *sql.DB
+	path string
+}
+
+// OpenInspector opens the SQLite database at path read-only
+func OpenInspector(path string) (*Inspector, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("state database: %w", err)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	q := url.Values{}
+	q.Set("mode", "ro")
+	q.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", DefaultBusyTimeout.Milliseconds()))
+	q.Add("_pragma", "query_only(1)")
+	q.Set("_txlock", "deferred")
+	dsn := (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs), RawQuery: q.Encode()}).String()
+
+	d, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.Ping(); err != nil {
+		_ = d.Close()
+		return nil, fmt.Errorf("failed to open state database read-only: %w", err)
+	}
+	return &Inspector{db: d, path: abs}, nil
+}
+
+// Close releases the inspector's connection
+func (in *Inspector) Close() error {
+	return in.db.Close()
+}
+
+// view runs fn in a read-only transaction
+func (in *Inspector) view(fn func(tx *sql.Tx) error) error {
+	tx, err := in.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	return fn(tx)
+}
+
+// DBStats summarizes a state database
+type DBStats struct {
+	Path          string           `json:"path"`
+	SchemaVersion int              `json:"schema_version"`
+	SizeBytes     int64            `json:"size_bytes"`
+	FreeBytes     int64            `json:"free_bytes"` // Unused pages a VACUUM would reclaim
+	WALBytes      int64            `json:"wal_bytes"`
+	Tables        map[string]int64 `json:"tables"`    // Row count per table
+	Downloads     map[string]int64 `json:"downloads"` // Download count per status
+}
+
+// Stats reports the size, schema version and row counts of the database
+func (in *Inspector) Stats() (*DBStats, error) {
+	stats := &DBStats{
+		Path:      in.path,
+		Tables:    make(map[string]int64),
+		Downloads: make(map[string]int64),
+	}
+	if fi, err := os.Stat(in.path + "-wal"); err == nil {
+		stats.WALBytes = fi.Size()
+	}
+
+	err := in.view(func(tx *sql.Tx) error {
+		var pageSize, pageCount, freePages int64
+		if err := tx.QueryRow("PRAGMA page_size").Scan(&pageSize); err != nil {
+			return err
+		}
+		if err := tx.QueryRow("PRAGMA page_count").Scan(&pageCount); err != nil {
+			return err
+		}
+		if err := tx.QueryRow("PRAGMA freelist_count").Scan(&freePages); err != nil {
+			return err
+		}
+		stats.SizeBytes = pageSize * pageCount
+		stats.FreeBytes = pageSize * freePages
+
+		tables, err := queryStrings(tx, "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+		if err != nil {
+			return err
+		}
+		for _, name := range tables {
+			var n int64
+			if err := tx.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %q", name)).Scan(&n); err != nil {
+				return err
+			}
+			stats.Tables[name] = n
+		}
+
+		if _, ok := stats.Tables["schema_version"]; ok {
+			var version sql.NullInt64
+			if err := tx.QueryRow("SELECT MAX(version) FROM schema_version").Scan(&version); err != nil {
+				return err
+			}
+			stats.SchemaVersion = int(version.Int64)
+		}
+
+		if _, ok := stats.Tables["downloads"]; !ok {
+			return nil
+		}
+		rows, err := tx.Query("SELECT COALESCE(status, ''), COUNT(*) FROM downloads GROUP BY status")
+		if err != nil {
+			return err
+		}
+		defer func() { _ = rows.Close() }()
+		for rows.Next() {
+			var status string
+			var n int64
+			if err := rows.Scan(&status, &n); err != nil {
+				return err
+			}
+			stats.Downloads[status] = n
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read database stats: %w", err)
+	}
+	return stats, nil
+}
+
+// StateDump is every stored column of one download and its remaining tasks
+type StateDump struct {
+	Download map[string]any   `json:"download"`
+	Tasks    []map[string]any `json:"tasks"`
+}
+
+// ResolveID returns the full id of the download whose id is or starts with
+// prefix, failing when none or several match
+func (in *Inspector) ResolveID(prefix string) (string, error) {
+	var ids []string
+	err := in.view(func(tx *sql.Tx) error {
+		var err error
+		ids, err = queryStrings(tx, "SELECT id FROM downloads WHERE id = ? OR substr(id, 1, length(?)) = ? ORDER BY id LIMIT 3", prefix, prefix, prefix)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, id := range ids {
+		if id == prefix {
+			return id, nil
+		}
+	}
+	switch len(ids) {
+	case 0:
+		return "", fmt.Errorf("no download matches %q", prefix)
+	case 1:
+		return ids[0], nil
+	default:
+		return "", fmt.Errorf("%q matches more than one download", prefix)
+	}
+}
+
+// Dump returns the stored state of download id as-is, including columns
+// newer than this binary knows about
+func (in *Inspector) Dump(id string) (*StateDump, error) {
+	dump := &StateDump{}
+	err := in.view(func(tx *sql.Tx) error {
+		downloads, err := queryMaps(tx, "SELECT * FROM downloads WHERE id = ?", id)
+		if err != nil {
+			return err
+		}
+		if len(downloads) == 0 {
+			return fmt.Errorf("download %s not found", id)
+		}
+		dump.Download = downloads[0]
+		dump.Tasks, err = queryMaps(tx, `SELECT * FROM tasks WHERE download_id = ? ORDER BY "offset"`, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dump, nil
+}
+
+// ChunkMap is a download's persisted chunk bitmap, one state per chunk
+type ChunkMap struct {
+	TotalSize  int64               `json:"total_size"`
+	Downloaded int64               `json:"downloaded"`
+	ChunkSize  int64               `json:"chunk_size"`
+	Chunks     []types.ChunkStatus `json:"chunks"`
+}
+
+// Count returns how many chunks are in status
+func (m *ChunkMap) Count(status types.ChunkStatus) int {
+	n := 0
+	for _, c := range m.Chunks {
+		if c == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Bitmap decodes the chunk bitmap saved for download id. Downloads that were
+// never paused mid-transfer have no bitmap and return no chunks.
+func (in *Inspector) Bitmap(id string) (*ChunkMap, error) {
+	m := &ChunkMap{}
+	var bitmap []byte
+	var chunkSize sql.NullInt64
+	err := in.view(func(tx *sql.Tx) error {
+		return tx.QueryRow("SELECT total_size, downloaded, chunk_bitmap, actual_chunk_size FROM downloads WHERE id = ?", id).
+			Scan(&m.TotalSize, &m.Downloaded, &bitmap, &chunkSize)
+	})
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("download %s not found", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m.ChunkSize = chunkSize.Int64
+	if m.ChunkSize <= 0 || m.TotalSize <= 0 || len(bitmap) == 0 {
+		return m, nil
+	}
+	// Same layout as ProgressState: 2 bits per chunk, 4 chunks per byte
+	numChunks := int((m.TotalSize + m.ChunkSize - 1) / m.ChunkSize)
+	if numChunks > len(bitmap)*4 {
+		numChunks = len(bitmap) * 4
+	}
+	m.Chunks = make([]types.ChunkStatus, numChunks)
+	for i := range m.Chunks {
+		m.Chunks[i] = types.ChunkStatus((bitmap[i/4] >> ((i % 4) * 2)) & 3)
+	}
+	return m, nil
+}
+
+func queryStrings(tx *sql.Tx, query string, args ...any) ([]string, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var out []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// queryMaps scans every row into a column name to value map. Text stored in
+// BLOB columns is left as []byte.
+func queryMaps(tx *sql.Tx, query string, args ...any) ([]map[string]any, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var out []map[string]any
+	for rows.Next() {
+		values := make([]any, len(cols))
+		ptrs := make([]any, len(cols))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]any, len(cols))
+		for i, c := range cols {
+			row[strings.Trim(c, `"`)] = values[i]
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
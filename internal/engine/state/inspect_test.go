@@ -0,0 +1,110 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestInspector_ReadsLiveDatabase(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	// 10 chunks: 0-3 completed, 4 downloading, the rest pending
+	bitmap := []byte{0xAA, 0x01, 0x00}
+	saved := &types.DownloadState{
+		ID:              "inspect-1",
+		URL:             "https://example.com/big.iso",
+		DestPath:        "/tmp/big.iso",
+		Filename:        "big.iso",
+		TotalSize:       1000,
+		Downloaded:      450,
+		Tasks:           []types.Task{{Offset: 500, Length: 500}},
+		ChunkBitmap:     bitmap,
+		ActualChunkSize: 100,
+	}
+	if err := SaveState(saved.URL, saved.DestPath, saved); err != nil {
+		t.Fatalf("SaveState: %v", err)
+	}
+	if err := AddToMasterList(types.DownloadEntry{ID: "done-1", URL: "https://example.com/a", DestPath: "/tmp/a", Status: "completed"}); err != nil {
+		t.Fatalf("AddToMasterList: %v", err)
+	}
+
+	// The daemon's connection stays open while the inspector reads
+	in, err := OpenInspector(filepath.Join(tmpDir, "surge.db"))
+	if err != nil {
+		t.Fatalf("OpenInspector: %v", err)
+	}
+	defer func() { _ = in.Close() }()
+
+	stats, err := in.Stats()
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if stats.SchemaVersion != latestSchemaVersion() {
+		t.Errorf("SchemaVersion = %d, want %d", stats.SchemaVersion, latestSchemaVersion())
+	}
+	if stats.Tables["downloads"] != 2 || stats.Tables["tasks"] != 1 {
+		t.Errorf("Tables = %v", stats.Tables)
+	}
+	if stats.Downloads["paused"] != 1 || stats.Downloads["completed"] != 1 {
+		t.Errorf("Downloads = %v", stats.Downloads)
+	}
+
+	id, err := in.ResolveID("inspect")
+	if err != nil || id != "inspect-1" {
+		t.Fatalf("ResolveID = %q, %v", id, err)
+	}
+	if _, err := in.ResolveID("nope"); err == nil {
+		t.Error("ResolveID(nope) succeeded")
+	}
+
+	dump, err := in.Dump(id)
+	if err != nil {
+		t.Fatalf("Dump: %v", err)
+	}
+	if dump.Download["filename"] != "big.iso" || len(dump.Tasks) != 1 {
+		t.Errorf("Dump = %+v", dump)
+	}
+
+	chunks, err := in.Bitmap(id)
+	if err != nil {
+		t.Fatalf("Bitmap: %v", err)
+	}
+	if len(chunks.Chunks) != 10 {
+		t.Fatalf("chunks = %d, want 10", len(chunks.Chunks))
+	}
+	if chunks.Count(types.ChunkCompleted) != 4 || chunks.Count(types.ChunkDownloading) != 1 || chunks.Count(types.ChunkPending) != 5 {
+		t.Errorf("chunk states = %v", chunks.Chunks)
+	}
+}
+
+func TestInspector_IsReadOnly(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	if err := AddToMasterList(types.DownloadEntry{ID: "keep", URL: "https://example.com/k", DestPath: "/tmp/k", Status: "queued"}); err != nil {
+		t.Fatalf("AddToMasterList: %v", err)
+	}
+
+	in, err := OpenInspector(filepath.Join(tmpDir, "surge.db"))
+	if err != nil {
+		t.Fatalf("OpenInspector: %v", err)
+	}
+	defer func() { _ = in.Close() }()
+
+	if _, err := in.db.Exec("DELETE FROM downloads"); err == nil {
+		t.Fatal("write through the inspector succeeded")
+	}
+	if entry, _ := GetDownload("keep"); entry == nil {
+		t.Fatal("row was deleted")
+	}
+
+	if _, err := OpenInspector(filepath.Join(tmpDir, "missing.db")); err == nil {
+		t.Error("OpenInspector created a missing database")
+	}
+}
@@ -0,0 +1,514 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// migration is one ordered schema change. up must be idempotent: databases
+// created before versioning get every migration applied, and their tables
+// may already have some of the columns. down reverts up and exists for
+// development; user data in dropped columns is lost.
+type migration struct {
+	version int
+	name    string
+	up      func(*sql.Tx) error
+	down    func(*sql.Tx) error
+}
+
+// column is a column added by a migration
+type column struct {
+	name string
+	def  string
+}
+
+// migrations lists every schema change in order. Append new steps; never
+// edit or reorder ones that have shipped.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "downloads and tasks",
+		up: func(tx *sql.Tx) error {
+			return execAll(tx, `
+				CREATE TABLE IF NOT EXISTS downloads (
+					id TEXT PRIMARY KEY,
+					url TEXT NOT NULL,
+					dest_path TEXT NOT NULL,
+					filename TEXT,
+					status TEXT,
+					total_size INTEGER,
+					downloaded INTEGER,
+					url_hash TEXT,
+					created_at INTEGER,
+					paused_at INTEGER,
+					completed_at INTEGER,
+					time_taken INTEGER
+				)`, `
+				CREATE TABLE IF NOT EXISTS tasks (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					download_id TEXT,
+					"offset" INTEGER,
+					length INTEGER,
+					FOREIGN KEY(download_id) REFERENCES downloads(id) ON DELETE CASCADE
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_tasks_download_id ON tasks(download_id)`,
+			)
+		},
+		down: func(tx *sql.Tx) error {
+			return execAll(tx, `DROP TABLE IF EXISTS tasks`, `DROP TABLE IF EXISTS downloads`)
+		},
+	},
+	{
+		version: 2,
+		name:    "resume metadata",
+		up: func(tx *sql.Tx) error {
+			return addColumns(tx, "downloads", resumeColumns)
+		},
+		down: func(tx *sql.Tx) error {
+			return dropColumns(tx, "downloads", resumeColumns)
+		},
+	},
+	{
+		version: 3,
+		name:    "url history",
+		up: func(tx *sql.Tx) error {
+			return execAll(tx, `
+				CREATE TABLE IF NOT EXISTS url_history (
+					url TEXT PRIMARY KEY,
+					status TEXT,
+					error TEXT,
+					updated_at INTEGER
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_url_history_updated_at ON url_history(updated_at)`,
+			)
+		},
+		down: func(tx *sql.Tx) error {
+			return execAll(tx, `DROP TABLE IF EXISTS url_history`)
+		},
+	},
+	{
+		version: 4,
+		name:    "host stats",
+		up: func(tx *sql.Tx) error {
+			return execAll(tx, `
+				CREATE TABLE IF NOT EXISTS host_stats (
+					host TEXT PRIMARY KEY,
+					max_connections INTEGER,
+					limit_hint TEXT,
+					updated_at INTEGER
+				)`)
+		},
+		down: func(tx *sql.Tx) error {
+			return execAll(tx, `DROP TABLE IF EXISTS host_stats`)
+		},
+	},
+	{
+		version: 5,
+		name:    "host headers",
+		up: func(tx *sql.Tx) error {
+			return execAll(tx, `
+				CREATE TABLE IF NOT EXISTS host_headers (
+					host TEXT PRIMARY KEY,
+					headers TEXT,
+					redacted TEXT,
+					worked INTEGER,
+					updated_at INTEGER
+				)`)
+		},
+		down: func(tx *sql.Tx) error {
+			return execAll(tx, `DROP TABLE IF EXISTS host_headers`)
+		},
+	},
+	{
+		version: 6,
+		name:    "probe cache",
+		up: func(tx *sql.Tx) error {
+			return addColumns(tx, "downloads", probeColumns)
+		},
+		down: func(tx *sql.Tx) error {
+			return dropColumns(tx, "downloads", probeColumns)
+		},
+	},
+	{
+		version: 7,
+		name:    "download trace ids",
+		up: func(tx *sql.Tx) error {
+			return addColumns(tx, "downloads", traceColumns)
+		},
+		down: func(tx *sql.Tx) error {
+			return dropColumns(tx, "downloads", traceColumns)
+		},
+	},
+	{
+		version: 8,
+		name:    "download tags",
+		up: func(tx *sql.Tx) error {
+			return addColumns(tx, "downloads", tagColumns)
+		},
+		down: func(tx *sql.Tx) error {
+			return dropColumns(tx, "downloads", tagColumns)
+		},
+	},
+	{
+		version: 9,
+		name:    "download categories",
+		up: func(tx *sql.Tx) error {
+			return addColumns(tx, "downloads", categoryColumns)
+		},
+		down: func(tx *sql.Tx) error {
+			return dropColumns(tx, "downloads", categoryColumns)
+		},
+	},
+	{
+		version: 10,
+		name:    "download notes",
+		up: func(tx *sql.Tx) error {
+			return addColumns(tx, "downloads", noteColumns)
+		},
+		down: func(tx *sql.Tx) error {
+			return dropColumns(tx, "downloads", noteColumns)
+		},
+	},
+	{
+		version: 11,
+		name:    "download archive",
+		up: func(tx *sql.Tx) error {
+			return execAll(tx, `
+				CREATE TABLE IF NOT EXISTS download_archive (
+					url_hash TEXT PRIMARY KEY,
+					completed_at INTEGER
+				)`)
+		},
+		down: func(tx *sql.Tx) error {
+			return execAll(tx, `DROP TABLE IF EXISTS download_archive`)
+		},
+	},
+	{
+		version: 12,
+		name:    "checksums",
+		up: func(tx *sql.Tx) error {
+			return addColumns(tx, "downloads", checksumColumns)
+		},
+		down: func(tx *sql.Tx) error {
+			return dropColumns(tx, "downloads", checksumColumns)
+		},
+	},
+	{
+		version: 13,
+		name:    "pause reasons",
+		up: func(tx *sql.Tx) error {
+			return addColumns(tx, "downloads", pauseColumns)
+		},
+		down: func(tx *sql.Tx) error {
+			return dropColumns(tx, "downloads", pauseColumns)
+		},
+	},
+	{
+		version: 14,
+		name:    "download overrides",
+		up: func(tx *sql.Tx) error {
+			return addColumns(tx, "downloads", overrideColumns)
+		},
+		down: func(tx *sql.Tx) error {
+			return dropColumns(tx, "downloads", overrideColumns)
+		},
+	},
+	{
+		version: 15,
+		name:    "trash",
+		up: func(tx *sql.Tx) error {
+			return addColumns(tx, "downloads", trashColumns)
+		},
+		down: func(tx *sql.Tx) error {
+			return dropColumns(tx, "downloads", trashColumns)
+		},
+	},
+	{
+		version: 16,
+		name:    "response metadata",
+		up: func(tx *sql.Tx) error {
+			return addColumns(tx, "downloads", responseColumns)
+		},
+		down: func(tx *sql.Tx) error {
+			return dropColumns(tx, "downloads", responseColumns)
+		},
+	},
+	{
+		version: 17,
+		name:    "queue positions",
+		up: func(tx *sql.Tx) error {
+			if err := addColumns(tx, "downloads", queueColumns); err != nil {
+				return err
+			}
+			// Existing downloads keep the order they were added in
+			return execAll(tx, `
+				UPDATE downloads SET queue_position = (
+					SELECT COUNT(*) FROM downloads AS d
+					WHERE (COALESCE(d.created_at, 0), d.id) <= (COALESCE(downloads.created_at, 0), downloads.id)
+				)`)
+		},
+		down: func(tx *sql.Tx) error {
+			return dropColumns(tx, "downloads", queueColumns)
+		},
+	},
+	{
+		version: 18,
+		name:    "download errors",
+		up: func(tx *sql.Tx) error {
+			return execAll(tx, `
+				CREATE TABLE IF NOT EXISTS download_errors (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					download_id TEXT NOT NULL,
+					occurred_at INTEGER,
+					kind TEXT,
+					http_status INTEGER,
+					mirror TEXT,
+					task_offset INTEGER,
+					message TEXT,
+					FOREIGN KEY(download_id) REFERENCES downloads(id) ON DELETE CASCADE
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_download_errors_download_id ON download_errors(download_id)`,
+			)
+		},
+		down: func(tx *sql.Tx) error {
+			return execAll(tx, `DROP TABLE IF EXISTS download_errors`)
+		},
+	},
+}
+
+var resumeColumns = []column{
+	{"mirrors", "TEXT"},
+	{"chunk_bitmap", "BLOB"},
+	{"actual_chunk_size", "INTEGER"},
+	{"avg_speed", "REAL"},
+	{"file_hash", "TEXT"},
+}
+
+var probeColumns = []column{
+	{"probe_size", "INTEGER"},
+	{"probe_ranges", "INTEGER"},
+	{"probe_etag", "TEXT"},
+	{"probe_final_url", "TEXT"},
+	{"probed_at", "INTEGER"},
+}
+
+var traceColumns = []column{
+	{"trace_id", "TEXT"},
+}
+
+var tagColumns = []column{
+	{"tags", "TEXT"},
+}
+
+var categoryColumns = []column{
+	{"category", "TEXT"},
+}
+
+var noteColumns = []column{
+	{"note", "TEXT"},
+	{"metadata", "TEXT"}, // JSON object of string values
+}
+
+var checksumColumns = []column{
+	{"checksum", "TEXT"}, // SHA-256 of the completed file
+	{"verify_status", "TEXT"},
+	{"verified_at", "INTEGER"},
+}
+
+var pauseColumns = []column{
+	{"pause_reason", "TEXT"},
+}
+
+var overrideColumns = []column{
+	{"overrides", "TEXT"}, // JSON DownloadOverrides
+}
+
+var trashColumns = []column{
+	{"trashed_at", "INTEGER"}, // Unix time the download was deleted into the trash
+}
+
+var responseColumns = []column{
+	{"probe_content_type", "TEXT"},
+	{"probe_server", "TEXT"},
+	{"probe_last_modified", "TEXT"},
+}
+
+var queueColumns = []column{
+	{"queue_position", "INTEGER"}, // Order downloads are started in, lowest first
+}
+
+// latestSchemaVersion is the version a fully migrated database reports
+func latestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+func execAll(tx *sql.Tx, statements ...string) error {
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tableColumns returns the column names of table
+func tableColumns(tx *sql.Tx, table string) (map[string]bool, error) {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var cid, notnull, pk int
+		var name, ctype string
+		var dfltValue interface{}
+		if err := rows.Scan(&cid, &name, &ctype, &notnull, &dfltValue, &pk); err != nil {
+			return nil, err
+		}
+		existing[name] = true
+	}
+	return existing, rows.Err()
+}
+
+// addColumns adds the columns table does not have yet
+func addColumns(tx *sql.Tx, table string, cols []column) error {
+	existing, err := tableColumns(tx, table)
+	if err != nil {
+		return err
+	}
+	for _, col := range cols {
+		if existing[col.name] {
+			continue
+		}
+		if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, col.name, col.def)); err != nil {
+			return fmt.Errorf("failed to add column %s: %w", col.name, err)
+		}
+	}
+	return nil
+}
+
+// dropColumns removes the columns table still has
+func dropColumns(tx *sql.Tx, table string, cols []column) error {
+	existing, err := tableColumns(tx, table)
+	if err != nil {
+		return err
+	}
+	for _, col := range cols {
+		if !existing[col.name] {
+			continue
+		}
+		if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, col.name)); err != nil {
+			return fmt.Errorf("failed to drop column %s: %w", col.name, err)
+		}
+	}
+	return nil
+}
+
+// ensureSchemaVersionTable creates the table recording applied migrations
+func ensureSchemaVersionTable(d *sql.DB) error {
+	_, err := d.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_version (
+			version INTEGER PRIMARY KEY,
+			name TEXT,
+			applied_at INTEGER
+		)
+	`)
+	return err
+}
+
+// currentSchemaVersion returns the highest applied migration, 0 for none
+func currentSchemaVersion(d *sql.DB) (int, error) {
+	var version sql.NullInt64
+	if err := d.QueryRow("SELECT MAX(version) FROM schema_version").Scan(&version); err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
+
+// migrate brings d up to the latest schema, one transaction per step
+func migrate(d *sql.DB) error {
+	return migrateTo(d, latestSchemaVersion())
+}
+
+// migrateTo applies or reverts migrations until d is at target
+func migrateTo(d *sql.DB, target int) error {
+	if target < 0 || target > latestSchemaVersion() {
+		return fmt.Errorf("unknown schema version %d", target)
+	}
+	if err := ensureSchemaVersionTable(d); err != nil {
+		return fmt.Errorf("failed to create schema_version: %w", err)
+	}
+	current, err := currentSchemaVersion(d)
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if current > latestSchemaVersion() {
+		return fmt.Errorf("database schema version %d is newer than this build supports (%d)", current, latestSchemaVersion())
+	}
+
+	for _, m := range migrations {
+		if m.version <= current || m.version > target {
+			continue
+		}
+		if err := runMigration(d, m.version, m.name, m.up, func(tx *sql.Tx) error {
+			_, err := tx.Exec("INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)", m.version, m.name, time.Now().Unix())
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.version > current || m.version <= target {
+			continue
+		}
+		if err := runMigration(d, m.version, m.name, m.down, func(tx *sql.Tx) error {
+			_, err := tx.Exec("DELETE FROM schema_version WHERE version = ?", m.version)
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runMigration runs step and record in one transaction
+func runMigration(d *sql.DB, version int, name string, step, record func(*sql.Tx) error) error {
+	tx, err := d.Begin()
+	if err != nil {
+		return err
+	}
+	if err := step(tx); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("migration %d (%s) failed: %w", version, name, err)
+	}
+	if err := record(tx); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("failed to record migration %d: %w", version, err)
+	}
+	return tx.Commit()
+}
+
+// SchemaVersion returns the schema version of the open state database
+func SchemaVersion() (int, error) {
+	d := getDBHelper()
+	if d == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+	return currentSchemaVersion(d)
+}
+
+// MigrateTo moves the state database to version, running down-migrations
+// when it is older than the current one. It is meant for development;
+// normal startup always migrates to the latest version.
+func MigrateTo(version int) error {
+	d := getDBHelper()
+	if d == nil {
+		return fmt.Errorf("database not initialized")
+	}
+	return migrateTo(d, version)
+}
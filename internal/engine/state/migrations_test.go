@@ -0,0 +1,227 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func openMigrationDB(t *testing.T) *sql.DB {
+	t.Helper()
+	s := &SQLiteStore{Path: filepath.Join(t.TempDir(), "surge.db")}
+	d, err := s.Open()
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = d.Close() })
+	return d
+}
+
+// schemaSnapshot describes every user table, index and column in d,
+// ignoring the schema_version bookkeeping table.
+func schemaSnapshot(t *testing.T, d *sql.DB) string {
+	t.Helper()
+	rows, err := d.Query(`SELECT type, name FROM sqlite_master
+		WHERE name NOT LIKE 'sqlite_%' AND name != 'schema_version'`)
+	if err != nil {
+		t.Fatalf("sqlite_master: %v", err)
+	}
+	var objects, tables []string
+	for rows.Next() {
+		var typ, name string
+		if err := rows.Scan(&typ, &name); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		objects = append(objects, typ+" "+name)
+		if typ == "table" {
+			tables = append(tables, name)
+		}
+	}
+	_ = rows.Close()
+
+	for _, table := range tables {
+		tx, err := d.Begin()
+		if err != nil {
+			t.Fatalf("begin: %v", err)
+		}
+		cols, err := tableColumns(tx, table)
+		_ = tx.Rollback()
+		if err != nil {
+			t.Fatalf("table_info(%s): %v", table, err)
+		}
+		for col := range cols {
+			objects = append(objects, fmt.Sprintf("column %s.%s", table, col))
+		}
+	}
+	sort.Strings(objects)
+	return strings.Join(objects, "\n")
+}
+
+func versionOf(t *testing.T, d *sql.DB) int {
+	t.Helper()
+	v, err := currentSchemaVersion(d)
+	if err != nil {
+		t.Fatalf("currentSchemaVersion: %v", err)
+	}
+	return v
+}
+
+func TestMigrations_AreOrdered(t *testing.T) {
+	for i, m := range migrations {
+		if m.version != i+1 {
+			t.Errorf("migration %q has version %d, want %d", m.name, m.version, i+1)
+		}
+		if m.up == nil || m.down == nil {
+			t.Errorf("migration %d is missing an up or down step", m.version)
+		}
+	}
+}
+
+func TestMigrations_FreshDatabase(t *testing.T) {
+	d := openMigrationDB(t)
+	if err := migrate(d); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if got := versionOf(t, d); got != latestSchemaVersion() {
+		t.Fatalf("version = %d, want %d", got, latestSchemaVersion())
+	}
+
+	before := schemaSnapshot(t, d)
+	if err := migrate(d); err != nil {
+		t.Fatalf("second migrate: %v", err)
+	}
+	if after := schemaSnapshot(t, d); after != before {
+		t.Errorf("re-running migrate changed the schema:\n%s\nvs\n%s", before, after)
+	}
+}
+
+// Each migration applies on top of its predecessor, is idempotent, and its
+// down step restores the previous schema exactly.
+func TestMigrations_EachStepUpAndDown(t *testing.T) {
+	for _, m := range migrations {
+		t.Run(fmt.Sprintf("%d_%s", m.version, strings.ReplaceAll(m.name, " ", "_")), func(t *testing.T) {
+			d := openMigrationDB(t)
+			if err := migrateTo(d, m.version-1); err != nil {
+				t.Fatalf("migrate to %d: %v", m.version-1, err)
+			}
+			before := schemaSnapshot(t, d)
+
+			if err := migrateTo(d, m.version); err != nil {
+				t.Fatalf("up: %v", err)
+			}
+			applied := schemaSnapshot(t, d)
+			if applied == before {
+				t.Fatal("up did not change the schema")
+			}
+
+			// Re-running up against an already migrated schema is how legacy
+			// databases are adopted, so it must be a no-op.
+			tx, err := d.Begin()
+			if err != nil {
+				t.Fatalf("begin: %v", err)
+			}
+			if err := m.up(tx); err != nil {
+				_ = tx.Rollback()
+				t.Fatalf("repeated up: %v", err)
+			}
+			if err := tx.Commit(); err != nil {
+				t.Fatalf("commit: %v", err)
+			}
+			if got := schemaSnapshot(t, d); got != applied {
+				t.Errorf("repeated up changed the schema:\n%s\nvs\n%s", applied, got)
+			}
+
+			if err := migrateTo(d, m.version-1); err != nil {
+				t.Fatalf("down: %v", err)
+			}
+			if got := schemaSnapshot(t, d); got != before {
+				t.Errorf("down did not restore the schema:\n%s\nvs\n%s", before, got)
+			}
+			if got := versionOf(t, d); got != m.version-1 {
+				t.Errorf("version after down = %d, want %d", got, m.version-1)
+			}
+		})
+	}
+}
+
+func TestMigrations_AdoptsUnversionedDatabase(t *testing.T) {
+	d := openMigrationDB(t)
+
+	// A database from before versioning: some later columns were already
+	// added by the old ad-hoc upgrade, others were not.
+	if _, err := d.Exec(`CREATE TABLE downloads (
+		id TEXT PRIMARY KEY, url TEXT NOT NULL, dest_path TEXT NOT NULL,
+		filename TEXT, status TEXT, total_size INTEGER, downloaded INTEGER,
+		url_hash TEXT, created_at INTEGER, paused_at INTEGER,
+		completed_at INTEGER, time_taken INTEGER, mirrors TEXT, avg_speed REAL
+	)`); err != nil {
+		t.Fatalf("create legacy table: %v", err)
+	}
+	if _, err := d.Exec(`INSERT INTO downloads (id, url, dest_path, status, mirrors)
+		VALUES ('legacy', 'http://example.com/a', '/tmp/a', 'paused', 'http://mirror/a')`); err != nil {
+		t.Fatalf("insert legacy row: %v", err)
+	}
+
+	if err := migrate(d); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if got := versionOf(t, d); got != latestSchemaVersion() {
+		t.Fatalf("version = %d, want %d", got, latestSchemaVersion())
+	}
+
+	var mirrors string
+	var probeSize sql.NullInt64
+	if err := d.QueryRow("SELECT mirrors, probe_size FROM downloads WHERE id = 'legacy'").Scan(&mirrors, &probeSize); err != nil {
+		t.Fatalf("query migrated row: %v", err)
+	}
+	if mirrors != "http://mirror/a" {
+		t.Errorf("mirrors = %q, want preserved value", mirrors)
+	}
+	if probeSize.Valid {
+		t.Errorf("probe_size = %d, want NULL", probeSize.Int64)
+	}
+}
+
+func TestMigrations_RejectsNewerDatabase(t *testing.T) {
+	d := openMigrationDB(t)
+	if err := migrate(d); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if _, err := d.Exec("INSERT INTO schema_version (version, name, applied_at) VALUES (?, 'future', 0)", latestSchemaVersion()+1); err != nil {
+		t.Fatalf("insert future version: %v", err)
+	}
+	if err := migrate(d); err == nil {
+		t.Fatal("expected migrate to refuse a newer schema")
+	}
+}
+
+func TestSchemaVersion(t *testing.T) {
+	tempDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tempDir) }()
+	defer CloseDB()
+
+	v, err := SchemaVersion()
+	if err != nil {
+		t.Fatalf("SchemaVersion: %v", err)
+	}
+	if v != latestSchemaVersion() {
+		t.Fatalf("SchemaVersion = %d, want %d", v, latestSchemaVersion())
+	}
+
+	if err := MigrateTo(latestSchemaVersion() - 1); err != nil {
+		t.Fatalf("MigrateTo down: %v", err)
+	}
+	if v, _ := SchemaVersion(); v != latestSchemaVersion()-1 {
+		t.Errorf("SchemaVersion after down = %d", v)
+	}
+	if err := MigrateTo(latestSchemaVersion()); err != nil {
+		t.Fatalf("MigrateTo up: %v", err)
+	}
+	if err := MigrateTo(latestSchemaVersion() + 1); err == nil {
+		t.Error("expected error for unknown version")
+	}
+}
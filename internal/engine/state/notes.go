@@ -0,0 +1,59 @@
+package state
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// UpdateNote applies update to the note and metadata of download id and
+// returns the result. It fails with types.ErrNotFound for unknown ids.
+func UpdateNote(id string, update types.NoteUpdate) (string, map[string]string, error) {
+	var note string
+	var metadata map[string]string
+	err := withTx(func(tx *sql.Tx) error {
+		var curNote, curMetadata sql.NullString
+		err := tx.QueryRow("SELECT note, metadata FROM downloads WHERE id = ?", id).Scan(&curNote, &curMetadata)
+		if err == sql.ErrNoRows {
+			return types.ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to query note: %w", err)
+		}
+
+		note, metadata = update.Apply(curNote.String, decodeMetadata(curMetadata.String))
+		encoded := ""
+		if len(metadata) > 0 {
+			data, err := json.Marshal(metadata)
+			if err != nil {
+				return err
+			}
+			encoded = string(data)
+		}
+		if _, err := tx.Exec("UPDATE downloads SET note = ?, metadata = ? WHERE id = ?", note, encoded, id); err != nil {
+			return fmt.Errorf("failed to save note: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return note, metadata, nil
+}
+
+// decodeMetadata parses the metadata column. A corrupt value is dropped
+// rather than failing the whole row.
+func decodeMetadata(s string) map[string]string {
+	if s == "" {
+		return nil
+	}
+	var m map[string]string
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		utils.Debug("Ignoring invalid download metadata: %v", err)
+		return nil
+	}
+	return m
+}
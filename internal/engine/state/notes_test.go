@@ -0,0 +1,67 @@
+package state
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestUpdateNote(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	note := "for the weekend"
+	if _, _, err := UpdateNote("note-id", types.NoteUpdate{Note: &note}); !errors.Is(err, types.ErrNotFound) {
+		t.Fatalf("UpdateNote on unknown id = %v, want ErrNotFound", err)
+	}
+
+	entry := types.DownloadEntry{ID: "note-id", URL: "https://example.com/a.iso", DestPath: "/tmp/a.iso", Status: "queued"}
+	if err := AddToMasterList(entry); err != nil {
+		t.Fatalf("AddToMasterList failed: %v", err)
+	}
+
+	if _, _, err := UpdateNote("note-id", types.NoteUpdate{Note: &note, Metadata: map[string]string{"source": "forum", "ticket": "42"}}); err != nil {
+		t.Fatalf("UpdateNote failed: %v", err)
+	}
+	// Metadata merges; the note is kept when not given
+	gotNote, gotMeta, err := UpdateNote("note-id", types.NoteUpdate{Metadata: map[string]string{"ticket": "43"}, Unset: []string{"source"}})
+	if err != nil {
+		t.Fatalf("UpdateNote failed: %v", err)
+	}
+	if gotNote != note || len(gotMeta) != 1 || gotMeta["ticket"] != "43" {
+		t.Fatalf("UpdateNote = %q, %v", gotNote, gotMeta)
+	}
+
+	// Lifecycle upserts must not wipe them
+	entry.Status = "completed"
+	if err := AddToMasterList(entry); err != nil {
+		t.Fatalf("AddToMasterList failed: %v", err)
+	}
+	got, err := GetDownload("note-id")
+	if err != nil || got == nil {
+		t.Fatalf("GetDownload = %v, %v", got, err)
+	}
+	if got.Note != note || got.Metadata["ticket"] != "43" {
+		t.Errorf("GetDownload note = %q, metadata = %v", got.Note, got.Metadata)
+	}
+
+	list, err := LoadMasterList()
+	if err != nil || len(list.Downloads) != 1 {
+		t.Fatalf("LoadMasterList = %v, %v", list, err)
+	}
+	if list.Downloads[0].Note != note {
+		t.Errorf("LoadMasterList note = %q", list.Downloads[0].Note)
+	}
+
+	empty := ""
+	if _, _, err := UpdateNote("note-id", types.NoteUpdate{Note: &empty, Unset: []string{"ticket"}}); err != nil {
+		t.Fatalf("UpdateNote failed: %v", err)
+	}
+	got, _ = GetDownload("note-id")
+	if got.Note != "" || got.Metadata != nil {
+		t.Errorf("after clearing: note = %q, metadata = %v", got.Note, got.Metadata)
+	}
+}
@@ -0,0 +1,153 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// Orphans are what is left when the database and the disk disagree about a
+// download: working files nothing claims, and paused downloads whose working
+// file is gone and so can no longer be resumed
+type Orphans struct {
+	Files   []string              `json:"files"`   // Working files with no unfinished download
+	Entries []types.DownloadEntry `json:"entries"` // Paused, queued or archived downloads whose working file is gone
+}
+
+// Empty reports whether nothing was found
+func (o *Orphans) Empty() bool {
+	return o == nil || (len(o.Files) == 0 && len(o.Entries) == 0)
+}
+
+// FindOrphans looks for orphans in the directories of every known download
+// and in dirs. Nothing is changed; pass the result to RemoveOrphans to clean up.
+func FindOrphans(dirs ...string) (*Orphans, error) {
+	list, err := LoadMasterList()
+	if err != nil {
+		return nil, err
+	}
+
+	found := &Orphans{}
+	claimed := claimedWorkingFiles(list.Downloads)
+	searchDirs := make(map[string]struct{}, len(dirs))
+	for _, dir := range dirs {
+		if dir != "" {
+			searchDirs[filepath.Clean(dir)] = struct{}{}
+		}
+	}
+
+	for _, e := range list.Downloads {
+		if e.DestPath == "" {
+			continue
+		}
+		searchDirs[filepath.Dir(e.DestPath)] = struct{}{}
+		if unresumable(e) {
+			found.Entries = append(found.Entries, e)
+		}
+	}
+
+	for dir := range searchDirs {
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read directory %s: %w", dir, err)
+		}
+		for _, f := range files {
+			if f.IsDir() || !strings.HasSuffix(f.Name(), types.IncompleteSuffix) {
+				continue
+			}
+			path := filepath.Join(dir, f.Name())
+			if _, ok := claimed[path]; !ok {
+				found.Files = append(found.Files, path)
+			}
+		}
+	}
+	sort.Strings(found.Files)
+
+	return found, nil
+}
+
+// RemoveOrphans deletes the files and download rows in o, skipping any that
+// stopped being orphaned since they were found: a file a new download now
+// claims, or a download whose working file is back. It returns how many of
+// each were removed.
+func RemoveOrphans(o *Orphans) (files, entries int, err error) {
+	if o.Empty() {
+		return 0, 0, nil
+	}
+
+	list, err := LoadMasterList()
+	if err != nil {
+		return 0, 0, err
+	}
+	claimed := claimedWorkingFiles(list.Downloads)
+	current := make(map[string]types.DownloadEntry, len(list.Downloads))
+	for _, e := range list.Downloads {
+		current[e.ID] = e
+	}
+
+	for _, path := range o.Files {
+		if _, ok := claimed[path]; ok {
+			continue
+		}
+		if err := retryRemove(path); err != nil && !os.IsNotExist(err) {
+			return files, entries, fmt.Errorf("failed to remove orphan file %s: %w", path, err)
+		}
+		utils.Debug("Orphans: removed working file %s", path)
+		files++
+	}
+
+	for _, e := range o.Entries {
+		if now, ok := current[e.ID]; !ok || !unresumable(now) {
+			continue
+		}
+		if err := removeDownloadAndTasks(e.ID); err != nil {
+			return files, entries, fmt.Errorf("failed to remove orphaned entry %s: %w", e.ID, err)
+		}
+		utils.Debug("Orphans: removed download %s, its working file %s is gone", e.ID, e.DestPath+types.IncompleteSuffix)
+		entries++
+	}
+
+	return files, entries, nil
+}
+
+// claimedWorkingFiles returns the working files of downloads that have not
+// completed and may still be written to
+func claimedWorkingFiles(downloads []types.DownloadEntry) map[string]struct{} {
+	claimed := make(map[string]struct{}, len(downloads))
+	for _, e := range downloads {
+		if e.DestPath != "" && e.Status != "completed" {
+			claimed[e.DestPath+types.IncompleteSuffix] = struct{}{}
+		}
+	}
+	return claimed
+}
+
+// unresumable reports whether e is a paused, queued or archived download that
+// lost its working file
+func unresumable(e types.DownloadEntry) bool {
+	switch e.Status {
+	case "paused", "queued", "archived":
+		return WorkingFileMissing(e.DestPath, e.Downloaded)
+	}
+	return false
+}
+
+// WorkingFileMissing reports whether a download to destPath that has
+// downloaded bytes no longer has its working file, so resuming it would leave
+// a hole where those bytes were. Downloads that have not written anything may
+// not have created theirs yet.
+func WorkingFileMissing(destPath string, downloaded int64) bool {
+	if destPath == "" || downloaded <= 0 {
+		return false
+	}
+	_, err := os.Stat(destPath + types.IncompleteSuffix)
+	return os.IsNotExist(err)
+}
@@ -0,0 +1,144 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestFindOrphans(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	downloadsDir := filepath.Join(tmpDir, "downloads")
+	if err := os.MkdirAll(downloadsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	touch := func(path string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte("partial"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, e := range []types.DownloadEntry{
+		// Resumable: its working file is there
+		{ID: "ok", URL: "https://example.com/ok", DestPath: filepath.Join(tmpDir, "ok.bin"), Status: "paused", Downloaded: 10},
+		// Lost its working file
+		{ID: "lost", URL: "https://example.com/lost", DestPath: filepath.Join(tmpDir, "lost.bin"), Status: "paused", Downloaded: 10},
+		// Nothing written yet, so nothing was lost
+		{ID: "fresh", URL: "https://example.com/fresh", DestPath: filepath.Join(tmpDir, "fresh.bin"), Status: "queued"},
+		// Completed downloads claim no working file
+		{ID: "done", URL: "https://example.com/done", DestPath: filepath.Join(tmpDir, "done.bin"), Status: "completed", Downloaded: 10, CompletedAt: time.Now().Unix()},
+	} {
+		if err := AddToMasterList(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	touch(filepath.Join(tmpDir, "ok.bin"+types.IncompleteSuffix))
+	touch(filepath.Join(tmpDir, "done.bin"+types.IncompleteSuffix))
+	touch(filepath.Join(downloadsDir, "stray.bin"+types.IncompleteSuffix))
+	touch(filepath.Join(downloadsDir, "unrelated.bin"))
+
+	orphans, err := FindOrphans(downloadsDir)
+	if err != nil {
+		t.Fatalf("FindOrphans failed: %v", err)
+	}
+
+	wantFiles := []string{
+		filepath.Join(tmpDir, "done.bin"+types.IncompleteSuffix),
+		filepath.Join(downloadsDir, "stray.bin"+types.IncompleteSuffix),
+	}
+	if len(orphans.Files) != len(wantFiles) {
+		t.Fatalf("orphaned files = %v, want %v", orphans.Files, wantFiles)
+	}
+	for i, want := range wantFiles {
+		if orphans.Files[i] != want {
+			t.Errorf("orphaned files[%d] = %s, want %s", i, orphans.Files[i], want)
+		}
+	}
+	if len(orphans.Entries) != 1 || orphans.Entries[0].ID != "lost" {
+		t.Fatalf("orphaned entries = %+v, want only lost", orphans.Entries)
+	}
+
+	// Finding changes nothing
+	if _, err := os.Stat(wantFiles[1]); err != nil {
+		t.Errorf("FindOrphans removed %s: %v", wantFiles[1], err)
+	}
+	if dl, _ := GetDownload("lost"); dl == nil {
+		t.Error("FindOrphans removed the lost download")
+	}
+}
+
+func TestRemoveOrphans(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	lost := &types.DownloadState{
+		ID:         "lost",
+		URL:        "https://example.com/lost",
+		DestPath:   filepath.Join(tmpDir, "lost.bin"),
+		Filename:   "lost.bin",
+		TotalSize:  100,
+		Downloaded: 50,
+		Tasks:      []types.Task{{Offset: 50, Length: 50}},
+	}
+	if err := SaveState(lost.URL, lost.DestPath, lost); err != nil {
+		t.Fatal(err)
+	}
+	stray := filepath.Join(tmpDir, "stray.bin"+types.IncompleteSuffix)
+	reclaimed := filepath.Join(tmpDir, "reclaimed.bin"+types.IncompleteSuffix)
+	for _, path := range []string{stray, reclaimed} {
+		if err := os.WriteFile(path, []byte("partial"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	orphans, err := FindOrphans()
+	if err != nil {
+		t.Fatalf("FindOrphans failed: %v", err)
+	}
+	if len(orphans.Files) != 2 || len(orphans.Entries) != 1 {
+		t.Fatalf("found %+v, want 2 files and 1 entry", orphans)
+	}
+
+	// A download started on reclaimed.bin after the scan owns that file now
+	if err := AddToMasterList(types.DownloadEntry{
+		ID:       "new",
+		URL:      "https://example.com/reclaimed",
+		DestPath: filepath.Join(tmpDir, "reclaimed.bin"),
+		Status:   "downloading",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	files, entries, err := RemoveOrphans(orphans)
+	if err != nil {
+		t.Fatalf("RemoveOrphans failed: %v", err)
+	}
+	if files != 1 || entries != 1 {
+		t.Errorf("removed %d files and %d entries, want 1 and 1", files, entries)
+	}
+	if _, err := os.Stat(stray); !os.IsNotExist(err) {
+		t.Errorf("stray file should be removed, stat err: %v", err)
+	}
+	if _, err := os.Stat(reclaimed); err != nil {
+		t.Errorf("reclaimed file should be kept, stat err: %v", err)
+	}
+	if dl, _ := GetDownload("lost"); dl != nil {
+		t.Error("lost download should be removed")
+	}
+
+	var taskCount int
+	if err := getDBHelper().QueryRow("SELECT COUNT(*) FROM tasks WHERE download_id = ?", "lost").Scan(&taskCount); err != nil {
+		t.Fatalf("failed to count tasks: %v", err)
+	}
+	if taskCount != 0 {
+		t.Errorf("expected tasks to be removed, got %d", taskCount)
+	}
+}
@@ -0,0 +1,91 @@
+package state
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// GetOverrides returns the settings download id was added with beyond the
+// user's configuration, or nil when it has none
+func GetOverrides(id string) (*types.DownloadOverrides, error) {
+	db := getDBHelper()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	var overrides sql.NullString
+	err := db.QueryRow("SELECT overrides FROM downloads WHERE id = ?", id).Scan(&overrides)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query overrides: %w", err)
+	}
+	return decodeOverrides(overrides.String), nil
+}
+
+// encodeOverrides renders the overrides column; nothing overridden is stored
+// as an empty string so updates that don't carry overrides keep the old ones.
+// Headers may hold credentials, so the column is a secret.
+func encodeOverrides(o *types.DownloadOverrides) (string, error) {
+	if o.IsZero() {
+		return "", nil
+	}
+	data, err := json.Marshal(o)
+	if err != nil {
+		return "", err
+	}
+	return sealSecret(string(data))
+}
+
+// decodeOverrides parses the overrides column. A corrupt value, or one that
+// can't be decrypted, is dropped rather than failing the whole row.
+func decodeOverrides(s string) *types.DownloadOverrides {
+	s, err := openSecret(s)
+	if err != nil {
+		utils.Debug("Ignoring download overrides: %v", err)
+		return nil
+	}
+	if s == "" {
+		return nil
+	}
+	var o types.DownloadOverrides
+	if err := json.Unmarshal([]byte(s), &o); err != nil {
+		utils.Debug("Ignoring invalid download overrides: %v", err)
+		return nil
+	}
+	return &o
+}
+
+// SetSpeedLimit changes the speed limit download id keeps for its resumes,
+// in bytes/sec (0 = unlimited)
+func SetSpeedLimit(id string, rate int64) error {
+	return withTx(func(tx *sql.Tx) error {
+		var cur sql.NullString
+		err := tx.QueryRow("SELECT overrides FROM downloads WHERE id = ?", id).Scan(&cur)
+		if err == sql.ErrNoRows {
+			return types.ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to query overrides: %w", err)
+		}
+
+		o := decodeOverrides(cur.String)
+		if o == nil {
+			o = &types.DownloadOverrides{}
+		}
+		o.SpeedLimit = max(rate, 0)
+		encoded, err := encodeOverrides(o)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE downloads SET overrides = ? WHERE id = ?", encoded, id); err != nil {
+			return fmt.Errorf("failed to save overrides: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,109 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// PageQuery selects one keyset page of downloads: the rows that sort after
+// (AfterKey, AfterID) when After is set, optionally only those labeled Tag,
+// in one of Statuses and matching Search. A Limit of 0 or less returns every
+// remaining row.
+type PageQuery struct {
+	After    bool
+	AfterKey int64
+	AfterID  string
+	Tag      string
+	Statuses []string // Empty for any status
+	Include  []string // IDs kept whatever their stored status, for active downloads whose row lags
+	Search   string   // Case-insensitive substring of the filename or URL
+	Limit    int
+}
+
+// ListDownloadsPage returns downloads in LoadMasterList order (created_at,
+// then id), starting after the query's position. Archived and trashed
+// downloads were removed from the list and are left out.
+func ListDownloadsPage(q PageQuery) ([]types.DownloadEntry, error) {
+	where := []string{"COALESCE(status, '') NOT IN ('archived', 'trashed')"}
+	var args []any
+	if q.After {
+		where = append(where, "(COALESCE(created_at, 0), id) > (?, ?)")
+		args = append(args, q.AfterKey, q.AfterID)
+	}
+	return queryPage(where, args, "COALESCE(created_at, 0), id", q)
+}
+
+// ListCompletedPage returns completed downloads in SortHistory order
+// (completed_at descending, then id), starting after the query's position
+func ListCompletedPage(q PageQuery) ([]types.DownloadEntry, error) {
+	where := []string{"status = 'completed'"}
+	var args []any
+	if q.After {
+		where = append(where, "(COALESCE(completed_at, 0) < ? OR (COALESCE(completed_at, 0) = ? AND id > ?))")
+		args = append(args, q.AfterKey, q.AfterKey, q.AfterID)
+	}
+	return queryPage(where, args, "COALESCE(completed_at, 0) DESC, id", q)
+}
+
+func queryPage(where []string, args []any, orderBy string, q PageQuery) ([]types.DownloadEntry, error) {
+	db := getDBHelper()
+	if db == nil {
+		return nil, nil
+	}
+
+	if tag := strings.ToLower(strings.TrimSpace(q.Tag)); tag != "" {
+		// tags are stored comma-joined, so wrap both sides to match whole tags
+		where = append(where, "instr(',' || COALESCE(tags, '') || ',', ?) > 0")
+		args = append(args, ","+tag+",")
+	}
+	if len(q.Statuses) > 0 {
+		cond := "COALESCE(status, '') IN (?" + strings.Repeat(", ?", len(q.Statuses)-1) + ")"
+		for _, st := range q.Statuses {
+			args = append(args, st)
+		}
+		if len(q.Include) > 0 {
+			cond = "(" + cond + " OR id IN (?" + strings.Repeat(", ?", len(q.Include)-1) + "))"
+			for _, id := range q.Include {
+				args = append(args, id)
+			}
+		}
+		where = append(where, cond)
+	}
+	if search := strings.ToLower(strings.TrimSpace(q.Search)); search != "" {
+		where = append(where, "instr(lower(COALESCE(filename, '') || char(10) || COALESCE(open_value(url), '')), ?) > 0")
+		args = append(args, search)
+	}
+
+	query := "SELECT " + masterListColumns + " FROM downloads"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY " + orderBy
+	if q.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, q.Limit)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query downloads page: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			utils.Debug("Error closing rows: %v", err)
+		}
+	}()
+
+	var out []types.DownloadEntry
+	for rows.Next() {
+		e, err := scanMasterListRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,121 @@
+package state
+
+import (
+	"os"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func seedPageRows(t *testing.T) {
+	t.Helper()
+	rows := []types.DownloadEntry{
+		{ID: "a", URL: "https://x/a", DestPath: "/tmp/a", Status: "completed", CreatedAt: 100, CompletedAt: 300, Tags: []string{"iso"}},
+		{ID: "b", URL: "https://x/b", DestPath: "/tmp/b", Status: "paused", CreatedAt: 100},
+		{ID: "c", URL: "https://x/c", DestPath: "/tmp/c", Status: "completed", CreatedAt: 200, CompletedAt: 300},
+		{ID: "d", URL: "https://x/d", DestPath: "/tmp/d", Status: "completed", CreatedAt: 300, CompletedAt: 400, Tags: []string{"iso", "linux"}},
+	}
+	for _, r := range rows {
+		if err := AddToMasterList(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func pageIDs(entries []types.DownloadEntry) []string {
+	ids := make([]string, len(entries))
+	for i, e := range entries {
+		ids[i] = e.ID
+	}
+	return ids
+}
+
+func assertIDs(t *testing.T, got []types.DownloadEntry, want ...string) {
+	t.Helper()
+	ids := pageIDs(got)
+	if len(ids) != len(want) {
+		t.Fatalf("ids = %v, want %v", ids, want)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("ids = %v, want %v", ids, want)
+		}
+	}
+}
+
+func TestListDownloadsPage_KeysetAndLimit(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+	seedPageRows(t)
+
+	first, err := ListDownloadsPage(PageQuery{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertIDs(t, first, "a", "b")
+
+	last := first[len(first)-1]
+	rest, err := ListDownloadsPage(PageQuery{After: true, AfterKey: last.CreatedAt, AfterID: last.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertIDs(t, rest, "c", "d")
+
+	tagged, err := ListDownloadsPage(PageQuery{Tag: "ISO"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertIDs(t, tagged, "a", "d")
+}
+
+func TestListCompletedPage_KeysetAndLimit(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+	seedPageRows(t)
+
+	first, err := ListCompletedPage(PageQuery{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertIDs(t, first, "d", "a")
+
+	rest, err := ListCompletedPage(PageQuery{After: true, AfterKey: 300, AfterID: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertIDs(t, rest, "c")
+
+	tagged, err := ListCompletedPage(PageQuery{Tag: "linux"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertIDs(t, tagged, "d")
+}
+
+func TestListDownloadsPage_StatusAndSearch(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+	seedPageRows(t)
+
+	paused, err := ListDownloadsPage(PageQuery{Statuses: []string{"paused"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertIDs(t, paused, "b")
+
+	// Included ids pass whatever their stored status
+	withActive, err := ListDownloadsPage(PageQuery{Statuses: []string{"paused"}, Include: []string{"c"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertIDs(t, withActive, "b", "c")
+
+	found, err := ListDownloadsPage(PageQuery{Search: "X/D"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertIDs(t, found, "d")
+}
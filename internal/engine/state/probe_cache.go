@@ -0,0 +1,88 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// SaveProbeCache stores probe metadata on an existing download row. It reports
+// whether the row existed; nothing is written for unknown ids.
+func SaveProbeCache(id string, probe types.CachedProbe) (bool, error) {
+	if probe.ProbedAt == 0 {
+		probe.ProbedAt = time.Now().Unix()
+	}
+
+	var saved bool
+	err := withTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec(`
+			UPDATE downloads
+			SET probe_size = ?, probe_ranges = ?, probe_etag = ?, probe_final_url = ?,
+				probe_content_type = ?, probe_server = ?, probe_last_modified = ?, probed_at = ?
+			WHERE id = ?
+		`, probe.FileSize, probe.SupportsRange, probe.ETag, sealValue(probe.FinalURL),
+			probe.ContentType, probe.Server, probe.LastModified, probe.ProbedAt, id)
+		if err != nil {
+			return fmt.Errorf("failed to save probe cache: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		saved = n > 0
+		return nil
+	})
+	return saved, err
+}
+
+// GetProbeCache returns the probe metadata stored for a download, or nil if
+// it was never probed or predates the cache
+func GetProbeCache(id string) (*types.CachedProbe, error) {
+	db := getDBHelper()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	var p probeScan
+	err := db.QueryRow("SELECT "+probeCacheColumns+" FROM downloads WHERE id = ?", id).Scan(p.dest()...)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query probe cache: %w", err)
+	}
+	return p.probe(), nil
+}
+
+// probeCacheColumns are the downloads columns a probeScan reads
+const probeCacheColumns = `probe_size, probe_ranges, probe_etag, probe_final_url, probe_content_type, probe_server, probe_last_modified, probed_at`
+
+// probeScan receives the probeCacheColumns of a row
+type probeScan struct {
+	size, ranges, probedAt                            sql.NullInt64
+	etag, finalURL, contentType, server, lastModified sql.NullString
+}
+
+// dest returns the scan destinations in probeCacheColumns order
+func (p *probeScan) dest() []any {
+	return []any{&p.size, &p.ranges, &p.etag, &p.finalURL, &p.contentType, &p.server, &p.lastModified, &p.probedAt}
+}
+
+// probe returns the scanned cache, or nil if the row was never probed
+func (p *probeScan) probe() *types.CachedProbe {
+	if !p.probedAt.Valid {
+		return nil
+	}
+	return &types.CachedProbe{
+		FileSize:      p.size.Int64,
+		SupportsRange: p.ranges.Int64 != 0,
+		ETag:          p.etag.String,
+		FinalURL:      openValue(p.finalURL.String),
+		ContentType:   p.contentType.String,
+		Server:        p.server.String,
+		LastModified:  p.lastModified.String,
+		ProbedAt:      p.probedAt.Int64,
+	}
+}
@@ -0,0 +1,91 @@
+package state
+
+import (
+	"os"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestProbeCache_SaveAndGet(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	probe := types.CachedProbe{
+		FileSize:      4096,
+		SupportsRange: true,
+		ETag:          `"abc123"`,
+		FinalURL:      "https://cdn.example.com/file.bin",
+		ContentType:   "application/octet-stream",
+		Server:        "nginx",
+		LastModified:  "Wed, 21 Oct 2015 07:28:00 GMT",
+	}
+
+	// Without a downloads row there is nothing to attach the probe to
+	saved, err := SaveProbeCache("probe-id", probe)
+	if err != nil {
+		t.Fatalf("SaveProbeCache failed: %v", err)
+	}
+	if saved {
+		t.Fatal("expected no row to be updated for an unknown id")
+	}
+
+	if err := AddToMasterList(types.DownloadEntry{
+		ID:       "probe-id",
+		URL:      "https://example.com/file.bin",
+		DestPath: "/tmp/file.bin",
+		Filename: "file.bin",
+		Status:   "queued",
+	}); err != nil {
+		t.Fatalf("AddToMasterList failed: %v", err)
+	}
+
+	got, err := GetProbeCache("probe-id")
+	if err != nil {
+		t.Fatalf("GetProbeCache failed: %v", err)
+	}
+	if got != nil {
+		t.Fatalf("expected nil before the probe is cached, got %+v", got)
+	}
+
+	saved, err = SaveProbeCache("probe-id", probe)
+	if err != nil || !saved {
+		t.Fatalf("SaveProbeCache = %v, %v; want saved", saved, err)
+	}
+
+	// Later lifecycle upserts must not wipe the cached probe
+	if err := AddToMasterList(types.DownloadEntry{
+		ID:       "probe-id",
+		URL:      "https://example.com/file.bin",
+		DestPath: "/tmp/file.bin",
+		Filename: "file.bin",
+		Status:   "paused",
+	}); err != nil {
+		t.Fatalf("AddToMasterList failed: %v", err)
+	}
+
+	got, err = GetProbeCache("probe-id")
+	if err != nil {
+		t.Fatalf("GetProbeCache failed: %v", err)
+	}
+	if got == nil || got.FileSize != 4096 || !got.SupportsRange || got.ETag != `"abc123"` || got.FinalURL != probe.FinalURL {
+		t.Fatalf("unexpected probe cache: %+v", got)
+	}
+	if got.ContentType != probe.ContentType || got.Server != probe.Server || got.LastModified != probe.LastModified {
+		t.Fatalf("response headers not kept: %+v", got)
+	}
+	if got.ProbedAt == 0 {
+		t.Error("expected probed_at to be set")
+	}
+
+	// The download row carries it too, for status and the details pane
+	entry, err := GetDownload("probe-id")
+	if err != nil || entry == nil || entry.Response == nil || *entry.Response != *got {
+		t.Fatalf("GetDownload response = %+v, %v; want %+v", entry, err, got)
+	}
+	list, err := LoadMasterList()
+	if err != nil || len(list.Downloads) != 1 || list.Downloads[0].Response == nil || list.Downloads[0].Response.Server != "nginx" {
+		t.Fatalf("LoadMasterList did not carry the response: %+v, %v", list, err)
+	}
+}
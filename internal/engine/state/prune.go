@@ -0,0 +1,94 @@
+package state
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// Reasons a download is pruned
+const (
+	PruneCompleted = "completed" // Finished; the file itself stays
+	PruneFailed    = "failed"    // In error; its working file goes too
+	PruneMissing   = "missing"   // Finished, but the file is gone
+)
+
+// PruneFilter picks which downloads to remove from the list
+type PruneFilter struct {
+	Completed bool
+	Failed    bool
+	Missing   bool // Completed downloads whose file no longer exists
+}
+
+// PruneCandidate is a download a PruneFilter picked, and why
+type PruneCandidate struct {
+	types.DownloadEntry
+	Reason string `json:"reason"`
+}
+
+// reason returns why f picks e, or "" if it doesn't. A finished download
+// whose file is gone counts as missing before completed.
+func (f PruneFilter) reason(e types.DownloadEntry) string {
+	switch e.Status {
+	case "completed":
+		if f.Missing && e.DestPath != "" {
+			if _, err := os.Stat(e.DestPath); os.IsNotExist(err) {
+				return PruneMissing
+			}
+		}
+		if f.Completed {
+			return PruneCompleted
+		}
+	case "error":
+		if f.Failed {
+			return PruneFailed
+		}
+	}
+	return ""
+}
+
+// FindPrunable lists the downloads f picks. Nothing is changed; pass the
+// result to RemovePrunable to remove them.
+func FindPrunable(f PruneFilter) ([]PruneCandidate, error) {
+	list, err := LoadMasterList()
+	if err != nil {
+		return nil, err
+	}
+	var found []PruneCandidate
+	for _, e := range list.Downloads {
+		if reason := f.reason(e); reason != "" {
+			found = append(found, PruneCandidate{DownloadEntry: e, Reason: reason})
+		}
+	}
+	return found, nil
+}
+
+// RemovePrunable removes the candidates from the list, skipping any f no
+// longer picks, e.g. a failed download retried since. Failed downloads lose
+// their working file too; other files are left alone. It returns how many
+// downloads were removed.
+func RemovePrunable(f PruneFilter, candidates []PruneCandidate) (int, error) {
+	removed := 0
+	for _, c := range candidates {
+		now, err := GetDownload(c.ID)
+		if err != nil {
+			return removed, err
+		}
+		if now == nil || f.reason(*now) == "" {
+			continue
+		}
+		if err := removeDownloadAndTasks(c.ID); err != nil {
+			return removed, fmt.Errorf("failed to remove download %s: %w", c.ID, err)
+		}
+		if now.Status == "error" && now.DestPath != "" {
+			if err := retryRemove(now.DestPath + types.IncompleteSuffix); err != nil && !os.IsNotExist(err) {
+				utils.Debug("Prune: failed to remove working file of %s: %v", c.ID, err)
+			}
+		}
+		utils.Debug("Prune: removed %s download %s", c.Reason, c.ID)
+		removed++
+	}
+	return removed, nil
+}
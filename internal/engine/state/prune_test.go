@@ -0,0 +1,76 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestFindAndRemovePrunable(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	kept := filepath.Join(tmpDir, "kept.iso")
+	failed := filepath.Join(tmpDir, "failed.iso")
+	for _, path := range []string{kept, failed + types.IncompleteSuffix} {
+		if err := os.WriteFile(path, []byte("data"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now().Unix()
+	for _, e := range []types.DownloadEntry{
+		{ID: "kept", URL: "https://example.com/kept", DestPath: kept, Status: "completed", CompletedAt: now},
+		{ID: "gone", URL: "https://example.com/gone", DestPath: filepath.Join(tmpDir, "gone.iso"), Status: "completed", CompletedAt: now},
+		{ID: "failed", URL: "https://example.com/failed", DestPath: failed, Status: "error", Downloaded: 4},
+		{ID: "paused", URL: "https://example.com/paused", DestPath: filepath.Join(tmpDir, "paused.iso"), Status: "paused"},
+	} {
+		if err := AddToMasterList(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	filter := PruneFilter{Failed: true, Missing: true}
+	found, err := FindPrunable(filter)
+	if err != nil {
+		t.Fatalf("FindPrunable failed: %v", err)
+	}
+	reasons := make(map[string]string)
+	for _, c := range found {
+		reasons[c.ID] = c.Reason
+	}
+	if len(reasons) != 2 || reasons["gone"] != PruneMissing || reasons["failed"] != PruneFailed {
+		t.Fatalf("found = %v", reasons)
+	}
+
+	removed, err := RemovePrunable(filter, found)
+	if err != nil || removed != 2 {
+		t.Fatalf("RemovePrunable = %d, %v", removed, err)
+	}
+	if _, err := os.Stat(failed + types.IncompleteSuffix); !os.IsNotExist(err) {
+		t.Errorf("working file of the failed download should be gone: %v", err)
+	}
+	for id, want := range map[string]bool{"kept": true, "gone": false, "failed": false, "paused": true} {
+		e, err := GetDownload(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (e != nil) != want {
+			t.Errorf("%s present = %v, want %v", id, e != nil, want)
+		}
+	}
+
+	found, _ = FindPrunable(PruneFilter{Completed: true})
+	if len(found) != 1 || found[0].ID != "kept" || found[0].Reason != PruneCompleted {
+		t.Fatalf("completed = %+v", found)
+	}
+	if removed, err := RemovePrunable(PruneFilter{Completed: true}, found); err != nil || removed != 1 {
+		t.Fatalf("RemovePrunable = %d, %v", removed, err)
+	}
+	if _, err := os.Stat(kept); err != nil {
+		t.Errorf("pruning a completed download removed its file: %v", err)
+	}
+}
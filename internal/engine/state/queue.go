@@ -0,0 +1,171 @@
+package state
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// nextQueuePosition is the SQL for the position of a download added now: the
+// end of the queue
+const nextQueuePosition = `(SELECT COALESCE(MAX(queue_position), 0) + 1 FROM downloads)`
+
+// waitingStatuses are the downloads that have a place in the queue: those
+// not yet started, and those a restart picks up again
+var waitingStatuses = []string{"queued", "paused", "downloading"}
+
+// ErrNotWaiting is returned when moving a download that is not in the queue
+var ErrNotWaiting = errors.New("download is not waiting in the queue")
+
+// Places SetPriority can put a download at among those of its priority
+const (
+	PlaceTop    = "top"
+	PlaceBottom = "bottom"
+)
+
+// MoveInQueue moves download id to position, counted from 1, among the
+// waiting downloads and returns their ids in the new order. Positions past
+// either end, or past the downloads of the same priority, are clamped. Only
+// the waiting downloads are renumbered, into the positions they already
+// held, so finished ones keep theirs.
+func MoveInQueue(id string, position int) ([]string, error) {
+	return moveInQueue(id, nil, func(int, bool) int { return position })
+}
+
+// MoveInQueueBy moves download id offset places among the waiting downloads,
+// toward the front when negative, like MoveInQueue
+func MoveInQueueBy(id string, offset int) ([]string, error) {
+	return moveInQueue(id, nil, func(current int, _ bool) int { return current + offset })
+}
+
+// SetPriority gives waiting download id priority and moves it among the
+// downloads of that priority: to the front for PlaceTop, to the back for
+// PlaceBottom, and otherwise to the back only when its priority changed. It
+// returns the waiting downloads in the new order, like MoveInQueue.
+func SetPriority(id string, priority int, place string) ([]string, error) {
+	return moveInQueue(id, &priority, func(current int, changed bool) int {
+		switch {
+		case place == PlaceTop:
+			return 1
+		case place == PlaceBottom, changed:
+			return math.MaxInt32
+		}
+		return current
+	})
+}
+
+// moveInQueue moves download id to the position target picks from its
+// current one, both counted from 1, after giving it priority unless nil.
+// The waiting downloads are kept ordered highest priority first, so the
+// queue lists them in the order they start, and target is clamped to the
+// downloads of the same priority.
+func moveInQueue(id string, priority *int, target func(current int, changed bool) int) ([]string, error) {
+	var order []string
+	err := withTx(func(tx *sql.Tx) error {
+		var status string
+		var overrides sql.NullString
+		err := tx.QueryRow("SELECT status, overrides FROM downloads WHERE id = ?", id).Scan(&status, &overrides)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return types.ErrNotFound
+			}
+			return fmt.Errorf("failed to query download: %w", err)
+		}
+		if !slices.Contains(waitingStatuses, status) {
+			return ErrNotWaiting
+		}
+
+		o := decodeOverrides(overrides.String)
+		own := o.GetPriority()
+		changed := priority != nil && *priority != own
+		if changed {
+			if o == nil {
+				o = &types.DownloadOverrides{}
+			}
+			o.Priority, own = *priority, *priority
+			encoded, err := encodeOverrides(o)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec("UPDATE downloads SET overrides = ? WHERE id = ?", encoded, id); err != nil {
+				return fmt.Errorf("failed to save priority: %w", err)
+			}
+		}
+
+		rows, err := tx.Query(`
+			SELECT id, COALESCE(queue_position, 0), overrides FROM downloads
+			WHERE status IN ('` + strings.Join(waitingStatuses, "', '") + `')
+			ORDER BY COALESCE(queue_position, 0), COALESCE(created_at, 0), id
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to query queue: %w", err)
+		}
+		var slots []int64
+		priorities := make(map[string]int)
+		for rows.Next() {
+			var otherID string
+			var slot int64
+			var otherOverrides sql.NullString
+			if err := rows.Scan(&otherID, &slot, &otherOverrides); err != nil {
+				_ = rows.Close()
+				return err
+			}
+			slots = append(slots, slot)
+			order = append(order, otherID)
+			priorities[otherID] = decodeOverrides(otherOverrides.String).GetPriority()
+		}
+		if err := rows.Err(); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		_ = rows.Close()
+		priorities[id] = own
+
+		sort.SliceStable(order, func(i, j int) bool { return priorities[order[i]] > priorities[order[j]] })
+		current := slices.Index(order, id) + 1
+		order = slices.Delete(order, current-1, current)
+		first, last := 1, len(order)+1
+		for _, otherID := range order {
+			if priorities[otherID] > own {
+				first++
+			} else if priorities[otherID] < own {
+				last--
+			}
+		}
+
+		index := min(max(target(current, changed), first), last) - 1
+		order = slices.Insert(order, index, id)
+
+		// Rows from before positions existed share slot 0; number them past
+		// the end instead so every waiting download gets a slot of its own
+		sort.Slice(slots, func(i, j int) bool { return slots[i] < slots[j] })
+		var next int64
+		if err := tx.QueryRow("SELECT COALESCE(MAX(queue_position), 0) FROM downloads").Scan(&next); err != nil {
+			return err
+		}
+		for i := range slots {
+			if slots[i] <= 0 || (i > 0 && slots[i] == slots[i-1]) {
+				next++
+				slots[i] = next
+			}
+		}
+		sort.Slice(slots, func(i, j int) bool { return slots[i] < slots[j] })
+
+		for i, otherID := range order {
+			if _, err := tx.Exec("UPDATE downloads SET queue_position = ? WHERE id = ?", slots[i], otherID); err != nil {
+				return fmt.Errorf("failed to move download: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
@@ -0,0 +1,158 @@
+package state
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestMoveInQueue(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	for _, e := range []types.DownloadEntry{
+		{ID: "a", URL: "https://example.com/a", DestPath: filepath.Join(tmpDir, "a.bin"), Status: "queued"},
+		{ID: "b", URL: "https://example.com/b", DestPath: filepath.Join(tmpDir, "b.bin"), Status: "paused"},
+		{ID: "done", URL: "https://example.com/done", DestPath: filepath.Join(tmpDir, "done.bin"), Status: "completed", CompletedAt: time.Now().Unix()},
+		{ID: "c", URL: "https://example.com/c", DestPath: filepath.Join(tmpDir, "c.bin"), Status: "queued"},
+	} {
+		if err := AddToMasterList(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pausedIDs := func() []string {
+		t.Helper()
+		paused, err := LoadPausedDownloads()
+		if err != nil {
+			t.Fatalf("LoadPausedDownloads failed: %v", err)
+		}
+		var ids []string
+		for _, e := range paused {
+			ids = append(ids, e.ID)
+		}
+		return ids
+	}
+	if got := pausedIDs(); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Fatalf("queue = %v, want the order added in", got)
+	}
+
+	order, err := MoveInQueue("c", 1)
+	if err != nil {
+		t.Fatalf("MoveInQueue failed: %v", err)
+	}
+	want := []string{"c", "a", "b"}
+	if !slices.Equal(order, want) {
+		t.Errorf("MoveInQueue order = %v, want %v", order, want)
+	}
+	if got := pausedIDs(); !slices.Equal(got, want) {
+		t.Errorf("queue after move = %v, want %v", got, want)
+	}
+
+	// Saving a download again keeps its place
+	if err := AddToMasterList(types.DownloadEntry{ID: "c", URL: "https://example.com/c", DestPath: filepath.Join(tmpDir, "c.bin"), Status: "paused"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := pausedIDs(); !slices.Equal(got, want) {
+		t.Errorf("queue after save = %v, want %v", got, want)
+	}
+
+	// Past the end is clamped to the end
+	if order, err = MoveInQueue("c", 99); err != nil {
+		t.Fatalf("MoveInQueue failed: %v", err)
+	}
+	if want := []string{"a", "b", "c"}; !slices.Equal(order, want) {
+		t.Errorf("MoveInQueue order = %v, want %v", order, want)
+	}
+
+	// Relative moves count from the current place and are clamped too
+	if order, err = MoveInQueueBy("c", -1); err != nil {
+		t.Fatalf("MoveInQueueBy failed: %v", err)
+	}
+	if want := []string{"a", "c", "b"}; !slices.Equal(order, want) {
+		t.Errorf("MoveInQueueBy(-1) order = %v, want %v", order, want)
+	}
+	if order, err = MoveInQueueBy("a", 5); err != nil {
+		t.Fatalf("MoveInQueueBy failed: %v", err)
+	}
+	if want := []string{"c", "b", "a"}; !slices.Equal(order, want) {
+		t.Errorf("MoveInQueueBy(5) order = %v, want %v", order, want)
+	}
+	if got := pausedIDs(); !slices.Equal(got, order) {
+		t.Errorf("queue after relative moves = %v, want %v", got, order)
+	}
+
+	if _, err := MoveInQueue("done", 1); !errors.Is(err, ErrNotWaiting) {
+		t.Errorf("MoveInQueue(done) = %v, want ErrNotWaiting", err)
+	}
+	if _, err := MoveInQueue("missing", 1); !errors.Is(err, types.ErrNotFound) {
+		t.Errorf("MoveInQueue(missing) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSetPriority(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	for _, id := range []string{"a", "b", "c", "d"} {
+		e := types.DownloadEntry{ID: id, URL: "https://example.com/" + id, DestPath: filepath.Join(tmpDir, id+".bin"), Status: "queued"}
+		if err := AddToMasterList(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	steps := []struct {
+		id       string
+		priority int
+		place    string
+		want     []string
+	}{
+		{"c", types.PriorityHigh, "", []string{"c", "a", "b", "d"}},
+		{"d", types.PriorityHigh, "", []string{"c", "d", "a", "b"}},         // Behind the other high one
+		{"d", types.PriorityHigh, PlaceTop, []string{"d", "c", "a", "b"}},   // In front of it
+		{"a", types.PriorityLow, "", []string{"d", "c", "b", "a"}},          // Behind every normal one
+		{"b", types.PriorityNormal, PlaceTop, []string{"d", "c", "b", "a"}}, // Can't pass the high ones
+		{"c", types.PriorityHigh, "", []string{"d", "c", "b", "a"}},         // Unchanged keeps its place
+	}
+	for _, s := range steps {
+		order, err := SetPriority(s.id, s.priority, s.place)
+		if err != nil {
+			t.Fatalf("SetPriority(%s) failed: %v", s.id, err)
+		}
+		if !slices.Equal(order, s.want) {
+			t.Errorf("SetPriority(%s, %d, %q) order = %v, want %v", s.id, s.priority, s.place, order, s.want)
+		}
+	}
+
+	// Moves stay among the downloads of the same priority
+	order, err := MoveInQueue("a", 1)
+	if err != nil {
+		t.Fatalf("MoveInQueue failed: %v", err)
+	}
+	if want := []string{"d", "c", "b", "a"}; !slices.Equal(order, want) {
+		t.Errorf("MoveInQueue(low, 1) order = %v, want %v", order, want)
+	}
+
+	// Kept with the download, and startup resumes in priority order
+	if o, err := GetOverrides("a"); err != nil || o.GetPriority() != types.PriorityLow {
+		t.Errorf("GetOverrides(a) = %+v, %v; want low priority", o, err)
+	}
+	paused, err := LoadPausedDownloads()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, e := range paused {
+		ids = append(ids, e.ID)
+	}
+	if want := []string{"d", "c", "b", "a"}; !slices.Equal(ids, want) {
+		t.Errorf("LoadPausedDownloads = %v, want %v", ids, want)
+	}
+}
@@ -0,0 +1,46 @@
+package state
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// ErrNotFailed is returned when retrying a download that has not failed
+var ErrNotFailed = errors.New("download has not failed")
+
+// ResetFailed sets failed download id back to the start, to be downloaded
+// again from scratch: it is queued at the end of the queue with no progress,
+// chunk state or size, which the cached probe fills in again when it starts.
+// Its history, note, overrides and error history are kept.
+func ResetFailed(id string) error {
+	return withTx(func(tx *sql.Tx) error {
+		var status sql.NullString
+		err := tx.QueryRow("SELECT status FROM downloads WHERE id = ?", id).Scan(&status)
+		if err == sql.ErrNoRows {
+			return types.ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to query download: %w", err)
+		}
+		if status.String != "error" {
+			return ErrNotFailed
+		}
+
+		if _, err := tx.Exec(`
+			UPDATE downloads SET
+				status = 'queued', total_size = 0, downloaded = 0, time_taken = 0, avg_speed = 0,
+				paused_at = NULL, pause_reason = NULL, chunk_bitmap = NULL, actual_chunk_size = 0, file_hash = NULL,
+				queue_position = `+nextQueuePosition+`
+			WHERE id = ?
+		`, id); err != nil {
+			return fmt.Errorf("failed to reset download: %w", err)
+		}
+		if _, err := tx.Exec("DELETE FROM tasks WHERE download_id = ?", id); err != nil {
+			return fmt.Errorf("failed to delete tasks: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,327 @@
+package state
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/surge-downloader/surge/internal/utils"
+	sqlite "modernc.org/sqlite"
+)
+
+// Columns that may hold credentials: the headers a download was added with,
+// the headers replayed per host, and URLs, whose query strings often carry
+// tokens, with the error messages that quote them. They are encrypted with
+// the state key when ConfigureSecrets asks for it; everything else is stored
+// as is. URL columns are sealed with sealValue so they can still be matched.
+var secretColumns = []struct {
+	table, key, column string
+	url                bool
+}{
+	{"downloads", "id", "overrides", false},
+	{"host_headers", "host", "headers", false},
+	{"downloads", "id", "url", true},
+	{"downloads", "id", "mirrors", true},
+	{"downloads", "id", "probe_final_url", true},
+	{"download_errors", "id", "mirror", true},
+	{"download_errors", "id", "message", true},
+	{"url_history", "url", "error", true},
+	{"url_history", "url", "url", true}, // Last, as it is also the row's key
+}
+
+// sealedPrefix marks a value encrypted with AES-GCM under the state key
+const sealedPrefix = "sealed:v1:"
+
+// SecretKeySize is the length of a state key in bytes
+const SecretKeySize = 32
+
+// ErrSecretsLocked is returned when reading an encrypted value without a key
+var ErrSecretsLocked = errors.New("value is encrypted and no state key is configured")
+
+var (
+	secretsMu   sync.RWMutex
+	secretsAEAD cipher.AEAD
+	secretsMAC  []byte // Derived from the state key, for sealValue nonces and URL hashes
+	secretsSeal bool
+)
+
+// ConfigureSecrets sets the key encrypted values are read with. With seal,
+// secret values written from now on are encrypted too; without it they are
+// written in plain text, so a key alone only lets old values be read. A nil
+// key forgets both.
+func ConfigureSecrets(key []byte, seal bool) error {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+
+	if key == nil {
+		secretsAEAD, secretsMAC, secretsSeal = nil, nil, false
+		return nil
+	}
+	if len(key) != SecretKeySize {
+		return fmt.Errorf("state key must be %d bytes, got %d", SecretKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("surge state mac"))
+	secretsAEAD, secretsMAC, secretsSeal = aead, mac.Sum(nil), seal
+	return nil
+}
+
+// NewSecretKey returns a random state key
+func NewSecretKey() ([]byte, error) {
+	key := make([]byte, SecretKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// sealSecret encrypts a secret column value when sealing is on
+func sealSecret(plain string) (string, error) {
+	secretsMu.RLock()
+	aead, seal := secretsAEAD, secretsSeal
+	secretsMu.RUnlock()
+
+	if plain == "" || !seal || strings.HasPrefix(plain, sealedPrefix) {
+		return plain, nil
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to encrypt: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plain), nil)
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// openSecret decrypts a secret column value; plain values pass through
+func openSecret(stored string) (string, error) {
+	if !strings.HasPrefix(stored, sealedPrefix) {
+		return stored, nil
+	}
+	secretsMu.RLock()
+	aead := secretsAEAD
+	secretsMu.RUnlock()
+	if aead == nil {
+		return "", ErrSecretsLocked
+	}
+
+	data, err := base64.StdEncoding.DecodeString(stored[len(sealedPrefix):])
+	if err != nil || len(data) < aead.NonceSize() {
+		return "", fmt.Errorf("corrupt encrypted value")
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt, wrong state key?: %w", err)
+	}
+	return string(plain), nil
+}
+
+// sealValue encrypts a URL column value when sealing is on. Unlike
+// sealSecret the nonce is derived from the value, so equal values seal to
+// the same text and sealed columns can still be matched with = and used as
+// keys; that they are equal is all it gives away.
+func sealValue(plain string) string {
+	secretsMu.RLock()
+	aead, mac, seal := secretsAEAD, secretsMAC, secretsSeal
+	secretsMu.RUnlock()
+
+	if plain == "" || !seal || strings.HasPrefix(plain, sealedPrefix) {
+		return plain
+	}
+	nonce := keyedHash(mac, "nonce", plain)[:aead.NonceSize()]
+	sealed := aead.Seal(append([]byte(nil), nonce...), nonce, []byte(plain), nil)
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed)
+}
+
+// openValue decrypts a URL column value. One that can't be decrypted is
+// returned as stored, so its download still loads.
+func openValue(stored string) string {
+	plain, err := openSecret(stored)
+	if err != nil {
+		utils.Debug("State: leaving a value encrypted: %v", err)
+		return stored
+	}
+	return plain
+}
+
+// open_value(x) is openValue for queries, to search sealed URL columns
+func init() {
+	sqlite.MustRegisterScalarFunction("open_value", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		stored, ok := args[0].(string)
+		if !ok {
+			return args[0], nil
+		}
+		if plain, err := openSecret(stored); err == nil {
+			return plain, nil
+		}
+		return stored, nil
+	})
+}
+
+// urlMAC returns the key URL hashes are keyed with, and whether new ones
+// should be: while sealing, hashes of URLs can't be checked against guesses
+// without the state key. The key is set whenever one is configured, so
+// hashes written while sealing still match after it is turned off.
+func urlMAC() (mac []byte, keyed bool) {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+	return secretsMAC, secretsSeal
+}
+
+// keyedHash is the HMAC-SHA256 of value under mac, separated by purpose
+func keyedHash(mac []byte, purpose, value string) []byte {
+	h := hmac.New(sha256.New, mac)
+	h.Write([]byte(purpose))
+	h.Write([]byte{0})
+	h.Write([]byte(value))
+	return h.Sum(nil)
+}
+
+// HasSealedSecrets reports whether any value in the database is encrypted
+func HasSealedSecrets() (bool, error) {
+	db := getDBHelper()
+	if db == nil {
+		return false, fmt.Errorf("database not initialized")
+	}
+	for _, c := range secretColumns {
+		var found int
+		err := db.QueryRow(fmt.Sprintf("SELECT 1 FROM %s WHERE %s LIKE ? LIMIT 1", c.table, c.column), sealedPrefix+"%").Scan(&found)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to look for encrypted values: %w", err)
+		}
+		return true, nil
+	}
+
+	// Keyed archive hashes need the key to be matched, though not to be read
+	var found int
+	err := db.QueryRow("SELECT 1 FROM download_archive WHERE url_hash LIKE ? LIMIT 1", keyedArchivePrefix+"%").Scan(&found)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to look for keyed archive entries: %w", err)
+	}
+	return true, nil
+}
+
+// ResealSecrets rewrites every secret value the way ConfigureSecrets asked:
+// plain values are encrypted when sealing, and encrypted ones decrypted when
+// not. It returns how many values changed. Values that can't be decrypted
+// are left alone.
+func ResealSecrets() (int, error) {
+	secretsMu.RLock()
+	aead, seal := secretsAEAD, secretsSeal
+	secretsMu.RUnlock()
+	if aead == nil {
+		return 0, nil
+	}
+
+	changed := 0
+	err := withTx(func(tx *sql.Tx) error {
+		for _, c := range secretColumns {
+			rows, err := tx.Query(fmt.Sprintf("SELECT %s, %s FROM %s WHERE COALESCE(%s, '') != ''", c.key, c.column, c.table, c.column))
+			if err != nil {
+				return fmt.Errorf("failed to read %s.%s: %w", c.table, c.column, err)
+			}
+			updates := make(map[string]string)
+			for rows.Next() {
+				var key, value string
+				if err := rows.Scan(&key, &value); err != nil {
+					_ = rows.Close()
+					return err
+				}
+				if strings.HasPrefix(value, sealedPrefix) == seal {
+					continue
+				}
+				plain, err := openSecret(value)
+				if err != nil {
+					continue
+				}
+				next := sealValue(plain)
+				if !c.url {
+					if next, err = sealSecret(plain); err != nil {
+						_ = rows.Close()
+						return err
+					}
+				}
+				updates[key] = next
+			}
+			if err := rows.Err(); err != nil {
+				_ = rows.Close()
+				return err
+			}
+			if err := rows.Close(); err != nil {
+				return err
+			}
+
+			for key, value := range updates {
+				if _, err := tx.Exec(fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ?", c.table, c.column, c.key), value, key); err != nil {
+					return fmt.Errorf("failed to rewrite %s.%s: %w", c.table, c.column, err)
+				}
+				changed++
+			}
+		}
+		return rehashURLs(tx)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return changed, nil
+}
+
+// rehashURLs recomputes downloads.url_hash from the URLs, keyed or not as
+// URLHash now hashes them
+func rehashURLs(tx *sql.Tx) error {
+	rows, err := tx.Query("SELECT id, url, COALESCE(url_hash, '') FROM downloads")
+	if err != nil {
+		return fmt.Errorf("failed to read url hashes: %w", err)
+	}
+	updates := make(map[string]string)
+	for rows.Next() {
+		var id, url, hash string
+		if err := rows.Scan(&id, &url, &hash); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		plain, err := openSecret(url)
+		if err != nil {
+			continue
+		}
+		if next := URLHash(plain); next != hash {
+			updates[id] = next
+		}
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return err
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	for id, hash := range updates {
+		if _, err := tx.Exec("UPDATE downloads SET url_hash = ? WHERE id = ?", hash, id); err != nil {
+			return fmt.Errorf("failed to rewrite url hash: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package state
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestSecrets_SealAndReseal(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+	defer func() { _ = ConfigureSecrets(nil, false) }()
+
+	rawOverrides := func(id string) string {
+		t.Helper()
+		var raw string
+		if err := getDBHelper().QueryRow("SELECT overrides FROM downloads WHERE id = ?", id).Scan(&raw); err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+	cookie := &types.DownloadOverrides{Headers: map[string]string{"Cookie": "session=secret"}}
+
+	// Written before encryption was turned on
+	if err := AddToMasterList(types.DownloadEntry{ID: "old", URL: "https://example.com/old", DestPath: "/tmp/old", Status: "queued", Overrides: cookie}); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := NewSecretKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ConfigureSecrets(key, true); err != nil {
+		t.Fatalf("ConfigureSecrets failed: %v", err)
+	}
+	if err := AddToMasterList(types.DownloadEntry{ID: "new", URL: "https://example.com/new", DestPath: "/tmp/new", Status: "queued", Overrides: cookie}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveHostHeaders(types.HostHeaderProfile{Host: "example.com", Headers: map[string]string{"Authorization": "Bearer token"}, Worked: true}); err != nil {
+		t.Fatal(err)
+	}
+	if raw := rawOverrides("new"); !strings.HasPrefix(raw, sealedPrefix) || strings.Contains(raw, "secret") {
+		t.Errorf("new overrides stored as %q, want encrypted", raw)
+	}
+	if raw := rawOverrides("old"); strings.HasPrefix(raw, sealedPrefix) {
+		t.Fatalf("old overrides encrypted before ResealSecrets: %q", raw)
+	}
+
+	changed, err := ResealSecrets()
+	if err != nil || changed != 2 {
+		t.Fatalf("ResealSecrets = %d, %v; want the old row's overrides and URL encrypted", changed, err)
+	}
+	if raw := rawOverrides("old"); !strings.HasPrefix(raw, sealedPrefix) {
+		t.Errorf("old overrides stored as %q after reseal, want encrypted", raw)
+	}
+	if sealed, err := HasSealedSecrets(); err != nil || !sealed {
+		t.Errorf("HasSealedSecrets = %v, %v; want true", sealed, err)
+	}
+
+	got, err := GetOverrides("old")
+	if err != nil || got == nil || got.Headers["Cookie"] != "session=secret" {
+		t.Fatalf("GetOverrides = %+v, %v; want the cookie back", got, err)
+	}
+	profile, err := GetHostHeaders("example.com")
+	if err != nil || profile == nil || profile.Headers["Authorization"] != "Bearer token" {
+		t.Fatalf("GetHostHeaders = %+v, %v; want the token back", profile, err)
+	}
+
+	// Without the key the headers are unreadable but the rows still load
+	if err := ConfigureSecrets(nil, false); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := GetOverrides("old"); err != nil || got != nil {
+		t.Errorf("GetOverrides without a key = %+v, %v; want nil", got, err)
+	}
+	if entry, err := GetDownload("old"); err != nil || entry == nil {
+		t.Errorf("GetDownload without a key = %+v, %v; want the entry", entry, err)
+	}
+	if profile, err := GetHostHeaders("example.com"); err != nil || profile == nil || profile.Headers != nil || !profile.Worked {
+		t.Errorf("GetHostHeaders without a key = %+v, %v; want the profile without headers", profile, err)
+	}
+
+	// Turning encryption off decrypts everything again
+	if err := ConfigureSecrets(key, false); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := ResealSecrets(); err != nil || changed != 5 {
+		t.Fatalf("ResealSecrets = %d, %v; want 3 header sets and 2 URLs decrypted", changed, err)
+	}
+	if raw := rawOverrides("new"); raw != `{"headers":{"Cookie":"session=secret"}}` {
+		t.Errorf("overrides after decrypting = %q", raw)
+	}
+	if sealed, _ := HasSealedSecrets(); sealed {
+		t.Error("HasSealedSecrets should be false once decrypted")
+	}
+}
+
+func TestSecrets_WrongKey(t *testing.T) {
+	defer func() { _ = ConfigureSecrets(nil, false) }()
+
+	key, _ := NewSecretKey()
+	if err := ConfigureSecrets(key, true); err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := sealSecret("token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, _ := NewSecretKey()
+	if err := ConfigureSecrets(other, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openSecret(sealed); err == nil {
+		t.Error("expected a value sealed with another key to fail")
+	}
+	if err := ConfigureSecrets([]byte("short"), true); err == nil {
+		t.Error("expected a short key to be rejected")
+	}
+}
+
+func TestSecrets_SealsURLs(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+	defer func() { _ = ConfigureSecrets(nil, false) }()
+
+	const secretURL = "https://cdn.example.com/file.iso?token=hunter2"
+	raw := func(query string, args ...any) string {
+		t.Helper()
+		var v string
+		if err := getDBHelper().QueryRow(query, args...).Scan(&v); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		return v
+	}
+
+	// Written in plain text, then sealed at the next start
+	if err := AddToMasterList(types.DownloadEntry{ID: "a", URL: secretURL, URLHash: URLHash(secretURL), DestPath: "/tmp/file.iso", Filename: "file.iso", Status: "error",
+		Mirrors: []string{secretURL, "https://mirror.example.com/file.iso?token=hunter2"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordURLHistory(secretURL, URLHistoryFailed, "Get \""+secretURL+"\": EOF"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordDownloadFailure("a", types.DownloadFailure{At: 1, Kind: types.FailureFatal, Mirror: secretURL, Message: "GET " + secretURL}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ArchiveURL(secretURL); err != nil {
+		t.Fatal(err)
+	}
+	plainHash := raw("SELECT url_hash FROM downloads WHERE id = 'a'")
+
+	key, _ := NewSecretKey()
+	if err := ConfigureSecrets(key, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ResealSecrets(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SaveProbeCache("a", types.CachedProbe{FinalURL: secretURL}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ArchiveURL("https://example.com/other?token=hunter2"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, q := range []string{
+		"SELECT url FROM downloads", "SELECT mirrors FROM downloads", "SELECT probe_final_url FROM downloads",
+		"SELECT url FROM url_history", "SELECT error FROM url_history",
+		"SELECT mirror FROM download_errors", "SELECT message FROM download_errors",
+	} {
+		if v := raw(q); strings.Contains(v, "hunter2") || !strings.HasPrefix(v, sealedPrefix) {
+			t.Errorf("%s = %q, want it encrypted", q, v)
+		}
+	}
+	if h := raw("SELECT url_hash FROM downloads WHERE id = 'a'"); h == plainHash || h != URLHash(secretURL) {
+		t.Errorf("url_hash = %q, want it keyed (plain was %q)", h, plainHash)
+	}
+	if n := raw("SELECT COUNT(*) FROM download_archive WHERE url_hash LIKE ?", keyedArchivePrefix+"%"); n != "1" {
+		t.Errorf("keyed archive entries = %s, want 1", n)
+	}
+
+	// Everything still reads back and matches by URL
+	entry, err := GetDownload("a")
+	if err != nil || entry.URL != secretURL || len(entry.Mirrors) != 2 || entry.Response == nil || entry.Response.FinalURL != secretURL {
+		t.Fatalf("GetDownload = %+v, %v", entry, err)
+	}
+	if exists, err := CheckDownloadExists(secretURL); err != nil || !exists {
+		t.Errorf("CheckDownloadExists = %v, %v; want true", exists, err)
+	}
+	if err := RecordURLHistory(secretURL, URLHistoryAdded, ""); err != nil {
+		t.Fatal(err)
+	}
+	history, err := LoadURLHistory(0)
+	if err != nil || len(history) != 1 || history[0].URL != secretURL || history[0].Status != URLHistoryAdded {
+		t.Errorf("LoadURLHistory = %+v, %v; want the one URL updated in place", history, err)
+	}
+	failures, err := LoadDownloadFailures("a")
+	if err != nil || len(failures) != 1 || failures[0].Mirror != secretURL {
+		t.Errorf("LoadDownloadFailures = %+v, %v", failures, err)
+	}
+	page, err := ListDownloadsPage(PageQuery{Search: "cdn.example.com"})
+	if err != nil || len(page) != 1 {
+		t.Errorf("search by URL = %d entries, %v; want 1", len(page), err)
+	}
+	for _, u := range []string{secretURL, "https://example.com/other?token=hunter2"} {
+		if archived, err := IsArchived(u); err != nil || !archived {
+			t.Errorf("IsArchived(%s) = %v, %v; want true", u, archived, err)
+		}
+	}
+
+	// Turning sealing off decrypts the URLs; keyed archive entries still match
+	if err := ConfigureSecrets(key, false); err != nil {
+		t.Fatal(err)
+	}
+	if sealed, _ := HasSealedSecrets(); !sealed {
+		t.Error("HasSealedSecrets should report keyed archive entries, which need the key")
+	}
+	if _, err := ResealSecrets(); err != nil {
+		t.Fatal(err)
+	}
+	if v := raw("SELECT url FROM downloads"); v != secretURL {
+		t.Errorf("url after decrypting = %q", v)
+	}
+	if h := raw("SELECT url_hash FROM downloads WHERE id = 'a'"); h != plainHash {
+		t.Errorf("url_hash after decrypting = %q, want %q", h, plainHash)
+	}
+	if archived, err := IsArchived("https://example.com/other?token=hunter2"); err != nil || !archived {
+		t.Errorf("IsArchived of a keyed entry = %v, %v; want true", archived, err)
+	}
+}
@@ -8,7 +8,7 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -36,8 +36,12 @@ type SaveStateOptions struct {
 }
 
 // URLHash returns a short hash of the URL for master list keying
-// This is used for tracking completed downloads by URL
+// This is used for tracking completed downloads by URL. While state is
+// sealed, the hash is keyed with the state key.
 func URLHash(url string) string {
+	if mac, keyed := urlMAC(); keyed {
+		return hex.EncodeToString(keyedHash(mac, "url", url)[:8])
+	}
 	h := sha256.Sum256([]byte(url))
 	return hex.EncodeToString(h[:8]) // 16 chars
 }
@@ -81,8 +85,8 @@ func SaveStateWithOptions(url string, destPath string, state *types.DownloadStat
 		// 1. Upsert into downloads table
 		_, err := tx.Exec(`
 				INSERT INTO downloads (
-					id, url, dest_path, filename, status, total_size, downloaded, url_hash, created_at, paused_at, time_taken, mirrors, chunk_bitmap, actual_chunk_size, file_hash
-			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+					id, url, dest_path, filename, status, total_size, downloaded, url_hash, created_at, paused_at, time_taken, mirrors, chunk_bitmap, actual_chunk_size, file_hash, queue_position
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, `+nextQueuePosition+`)
 			ON CONFLICT(id) DO UPDATE SET
 				url=excluded.url,
 				dest_path=excluded.dest_path,
@@ -97,7 +101,7 @@ func SaveStateWithOptions(url string, destPath string, state *types.DownloadStat
 				chunk_bitmap=excluded.chunk_bitmap,
 				actual_chunk_size=excluded.actual_chunk_size,
 				file_hash=excluded.file_hash
-		`, state.ID, state.URL, state.DestPath, state.Filename, "paused", state.TotalSize, state.Downloaded, state.URLHash, state.CreatedAt, state.PausedAt, state.Elapsed/1e6, strings.Join(state.Mirrors, ","), state.ChunkBitmap, state.ActualChunkSize, state.FileHash)
+		`, state.ID, sealValue(state.URL), state.DestPath, state.Filename, "paused", state.TotalSize, state.Downloaded, state.URLHash, state.CreatedAt, state.PausedAt, state.Elapsed/1e6, sealValue(strings.Join(state.Mirrors, ",")), state.ChunkBitmap, state.ActualChunkSize, state.FileHash)
 		if err != nil {
 			return fmt.Errorf("failed to upsert download: %w", err)
 		}
@@ -118,7 +122,7 @@ func SaveStateWithOptions(url string, destPath string, state *types.DownloadStat
 			// Prepare statement for full batches
 			placeholders := strings.Repeat("(?, ?, ?),", batchSize)
 			placeholders = placeholders[:len(placeholders)-1] // remove trailing comma
-			stmt, err := tx.Prepare("INSERT INTO tasks (download_id, offset, length) VALUES " + placeholders)
+			stmt, err := tx.Prepare("INSERT INTO tasks (download_id, \"offset\", length) VALUES " + placeholders)
 			if err != nil {
 				return fmt.Errorf("failed to prepare batch insert: %w", err)
 			}
@@ -132,7 +136,7 @@ func SaveStateWithOptions(url string, destPath string, state *types.DownloadStat
 					batch := tasks[i:end]
 
 					var q strings.Builder
-					q.WriteString("INSERT INTO tasks (download_id, offset, length) VALUES ")
+					q.WriteString("INSERT INTO tasks (download_id, \"offset\", length) VALUES ")
 					args := make([]interface{}, 0, len(batch)*3)
 					for j, task := range batch {
 						if j > 0 {
@@ -262,7 +266,7 @@ func LoadState(url string, destPath string) (*types.DownloadState, error) {
 		FROM downloads 
 		WHERE url = ? AND dest_path = ? AND status != 'completed'
 		ORDER BY paused_at DESC LIMIT 1
-	`, url, destPath)
+	`, sealValue(url), destPath)
 
 	err := row.Scan(
 		&state.ID, &state.URL, &state.DestPath, &state.Filename,
@@ -277,6 +281,7 @@ func LoadState(url string, destPath string) (*types.DownloadState, error) {
 		return nil, fmt.Errorf("failed to query download: %w", err)
 	}
 
+	state.URL = openValue(state.URL)
 	if createdAt.Valid {
 		state.CreatedAt = createdAt.Int64
 	}
@@ -287,7 +292,7 @@ func LoadState(url string, destPath string) (*types.DownloadState, error) {
 		state.Elapsed = timeTaken.Int64 * 1e6 // Convert ms to ns
 	}
 	if mirrors.Valid && mirrors.String != "" {
-		state.Mirrors = strings.Split(mirrors.String, ",")
+		state.Mirrors = strings.Split(openValue(mirrors.String), ",")
 	}
 	if actualChunkSize.Valid {
 		state.ActualChunkSize = actualChunkSize.Int64
@@ -298,7 +303,7 @@ func LoadState(url string, destPath string) (*types.DownloadState, error) {
 	}
 
 	// Load tasks
-	rows, err := db.Query("SELECT offset, length FROM tasks WHERE download_id = ?", state.ID)
+	rows, err := db.Query("SELECT \"offset\", length FROM tasks WHERE download_id = ?", state.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query tasks: %w", err)
 	}
@@ -358,6 +363,9 @@ func DeleteTasks(id string) error {
 
 // ================== Master List Functions ==================
 
+// masterListColumns are the downloads columns scanMasterListRow reads
+const masterListColumns = `id, url, dest_path, filename, status, total_size, downloaded, completed_at, time_taken, url_hash, mirrors, avg_speed, created_at, trace_id, tags, category, note, metadata, checksum, verify_status, verified_at, pause_reason, overrides, trashed_at, queue_position, ` + probeCacheColumns
+
 // LoadMasterList loads ALL downloads (paused and completed)
 func LoadMasterList() (*types.MasterList, error) {
 	db := getDBHelper()
@@ -367,8 +375,9 @@ func LoadMasterList() (*types.MasterList, error) {
 	}
 
 	rows, err := db.Query(`
-		SELECT id, url, dest_path, filename, status, total_size, downloaded, completed_at, time_taken, url_hash, mirrors, avg_speed 
+		SELECT ` + masterListColumns + `
 		FROM downloads
+		ORDER BY COALESCE(created_at, 0), id
 	`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query downloads: %w", err)
@@ -381,43 +390,79 @@ func LoadMasterList() (*types.MasterList, error) {
 
 	var list types.MasterList
 	for rows.Next() {
-		var e types.DownloadEntry
-		var completedAt, timeTaken sql.NullInt64      // handle nulls
-		var filename, urlHash, mirrors sql.NullString // handle nulls
-		var avgSpeed sql.NullFloat64                  // handle null avg_speed
-
-		if err := rows.Scan(
-			&e.ID, &e.URL, &e.DestPath, &filename, &e.Status, &e.TotalSize, &e.Downloaded,
-			&completedAt, &timeTaken, &urlHash, &mirrors, &avgSpeed,
-		); err != nil {
+		e, err := scanMasterListRow(rows)
+		if err != nil {
 			return nil, err
 		}
-
-		if completedAt.Valid {
-			e.CompletedAt = completedAt.Int64
-		}
-		if timeTaken.Valid {
-			e.TimeTaken = timeTaken.Int64
-		}
-		if filename.Valid {
-			e.Filename = filename.String
-		}
-		if urlHash.Valid {
-			e.URLHash = urlHash.String
-		}
-		if mirrors.Valid && mirrors.String != "" {
-			e.Mirrors = strings.Split(mirrors.String, ",")
-		}
-		if avgSpeed.Valid {
-			e.AvgSpeed = avgSpeed.Float64
-		}
-
 		list.Downloads = append(list.Downloads, e)
 	}
 
 	return &list, nil
 }
 
+// scanMasterListRow reads one row selected with masterListColumns
+func scanMasterListRow(rows *sql.Rows) (types.DownloadEntry, error) {
+	var e types.DownloadEntry
+	var completedAt, timeTaken, createdAt sql.NullInt64                    // handle nulls
+	var filename, urlHash, mirrors, traceID, tags, category sql.NullString // handle nulls
+	var note, metadata, checksum, verifyStatus sql.NullString              // handle nulls
+	var pauseReason, overrides sql.NullString                              // handle null pause_reason/overrides
+	var verifiedAt, trashedAt, queuePosition sql.NullInt64                 // handle null verified_at/trashed_at/queue_position
+	var avgSpeed sql.NullFloat64                                           // handle null avg_speed
+	var probe probeScan
+
+	if err := rows.Scan(append([]any{
+		&e.ID, &e.URL, &e.DestPath, &filename, &e.Status, &e.TotalSize, &e.Downloaded,
+		&completedAt, &timeTaken, &urlHash, &mirrors, &avgSpeed, &createdAt, &traceID, &tags, &category, &note, &metadata,
+		&checksum, &verifyStatus, &verifiedAt, &pauseReason, &overrides, &trashedAt, &queuePosition,
+	}, probe.dest()...)...); err != nil {
+		return e, err
+	}
+
+	e.URL = openValue(e.URL)
+	if completedAt.Valid {
+		e.CompletedAt = completedAt.Int64
+	}
+	if timeTaken.Valid {
+		e.TimeTaken = timeTaken.Int64
+	}
+	if filename.Valid {
+		e.Filename = filename.String
+	}
+	if urlHash.Valid {
+		e.URLHash = urlHash.String
+	}
+	if mirrors.Valid && mirrors.String != "" {
+		e.Mirrors = strings.Split(openValue(mirrors.String), ",")
+	}
+	if avgSpeed.Valid {
+		e.AvgSpeed = avgSpeed.Float64
+	}
+	if createdAt.Valid {
+		e.CreatedAt = createdAt.Int64
+	}
+	if traceID.Valid {
+		e.TraceID = traceID.String
+	}
+	if tags.Valid && tags.String != "" {
+		e.Tags = strings.Split(tags.String, ",")
+	}
+	if category.Valid {
+		e.Category = category.String
+	}
+	e.Note = note.String
+	e.Metadata = decodeMetadata(metadata.String)
+	e.Checksum = checksum.String
+	e.VerifyStatus = verifyStatus.String
+	e.VerifiedAt = verifiedAt.Int64
+	e.PauseReason = pauseReason.String
+	e.Overrides = decodeOverrides(overrides.String)
+	e.TrashedAt = trashedAt.Int64
+	e.QueuePosition = queuePosition.Int64
+	e.Response = probe.probe()
+	return e, nil
+}
+
 // AddToMasterList adds or updates a download entry
 func AddToMasterList(entry types.DownloadEntry) error {
 	// Ensure ID
@@ -430,11 +475,23 @@ func AddToMasterList(entry types.DownloadEntry) error {
 		}
 	}
 
+	if entry.CreatedAt == 0 {
+		entry.CreatedAt = time.Now().Unix()
+	}
+
 	return withTx(func(tx *sql.Tx) error {
-		_, err := tx.Exec(`
+		overrides, err := encodeOverrides(entry.Overrides)
+		if err != nil {
+			return err
+		}
+
+		// created_at and queue_position are kept once set so list and queue
+		// order don't shift on updates; an update without a trace ID, tags,
+		// category or overrides keeps the ones the download started with
+		_, err = tx.Exec(`
 			INSERT INTO downloads (
-				id, url, dest_path, filename, status, total_size, downloaded, completed_at, time_taken, url_hash, mirrors, avg_speed
-			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				id, url, dest_path, filename, status, total_size, downloaded, completed_at, time_taken, url_hash, mirrors, avg_speed, created_at, trace_id, tags, category, pause_reason, overrides, queue_position
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), `+nextQueuePosition+`)
 			ON CONFLICT(id) DO UPDATE SET
 				url=excluded.url,
 				dest_path=excluded.dest_path,
@@ -446,10 +503,16 @@ func AddToMasterList(entry types.DownloadEntry) error {
 				time_taken=excluded.time_taken,
 				url_hash=excluded.url_hash,
 				mirrors=excluded.mirrors,
-				avg_speed=excluded.avg_speed
+				avg_speed=excluded.avg_speed,
+				created_at=COALESCE(downloads.created_at, excluded.created_at),
+				trace_id=COALESCE(NULLIF(excluded.trace_id, ''), downloads.trace_id),
+				tags=COALESCE(NULLIF(excluded.tags, ''), downloads.tags),
+				category=COALESCE(NULLIF(excluded.category, ''), downloads.category),
+				pause_reason=excluded.pause_reason,
+				overrides=COALESCE(excluded.overrides, downloads.overrides)
 		`,
-			entry.ID, entry.URL, entry.DestPath, entry.Filename, entry.Status, entry.TotalSize, entry.Downloaded,
-			entry.CompletedAt, entry.TimeTaken, entry.URLHash, strings.Join(entry.Mirrors, ","), entry.AvgSpeed)
+			entry.ID, sealValue(entry.URL), entry.DestPath, entry.Filename, entry.Status, entry.TotalSize, entry.Downloaded,
+			entry.CompletedAt, entry.TimeTaken, entry.URLHash, sealValue(strings.Join(entry.Mirrors, ",")), entry.AvgSpeed, entry.CreatedAt, entry.TraceID, strings.Join(entry.Tags, ","), entry.Category, entry.PauseReason, overrides)
 
 		return err
 	})
@@ -475,25 +538,30 @@ func GetDownload(id string) (*types.DownloadEntry, error) {
 
 	var e types.DownloadEntry
 	var completedAt, timeTaken sql.NullInt64
-	var urlHash, filename, mirrors sql.NullString
+	var urlHash, filename, mirrors, traceID, tags, category, note, metadata sql.NullString
+	var checksum, verifyStatus, pauseReason, overrides sql.NullString
+	var verifiedAt, trashedAt, queuePosition sql.NullInt64
 	var avgSpeed sql.NullFloat64
+	var probe probeScan
 
 	row := db.QueryRow(`
-		SELECT id, url, dest_path, filename, status, total_size, downloaded, completed_at, time_taken, url_hash, mirrors, avg_speed 
+		SELECT id, url, dest_path, filename, status, total_size, downloaded, completed_at, time_taken, url_hash, mirrors, avg_speed, trace_id, tags, category, note, metadata, checksum, verify_status, verified_at, pause_reason, overrides, trashed_at, queue_position, `+probeCacheColumns+`
 		FROM downloads
 		WHERE id = ?
 	`, id)
 
-	if err := row.Scan(
+	if err := row.Scan(append([]any{
 		&e.ID, &e.URL, &e.DestPath, &filename, &e.Status, &e.TotalSize, &e.Downloaded,
-		&completedAt, &timeTaken, &urlHash, &mirrors, &avgSpeed,
-	); err != nil {
+		&completedAt, &timeTaken, &urlHash, &mirrors, &avgSpeed, &traceID, &tags, &category, &note, &metadata,
+		&checksum, &verifyStatus, &verifiedAt, &pauseReason, &overrides, &trashedAt, &queuePosition,
+	}, probe.dest()...)...); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Not found
 		}
 		return nil, fmt.Errorf("failed to query download: %w", err)
 	}
 
+	e.URL = openValue(e.URL)
 	if completedAt.Valid {
 		e.CompletedAt = completedAt.Int64
 	}
@@ -507,16 +575,35 @@ func GetDownload(id string) (*types.DownloadEntry, error) {
 		e.Filename = filename.String
 	}
 	if mirrors.Valid && mirrors.String != "" {
-		e.Mirrors = strings.Split(mirrors.String, ",")
+		e.Mirrors = strings.Split(openValue(mirrors.String), ",")
 	}
 	if avgSpeed.Valid {
 		e.AvgSpeed = avgSpeed.Float64
 	}
+	if traceID.Valid {
+		e.TraceID = traceID.String
+	}
+	if tags.Valid && tags.String != "" {
+		e.Tags = strings.Split(tags.String, ",")
+	}
+	if category.Valid {
+		e.Category = category.String
+	}
+	e.Note = note.String
+	e.Metadata = decodeMetadata(metadata.String)
+	e.Checksum = checksum.String
+	e.VerifyStatus = verifyStatus.String
+	e.VerifiedAt = verifiedAt.Int64
+	e.PauseReason = pauseReason.String
+	e.Overrides = decodeOverrides(overrides.String)
+	e.TrashedAt = trashedAt.Int64
+	e.QueuePosition = queuePosition.Int64
+	e.Response = probe.probe()
 
 	return &e, nil
 }
 
-// LoadPausedDownloads returns all paused downloads
+// LoadPausedDownloads returns all paused and queued downloads in queue order
 func LoadPausedDownloads() ([]types.DownloadEntry, error) {
 	// Reuse LoadMasterList logic or optimize with WHERE
 	list, err := LoadMasterList()
@@ -530,10 +617,19 @@ func LoadPausedDownloads() ([]types.DownloadEntry, error) {
 			paused = append(paused, e)
 		}
 	}
+	// Highest priority first, then in queue order. Stable, so rows without a
+	// position keep the order they were added in.
+	sort.SliceStable(paused, func(i, j int) bool {
+		if pi, pj := paused[i].Overrides.GetPriority(), paused[j].Overrides.GetPriority(); pi != pj {
+			return pi > pj
+		}
+		return paused[i].QueuePosition < paused[j].QueuePosition
+	})
 	return paused, nil
 }
 
-// LoadCompletedDownloads returns all completed downloads
+// LoadCompletedDownloads returns all completed downloads, most recently
+// completed first
 func LoadCompletedDownloads() ([]types.DownloadEntry, error) {
 	list, err := LoadMasterList()
 	if err != nil {
@@ -546,9 +642,21 @@ func LoadCompletedDownloads() ([]types.DownloadEntry, error) {
 			completed = append(completed, e)
 		}
 	}
+	SortHistory(completed)
 	return completed, nil
 }
 
+// SortHistory orders completed downloads most recently completed first, by
+// id within the same second
+func SortHistory(entries []types.DownloadEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].CompletedAt != entries[j].CompletedAt {
+			return entries[i].CompletedAt > entries[j].CompletedAt
+		}
+		return entries[i].ID < entries[j].ID
+	})
+}
+
 // CheckDownloadExists checks if a download with the given URL exists in the database
 func CheckDownloadExists(url string) (bool, error) {
 	db := getDBHelper()
@@ -558,7 +666,7 @@ func CheckDownloadExists(url string) (bool, error) {
 
 	var count int
 	// Check for any status (active, paused, completed)
-	err := db.QueryRow("SELECT COUNT(*) FROM downloads WHERE url = ?", url).Scan(&count)
+	err := db.QueryRow("SELECT COUNT(*) FROM downloads WHERE url = ?", sealValue(url)).Scan(&count)
 	if err != nil {
 		return false, fmt.Errorf("failed to query download existence: %w", err)
 	}
@@ -595,7 +703,7 @@ func UpdateURL(id string, newURL string) error {
 
 	newHash := URLHash(newURL)
 
-	result, err := db.Exec("UPDATE downloads SET url = ?, url_hash = ? WHERE id = ?", newURL, newHash, id)
+	result, err := db.Exec("UPDATE downloads SET url = ?, url_hash = ? WHERE id = ?", sealValue(newURL), newHash, id)
 	if err != nil {
 		return fmt.Errorf("failed to update url: %w", err)
 	}
@@ -608,6 +716,26 @@ func UpdateURL(id string, newURL string) error {
 	return nil
 }
 
+// UpdateDestPath records that a download's file now lives at destPath
+func UpdateDestPath(id string, destPath string, filename string) error {
+	db := getDBHelper()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	result, err := db.Exec("UPDATE downloads SET dest_path = ?, filename = ? WHERE id = ?", destPath, filename, id)
+	if err != nil {
+		return fmt.Errorf("failed to update dest path: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("download not found: %s", id)
+	}
+
+	return nil
+}
+
 // PauseAllDownloads pauses all non-completed downloads
 func PauseAllDownloads() error {
 	db := getDBHelper()
@@ -655,7 +783,8 @@ func RemoveCompletedDownloads() (int64, error) {
 	return count, nil
 }
 
-// LoadStates loads multiple download states from SQLite in batch
+// LoadStates loads multiple download states from SQLite in batch. Completed,
+// archived and trashed downloads are left out, as none can be resumed.
 func LoadStates(ids []string) (map[string]*types.DownloadState, error) {
 	if len(ids) == 0 {
 		return make(map[string]*types.DownloadState), nil
@@ -679,7 +808,7 @@ func LoadStates(ids []string) (map[string]*types.DownloadState, error) {
 	query := fmt.Sprintf(`
 		SELECT id, url, dest_path, filename, total_size, downloaded, url_hash, created_at, paused_at, time_taken, mirrors, chunk_bitmap, actual_chunk_size
 		FROM downloads
-		WHERE id IN (%s) AND status != 'completed'
+		WHERE id IN (%s) AND status NOT IN ('completed', 'archived', 'trashed')
 	`, inClause)
 
 	rows, err := db.Query(query, args...)
@@ -709,6 +838,7 @@ func LoadStates(ids []string) (map[string]*types.DownloadState, error) {
 			return nil, err
 		}
 
+		state.URL = openValue(state.URL)
 		if createdAt.Valid {
 			state.CreatedAt = createdAt.Int64
 		}
@@ -719,7 +849,7 @@ func LoadStates(ids []string) (map[string]*types.DownloadState, error) {
 			state.Elapsed = timeTaken.Int64 * 1e6
 		}
 		if mirrors.Valid && mirrors.String != "" {
-			state.Mirrors = strings.Split(mirrors.String, ",")
+			state.Mirrors = strings.Split(openValue(mirrors.String), ",")
 		}
 		if actualChunkSize.Valid {
 			state.ActualChunkSize = actualChunkSize.Int64
@@ -730,7 +860,7 @@ func LoadStates(ids []string) (map[string]*types.DownloadState, error) {
 	}
 
 	// 2. Load Tasks for all these downloads
-	taskQuery := fmt.Sprintf(`SELECT download_id, offset, length FROM tasks WHERE download_id IN (%s)`, inClause)
+	taskQuery := fmt.Sprintf(`SELECT download_id, "offset", length FROM tasks WHERE download_id IN (%s)`, inClause)
 	taskRows, err := db.Query(taskQuery, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query tasks batch: %w", err)
@@ -795,7 +925,7 @@ func NormalizeStaleDownloads() (int, error) {
 		return 0, fmt.Errorf("database not initialized")
 	}
 
-	result, err := db.Exec(`UPDATE downloads SET status = 'paused' WHERE status = 'downloading'`)
+	result, err := db.Exec(`UPDATE downloads SET status = 'paused', pause_reason = ? WHERE status = 'downloading'`, types.PauseInterrupted)
 	if err != nil {
 		return 0, fmt.Errorf("failed to normalize stale downloads: %w", err)
 	}
@@ -804,20 +934,21 @@ func NormalizeStaleDownloads() (int, error) {
 	return int(count), nil
 }
 
-// ValidateIntegrity checks that paused .surge files still exist and haven't been tampered with.
-// Removes orphaned or corrupted entries from the database.
-// Returns the number of entries removed.
+// ValidateIntegrity checks that paused .surge files haven't been tampered with
+// since they were saved, and removes the ones that were along with their
+// database entries. Missing and unclaimed working files are left for
+// FindOrphans. Returns the number of entries removed.
 func ValidateIntegrity() (int, error) {
 	db := getDBHelper()
 	if db == nil {
 		return 0, fmt.Errorf("database not initialized")
 	}
 
-	// Load all paused/queued downloads
+	// Load all paused/queued downloads that recorded a hash
 	rows, err := db.Query(`
-		SELECT id, dest_path, file_hash, status, downloaded
+		SELECT id, dest_path, file_hash
 		FROM downloads
-		WHERE status IN ('paused', 'queued')
+		WHERE status IN ('paused', 'queued') AND file_hash IS NOT NULL AND file_hash != ''
 	`)
 	if err != nil {
 		return 0, fmt.Errorf("failed to query paused downloads: %w", err)
@@ -825,23 +956,17 @@ func ValidateIntegrity() (int, error) {
 	defer func() { _ = rows.Close() }()
 
 	type entry struct {
-		id         string
-		destPath   string
-		fileHash   string
-		status     string
-		downloaded int64
+		id       string
+		destPath string
+		fileHash string
 	}
 
 	var entries []entry
 	for rows.Next() {
 		var e entry
-		var fh sql.NullString
-		if err := rows.Scan(&e.id, &e.destPath, &fh, &e.status, &e.downloaded); err != nil {
+		if err := rows.Scan(&e.id, &e.destPath, &e.fileHash); err != nil {
 			return 0, err
 		}
-		if fh.Valid {
-			e.fileHash = fh.String
-		}
 		entries = append(entries, e)
 	}
 	if err := rows.Err(); err != nil {
@@ -849,117 +974,34 @@ func ValidateIntegrity() (int, error) {
 	}
 
 	removed := 0
-	expectedSurgePaths := make(map[string]struct{}, len(entries))
-	candidateDirs := make(map[string]struct{}, len(entries))
-
 	for _, e := range entries {
 		if e.destPath == "" {
 			continue
 		}
-		// Fresh queued entries may not have a partial file yet.
-		if e.status == "queued" && e.downloaded <= 0 {
-			candidateDirs[filepath.Dir(e.destPath)] = struct{}{}
-			continue
-		}
-		expectedSurgePaths[e.destPath+types.IncompleteSuffix] = struct{}{}
-		candidateDirs[filepath.Dir(e.destPath)] = struct{}{}
-	}
-
-	// Also include directories of all known downloads so we can clean orphan .surge
-	// files that no longer have corresponding DB entries.
-	// Keep .surge files for any non-completed entry (e.g. downloading after crash).
-	allRows, err := db.Query(`
-		SELECT dest_path, status
-		FROM downloads
-		WHERE dest_path IS NOT NULL AND dest_path != ''
-	`)
-	if err != nil {
-		return 0, fmt.Errorf("failed to query known download paths: %w", err)
-	}
-	for allRows.Next() {
-		var dest string
-		var status string
-		if err := allRows.Scan(&dest, &status); err != nil {
-			_ = allRows.Close()
-			return 0, fmt.Errorf("failed to scan download path: %w", err)
-		}
-		candidateDirs[filepath.Dir(dest)] = struct{}{}
-		if status != "completed" {
-			expectedSurgePaths[dest+types.IncompleteSuffix] = struct{}{}
-		}
-	}
-	if err := allRows.Err(); err != nil {
-		_ = allRows.Close()
-		return 0, fmt.Errorf("failed to iterate download paths: %w", err)
-	}
-	_ = allRows.Close()
-
-	for _, e := range entries {
-		if e.status == "queued" && e.downloaded <= 0 {
-			continue
-		}
 		surgePath := e.destPath + types.IncompleteSuffix
 
-		// Check if .surge file exists
 		_, statErr := os.Stat(surgePath)
 		if os.IsNotExist(statErr) {
-			// File missing — remove orphaned DB entry
-			utils.Debug("Integrity: .surge file missing for %s, removing entry %s", e.destPath, e.id)
-			if err := removeDownloadAndTasks(e.id); err != nil {
-				return removed, fmt.Errorf("failed to remove orphaned entry %s: %w", e.id, err)
-			}
-			removed++
-			continue
+			continue // Reported by FindOrphans
 		}
 		if statErr != nil {
 			return removed, fmt.Errorf("failed to stat %s: %w", surgePath, statErr)
 		}
 
-		// If we have a stored hash, verify it
-		if e.fileHash != "" {
-			matches, err := compareAgainstStoredFileHash(surgePath, e.fileHash)
-			if err != nil {
-				return removed, fmt.Errorf("failed to verify hash for %s: %w", surgePath, err)
-			}
-			if !matches {
-				// File has been tampered with — remove entry and corrupted file
-				utils.Debug("Integrity: hash mismatch for %s (expected %s), removing", surgePath, e.fileHash)
-				if err := retryRemove(surgePath); err != nil && !os.IsNotExist(err) {
-					return removed, fmt.Errorf("failed to remove tampered file %s: %w", surgePath, err)
-				}
-				if err := removeDownloadAndTasks(e.id); err != nil {
-					return removed, fmt.Errorf("failed to remove tampered entry %s: %w", e.id, err)
-				}
-				removed++
-			}
-		}
-	}
-
-	// Remove orphan .surge files that no longer have matching paused/queued entries.
-	for dir := range candidateDirs {
-		files, err := os.ReadDir(dir)
+		matches, err := compareAgainstStoredFileHash(surgePath, e.fileHash)
 		if err != nil {
-			if os.IsNotExist(err) {
-				continue
-			}
-			return removed, fmt.Errorf("failed to read directory %s: %w", dir, err)
+			return removed, fmt.Errorf("failed to verify hash for %s: %w", surgePath, err)
 		}
-		for _, f := range files {
-			if f.IsDir() {
-				continue
-			}
-			name := f.Name()
-			if !strings.HasSuffix(name, types.IncompleteSuffix) {
-				continue
-			}
-			surgePath := filepath.Join(dir, name)
-			if _, ok := expectedSurgePaths[surgePath]; ok {
-				continue
-			}
+		if !matches {
+			// File has been tampered with — remove entry and corrupted file
+			utils.Debug("Integrity: hash mismatch for %s (expected %s), removing", surgePath, e.fileHash)
 			if err := retryRemove(surgePath); err != nil && !os.IsNotExist(err) {
-				return removed, fmt.Errorf("failed to remove orphan file %s: %w", surgePath, err)
+				return removed, fmt.Errorf("failed to remove tampered file %s: %w", surgePath, err)
 			}
-			utils.Debug("Integrity: removed orphan .surge file %s", surgePath)
+			if err := removeDownloadAndTasks(e.id); err != nil {
+				return removed, fmt.Errorf("failed to remove tampered entry %s: %w", e.id, err)
+			}
+			removed++
 		}
 	}
 
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -24,7 +25,7 @@ func setupTestDB(t *testing.T) string {
 		_ = db.Close()
 		db = nil
 	}
-	configured = false // Reset configured flag
+	store = nil // Reset configured store
 	dbMu.Unlock()
 
 	// Configure DB
@@ -32,7 +33,7 @@ func setupTestDB(t *testing.T) string {
 	Configure(dbPath)
 
 	// Initialize DB
-	if err := initDB(); err != nil {
+	if _, err := GetDB(); err != nil {
 		t.Fatalf("Failed to init DB: %v", err)
 	}
 
@@ -725,7 +726,7 @@ func TestMirrorsPersistence(t *testing.T) {
 // ValidateIntegrity Tests
 // =============================================================================
 
-func TestValidateIntegrity_MissingFile(t *testing.T) {
+func TestValidateIntegrity_LeavesMissingFileToFindOrphans(t *testing.T) {
 	tmpDir := setupTestDB(t)
 	defer func() { _ = os.RemoveAll(tmpDir) }()
 	defer CloseDB()
@@ -733,47 +734,35 @@ func TestValidateIntegrity_MissingFile(t *testing.T) {
 	destPath := filepath.Join(tmpDir, "missing.zip")
 	// Insert a paused download — but DO NOT create the .surge file
 	entry := types.DownloadEntry{
-		ID:       "integrity-missing",
-		URL:      "https://example.com/missing.zip",
-		DestPath: destPath,
-		Filename: "missing.zip",
-		Status:   "paused",
+		ID:         "integrity-missing",
+		URL:        "https://example.com/missing.zip",
+		DestPath:   destPath,
+		Filename:   "missing.zip",
+		Status:     "paused",
+		Downloaded: 512,
 	}
 	if err := AddToMasterList(entry); err != nil {
 		t.Fatalf("AddToMasterList failed: %v", err)
 	}
 
-	// Verify entry exists
-	dl, err := GetDownload("integrity-missing")
-	if err != nil || dl == nil {
-		t.Fatalf("Expected entry to exist before integrity check")
-	}
-
-	// Run integrity check — file is missing, entry should be removed
+	// Missing files are only reported, so the user decides what to remove
 	removed, err := ValidateIntegrity()
 	if err != nil {
 		t.Fatalf("ValidateIntegrity failed: %v", err)
 	}
-	if removed != 1 {
-		t.Errorf("ValidateIntegrity removed = %d, want 1", removed)
+	if removed != 0 {
+		t.Errorf("ValidateIntegrity removed = %d, want 0", removed)
 	}
-
-	// Verify entry is gone
-	dl, err = GetDownload("integrity-missing")
-	if err != nil {
-		t.Fatalf("GetDownload failed: %v", err)
-	}
-	if dl != nil {
-		t.Error("Entry should have been removed after integrity check")
+	if dl, err := GetDownload("integrity-missing"); err != nil || dl == nil {
+		t.Fatalf("entry should be kept, got %v, %v", dl, err)
 	}
 
-	d := getDBHelper()
-	var taskCount int
-	if err := d.QueryRow("SELECT COUNT(*) FROM tasks WHERE download_id = ?", entry.ID).Scan(&taskCount); err != nil {
-		t.Fatalf("failed to count tasks: %v", err)
+	orphans, err := FindOrphans()
+	if err != nil {
+		t.Fatalf("FindOrphans failed: %v", err)
 	}
-	if taskCount != 0 {
-		t.Errorf("expected tasks to be removed, got %d", taskCount)
+	if len(orphans.Entries) != 1 || orphans.Entries[0].ID != entry.ID {
+		t.Fatalf("orphaned entries = %+v, want %s", orphans.Entries, entry.ID)
 	}
 }
 
@@ -953,7 +942,7 @@ func TestValidateIntegrity_QueuedWithoutPartialFilePreserved(t *testing.T) {
 	}
 }
 
-func TestValidateIntegrity_DeletesOrphanSurgeFile(t *testing.T) {
+func TestValidateIntegrity_LeavesOrphanSurgeFile(t *testing.T) {
 	tmpDir := setupTestDB(t)
 	defer func() { _ = os.RemoveAll(tmpDir) }()
 	defer CloseDB()
@@ -983,8 +972,9 @@ func TestValidateIntegrity_DeletesOrphanSurgeFile(t *testing.T) {
 		t.Errorf("ValidateIntegrity removed = %d, want 0 (no paused/queued DB entries removed)", removed)
 	}
 
-	if _, err := os.Stat(orphanPath); !os.IsNotExist(err) {
-		t.Errorf("orphan .surge file should be removed, stat err: %v", err)
+	// Orphan files are left for FindOrphans and RemoveOrphans
+	if _, err := os.Stat(orphanPath); err != nil {
+		t.Errorf("orphan .surge file should be kept, stat err: %v", err)
 	}
 }
 
@@ -1113,12 +1103,18 @@ func TestNormalizeStaleDownloads(t *testing.T) {
 		if dl.Status != "paused" {
 			t.Errorf("%s status = %q, want paused", id, dl.Status)
 		}
+		if dl.PauseReason != types.PauseInterrupted {
+			t.Errorf("%s pause reason = %q, want %q", id, dl.PauseReason, types.PauseInterrupted)
+		}
 	}
 	// Verify other statuses untouched
 	dl3, _ := GetDownload("ok-3")
 	if dl3.Status != "paused" {
 		t.Errorf("ok-3 status = %q, want paused", dl3.Status)
 	}
+	if dl3.PauseReason != "" {
+		t.Errorf("ok-3 pause reason = %q, want none", dl3.PauseReason)
+	}
 	dl4, _ := GetDownload("ok-4")
 	if dl4.Status != "completed" {
 		t.Errorf("ok-4 status = %q, want completed", dl4.Status)
@@ -1128,3 +1124,168 @@ func TestNormalizeStaleDownloads(t *testing.T) {
 		t.Errorf("ok-5 status = %q, want queued", dl5.Status)
 	}
 }
+
+func TestLoadMasterList_OrderedByCreation(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	for _, e := range []types.DownloadEntry{
+		{ID: "c", URL: "https://example.com/c", Status: "queued", CreatedAt: 30},
+		{ID: "b", URL: "https://example.com/b", Status: "queued", CreatedAt: 10},
+		{ID: "a", URL: "https://example.com/a", Status: "queued", CreatedAt: 30},
+	} {
+		if err := AddToMasterList(e); err != nil {
+			t.Fatalf("AddToMasterList(%s): %v", e.ID, err)
+		}
+	}
+
+	// Later updates don't carry created_at; the original must be kept
+	if err := AddToMasterList(types.DownloadEntry{ID: "b", URL: "https://example.com/b", Status: "completed", CompletedAt: 99}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	list, err := LoadMasterList()
+	if err != nil {
+		t.Fatalf("LoadMasterList: %v", err)
+	}
+	var ids []string
+	for _, e := range list.Downloads {
+		ids = append(ids, e.ID)
+	}
+	if strings.Join(ids, ",") != "b,a,c" {
+		t.Errorf("order = %v, want b,a,c", ids)
+	}
+	if list.Downloads[0].CreatedAt != 10 {
+		t.Errorf("created_at after update = %d, want 10", list.Downloads[0].CreatedAt)
+	}
+}
+
+func TestAddToMasterList_KeepsTraceID(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	if err := AddToMasterList(types.DownloadEntry{ID: "t1", URL: "https://example.com/t", Status: "downloading", TraceID: traceID}); err != nil {
+		t.Fatalf("AddToMasterList: %v", err)
+	}
+	// Pause/complete/error updates are written without the trace ID
+	if err := AddToMasterList(types.DownloadEntry{ID: "t1", URL: "https://example.com/t", Status: "error"}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	got, err := GetDownload("t1")
+	if err != nil || got == nil {
+		t.Fatalf("GetDownload: %v, %v", got, err)
+	}
+	if got.TraceID != traceID || got.Status != "error" {
+		t.Errorf("entry = %+v, want trace %s and status error", got, traceID)
+	}
+
+	list, err := LoadMasterList()
+	if err != nil {
+		t.Fatalf("LoadMasterList: %v", err)
+	}
+	if len(list.Downloads) != 1 || list.Downloads[0].TraceID != traceID {
+		t.Errorf("LoadMasterList = %+v", list.Downloads)
+	}
+}
+
+func TestAddToMasterList_Tags(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	if err := AddToMasterList(types.DownloadEntry{ID: "g1", URL: "https://example.com/g", Status: "downloading", Tags: []string{"work", "iso"}}); err != nil {
+		t.Fatalf("AddToMasterList: %v", err)
+	}
+	// Lifecycle updates don't carry tags; the originals must be kept
+	if err := AddToMasterList(types.DownloadEntry{ID: "g1", URL: "https://example.com/g", Status: "completed"}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	got, err := GetDownload("g1")
+	if err != nil || got == nil {
+		t.Fatalf("GetDownload: %v, %v", got, err)
+	}
+	if strings.Join(got.Tags, ",") != "work,iso" {
+		t.Errorf("tags = %v, want [work iso]", got.Tags)
+	}
+
+	history, err := LoadCompletedDownloads()
+	if err != nil {
+		t.Fatalf("LoadCompletedDownloads: %v", err)
+	}
+	if len(history) != 1 || strings.Join(history[0].Tags, ",") != "work,iso" {
+		t.Errorf("history = %+v", history)
+	}
+}
+
+func TestAddToMasterList_KeepsCategory(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	if err := AddToMasterList(types.DownloadEntry{ID: "c1", URL: "https://example.com/c", Status: "queued", Category: "Videos"}); err != nil {
+		t.Fatalf("AddToMasterList: %v", err)
+	}
+	if err := AddToMasterList(types.DownloadEntry{ID: "c1", URL: "https://example.com/c", Status: "paused"}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	got, err := GetDownload("c1")
+	if err != nil || got == nil {
+		t.Fatalf("GetDownload: %v, %v", got, err)
+	}
+	if got.Category != "Videos" {
+		t.Errorf("category = %q, want Videos", got.Category)
+	}
+}
+
+func TestAddToMasterList_KeepsOverrides(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	entry := types.DownloadEntry{
+		ID:       "with-overrides",
+		URL:      "https://example.com/file.bin",
+		DestPath: filepath.Join(tmpDir, "file.bin"),
+		Status:   "queued",
+		Overrides: &types.DownloadOverrides{
+			Headers:     map[string]string{"Authorization": "Bearer t"},
+			Connections: 2,
+			SpeedLimit:  1024,
+		},
+	}
+	if err := AddToMasterList(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	entry.Overrides = nil
+	entry.Status = "paused"
+	if err := AddToMasterList(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := GetOverrides(entry.ID)
+	if err != nil {
+		t.Fatalf("GetOverrides failed: %v", err)
+	}
+	if got == nil || got.Headers["Authorization"] != "Bearer t" || got.Connections != 2 || got.SpeedLimit != 1024 {
+		t.Fatalf("overrides = %+v, want the ones the download was added with", got)
+	}
+
+	list, err := LoadMasterList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Downloads) != 1 || list.Downloads[0].Overrides == nil || list.Downloads[0].Overrides.Connections != 2 {
+		t.Errorf("master list lost the overrides: %+v", list.Downloads)
+	}
+
+	if none, err := GetOverrides("missing"); err != nil || none != nil {
+		t.Errorf("GetOverrides(missing) = %+v, %v", none, err)
+	}
+}
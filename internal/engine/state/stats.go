@@ -0,0 +1,116 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// DownloadStats sums up the downloads in the database: those completed and
+// those that failed since a time
+type DownloadStats struct {
+	Since       int64       `json:"since,omitempty"` // Unix time counted from, 0 = all time
+	Completed   int         `json:"completed"`
+	Failed      int         `json:"failed"`        // Downloads now in error
+	TotalBytes  int64       `json:"total_bytes"`   // Size of the completed downloads
+	TotalTimeMs int64       `json:"total_time_ms"` // Time spent downloading them
+	AvgSpeed    float64     `json:"avg_speed"`     // Bytes/sec over that time
+	FailureRate float64     `json:"failure_rate"`  // Failed / (completed + failed), 0-1
+	Hosts       []HostTotal `json:"hosts"`         // Busiest first
+}
+
+// HostTotal is one host's share of DownloadStats
+type HostTotal struct {
+	Host       string `json:"host"`
+	Completed  int    `json:"completed"`
+	Failed     int    `json:"failed"`
+	TotalBytes int64  `json:"total_bytes"`
+}
+
+// LoadDownloadStats sums up the downloads completed, or added and since
+// failed, at or after since (Unix time, 0 for all time), with the topHosts
+// hosts that served the most bytes. Trashed downloads still count: they were
+// downloaded all the same.
+func LoadDownloadStats(since int64, topHosts int) (*DownloadStats, error) {
+	db := getDBHelper()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query(`
+		SELECT url, status, total_size, completed_at, time_taken, created_at
+		FROM downloads
+		WHERE (COALESCE(completed_at, 0) > 0 AND completed_at >= ?)
+			OR (status = 'error' AND COALESCE(created_at, 0) >= ?)
+	`, since, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query download stats: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	stats := &DownloadStats{Since: since, Hosts: []HostTotal{}}
+	hosts := make(map[string]*HostTotal)
+	for rows.Next() {
+		var rawURL string
+		var status sql.NullString
+		var size, completedAt, timeTaken, createdAt sql.NullInt64
+		if err := rows.Scan(&rawURL, &status, &size, &completedAt, &timeTaken, &createdAt); err != nil {
+			return nil, err
+		}
+
+		host := statsHost(openValue(rawURL))
+		h := hosts[host]
+		if h == nil {
+			h = &HostTotal{Host: host}
+			hosts[host] = h
+		}
+		if status.String == "error" {
+			stats.Failed++
+			h.Failed++
+			continue
+		}
+		stats.Completed++
+		stats.TotalBytes += size.Int64
+		stats.TotalTimeMs += timeTaken.Int64
+		h.Completed++
+		h.TotalBytes += size.Int64
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if stats.TotalTimeMs > 0 {
+		stats.AvgSpeed = float64(stats.TotalBytes) / (float64(stats.TotalTimeMs) / 1000)
+	}
+	if n := stats.Completed + stats.Failed; n > 0 {
+		stats.FailureRate = float64(stats.Failed) / float64(n)
+	}
+	for _, h := range hosts {
+		stats.Hosts = append(stats.Hosts, *h)
+	}
+	sort.Slice(stats.Hosts, func(i, j int) bool {
+		a, b := stats.Hosts[i], stats.Hosts[j]
+		if a.TotalBytes != b.TotalBytes {
+			return a.TotalBytes > b.TotalBytes
+		}
+		if a.Completed+a.Failed != b.Completed+b.Failed {
+			return a.Completed+a.Failed > b.Completed+b.Failed
+		}
+		return a.Host < b.Host
+	})
+	if topHosts >= 0 && len(stats.Hosts) > topHosts {
+		stats.Hosts = stats.Hosts[:topHosts]
+	}
+	return stats, nil
+}
+
+// statsHost returns the host a download's URL names, lowercased
+func statsHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return "unknown"
+	}
+	return strings.ToLower(u.Hostname())
+}
@@ -0,0 +1,53 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestLoadDownloadStats(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	now := time.Now().Unix()
+	weekAgo := now - 7*24*3600
+	for _, e := range []types.DownloadEntry{
+		{ID: "a", URL: "https://cdn.example.com/a", Status: "completed", TotalSize: 3000, TimeTaken: 1000, CompletedAt: now, CreatedAt: now},
+		{ID: "b", URL: "https://CDN.example.com/b", Status: "completed", TotalSize: 1000, TimeTaken: 1000, CompletedAt: weekAgo, CreatedAt: weekAgo},
+		{ID: "c", URL: "https://mirror.example.org/c", Status: "completed", TotalSize: 500, TimeTaken: 500, CompletedAt: now, CreatedAt: now},
+		{ID: "d", URL: "https://mirror.example.org/d", Status: "error", CreatedAt: now},
+		{ID: "e", URL: "https://slow.example.net/e", Status: "paused", CreatedAt: now},
+	} {
+		e.DestPath = filepath.Join(tmpDir, e.ID)
+		if err := AddToMasterList(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, err := LoadDownloadStats(0, 10)
+	if err != nil {
+		t.Fatalf("LoadDownloadStats failed: %v", err)
+	}
+	if all.Completed != 3 || all.Failed != 1 || all.TotalBytes != 4500 || all.TotalTimeMs != 2500 {
+		t.Fatalf("all time = %+v", all)
+	}
+	if all.AvgSpeed != 1800 || all.FailureRate != 0.25 {
+		t.Errorf("avg speed %v, failure rate %v", all.AvgSpeed, all.FailureRate)
+	}
+	if len(all.Hosts) != 2 || all.Hosts[0].Host != "cdn.example.com" || all.Hosts[0].TotalBytes != 4000 || all.Hosts[1].Failed != 1 {
+		t.Errorf("hosts = %+v", all.Hosts)
+	}
+
+	recent, err := LoadDownloadStats(now-3600, 1)
+	if err != nil {
+		t.Fatalf("LoadDownloadStats failed: %v", err)
+	}
+	if recent.Completed != 2 || recent.TotalBytes != 3500 || len(recent.Hosts) != 1 {
+		t.Errorf("recent = %+v", recent)
+	}
+}
@@ -0,0 +1,76 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Store opens the state database. Queries are written in SQLite syntax, the
+// only backend there is.
+type Store interface {
+	// Name identifies the backend, e.g. "sqlite".
+	Name() string
+	// Open connects to the backend and tunes the connection pool.
+	Open() (*sql.DB, error)
+}
+
+// NewStore returns the Store for dsn, a SQLite file path. Database URLs are
+// rejected rather than taken as odd file names.
+func NewStore(dsn string) (Store, error) {
+	dsn = strings.TrimSpace(dsn)
+	switch {
+	case dsn == "":
+		return nil, fmt.Errorf("empty state store")
+	case strings.Contains(dsn, "://"):
+		return nil, fmt.Errorf("unsupported state store %q: only SQLite file paths are supported", dsn)
+	default:
+		return &SQLiteStore{Path: dsn}, nil
+	}
+}
+
+// DefaultBusyTimeout is how long a SQLite write waits for another writer's
+// lock when SQLiteStore.BusyTimeout is unset
+const DefaultBusyTimeout = 5 * time.Second
+
+// SQLiteStore keeps state in a local SQLite file
+type SQLiteStore struct {
+	Path        string
+	BusyTimeout time.Duration
+}
+
+func (s *SQLiteStore) Name() string { return "sqlite" }
+
+func (s *SQLiteStore) Open() (*sql.DB, error) {
+	d, err := sql.Open("sqlite", s.dsn())
+	if err != nil {
+		return nil, err
+	}
+
+	// Enable WAL mode for concurrent reader-writer access
+	// (required now that the processing layer's event worker writes from a goroutine)
+	if _, err := d.Exec("PRAGMA journal_mode=WAL"); err != nil {
+		_ = d.Close()
+		return nil, fmt.Errorf("failed to set WAL mode: %w", err)
+	}
+	return d, nil
+}
+
+// dsn carries the per-connection pragmas, so every pooled connection gets
+// them, not just the one that happens to run a PRAGMA statement.
+// Transactions begin IMMEDIATE: a deferred transaction that reads first and
+// then writes can't wait out another writer and fails with SQLITE_BUSY
+// straight away, whatever busy_timeout says.
+func (s *SQLiteStore) dsn() string {
+	timeout := s.BusyTimeout
+	if timeout <= 0 {
+		timeout = DefaultBusyTimeout
+	}
+	q := url.Values{}
+	q.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", timeout.Milliseconds()))
+	q.Add("_pragma", "foreign_keys(1)")
+	q.Set("_txlock", "immediate")
+	return s.Path + "?" + q.Encode()
+}
@@ -0,0 +1,170 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestNewStore(t *testing.T) {
+	tests := []struct {
+		dsn  string
+		want string
+	}{
+		{"/var/lib/surge/surge.db", "sqlite"},
+		{"surge.db", "sqlite"},
+	}
+	for _, tt := range tests {
+		s, err := NewStore(tt.dsn)
+		if err != nil {
+			t.Fatalf("NewStore(%q): %v", tt.dsn, err)
+		}
+		if s.Name() != tt.want {
+			t.Errorf("NewStore(%q) = %s, want %s", tt.dsn, s.Name(), tt.want)
+		}
+	}
+
+	if _, err := NewStore("  "); err == nil {
+		t.Error("expected error for empty DSN")
+	}
+	if _, err := NewStore("postgres://surge@db/surge"); err == nil {
+		t.Error("expected error for a database URL")
+	}
+}
+
+func TestConfigureStore_SQLite(t *testing.T) {
+	CloseDB()
+	defer CloseDB()
+
+	dbPath := filepath.Join(t.TempDir(), "surge.db")
+	s, err := NewStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	ConfigureStore(s)
+
+	if err := AddToMasterList(types.DownloadEntry{ID: "store-1", URL: "https://example.com/a", DestPath: "/tmp/a", Status: "queued"}); err != nil {
+		t.Fatalf("AddToMasterList: %v", err)
+	}
+	if _, err := os.Stat(dbPath); err != nil {
+		t.Fatalf("database file not created: %v", err)
+	}
+	entry, err := GetDownload("store-1")
+	if err != nil || entry == nil {
+		t.Fatalf("GetDownload = %v, %v", entry, err)
+	}
+}
+
+func TestSQLiteStore_ConnectionPragmas(t *testing.T) {
+	s := &SQLiteStore{Path: filepath.Join(t.TempDir(), "surge.db"), BusyTimeout: 7 * time.Second}
+	d, err := s.Open()
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = d.Close() }()
+
+	// Hold several connections at once so the pragmas are checked on more
+	// than the first pooled connection.
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		c, err := d.Conn(ctx)
+		if err != nil {
+			t.Fatalf("Conn: %v", err)
+		}
+		defer func() { _ = c.Close() }()
+
+		var busy, fk int
+		var journal string
+		if err := c.QueryRowContext(ctx, "PRAGMA busy_timeout").Scan(&busy); err != nil {
+			t.Fatalf("busy_timeout: %v", err)
+		}
+		if err := c.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&fk); err != nil {
+			t.Fatalf("foreign_keys: %v", err)
+		}
+		if err := c.QueryRowContext(ctx, "PRAGMA journal_mode").Scan(&journal); err != nil {
+			t.Fatalf("journal_mode: %v", err)
+		}
+		if busy != 7000 {
+			t.Errorf("conn %d: busy_timeout = %d, want 7000", i, busy)
+		}
+		if fk != 1 {
+			t.Errorf("conn %d: foreign_keys = %d, want 1", i, fk)
+		}
+		if journal != "wal" {
+			t.Errorf("conn %d: journal_mode = %q, want wal", i, journal)
+		}
+	}
+}
+
+func TestSQLiteStore_ForeignKeysCascade(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	id := uuid.New().String()
+	st := &types.DownloadState{
+		ID: id, URL: "https://example.com/fk", DestPath: filepath.Join(tmpDir, "fk"),
+		TotalSize: 100, Tasks: []types.Task{{Offset: 0, Length: 100}},
+	}
+	if err := SaveStateWithOptions(st.URL, st.DestPath, st, SaveStateOptions{SkipFileHash: true}); err != nil {
+		t.Fatalf("SaveState: %v", err)
+	}
+
+	d, _ := GetDB()
+	if _, err := d.Exec("DELETE FROM downloads WHERE id = ?", id); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	var n int
+	if err := d.QueryRow("SELECT COUNT(*) FROM tasks WHERE download_id = ?", id).Scan(&n); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if n != 0 {
+		t.Errorf("%d tasks left after deleting their download", n)
+	}
+}
+
+// Several active downloads checkpoint at once; none of them may fail with
+// SQLITE_BUSY.
+func TestSQLiteStore_ConcurrentSaveState(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	const downloads, saves = 8, 20
+	var wg sync.WaitGroup
+	errs := make(chan error, downloads*saves)
+	for i := 0; i < downloads; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			st := &types.DownloadState{
+				ID:        uuid.New().String(),
+				URL:       fmt.Sprintf("https://example.com/%d", i),
+				DestPath:  filepath.Join(tmpDir, fmt.Sprintf("file-%d", i)),
+				TotalSize: 1 << 20,
+			}
+			for j := 0; j < saves; j++ {
+				st.Downloaded = int64(j) * 1024
+				st.Tasks = []types.Task{{Offset: st.Downloaded, Length: st.TotalSize - st.Downloaded}}
+				if err := SaveStateWithOptions(st.URL, st.DestPath, st, SaveStateOptions{SkipFileHash: true}); err != nil {
+					errs <- err
+				}
+				if _, err := LoadState(st.URL, st.DestPath); err != nil {
+					errs <- err
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("concurrent save: %v", err)
+	}
+}
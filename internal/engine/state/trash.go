@@ -0,0 +1,108 @@
+package state
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// ErrNotTrashed is returned when restoring a download that is not in the trash
+var ErrNotTrashed = errors.New("download is not in the trash")
+
+// TrashDownload moves download id to the trash. Its row, resume state and
+// files are kept until RestoreTrashed brings it back or PurgeTrash expires it.
+func TrashDownload(id string) error {
+	db := getDBHelper()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	result, err := db.Exec("UPDATE downloads SET status = 'trashed', trashed_at = ? WHERE id = ?", time.Now().Unix(), id)
+	if err != nil {
+		return fmt.Errorf("failed to trash download: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return types.ErrNotFound
+	}
+	return nil
+}
+
+// RestoreTrashed takes download id out of the trash and returns it as it is
+// now. A download that had completed is completed again; any other comes
+// back paused, waiting for the user to resume it.
+func RestoreTrashed(id string) (*types.DownloadEntry, error) {
+	err := withTx(func(tx *sql.Tx) error {
+		var status sql.NullString
+		var completedAt sql.NullInt64
+		err := tx.QueryRow("SELECT status, completed_at FROM downloads WHERE id = ?", id).Scan(&status, &completedAt)
+		if err == sql.ErrNoRows {
+			return types.ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to query download: %w", err)
+		}
+		if status.String != "trashed" {
+			return ErrNotTrashed
+		}
+
+		if completedAt.Int64 > 0 {
+			_, err = tx.Exec("UPDATE downloads SET status = 'completed', trashed_at = NULL WHERE id = ?", id)
+		} else {
+			_, err = tx.Exec("UPDATE downloads SET status = 'paused', pause_reason = ?, trashed_at = NULL WHERE id = ?", types.PauseUser, id)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to restore download: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return GetDownload(id)
+}
+
+// LoadTrash returns the downloads in the trash, most recently deleted first
+func LoadTrash() ([]types.DownloadEntry, error) {
+	return queryTrash("status = 'trashed' ORDER BY COALESCE(trashed_at, 0) DESC, id")
+}
+
+// PurgeTrash deletes the rows and resume state of downloads trashed before
+// cutoff and returns them, so the caller can remove their working files.
+func PurgeTrash(cutoff time.Time) ([]types.DownloadEntry, error) {
+	expired, err := queryTrash("status = 'trashed' AND COALESCE(trashed_at, 0) < ? ORDER BY id", cutoff.Unix())
+	if err != nil {
+		return nil, err
+	}
+	for i, e := range expired {
+		if err := removeDownloadAndTasks(e.ID); err != nil {
+			return expired[:i], fmt.Errorf("failed to purge %s from the trash: %w", e.ID, err)
+		}
+	}
+	return expired, nil
+}
+
+func queryTrash(where string, args ...any) ([]types.DownloadEntry, error) {
+	db := getDBHelper()
+	if db == nil {
+		return nil, nil
+	}
+
+	rows, err := db.Query("SELECT "+masterListColumns+" FROM downloads WHERE "+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trash: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var trashed []types.DownloadEntry
+	for rows.Next() {
+		e, err := scanMasterListRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		trashed = append(trashed, e)
+	}
+	return trashed, rows.Err()
+}
@@ -0,0 +1,97 @@
+package state
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestTrashAndRestore(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	for _, e := range []types.DownloadEntry{
+		{ID: "partial", URL: "https://example.com/partial", DestPath: filepath.Join(tmpDir, "partial.bin"), Status: "paused", Downloaded: 10},
+		{ID: "done", URL: "https://example.com/done", DestPath: filepath.Join(tmpDir, "done.bin"), Status: "completed", Downloaded: 10, CompletedAt: time.Now().Unix()},
+	} {
+		if err := AddToMasterList(e); err != nil {
+			t.Fatal(err)
+		}
+		if err := TrashDownload(e.ID); err != nil {
+			t.Fatalf("TrashDownload(%s) failed: %v", e.ID, err)
+		}
+	}
+	if err := TrashDownload("missing"); !errors.Is(err, types.ErrNotFound) {
+		t.Errorf("TrashDownload(missing) = %v, want ErrNotFound", err)
+	}
+
+	trash, err := LoadTrash()
+	if err != nil {
+		t.Fatalf("LoadTrash failed: %v", err)
+	}
+	if len(trash) != 2 || trash[0].TrashedAt == 0 {
+		t.Fatalf("trash = %+v, want 2 entries with trashed_at set", trash)
+	}
+	page, err := ListDownloadsPage(PageQuery{})
+	if err != nil {
+		t.Fatalf("ListDownloadsPage failed: %v", err)
+	}
+	if len(page) != 0 {
+		t.Errorf("trashed downloads should not be listed, got %+v", page)
+	}
+
+	partial, err := RestoreTrashed("partial")
+	if err != nil {
+		t.Fatalf("RestoreTrashed(partial) failed: %v", err)
+	}
+	if partial.Status != "paused" || partial.PauseReason != types.PauseUser || partial.TrashedAt != 0 {
+		t.Errorf("restored partial = %+v, want paused by user", partial)
+	}
+	done, err := RestoreTrashed("done")
+	if err != nil {
+		t.Fatalf("RestoreTrashed(done) failed: %v", err)
+	}
+	if done.Status != "completed" {
+		t.Errorf("restored done status = %s, want completed", done.Status)
+	}
+	if _, err := RestoreTrashed("done"); !errors.Is(err, ErrNotTrashed) {
+		t.Errorf("restoring twice = %v, want ErrNotTrashed", err)
+	}
+}
+
+func TestPurgeTrash(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	for _, id := range []string{"old", "recent"} {
+		if err := AddToMasterList(types.DownloadEntry{ID: id, URL: "https://example.com/" + id, DestPath: filepath.Join(tmpDir, id), Status: "paused"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := TrashDownload(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := getDBHelper().Exec("UPDATE downloads SET trashed_at = ? WHERE id = 'old'", time.Now().Add(-48*time.Hour).Unix()); err != nil {
+		t.Fatal(err)
+	}
+
+	purged, err := PurgeTrash(time.Now().Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("PurgeTrash failed: %v", err)
+	}
+	if len(purged) != 1 || purged[0].ID != "old" {
+		t.Fatalf("purged = %+v, want only old", purged)
+	}
+	if dl, _ := GetDownload("old"); dl != nil {
+		t.Error("old download should be purged")
+	}
+	if dl, _ := GetDownload("recent"); dl == nil || dl.Status != "trashed" {
+		t.Errorf("recent download should stay in the trash, got %+v", dl)
+	}
+}
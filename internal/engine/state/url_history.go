@@ -0,0 +1,125 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// URL history statuses
+const (
+	URLHistoryAdded    = "added"
+	URLHistoryRejected = "rejected"
+	URLHistoryFailed   = "failed"
+)
+
+// maxURLHistory bounds the number of remembered URLs
+const maxURLHistory = 200
+
+// RecordURLHistory upserts a URL into the history with its latest outcome
+func RecordURLHistory(url string, status string, errMsg string) error {
+	url = strings.TrimSpace(url)
+	if url == "" {
+		return nil
+	}
+
+	return withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			INSERT INTO url_history (url, status, error, updated_at)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(url) DO UPDATE SET
+				status = excluded.status,
+				error = excluded.error,
+				updated_at = excluded.updated_at
+		`, sealValue(url), status, sealValue(errMsg), time.Now().UnixNano())
+		if err != nil {
+			return fmt.Errorf("failed to record url history: %w", err)
+		}
+
+		// Trim oldest entries beyond the cap
+		_, err = tx.Exec(`
+			DELETE FROM url_history WHERE url NOT IN (
+				SELECT url FROM url_history ORDER BY updated_at DESC LIMIT ?
+			)
+		`, maxURLHistory)
+		if err != nil {
+			return fmt.Errorf("failed to trim url history: %w", err)
+		}
+		return nil
+	})
+}
+
+// LoadURLHistory returns up to limit URLs, most recently used first.
+// A limit <= 0 returns the whole history.
+func LoadURLHistory(limit int) ([]types.URLHistoryEntry, error) {
+	db := getDBHelper()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	if limit <= 0 {
+		limit = maxURLHistory
+	}
+
+	rows, err := db.Query(`
+		SELECT url, status, error, updated_at
+		FROM url_history
+		ORDER BY updated_at DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query url history: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var entries []types.URLHistoryEntry
+	for rows.Next() {
+		var e types.URLHistoryEntry
+		var status, errMsg sql.NullString
+		var updatedAt sql.NullInt64
+		if err := rows.Scan(&e.URL, &status, &errMsg, &updatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan url history: %w", err)
+		}
+		e.URL = openValue(e.URL)
+		e.Status = status.String
+		e.Error = openValue(errMsg.String)
+		e.UpdatedAt = time.Unix(0, updatedAt.Int64).Unix()
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// GetLastFailedURL returns the most recent rejected or failed URL, or nil if none
+func GetLastFailedURL() (*types.URLHistoryEntry, error) {
+	db := getDBHelper()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	var e types.URLHistoryEntry
+	var status, errMsg sql.NullString
+	var updatedAt sql.NullInt64
+	err := db.QueryRow(`
+		SELECT url, status, error, updated_at
+		FROM url_history
+		WHERE status IN (?, ?)
+		ORDER BY updated_at DESC
+		LIMIT 1
+	`, URLHistoryRejected, URLHistoryFailed).Scan(&e.URL, &status, &errMsg, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query last failed url: %w", err)
+	}
+
+	e.URL = openValue(e.URL)
+	e.Status = status.String
+	e.Error = openValue(errMsg.String)
+	e.UpdatedAt = time.Unix(0, updatedAt.Int64).Unix()
+	return &e, nil
+}
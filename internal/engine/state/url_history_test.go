@@ -0,0 +1,109 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestURLHistory_RecordAndLoad(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	if err := RecordURLHistory("https://example.com/a.zip", URLHistoryAdded, ""); err != nil {
+		t.Fatalf("RecordURLHistory failed: %v", err)
+	}
+	if err := RecordURLHistory("https://example.com/b.zip", URLHistoryRejected, "probe failed"); err != nil {
+		t.Fatalf("RecordURLHistory failed: %v", err)
+	}
+	// Re-recording moves the URL to the front and updates its status
+	if err := RecordURLHistory("https://example.com/a.zip", URLHistoryFailed, "connection reset"); err != nil {
+		t.Fatalf("RecordURLHistory failed: %v", err)
+	}
+
+	entries, err := LoadURLHistory(0)
+	if err != nil {
+		t.Fatalf("LoadURLHistory failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].URL != "https://example.com/a.zip" {
+		t.Errorf("expected most recent URL first, got %s", entries[0].URL)
+	}
+	if entries[0].Status != URLHistoryFailed || entries[0].Error != "connection reset" {
+		t.Errorf("unexpected entry: %+v", entries[0])
+	}
+
+	limited, err := LoadURLHistory(1)
+	if err != nil {
+		t.Fatalf("LoadURLHistory failed: %v", err)
+	}
+	if len(limited) != 1 {
+		t.Errorf("expected limit to apply, got %d entries", len(limited))
+	}
+}
+
+func TestURLHistory_IgnoresEmptyURL(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	if err := RecordURLHistory("   ", URLHistoryAdded, ""); err != nil {
+		t.Fatalf("RecordURLHistory failed: %v", err)
+	}
+	entries, err := LoadURLHistory(0)
+	if err != nil {
+		t.Fatalf("LoadURLHistory failed: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected no entries, got %d", len(entries))
+	}
+}
+
+func TestURLHistory_TrimsToCap(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	for i := 0; i < maxURLHistory+10; i++ {
+		if err := RecordURLHistory(fmt.Sprintf("https://example.com/%d", i), URLHistoryAdded, ""); err != nil {
+			t.Fatalf("RecordURLHistory failed: %v", err)
+		}
+	}
+
+	entries, err := LoadURLHistory(0)
+	if err != nil {
+		t.Fatalf("LoadURLHistory failed: %v", err)
+	}
+	if len(entries) != maxURLHistory {
+		t.Errorf("expected %d entries, got %d", maxURLHistory, len(entries))
+	}
+}
+
+func TestGetLastFailedURL(t *testing.T) {
+	tmpDir := setupTestDB(t)
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer CloseDB()
+
+	last, err := GetLastFailedURL()
+	if err != nil {
+		t.Fatalf("GetLastFailedURL failed: %v", err)
+	}
+	if last != nil {
+		t.Fatalf("expected nil with empty history, got %+v", last)
+	}
+
+	_ = RecordURLHistory("https://example.com/old-fail", URLHistoryFailed, "boom")
+	_ = RecordURLHistory("https://example.com/rejected", URLHistoryRejected, "bad url")
+	_ = RecordURLHistory("https://example.com/ok", URLHistoryAdded, "")
+
+	last, err = GetLastFailedURL()
+	if err != nil {
+		t.Fatalf("GetLastFailedURL failed: %v", err)
+	}
+	if last == nil || last.URL != "https://example.com/rejected" {
+		t.Errorf("expected most recent rejected URL, got %+v", last)
+	}
+}
@@ -0,0 +1,59 @@
+package types
+
+import "strings"
+
+// WorkerStatus is the range one connection of a running download is fetching
+type WorkerStatus struct {
+	Worker int     `json:"worker"`
+	Start  int64   `json:"start"`  // First byte of its range
+	Offset int64   `json:"offset"` // Next byte it writes
+	End    int64   `json:"end"`    // Byte its range stops before
+	Speed  float64 `json:"speed"`  // Bytes/sec, smoothed
+	Mirror string  `json:"mirror"` // URL it is fetching from
+}
+
+// ChunkMap is the segment view of one download: the state of each chunk of
+// the file and, while it runs, what each worker is fetching
+type ChunkMap struct {
+	ID            string         `json:"id"`
+	TotalSize     int64          `json:"total_size"`
+	ChunkSize     int64          `json:"chunk_size"`               // Bytes per chunk, the last may be shorter
+	Chunks        string         `json:"chunks"`                   // One digit per chunk: 0 pending, 1 downloading, 2 completed
+	ChunkProgress []int64        `json:"chunk_progress,omitempty"` // Bytes done per chunk, while running
+	Workers       []WorkerStatus `json:"workers"`
+	Mirrors       []MirrorStatus `json:"mirrors,omitempty"`
+}
+
+// FormatChunks renders the first width chunks of a 2-bit chunk bitmap as the
+// digits of ChunkMap.Chunks
+func FormatChunks(bitmap []byte, width int) string {
+	width = min(width, len(bitmap)*4)
+	var b strings.Builder
+	b.Grow(width)
+	for i := 0; i < width; i++ {
+		status := ChunkStatus((bitmap[i/4] >> ((i % 4) * 2)) & 3)
+		b.WriteByte('0' + byte(status))
+	}
+	return b.String()
+}
+
+// GetChunkMap returns the chunk map of the download ps tracks
+func (ps *ProgressState) GetChunkMap() *ChunkMap {
+	bitmap, width, total, chunkSize, progress := ps.GetBitmap()
+	if total == 0 {
+		total = ps.GetTotalSize()
+	}
+	workers := ps.GetWorkers()
+	if workers == nil {
+		workers = []WorkerStatus{}
+	}
+	return &ChunkMap{
+		ID:            ps.ID,
+		TotalSize:     total,
+		ChunkSize:     chunkSize,
+		Chunks:        FormatChunks(bitmap, width),
+		ChunkProgress: progress,
+		Workers:       workers,
+		Mirrors:       ps.GetMirrors(),
+	}
+}
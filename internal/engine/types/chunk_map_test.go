@@ -0,0 +1,38 @@
+package types
+
+import "testing"
+
+func TestFormatChunks(t *testing.T) {
+	// Chunks 0-3 in the first byte, two bits each from the low end
+	bitmap := []byte{byte(ChunkCompleted) | byte(ChunkDownloading)<<2 | byte(ChunkCompleted)<<6, byte(ChunkDownloading)}
+	if got := FormatChunks(bitmap, 5); got != "21021" {
+		t.Errorf("FormatChunks = %q, want %q", got, "21021")
+	}
+	if got := FormatChunks(bitmap, 100); len(got) != 8 {
+		t.Errorf("FormatChunks past the bitmap = %q, want 8 chunks", got)
+	}
+	if got := FormatChunks(nil, 3); got != "" {
+		t.Errorf("FormatChunks(nil) = %q", got)
+	}
+}
+
+func TestProgressState_GetChunkMap(t *testing.T) {
+	ps := NewProgressState("map-id", 4*MB)
+	ps.InitBitmap(4*MB, MB)
+	ps.UpdateChunkStatus(0, MB, ChunkCompleted)
+	ps.SetMirrors([]MirrorStatus{{URL: "https://a", Active: true}})
+
+	m := ps.GetChunkMap()
+	if m.ID != "map-id" || m.TotalSize != 4*MB || m.ChunkSize != MB {
+		t.Fatalf("map = %+v", m)
+	}
+	if m.Chunks != "2000" {
+		t.Errorf("chunks = %q, want 2000", m.Chunks)
+	}
+	if m.Workers == nil || len(m.Workers) != 0 {
+		t.Errorf("workers = %#v, want an empty list while none run", m.Workers)
+	}
+	if len(m.Mirrors) != 1 || m.Mirrors[0].URL != "https://a" {
+		t.Errorf("mirrors = %+v", m.Mirrors)
+	}
+}
@@ -40,6 +40,7 @@ const (
 	DialTimeout                  = 10 * time.Second
 	KeepAliveDuration            = 30 * time.Second
 	ProbeTimeout                 = 30 * time.Second
+	MirrorProbeTimeout           = 5 * time.Second // Whole budget for checking a mirror before download start
 )
 
 // Channel buffer sizes
@@ -57,13 +58,18 @@ type DownloadConfig struct {
 	IsResume           bool // True if this is explicitly a resume, not a fresh download
 	ProgressCh         chan<- any
 	State              *ProgressState
-	SavedState         *DownloadState    // Pre-loaded state for resume optimization
-	Runtime            *RuntimeConfig    // Dynamic settings from user config
-	Mirrors            []string          // List of mirror URLs (including primary)
-	Headers            map[string]string // Custom HTTP headers to include in download requests
-	IsExplicitCategory bool              // Used to override category routing from TUI
-	TotalSize          int64             // Total size in bytes of the required download
-	SupportsRange      bool              // Indicates whether the server supports range requests for concurrency
+	SavedState         *DownloadState     // Pre-loaded state for resume optimization
+	Runtime            *RuntimeConfig     // Dynamic settings from user config
+	Mirrors            []string           // List of mirror URLs (including primary)
+	Headers            map[string]string  // Custom HTTP headers to include in download requests
+	Tags               []string           // User labels, normalized (see utils.NormalizeTags)
+	Category           string             // Category name; its connection limit is already applied to Runtime
+	IsExplicitCategory bool               // Used to override category routing from TUI
+	TotalSize          int64              // Total size in bytes of the required download
+	SupportsRange      bool               // Indicates whether the server supports range requests for concurrency
+	Probe              *CachedProbe       // Persisted probe metadata; lets a resume skip re-probing
+	TraceID            string             // Correlates this download's logs, events and spans across restarts
+	Overrides          *DownloadOverrides // What the download was added with beyond settings; already applied
 }
 
 // RuntimeConfig holds dynamic settings that can override defaults
@@ -80,6 +86,21 @@ type RuntimeConfig struct {
 	SlowWorkerGracePeriod time.Duration
 	StallTimeout          time.Duration
 	SpeedEmaAlpha         float64
+	GlobalRateLimit       int64         // Bytes/sec shared by all downloads, 0 = unlimited
+	SpeedLimit            int64         // Bytes/sec for this download alone, 0 = unlimited
+	RateLimitExemptHosts  []string      // Host patterns never throttled
+	KeepCompressed        bool          // Save gzip-encoded bodies as received instead of decoding
+	PrewarmConnections    bool          // Resolve and handshake with this download's host while it waits in the queue
+	MaxBufferMemory       int64         // Bytes of worker buffers shared by all downloads, 0 = unlimited
+	ReverifyAfter         time.Duration // Resumes paused longer than this re-check the remote file, 0 = never
+	DensePreallocation    bool          // Reserve the whole working file up front instead of leaving it sparse
+	RelayPeers            []RelayPeer   // Peer daemons that fetch ranges as extra mirrors (coordinator mode)
+}
+
+// RelayPeer is a remote daemon that fetches ranges on this instance's behalf
+type RelayPeer struct {
+	URL   string
+	Token string
 }
 
 // GetUserAgent returns the configured user agent or the default
@@ -165,3 +186,36 @@ func (r *RuntimeConfig) GetSpeedEmaAlpha() float64 {
 	}
 	return r.SpeedEmaAlpha
 }
+
+// GetGlobalRateLimit returns the shared speed limit in bytes/sec (0 = unlimited)
+func (r *RuntimeConfig) GetGlobalRateLimit() int64 {
+	if r == nil || r.GlobalRateLimit <= 0 {
+		return 0
+	}
+	return r.GlobalRateLimit
+}
+
+// GetSpeedLimit returns this download's own speed limit in bytes/sec (0 = unlimited)
+func (r *RuntimeConfig) GetSpeedLimit() int64 {
+	if r == nil || r.SpeedLimit <= 0 {
+		return 0
+	}
+	return r.SpeedLimit
+}
+
+// GetMaxBufferMemory returns the shared worker buffer budget in bytes (0 = unlimited)
+func (r *RuntimeConfig) GetMaxBufferMemory() int64 {
+	if r == nil || r.MaxBufferMemory <= 0 {
+		return 0
+	}
+	return r.MaxBufferMemory
+}
+
+// GetReverifyAfter returns how long a download may stay paused before a
+// resume re-checks the remote file (0 = never)
+func (r *RuntimeConfig) GetReverifyAfter() time.Duration {
+	if r == nil || r.ReverifyAfter <= 0 {
+		return 0
+	}
+	return r.ReverifyAfter
+}
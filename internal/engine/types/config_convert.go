@@ -16,5 +16,24 @@ func ConvertRuntimeConfig(rc *config.RuntimeConfig) *RuntimeConfig {
 		SlowWorkerGracePeriod: rc.SlowWorkerGracePeriod,
 		StallTimeout:          rc.StallTimeout,
 		SpeedEmaAlpha:         rc.SpeedEmaAlpha,
+		GlobalRateLimit:       rc.GlobalRateLimit,
+		RateLimitExemptHosts:  append([]string(nil), rc.RateLimitExemptHosts...),
+		KeepCompressed:        rc.KeepCompressed,
+		PrewarmConnections:    rc.PrewarmConnections,
+		MaxBufferMemory:       rc.MaxBufferMemory,
+		ReverifyAfter:         rc.ReverifyAfter,
+		DensePreallocation:    rc.DensePreallocation,
+		RelayPeers:            convertRelayPeers(rc.RelayPeers),
 	}
 }
+
+func convertRelayPeers(peers []config.PeerDaemon) []RelayPeer {
+	if len(peers) == 0 {
+		return nil
+	}
+	out := make([]RelayPeer, len(peers))
+	for i, p := range peers {
+		out[i] = RelayPeer{URL: p.URL, Token: p.Token}
+	}
+	return out
+}
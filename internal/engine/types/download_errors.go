@@ -0,0 +1,29 @@
+package types
+
+import "errors"
+
+// DownloadFailure is one entry in a download's error history: a failed
+// attempt at one of its tasks, or the error that stopped it
+type DownloadFailure struct {
+	At      int64  `json:"at"`               // Unix time
+	Kind    string `json:"kind"`             // One of WorkerErrorKinds, or "fatal"
+	Status  int    `json:"status,omitempty"` // HTTP status, 0 if the server never answered
+	Mirror  string `json:"mirror,omitempty"` // URL the attempt was made against
+	Offset  int64  `json:"offset"`           // Start of the task that failed
+	Message string `json:"message,omitempty"`
+}
+
+// FailureFatal is the kind of the error that stopped a download
+const FailureFatal = "fatal"
+
+// MaxDownloadFailures is how many failures are kept per download, newest last
+const MaxDownloadFailures = 50
+
+// HTTPStatus returns the response status carried by err, or 0 if it has none
+func HTTPStatus(err error) int {
+	var status interface{ HTTPStatus() int }
+	if errors.As(err, &status) {
+		return status.HTTPStatus()
+	}
+	return 0
+}
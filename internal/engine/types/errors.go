@@ -4,5 +4,8 @@ import "errors"
 
 // Common errors
 var (
-	ErrPaused = errors.New("download paused")
+	ErrPaused   = errors.New("download paused")
+	ErrNotFound = errors.New("download not found")
+
+	ErrNotCompleted = errors.New("download is not completed")
 )
@@ -7,6 +7,7 @@ type Task struct {
 	Offset          int64         `json:"offset"`
 	Length          int64         `json:"length"`
 	SharedMaxOffset *atomic.Int64 `json:"-"` // Used to deduplicate byte counting in hedged requests
+	AvoidMirror     string        `json:"-"` // Mirror the raced original is using; a hedged copy prefers another
 }
 
 // DownloadState represents persisted download state for resume
@@ -39,13 +40,73 @@ type DownloadEntry struct {
 	URL         string   `json:"url"`
 	DestPath    string   `json:"dest_path"`
 	Filename    string   `json:"filename"`
-	Status      string   `json:"status"`       // "paused", "completed", "error"
-	TotalSize   int64    `json:"total_size"`   // File size in bytes
-	Downloaded  int64    `json:"downloaded"`   // Bytes downloaded
-	CompletedAt int64    `json:"completed_at"` // Unix timestamp when completed
-	TimeTaken   int64    `json:"time_taken"`   // Duration in milliseconds (for completed)
-	AvgSpeed    float64  `json:"avg_speed"`    // Average speed in bytes/sec (for completed)
+	Status      string   `json:"status"`                 // "paused", "completed", "error"
+	PauseReason string   `json:"pause_reason,omitempty"` // Why a paused download stopped, one of the Pause* values
+	TotalSize   int64    `json:"total_size"`             // File size in bytes
+	Downloaded  int64    `json:"downloaded"`             // Bytes downloaded
+	CompletedAt int64    `json:"completed_at"`           // Unix timestamp when completed
+	TimeTaken   int64    `json:"time_taken"`             // Duration in milliseconds (for completed)
+	AvgSpeed    float64  `json:"avg_speed"`              // Average speed in bytes/sec (for completed)
 	Mirrors     []string `json:"mirrors,omitempty"`
+	CreatedAt   int64    `json:"created_at,omitempty"` // Unix timestamp when added
+	Tags        []string `json:"tags,omitempty"`
+	Category    string   `json:"category,omitempty"` // Category the download was sorted into
+	TraceID     string   `json:"trace_id,omitempty"` // Correlates logs, events and spans for this download
+
+	Note     string            `json:"note,omitempty"`     // Free-text note set by the user
+	Metadata map[string]string `json:"metadata,omitempty"` // User-defined key/value pairs
+
+	Checksum     string `json:"checksum,omitempty"`      // SHA-256 of the completed file
+	VerifyStatus string `json:"verify_status,omitempty"` // Outcome of the last verification, one of the Verify* values
+	VerifiedAt   int64  `json:"verified_at,omitempty"`   // Unix timestamp of the last verification
+	TrashedAt    int64  `json:"trashed_at,omitempty"`    // Unix timestamp the download was deleted into the trash
+
+	QueuePosition int64 `json:"queue_position,omitempty"` // Where it waits in the queue, lowest starts first
+
+	Response *CachedProbe `json:"response,omitempty"` // What the server answered when probed, nil if never probed
+
+	Overrides *DownloadOverrides `json:"-"` // Add-time settings reapplied on resume; never sent to clients, headers may hold credentials
+}
+
+// URLHistoryEntry is a recently added or attempted URL
+type URLHistoryEntry struct {
+	URL       string `json:"url"`
+	Status    string `json:"status"`          // "added", "rejected", "failed"
+	Error     string `json:"error,omitempty"` // Last failure reason, if any
+	UpdatedAt int64  `json:"updated_at"`      // Unix timestamp of last use
+}
+
+// HostStats holds what we have learned about a host across downloads
+type HostStats struct {
+	Host           string `json:"host"`
+	MaxConnections int    `json:"max_connections"` // Inferred connection cap, 0 = unknown
+	LimitHint      string `json:"limit_hint"`      // Header the cap was inferred from
+	UpdatedAt      int64  `json:"updated_at"`      // Unix timestamp of last update
+}
+
+// HostHeaderProfile is the header set a browser used successfully (or not)
+// against a host. Only non-credential values are kept; credentials are
+// recorded by name so the fingerprint shows they were involved.
+type HostHeaderProfile struct {
+	Host      string            `json:"host"`
+	Headers   map[string]string `json:"headers,omitempty"`  // Replayable header values
+	Redacted  []string          `json:"redacted,omitempty"` // Names of headers whose values were dropped
+	Worked    bool              `json:"worked"`             // false when the host answered 403
+	UpdatedAt int64             `json:"updated_at"`         // Unix timestamp of last update
+}
+
+// CachedProbe is the server metadata learned when a download was first probed,
+// kept so a resume can skip probing the origin again and to show what the
+// server answered when debugging one
+type CachedProbe struct {
+	FileSize      int64  `json:"file_size"`
+	SupportsRange bool   `json:"supports_range"`
+	ETag          string `json:"etag,omitempty"`
+	FinalURL      string `json:"final_url,omitempty"` // URL after following redirects
+	ContentType   string `json:"content_type,omitempty"`
+	Server        string `json:"server,omitempty"`        // Server response header
+	LastModified  string `json:"last_modified,omitempty"` // Last-Modified response header, as sent
+	ProbedAt      int64  `json:"probed_at"`               // Unix timestamp of the probe
 }
 
 // MasterList holds all tracked downloads
@@ -61,13 +122,51 @@ type DownloadStatus struct {
 	DestPath    string  `json:"dest_path,omitempty"` // Full absolute path to file
 	TotalSize   int64   `json:"total_size"`
 	Downloaded  int64   `json:"downloaded"`
-	Progress    float64 `json:"progress"` // Percentage 0-100
-	Speed       float64 `json:"speed"`    // MB/s
-	Status      string  `json:"status"`   // "queued", "paused", "downloading", "completed", "error"
+	Progress    float64 `json:"progress"`               // Percentage 0-100
+	Speed       float64 `json:"speed"`                  // MB/s
+	Status      string  `json:"status"`                 // "queued", "paused", "downloading", "completed", "error"
+	PauseReason string  `json:"pause_reason,omitempty"` // Why it is paused, one of the Pause* values
 	Error       string  `json:"error,omitempty"`
 	ETA         int64   `json:"eta"`         // Estimated seconds remaining
 	Connections int     `json:"connections"` // Active connections
 	AddedAt     int64   `json:"added_at"`    // Unix timestamp when added
 	TimeTaken   int64   `json:"time_taken"`  // Duration in milliseconds (completed only)
 	AvgSpeed    float64 `json:"avg_speed"`   // Average speed in bytes/sec (completed only)
+
+	Phase         string  `json:"phase,omitempty"`          // One of the Phase* stages while active or complete
+	PhaseProgress float64 `json:"phase_progress,omitempty"` // Percentage 0-100 within a post-download phase
+
+	Tags     []string `json:"tags,omitempty"`
+	Category string   `json:"category,omitempty"`
+	TraceID  string   `json:"trace_id,omitempty"` // Matches the download's log lines, events and spans
+
+	QueuePosition int64 `json:"queue_position,omitempty"` // Where it waits in the queue, lowest starts first
+
+	Note     string            `json:"note,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	Response *CachedProbe `json:"response,omitempty"` // What the server answered when probed
+
+	Errors []DownloadFailure `json:"errors,omitempty"` // Error history, oldest first; only filled in by GetStatus
+}
+
+// Download phases in the order they run. Everything after PhaseDownloading
+// happens once all bytes are in, so Progress alone reads 100% during them.
+const (
+	PhaseDownloading = "downloading"
+	PhaseVerifying   = "verifying"
+	PhaseExtracting  = "extracting"
+	PhaseMoving      = "moving"
+	PhaseScripting   = "scripting" // A category's script step
+	PhaseNotifying   = "notifying" // A category's notify step
+	PhaseComplete    = "complete"
+)
+
+// IsFinalizingPhase reports whether phase is a post-download stage still running
+func IsFinalizingPhase(phase string) bool {
+	switch phase {
+	case PhaseVerifying, PhaseExtracting, PhaseMoving, PhaseScripting, PhaseNotifying:
+		return true
+	}
+	return false
 }
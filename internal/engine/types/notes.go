@@ -0,0 +1,66 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Limits on what a NoteUpdate may set
+const (
+	MaxNoteLength          = 4096
+	MaxMetadataKeys        = 32
+	MaxMetadataKeyLength   = 64
+	MaxMetadataValueLength = 1024
+)
+
+// NoteUpdate changes a download's note and metadata. A nil Note leaves the
+// note as is; Unset keys are removed before Metadata is merged in.
+type NoteUpdate struct {
+	Note     *string           `json:"note,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Unset    []string          `json:"unset,omitempty"`
+}
+
+// Validate checks the update against the size limits
+func (u NoteUpdate) Validate() error {
+	if u.Note != nil && len(*u.Note) > MaxNoteLength {
+		return fmt.Errorf("note is longer than %d bytes", MaxNoteLength)
+	}
+	if len(u.Metadata) > MaxMetadataKeys {
+		return fmt.Errorf("more than %d metadata keys", MaxMetadataKeys)
+	}
+	for k, v := range u.Metadata {
+		if strings.TrimSpace(k) == "" {
+			return fmt.Errorf("metadata key cannot be empty")
+		}
+		if len(k) > MaxMetadataKeyLength {
+			return fmt.Errorf("metadata key %q is longer than %d bytes", k, MaxMetadataKeyLength)
+		}
+		if len(v) > MaxMetadataValueLength {
+			return fmt.Errorf("metadata value for %q is longer than %d bytes", k, MaxMetadataValueLength)
+		}
+	}
+	return nil
+}
+
+// Apply returns the note and metadata that result from applying u to the
+// current ones. The current map is not modified.
+func (u NoteUpdate) Apply(note string, metadata map[string]string) (string, map[string]string) {
+	if u.Note != nil {
+		note = strings.TrimSpace(*u.Note)
+	}
+	merged := make(map[string]string, len(metadata)+len(u.Metadata))
+	for k, v := range metadata {
+		merged[k] = v
+	}
+	for _, k := range u.Unset {
+		delete(merged, strings.TrimSpace(k))
+	}
+	for k, v := range u.Metadata {
+		merged[strings.TrimSpace(k)] = v
+	}
+	if len(merged) == 0 {
+		merged = nil
+	}
+	return note, merged
+}
@@ -0,0 +1,48 @@
+package types
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNoteUpdate_Validate(t *testing.T) {
+	long := strings.Repeat("x", MaxNoteLength+1)
+	tests := []struct {
+		name    string
+		update  NoteUpdate
+		wantErr bool
+	}{
+		{"empty", NoteUpdate{}, false},
+		{"note and metadata", NoteUpdate{Note: new(string), Metadata: map[string]string{"k": "v"}}, false},
+		{"note too long", NoteUpdate{Note: &long}, true},
+		{"blank key", NoteUpdate{Metadata: map[string]string{" ": "v"}}, true},
+		{"value too long", NoteUpdate{Metadata: map[string]string{"k": strings.Repeat("v", MaxMetadataValueLength+1)}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.update.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNoteUpdate_Apply(t *testing.T) {
+	current := map[string]string{"a": "1", "b": "2"}
+	note := "  new note "
+	gotNote, got := NoteUpdate{Note: &note, Metadata: map[string]string{" c ": "3"}, Unset: []string{"a"}}.Apply("old", current)
+
+	if gotNote != "new note" {
+		t.Errorf("note = %q", gotNote)
+	}
+	if len(got) != 2 || got["b"] != "2" || got["c"] != "3" {
+		t.Errorf("metadata = %v", got)
+	}
+	if len(current) != 2 || current["a"] != "1" {
+		t.Errorf("current map was modified: %v", current)
+	}
+
+	if gotNote, got := (NoteUpdate{Unset: []string{"a", "b"}}).Apply("keep", current); gotNote != "keep" || got != nil {
+		t.Errorf("Apply = %q, %v; want note kept and nil metadata", gotNote, got)
+	}
+}
@@ -0,0 +1,66 @@
+package types
+
+import "maps"
+
+// DownloadOverrides are the settings a download was added with that differ
+// from the user's configuration. They are stored with the download so a
+// resume, even after a restart, runs it the same way.
+type DownloadOverrides struct {
+	Headers     map[string]string `json:"headers,omitempty"`     // Custom HTTP headers, including cookies and auth
+	Connections int               `json:"connections,omitempty"` // Connections per host, 0 = use settings
+	SpeedLimit  int64             `json:"speed_limit,omitempty"` // Bytes/sec for this download, 0 = unlimited
+	ChunkSize   int64             `json:"chunk_size,omitempty"`  // Minimum bytes per chunk, 0 = use settings
+	UserAgent   string            `json:"user_agent,omitempty"`  // Sent unless Headers carry one, "" = use settings
+	Tries       int               `json:"tries,omitempty"`       // Attempts per chunk before failing, 0 = use settings
+	Priority    int               `json:"priority,omitempty"`    // PriorityHigh, PriorityNormal or PriorityLow
+}
+
+// IsZero reports whether nothing is overridden
+func (o *DownloadOverrides) IsZero() bool {
+	return o == nil || (len(o.Headers) == 0 && o.Priority == PriorityNormal && !o.tunesRuntime())
+}
+
+// GetPriority returns the priority the download waits with, PriorityNormal
+// when o is nil
+func (o *DownloadOverrides) GetPriority() int {
+	if o == nil {
+		return PriorityNormal
+	}
+	return o.Priority
+}
+
+// tunesRuntime reports whether any override lives in the runtime config
+func (o *DownloadOverrides) tunesRuntime() bool {
+	return o.Connections > 0 || o.SpeedLimit > 0 || o.ChunkSize > 0 || o.UserAgent != "" || o.Tries > 0
+}
+
+// Apply sets the overrides on cfg, leaving what they don't cover alone
+func (o *DownloadOverrides) Apply(cfg *DownloadConfig) {
+	if o.IsZero() {
+		return
+	}
+	if len(o.Headers) > 0 {
+		cfg.Headers = maps.Clone(o.Headers)
+	}
+	if !o.tunesRuntime() {
+		return
+	}
+	if cfg.Runtime == nil {
+		cfg.Runtime = &RuntimeConfig{}
+	}
+	if o.Connections > 0 {
+		cfg.Runtime.MaxConnectionsPerHost = min(o.Connections, PerHostMax)
+	}
+	if o.SpeedLimit > 0 {
+		cfg.Runtime.SpeedLimit = o.SpeedLimit
+	}
+	if o.ChunkSize > 0 {
+		cfg.Runtime.MinChunkSize = max(o.ChunkSize, AlignSize)
+	}
+	if o.UserAgent != "" {
+		cfg.Runtime.UserAgent = o.UserAgent
+	}
+	if o.Tries > 0 {
+		cfg.Runtime.MaxTaskRetries = o.Tries
+	}
+}
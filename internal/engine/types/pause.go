@@ -0,0 +1,19 @@
+package types
+
+// Reasons a download is paused, stored with it so auto-resume only restarts
+// downloads the user did not stop themselves
+const (
+	PauseUser        = "user"        // Paused from the TUI, CLI or API
+	PauseShutdown    = "shutdown"    // Surge exited while it was running
+	PauseInterrupted = "interrupted" // Surge was killed while it was running
+	PauseDiskFull    = "disk_full"   // The destination ran out of space
+	PauseArchived    = "archived"    // Removed from the list with its partial data kept
+	PauseTrashed     = "trashed"     // Deleted into the trash, restorable until it expires
+)
+
+// AutoResumable reports whether auto-resume may restart a download paused for
+// reason. Downloads paused before reasons were kept have none and are resumed
+// as they always were.
+func AutoResumable(reason string) bool {
+	return reason != PauseUser && reason != PauseDiskFull && reason != PauseArchived && reason != PauseTrashed
+}
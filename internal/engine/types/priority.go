@@ -0,0 +1,35 @@
+package types
+
+import "fmt"
+
+// Priorities of a waiting download. Downloads start highest priority first,
+// and in queue order among the same priority.
+const (
+	PriorityLow    = -1
+	PriorityNormal = 0
+	PriorityHigh   = 1
+)
+
+// ParsePriority reads a priority named high, normal or low
+func ParsePriority(s string) (int, error) {
+	switch s {
+	case "high":
+		return PriorityHigh, nil
+	case "normal":
+		return PriorityNormal, nil
+	case "low":
+		return PriorityLow, nil
+	}
+	return 0, fmt.Errorf("invalid priority %q, want high, normal or low", s)
+}
+
+// PriorityName returns the name ParsePriority reads priority from
+func PriorityName(priority int) string {
+	switch {
+	case priority > PriorityNormal:
+		return "high"
+	case priority < PriorityNormal:
+		return "low"
+	}
+	return "normal"
+}
@@ -22,6 +22,7 @@ type ProgressState struct {
 	Error         atomic.Pointer[error]
 	Paused        atomic.Bool
 	Pausing       atomic.Bool // Intermediate state: Pause requested but workers not yet exited
+	pauseReason   string      // Why the download was paused, one of the Pause* values
 	cancelFunc    context.CancelFunc
 
 	VerifiedProgress  atomic.Int64  // Verified bytes written to disk (for UI progress)
@@ -30,6 +31,12 @@ type ProgressState struct {
 
 	Mirrors []MirrorStatus // Status of each mirror
 
+	workerErrors []WorkerErrorStats    // Per-worker failure counts, set when workers finish
+	workerSource func() []WorkerStatus // Snapshot of the running workers, set while they run
+	speedLimiter func(rate int64)      // Changes the running download's own speed limit, set while it runs
+	speedLimit   int64                 // Own speed limit last set by ApplySpeedLimit
+	speedLimitOK bool                  // Whether ApplySpeedLimit was called
+
 	// Chunk Visualization (Bitmap)
 	// Chunk Visualization (Bitmap)
 	ChunkBitmap     []byte  // 2 bits per chunk
@@ -37,13 +44,13 @@ type ProgressState struct {
 	ActualChunkSize int64   // Size of each actual chunk in bytes
 	BitmapWidth     int     // Number of chunks tracked
 
-	mu sync.Mutex // Protects TotalSize, StartTime, SessionStartBytes, SavedElapsed, Mirrors
+	mu sync.Mutex // Protects TotalSize, StartTime, SessionStartBytes, SavedElapsed, Mirrors, workerErrors, workerSource, speedLimiter, speedLimit, speedLimitOK, pauseReason
 }
 
 type MirrorStatus struct {
-	URL    string
-	Active bool
-	Error  bool
+	URL    string `json:"url"`
+	Active bool   `json:"active"`
+	Error  bool   `json:"error"`
 }
 
 func (ps *ProgressState) SetDestPath(path string) {
@@ -98,6 +105,21 @@ func (ps *ProgressState) SetTotalSize(size int64) {
 	ps.StartTime = time.Now()
 }
 
+// GetTotalSize returns the expected (or, once known, actual) file size
+func (ps *ProgressState) GetTotalSize() int64 {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return ps.TotalSize
+}
+
+// SetActualSize corrects TotalSize once the real size is known, e.g. after
+// decoding a compressed body, without restarting the session clock.
+func (ps *ProgressState) SetActualSize(size int64) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.TotalSize = size
+}
+
 func (ps *ProgressState) SyncSessionStart() {
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
@@ -147,14 +169,27 @@ func (ps *ProgressState) GetProgress() (downloaded int64, total int64, totalElap
 }
 
 func (ps *ProgressState) Pause() {
-	ps.Paused.Store(true)
+	ps.PauseFor(PauseUser)
+}
+
+// PauseFor pauses the download and records why
+func (ps *ProgressState) PauseFor(reason string) {
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
+	ps.pauseReason = reason
+	ps.Paused.Store(true)
 	if ps.cancelFunc != nil {
 		ps.cancelFunc()
 	}
 }
 
+// PauseReason returns why the download was last paused
+func (ps *ProgressState) PauseReason() string {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return ps.pauseReason
+}
+
 func (ps *ProgressState) SetCancelFunc(cancel context.CancelFunc) {
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
@@ -162,6 +197,9 @@ func (ps *ProgressState) SetCancelFunc(cancel context.CancelFunc) {
 }
 
 func (ps *ProgressState) Resume() {
+	ps.mu.Lock()
+	ps.pauseReason = ""
+	ps.mu.Unlock()
 	ps.Paused.Store(false)
 }
 
@@ -244,6 +282,73 @@ func (ps *ProgressState) GetMirrors() []MirrorStatus {
 	return mirrors
 }
 
+// SetWorkerErrors stores the per-worker error summary of the last session
+func (ps *ProgressState) SetWorkerErrors(stats []WorkerErrorStats) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.workerErrors = append([]WorkerErrorStats(nil), stats...)
+}
+
+// GetWorkerErrors returns a copy of the per-worker error summary
+func (ps *ProgressState) GetWorkerErrors() []WorkerErrorStats {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if len(ps.workerErrors) == 0 {
+		return nil
+	}
+	return append([]WorkerErrorStats(nil), ps.workerErrors...)
+}
+
+// SetWorkerSource registers how to list the running workers, or clears it
+// with nil once they have stopped
+func (ps *ProgressState) SetWorkerSource(source func() []WorkerStatus) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.workerSource = source
+}
+
+// GetWorkers returns what each running worker is fetching, nil when none run
+func (ps *ProgressState) GetWorkers() []WorkerStatus {
+	ps.mu.Lock()
+	source := ps.workerSource
+	ps.mu.Unlock()
+	if source == nil {
+		return nil
+	}
+	return source()
+}
+
+// SetSpeedLimiter registers how to change the running download's own speed
+// limit, or clears it with nil once it has stopped
+func (ps *ProgressState) SetSpeedLimiter(limiter func(rate int64)) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.speedLimiter = limiter
+}
+
+// ApplySpeedLimit changes the download's own speed limit in bytes/sec
+// (0 = unlimited). It returns whether the running download took it at once;
+// either way GetSpeedLimit keeps it for the next start.
+func (ps *ProgressState) ApplySpeedLimit(rate int64) bool {
+	ps.mu.Lock()
+	ps.speedLimit, ps.speedLimitOK = rate, true
+	limiter := ps.speedLimiter
+	ps.mu.Unlock()
+	if limiter == nil {
+		return false
+	}
+	limiter(rate)
+	return true
+}
+
+// GetSpeedLimit returns the speed limit last given to ApplySpeedLimit, and
+// false when there was none
+func (ps *ProgressState) GetSpeedLimit() (int64, bool) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return ps.speedLimit, ps.speedLimitOK
+}
+
 // ChunkStatus represents the status of a visualization chunk
 type ChunkStatus int
 
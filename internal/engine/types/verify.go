@@ -0,0 +1,26 @@
+package types
+
+// Outcomes of verifying a completed download against its stored checksum
+const (
+	VerifyOK           = "ok"           // The file matches its checksum
+	VerifyCorrupted    = "corrupted"    // The file no longer matches
+	VerifyMissing      = "missing"      // The file is gone
+	VerifyHashed       = "hashed"       // There was no checksum; the file's is now stored
+	VerifyUnverifiable = "unverifiable" // Post-processing removed the file on purpose, e.g. an extracted archive
+)
+
+// VerifyResult is the outcome of verifying one download
+type VerifyResult struct {
+	ID       string `json:"id"`
+	Filename string `json:"filename"`
+	Path     string `json:"path"`
+	Status   string `json:"status"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+	Error    string `json:"error,omitempty"` // Set when the file could not be read
+}
+
+// Failed reports whether the result needs attention
+func (r VerifyResult) Failed() bool {
+	return r.Status == VerifyCorrupted || r.Status == VerifyMissing || r.Error != ""
+}
@@ -0,0 +1,76 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WorkerErrorKinds names the columns returned by WorkerErrorStats.Counts
+var WorkerErrorKinds = []string{"timeout", "reset", "5xx", "429", "stall", "other"}
+
+// WorkerErrorStats counts the failed attempts of one worker during a download
+type WorkerErrorStats struct {
+	Worker       int
+	Timeouts     int
+	Resets       int
+	ServerErrors int // 5xx responses
+	RateLimited  int // 429 responses
+	Stalls       int // Cancelled by the health monitor for being stalled or slow
+	Other        int
+}
+
+// Counts returns the counters in WorkerErrorKinds order
+func (s WorkerErrorStats) Counts() []int {
+	return []int{s.Timeouts, s.Resets, s.ServerErrors, s.RateLimited, s.Stalls, s.Other}
+}
+
+// Total returns the number of failed attempts across all kinds
+func (s WorkerErrorStats) Total() int {
+	total := 0
+	for _, c := range s.Counts() {
+		total += c
+	}
+	return total
+}
+
+// heatShades goes from no errors to the busiest cell in the map
+var heatShades = []string{"·", "░", "▒", "▓", "█"}
+
+// FormatWorkerErrorHeatmap renders one row per worker and one column per
+// error kind, shading each cell relative to the worst cell. It returns nil
+// when no worker failed.
+func FormatWorkerErrorHeatmap(stats []WorkerErrorStats) []string {
+	maxCount := 0
+	for _, s := range stats {
+		for _, c := range s.Counts() {
+			maxCount = max(maxCount, c)
+		}
+	}
+	if maxCount == 0 {
+		return nil
+	}
+
+	var header strings.Builder
+	header.WriteString("worker ")
+	for _, kind := range WorkerErrorKinds {
+		fmt.Fprintf(&header, " %-7s", kind)
+	}
+	lines := []string{strings.TrimRight(header.String(), " ")}
+
+	for _, s := range stats {
+		if s.Total() == 0 {
+			continue
+		}
+		var row strings.Builder
+		fmt.Fprintf(&row, "#%-5d ", s.Worker)
+		for _, c := range s.Counts() {
+			shade := heatShades[0]
+			if c > 0 {
+				shade = heatShades[1+(c*(len(heatShades)-1)-1)/maxCount]
+			}
+			fmt.Fprintf(&row, " %s %-5d", shade, c)
+		}
+		lines = append(lines, strings.TrimRight(row.String(), " "))
+	}
+	return lines
+}
@@ -0,0 +1,172 @@
+// Package warmup shortens the cold start of the next queued download by
+// resolving its host and completing a TLS handshake before a slot frees.
+// Transports pick the results up through DialContext and SessionCache: the
+// dial skips the DNS lookup and the handshake resumes the cached session
+// instead of doing a full one.
+package warmup
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+const (
+	// ResolveTTL bounds how long pre-resolved addresses are used. The system
+	// resolver does not expose record TTLs, so this stays short.
+	ResolveTTL = 30 * time.Second
+	// ticketWait is how long a warm handshake waits for TLS 1.3 session
+	// tickets, which servers send after the handshake completes.
+	ticketWait = 300 * time.Millisecond
+)
+
+var (
+	sessionCache = tls.NewLRUClientSessionCache(128)
+
+	resolvedMu sync.Mutex
+	resolved   = make(map[string]resolvedHost) // lowercase host -> addresses
+
+	// lookupHost is swapped out by tests
+	lookupHost = net.DefaultResolver.LookupHost
+)
+
+type resolvedHost struct {
+	addrs   []string
+	expires time.Time
+}
+
+// SessionCache returns the TLS session cache shared by all download transports
+func SessionCache() tls.ClientSessionCache {
+	return sessionCache
+}
+
+// TLSConfig returns the client TLS config download transports use so they
+// resume sessions established by Prewarm
+func TLSConfig() *tls.Config {
+	return &tls.Config{ClientSessionCache: sessionCache}
+}
+
+// DialContext wraps dialer so that hosts resolved by Prewarm are dialed by
+// address. Other hosts, and cached addresses that all fail, fall back to a
+// normal dial.
+func DialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		for _, ip := range cachedAddrs(host) {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+}
+
+func cachedAddrs(host string) []string {
+	resolvedMu.Lock()
+	defer resolvedMu.Unlock()
+	key := strings.ToLower(host)
+	entry, ok := resolved[key]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(entry.expires) {
+		delete(resolved, key)
+		return nil
+	}
+	return entry.addrs
+}
+
+// resolve looks host up and caches the result for ResolveTTL
+func resolve(ctx context.Context, host string) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+	if addrs := cachedAddrs(host); len(addrs) > 0 {
+		return addrs, nil
+	}
+	addrs, err := lookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	resolvedMu.Lock()
+	resolved[strings.ToLower(host)] = resolvedHost{addrs: addrs, expires: time.Now().Add(ResolveTTL)}
+	resolvedMu.Unlock()
+	return addrs, nil
+}
+
+// Prewarm resolves rawurl's host and, for https, completes one TLS handshake
+// so the download's first connections can resume the session. Downloads going
+// through a proxy are skipped: the proxy does the resolving and the TLS
+// session is tunnelled through a connection we cannot reuse.
+func Prewarm(ctx context.Context, rawurl string, runtime *types.RuntimeConfig) error {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil
+	}
+	if usesProxy(u, runtime) {
+		return nil
+	}
+
+	host := u.Hostname()
+	if _, err := resolve(ctx, host); err != nil {
+		return err
+	}
+	if u.Scheme != "https" {
+		return nil
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = "443"
+	}
+	dialer := &net.Dialer{Timeout: types.DialTimeout, KeepAlive: types.KeepAliveDuration}
+	raw, err := DialContext(dialer)(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = raw.Close() }()
+
+	conn := tls.Client(raw, &tls.Config{
+		ServerName:         host,
+		ClientSessionCache: sessionCache,
+		NextProtos:         []string{"http/1.1"},
+	})
+	hsCtx, cancel := context.WithTimeout(ctx, types.DefaultTLSHandshakeTimeout)
+	defer cancel()
+	if err := conn.HandshakeContext(hsCtx); err != nil {
+		return err
+	}
+	// TLS 1.3 tickets arrive after the handshake and are only processed on
+	// read. The server sends no data, so this read just times out.
+	if conn.ConnectionState().Version >= tls.VersionTLS13 {
+		_ = conn.SetReadDeadline(time.Now().Add(ticketWait))
+		var buf [1]byte
+		_, _ = conn.Read(buf[:])
+	}
+	return nil
+}
+
+func usesProxy(u *url.URL, runtime *types.RuntimeConfig) bool {
+	if runtime != nil && runtime.ProxyURL != "" {
+		return true
+	}
+	proxy, err := http.ProxyFromEnvironment(&http.Request{URL: u})
+	return err != nil || proxy != nil
+}
@@ -0,0 +1,132 @@
+package warmup
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func resetResolved(t *testing.T) {
+	t.Helper()
+	resolvedMu.Lock()
+	resolved = make(map[string]resolvedHost)
+	resolvedMu.Unlock()
+	origLookup := lookupHost
+	t.Cleanup(func() {
+		lookupHost = origLookup
+		resolvedMu.Lock()
+		resolved = make(map[string]resolvedHost)
+		resolvedMu.Unlock()
+	})
+}
+
+func cacheHost(host string, addrs []string, expires time.Time) {
+	resolvedMu.Lock()
+	resolved[host] = resolvedHost{addrs: addrs, expires: expires}
+	resolvedMu.Unlock()
+}
+
+func listen(t *testing.T) (net.Listener, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_ = c.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	return ln, port
+}
+
+func TestDialContext_UsesCachedAddrs(t *testing.T) {
+	resetResolved(t)
+	_, port := listen(t)
+	// .invalid never resolves, so the dial only succeeds through the cache
+	cacheHost("warm.invalid", []string{"127.0.0.1"}, time.Now().Add(time.Minute))
+
+	conn, err := DialContext(&net.Dialer{Timeout: time.Second})(context.Background(), "tcp", net.JoinHostPort("warm.invalid", port))
+	if err != nil {
+		t.Fatalf("dial with cached address failed: %v", err)
+	}
+	_ = conn.Close()
+}
+
+func TestDialContext_FallsBackWhenCachedAddrsFail(t *testing.T) {
+	resetResolved(t)
+	_, port := listen(t)
+	// Nothing listens on 127.0.0.2, so the cached address is refused
+	cacheHost("localhost", []string{"127.0.0.2"}, time.Now().Add(time.Minute))
+
+	conn, err := DialContext(&net.Dialer{Timeout: time.Second})(context.Background(), "tcp4", net.JoinHostPort("localhost", port))
+	if err != nil {
+		t.Fatalf("expected fallback to a normal dial, got %v", err)
+	}
+	_ = conn.Close()
+}
+
+func TestCachedAddrs_ExpiresAfterTTL(t *testing.T) {
+	resetResolved(t)
+	cacheHost("stale.invalid", []string{"127.0.0.1"}, time.Now().Add(-time.Second))
+
+	if addrs := cachedAddrs("stale.invalid"); addrs != nil {
+		t.Fatalf("expired entry returned %v", addrs)
+	}
+	resolvedMu.Lock()
+	_, still := resolved["stale.invalid"]
+	resolvedMu.Unlock()
+	if still {
+		t.Fatal("expired entry was not evicted")
+	}
+
+	_, port := listen(t)
+	if _, err := DialContext(&net.Dialer{Timeout: time.Second})(context.Background(), "tcp", net.JoinHostPort("stale.invalid", port)); err == nil {
+		t.Fatal("expired entry must not be used for dialing")
+	}
+}
+
+func TestPrewarm_ResolvesPlainHTTPHost(t *testing.T) {
+	resetResolved(t)
+	t.Setenv("HTTP_PROXY", "")
+	t.Setenv("http_proxy", "")
+	lookups := 0
+	lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		lookups++
+		return []string{"127.0.0.1"}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := Prewarm(context.Background(), "http://Mirror.Example/file.iso", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if lookups != 1 {
+		t.Fatalf("lookups = %d, want 1 (second prewarm should hit the cache)", lookups)
+	}
+	if addrs := cachedAddrs("mirror.example"); len(addrs) != 1 || addrs[0] != "127.0.0.1" {
+		t.Fatalf("cached addrs = %v", addrs)
+	}
+}
+
+func TestPrewarm_SkipsProxiedDownloads(t *testing.T) {
+	resetResolved(t)
+	lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		t.Fatalf("unexpected lookup of %s", host)
+		return nil, nil
+	}
+
+	runtime := &types.RuntimeConfig{ProxyURL: "http://127.0.0.1:3128"}
+	if err := Prewarm(context.Background(), "https://example.com/file.iso", runtime); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,39 @@
+// Package keychain keeps small secrets in the operating system's credential
+// store: the login keychain on macOS, the Secret Service (through secret-tool)
+// on Linux and other Unix systems, and DPAPI-protected files on Windows.
+package keychain
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Service is the name secrets are filed under in the credential store
+const Service = "surge"
+
+var (
+	// ErrNotFound is returned by Get when nothing is stored under the account
+	ErrNotFound = errors.New("secret not found in the keychain")
+	// ErrUnavailable is returned when this system has no usable credential store
+	ErrUnavailable = errors.New("no keychain available")
+)
+
+// Get returns the secret stored under account
+func Get(account string) ([]byte, error) {
+	encoded, err := get(account)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret in the keychain for %s: %w", account, err)
+	}
+	return secret, nil
+}
+
+// Set stores secret under account, replacing what was there
+func Set(account string, secret []byte) error {
+	return set(account, base64.StdEncoding.EncodeToString(secret))
+}
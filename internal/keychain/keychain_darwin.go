@@ -0,0 +1,39 @@
+//go:build darwin
+
+package keychain
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+func get(account string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", Service, "-a", account, "-w").Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// security exits 44 when the item does not exist
+			if exitErr.ExitCode() == 44 {
+				return "", ErrNotFound
+			}
+			return "", fmt.Errorf("security: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return string(out), nil
+}
+
+func set(account, secret string) error {
+	// -U updates an existing item instead of failing on it
+	out, err := exec.Command("security", "add-generic-password", "-U", "-s", Service, "-a", account, "-w", secret).CombinedOutput()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return fmt.Errorf("%w: %v", ErrUnavailable, err)
+		}
+		return fmt.Errorf("security: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
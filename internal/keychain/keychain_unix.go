@@ -0,0 +1,48 @@
+//go:build !darwin && !windows
+
+package keychain
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+func get(account string) (string, error) {
+	path, err := exec.LookPath("secret-tool")
+	if err != nil {
+		return "", fmt.Errorf("%w: secret-tool not found", ErrUnavailable)
+	}
+	out, err := exec.Command(path, "lookup", "service", Service, "account", account).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return "", err
+		}
+		// lookup exits 1 without a message when nothing matches
+		msg := strings.TrimSpace(string(exitErr.Stderr))
+		if msg == "" {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("secret-tool: %s", msg)
+	}
+	if len(out) == 0 {
+		return "", ErrNotFound
+	}
+	return string(out), nil
+}
+
+func set(account, secret string) error {
+	path, err := exec.LookPath("secret-tool")
+	if err != nil {
+		return fmt.Errorf("%w: secret-tool not found", ErrUnavailable)
+	}
+	// The secret goes in on stdin so it never shows up in the process list
+	cmd := exec.Command(path, "store", "--label=Surge "+account, "service", Service, "account", account)
+	cmd.Stdin = strings.NewReader(secret)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("secret-tool: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+//go:build windows
+
+package keychain
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Secrets are encrypted with DPAPI for the current user and kept in files,
+// since the Credential Manager API is not in golang.org/x/sys
+
+func secretPath(account string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return filepath.Join(dir, Service, "keychain", account+".dpapi"), nil
+}
+
+func get(account string) (string, error) {
+	path, err := secretPath(account)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	var out windows.DataBlob
+	if err := windows.CryptUnprotectData(blob(sealed), nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return "", fmt.Errorf("failed to unprotect %s: %w", path, err)
+	}
+	defer func() { _, _ = windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data))) }()
+	return string(unsafe.Slice(out.Data, out.Size)), nil
+}
+
+func set(account, secret string) error {
+	path, err := secretPath(account)
+	if err != nil {
+		return err
+	}
+	var out windows.DataBlob
+	if err := windows.CryptProtectData(blob([]byte(secret)), nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return fmt.Errorf("failed to protect secret: %w", err)
+	}
+	defer func() { _, _ = windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data))) }()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, unsafe.Slice(out.Data, out.Size), 0o600)
+}
+
+func blob(data []byte) *windows.DataBlob {
+	if len(data) == 0 {
+		return &windows.DataBlob{}
+	}
+	return &windows.DataBlob{Size: uint32(len(data)), Data: &data[0]}
+}
@@ -0,0 +1,93 @@
+// Package metafile reads the download descriptions browsers hand off instead
+// of the download itself: metalinks and torrents. Surge downloads over HTTP
+// only, so each describes files by their http(s) sources: a metalink's URLs,
+// or a torrent's web seeds (BEP 19).
+package metafile
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Kinds of description
+const (
+	KindMetalink = "metalink"
+	KindTorrent  = "torrent"
+	KindDLC      = "dlc"
+)
+
+// ErrUnsupported is returned for descriptions Surge can't download from
+var ErrUnsupported = errors.New("unsupported")
+
+// File is one file a description lists
+type File struct {
+	Name string   // Filename, without directories
+	Dir  string   // Subdirectory to save it in, slash-separated; empty for none
+	Size int64    // Bytes, 0 when unknown
+	URLs []string // http(s) sources, best first
+}
+
+// Detect returns the kind of the description named name, by its extension
+// and, failing that, its first bytes. It returns "" for anything else.
+func Detect(name string, data []byte) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".metalink", ".meta4":
+		return KindMetalink
+	case ".torrent":
+		return KindTorrent
+	case ".dlc":
+		return KindDLC
+	}
+	head := strings.TrimSpace(string(data[:min(len(data), 512)]))
+	switch {
+	case strings.HasPrefix(head, "d8:announce"), strings.HasPrefix(head, "d4:info"), strings.HasPrefix(head, "d13:announce-list"):
+		return KindTorrent
+	case strings.Contains(head, "<metalink"):
+		return KindMetalink
+	}
+	return ""
+}
+
+// Parse reads a description of kind. DLC containers are encrypted for
+// JDownloader's service and are always ErrUnsupported.
+func Parse(kind string, data []byte) ([]File, error) {
+	switch kind {
+	case KindMetalink:
+		return ParseMetalink(data)
+	case KindTorrent:
+		return ParseTorrent(data)
+	case KindDLC:
+		return nil, fmt.Errorf("%w: DLC containers are encrypted and can't be read", ErrUnsupported)
+	}
+	return nil, fmt.Errorf("%w: not a metalink or torrent", ErrUnsupported)
+}
+
+// isHTTP reports whether u is an absolute http(s) URL
+func isHTTP(u string) bool {
+	parsed, err := url.Parse(u)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+// safeName checks that a name taken from a description is one path
+// component that stays where it is put
+func safeName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) || strings.ContainsRune(name, 0) {
+		return fmt.Errorf("invalid file name %q", name)
+	}
+	return nil
+}
+
+// splitPath splits a slash-separated relative path from a description into
+// its directory and name, rejecting components that would escape it
+func splitPath(p string) (string, string, error) {
+	parts := strings.Split(strings.Trim(p, "/"), "/")
+	for _, part := range parts {
+		if err := safeName(part); err != nil {
+			return "", "", err
+		}
+	}
+	return strings.Join(parts[:len(parts)-1], "/"), parts[len(parts)-1], nil
+}
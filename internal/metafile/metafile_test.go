@@ -0,0 +1,114 @@
+package metafile
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const metalink4 = `<?xml version="1.0" encoding="UTF-8"?>
+<metalink xmlns="urn:ietf:params:xml:ns:metalink">
+  <file name="iso/debian.iso">
+    <size>1024</size>
+    <url priority="2">https://mirror2.example/debian.iso</url>
+    <url>ftp://mirror.example/debian.iso</url>
+    <url priority="1">https://mirror1.example/debian.iso</url>
+    <metaurl mediatype="torrent">https://example/debian.torrent</metaurl>
+  </file>
+  <file name="only-ftp.bin"><url>ftp://example/only-ftp.bin</url></file>
+</metalink>`
+
+const metalink3 = `<?xml version="1.0"?>
+<metalink version="3.0" xmlns="http://www.metalinker.org/">
+  <files>
+    <file name="tool.tar.gz">
+      <resources>
+        <url type="http" preference="50">http://slow.example/tool.tar.gz</url>
+        <url type="http" preference="100">http://fast.example/tool.tar.gz</url>
+      </resources>
+    </file>
+  </files>
+</metalink>`
+
+func TestParseMetalink(t *testing.T) {
+	files, err := ParseMetalink([]byte(metalink4))
+	if err != nil {
+		t.Fatalf("metalink 4: %v", err)
+	}
+	want := []File{{Name: "debian.iso", Dir: "iso", Size: 1024, URLs: []string{"https://mirror1.example/debian.iso", "https://mirror2.example/debian.iso"}}}
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("metalink 4 = %+v, want %+v", files, want)
+	}
+
+	files, err = ParseMetalink([]byte(metalink3))
+	if err != nil {
+		t.Fatalf("metalink 3: %v", err)
+	}
+	if len(files) != 1 || files[0].URLs[0] != "http://fast.example/tool.tar.gz" {
+		t.Fatalf("metalink 3 = %+v", files)
+	}
+
+	if _, err := ParseMetalink([]byte(`<metalink><file name="../etc/passwd"><url>http://x/y</url></file></metalink>`)); err == nil {
+		t.Fatal("escaping file name accepted")
+	}
+	if _, err := ParseMetalink([]byte(`<metalink><file name="a"><url>ftp://x/a</url></file></metalink>`)); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("no http URLs: %v, want ErrUnsupported", err)
+	}
+}
+
+func TestParseTorrent(t *testing.T) {
+	single := "d8:announce3:udp8:url-listl19:https://seed.local/e4:infod6:lengthi42e4:name5:a.isoee"
+	files, err := ParseTorrent([]byte(single))
+	if err != nil {
+		t.Fatalf("single file: %v", err)
+	}
+	want := []File{{Name: "a.iso", Size: 42, URLs: []string{"https://seed.local/a.iso"}}}
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("single = %+v, want %+v", files, want)
+	}
+
+	multi := "d8:url-list17:http://seed.local4:infod5:filesld6:lengthi1e4:pathl3:sub7:a b.txteee4:name3:setee"
+	files, err = ParseTorrent([]byte(multi))
+	if err != nil {
+		t.Fatalf("multi file: %v", err)
+	}
+	want = []File{{Name: "a b.txt", Dir: "set/sub", Size: 1, URLs: []string{"http://seed.local/set/sub/a%20b.txt"}}}
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("multi = %+v, want %+v", files, want)
+	}
+
+	if _, err := ParseTorrent([]byte("d4:infod6:lengthi1e4:name1:aee")); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("no web seeds: %v, want ErrUnsupported", err)
+	}
+	escape := "d8:url-list11:http://s/x/4:infod5:filesld6:lengthi1e4:pathl2:..6:passwdeee4:name1:aee"
+	if _, err := ParseTorrent([]byte(escape)); err == nil {
+		t.Fatal("escaping file path accepted")
+	}
+	for _, bad := range []string{"", "d", "i12", "l" + string(make([]byte, 100)), "d4:info99:x"} {
+		if _, err := ParseTorrent([]byte(bad)); err == nil {
+			t.Errorf("ParseTorrent(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name, data, want string
+	}{
+		{"file.METALINK", "", KindMetalink},
+		{"file.meta4", "", KindMetalink},
+		{"file.torrent", "", KindTorrent},
+		{"links.dlc", "", KindDLC},
+		{"upload", "d8:announce", KindTorrent},
+		{"upload", `<?xml version="1.0"?><metalink>`, KindMetalink},
+		{"notes.txt", "hello", ""},
+	}
+	for _, tt := range tests {
+		if got := Detect(tt.name, []byte(tt.data)); got != tt.want {
+			t.Errorf("Detect(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if _, err := Parse(KindDLC, nil); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("DLC: %v, want ErrUnsupported", err)
+	}
+}
@@ -0,0 +1,70 @@
+package metafile
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// metalinkDoc covers Metalink 4 (RFC 5854) and the older 3.0 format, whose
+// files sit under <files> and URLs under <resources>. Tags match any
+// namespace.
+type metalinkDoc struct {
+	Files  []metalinkFile `xml:"file"`
+	Files3 []metalinkFile `xml:"files>file"`
+}
+
+type metalinkFile struct {
+	Name  string        `xml:"name,attr"`
+	Size  int64         `xml:"size"`
+	URLs  []metalinkURL `xml:"url"`
+	URLs3 []metalinkURL `xml:"resources>url"`
+}
+
+type metalinkURL struct {
+	Priority   int    `xml:"priority,attr"`   // Metalink 4: 1 is best
+	Preference int    `xml:"preference,attr"` // Metalink 3: 100 is best
+	URL        string `xml:",chardata"`
+}
+
+// ParseMetalink reads a Metalink 3 or 4 document. Files without an http(s)
+// URL are left out; a document left with none is ErrUnsupported.
+func ParseMetalink(data []byte) ([]File, error) {
+	var doc metalinkDoc
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid metalink: %w", err)
+	}
+
+	var files []File
+	for _, f := range append(doc.Files, doc.Files3...) {
+		dir, name, err := splitPath(f.Name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid metalink: %w", err)
+		}
+		urls := append(f.URLs, f.URLs3...)
+		// Metalink 4 ranks by ascending priority, with none last; 3.0 by
+		// descending preference
+		sort.SliceStable(urls, func(i, j int) bool {
+			pi, pj := urls[i].Priority, urls[j].Priority
+			if pi != pj {
+				return pj == 0 || (pi != 0 && pi < pj)
+			}
+			return urls[i].Preference > urls[j].Preference
+		})
+
+		file := File{Name: name, Dir: dir, Size: f.Size}
+		for _, u := range urls {
+			if u := strings.TrimSpace(u.URL); isHTTP(u) {
+				file.URLs = append(file.URLs, u)
+			}
+		}
+		if len(file.URLs) > 0 {
+			files = append(files, file)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%w: the metalink has no http or https URLs", ErrUnsupported)
+	}
+	return files, nil
+}
@@ -0,0 +1,193 @@
+package metafile
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// maxBencodeDepth bounds nesting so a crafted torrent can't exhaust the stack
+const maxBencodeDepth = 64
+
+// ParseTorrent reads a torrent and returns its files with URLs from the
+// torrent's web seeds (url-list). A torrent without web seeds is
+// ErrUnsupported, since Surge can't download from peers.
+func ParseTorrent(data []byte) ([]File, error) {
+	d := &bdecoder{data: data}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid torrent: %w", err)
+	}
+	root, ok := v.(map[string]any)
+	if !ok {
+		return nil, errors.New("invalid torrent: not a dictionary")
+	}
+	info, ok := root["info"].(map[string]any)
+	if !ok {
+		return nil, errors.New("invalid torrent: missing info")
+	}
+	name, _ := info["name"].(string)
+	if err := safeName(name); err != nil {
+		return nil, fmt.Errorf("invalid torrent: %w", err)
+	}
+
+	var seeds []string
+	switch list := root["url-list"].(type) {
+	case string:
+		seeds = []string{list}
+	case []any:
+		for _, s := range list {
+			if s, ok := s.(string); ok {
+				seeds = append(seeds, s)
+			}
+		}
+	}
+	var httpSeeds []string
+	for _, s := range seeds {
+		if s = strings.TrimSpace(s); isHTTP(s) {
+			httpSeeds = append(httpSeeds, s)
+		}
+	}
+	if len(httpSeeds) == 0 {
+		return nil, fmt.Errorf("%w: the torrent has no http web seeds and Surge doesn't download from peers", ErrUnsupported)
+	}
+
+	entries, _ := info["files"].([]any)
+	if len(entries) == 0 {
+		// Single file: a seed ending in / is a directory holding it
+		length, _ := info["length"].(int64)
+		file := File{Name: name, Size: length}
+		for _, s := range httpSeeds {
+			if strings.HasSuffix(s, "/") {
+				s += url.PathEscape(name)
+			}
+			file.URLs = append(file.URLs, s)
+		}
+		return []File{file}, nil
+	}
+
+	// Several files: each seed is the root above the torrent's directory
+	files := make([]File, 0, len(entries))
+	for _, e := range entries {
+		entry, _ := e.(map[string]any)
+		parts, _ := entry["path"].([]any)
+		segments := []string{name}
+		for _, p := range parts {
+			s, ok := p.(string)
+			if !ok {
+				return nil, errors.New("invalid torrent: bad file path")
+			}
+			segments = append(segments, s)
+		}
+		if len(segments) < 2 {
+			return nil, errors.New("invalid torrent: empty file path")
+		}
+		dir, fileName, err := splitPath(strings.Join(segments, "/"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid torrent: %w", err)
+		}
+		escaped := make([]string, len(segments))
+		for i, s := range segments {
+			escaped[i] = url.PathEscape(s)
+		}
+		length, _ := entry["length"].(int64)
+		file := File{Name: fileName, Dir: dir, Size: length}
+		for _, s := range httpSeeds {
+			file.URLs = append(file.URLs, strings.TrimSuffix(s, "/")+"/"+strings.Join(escaped, "/"))
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// bdecoder decodes bencode: integers to int64, strings to string, lists to
+// []any and dictionaries to map[string]any
+type bdecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *bdecoder) value(depth int) (any, error) {
+	if depth > maxBencodeDepth {
+		return nil, errors.New("nested too deeply")
+	}
+	if d.pos >= len(d.data) {
+		return nil, errors.New("unexpected end")
+	}
+	switch c := d.data[d.pos]; {
+	case c == 'i':
+		end := d.index('e', d.pos+1)
+		if end < 0 {
+			return nil, errors.New("unterminated integer")
+		}
+		n, err := strconv.ParseInt(string(d.data[d.pos+1:end]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad integer: %w", err)
+		}
+		d.pos = end + 1
+		return n, nil
+	case c == 'l':
+		d.pos++
+		var list []any
+		for d.pos < len(d.data) && d.data[d.pos] != 'e' {
+			v, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		if d.pos >= len(d.data) {
+			return nil, errors.New("unterminated list")
+		}
+		d.pos++
+		return list, nil
+	case c == 'd':
+		d.pos++
+		dict := make(map[string]any)
+		for d.pos < len(d.data) && d.data[d.pos] != 'e' {
+			key, err := d.str()
+			if err != nil {
+				return nil, err
+			}
+			v, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			dict[key] = v
+		}
+		if d.pos >= len(d.data) {
+			return nil, errors.New("unterminated dictionary")
+		}
+		d.pos++
+		return dict, nil
+	case c >= '0' && c <= '9':
+		return d.str()
+	default:
+		return nil, fmt.Errorf("unexpected %q at %d", c, d.pos)
+	}
+}
+
+func (d *bdecoder) str() (string, error) {
+	colon := d.index(':', d.pos)
+	if colon < 0 {
+		return "", errors.New("bad string")
+	}
+	n, err := strconv.Atoi(string(d.data[d.pos:colon]))
+	if err != nil || n < 0 || n > len(d.data)-colon-1 {
+		return "", errors.New("bad string length")
+	}
+	s := string(d.data[colon+1 : colon+1+n])
+	d.pos = colon + 1 + n
+	return s, nil
+}
+
+func (d *bdecoder) index(b byte, from int) int {
+	for i := from; i < len(d.data); i++ {
+		if d.data[i] == b {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,57 @@
+// Package nativemsg implements the framing of Chrome and Firefox native
+// messaging: each message is JSON preceded by its length as a 32-bit
+// unsigned integer in native byte order, over the host's stdin and stdout.
+package nativemsg
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Size limits: browsers send at most 64 MiB to a host and accept at most
+// 1 MiB from it
+const (
+	MaxIncoming = 64 << 20
+	MaxOutgoing = 1 << 20
+)
+
+// ErrTooLarge is returned for messages over the size limits
+var ErrTooLarge = errors.New("native message too large")
+
+// Read reads one message from r. It returns io.EOF when the browser has
+// closed the pipe between messages.
+func Read(r io.Reader) ([]byte, error) {
+	var size uint32
+	if err := binary.Read(r, binary.NativeEndian, &size); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("reading message length: %w", err)
+		}
+		return nil, err
+	}
+	if size > MaxIncoming {
+		return nil, fmt.Errorf("%w: %d bytes", ErrTooLarge, size)
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, fmt.Errorf("reading message: %w", err)
+	}
+	return msg, nil
+}
+
+// Write encodes v as JSON and writes it to w as one message
+func Write(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if len(data) > MaxOutgoing {
+		return fmt.Errorf("%w: %d bytes", ErrTooLarge, len(data))
+	}
+	buf := make([]byte, 4, 4+len(data))
+	binary.NativeEndian.PutUint32(buf, uint32(len(data)))
+	_, err = w.Write(append(buf, data...))
+	return err
+}
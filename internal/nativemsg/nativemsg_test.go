@@ -0,0 +1,51 @@
+package nativemsg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, map[string]string{"type": "ping"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Write(&buf, []int{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if got := binary.NativeEndian.Uint32(buf.Bytes()[:4]); got != uint32(len(`{"type":"ping"}`)) {
+		t.Fatalf("length prefix = %d", got)
+	}
+
+	for _, want := range []string{`{"type":"ping"}`, `[1,2]`} {
+		msg, err := Read(&buf)
+		if err != nil || string(msg) != want {
+			t.Fatalf("Read = %q, %v; want %q", msg, err, want)
+		}
+	}
+	if _, err := Read(&buf); err != io.EOF {
+		t.Fatalf("Read at end = %v, want io.EOF", err)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.NativeEndian, uint32(MaxIncoming+1))
+	if _, err := Read(&buf); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("oversized incoming = %v", err)
+	}
+	if err := Write(io.Discard, string(make([]byte, MaxOutgoing))); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("oversized outgoing = %v", err)
+	}
+
+	// A message cut short is an error, not a clean end
+	buf.Reset()
+	_ = binary.Write(&buf, binary.NativeEndian, uint32(10))
+	buf.WriteString("{}")
+	if _, err := Read(&buf); err == nil || err == io.EOF {
+		t.Fatalf("truncated message = %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package processing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// Outcomes of a single item in a batch add
+const (
+	BatchItemQueued     = "queued"      // Written and handed to the engine
+	BatchItemSkipped    = "skipped"     // In the download archive, left out without failing the batch
+	BatchItemFailed     = "failed"      // This item stopped the batch
+	BatchItemRolledBack = "rolled_back" // Fine on its own, undone because another item failed
+)
+
+// ErrBatchRolledBack means no download of a batch was queued
+var ErrBatchRolledBack = errors.New("batch rolled back")
+
+// BatchItemResult is the outcome of one request of a batch add
+type BatchItemResult struct {
+	URL      string `json:"url"`
+	ID       string `json:"id,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+// BatchReport lists the outcome of every request of a batch add, in order
+type BatchReport struct {
+	Items []BatchItemResult `json:"items"`
+}
+
+// Count returns how many items ended with status
+func (r *BatchReport) Count(status string) int {
+	n := 0
+	for _, item := range r.Items {
+		if item.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// EnqueueBatch queues reqs as a unit. Every request is probed and reserved
+// first; only if all succeed are their rows written in a single transaction
+// and handed to the engine. Otherwise every reservation is released, nothing
+// is queued and the error wraps ErrBatchRolledBack. The report is returned
+// either way. Requests for archived URLs are skipped without failing the
+// batch.
+func (mgr *LifecycleManager) EnqueueBatch(ctx context.Context, reqs []*DownloadRequest) (*BatchReport, error) {
+	if mgr.addWithIDFunc == nil {
+		return nil, fmt.Errorf("addWithID function unavailable")
+	}
+
+	utils.Debug("Lifecycle: EnqueueBatch %d downloads", len(reqs))
+	report := &BatchReport{Items: make([]BatchItemResult, len(reqs))}
+	reserved := make([]*reservation, len(reqs))
+
+	rollback := func(cause error) (*BatchReport, error) {
+		for i, res := range reserved {
+			if res != nil {
+				res.release()
+			}
+			if st := report.Items[i].Status; st != BatchItemFailed && st != BatchItemSkipped {
+				report.Items[i].Status = BatchItemRolledBack
+				report.Items[i].ID = ""
+			}
+		}
+		return report, fmt.Errorf("%w: %w", ErrBatchRolledBack, cause)
+	}
+
+	// Keep going past a failure so the report names every bad item
+	var firstErr error
+	for i, req := range reqs {
+		report.Items[i].URL = req.URL
+		if ctx.Err() != nil {
+			return rollback(fmt.Errorf("enqueue aborted: %w", ctx.Err()))
+		}
+
+		res, err := mgr.reserve(ctx, req)
+		if errors.Is(err, ErrAlreadyDownloaded) {
+			report.Items[i].Status = BatchItemSkipped
+			report.Items[i].Error = err.Error()
+			continue
+		}
+		if err != nil {
+			report.Items[i].Status = BatchItemFailed
+			report.Items[i].Error = err.Error()
+			recordEnqueueOutcome(req.URL, err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", req.URL, err)
+			}
+			continue
+		}
+		reserved[i] = res
+		report.Items[i].ID = uuid.New().String()
+		report.Items[i].Filename = res.filename
+	}
+	if firstErr != nil {
+		return rollback(firstErr)
+	}
+
+	// rowItems maps each row back to its item, as skipped items have none
+	var rows []state.QueuedDownload
+	var rowItems []int
+	for i, req := range reqs {
+		res := reserved[i]
+		if res == nil {
+			continue
+		}
+		rowItems = append(rowItems, i)
+		rows = append(rows, state.QueuedDownload{
+			Entry: types.DownloadEntry{
+				ID:        report.Items[i].ID,
+				URL:       req.URL,
+				DestPath:  res.destPath(),
+				Filename:  res.filename,
+				TotalSize: res.probe.FileSize,
+				Mirrors:   append([]string(nil), req.Mirrors...),
+				Tags:      utils.NormalizeTags(req.Tags),
+				Category:  res.category,
+				Overrides: req.Overrides(),
+			},
+			Probe: res.probe.Cache(),
+		})
+	}
+	if err := state.InsertQueuedDownloads(rows); err != nil {
+		var itemErr *state.BatchInsertError
+		if errors.As(err, &itemErr) && itemErr.Index < len(rowItems) {
+			i := rowItems[itemErr.Index]
+			report.Items[i].Status = BatchItemFailed
+			report.Items[i].Error = itemErr.Err.Error()
+		}
+		return rollback(fmt.Errorf("failed to save batch: %w", err))
+	}
+
+	// The rows are committed, so a dispatch failure here leaves the download
+	// queued for the next start rather than undoing the batch
+	for _, i := range rowItems {
+		req, res := reqs[i], reserved[i]
+		item := &report.Items[i]
+		item.Status = BatchItemQueued
+		if _, err := mgr.addWithIDFunc(req.resolved(res.path, res.filename, res.category, res.probe), item.ID); err != nil {
+			utils.Debug("Lifecycle: Batch dispatch of %s failed, left queued: %v", item.ID, err)
+			item.Error = err.Error()
+		}
+		recordEnqueueOutcome(req.URL, nil)
+	}
+	return report, nil
+}
@@ -0,0 +1,139 @@
+package processing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/testutil"
+)
+
+func TestLifecycleManager_EnqueueBatch_CommitsAllTogether(t *testing.T) {
+	testutil.SetupStateDB(t)
+	server := newProbeTestServer(t, 2048)
+	defer server.Close()
+
+	dir := t.TempDir()
+	mgr := newLifecycleManagerForTest()
+	var dispatched []string
+	mgr.addWithIDFunc = func(req *DownloadRequest, id string) (string, error) {
+		entry, err := state.GetDownload(id)
+		if err != nil || entry == nil || entry.Status != "queued" {
+			t.Errorf("expected row for %s to be committed before dispatch, got %+v, %v", id, entry, err)
+		}
+		dispatched = append(dispatched, id)
+		return id, nil
+	}
+
+	report, err := mgr.EnqueueBatch(context.Background(), []*DownloadRequest{
+		{URL: server.URL + "/a.bin", Path: dir},
+		{URL: server.URL + "/b.bin", Path: dir},
+	})
+	if err != nil {
+		t.Fatalf("EnqueueBatch failed: %v", err)
+	}
+	if report.Count(BatchItemQueued) != 2 || len(dispatched) != 2 {
+		t.Fatalf("expected both items queued and dispatched, got %+v", report.Items)
+	}
+	for i, item := range report.Items {
+		if item.ID != dispatched[i] {
+			t.Errorf("item %d: report id %q, dispatched %q", i, item.ID, dispatched[i])
+		}
+		if probe, _ := state.GetProbeCache(item.ID); probe == nil || probe.FileSize != 2048 {
+			t.Errorf("item %d: expected probe cache with the row, got %+v", i, probe)
+		}
+	}
+}
+
+func TestLifecycleManager_EnqueueBatch_RollsBackOnFailure(t *testing.T) {
+	testutil.SetupStateDB(t)
+	good := newProbeTestServer(t, 2048)
+	defer good.Close()
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer bad.Close()
+
+	dir := t.TempDir()
+	mgr := newLifecycleManagerForTest()
+	mgr.addWithIDFunc = func(_ *DownloadRequest, id string) (string, error) {
+		t.Errorf("nothing should be dispatched from a failed batch, got %s", id)
+		return id, nil
+	}
+
+	report, err := mgr.EnqueueBatch(context.Background(), []*DownloadRequest{
+		{URL: good.URL + "/a.bin", Path: dir},
+		{URL: bad.URL + "/missing.bin", Path: dir},
+	})
+	if !errors.Is(err, ErrBatchRolledBack) {
+		t.Fatalf("expected ErrBatchRolledBack, got %v", err)
+	}
+	if report == nil || len(report.Items) != 2 {
+		t.Fatalf("expected a report for every item, got %+v", report)
+	}
+	if report.Items[0].Status != BatchItemRolledBack || report.Items[0].ID != "" {
+		t.Errorf("expected the good item to be rolled back, got %+v", report.Items[0])
+	}
+	if report.Items[1].Status != BatchItemFailed || !strings.Contains(report.Items[1].Error, "404") {
+		t.Errorf("expected the bad item to carry its error, got %+v", report.Items[1])
+	}
+
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), types.IncompleteSuffix) {
+			t.Errorf("expected reservations to be released, found %s", filepath.Join(dir, e.Name()))
+		}
+	}
+	if list, _ := state.ListAllDownloads(); len(list) != 0 {
+		t.Errorf("expected no rows after rollback, got %+v", list)
+	}
+}
+
+func TestLifecycleManager_EnqueueBatch_SkipsArchivedURLs(t *testing.T) {
+	testutil.SetupStateDB(t)
+	server := newProbeTestServer(t, 2048)
+	defer server.Close()
+
+	archived := server.URL + "/old.bin"
+	if err := state.ArchiveURL(archived); err != nil {
+		t.Fatalf("ArchiveURL: %v", err)
+	}
+
+	dir := t.TempDir()
+	mgr := newLifecycleManagerForTest()
+	mgr.addWithIDFunc = func(req *DownloadRequest, id string) (string, error) {
+		if req.URL == archived {
+			t.Errorf("archived URL was dispatched")
+		}
+		return id, nil
+	}
+
+	report, err := mgr.EnqueueBatch(context.Background(), []*DownloadRequest{
+		{URL: archived, Path: dir, DownloadArchive: true},
+		{URL: server.URL + "/new.bin", Path: dir, DownloadArchive: true},
+	})
+	if err != nil {
+		t.Fatalf("an archived URL must not roll back the batch: %v", err)
+	}
+	if report.Items[0].Status != BatchItemSkipped || report.Items[1].Status != BatchItemQueued {
+		t.Fatalf("unexpected report: %+v", report.Items)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "old.bin") + types.IncompleteSuffix); !os.IsNotExist(err) {
+		t.Errorf("skipped item must not reserve a working file: %v", err)
+	}
+
+	mgr.addFunc = func(req *DownloadRequest) (string, error) {
+		t.Errorf("archived URL was queued")
+		return "", nil
+	}
+	if _, err := mgr.Enqueue(context.Background(), &DownloadRequest{URL: archived, Path: dir, DownloadArchive: true}); !errors.Is(err, ErrAlreadyDownloaded) {
+		t.Errorf("Enqueue of archived URL: err = %v, want ErrAlreadyDownloaded", err)
+	}
+}
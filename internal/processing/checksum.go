@@ -0,0 +1,94 @@
+package processing
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// HashFile returns the hex SHA-256 of the file at path
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	if _, err := io.CopyBuffer(h, f, make([]byte, 1<<20)); err != nil {
+		return "", fmt.Errorf("failed to hash file: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// recordChecksum hashes a completed download's file and stores the result
+func recordChecksum(id, path string) {
+	sum, err := HashFile(path)
+	if err != nil {
+		utils.Debug("Lifecycle: Failed to hash %s: %v", path, err)
+		return
+	}
+	if err := state.SetChecksum(id, sum); err != nil {
+		utils.Debug("Lifecycle: Failed to store checksum for %s: %v", id, err)
+	}
+}
+
+// VerifyDownload re-hashes a completed download's file, compares it with the
+// stored checksum and records the outcome. A download without a checksum
+// gets one.
+func VerifyDownload(entry types.DownloadEntry) types.VerifyResult {
+	res := types.VerifyResult{
+		ID:       entry.ID,
+		Filename: entry.Filename,
+		Path:     entry.DestPath,
+		Expected: entry.Checksum,
+	}
+	if entry.Status != "completed" {
+		res.Error = fmt.Sprintf("download is %s, not completed", entry.Status)
+		return res
+	}
+
+	info, err := os.Stat(entry.DestPath)
+	switch {
+	case os.IsNotExist(err) && entry.VerifyStatus == types.VerifyUnverifiable:
+		res.Status = types.VerifyUnverifiable
+	case os.IsNotExist(err):
+		res.Status = types.VerifyMissing
+	case err != nil:
+		res.Error = err.Error()
+		return res
+	case !info.Mode().IsRegular():
+		res.Error = fmt.Sprintf("%s is not a regular file", entry.DestPath)
+		return res
+	default:
+		sum, err := HashFile(entry.DestPath)
+		if err != nil {
+			res.Error = err.Error()
+			return res
+		}
+		res.Actual = sum
+		switch {
+		case entry.Checksum == "":
+			res.Status = types.VerifyHashed
+			if err := state.SetChecksum(entry.ID, sum); err != nil {
+				res.Error = err.Error()
+				return res
+			}
+		case sum == entry.Checksum:
+			res.Status = types.VerifyOK
+		default:
+			res.Status = types.VerifyCorrupted
+		}
+	}
+
+	if err := state.RecordVerification(entry.ID, res.Status); err != nil {
+		res.Error = err.Error()
+	}
+	return res
+}
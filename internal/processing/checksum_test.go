@@ -0,0 +1,139 @@
+package processing
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/testutil"
+)
+
+func TestCompletion_RecordsChecksum(t *testing.T) {
+	dir := testutil.SetupStateDB(t)
+	finalPath := filepath.Join(dir, "data.bin")
+	if err := os.WriteFile(finalPath+types.IncompleteSuffix, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.AddToMasterList(types.DownloadEntry{ID: "sum", URL: "https://example.com/data.bin", DestPath: finalPath, Filename: "data.bin", Status: "downloading"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Record what the database holds at the moment completion is announced
+	var phases []string
+	atComplete := make(chan string, 1)
+	mgr := NewLifecycleManager(nil, nil)
+	mgr.SetEngineHooks(EngineHooks{PublishEvent: func(msg interface{}) error {
+		if m, ok := msg.(events.DownloadPhaseMsg); ok {
+			phases = append(phases, m.Phase)
+			if m.Phase == types.PhaseComplete {
+				entry, _ := state.GetDownload("sum")
+				atComplete <- entry.Checksum
+			}
+		}
+		return nil
+	}})
+
+	ch := make(chan interface{}, 1)
+	ch <- events.DownloadCompleteMsg{DownloadID: "sum", Filename: "data.bin", Elapsed: time.Second, Total: 5}
+	close(ch)
+	mgr.StartEventWorker(ch)
+	mgr.WaitPostProcessing()
+
+	// sha256("hello")
+	const want = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	select {
+	case got := <-atComplete:
+		if got != want {
+			t.Fatalf("checksum when complete = %q, want %q", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("download never reported complete")
+	}
+	if n := len(phases); n < 2 || phases[n-2] != types.PhaseVerifying {
+		t.Errorf("phases = %v, want verifying right before complete", phases)
+	}
+}
+
+func TestVerifyDownload(t *testing.T) {
+	dir := testutil.SetupStateDB(t)
+
+	add := func(id, body string, checksum string) types.DownloadEntry {
+		t.Helper()
+		path := filepath.Join(dir, id)
+		if body != "" {
+			if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := state.AddToMasterList(types.DownloadEntry{ID: id, URL: "https://example.com/" + id, DestPath: path, Filename: id, Status: "completed"}); err != nil {
+			t.Fatal(err)
+		}
+		if checksum != "" {
+			if err := state.SetChecksum(id, checksum); err != nil {
+				t.Fatal(err)
+			}
+		}
+		entry, err := state.GetDownload(id)
+		if err != nil || entry == nil {
+			t.Fatalf("GetDownload(%s): %v", id, err)
+		}
+		return *entry
+	}
+
+	sum := func(body string) string {
+		p := filepath.Join(t.TempDir(), "f")
+		_ = os.WriteFile(p, []byte(body), 0o644)
+		s, err := HashFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	// An archive the extract step deleted afterwards
+	extracted := func(id string) types.DownloadEntry {
+		t.Helper()
+		add(id, "", sum("archive"))
+		if err := state.RecordVerification(id, types.VerifyUnverifiable); err != nil {
+			t.Fatal(err)
+		}
+		entry, _ := state.GetDownload(id)
+		return *entry
+	}
+
+	tests := []struct {
+		entry  types.DownloadEntry
+		want   string
+		failed bool
+	}{
+		{add("intact", "same", sum("same")), types.VerifyOK, false},
+		{add("changed", "tampered", sum("original")), types.VerifyCorrupted, true},
+		{add("gone", "", sum("anything")), types.VerifyMissing, true},
+		{add("legacy", "old file", ""), types.VerifyHashed, false},
+		{extracted("unpacked"), types.VerifyUnverifiable, false},
+	}
+	for _, tt := range tests {
+		res := VerifyDownload(tt.entry)
+		if res.Status != tt.want || res.Failed() != tt.failed || res.Error != "" {
+			t.Errorf("%s: result = %+v, want %s", tt.entry.ID, res, tt.want)
+		}
+		stored, _ := state.GetDownload(tt.entry.ID)
+		if stored.VerifyStatus != tt.want || stored.VerifiedAt == 0 {
+			t.Errorf("%s: stored verification = %q at %d", tt.entry.ID, stored.VerifyStatus, stored.VerifiedAt)
+		}
+	}
+
+	// The legacy download now has a checksum to verify against
+	if stored, _ := state.GetDownload("legacy"); stored.Checksum != sum("old file") {
+		t.Errorf("legacy checksum = %q", stored.Checksum)
+	}
+
+	paused := types.DownloadEntry{ID: "p", Status: "paused"}
+	if res := VerifyDownload(paused); res.Error == "" || !res.Failed() {
+		t.Errorf("paused download verified: %+v", res)
+	}
+}
@@ -0,0 +1,164 @@
+package processing
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+var (
+	// ErrInsufficientSpace means the destination cannot hold the download
+	ErrInsufficientSpace = errors.New("not enough disk space")
+	// ErrFileTooLarge means the destination filesystem caps file sizes below the download's size
+	ErrFileTooLarge = errors.New("file too large for destination filesystem")
+)
+
+// fatMaxFileSize is the largest file FAT12/16/32 can store (4 GiB - 1).
+// exFAT has no such limit.
+const fatMaxFileSize = 4*types.GB - 1
+
+// diskInfo describes the filesystem a destination lives on
+type diskInfo struct {
+	Free        uint64 // Bytes available to the current user
+	FSType      string // Filesystem name, lowercase, "" if unknown
+	Volume      string // Identifies the filesystem, "" if unknown
+	MaxFileSize int64  // 0 = no known limit
+}
+
+var (
+	// statDestinationDisk and allocatedDiskBytes are swapped out in tests
+	statDestinationDisk = statDisk
+	allocatedDiskBytes  = allocatedBytes
+)
+
+// diskClaim is space promised to a queued or running download whose working
+// file has not grown to its full size yet
+type diskClaim struct {
+	volume    string
+	size      int64
+	confirmed bool // The working file exists; a missing file now means the claim is over
+}
+
+var (
+	diskClaimsMu sync.Mutex
+	diskClaims   = make(map[string]*diskClaim) // working file path -> claim
+)
+
+// checkDiskCapacity fails early when a download of size bytes cannot fit in
+// dir, rather than hitting ENOSPC or EFBIG halfway through. The working file
+// (with the incomplete suffix) is sparse unless dense preallocation is on, so
+// free space shrinks as data arrives rather than up front; the full size must
+// still be available, minus what other queued and running downloads on the
+// same volume have yet to write. On success the space is claimed for
+// workingPath until that file is gone (completed, removed or never created).
+// Unknown sizes and filesystems that cannot be inspected are let through.
+func checkDiskCapacity(dir, workingPath string, size int64) error {
+	if size <= 0 {
+		return nil
+	}
+
+	info, err := statDestinationDisk(existingAncestor(dir))
+	if err != nil {
+		utils.Debug("Disk check skipped for %s: %v", dir, err)
+		return nil
+	}
+
+	if info.MaxFileSize > 0 && size > info.MaxFileSize {
+		return fmt.Errorf("%w: %s is on %s, which cannot store files over %s (download is %s)",
+			ErrFileTooLarge, dir, info.FSType,
+			utils.ConvertBytesToHumanReadable(info.MaxFileSize), utils.ConvertBytesToHumanReadable(size))
+	}
+
+	diskClaimsMu.Lock()
+	defer diskClaimsMu.Unlock()
+
+	pending := pendingClaimsLocked(info.Volume)
+	if uint64(size+pending) > info.Free {
+		if pending > 0 {
+			return fmt.Errorf("%w at %s: need %s, only %s free of which %s is needed by other downloads",
+				ErrInsufficientSpace, dir,
+				utils.ConvertBytesToHumanReadable(size), utils.ConvertBytesToHumanReadable(int64(info.Free)),
+				utils.ConvertBytesToHumanReadable(pending))
+		}
+		return fmt.Errorf("%w at %s: need %s, only %s free",
+			ErrInsufficientSpace, dir,
+			utils.ConvertBytesToHumanReadable(size), utils.ConvertBytesToHumanReadable(int64(info.Free)))
+	}
+	if info.Volume != "" && workingPath != "" {
+		diskClaims[workingPath] = &diskClaim{volume: info.Volume, size: size}
+	}
+	return nil
+}
+
+// pendingClaimsLocked sums the bytes claimed downloads on volume still have to
+// write, dropping claims whose working file is gone. Callers hold diskClaimsMu.
+func pendingClaimsLocked(volume string) int64 {
+	if volume == "" {
+		return 0
+	}
+	var pending int64
+	for path, c := range diskClaims {
+		if c.volume != volume {
+			continue
+		}
+		allocated, err := allocatedDiskBytes(path)
+		if err != nil {
+			if c.confirmed && errors.Is(err, os.ErrNotExist) {
+				delete(diskClaims, path)
+				continue
+			}
+			allocated = 0
+		}
+		if remaining := c.size - allocated; remaining > 0 {
+			pending += remaining
+		}
+	}
+	return pending
+}
+
+// confirmDiskClaim marks workingPath's claim as backed by a file on disk
+func confirmDiskClaim(workingPath string) {
+	diskClaimsMu.Lock()
+	if c, ok := diskClaims[workingPath]; ok {
+		c.confirmed = true
+	}
+	diskClaimsMu.Unlock()
+}
+
+// releaseDiskClaim gives up workingPath's claim
+func releaseDiskClaim(workingPath string) {
+	diskClaimsMu.Lock()
+	delete(diskClaims, workingPath)
+	diskClaimsMu.Unlock()
+}
+
+// FreeSpace returns the bytes available to the current user on the
+// filesystem dir is on, or would be created on
+func FreeSpace(dir string) (uint64, error) {
+	info, err := statDestinationDisk(existingAncestor(dir))
+	if err != nil {
+		return 0, err
+	}
+	return info.Free, nil
+}
+
+// existingAncestor returns dir or its nearest parent that exists, since the
+// destination directory is only created when the working file is reserved
+func existingAncestor(dir string) string {
+	dir = filepath.Clean(dir)
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
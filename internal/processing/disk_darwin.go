@@ -0,0 +1,39 @@
+//go:build darwin
+
+package processing
+
+import (
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+func statDisk(dir string) (diskInfo, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(dir, &st); err != nil {
+		return diskInfo{}, err
+	}
+
+	info := diskInfo{
+		Free:   st.Bavail * uint64(st.Bsize),
+		FSType: unix.ByteSliceToString(st.Fstypename[:]),
+	}
+	var dst unix.Stat_t
+	if err := unix.Stat(dir, &dst); err == nil {
+		info.Volume = strconv.FormatUint(uint64(dst.Dev), 10)
+	}
+	if info.FSType == "msdos" {
+		info.MaxFileSize = fatMaxFileSize
+	}
+	return info, nil
+}
+
+// allocatedBytes returns the disk space path actually occupies, which for a
+// sparse working file is only the ranges written so far
+func allocatedBytes(path string) (int64, error) {
+	var st unix.Stat_t
+	if err := unix.Stat(path, &st); err != nil {
+		return 0, err
+	}
+	return st.Blocks * 512, nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !windows
+
+package processing
+
+import "errors"
+
+func statDisk(string) (diskInfo, error) {
+	return diskInfo{}, errors.New("disk inspection not supported on this platform")
+}
+
+func allocatedBytes(string) (int64, error) {
+	return 0, errors.New("disk inspection not supported on this platform")
+}
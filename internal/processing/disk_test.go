@@ -0,0 +1,119 @@
+package processing
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestCheckDiskCapacity(t *testing.T) {
+	orig := statDestinationDisk
+	t.Cleanup(func() { statDestinationDisk = orig })
+
+	tests := []struct {
+		name string
+		info diskInfo
+		size int64
+		want error
+	}{
+		{"fits", diskInfo{Free: 10 * types.GB}, 5 * types.GB, nil},
+		{"unknown size", diskInfo{Free: 0}, 0, nil},
+		{"no space", diskInfo{Free: types.GB}, 2 * types.GB, ErrInsufficientSpace},
+		{"fat32 limit", diskInfo{Free: 100 * types.GB, FSType: "vfat", MaxFileSize: fatMaxFileSize}, 5 * types.GB, ErrFileTooLarge},
+		{"fat32 small file", diskInfo{Free: 100 * types.GB, FSType: "vfat", MaxFileSize: fatMaxFileSize}, 3 * types.GB, nil},
+		{"exfat large file", diskInfo{Free: 100 * types.GB, FSType: "exfat"}, 50 * types.GB, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statDestinationDisk = func(string) (diskInfo, error) { return tt.info, nil }
+			err := checkDiskCapacity(t.TempDir(), "", tt.size)
+			if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+				t.Fatalf("checkDiskCapacity() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// A failing inspection never blocks the download
+	statDestinationDisk = func(string) (diskInfo, error) { return diskInfo{}, errors.New("boom") }
+	if err := checkDiskCapacity(t.TempDir(), "", types.GB); err != nil {
+		t.Fatalf("expected inspection failure to be ignored, got %v", err)
+	}
+}
+
+func TestCheckDiskCapacity_InspectsNearestExistingParent(t *testing.T) {
+	base := t.TempDir()
+	var got string
+	orig := statDestinationDisk
+	t.Cleanup(func() { statDestinationDisk = orig })
+	statDestinationDisk = func(dir string) (diskInfo, error) {
+		got = dir
+		return orig(dir)
+	}
+
+	if err := checkDiskCapacity(filepath.Join(base, "not", "yet", "created"), "", 1); err != nil {
+		t.Fatalf("expected a 1 byte download to fit: %v", err)
+	}
+	if got != base {
+		t.Fatalf("inspected %q, want %q", got, base)
+	}
+}
+
+func TestCheckDiskCapacity_CountsSpaceClaimedByOtherDownloads(t *testing.T) {
+	origStat, origAlloc := statDestinationDisk, allocatedDiskBytes
+	t.Cleanup(func() {
+		statDestinationDisk, allocatedDiskBytes = origStat, origAlloc
+		diskClaimsMu.Lock()
+		diskClaims = make(map[string]*diskClaim)
+		diskClaimsMu.Unlock()
+	})
+	statDestinationDisk = func(string) (diskInfo, error) {
+		return diskInfo{Free: 20 * types.GB, Volume: "vol"}, nil
+	}
+	written := map[string]int64{}
+	allocatedDiskBytes = func(path string) (int64, error) {
+		n, ok := written[path]
+		if !ok {
+			return 0, os.ErrNotExist
+		}
+		return n, nil
+	}
+
+	dir := t.TempDir()
+	claim := func(name string) error {
+		p := filepath.Join(dir, name+types.IncompleteSuffix)
+		if err := checkDiskCapacity(dir, p, 5*types.GB); err != nil {
+			return err
+		}
+		written[p] = 0
+		confirmDiskClaim(p)
+		return nil
+	}
+
+	// 4 x 5 GB fill the 20 GB; the fifth must be refused up front
+	for i := 0; i < 4; i++ {
+		if err := claim(fmt.Sprintf("part%d", i)); err != nil {
+			t.Fatalf("download %d: %v", i, err)
+		}
+	}
+	if err := claim("part4"); !errors.Is(err, ErrInsufficientSpace) {
+		t.Fatalf("fifth download: got %v, want ErrInsufficientSpace", err)
+	}
+
+	// A finished download's working file is renamed away, freeing its claim
+	delete(written, filepath.Join(dir, "part0"+types.IncompleteSuffix))
+	if err := claim("part4"); err != nil {
+		t.Fatalf("expected space after one download finished: %v", err)
+	}
+
+	// Other volumes are unaffected
+	statDestinationDisk = func(string) (diskInfo, error) {
+		return diskInfo{Free: 6 * types.GB, Volume: "other"}, nil
+	}
+	if err := checkDiskCapacity(dir, filepath.Join(dir, "x"+types.IncompleteSuffix), 5*types.GB); err != nil {
+		t.Fatalf("claims on another volume were counted: %v", err)
+	}
+}
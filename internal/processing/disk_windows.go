@@ -0,0 +1,58 @@
+//go:build windows
+
+package processing
+
+import (
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// invalidFileSize is GetCompressedFileSizeW's failure marker for the low word
+const invalidFileSize = 0xFFFFFFFF
+
+var procGetCompressedFileSizeW = windows.NewLazySystemDLL("kernel32.dll").NewProc("GetCompressedFileSizeW")
+
+func statDisk(dir string) (diskInfo, error) {
+	dirPtr, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return diskInfo{}, err
+	}
+
+	var info diskInfo
+	if err := windows.GetDiskFreeSpaceEx(dirPtr, &info.Free, nil, nil); err != nil {
+		return diskInfo{}, err
+	}
+
+	// Filesystem type is best effort; free space alone is still useful
+	volume := make([]uint16, windows.MAX_PATH+1)
+	if err := windows.GetVolumePathName(dirPtr, &volume[0], uint32(len(volume))); err != nil {
+		return info, nil
+	}
+	info.Volume = strings.ToLower(windows.UTF16ToString(volume))
+	fsName := make([]uint16, windows.MAX_PATH+1)
+	if err := windows.GetVolumeInformation(&volume[0], nil, 0, nil, nil, nil, &fsName[0], uint32(len(fsName))); err != nil {
+		return info, nil
+	}
+	info.FSType = strings.ToLower(windows.UTF16ToString(fsName))
+	if info.FSType == "fat" || info.FSType == "fat32" {
+		info.MaxFileSize = fatMaxFileSize
+	}
+	return info, nil
+}
+
+// allocatedBytes returns the disk space path actually occupies, which for a
+// sparse working file is only the ranges written so far
+func allocatedBytes(path string) (int64, error) {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var high uint32
+	low, _, callErr := procGetCompressedFileSizeW.Call(uintptr(unsafe.Pointer(pathPtr)), uintptr(unsafe.Pointer(&high)))
+	if uint32(low) == invalidFileSize && callErr != windows.ERROR_SUCCESS {
+		return 0, callErr
+	}
+	return int64(high)<<32 | int64(uint32(low)), nil
+}
@@ -15,7 +15,7 @@ import (
 
 var (
 	renameCompletedFile = retryRename
-	copyCompletedFile   = utils.CopyFile
+	copyCompletedFile   = utils.CopyFileWithProgress
 )
 
 // advanceRemainingTasks keeps saved chunk boundaries aligned when pause
@@ -44,31 +44,53 @@ func advanceRemainingTasks(tasks []types.Task, consumed int64) []types.Task {
 	return out
 }
 
-func finalizeCompletedFile(finalPath string) error {
+// finalizeCompletedFile promotes the working file to finalPath. onProgress,
+// if set, is called with bytes copied and the file size when the move falls
+// back to a copy across devices.
+func finalizeCompletedFile(finalPath string, onProgress func(copied, total int64)) error {
 	if finalPath == "" {
 		return fmt.Errorf("missing destination path for completed download")
 	}
 
-	surgePath := finalPath + types.IncompleteSuffix
-	if err := renameCompletedFile(surgePath, finalPath); err != nil {
-		if errors.Is(err, syscall.EXDEV) {
-			if err := copyCompletedFile(surgePath, finalPath); err != nil {
-				_ = os.Remove(finalPath)
-				return fmt.Errorf("copy completed file: %w", err)
+	if err := moveFile(finalPath+types.IncompleteSuffix, finalPath, onProgress); err != nil {
+		// A failed rename after an earlier promotion is not an error
+		var linkErr *os.LinkError
+		if errors.As(err, &linkErr) {
+			if _, statErr := os.Stat(finalPath); statErr == nil {
+				return nil
 			}
-			if err := retryRemove(surgePath); err != nil {
-				return fmt.Errorf("remove copied working file: %w", err)
-			}
-			return nil
-		}
-		if _, statErr := os.Stat(finalPath); statErr == nil {
-			return nil
 		}
 		return err
 	}
 	return nil
 }
 
+// moveFile renames src to dst, copying and removing src when they are on
+// different devices. onProgress is as for finalizeCompletedFile.
+func moveFile(src, dst string, onProgress func(copied, total int64)) error {
+	err := renameCompletedFile(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	var report func(int64)
+	if onProgress != nil {
+		var total int64
+		if info, err := os.Stat(src); err == nil {
+			total = info.Size()
+		}
+		report = func(copied int64) { onProgress(copied, total) }
+	}
+	if err := copyCompletedFile(src, dst, report); err != nil {
+		_ = os.Remove(dst)
+		return fmt.Errorf("copy completed file: %w", err)
+	}
+	if err := retryRemove(src); err != nil {
+		return fmt.Errorf("remove copied working file: %w", err)
+	}
+	return nil
+}
+
 // StartEventWorker listens to engine events and handles database persistence
 // and file cleanup, ensuring the core engine remains stateless.
 func (mgr *LifecycleManager) StartEventWorker(ch <-chan interface{}) {
@@ -87,6 +109,9 @@ func (mgr *LifecycleManager) StartEventWorker(ch <-chan interface{}) {
 				Status:     "downloading",
 				TotalSize:  m.Total,
 				Downloaded: 0,
+				Tags:       m.Tags,
+				Category:   m.Category,
+				TraceID:    m.TraceID,
 			}
 			if existing, _ := state.GetDownload(m.DownloadID); existing != nil {
 				entry.Mirrors = append([]string(nil), existing.Mirrors...)
@@ -97,6 +122,7 @@ func (mgr *LifecycleManager) StartEventWorker(ch <-chan interface{}) {
 			if err := state.AddToMasterList(entry); err != nil {
 				utils.Debug("Lifecycle: Failed to save initial download state: %v", err)
 			}
+			mgr.flushProbe(m.DownloadID)
 
 		case events.DownloadPausedMsg:
 			if m.State == nil {
@@ -108,6 +134,7 @@ func (mgr *LifecycleManager) StartEventWorker(ch <-chan interface{}) {
 
 				entry := *existing
 				entry.Status = "paused"
+				entry.PauseReason = pauseReason(m)
 				if m.Downloaded > 0 {
 					entry.Downloaded = m.Downloaded
 				}
@@ -160,13 +187,14 @@ func (mgr *LifecycleManager) StartEventWorker(ch <-chan interface{}) {
 			}
 
 			entry := types.DownloadEntry{
-				ID:         m.DownloadID,
-				Status:     "paused",
-				Downloaded: m.State.Downloaded,
-				DestPath:   destPath,
-				Filename:   m.Filename,
-				TotalSize:  m.State.TotalSize,
-				TimeTaken:  m.State.Elapsed / int64(time.Millisecond),
+				ID:          m.DownloadID,
+				Status:      "paused",
+				PauseReason: pauseReason(m),
+				Downloaded:  m.State.Downloaded,
+				DestPath:    destPath,
+				Filename:    m.Filename,
+				TotalSize:   m.State.TotalSize,
+				TimeTaken:   m.State.Elapsed / int64(time.Millisecond),
 			}
 			if existing != nil {
 				entry.URL = existing.URL
@@ -212,7 +240,19 @@ func (mgr *LifecycleManager) StartEventWorker(ch <-chan interface{}) {
 
 			// Completion only becomes durable once the working file is promoted, so a
 			// finalization failure must stay retryable instead of being recorded as done.
-			if err := finalizeCompletedFile(destPath); err != nil {
+			mgr.publishPhase(m.DownloadID, filename, types.PhaseMoving, 0)
+			lastPct := 0.0
+			moveProgress := func(copied, total int64) {
+				if total <= 0 {
+					return
+				}
+				pct := float64(copied) * 100 / float64(total)
+				if pct-lastPct >= 1 || copied >= total {
+					lastPct = pct
+					mgr.publishPhase(m.DownloadID, filename, types.PhaseMoving, pct)
+				}
+			}
+			if err := finalizeCompletedFile(destPath, moveProgress); err != nil {
 				utils.Debug("Lifecycle: Failed to finalize completed file at %s: %v", destPath, err)
 				if err := state.AddToMasterList(types.DownloadEntry{
 					ID:         m.DownloadID,
@@ -246,10 +286,39 @@ func (mgr *LifecycleManager) StartEventWorker(ch <-chan interface{}) {
 			}); err != nil {
 				utils.Debug("Lifecycle: Failed to persist completed download: %v", err)
 			}
+			if err := state.ArchiveURL(url); err != nil {
+				utils.Debug("Lifecycle: Failed to archive url: %v", err)
+			}
 			if err := state.DeleteTasks(m.DownloadID); err != nil {
 				utils.Debug("Lifecycle: Failed to delete completed tasks: %v", err)
 			}
 
+			var category string
+			if existing != nil {
+				category = existing.Category
+			}
+			steps := mgr.postProcessSteps(category)
+			// Hashing and post-processing can take a while; keep them off the
+			// event stream. The checksum is taken before any step can move or
+			// delete the file, and before the download is reported complete.
+			mgr.postJobs.Add(1)
+			go func(job *postJob) {
+				defer mgr.postJobs.Done()
+				mgr.publishPhase(job.id, job.filename(), types.PhaseVerifying, 0)
+				recordChecksum(job.id, job.path)
+				if len(steps) > 0 {
+					mgr.runPostProcess(job, steps)
+				} else {
+					mgr.publishPhase(job.id, job.filename(), types.PhaseComplete, 100)
+				}
+			}(&postJob{
+				id:       m.DownloadID,
+				url:      url,
+				category: category,
+				path:     destPath,
+				size:     m.Total,
+			})
+
 		case events.DownloadErrorMsg:
 			existing, _ := state.GetDownload(m.DownloadID)
 			destPath := m.DestPath
@@ -258,6 +327,23 @@ func (mgr *LifecycleManager) StartEventWorker(ch <-chan interface{}) {
 				if err := state.AddToMasterList(*existing); err != nil {
 					utils.Debug("Lifecycle: Failed to persist error state: %v", err)
 				}
+				errMsg := ""
+				if m.Err != nil {
+					errMsg = m.Err.Error()
+				}
+				if err := state.RecordURLHistory(existing.URL, state.URLHistoryFailed, errMsg); err != nil {
+					utils.Debug("Lifecycle: Failed to record url history: %v", err)
+				}
+				failure := types.DownloadFailure{
+					At:      time.Now().Unix(),
+					Kind:    types.FailureFatal,
+					Status:  types.HTTPStatus(m.Err),
+					Mirror:  existing.URL,
+					Message: errMsg,
+				}
+				if err := state.RecordDownloadFailure(m.DownloadID, failure); err != nil {
+					utils.Debug("Lifecycle: Failed to record error history: %v", err)
+				}
 				if existing.DestPath != "" {
 					destPath = existing.DestPath
 				}
@@ -269,6 +355,25 @@ func (mgr *LifecycleManager) StartEventWorker(ch <-chan interface{}) {
 			}
 
 		case events.DownloadRemovedMsg:
+			if m.Trashed {
+				// Like an archive, but completed downloads go too and the trash
+				// expires; PurgeTrash removes what is left once it does
+				if err := state.TrashDownload(m.DownloadID); err != nil {
+					utils.Debug("Lifecycle: Failed to trash download: %v", err)
+				}
+				mgr.pendingProbes.Delete(m.DownloadID)
+				break
+			}
+			if m.Archived {
+				// The pause before this saved the resume state; keep it and the working
+				// file, and only set the download aside until it is restored.
+				if err := state.UpdateStatus(m.DownloadID, "archived"); err != nil {
+					utils.Debug("Lifecycle: Failed to archive download: %v", err)
+				}
+				mgr.pendingProbes.Delete(m.DownloadID)
+				break
+			}
+
 			// Remove resume metadata before touching files so a deleted download does not
 			// come back during startup recovery.
 			if err := state.DeleteState(m.DownloadID); err != nil {
@@ -277,6 +382,7 @@ func (mgr *LifecycleManager) StartEventWorker(ch <-chan interface{}) {
 			if err := state.RemoveFromMasterList(m.DownloadID); err != nil {
 				utils.Debug("Lifecycle: Failed to remove from master list: %v", err)
 			}
+			mgr.pendingProbes.Delete(m.DownloadID)
 
 			// Only incomplete working files should be removed here; completed files have
 			// already been promoted to their final name by the completion path.
@@ -290,16 +396,19 @@ func (mgr *LifecycleManager) StartEventWorker(ch <-chan interface{}) {
 			// Queue persistence is what lets downloads survive shutdown before any worker
 			// has emitted a started event.
 			if err := state.AddToMasterList(types.DownloadEntry{
-				ID:       m.DownloadID,
-				URL:      m.URL,
-				URLHash:  state.URLHash(m.URL),
-				DestPath: m.DestPath,
-				Filename: m.Filename,
-				Mirrors:  append([]string(nil), m.Mirrors...),
-				Status:   "queued",
+				ID:        m.DownloadID,
+				URL:       m.URL,
+				URLHash:   state.URLHash(m.URL),
+				DestPath:  m.DestPath,
+				Filename:  m.Filename,
+				Mirrors:   append([]string(nil), m.Mirrors...),
+				Category:  m.Category,
+				Status:    "queued",
+				Overrides: m.Overrides,
 			}); err != nil {
 				utils.Debug("Lifecycle: Failed to persist queued download: %v", err)
 			}
+			mgr.flushProbe(m.DownloadID)
 
 		case events.BatchProgressMsg, events.ProgressMsg:
 			// Progress ticks are intentionally transient; persisting them would add
@@ -307,3 +416,29 @@ func (mgr *LifecycleManager) StartEventWorker(ch <-chan interface{}) {
 		}
 	}
 }
+
+// publishPhase reports a post-download phase to clients. It is best effort:
+// without an engine attached there is nobody to tell.
+func (mgr *LifecycleManager) publishPhase(id, filename, phase string, progress float64) {
+	hooks := mgr.getEngineHooks()
+	if hooks.PublishEvent == nil {
+		return
+	}
+	if err := hooks.PublishEvent(events.DownloadPhaseMsg{
+		DownloadID: id,
+		Filename:   filename,
+		Phase:      phase,
+		Progress:   progress,
+	}); err != nil {
+		utils.Debug("Lifecycle: Failed to publish %s phase for %s: %v", phase, id, err)
+	}
+}
+
+// pauseReason is why a pause event's download stopped. Events from engines
+// that don't say were paused on request.
+func pauseReason(m events.DownloadPausedMsg) string {
+	if m.Reason == "" {
+		return types.PauseUser
+	}
+	return m.Reason
+}
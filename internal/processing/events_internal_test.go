@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/surge-downloader/surge/internal/config"
 	"github.com/surge-downloader/surge/internal/engine/events"
 	"github.com/surge-downloader/surge/internal/engine/state"
 	"github.com/surge-downloader/surge/internal/engine/types"
@@ -33,7 +34,7 @@ func TestFinalizeCompletedFile_CopiesAcrossDevicesOnEXDEV(t *testing.T) {
 	renameCompletedFile = func(string, string) error {
 		return &os.LinkError{Op: "rename", Old: surgePath, New: finalPath, Err: syscall.EXDEV}
 	}
-	copyCompletedFile = func(src, dst string) error {
+	copyCompletedFile = func(src, dst string, _ func(int64)) error {
 		copied = true
 		data, err := os.ReadFile(src)
 		if err != nil {
@@ -42,7 +43,7 @@ func TestFinalizeCompletedFile_CopiesAcrossDevicesOnEXDEV(t *testing.T) {
 		return os.WriteFile(dst, data, 0o644)
 	}
 
-	if err := finalizeCompletedFile(finalPath); err != nil {
+	if err := finalizeCompletedFile(finalPath, nil); err != nil {
 		t.Fatalf("finalizeCompletedFile failed: %v", err)
 	}
 	if !copied {
@@ -100,6 +101,7 @@ func TestStartEventWorker_MarksCompletionAsErrorWhenFinalizationFails(t *testing
 	close(ch)
 
 	mgr.StartEventWorker(ch)
+	mgr.WaitPostProcessing()
 
 	entry, err := state.GetDownload("download-1")
 	if err != nil {
@@ -138,8 +140,292 @@ func TestStartEventWorker_RemovesIncompleteFileOnErrorWithoutDBEntry(t *testing.
 	close(ch)
 
 	mgr.StartEventWorker(ch)
+	mgr.WaitPostProcessing()
 
 	if _, err := os.Stat(surgePath); !os.IsNotExist(err) {
 		t.Fatalf("expected working file to be removed even without DB entry, stat err: %v", err)
 	}
 }
+
+func TestStartEventWorker_PublishesMovePhases(t *testing.T) {
+	tempDir := testutil.SetupStateDB(t)
+
+	finalPath := filepath.Join(tempDir, "big.iso")
+	surgePath := finalPath + types.IncompleteSuffix
+	if err := os.WriteFile(surgePath, make([]byte, 4096), 0o644); err != nil {
+		t.Fatalf("failed to create working file: %v", err)
+	}
+	if err := state.AddToMasterList(types.DownloadEntry{
+		ID:       "download-1",
+		URL:      "https://example.com/big.iso",
+		DestPath: finalPath,
+		Filename: "big.iso",
+		Status:   "downloading",
+	}); err != nil {
+		t.Fatalf("failed to seed download entry: %v", err)
+	}
+
+	// Force the cross-device copy path, which is the slow one worth reporting
+	origRename := renameCompletedFile
+	origCopy := copyCompletedFile
+	t.Cleanup(func() {
+		renameCompletedFile = origRename
+		copyCompletedFile = origCopy
+	})
+	renameCompletedFile = func(string, string) error {
+		return &os.LinkError{Op: "rename", Old: surgePath, New: finalPath, Err: syscall.EXDEV}
+	}
+	copyCompletedFile = func(src, dst string, onProgress func(int64)) error {
+		onProgress(1024)
+		onProgress(4096)
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(dst, data, 0o644)
+	}
+
+	var phases []events.DownloadPhaseMsg
+	completed := make(chan struct{})
+	mgr := NewLifecycleManager(nil, nil)
+	mgr.SetEngineHooks(EngineHooks{
+		PublishEvent: func(msg interface{}) error {
+			if m, ok := msg.(events.DownloadPhaseMsg); ok {
+				phases = append(phases, m)
+				if m.Phase == types.PhaseComplete {
+					close(completed)
+				}
+			}
+			return nil
+		},
+	})
+
+	ch := make(chan interface{}, 1)
+	ch <- events.DownloadCompleteMsg{DownloadID: "download-1", Filename: "big.iso", Elapsed: time.Second, Total: 4096}
+	close(ch)
+	mgr.StartEventWorker(ch)
+	mgr.WaitPostProcessing()
+
+	// Completion is reported once the checksum is stored
+	select {
+	case <-completed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("download never reported complete")
+	}
+
+	want := []struct {
+		phase    string
+		progress float64
+	}{
+		{types.PhaseMoving, 0},
+		{types.PhaseMoving, 25},
+		{types.PhaseMoving, 100},
+		{types.PhaseVerifying, 0},
+		{types.PhaseComplete, 100},
+	}
+	if len(phases) != len(want) {
+		t.Fatalf("got phases %+v, want %+v", phases, want)
+	}
+	for i, w := range want {
+		if phases[i].Phase != w.phase || phases[i].Progress != w.progress || phases[i].DownloadID != "download-1" {
+			t.Errorf("phase %d = %+v, want %s at %.0f%%", i, phases[i], w.phase, w.progress)
+		}
+	}
+}
+
+func TestLifecycle_ProbeCacheSurvivesToResume(t *testing.T) {
+	testutil.SetupStateDB(t)
+
+	mgr := NewLifecycleManager(nil, nil)
+	// Enqueue finishes before the event worker has written the row
+	mgr.rememberProbe("download-1", types.CachedProbe{FileSize: 4096, SupportsRange: true, ETag: `"v1"`})
+
+	ch := make(chan interface{}, 1)
+	ch <- events.DownloadQueuedMsg{
+		DownloadID: "download-1",
+		URL:        "https://example.com/file.bin",
+		DestPath:   "/tmp/file.bin",
+		Filename:   "file.bin",
+	}
+	close(ch)
+	mgr.StartEventWorker(ch)
+	mgr.WaitPostProcessing()
+
+	entry, err := state.GetDownload("download-1")
+	if err != nil || entry == nil {
+		t.Fatalf("expected queued entry, got %v, %v", entry, err)
+	}
+
+	cfg := buildResumeConfig("download-1", t.TempDir(), entry, nil, config.DefaultSettings())
+	if cfg.Probe == nil || cfg.Probe.FileSize != 4096 || !cfg.Probe.SupportsRange || cfg.Probe.ETag != `"v1"` {
+		t.Fatalf("expected cached probe on resume config, got %+v", cfg.Probe)
+	}
+	if cfg.TotalSize != 4096 {
+		t.Errorf("expected total size from cached probe, got %d", cfg.TotalSize)
+	}
+	if _, pending := mgr.pendingProbes.Load("download-1"); pending {
+		t.Error("expected pending probe to be flushed")
+	}
+}
+
+func TestLifecycle_OverridesSurviveToResume(t *testing.T) {
+	testutil.SetupStateDB(t)
+
+	mgr := NewLifecycleManager(nil, nil)
+	ch := make(chan interface{}, 1)
+	ch <- events.DownloadQueuedMsg{
+		DownloadID: "download-1",
+		URL:        "https://example.com/file.bin",
+		DestPath:   "/tmp/file.bin",
+		Filename:   "file.bin",
+		Mirrors:    []string{"https://mirror.example.com/file.bin"},
+		Overrides: &types.DownloadOverrides{
+			Headers:     map[string]string{"Cookie": "session=abc"},
+			Connections: 4,
+			SpeedLimit:  512 * 1024,
+			ChunkSize:   8 * types.MB,
+			UserAgent:   "Wget/1.21",
+		},
+	}
+	close(ch)
+	mgr.StartEventWorker(ch)
+	mgr.WaitPostProcessing()
+
+	// Later updates don't carry overrides and must not clear them
+	if err := state.AddToMasterList(types.DownloadEntry{
+		ID:       "download-1",
+		URL:      "https://example.com/file.bin",
+		DestPath: "/tmp/file.bin",
+		Filename: "file.bin",
+		Mirrors:  []string{"https://mirror.example.com/file.bin"},
+		Status:   "paused",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := state.GetDownload("download-1")
+	if err != nil || entry == nil {
+		t.Fatalf("expected entry, got %v, %v", entry, err)
+	}
+	for name, cfg := range map[string]types.DownloadConfig{
+		"entry":    buildResumeConfig("download-1", t.TempDir(), entry, nil, config.DefaultSettings()),
+		"no entry": buildResumeConfig("download-1", t.TempDir(), nil, nil, config.DefaultSettings()),
+	} {
+		if cfg.Headers["Cookie"] != "session=abc" {
+			t.Errorf("%s: headers = %v, want the cookie back", name, cfg.Headers)
+		}
+		if cfg.Runtime.MaxConnectionsPerHost != 4 {
+			t.Errorf("%s: connections = %d, want 4", name, cfg.Runtime.MaxConnectionsPerHost)
+		}
+		if cfg.Runtime.GetSpeedLimit() != 512*1024 {
+			t.Errorf("%s: speed limit = %d, want %d", name, cfg.Runtime.GetSpeedLimit(), 512*1024)
+		}
+		if cfg.Runtime.GetMinChunkSize() != 8*types.MB || cfg.Runtime.GetUserAgent() != "Wget/1.21" {
+			t.Errorf("%s: chunk size = %d, user agent = %q", name, cfg.Runtime.GetMinChunkSize(), cfg.Runtime.GetUserAgent())
+		}
+	}
+
+	cfg := buildResumeConfig("download-1", t.TempDir(), entry, nil, config.DefaultSettings())
+	if len(cfg.Mirrors) != 2 || cfg.Mirrors[1] != "https://mirror.example.com/file.bin" {
+		t.Errorf("mirrors = %v, want the primary and the stored mirror", cfg.Mirrors)
+	}
+}
+
+func TestLifecycle_ResumeRefusesDownloadWithoutWorkingFile(t *testing.T) {
+	testutil.SetupStateDB(t)
+
+	dest := filepath.Join(t.TempDir(), "lost.bin")
+	if err := state.AddToMasterList(types.DownloadEntry{
+		ID:         "lost",
+		URL:        "https://example.com/lost.bin",
+		DestPath:   dest,
+		Filename:   "lost.bin",
+		Status:     "paused",
+		TotalSize:  100,
+		Downloaded: 50,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var added []types.DownloadConfig
+	mgr := NewLifecycleManager(nil, nil)
+	mgr.SetEngineHooks(EngineHooks{
+		Resume:    func(string) bool { return false },
+		AddConfig: func(cfg types.DownloadConfig) { added = append(added, cfg) },
+	})
+
+	if err := mgr.Resume("lost"); !errors.Is(err, ErrWorkingFileMissing) {
+		t.Fatalf("Resume error = %v, want ErrWorkingFileMissing", err)
+	}
+	if errs := mgr.ResumeBatch([]string{"lost"}); !errors.Is(errs[0], ErrWorkingFileMissing) {
+		t.Fatalf("ResumeBatch error = %v, want ErrWorkingFileMissing", errs[0])
+	}
+	if len(added) != 0 {
+		t.Fatalf("resumed %d downloads, want none", len(added))
+	}
+}
+
+func TestLifecycle_ArchiveKeepsPartialDataUntilRestored(t *testing.T) {
+	testutil.SetupStateDB(t)
+
+	dest := filepath.Join(t.TempDir(), "kept.bin")
+	saved := &types.DownloadState{
+		ID:         "kept",
+		URL:        "https://example.com/kept.bin",
+		DestPath:   dest,
+		Filename:   "kept.bin",
+		TotalSize:  100,
+		Downloaded: 50,
+		Tasks:      []types.Task{{Offset: 50, Length: 50}},
+	}
+	if err := state.SaveState(saved.URL, dest, saved); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dest+types.IncompleteSuffix, make([]byte, 50), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var added []types.DownloadConfig
+	mgr := NewLifecycleManager(nil, nil)
+	mgr.SetEngineHooks(EngineHooks{
+		Resume:    func(string) bool { return false },
+		AddConfig: func(cfg types.DownloadConfig) { added = append(added, cfg) },
+	})
+
+	ch := make(chan interface{}, 1)
+	ch <- events.DownloadRemovedMsg{DownloadID: "kept", Filename: "kept.bin", DestPath: dest, Archived: true}
+	close(ch)
+	mgr.StartEventWorker(ch)
+	mgr.WaitPostProcessing()
+
+	entry, err := state.GetDownload("kept")
+	if err != nil || entry == nil {
+		t.Fatalf("archived download was removed: %v, %v", entry, err)
+	}
+	if entry.Status != "archived" {
+		t.Errorf("status = %q, want archived", entry.Status)
+	}
+	if _, err := os.Stat(dest + types.IncompleteSuffix); err != nil {
+		t.Errorf("working file should be kept: %v", err)
+	}
+
+	if err := mgr.Resume("kept"); !errors.Is(err, ErrArchived) {
+		t.Fatalf("Resume error = %v, want ErrArchived", err)
+	}
+	if errs := mgr.ResumeBatch([]string{"kept"}); !errors.Is(errs[0], ErrArchived) {
+		t.Fatalf("ResumeBatch error = %v, want ErrArchived", errs[0])
+	}
+	if len(added) != 0 {
+		t.Fatalf("resumed %d downloads before restore, want none", len(added))
+	}
+
+	if err := mgr.RestorePartial("kept"); err != nil {
+		t.Fatalf("RestorePartial failed: %v", err)
+	}
+	if len(added) != 1 || added[0].State == nil || added[0].State.Downloaded.Load() != 50 {
+		t.Fatalf("restore should resume from the saved state, got %+v", added)
+	}
+	if err := mgr.RestorePartial("kept"); err == nil {
+		t.Error("restoring a download that is not archived should fail")
+	}
+}
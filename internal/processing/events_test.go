@@ -56,6 +56,7 @@ func TestStartEventWorker_FinalizesCompletedFileUsingDestPath(t *testing.T) {
 	close(ch)
 
 	mgr.StartEventWorker(ch)
+	mgr.WaitPostProcessing()
 
 	if _, err := os.Stat(finalPath); err != nil {
 		t.Fatalf("expected finalized file at %s: %v", finalPath, err)
@@ -107,6 +108,7 @@ func TestStartEventWorker_PersistsQueuedMirrorsForResume(t *testing.T) {
 	close(ch)
 
 	mgr.StartEventWorker(ch)
+	mgr.WaitPostProcessing()
 
 	queuedState, err := state.LoadState("https://example.com/video.mp4", finalPath)
 	if err != nil {
@@ -152,6 +154,7 @@ func TestStartEventWorker_PreservesQueuedMirrorsAcrossStartedThenError(t *testin
 	close(ch)
 
 	mgr.StartEventWorker(ch)
+	mgr.WaitPostProcessing()
 
 	entry, err := state.GetDownload("download-queued")
 	if err != nil {
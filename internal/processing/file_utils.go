@@ -153,6 +153,19 @@ func GetCategoryPath(filename, defaultDir string, settings *config.Settings) (st
 	return defaultDir, nil
 }
 
+// routedCategory picks the category a download is auto-sorted into from its
+// filename, URL and probed Content-Type, or nil when routing is off.
+func routedCategory(url, filename string, settings *config.Settings, probe *ProbeResult) *config.Category {
+	if settings == nil || !settings.General.CategoryEnabled {
+		return nil
+	}
+	contentType := ""
+	if probe != nil {
+		contentType = probe.ContentType
+	}
+	return config.MatchCategory(filename, url, contentType, settings.General.Categories)
+}
+
 // getBaseFilename keeps naming deterministic across retries by preferring the
 // most authoritative source available before uniqueness is applied.
 func getBaseFilename(url, candidate string, probe *ProbeResult) string {
@@ -171,11 +184,11 @@ func ResolveDestination(url, candidateFilename, defaultDir string, routeToCatego
 	filename := getBaseFilename(url, candidateFilename, probe)
 
 	destPath := defaultDir
-	if routeToCategory && settings != nil && settings.General.CategoryEnabled && filename != "" {
-		var err error
-		destPath, err = GetCategoryPath(filename, defaultDir, settings)
-		if err != nil {
-			return "", "", err
+	if routeToCategory {
+		if cat := routedCategory(url, filename, settings, probe); cat != nil {
+			if catPath := config.ResolveCategoryPath(cat, defaultDir); catPath != "" {
+				destPath = utils.EnsureAbsPath(catPath)
+			}
 		}
 	}
 
@@ -0,0 +1,105 @@
+package processing
+
+import (
+	"errors"
+	"maps"
+	"net/http"
+	neturl "net/url"
+	"slices"
+	"strings"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// replayableHeaders are browser headers that are safe to store and resend for
+// later adds to the same host. Anything else (cookies, tokens, auth) is only
+// remembered by name.
+var replayableHeaders = map[string]bool{
+	"user-agent":      true,
+	"referer":         true,
+	"accept":          true,
+	"accept-language": true,
+	"origin":          true,
+}
+
+func headerHost(rawurl string) string {
+	parsed, err := neturl.Parse(rawurl)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Hostname())
+}
+
+// splitReplayableHeaders separates the headers we may store from the names of
+// the ones we must not
+func splitReplayableHeaders(headers map[string]string) (map[string]string, []string) {
+	replay := make(map[string]string)
+	var redacted []string
+	for k, v := range headers {
+		if strings.EqualFold(k, "Range") {
+			continue
+		}
+		if replayableHeaders[strings.ToLower(k)] {
+			replay[http.CanonicalHeaderKey(k)] = v
+		} else {
+			redacted = append(redacted, http.CanonicalHeaderKey(k))
+		}
+	}
+	slices.Sort(redacted)
+	return replay, redacted
+}
+
+// applyHostHeaders fills in the headers that last worked for the request's host
+// when the caller sent none, as CLI and TUI adds do. It reports whether any
+// headers were replayed.
+func applyHostHeaders(req *DownloadRequest) bool {
+	if len(req.Headers) > 0 {
+		return false
+	}
+	profile, err := state.GetHostHeaders(headerHost(req.URL))
+	if err != nil || profile == nil || !profile.Worked || len(profile.Headers) == 0 {
+		return false
+	}
+	req.Headers = maps.Clone(profile.Headers)
+	utils.Debug("Lifecycle: Replaying %d remembered headers for %s", len(req.Headers), profile.Host)
+	return true
+}
+
+// recordHostHeaders remembers how the host responded to the request's headers.
+// Browser-supplied headers that got through become the host's working set; a
+// 403 on a replayed set retires it. Other failures say nothing about headers.
+func recordHostHeaders(rawurl string, headers map[string]string, replayed bool, probeErr error) {
+	if len(headers) == 0 {
+		return
+	}
+	var statusErr *ProbeStatusError
+	forbidden := errors.As(probeErr, &statusErr) && statusErr.StatusCode == http.StatusForbidden
+	if probeErr != nil && !forbidden {
+		return
+	}
+	if replayed && !forbidden {
+		return
+	}
+
+	host := headerHost(rawurl)
+	replay, redacted := splitReplayableHeaders(headers)
+	if forbidden {
+		// Keep a different set that is known to work; a browser request for
+		// another file on the host may have failed for unrelated reasons
+		existing, err := state.GetHostHeaders(host)
+		if err == nil && existing != nil && existing.Worked && !maps.Equal(existing.Headers, replay) {
+			return
+		}
+	}
+
+	if err := state.SaveHostHeaders(types.HostHeaderProfile{
+		Host:     host,
+		Headers:  replay,
+		Redacted: redacted,
+		Worked:   !forbidden,
+	}); err != nil {
+		utils.Debug("Lifecycle: Failed to save header profile for %s: %v", host, err)
+	}
+}
@@ -0,0 +1,91 @@
+package processing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/testutil"
+)
+
+func TestLifecycleManager_ReplaysWorkingHeadersPerHost(t *testing.T) {
+	testutil.SetupStateDB(t)
+
+	var requireReferer atomic.Value
+	requireReferer.Store("https://example.com/page")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Referer") != requireReferer.Load().(string) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Range", "bytes 0-0/10")
+		w.WriteHeader(http.StatusPartialContent)
+	}))
+	defer server.Close()
+
+	var gotHeaders map[string]string
+	mgr := newLifecycleManagerForTest()
+	mgr.addFunc = func(req *DownloadRequest) (string, error) {
+		gotHeaders = req.Headers
+		return "id", nil
+	}
+	enqueue := func(name string, headers map[string]string) error {
+		_, err := mgr.Enqueue(context.Background(), &DownloadRequest{
+			URL:      server.URL + "/" + name,
+			Filename: name,
+			Path:     t.TempDir(),
+			Headers:  headers,
+		})
+		return err
+	}
+
+	// The extension's request gets through and teaches us the working set
+	if err := enqueue("a.bin", map[string]string{
+		"Referer":    "https://example.com/page",
+		"User-Agent": "BrowserUA",
+		"Cookie":     "session=secret",
+	}); err != nil {
+		t.Fatalf("extension enqueue failed: %v", err)
+	}
+
+	profile, err := state.GetHostHeaders(headerHost(server.URL))
+	if err != nil || profile == nil {
+		t.Fatalf("expected header profile, got %v, %v", profile, err)
+	}
+	if !profile.Worked || profile.Headers["Cookie"] != "" || len(profile.Redacted) != 1 || profile.Redacted[0] != "Cookie" {
+		t.Fatalf("expected cookie to be redacted from a working profile, got %+v", profile)
+	}
+
+	// A later CLI add with no headers replays the non-secret ones
+	if err := enqueue("b.bin", nil); err != nil {
+		t.Fatalf("replayed enqueue failed: %v", err)
+	}
+	if gotHeaders["Referer"] != "https://example.com/page" || gotHeaders["User-Agent"] != "BrowserUA" {
+		t.Fatalf("expected replayed headers, got %v", gotHeaders)
+	}
+	if _, ok := gotHeaders["Cookie"]; ok {
+		t.Fatal("credentials must never be replayed")
+	}
+
+	// Once the replayed set is refused it is retired
+	requireReferer.Store("https://example.com/other")
+	if err := enqueue("c.bin", nil); err == nil {
+		t.Fatal("expected probe to be refused")
+	}
+	profile, _ = state.GetHostHeaders(headerHost(server.URL))
+	if profile == nil || profile.Worked {
+		t.Fatalf("expected profile to be marked as refused, got %+v", profile)
+	}
+
+	gotHeaders = nil
+	requireReferer.Store("")
+	if err := enqueue("d.bin", nil); err != nil {
+		t.Fatalf("plain enqueue failed: %v", err)
+	}
+	if len(gotHeaders) != 0 {
+		t.Fatalf("expected no replay after refusal, got %v", gotHeaders)
+	}
+}
@@ -4,21 +4,28 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/state"
 	"github.com/surge-downloader/surge/internal/engine/types"
 	"github.com/surge-downloader/surge/internal/utils"
 )
 
 // AddDownloadFunc is the lifecycle's handoff into the engine-facing queue layer.
-type AddDownloadFunc func(string, string, string, []string, map[string]string, bool, int64, bool) (string, error)
+type AddDownloadFunc func(req *DownloadRequest) (string, error)
 
 // AddDownloadWithIDFunc preserves caller-chosen ids when a remote/UI layer already owns them.
-type AddDownloadWithIDFunc func(string, string, string, []string, map[string]string, string, int64, bool) (string, error)
+type AddDownloadWithIDFunc func(req *DownloadRequest, id string) (string, error)
+
+// ErrAlreadyDownloaded means the URL is in the download archive, so it is
+// skipped rather than downloaded again
+var ErrAlreadyDownloaded = errors.New("already downloaded")
 
 // IsNameActiveFunc lets routing treat in-flight downloads as filename conflicts within a directory.
 type IsNameActiveFunc func(dir, name string) bool
@@ -32,6 +39,21 @@ type LifecycleManager struct {
 	isNameActive        IsNameActiveFunc
 	engineHooks         EngineHooks
 	hooksMu             sync.RWMutex
+
+	// pendingProbes holds probe results for enqueued downloads whose row the
+	// event worker has not written yet
+	pendingProbes sync.Map // map[string]types.CachedProbe
+
+	// postJobs tracks the hashing and post-processing of completed downloads,
+	// which outlive the event that started them
+	postJobs sync.WaitGroup
+}
+
+// WaitPostProcessing blocks until the hashing and post-processing started for
+// completed downloads has finished. Call it once the event worker has
+// returned, before the state database is closed.
+func (mgr *LifecycleManager) WaitPostProcessing() {
+	mgr.postJobs.Wait()
 }
 
 const maxWorkingFileReservationAttempts = 100
@@ -161,8 +183,69 @@ type DownloadRequest struct {
 	Path               string
 	Mirrors            []string
 	Headers            map[string]string
+	Tags               []string
+	Category           string // Named category; routes to its path even when auto-sorting is off
 	IsExplicitCategory bool
 	SkipApproval       bool
+	DownloadArchive    bool   // Skip archived URLs even when the download_archive setting is off
+	Connections        int    // Connections per host for this download, 0 = use settings
+	SpeedLimit         int64  // Bytes/sec for this download, 0 = unlimited
+	ChunkSize          int64  // Minimum bytes per chunk for this download, 0 = use settings
+	UserAgent          string // User-Agent for this download, "" = use settings
+	Tries              int    // Attempts per chunk for this download, 0 = use settings
+
+	// Probe results, filled in by the lifecycle before the request reaches
+	// the queue layer. A zero TotalSize means the size is unknown.
+	TotalSize     int64
+	SupportsRange bool
+}
+
+// Overrides returns what req sets beyond the user's settings, to be stored
+// with the download and reapplied when it resumes. Nil means nothing.
+func (req *DownloadRequest) Overrides() *types.DownloadOverrides {
+	o := &types.DownloadOverrides{
+		Headers:     maps.Clone(req.Headers),
+		Connections: max(req.Connections, 0),
+		SpeedLimit:  max(req.SpeedLimit, 0),
+		ChunkSize:   max(req.ChunkSize, 0),
+		UserAgent:   req.UserAgent,
+		Tries:       max(req.Tries, 0),
+	}
+	if o.IsZero() {
+		return nil
+	}
+	return o
+}
+
+// probeHeaders returns the headers to probe with: req's own, plus its
+// User-Agent override unless they already carry one
+func (req *DownloadRequest) probeHeaders() map[string]string {
+	if req.UserAgent == "" {
+		return req.Headers
+	}
+	for key := range req.Headers {
+		if strings.EqualFold(key, "User-Agent") {
+			return req.Headers
+		}
+	}
+	headers := maps.Clone(req.Headers)
+	if headers == nil {
+		headers = make(map[string]string, 1)
+	}
+	headers["User-Agent"] = req.UserAgent
+	return headers
+}
+
+// resolved returns a copy of req aimed at the reserved destination, carrying
+// what the probe learned
+func (req *DownloadRequest) resolved(path, filename, category string, probe *ProbeResult) *DownloadRequest {
+	out := *req
+	out.Path = path
+	out.Filename = filename
+	out.Category = category
+	out.TotalSize = probe.FileSize
+	out.SupportsRange = probe.SupportsRange
+	return &out
 }
 
 // Enqueue probes and reserves a stable destination before dispatching to the queue layer.
@@ -172,17 +255,8 @@ func (mgr *LifecycleManager) Enqueue(ctx context.Context, req *DownloadRequest)
 	}
 
 	utils.Debug("Lifecycle: Enqueue %s", req.URL)
-	return mgr.enqueueResolved(ctx, req, func(finalPath, finalFilename string, probe *ProbeResult) (string, error) {
-		return mgr.addFunc(
-			req.URL,
-			finalPath,
-			finalFilename,
-			req.Mirrors,
-			req.Headers,
-			req.IsExplicitCategory,
-			probe.FileSize,
-			probe.SupportsRange,
-		)
+	return mgr.enqueueResolved(ctx, req, func(finalPath, finalFilename, category string, probe *ProbeResult) (string, error) {
+		return mgr.addFunc(req.resolved(finalPath, finalFilename, category, probe))
 	})
 }
 
@@ -193,78 +267,181 @@ func (mgr *LifecycleManager) EnqueueWithID(ctx context.Context, req *DownloadReq
 	}
 
 	utils.Debug("Lifecycle: EnqueueWithID %s (%s)", req.URL, requestID)
-	return mgr.enqueueResolved(ctx, req, func(finalPath, finalFilename string, probe *ProbeResult) (string, error) {
-		return mgr.addWithIDFunc(
-			req.URL,
-			finalPath,
-			finalFilename,
-			req.Mirrors,
-			req.Headers,
-			requestID,
-			probe.FileSize,
-			probe.SupportsRange,
-		)
+	return mgr.enqueueResolved(ctx, req, func(finalPath, finalFilename, category string, probe *ProbeResult) (string, error) {
+		return mgr.addWithIDFunc(req.resolved(finalPath, finalFilename, category, probe), requestID)
 	})
 }
 
 // enqueueResolved prepares the final path and working file before handing the
 // download to the engine, so workers and lifecycle events agree on one stable destination.
-func (mgr *LifecycleManager) enqueueResolved(ctx context.Context, req *DownloadRequest, dispatch func(string, string, *ProbeResult) (string, error)) (string, error) {
+func (mgr *LifecycleManager) enqueueResolved(ctx context.Context, req *DownloadRequest, dispatch func(string, string, string, *ProbeResult) (string, error)) (id string, err error) {
+	defer func() { recordEnqueueOutcome(req.URL, err) }()
+
+	res, err := mgr.reserve(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	newID, err := dispatch(res.path, res.filename, res.category, res.probe)
+	if err != nil {
+		res.release()
+		return "", err
+	}
+
+	mgr.rememberProbe(newID, res.probe.Cache())
+	return newID, nil
+}
+
+// reservation is a probed download whose working file has been created
+type reservation struct {
+	path     string
+	filename string
+	category string
+	probe    *ProbeResult
+}
+
+// destPath returns the final path of the download
+func (r *reservation) destPath() string {
+	return filepath.Join(r.path, r.filename)
+}
+
+// release deletes the working file so the name is free again
+func (r *reservation) release() {
+	_ = os.Remove(r.destPath() + types.IncompleteSuffix)
+	releaseDiskClaim(r.destPath() + types.IncompleteSuffix)
+}
+
+// reserve probes req, resolves its destination and creates the working file
+func (mgr *LifecycleManager) reserve(ctx context.Context, req *DownloadRequest) (*reservation, error) {
 	if req.URL == "" {
-		return "", fmt.Errorf("URL is required")
+		return nil, fmt.Errorf("URL is required")
 	}
 	if req.Path == "" {
-		return "", fmt.Errorf("destination path is required")
+		return nil, fmt.Errorf("destination path is required")
 	}
 
 	settings := mgr.GetSettings()
 
-	probe, err := ProbeServerWithProxy(ctx, req.URL, req.Filename, req.Headers, settings.Network.ProxyURL)
+	if req.DownloadArchive || settings.General.DownloadArchive {
+		archived, err := state.IsArchived(req.URL)
+		if err != nil {
+			utils.Debug("Lifecycle: Failed to check download archive: %v", err)
+		} else if archived {
+			utils.Debug("Lifecycle: Skipping archived URL: %s", req.URL)
+			return nil, ErrAlreadyDownloaded
+		}
+	}
+
+	path, route := req.Path, !req.IsExplicitCategory
+	var category *config.Category
+	if req.Category != "" {
+		category = config.FindCategory(req.Category, settings.General.Categories)
+		if category == nil {
+			return nil, fmt.Errorf("unknown category %q", req.Category)
+		}
+		if catPath := config.ResolveCategoryPath(category, req.Path); catPath != "" {
+			path = utils.EnsureAbsPath(catPath)
+		}
+		route = false
+	}
+
+	replayed := applyHostHeaders(req)
+	probe, err := ProbeServerWithProxy(ctx, req.URL, req.Filename, req.probeHeaders(), settings.Network.ProxyURL)
+	recordHostHeaders(req.URL, req.Headers, replayed, err)
 	if err != nil {
 		utils.Debug("Lifecycle: Probe failed: %v\n", err)
-		return "", fmt.Errorf("probe failed: %w", err)
+		return nil, fmt.Errorf("probe failed: %w", err)
 	}
 
 	isNameActive := mgr.buildIsNameActive()
 
 	for attempt := 0; attempt < maxWorkingFileReservationAttempts; attempt++ {
 		if ctx.Err() != nil {
-			return "", fmt.Errorf("enqueue aborted: %w", ctx.Err())
+			return nil, fmt.Errorf("enqueue aborted: %w", ctx.Err())
 		}
 
 		finalPath, finalFilename, err := ResolveDestination(
 			req.URL,
 			req.Filename,
-			req.Path,
-			!req.IsExplicitCategory,
+			path,
+			route,
 			settings,
 			probe,
 			isNameActive,
 		)
 		if err != nil {
-			return "", fmt.Errorf("failed to resolve destination: %w", err)
+			return nil, fmt.Errorf("failed to resolve destination: %w", err)
+		}
+
+		workingPath := filepath.Join(finalPath, finalFilename) + types.IncompleteSuffix
+		if err := checkDiskCapacity(finalPath, workingPath, probe.FileSize); err != nil {
+			return nil, err
 		}
 
 		// Reserve the working path before dispatch so a concurrent enqueue has to
 		// pick a different name instead of truncating this in-flight download.
 		if err := reserveWorkingFile(finalPath, finalFilename); err != nil {
+			releaseDiskClaim(workingPath)
 			if errors.Is(err, os.ErrExist) {
 				continue
 			}
-			return "", err
+			return nil, err
 		}
+		confirmDiskClaim(workingPath)
 
-		surgePath := filepath.Join(finalPath, finalFilename) + types.IncompleteSuffix
-		newID, err := dispatch(finalPath, finalFilename, probe)
-		if err != nil {
-			_ = os.Remove(surgePath)
-			return "", err
+		if route {
+			category = routedCategory(req.URL, getBaseFilename(req.URL, req.Filename, probe), settings, probe)
 		}
+		res := &reservation{path: finalPath, filename: finalFilename, probe: probe}
+		if category != nil {
+			res.category = category.Name
+		}
+		return res, nil
+	}
+
+	return nil, fmt.Errorf("failed to reserve unique working file for %q after %d attempts", req.URL, maxWorkingFileReservationAttempts)
+}
 
-		return newID, nil
+// rememberProbe persists probe metadata so a later resume can skip the probe.
+// The downloads row is written asynchronously by the event worker, so the
+// result is parked until flushProbe sees the row; saving here as well covers
+// the worker having got there first.
+func (mgr *LifecycleManager) rememberProbe(id string, probe types.CachedProbe) {
+	mgr.pendingProbes.Store(id, probe)
+	saved, err := state.SaveProbeCache(id, probe)
+	if err != nil {
+		utils.Debug("Lifecycle: Failed to cache probe for %s: %v", id, err)
+		return
+	}
+	if saved {
+		mgr.pendingProbes.Delete(id)
 	}
+}
 
-	return "", fmt.Errorf("failed to reserve unique working file for %q after %d attempts", req.URL, maxWorkingFileReservationAttempts)
+// flushProbe writes a parked probe result once the download's row exists
+func (mgr *LifecycleManager) flushProbe(id string) {
+	raw, ok := mgr.pendingProbes.LoadAndDelete(id)
+	if !ok {
+		return
+	}
+	if _, err := state.SaveProbeCache(id, raw.(types.CachedProbe)); err != nil {
+		utils.Debug("Lifecycle: Failed to cache probe for %s: %v", id, err)
+	}
+}
+
+// recordEnqueueOutcome remembers the URL in the quick-add history so rejected
+// URLs can be retried later. Cancelled enqueues are not the URL's fault.
+func recordEnqueueOutcome(url string, err error) {
+	status, errMsg := state.URLHistoryAdded, ""
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return
+		}
+		status, errMsg = state.URLHistoryRejected, err.Error()
+	}
+	if err := state.RecordURLHistory(url, status, errMsg); err != nil {
+		utils.Debug("Lifecycle: Failed to record url history: %v", err)
+	}
 }
 
 // IsNameActive reports whether the configured active-download callback would
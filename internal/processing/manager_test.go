@@ -8,6 +8,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -45,27 +46,27 @@ func TestLifecycleManager_Enqueue_PrecreatesWorkingFileBeforeDispatch(t *testing
 	expectedID := "enqueue-id"
 
 	mgr := newLifecycleManagerForTest()
-	mgr.addFunc = func(url, path, filename string, _ []string, _ map[string]string, explicit bool, totalSize int64, supportsRange bool) (string, error) {
-		if url != server.URL {
-			t.Fatalf("url = %q, want %q", url, server.URL)
+	mgr.addFunc = func(req *DownloadRequest) (string, error) {
+		if req.URL != server.URL {
+			t.Fatalf("url = %q, want %q", req.URL, server.URL)
 		}
-		if path != tempDir {
-			t.Fatalf("path = %q, want %q", path, tempDir)
+		if req.Path != tempDir {
+			t.Fatalf("path = %q, want %q", req.Path, tempDir)
 		}
-		if filename != expectedFile {
-			t.Fatalf("filename = %q, want %q", filename, expectedFile)
+		if req.Filename != expectedFile {
+			t.Fatalf("filename = %q, want %q", req.Filename, expectedFile)
 		}
-		if !explicit {
+		if !req.IsExplicitCategory {
 			t.Fatal("expected explicit category flag to be preserved")
 		}
-		if totalSize != 1234 {
-			t.Fatalf("totalSize = %d, want 1234", totalSize)
+		if req.TotalSize != 1234 {
+			t.Fatalf("totalSize = %d, want 1234", req.TotalSize)
 		}
-		if !supportsRange {
+		if !req.SupportsRange {
 			t.Fatal("expected range support from probe")
 		}
 
-		surgePath := filepath.Join(path, filename) + types.IncompleteSuffix
+		surgePath := filepath.Join(req.Path, req.Filename) + types.IncompleteSuffix
 		if _, err := os.Stat(surgePath); err != nil {
 			t.Fatalf("expected working file to exist before dispatch: %v", err)
 		}
@@ -103,27 +104,27 @@ func TestLifecycleManager_EnqueueWithID_PrecreatesWorkingFileBeforeDispatch(t *t
 	expectedID := "request-id"
 
 	mgr := newLifecycleManagerForTest()
-	mgr.addWithIDFunc = func(url, path, filename string, _ []string, _ map[string]string, requestID string, totalSize int64, supportsRange bool) (string, error) {
-		if url != server.URL {
-			t.Fatalf("url = %q, want %q", url, server.URL)
+	mgr.addWithIDFunc = func(req *DownloadRequest, requestID string) (string, error) {
+		if req.URL != server.URL {
+			t.Fatalf("url = %q, want %q", req.URL, server.URL)
 		}
-		if path != tempDir {
-			t.Fatalf("path = %q, want %q", path, tempDir)
+		if req.Path != tempDir {
+			t.Fatalf("path = %q, want %q", req.Path, tempDir)
 		}
-		if filename != expectedFile {
-			t.Fatalf("filename = %q, want %q", filename, expectedFile)
+		if req.Filename != expectedFile {
+			t.Fatalf("filename = %q, want %q", req.Filename, expectedFile)
 		}
 		if requestID != expectedID {
 			t.Fatalf("requestID = %q, want %q", requestID, expectedID)
 		}
-		if totalSize != 4321 {
-			t.Fatalf("totalSize = %d, want 4321", totalSize)
+		if req.TotalSize != 4321 {
+			t.Fatalf("totalSize = %d, want 4321", req.TotalSize)
 		}
-		if !supportsRange {
+		if !req.SupportsRange {
 			t.Fatal("expected range support from probe")
 		}
 
-		surgePath := filepath.Join(path, filename) + types.IncompleteSuffix
+		surgePath := filepath.Join(req.Path, req.Filename) + types.IncompleteSuffix
 		if _, err := os.Stat(surgePath); err != nil {
 			t.Fatalf("expected working file to exist before dispatch: %v", err)
 		}
@@ -161,7 +162,7 @@ func TestLifecycleManager_Enqueue_RemovesWorkingFileOnDispatchError(t *testing.T
 	expectedErr := errors.New("dispatch failed")
 
 	mgr := newLifecycleManagerForTest()
-	mgr.addFunc = func(string, string, string, []string, map[string]string, bool, int64, bool) (string, error) {
+	mgr.addFunc = func(_ *DownloadRequest) (string, error) {
 		return "", expectedErr
 	}
 
@@ -210,16 +211,16 @@ func TestLifecycleManager_Enqueue_RetriesWhenWorkingFileReservationCollides(t *t
 
 	mgr := newLifecycleManagerForTest()
 	var dispatchedFilename string
-	mgr.addFunc = func(url, path, filename string, _ []string, _ map[string]string, explicit bool, totalSize int64, supportsRange bool) (string, error) {
-		dispatchedFilename = filename
-		if path != tempDir {
-			t.Fatalf("path = %q, want %q", path, tempDir)
+	mgr.addFunc = func(req *DownloadRequest) (string, error) {
+		dispatchedFilename = req.Filename
+		if req.Path != tempDir {
+			t.Fatalf("path = %q, want %q", req.Path, tempDir)
 		}
-		if explicit != true {
+		if req.IsExplicitCategory != true {
 			t.Fatal("expected explicit category flag to be preserved")
 		}
-		if totalSize != 1024 || !supportsRange {
-			t.Fatalf("unexpected probe metadata: total=%d range=%v", totalSize, supportsRange)
+		if req.TotalSize != 1024 || !req.SupportsRange {
+			t.Fatalf("unexpected probe metadata: total=%d range=%v", req.TotalSize, req.SupportsRange)
 		}
 		return "retry-id", nil
 	}
@@ -283,16 +284,16 @@ func TestLifecycleManager_EnqueueWithID_RetriesWhenWorkingFileReservationCollide
 
 	mgr := newLifecycleManagerForTest()
 	var dispatchedFilename string
-	mgr.addWithIDFunc = func(url, path, filename string, _ []string, _ map[string]string, gotRequestID string, totalSize int64, supportsRange bool) (string, error) {
-		dispatchedFilename = filename
-		if path != tempDir {
-			t.Fatalf("path = %q, want %q", path, tempDir)
+	mgr.addWithIDFunc = func(req *DownloadRequest, gotRequestID string) (string, error) {
+		dispatchedFilename = req.Filename
+		if req.Path != tempDir {
+			t.Fatalf("path = %q, want %q", req.Path, tempDir)
 		}
 		if gotRequestID != requestID {
 			t.Fatalf("requestID = %q, want %q", gotRequestID, requestID)
 		}
-		if totalSize != 1024 || !supportsRange {
-			t.Fatalf("unexpected probe metadata: total=%d range=%v", totalSize, supportsRange)
+		if req.TotalSize != 1024 || !req.SupportsRange {
+			t.Fatalf("unexpected probe metadata: total=%d range=%v", req.TotalSize, req.SupportsRange)
 		}
 		return gotRequestID, nil
 	}
@@ -335,7 +336,7 @@ func TestLifecycleManager_EnqueueWithID_RemovesWorkingFileOnDispatchError(t *tes
 	expectedErr := errors.New("dispatch failed")
 
 	mgr := newLifecycleManagerForTest()
-	mgr.addWithIDFunc = func(string, string, string, []string, map[string]string, string, int64, bool) (string, error) {
+	mgr.addWithIDFunc = func(_ *DownloadRequest, _ string) (string, error) {
 		return "", expectedErr
 	}
 
@@ -376,7 +377,7 @@ func TestLifecycleManager_Enqueue_FailsAfterReservationAttemptLimit(t *testing.T
 	}
 
 	mgr := newLifecycleManagerForTest()
-	mgr.addFunc = func(string, string, string, []string, map[string]string, bool, int64, bool) (string, error) {
+	mgr.addFunc = func(_ *DownloadRequest) (string, error) {
 		t.Fatal("dispatch should not run when reservation never succeeds")
 		return "", nil
 	}
@@ -462,7 +463,7 @@ func TestLifecycleManager_Enqueue_ContextCancellationDuringProbe(t *testing.T) {
 	defer server.Close()
 
 	mgr := newLifecycleManagerForTest()
-	mgr.addFunc = func(string, string, string, []string, map[string]string, bool, int64, bool) (string, error) {
+	mgr.addFunc = func(_ *DownloadRequest) (string, error) {
 		t.Fatal("dispatch should not run when probe fails")
 		return "", nil
 	}
@@ -504,7 +505,7 @@ func TestLifecycleManager_EnqueueWithID_FailsAfterReservationAttemptLimit(t *tes
 	}
 
 	mgr := newLifecycleManagerForTest()
-	mgr.addWithIDFunc = func(string, string, string, []string, map[string]string, string, int64, bool) (string, error) {
+	mgr.addWithIDFunc = func(_ *DownloadRequest, _ string) (string, error) {
 		t.Fatal("dispatch should not run when reservation never succeeds")
 		return "", nil
 	}
@@ -563,7 +564,7 @@ func TestLifecycleManager_Enqueue_ContextCancellationBeforeReservation(t *testin
 	defer server.Close()
 
 	mgr := newLifecycleManagerForTest()
-	mgr.addFunc = func(string, string, string, []string, map[string]string, bool, int64, bool) (string, error) {
+	mgr.addFunc = func(_ *DownloadRequest) (string, error) {
 		t.Fatal("dispatch should not run when context is canceled before reservation")
 		return "", nil
 	}
@@ -581,3 +582,125 @@ func TestLifecycleManager_Enqueue_ContextCancellationBeforeReservation(t *testin
 		t.Fatalf("expected context.Canceled, got %v", err)
 	}
 }
+
+func TestLifecycleManager_Enqueue_NamedCategory(t *testing.T) {
+	server := newProbeTestServer(t, 64)
+	defer server.Close()
+
+	catDir := t.TempDir()
+	mgr := newLifecycleManagerForTest()
+	mgr.settings.General.Categories = []config.Category{
+		{Name: "Releases", URLPattern: `^https://github\.com/`, Path: catDir, MaxConnections: 4},
+	}
+
+	var gotPath, gotCategory string
+	mgr.addFunc = func(req *DownloadRequest) (string, error) {
+		gotPath, gotCategory = req.Path, req.Category
+		return "named-id", nil
+	}
+
+	// Auto-sorting is off, but a named category still routes
+	if _, err := mgr.Enqueue(context.Background(), &DownloadRequest{
+		URL:      server.URL,
+		Filename: "tool.bin",
+		Path:     t.TempDir(),
+		Category: "releases",
+	}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if gotPath != catDir {
+		t.Fatalf("path = %q, want category dir %q", gotPath, catDir)
+	}
+	if gotCategory != "Releases" {
+		t.Fatalf("category = %q, want Releases", gotCategory)
+	}
+
+	_, err := mgr.Enqueue(context.Background(), &DownloadRequest{
+		URL:      server.URL,
+		Path:     t.TempDir(),
+		Category: "nope",
+	})
+	if err == nil || !strings.Contains(err.Error(), "unknown category") {
+		t.Fatalf("err = %v, want unknown category", err)
+	}
+}
+
+func TestLifecycleManager_Enqueue_RoutesByContentType(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Range", "bytes 0-0/64")
+		w.Header().Set("Content-Length", "1")
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = w.Write([]byte("x"))
+	}))
+	defer server.Close()
+
+	catDir := t.TempDir()
+	mgr := newLifecycleManagerForTest()
+	mgr.settings.General.CategoryEnabled = true
+	mgr.settings.General.Categories = []config.Category{
+		{Name: "Docs", MimeTypes: []string{"application/pdf"}, Path: catDir},
+	}
+
+	var gotPath, gotCategory string
+	mgr.addFunc = func(req *DownloadRequest) (string, error) {
+		gotPath, gotCategory = req.Path, req.Category
+		return "mime-id", nil
+	}
+
+	if _, err := mgr.Enqueue(context.Background(), &DownloadRequest{
+		URL:      server.URL + "/paper",
+		Filename: "paper",
+		Path:     t.TempDir(),
+	}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if gotPath != catDir || gotCategory != "Docs" {
+		t.Fatalf("dispatched to %q as %q, want %q as Docs", gotPath, gotCategory, catDir)
+	}
+}
+
+func TestLifecycleManager_Enqueue_ProbesWithUserAgentOverride(t *testing.T) {
+	var probedWith string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probedWith = r.Header.Get("User-Agent")
+		w.Header().Set("Content-Range", "bytes 0-0/100")
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = w.Write([]byte("x"))
+	}))
+	defer server.Close()
+
+	mgr := newLifecycleManagerForTest()
+	var added *DownloadRequest
+	mgr.addFunc = func(req *DownloadRequest) (string, error) {
+		added = req
+		return "ua-id", nil
+	}
+
+	req := &DownloadRequest{URL: server.URL, Filename: "a.bin", Path: t.TempDir(), UserAgent: "Wget/1.21", ChunkSize: 8 * types.MB}
+	if _, err := mgr.Enqueue(context.Background(), req); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if probedWith != "Wget/1.21" {
+		t.Fatalf("probe User-Agent = %q, want the override", probedWith)
+	}
+	if req.Headers != nil {
+		t.Fatalf("probing must not add to the request's headers: %v", req.Headers)
+	}
+	o := added.Overrides()
+	if o == nil || o.UserAgent != "Wget/1.21" || o.ChunkSize != 8*types.MB {
+		t.Fatalf("overrides = %+v", o)
+	}
+}
+
+func TestDownloadRequest_TriesOverride(t *testing.T) {
+	o := (&DownloadRequest{URL: "https://example.com/a", Tries: 7}).Overrides()
+	if o == nil || o.Tries != 7 {
+		t.Fatalf("overrides = %+v, want tries kept", o)
+	}
+	cfg := &types.DownloadConfig{}
+	o.Apply(cfg)
+	if cfg.Runtime.GetMaxTaskRetries() != 7 {
+		t.Errorf("MaxTaskRetries = %d, want 7", cfg.Runtime.MaxTaskRetries)
+	}
+}
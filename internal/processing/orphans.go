@@ -0,0 +1,19 @@
+package processing
+
+import (
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/state"
+)
+
+// FindOrphans looks for orphaned working files and downloads in the
+// directories of known downloads and every folder settings send downloads to
+func FindOrphans(settings *config.Settings) (*state.Orphans, error) {
+	if settings == nil {
+		settings = config.DefaultSettings()
+	}
+	dirs := []string{settings.General.DefaultDownloadDir}
+	for i := range settings.General.Categories {
+		dirs = append(dirs, config.ResolveCategoryPath(&settings.General.Categories[i], ""))
+	}
+	return state.FindOrphans(dirs...)
+}
@@ -1,6 +1,7 @@
 package processing
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -10,6 +11,18 @@ import (
 	"github.com/surge-downloader/surge/internal/engine/types"
 )
 
+// ErrWorkingFileMissing means a paused download lost its partial file, so
+// resuming it would not produce the whole file
+var ErrWorkingFileMissing = errors.New("working file is missing; run 'surge prune --orphans' to remove the download")
+
+// ErrArchived means the download was removed with its partial data kept, and
+// has to be restored before it can be resumed
+var ErrArchived = errors.New("download is archived; run 'surge restore-partial' to bring it back")
+
+// ErrTrashed means the download was deleted into the trash, and has to be
+// restored before it can be resumed
+var ErrTrashed = errors.New("download is in the trash; run 'surge trash restore' to bring it back")
+
 // EngineHooks defines the minimal callbacks Processing needs to orchestrate the worker pool.
 type EngineHooks struct {
 	Pause     func(id string) bool
@@ -41,6 +54,7 @@ func (mgr *LifecycleManager) Pause(id string) error {
 				DownloadID: id,
 				Filename:   entry.Filename,
 				Downloaded: entry.Downloaded,
+				Reason:     types.PauseUser,
 			})
 		}
 		return nil // Already stopped
@@ -74,6 +88,15 @@ func (mgr *LifecycleManager) Resume(id string) error {
 	if entry.Status == "completed" {
 		return fmt.Errorf("download already completed")
 	}
+	if entry.Status == "archived" {
+		return ErrArchived
+	}
+	if entry.Status == "trashed" {
+		return ErrTrashed
+	}
+	if state.WorkingFileMissing(entry.DestPath, entry.Downloaded) {
+		return ErrWorkingFileMissing
+	}
 
 	settings := mgr.GetSettings()
 
@@ -157,6 +180,18 @@ func (mgr *LifecycleManager) ResumeBatch(ids []string) []error {
 		savedState, ok := states[id]
 		if !ok {
 			errs[idx] = fmt.Errorf("download not found or completed")
+			if entry, _ := state.GetDownload(id); entry != nil {
+				switch entry.Status {
+				case "archived":
+					errs[idx] = ErrArchived
+				case "trashed":
+					errs[idx] = ErrTrashed
+				}
+			}
+			continue
+		}
+		if state.WorkingFileMissing(savedState.DestPath, savedState.Downloaded) {
+			errs[idx] = ErrWorkingFileMissing
 			continue
 		}
 
@@ -178,14 +213,22 @@ func (mgr *LifecycleManager) ResumeBatch(ids []string) []error {
 
 // buildResumeConfig constructs a DownloadConfig for a cold-path resume from saved state.
 // When entry is non-nil it provides identity fields (URL, filename, destPath); savedState
-// When entry is non-nil it provides identity fields (URL, filename, destPath); savedState
 // takes precedence for progress, elapsed time, and mirror topology. If savedState is nil,
 // SupportsRange is false and the download restarts from the entry's Downloaded offset.
+// Probe metadata cached at enqueue time is attached so the engine can skip re-probing,
+// and the headers, connections and speed limit the download was added with are reapplied.
 func buildResumeConfig(id, outputPath string, entry *types.DownloadEntry, savedState *types.DownloadState, settings *config.Settings) types.DownloadConfig {
-	var destPath, url, filename string
+	var destPath, url, filename, traceID, category string
+	var tags, entryMirrors []string
 	var totalSize, downloaded int64
+	var overrides *types.DownloadOverrides
 
 	if entry != nil {
+		overrides = entry.Overrides
+		entryMirrors = entry.Mirrors
+		traceID = entry.TraceID
+		tags = entry.Tags
+		category = entry.Category
 		destPath = entry.DestPath
 		url = entry.URL
 		filename = entry.Filename
@@ -226,20 +269,70 @@ func buildResumeConfig(id, outputPath string, entry *types.DownloadEntry, savedS
 		dmState.DestPath = destPath
 		dmState.SyncSessionStart()
 		mirrorURLs = []string{url}
+		for _, m := range entryMirrors {
+			if m != url {
+				mirrorURLs = append(mirrorURLs, m)
+			}
+		}
+	}
+
+	if entry == nil {
+		if o, err := state.GetOverrides(id); err == nil {
+			overrides = o
+		}
 	}
 
-	return types.DownloadConfig{
+	probe, err := state.GetProbeCache(id)
+	if err != nil {
+		probe = nil
+	}
+	if probe != nil && totalSize <= 0 {
+		totalSize = probe.FileSize
+	}
+
+	// A download with nothing written yet, such as a retried one, starts
+	// like a new one would: split up when the probe found range support
+	freshWithRanges := downloaded == 0 && probe != nil && probe.SupportsRange && totalSize > 0
+
+	cfg := types.DownloadConfig{
 		URL:           url,
 		OutputPath:    outputPath,
 		DestPath:      destPath,
 		ID:            id,
 		Filename:      filename,
 		TotalSize:     totalSize,
-		SupportsRange: savedState != nil && len(savedState.Tasks) > 0,
+		SupportsRange: savedState != nil && len(savedState.Tasks) > 0 || freshWithRanges,
 		IsResume:      true,
 		State:         dmState,
 		SavedState:    savedState,
-		Runtime:       types.ConvertRuntimeConfig(settings.ToRuntimeConfig()),
+		Runtime:       types.ConvertRuntimeConfig(settings.ToRuntimeConfigForCategory(category)),
 		Mirrors:       mirrorURLs,
+		Probe:         probe,
+		Tags:          tags,
+		Category:      category,
+		TraceID:       traceID,
+		Overrides:     overrides,
+	}
+	overrides.Apply(&cfg)
+	return cfg
+}
+
+// RestorePartial brings back a download that was archived, removed with its
+// partial data kept, and resumes it from where it stopped.
+func (mgr *LifecycleManager) RestorePartial(id string) error {
+	entry, err := state.GetDownload(id)
+	if err != nil || entry == nil {
+		return fmt.Errorf("download not found")
+	}
+	if entry.Status != "archived" {
+		return fmt.Errorf("download is not archived")
+	}
+	if state.WorkingFileMissing(entry.DestPath, entry.Downloaded) {
+		return ErrWorkingFileMissing
+	}
+
+	if err := state.UpdateStatus(id, "paused"); err != nil {
+		return err
 	}
+	return mgr.Resume(id)
 }
@@ -0,0 +1,502 @@
+package processing
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// Step timeouts used when a step does not set its own
+const (
+	defaultScriptTimeout = 10 * time.Minute
+	defaultNotifyTimeout = 10 * time.Second
+)
+
+// maxScriptOutput is how much of a failed script's output goes in the error
+const maxScriptOutput = 512
+
+// postJob is a completed download going through its category's pipeline
+type postJob struct {
+	id       string
+	url      string
+	category string
+	path     string // Where the file is now; the move step changes it
+	size     int64  // Expected size, 0 when unknown
+}
+
+func (j *postJob) filename() string {
+	return filepath.Base(j.path)
+}
+
+// desktopNotify shows a desktop notification. Tests replace it.
+var desktopNotify = func(title, body string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "linux", "freebsd", "openbsd", "netbsd":
+		cmd = exec.Command("notify-send", title, body)
+	case "darwin":
+		cmd = exec.Command("osascript", "-e", fmt.Sprintf("display notification %q with title %q", body, title))
+	default:
+		return fmt.Errorf("desktop notifications are not supported on %s; set a url", runtime.GOOS)
+	}
+	return cmd.Run()
+}
+
+// postProcessSteps returns the pipeline of the named category
+func (mgr *LifecycleManager) postProcessSteps(category string) []config.PostStep {
+	if category == "" {
+		return nil
+	}
+	settings := mgr.GetSettings()
+	cat := config.FindCategory(category, settings.General.Categories)
+	if cat == nil {
+		return nil
+	}
+	return cat.PostProcess
+}
+
+// runPostProcess runs steps in order, publishing each one's phase, and ends
+// with PhaseComplete, or with a DownloadErrorMsg when a step set to fail does.
+func (mgr *LifecycleManager) runPostProcess(job *postJob, steps []config.PostStep) {
+	for i, step := range steps {
+		if step.Disabled {
+			continue
+		}
+		err := mgr.runStep(job, step)
+		if err == nil {
+			continue
+		}
+
+		utils.Debug("Lifecycle: Post-processing step %d (%s) failed for %s: %v", i+1, step.Type, job.id, err)
+		msg := fmt.Sprintf("Post-processing %s failed for %s: %v", step.Type, job.filename(), err)
+		policy := step.FailurePolicy()
+		if policy == config.OnFailureFail {
+			mgr.publish(events.DownloadErrorMsg{
+				DownloadID: job.id,
+				Filename:   job.filename(),
+				DestPath:   job.path,
+				Err:        fmt.Errorf("post-processing %s: %w", step.Type, err),
+			})
+			return
+		}
+		mgr.publish(events.SystemLogMsg{Message: msg})
+		if policy == config.OnFailureStop {
+			break
+		}
+	}
+	mgr.publishPhase(job.id, job.filename(), types.PhaseComplete, 100)
+}
+
+// publish sends msg to clients when an engine is attached
+func (mgr *LifecycleManager) publish(msg interface{}) {
+	hooks := mgr.getEngineHooks()
+	if hooks.PublishEvent == nil {
+		return
+	}
+	if err := hooks.PublishEvent(msg); err != nil {
+		utils.Debug("Lifecycle: Failed to publish %T: %v", msg, err)
+	}
+}
+
+func (mgr *LifecycleManager) runStep(job *postJob, step config.PostStep) error {
+	switch step.Type {
+	case config.StepVerify:
+		mgr.publishPhase(job.id, job.filename(), types.PhaseVerifying, 0)
+		return verifyFile(job.path, job.size)
+	case config.StepExtract:
+		mgr.publishPhase(job.id, job.filename(), types.PhaseExtracting, 0)
+		if err := extractArchive(job.path, step.Target, step.DeleteArchive, mgr.phaseReporter(job, types.PhaseExtracting)); err != nil {
+			return err
+		}
+		if step.DeleteArchive {
+			// The stored checksum is of the archive, which is now gone on purpose
+			if err := state.RecordVerification(job.id, types.VerifyUnverifiable); err != nil {
+				utils.Debug("Lifecycle: Failed to mark %s unverifiable: %v", job.id, err)
+			}
+		}
+		return nil
+	case config.StepMove:
+		mgr.publishPhase(job.id, job.filename(), types.PhaseMoving, 0)
+		return mgr.moveStep(job, step.Target)
+	case config.StepScript:
+		mgr.publishPhase(job.id, job.filename(), types.PhaseScripting, 0)
+		return runScript(job, step)
+	case config.StepNotify:
+		mgr.publishPhase(job.id, job.filename(), types.PhaseNotifying, 0)
+		return notify(job, step)
+	}
+	return fmt.Errorf("unknown step %q", step.Type)
+}
+
+// phaseReporter publishes progress through phase in whole percents
+func (mgr *LifecycleManager) phaseReporter(job *postJob, phase string) func(float64) {
+	last := 0.0
+	return func(pct float64) {
+		if pct-last >= 1 || (pct >= 100 && last < 100) {
+			last = pct
+			mgr.publishPhase(job.id, job.filename(), phase, pct)
+		}
+	}
+}
+
+// verifyFile checks the file is there and, when the size is known, complete
+func verifyFile(path string, size int64) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", path)
+	}
+	if size > 0 && info.Size() != size {
+		return fmt.Errorf("size is %d bytes, expected %d", info.Size(), size)
+	}
+	return nil
+}
+
+// moveStep moves the file into target, renaming it if the name is taken
+func (mgr *LifecycleManager) moveStep(job *postJob, target string) error {
+	dir := utils.EnsureAbsPath(expandHome(target))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	name := GetUniqueFilename(dir, job.filename(), nil)
+	if name == "" {
+		return fmt.Errorf("no usable name for %s in %s", job.filename(), dir)
+	}
+	dst := filepath.Join(dir, name)
+	if err := moveFile(job.path, dst, func(copied, total int64) {
+		if total > 0 {
+			mgr.publishPhase(job.id, job.filename(), types.PhaseMoving, float64(copied)*100/float64(total))
+		}
+	}); err != nil {
+		return err
+	}
+	job.path = dst
+	if err := state.UpdateDestPath(job.id, dst, name); err != nil {
+		return fmt.Errorf("moved to %s but failed to record it: %w", dst, err)
+	}
+	return nil
+}
+
+// expandHome replaces a leading ~ with the user's home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, `~\`) {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// scriptVars are the placeholders a script step's args may use, also set in
+// its environment as SURGE_<NAME>
+func scriptVars(job *postJob) map[string]string {
+	return map[string]string{
+		"id":       job.id,
+		"url":      job.url,
+		"file":     job.path,
+		"dir":      filepath.Dir(job.path),
+		"filename": job.filename(),
+		"category": job.category,
+	}
+}
+
+// runScript runs the step's command in the file's directory. Args may use
+// {file}, {dir}, {filename}, {id}, {url} and {category}.
+func runScript(job *postJob, step config.PostStep) error {
+	timeout := defaultScriptTimeout
+	if step.TimeoutSeconds > 0 {
+		timeout = time.Duration(step.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	vars := scriptVars(job)
+	// One pass, so a value that itself contains "{file}" is not expanded again
+	pairs := make([]string, 0, 2*len(vars))
+	for k, v := range vars {
+		pairs = append(pairs, "{"+k+"}", v)
+	}
+	replacer := strings.NewReplacer(pairs...)
+	args := make([]string, len(step.Args))
+	for i, arg := range step.Args {
+		args[i] = replacer.Replace(arg)
+	}
+
+	cmd := exec.CommandContext(ctx, expandHome(step.Command), args...)
+	cmd.Dir = filepath.Dir(job.path)
+	cmd.Env = os.Environ()
+	for k, v := range vars {
+		cmd.Env = append(cmd.Env, "SURGE_"+strings.ToUpper(k)+"="+v)
+	}
+	out, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	if err != nil {
+		output := strings.TrimSpace(string(out))
+		if len(output) > maxScriptOutput {
+			output = "..." + output[len(output)-maxScriptOutput:]
+		}
+		if output != "" {
+			return fmt.Errorf("%w: %s", err, output)
+		}
+		return err
+	}
+	return nil
+}
+
+// notifyPayload is the body of a notify step's webhook request
+type notifyPayload struct {
+	Event    string `json:"event"`
+	ID       string `json:"id"`
+	URL      string `json:"url"`
+	File     string `json:"file"`
+	Category string `json:"category,omitempty"`
+}
+
+// notify posts to the step's URL, or shows a desktop notification without one
+func notify(job *postJob, step config.PostStep) error {
+	if step.URL == "" {
+		return desktopNotify("Download complete", job.filename())
+	}
+
+	timeout := defaultNotifyTimeout
+	if step.TimeoutSeconds > 0 {
+		timeout = time.Duration(step.TimeoutSeconds) * time.Second
+	}
+	body, err := json.Marshal(notifyPayload{
+		Event:    "download.completed",
+		ID:       job.id,
+		URL:      job.url,
+		File:     job.path,
+		Category: job.category,
+	})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, step.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// archiveKind returns the archive extension of name, or "" if it is not an
+// archive extract understands
+func archiveKind(name string) string {
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".tar.gz", ".tgz", ".tar", ".zip"} {
+		if strings.HasSuffix(lower, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// extractArchive unpacks path into target, by default a directory next to it
+// named after the archive. Files that are not archives are left alone.
+// Existing files are never overwritten.
+func extractArchive(path, target string, deleteArchive bool, onProgress func(float64)) error {
+	kind := archiveKind(path)
+	if kind == "" {
+		return nil
+	}
+
+	dir := filepath.Dir(path)
+	dest := filepath.Join(dir, filepath.Base(path)[:len(filepath.Base(path))-len(kind)])
+	if target != "" {
+		dest = expandHome(target)
+		if !filepath.IsAbs(dest) {
+			dest = filepath.Join(dir, dest)
+		}
+	}
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		return err
+	}
+
+	var err error
+	if kind == ".zip" {
+		err = extractZip(path, dest, onProgress)
+	} else {
+		err = extractTar(path, dest, kind != ".tar", onProgress)
+	}
+	if err != nil {
+		return err
+	}
+	onProgress(100)
+	if deleteArchive {
+		return os.Remove(path)
+	}
+	return nil
+}
+
+// archivePath joins an archive member's name to dest, refusing names that
+// would land outside it
+func archivePath(dest, name string) (string, error) {
+	if filepath.IsAbs(name) || strings.HasPrefix(name, "/") || strings.HasPrefix(name, `\`) {
+		return "", fmt.Errorf("unsafe path %q in archive", name)
+	}
+	p := filepath.Join(dest, name)
+	rel, err := filepath.Rel(dest, p)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("unsafe path %q in archive", name)
+	}
+	return p, nil
+}
+
+// writeArchiveFile creates p, which must not exist, with r's contents
+func writeArchiveFile(p string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func extractZip(path, dest string, onProgress func(float64)) error {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = zr.Close() }()
+
+	var total, done uint64
+	for _, f := range zr.File {
+		total += f.UncompressedSize64
+	}
+	for _, f := range zr.File {
+		p, err := archivePath(dest, f.Name)
+		if err != nil {
+			return err
+		}
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(p, 0o755); err != nil {
+				return err
+			}
+			continue
+		}
+		if !f.Mode().IsRegular() {
+			continue // Links and devices are skipped
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = writeArchiveFile(p, rc)
+		_ = rc.Close()
+		if err != nil {
+			return err
+		}
+		done += f.UncompressedSize64
+		if total > 0 {
+			onProgress(float64(done) * 100 / float64(total))
+		}
+	}
+	return nil
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func extractTar(path, dest string, gzipped bool, onProgress func(float64)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	// Progress follows the compressed bytes read, since tar has no index
+	counter := &countingReader{r: f}
+	var r io.Reader = counter
+	if gzipped {
+		gz, err := gzip.NewReader(counter)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = gz.Close() }()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		p, err := archivePath(dest, hdr.Name)
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(p, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeArchiveFile(p, tr); err != nil {
+				return err
+			}
+		default:
+			continue // Links and devices are skipped
+		}
+		if info.Size() > 0 {
+			onProgress(float64(counter.n) * 100 / float64(info.Size()))
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package processing
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/testutil"
+)
+
+func writeZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, body := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(body))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+}
+
+func writeTarGz(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for name, body := range files {
+		_ = tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(body)), Typeflag: tar.TypeReg})
+		_, _ = tw.Write([]byte(body))
+	}
+	_ = tw.Close()
+	_ = gz.Close()
+	_ = f.Close()
+}
+
+func TestExtractArchive(t *testing.T) {
+	files := map[string]string{"a.txt": "alpha", "sub/b.txt": "beta"}
+	noProgress := func(float64) {}
+
+	for _, name := range []string{"bundle.zip", "bundle.tar.gz", "bundle.TGZ"} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, name)
+			if strings.HasSuffix(name, ".zip") {
+				writeZip(t, path, files)
+			} else {
+				writeTarGz(t, path, files)
+			}
+
+			if err := extractArchive(path, "", true, noProgress); err != nil {
+				t.Fatalf("extractArchive: %v", err)
+			}
+			for member, body := range files {
+				got, err := os.ReadFile(filepath.Join(dir, "bundle", member))
+				if err != nil || string(got) != body {
+					t.Errorf("%s = %q, %v; want %q", member, got, err, body)
+				}
+			}
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Error("archive kept despite delete_archive")
+			}
+		})
+	}
+
+	t.Run("not an archive", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "movie.mkv")
+		_ = os.WriteFile(path, []byte("x"), 0o644)
+		if err := extractArchive(path, "", true, noProgress); err != nil {
+			t.Fatalf("extractArchive: %v", err)
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Error("non-archive was touched")
+		}
+	})
+
+	t.Run("unsafe path", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "evil.zip")
+		writeZip(t, path, map[string]string{"../escaped.txt": "x"})
+		if err := extractArchive(path, "", false, noProgress); err == nil {
+			t.Fatal("archive escaping its directory was extracted")
+		}
+		if _, err := os.Stat(filepath.Join(dir, "escaped.txt")); !os.IsNotExist(err) {
+			t.Error("file written outside the extraction directory")
+		}
+	})
+}
+
+// recorder collects the events a manager publishes
+type recorder struct {
+	mu     sync.Mutex
+	events []interface{}
+}
+
+func (r *recorder) hooks() EngineHooks {
+	return EngineHooks{PublishEvent: func(msg interface{}) error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.events = append(r.events, msg)
+		return nil
+	}}
+}
+
+func (r *recorder) phases() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []string
+	for _, e := range r.events {
+		if m, ok := e.(events.DownloadPhaseMsg); ok && (len(out) == 0 || out[len(out)-1] != m.Phase) {
+			out = append(out, m.Phase)
+		}
+	}
+	return out
+}
+
+func (r *recorder) errorMsg() *events.DownloadErrorMsg {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.events {
+		if m, ok := e.(events.DownloadErrorMsg); ok {
+			return &m
+		}
+	}
+	return nil
+}
+
+func TestRunPostProcess_Pipeline(t *testing.T) {
+	testutil.SetupStateDB(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.pdf")
+	if err := os.WriteFile(path, []byte("12345"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.AddToMasterList(types.DownloadEntry{ID: "pp", URL: "https://example.com/report.pdf", DestPath: path, Filename: "report.pdf", Status: "completed", Category: "Docs"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var payload notifyPayload
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer hook.Close()
+
+	target := filepath.Join(dir, "archive")
+	steps := []config.PostStep{
+		{Type: config.StepVerify},
+		{Type: config.StepExtract, Disabled: true},
+		{Type: config.StepMove, Target: target},
+	}
+	if runtime.GOOS != "windows" {
+		steps = append(steps, config.PostStep{Type: config.StepScript, Command: "sh", Args: []string{"-c", `echo "$SURGE_ID {filename}" > marker`}})
+	}
+	steps = append(steps, config.PostStep{Type: config.StepNotify, URL: hook.URL})
+
+	rec := &recorder{}
+	mgr := NewLifecycleManager(nil, nil)
+	mgr.SetEngineHooks(rec.hooks())
+	mgr.runPostProcess(&postJob{id: "pp", url: "https://example.com/report.pdf", category: "Docs", path: path, size: 5}, steps)
+
+	want := []string{types.PhaseVerifying, types.PhaseMoving, types.PhaseScripting, types.PhaseNotifying, types.PhaseComplete}
+	if runtime.GOOS == "windows" {
+		want = []string{types.PhaseVerifying, types.PhaseMoving, types.PhaseNotifying, types.PhaseComplete}
+	}
+	if got := rec.phases(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("phases = %v, want %v", got, want)
+	}
+
+	moved := filepath.Join(target, "report.pdf")
+	if _, err := os.Stat(moved); err != nil {
+		t.Fatalf("file not moved: %v", err)
+	}
+	entry, err := state.GetDownload("pp")
+	if err != nil || entry == nil || entry.DestPath != moved {
+		t.Errorf("dest path = %+v, %v; want %s", entry, err, moved)
+	}
+	if runtime.GOOS != "windows" {
+		if got, _ := os.ReadFile(filepath.Join(target, "marker")); strings.TrimSpace(string(got)) != "pp report.pdf" {
+			t.Errorf("script output = %q", got)
+		}
+	}
+	if payload.ID != "pp" || payload.File != moved || payload.Event != "download.completed" {
+		t.Errorf("webhook payload = %+v", payload)
+	}
+}
+
+func TestRunPostProcess_FailurePolicies(t *testing.T) {
+	var notified int
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { notified++ }))
+	defer hook.Close()
+
+	tests := []struct {
+		policy       string
+		wantNotified int
+		wantError    bool
+	}{
+		{config.OnFailureContinue, 1, false},
+		{config.OnFailureStop, 0, false},
+		{config.OnFailureFail, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			notified = 0
+			path := filepath.Join(t.TempDir(), "short.bin")
+			_ = os.WriteFile(path, []byte("abc"), 0o644)
+
+			rec := &recorder{}
+			mgr := NewLifecycleManager(nil, nil)
+			mgr.SetEngineHooks(rec.hooks())
+			mgr.runPostProcess(&postJob{id: "f", path: path, size: 10}, []config.PostStep{
+				{Type: config.StepVerify, OnFailure: tt.policy},
+				{Type: config.StepNotify, URL: hook.URL},
+			})
+
+			if notified != tt.wantNotified {
+				t.Errorf("notify ran %d times, want %d", notified, tt.wantNotified)
+			}
+			phases := rec.phases()
+			completed := len(phases) > 0 && phases[len(phases)-1] == types.PhaseComplete
+			if errMsg := rec.errorMsg(); (errMsg != nil) != tt.wantError || completed == tt.wantError {
+				t.Errorf("error = %+v, phases = %v", errMsg, phases)
+			}
+		})
+	}
+}
+
+func TestRunScript_ExpandsVariablesOnce(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.bin")
+	if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The URL contains a placeholder that must reach the script verbatim
+	job := &postJob{id: "s", url: "https://example.com/?q={filename}", path: path}
+	step := config.PostStep{Type: config.StepScript, Command: "sh", Args: []string{"-c", `printf '%s' "$1" > marker`, "sh", "{url} {filename}"}}
+	if err := runScript(job, step); err != nil {
+		t.Fatalf("runScript: %v", err)
+	}
+	got, _ := os.ReadFile(filepath.Join(dir, "marker"))
+	if want := "https://example.com/?q={filename} a.bin"; string(got) != want {
+		t.Errorf("script arg = %q, want %q", got, want)
+	}
+}
@@ -13,7 +13,9 @@ import (
 	"time"
 
 	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/ratelimit"
 	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/engine/warmup"
 	"github.com/surge-downloader/surge/internal/utils"
 )
 
@@ -35,6 +37,39 @@ type ProbeResult struct {
 	SupportsRange bool
 	Filename      string
 	ContentType   string
+	ETag          string
+	FinalURL      string // URL the probe ended up at after redirects
+	Server        string
+	LastModified  string
+}
+
+// ProbeStatusError is returned when the server answers the probe with a
+// status that is neither 200 nor 206
+type ProbeStatusError struct {
+	StatusCode int
+}
+
+func (e *ProbeStatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
+// HTTPStatus returns the status, for types.HTTPStatus
+func (e *ProbeStatusError) HTTPStatus() int {
+	return e.StatusCode
+}
+
+// Cache returns the parts of the probe worth persisting for resume
+func (r *ProbeResult) Cache() types.CachedProbe {
+	return types.CachedProbe{
+		FileSize:      r.FileSize,
+		SupportsRange: r.SupportsRange,
+		ETag:          r.ETag,
+		FinalURL:      r.FinalURL,
+		ContentType:   r.ContentType,
+		Server:        r.Server,
+		LastModified:  r.LastModified,
+		ProbedAt:      time.Now().Unix(),
+	}
 }
 
 // probeHeadersContextKey is used to pass custom headers to the HTTP client's CheckRedirect function
@@ -78,6 +113,19 @@ func getProbeHostLock(rawurl string) *sync.Mutex {
 // the effective proxy and want probe traffic to match the eventual download path
 // without re-reading settings from disk.
 func ProbeServerWithProxy(ctx context.Context, rawurl string, filenameHint string, headers map[string]string, proxyURL string) (*ProbeResult, error) {
+	return probeServer(ctx, rawurl, filenameHint, headers, proxyURL, probeOptions{attempts: 3, serialize: true})
+}
+
+// probeOptions tunes how hard a probe tries. Primary probes retry and take
+// turns per host; mirror probes get one quick shot so a dead mirror is
+// dropped instead of delaying the start.
+type probeOptions struct {
+	attempts  int
+	serialize bool
+	timeout   time.Duration // Per attempt, not counting time queued behind other probes
+}
+
+func probeServer(ctx context.Context, rawurl string, filenameHint string, headers map[string]string, proxyURL string, opts probeOptions) (*ProbeResult, error) {
 	utils.Debug("Probing server: %s", rawurl)
 
 	// Embed custom headers in context so CheckRedirect can use them
@@ -90,14 +138,16 @@ func ProbeServerWithProxy(ctx context.Context, rawurl string, filenameHint strin
 	client := getProbeClient(proxyURL)
 
 	// Sequentialize probes to the same host to prevent rate limiting (e.g., Google Drive)
-	hostLock := getProbeHostLock(rawurl)
-	hostLock.Lock()
-	defer hostLock.Unlock()
+	if opts.serialize {
+		hostLock := getProbeHostLock(rawurl)
+		hostLock.Lock()
+		defer hostLock.Unlock()
+	}
 
 	var err error
 	var finalCancel context.CancelFunc
 
-	for attempt := range 3 {
+	for attempt := range max(opts.attempts, 1) {
 		if ctx.Err() != nil {
 			if err == nil {
 				err = fmt.Errorf("probe request aborted: %w", ctx.Err())
@@ -117,10 +167,22 @@ func ProbeServerWithProxy(ctx context.Context, rawurl string, filenameHint strin
 			utils.Debug("Retrying probe... attempt %d", attempt+1)
 		}
 
-		probeCtx, cancel := context.WithTimeout(ctx, types.ProbeTimeout)
+		// Probes share a limiter that yields to active downloads
+		release, acquireErr := ratelimit.Probes.Acquire(ctx)
+		if acquireErr != nil {
+			err = fmt.Errorf("probe request aborted: %w", acquireErr)
+			break
+		}
+
+		timeout := opts.timeout
+		if timeout <= 0 {
+			timeout = types.ProbeTimeout
+		}
+		probeCtx, cancel := context.WithTimeout(ctx, timeout)
 
 		req, reqErr := newProbeRequest(probeCtx, rawurl, headers, true)
 		if reqErr != nil {
+			release()
 			cancel()
 			err = fmt.Errorf("failed to create probe request: %w", reqErr)
 			break
@@ -136,6 +198,7 @@ func ProbeServerWithProxy(ctx context.Context, rawurl string, filenameHint strin
 
 			reqNoRange, reqNoRangeErr := newProbeRequest(probeCtx, rawurl, headers, false)
 			if reqNoRangeErr != nil {
+				release()
 				cancel()
 				err = fmt.Errorf("failed to create probe request without range: %w", reqNoRangeErr)
 				break
@@ -143,6 +206,7 @@ func ProbeServerWithProxy(ctx context.Context, rawurl string, filenameHint strin
 
 			resp, err = client.Do(reqNoRange)
 		}
+		release()
 
 		if err == nil {
 			finalCancel = cancel
@@ -197,7 +261,7 @@ func ProbeServerWithProxy(ctx context.Context, rawurl string, filenameHint strin
 		utils.Debug("Range NOT supported (got 200), file size: %d", result.FileSize)
 
 	default:
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, &ProbeStatusError{StatusCode: resp.StatusCode}
 	}
 
 	name, _, err := utils.DetermineFilename(rawurl, resp, false)
@@ -213,6 +277,21 @@ func ProbeServerWithProxy(ctx context.Context, rawurl string, filenameHint strin
 	}
 
 	result.ContentType = resp.Header.Get("Content-Type")
+	result.ETag = resp.Header.Get("ETag")
+	result.Server = resp.Header.Get("Server")
+	result.LastModified = resp.Header.Get("Last-Modified")
+	if resp.Request != nil && resp.Request.URL != nil {
+		result.FinalURL = resp.Request.URL.String()
+	}
+
+	// A forced gzip encoding makes Content-Length and ranges refer to the
+	// compressed stream, so the download must be a single decoded stream of
+	// unknown size.
+	if utils.IsTransferGzip(resp, result.Filename) {
+		utils.Debug("Response is gzip-encoded, disabling ranges (encoded size: %d)", result.FileSize)
+		result.SupportsRange = false
+		result.FileSize = 0
+	}
 
 	utils.Debug("Probe complete - filename: %s, size: %d, range: %v",
 		result.Filename, result.FileSize, result.SupportsRange)
@@ -311,10 +390,11 @@ func newProbeTransport(proxyURL string) *http.Transport {
 		TLSHandshakeTimeout:   types.DefaultTLSHandshakeTimeout,
 		ResponseHeaderTimeout: types.DefaultResponseHeaderTimeout,
 		ExpectContinueTimeout: types.DefaultExpectContinueTimeout,
-		DialContext: (&net.Dialer{
+		TLSClientConfig:       warmup.TLSConfig(),
+		DialContext: warmup.DialContext(&net.Dialer{
 			Timeout:   types.DialTimeout,
 			KeepAlive: types.KeepAliveDuration,
-		}).DialContext,
+		}),
 	}
 }
 
@@ -356,6 +436,13 @@ func ProbeMirrors(ctx context.Context, mirrors []string) (valid []string, errs m
 
 // ProbeMirrorsWithProxy preserves caller order so mirror priority remains stable.
 func ProbeMirrorsWithProxy(ctx context.Context, mirrors []string, proxyURL string) (valid []string, errs map[string]error) {
+	return ProbeMirrorsForSize(ctx, mirrors, proxyURL, 0)
+}
+
+// ProbeMirrorsForSize probes all mirrors at once, each with a single attempt
+// bounded by MirrorProbeTimeout. Mirrors that are unreachable, lack range
+// support or report a size other than expectedSize (when > 0) are dropped.
+func ProbeMirrorsForSize(ctx context.Context, mirrors []string, proxyURL string, expectedSize int64) (valid []string, errs map[string]error) {
 	candidates := orderedUniqueMirrors(mirrors)
 	utils.Debug("Probing %d mirrors...", len(candidates))
 
@@ -377,19 +464,20 @@ func ProbeMirrorsWithProxy(ctx context.Context, mirrors []string, proxyURL strin
 
 			// Mirror checks stay short so a dead backup does not delay the primary
 			// download from starting with the best candidates we can confirm quickly.
-			probeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
-			defer cancel()
-
-			result, err := ProbeServerWithProxy(probeCtx, target, "", nil, proxyURL)
+			// The timeout starts once the probe limiter lets the request go.
+			result, err := probeServer(ctx, target, "", nil, proxyURL, probeOptions{attempts: 1, timeout: types.MirrorProbeTimeout})
 
 			outcome := mirrorProbeResult{}
-			if err != nil {
+			switch {
+			case err != nil:
 				outcome.err = err
-			} else {
-				outcome.valid = result.SupportsRange
-				if !result.SupportsRange {
-					outcome.err = fmt.Errorf("does not support ranges")
-				}
+			case !result.SupportsRange:
+				outcome.err = fmt.Errorf("does not support ranges")
+			case expectedSize > 0 && result.FileSize > 0 && result.FileSize != expectedSize:
+				// A mirror serving a different file would corrupt the download
+				outcome.err = fmt.Errorf("size mismatch: mirror has %d bytes, expected %d", result.FileSize, expectedSize)
+			default:
+				outcome.valid = true
 			}
 			results[idx] = outcome
 		}(i, url)
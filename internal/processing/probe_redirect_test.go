@@ -165,3 +165,37 @@ func TestProbeServer_RetryWithoutRangeReusesHeaderSetup(t *testing.T) {
 		t.Fatalf("fileSize = %d, want 5", res.FileSize)
 	}
 }
+
+func TestProbeRedirect_RecordsFinalURLAndETag(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/final", http.StatusFound)
+	})
+	mux.HandleFunc("/final", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Server", "test-origin")
+		w.Header().Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Range", "bytes 0-0/2048")
+		w.WriteHeader(http.StatusPartialContent)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	res, err := processing.ProbeServer(context.Background(), server.URL+"/redirect", "", nil)
+	if err != nil {
+		t.Fatalf("ProbeServer failed: %v", err)
+	}
+
+	cached := res.Cache()
+	if cached.FinalURL != server.URL+"/final" {
+		t.Errorf("FinalURL = %q, want %q", cached.FinalURL, server.URL+"/final")
+	}
+	if cached.ETag != `"v1"` || cached.FileSize != 2048 || !cached.SupportsRange {
+		t.Errorf("unexpected cached probe: %+v", cached)
+	}
+	if cached.Server != "test-origin" || cached.LastModified != "Wed, 21 Oct 2015 07:28:00 GMT" || cached.ContentType != "application/zip" {
+		t.Errorf("response headers not recorded: %+v", cached)
+	}
+}
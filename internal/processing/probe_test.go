@@ -2,8 +2,10 @@ package processing_test
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -123,3 +125,66 @@ func TestProbeServer_ReadsBodyBeforeContextCancel(t *testing.T) {
 		t.Errorf("Expected filename 'delayed.txt', got %q. The context might have been prematurely canceled.", result.Filename)
 	}
 }
+
+func TestProbeServer_ForcedGzipDisablesRanges(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Range", "bytes 0-0/500")
+		w.WriteHeader(http.StatusPartialContent)
+	}))
+	defer server.Close()
+
+	result, err := processing.ProbeServerWithProxy(context.Background(), server.URL+"/report.csv", "", nil, "")
+	if err != nil {
+		t.Fatalf("ProbeServerWithProxy() error = %v", err)
+	}
+	if result.SupportsRange {
+		t.Error("expected ranges to be disabled for gzip-encoded response")
+	}
+	if result.FileSize != 0 {
+		t.Errorf("expected unknown size for gzip-encoded response, got %d", result.FileSize)
+	}
+
+	// A .gz file mislabelled with Content-Encoding is stored as-is, so ranges still apply
+	result, err = processing.ProbeServerWithProxy(context.Background(), server.URL+"/archive.tar.gz", "", nil, "")
+	if err != nil {
+		t.Fatalf("ProbeServerWithProxy() error = %v", err)
+	}
+	if !result.SupportsRange || result.FileSize != 500 {
+		t.Errorf("expected .gz payload to keep range support, got range=%v size=%d", result.SupportsRange, result.FileSize)
+	}
+}
+
+func TestProbeMirrorsForSize_DropsDeadAndMismatchedMirrorsQuickly(t *testing.T) {
+	newMirror := func(size int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-0/%d", size))
+			w.WriteHeader(http.StatusPartialContent)
+		}))
+	}
+	good := newMirror(10)
+	defer good.Close()
+	wrongSize := newMirror(99)
+	defer wrongSize.Close()
+
+	// A closed server refuses connections; primary probes would retry it
+	dead := httptest.NewServer(http.NotFoundHandler())
+	deadURL := dead.URL
+	dead.Close()
+
+	start := time.Now()
+	valid, errs := processing.ProbeMirrorsForSize(context.Background(), []string{deadURL, wrongSize.URL, good.URL}, "", 10)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected dead mirror to be dropped without retries, took %v", elapsed)
+	}
+
+	if len(valid) != 1 || valid[0] != good.URL {
+		t.Fatalf("valid = %v, want only %s", valid, good.URL)
+	}
+	if _, ok := errs[deadURL]; !ok {
+		t.Errorf("expected dead mirror error, got %v", errs)
+	}
+	if err, ok := errs[wrongSize.URL]; !ok || !strings.Contains(err.Error(), "size mismatch") {
+		t.Errorf("expected size mismatch for %s, got %v", wrongSize.URL, errs)
+	}
+}
@@ -0,0 +1,101 @@
+package processing
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// ErrNotRelocatable is returned when moving or renaming the file of a
+// download that is neither finished nor paused
+var ErrNotRelocatable = errors.New("only finished or paused downloads can be moved or renamed; pause it first")
+
+// ErrInvalidFilename is returned when renaming a file to an empty name or one
+// with a path in it
+var ErrInvalidFilename = errors.New("invalid file name")
+
+// RelocateDownload moves the file of a finished or paused download into
+// dir, renaming it if the name is taken there, and records the new path. A
+// paused download's working file moves instead; its saved progress is looked
+// up by the new path from then on, so it resumes where it left off. It
+// returns the new path.
+func RelocateDownload(id, dir string) (string, error) {
+	return relocate(id, utils.EnsureAbsPath(expandHome(dir)), "")
+}
+
+// RenameDownload renames the file of a finished or paused download in its
+// directory like RelocateDownload, adding a number to name if it is taken
+func RenameDownload(id, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("%w %q", ErrInvalidFilename, name)
+	}
+	return relocate(id, "", name)
+}
+
+// relocate moves download id's file to name in dir; an empty dir keeps its
+// directory and an empty name its filename
+func relocate(id, dir, name string) (string, error) {
+	entry, err := state.GetDownload(id)
+	if err != nil {
+		return "", err
+	}
+	if entry == nil {
+		return "", types.ErrNotFound
+	}
+	if entry.Status != "completed" && entry.Status != "paused" {
+		return "", ErrNotRelocatable
+	}
+
+	if dir == "" {
+		dir = filepath.Dir(entry.DestPath)
+	}
+	if name == "" {
+		name = entry.Filename
+		if name == "" {
+			name = filepath.Base(entry.DestPath)
+		}
+	}
+	if filepath.Join(dir, name) == entry.DestPath {
+		return entry.DestPath, nil
+	}
+
+	// A paused download that never wrote anything has no file to move
+	src, suffix := entry.DestPath, ""
+	if entry.Status == "paused" {
+		suffix = types.IncompleteSuffix
+		if state.WorkingFileMissing(entry.DestPath, entry.Downloaded) {
+			return "", ErrWorkingFileMissing
+		}
+		if _, err := os.Stat(src + suffix); os.IsNotExist(err) {
+			src = ""
+		}
+	} else if _, err := os.Stat(src); err != nil {
+		return "", fmt.Errorf("file of %s: %w", id, err)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	unique := GetUniqueFilename(dir, name, nil)
+	if unique == "" {
+		return "", fmt.Errorf("no usable name for %s in %s", name, dir)
+	}
+	dst := filepath.Join(dir, unique)
+	if src != "" {
+		if err := moveFile(src+suffix, dst+suffix, nil); err != nil {
+			return "", err
+		}
+	}
+	if err := state.UpdateDestPath(id, dst, unique); err != nil {
+		return "", fmt.Errorf("moved to %s but failed to record it: %w", dst, err)
+	}
+	utils.Debug("Relocate: moved %s from %s to %s", id, entry.DestPath, dst)
+	return dst, nil
+}
@@ -0,0 +1,135 @@
+package processing
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/testutil"
+)
+
+func TestRelocateDownload_Completed(t *testing.T) {
+	tmpDir := testutil.SetupStateDB(t)
+	src := filepath.Join(tmpDir, "file.iso")
+	if err := os.WriteFile(src, []byte("done"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.AddToMasterList(types.DownloadEntry{ID: "done", URL: "https://example.com/file.iso", DestPath: src, Filename: "file.iso", Status: "completed"}); err != nil {
+		t.Fatal(err)
+	}
+	// The name is taken in the new directory, so the file is renamed
+	dir := filepath.Join(tmpDir, "archive")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "file.iso"), []byte("other"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dst, err := RelocateDownload("done", dir)
+	if err != nil {
+		t.Fatalf("RelocateDownload failed: %v", err)
+	}
+	if filepath.Dir(dst) != dir || filepath.Base(dst) == "file.iso" {
+		t.Fatalf("moved to %s, want a new name in %s", dst, dir)
+	}
+	if data, err := os.ReadFile(dst); err != nil || string(data) != "done" {
+		t.Fatalf("moved file = %q, %v", data, err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Errorf("old file still exists: %v", err)
+	}
+	entry, err := state.GetDownload("done")
+	if err != nil || entry.DestPath != dst || entry.Filename != filepath.Base(dst) {
+		t.Fatalf("entry = %+v, %v; want dest %s", entry, err, dst)
+	}
+}
+
+func TestRelocateDownload_PausedKeepsResumeState(t *testing.T) {
+	tmpDir := testutil.SetupStateDB(t)
+	paused := &types.DownloadState{
+		ID:         "paused",
+		URL:        "https://example.com/big.bin",
+		DestPath:   filepath.Join(tmpDir, "big.bin"),
+		Filename:   "big.bin",
+		TotalSize:  100,
+		Downloaded: 50,
+		Tasks:      []types.Task{{Offset: 50, Length: 50}},
+	}
+	if err := os.WriteFile(paused.DestPath+types.IncompleteSuffix, make([]byte, 50), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.SaveState(paused.URL, paused.DestPath, paused); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(tmpDir, "elsewhere")
+	dst, err := RelocateDownload("paused", dir)
+	if err != nil {
+		t.Fatalf("RelocateDownload failed: %v", err)
+	}
+	if dst != filepath.Join(dir, "big.bin") {
+		t.Fatalf("moved to %s", dst)
+	}
+	if _, err := os.Stat(dst + types.IncompleteSuffix); err != nil {
+		t.Fatalf("working file not moved: %v", err)
+	}
+	saved, err := state.LoadState(paused.URL, dst)
+	if err != nil {
+		t.Fatalf("LoadState at the new path failed: %v", err)
+	}
+	if saved.Downloaded != 50 || len(saved.Tasks) != 1 || saved.Tasks[0].Offset != 50 {
+		t.Errorf("saved state = %+v, want the progress kept", saved)
+	}
+}
+
+func TestRelocateDownload_Refused(t *testing.T) {
+	tmpDir := testutil.SetupStateDB(t)
+	if err := state.AddToMasterList(types.DownloadEntry{ID: "running", URL: "https://example.com/a", DestPath: filepath.Join(tmpDir, "a"), Filename: "a", Status: "downloading"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RelocateDownload("running", filepath.Join(tmpDir, "x")); !errors.Is(err, ErrNotRelocatable) {
+		t.Errorf("running download: err = %v, want ErrNotRelocatable", err)
+	}
+	if _, err := RelocateDownload("missing", filepath.Join(tmpDir, "x")); !errors.Is(err, types.ErrNotFound) {
+		t.Errorf("unknown download: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestRenameDownload(t *testing.T) {
+	tmpDir := testutil.SetupStateDB(t)
+	src := filepath.Join(tmpDir, "download.bin")
+	for _, path := range []string{src, filepath.Join(tmpDir, "ubuntu.iso")} {
+		if err := os.WriteFile(path, []byte("data"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := state.AddToMasterList(types.DownloadEntry{ID: "done", URL: "https://example.com/download.bin", DestPath: src, Filename: "download.bin", Status: "completed"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"", "..", "sub/ubuntu.iso"} {
+		if _, err := RenameDownload("done", name); !errors.Is(err, ErrInvalidFilename) {
+			t.Errorf("RenameDownload(%q): err = %v, want ErrInvalidFilename", name, err)
+		}
+	}
+
+	// ubuntu.iso is taken, so the file gets the next free name
+	dst, err := RenameDownload("done", "ubuntu.iso")
+	if err != nil {
+		t.Fatalf("RenameDownload failed: %v", err)
+	}
+	if want := filepath.Join(tmpDir, "ubuntu(1).iso"); dst != want {
+		t.Fatalf("renamed to %s, want %s", dst, want)
+	}
+	if _, err := os.Stat(dst); err != nil {
+		t.Fatalf("renamed file missing: %v", err)
+	}
+	entry, err := state.GetDownload("done")
+	if err != nil || entry.Filename != "ubuntu(1).iso" || entry.DestPath != dst {
+		t.Fatalf("entry = %+v, %v", entry, err)
+	}
+}
@@ -0,0 +1,52 @@
+package processing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// ErrStillFailing means the probe Retry was asked for failed, so the
+// download was left as it was
+var ErrStillFailing = errors.New("the URL still fails")
+
+// Retry starts a failed download over from the beginning, in place, keeping
+// its ID, destination and overrides. With probe the URL is probed first: a
+// download whose server still fails stays failed, and otherwise it starts
+// from what the new probe found instead of the one cached when it was added.
+func (mgr *LifecycleManager) Retry(id string, probe bool) error {
+	entry, err := state.GetDownload(id)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return types.ErrNotFound
+	}
+	if entry.Status != "error" {
+		return state.ErrNotFailed
+	}
+
+	if probe {
+		var headers map[string]string
+		if entry.Overrides != nil {
+			headers = entry.Overrides.Headers
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), types.ProbeTimeout)
+		defer cancel()
+		result, err := ProbeServerWithProxy(ctx, entry.URL, entry.Filename, headers, mgr.GetSettings().Network.ProxyURL)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrStillFailing, err)
+		}
+		if _, err := state.SaveProbeCache(id, result.Cache()); err != nil {
+			return err
+		}
+	}
+
+	if err := state.ResetFailed(id); err != nil {
+		return err
+	}
+	return mgr.Resume(id)
+}
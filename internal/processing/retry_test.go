@@ -0,0 +1,89 @@
+package processing
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/testutil"
+)
+
+func TestLifecycle_RetryStartsFailedDownloadOver(t *testing.T) {
+	testutil.SetupStateDB(t)
+
+	var notFound bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if notFound {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Range", "bytes 0-0/8192")
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = w.Write([]byte("x"))
+	}))
+	defer server.Close()
+
+	destPath := filepath.Join(t.TempDir(), "file.bin")
+	for _, e := range []types.DownloadEntry{
+		{ID: "failed", URL: server.URL + "/file.bin", DestPath: destPath, Filename: "file.bin", Status: "error", TotalSize: 4096, Downloaded: 1024,
+			Overrides: &types.DownloadOverrides{Connections: 2}},
+		{ID: "paused", URL: server.URL + "/other.bin", DestPath: destPath + ".2", Filename: "other.bin", Status: "paused"},
+	} {
+		if err := state.AddToMasterList(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := state.SaveProbeCache("failed", types.CachedProbe{FileSize: 4096, SupportsRange: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	var queued []types.DownloadConfig
+	mgr := NewLifecycleManager(nil, nil)
+	mgr.SetEngineHooks(EngineHooks{
+		Resume:    func(string) bool { return false },
+		AddConfig: func(cfg types.DownloadConfig) { queued = append(queued, cfg) },
+	})
+
+	if err := mgr.Retry("paused", false); !errors.Is(err, state.ErrNotFailed) {
+		t.Errorf("Retry(paused) = %v, want ErrNotFailed", err)
+	}
+	if err := mgr.Retry("missing", false); !errors.Is(err, types.ErrNotFound) {
+		t.Errorf("Retry(missing) = %v, want ErrNotFound", err)
+	}
+
+	// A probe that still fails leaves the download as it was
+	notFound = true
+	if err := mgr.Retry("failed", true); !errors.Is(err, ErrStillFailing) {
+		t.Fatalf("Retry with a failing probe = %v, want ErrStillFailing", err)
+	}
+	if entry, _ := state.GetDownload("failed"); entry == nil || entry.Status != "error" || len(queued) != 0 {
+		t.Fatalf("after a failing probe: entry = %+v, queued = %d", entry, len(queued))
+	}
+
+	notFound = false
+	if err := mgr.Retry("failed", true); err != nil {
+		t.Fatalf("Retry failed: %v", err)
+	}
+	entry, err := state.GetDownload("failed")
+	if err != nil || entry == nil {
+		t.Fatalf("GetDownload = %v, %v", entry, err)
+	}
+	if entry.Status != "queued" || entry.Downloaded != 0 || entry.Overrides == nil || entry.Overrides.Connections != 2 {
+		t.Errorf("retried entry = %+v, want it queued from scratch with its overrides", entry)
+	}
+
+	if len(queued) != 1 {
+		t.Fatalf("queued %d configs, want 1", len(queued))
+	}
+	cfg := queued[0]
+	if cfg.ID != "failed" || cfg.DestPath != destPath || cfg.TotalSize != 8192 || !cfg.SupportsRange {
+		t.Errorf("retry config = id %s, dest %s, size %d, ranges %v; want the new probe's size with ranges", cfg.ID, cfg.DestPath, cfg.TotalSize, cfg.SupportsRange)
+	}
+	if cfg.State == nil || cfg.State.Downloaded.Load() != 0 {
+		t.Error("retry should start from zero bytes")
+	}
+}
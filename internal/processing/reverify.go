@@ -0,0 +1,170 @@
+package processing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// ErrRemoteChanged means the remote file no longer matches what was already
+// downloaded, so resuming would splice new bytes onto stale content
+var ErrRemoteChanged = errors.New("remote file changed since the download was paused")
+
+const (
+	// reverifySampleSize is how much of each sampled range is fetched again
+	reverifySampleSize = 64 * types.KB
+	// reverifySamples is how many downloaded ranges are compared
+	reverifySamples = 3
+)
+
+// NeedsRevalidation reports whether saved was paused long enough ago that
+// the remote file must be re-checked before resuming
+func NeedsRevalidation(saved *types.DownloadState, runtime *types.RuntimeConfig, now time.Time) bool {
+	after := runtime.GetReverifyAfter()
+	if after == 0 || saved == nil || saved.PausedAt <= 0 || saved.Downloaded <= 0 {
+		return false
+	}
+	return now.Sub(time.Unix(saved.PausedAt, 0)) >= after
+}
+
+// RevalidateResume re-probes the URL of a long-paused download and compares
+// its size and ETag with what was recorded, then re-downloads a few samples
+// of the finished ranges and compares them with the working file. It returns
+// an error wrapping ErrRemoteChanged on any mismatch.
+func RevalidateResume(ctx context.Context, cfg *types.DownloadConfig, saved *types.DownloadState) error {
+	proxyURL := ""
+	if cfg.Runtime != nil {
+		proxyURL = cfg.Runtime.ProxyURL
+	}
+	utils.Debug("Revalidating %s, paused since %s", cfg.ID, time.Unix(saved.PausedAt, 0).Format(time.RFC3339))
+
+	probe, err := ProbeServerWithProxy(ctx, cfg.URL, "", cfg.Headers, proxyURL)
+	if err != nil {
+		return fmt.Errorf("failed to revalidate paused download: %w", err)
+	}
+
+	if saved.TotalSize > 0 && probe.FileSize > 0 && probe.FileSize != saved.TotalSize {
+		return fmt.Errorf("%w: size is now %d bytes, was %d", ErrRemoteChanged, probe.FileSize, saved.TotalSize)
+	}
+	if cfg.Probe != nil && cfg.Probe.ETag != "" && probe.ETag != "" && probe.ETag != cfg.Probe.ETag {
+		return fmt.Errorf("%w: ETag is now %s, was %s", ErrRemoteChanged, probe.ETag, cfg.Probe.ETag)
+	}
+
+	if probe.SupportsRange {
+		if err := compareSamples(ctx, cfg, saved, proxyURL); err != nil {
+			return err
+		}
+	}
+
+	if _, err := state.SaveProbeCache(cfg.ID, probe.Cache()); err != nil {
+		utils.Debug("Failed to refresh probe cache for %s: %v", cfg.ID, err)
+	}
+	return nil
+}
+
+// byteRange is a half-open [start, end) span of the file
+type byteRange struct {
+	start, end int64
+}
+
+// downloadedRanges returns the parts of the file not covered by remaining tasks
+func downloadedRanges(totalSize int64, remaining []types.Task) []byteRange {
+	tasks := append([]types.Task(nil), remaining...)
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].Offset < tasks[j].Offset })
+
+	var out []byteRange
+	pos := int64(0)
+	for _, t := range tasks {
+		if t.Offset > pos {
+			out = append(out, byteRange{pos, t.Offset})
+		}
+		pos = max(pos, t.Offset+t.Length)
+	}
+	if pos < totalSize {
+		out = append(out, byteRange{pos, totalSize})
+	}
+	return out
+}
+
+// pickSamples spreads up to reverifySamples sample windows over the first,
+// middle and last downloaded ranges
+func pickSamples(ranges []byteRange) []byteRange {
+	if len(ranges) == 0 {
+		return nil
+	}
+	picks := []int{0, len(ranges) / 2, len(ranges) - 1}
+	seen := make(map[int]bool)
+	var out []byteRange
+	for _, i := range picks[:min(reverifySamples, len(picks))] {
+		if seen[i] {
+			continue
+		}
+		seen[i] = true
+		r := ranges[i]
+		size := min(int64(reverifySampleSize), r.end-r.start)
+		start := r.start + (r.end-r.start-size)/2
+		out = append(out, byteRange{start, start + size})
+	}
+	return out
+}
+
+func compareSamples(ctx context.Context, cfg *types.DownloadConfig, saved *types.DownloadState, proxyURL string) error {
+	if len(saved.Tasks) == 0 {
+		// Without a task list we cannot tell which bytes are real data
+		return nil
+	}
+	samples := pickSamples(downloadedRanges(saved.TotalSize, saved.Tasks))
+	if len(samples) == 0 {
+		return nil
+	}
+
+	file, err := os.Open(saved.DestPath + types.IncompleteSuffix)
+	if err != nil {
+		return fmt.Errorf("failed to open working file for revalidation: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	client := getProbeClient(proxyURL)
+	for _, sample := range samples {
+		size := sample.end - sample.start
+		local := make([]byte, size)
+		if _, err := file.ReadAt(local, sample.start); err != nil {
+			return fmt.Errorf("failed to read working file for revalidation: %w", err)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, cfg.URL, nil)
+		if err != nil {
+			return err
+		}
+		applyProbeHeaders(req, cfg.Headers, false)
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", sample.start, sample.end-1))
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to fetch revalidation sample: %w", err)
+		}
+		remote, err := io.ReadAll(io.LimitReader(resp.Body, size+1))
+		_ = resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read revalidation sample: %w", err)
+		}
+		if resp.StatusCode != http.StatusPartialContent {
+			utils.Debug("Revalidation sample got %d, skipping content check", resp.StatusCode)
+			return nil
+		}
+		if !bytes.Equal(local, remote) {
+			return fmt.Errorf("%w: bytes %d-%d differ from the downloaded data", ErrRemoteChanged, sample.start, sample.end-1)
+		}
+	}
+	return nil
+}
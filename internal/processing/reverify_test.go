@@ -0,0 +1,141 @@
+package processing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/testutil"
+)
+
+type reverifyFixture struct {
+	server   *httptest.Server
+	content  atomic.Value // []byte
+	etag     atomic.Value // string
+	requests atomic.Int32
+	cfg      *types.DownloadConfig
+	saved    *types.DownloadState
+}
+
+// newReverifyFixture serves 1MB of data and leaves a working file whose
+// first and last quarters are downloaded, with the middle still pending
+func newReverifyFixture(t *testing.T) *reverifyFixture {
+	t.Helper()
+	testutil.SetupStateDB(t)
+
+	f := &reverifyFixture{}
+	content := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	f.content.Store(content)
+	f.etag.Store(`"v1"`)
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.requests.Add(1)
+		w.Header().Set("ETag", f.etag.Load().(string))
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(f.content.Load().([]byte)))
+	}))
+	t.Cleanup(f.server.Close)
+
+	size := int64(len(content))
+	dest := filepath.Join(t.TempDir(), "file.bin")
+	working := make([]byte, size)
+	copy(working[:size/4], content[:size/4])
+	copy(working[size*3/4:], content[size*3/4:])
+	if err := os.WriteFile(dest+types.IncompleteSuffix, working, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f.cfg = &types.DownloadConfig{
+		ID:      "reverify-id",
+		URL:     f.server.URL,
+		Runtime: &types.RuntimeConfig{ReverifyAfter: 24 * time.Hour},
+		Probe:   &types.CachedProbe{FileSize: size, SupportsRange: true, ETag: `"v1"`},
+	}
+	f.saved = &types.DownloadState{
+		ID:         "reverify-id",
+		DestPath:   dest,
+		TotalSize:  size,
+		Downloaded: size / 2,
+		Tasks:      []types.Task{{Offset: size / 4, Length: size / 2}},
+		PausedAt:   time.Now().Add(-48 * time.Hour).Unix(),
+	}
+	return f
+}
+
+func TestNeedsRevalidation(t *testing.T) {
+	f := newReverifyFixture(t)
+	now := time.Now()
+
+	if !NeedsRevalidation(f.saved, f.cfg.Runtime, now) {
+		t.Error("expected a download paused two days ago to need revalidation")
+	}
+	f.saved.PausedAt = now.Add(-time.Hour).Unix()
+	if NeedsRevalidation(f.saved, f.cfg.Runtime, now) {
+		t.Error("expected a recent pause to skip revalidation")
+	}
+	f.saved.PausedAt = now.Add(-48 * time.Hour).Unix()
+	if NeedsRevalidation(f.saved, &types.RuntimeConfig{}, now) {
+		t.Error("expected revalidation to be off when disabled")
+	}
+}
+
+func TestRevalidateResume_UnchangedPasses(t *testing.T) {
+	f := newReverifyFixture(t)
+	if err := RevalidateResume(context.Background(), f.cfg, f.saved); err != nil {
+		t.Fatalf("expected unchanged remote to pass, got %v", err)
+	}
+	// One probe plus one sample from each downloaded range
+	if got := f.requests.Load(); got < 3 {
+		t.Errorf("expected probe and sample requests, got %d", got)
+	}
+}
+
+func TestRevalidateResume_DetectsChanges(t *testing.T) {
+	t.Run("etag", func(t *testing.T) {
+		f := newReverifyFixture(t)
+		f.etag.Store(`"v2"`)
+		if err := RevalidateResume(context.Background(), f.cfg, f.saved); !errors.Is(err, ErrRemoteChanged) {
+			t.Fatalf("expected ErrRemoteChanged, got %v", err)
+		}
+	})
+
+	t.Run("size", func(t *testing.T) {
+		f := newReverifyFixture(t)
+		f.content.Store(append(f.content.Load().([]byte), 'x'))
+		if err := RevalidateResume(context.Background(), f.cfg, f.saved); !errors.Is(err, ErrRemoteChanged) {
+			t.Fatalf("expected ErrRemoteChanged, got %v", err)
+		}
+	})
+
+	t.Run("content", func(t *testing.T) {
+		f := newReverifyFixture(t)
+		// Same size and ETag, but the bytes we already have are stale
+		changed := bytes.Clone(f.content.Load().([]byte))
+		for i := range changed[:len(changed)/4] {
+			changed[i] = 'z'
+		}
+		f.content.Store(changed)
+		if err := RevalidateResume(context.Background(), f.cfg, f.saved); !errors.Is(err, ErrRemoteChanged) {
+			t.Fatalf("expected ErrRemoteChanged, got %v", err)
+		}
+	})
+}
+
+func TestDownloadedRanges(t *testing.T) {
+	got := downloadedRanges(100, []types.Task{{Offset: 60, Length: 10}, {Offset: 20, Length: 10}})
+	want := []byteRange{{0, 20}, {30, 60}, {70, 100}}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package processing
+
+import (
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// RestoreTrashed brings a download back from the trash. A completed download
+// is listed as completed again; any other comes back paused for the user to
+// resume.
+func (mgr *LifecycleManager) RestoreTrashed(id string) error {
+	entry, err := state.RestoreTrashed(id)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return nil
+	}
+
+	if hooks := mgr.getEngineHooks(); hooks.PublishEvent != nil {
+		_ = hooks.PublishEvent(events.DownloadRestoredMsg{
+			DownloadID: entry.ID,
+			URL:        entry.URL,
+			Filename:   entry.Filename,
+			DestPath:   entry.DestPath,
+			Status:     entry.Status,
+			TotalSize:  entry.TotalSize,
+			Downloaded: entry.Downloaded,
+			Tags:       entry.Tags,
+		})
+	}
+	return nil
+}
+
+// PurgeTrash permanently deletes the downloads that have been in the trash
+// longer than settings keep them, with the working files of unfinished ones.
+// Completed files stay on disk, as they do when a download is deleted at
+// once. It returns how many downloads were purged.
+func PurgeTrash(settings *config.Settings) (int, error) {
+	if settings == nil {
+		settings = config.DefaultSettings()
+	}
+	retention := time.Duration(max(settings.General.TrashRetentionDays, 0)) * 24 * time.Hour
+	purged, err := state.PurgeTrash(time.Now().Add(-retention))
+	for _, e := range purged {
+		if e.CompletedAt > 0 {
+			continue
+		}
+		if rmErr := RemoveIncompleteFile(e.DestPath); rmErr != nil {
+			utils.Debug("Lifecycle: Failed to remove working file of purged download %s: %v", e.ID, rmErr)
+		}
+	}
+	return len(purged), err
+}
@@ -0,0 +1,5 @@
+// Package surgepb holds the Go bindings generated from
+// proto/surge/v1/surge.proto, the daemon's gRPC API.
+package surgepb
+
+//go:generate protoc -I ../../../proto --go_out=../../.. --go_opt=module=github.com/surge-downloader/surge --go-grpc_out=../../.. --go-grpc_opt=module=github.com/surge-downloader/surge surge/v1/surge.proto
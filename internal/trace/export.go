@@ -0,0 +1,276 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	exportQueueSize = 2048
+	exportBatchSize = 512
+	exportInterval  = 5 * time.Second
+	exportTimeout   = 10 * time.Second
+)
+
+var (
+	exporterMu sync.RWMutex
+	exporter   *otlpExporter
+)
+
+// ConfigureFromEnv enables OTLP/HTTP (JSON) span export when the standard
+// OpenTelemetry environment variables name a collector:
+//
+//	OTEL_EXPORTER_OTLP_TRACES_ENDPOINT  full URL spans are POSTed to
+//	OTEL_EXPORTER_OTLP_ENDPOINT         base URL; /v1/traces is appended
+//	OTEL_EXPORTER_OTLP_HEADERS          extra headers, "k1=v1,k2=v2"
+//	OTEL_SERVICE_NAME                   service.name resource (default "surge")
+//	OTEL_TRACES_EXPORTER=none           disables export
+//
+// It reports whether export was enabled.
+func ConfigureFromEnv() bool {
+	if strings.EqualFold(os.Getenv("OTEL_TRACES_EXPORTER"), "none") {
+		return false
+	}
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if endpoint == "" {
+		if base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); base != "" {
+			endpoint = strings.TrimRight(base, "/") + "/v1/traces"
+		}
+	}
+	if endpoint == "" {
+		return false
+	}
+	service := os.Getenv("OTEL_SERVICE_NAME")
+	if service == "" {
+		service = "surge"
+	}
+	ConfigureExport(endpoint, service, parseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")))
+	return true
+}
+
+// ConfigureExport starts exporting finished spans to an OTLP/HTTP endpoint,
+// replacing any previous exporter. An empty endpoint disables export.
+func ConfigureExport(endpoint, service string, headers map[string]string) {
+	var e *otlpExporter
+	if endpoint != "" {
+		e = &otlpExporter{
+			endpoint: endpoint,
+			service:  service,
+			headers:  headers,
+			client:   &http.Client{Timeout: exportTimeout},
+			queue:    make(chan *Span, exportQueueSize),
+			done:     make(chan struct{}),
+		}
+		go e.run()
+	}
+
+	exporterMu.Lock()
+	old := exporter
+	exporter = e
+	exporterMu.Unlock()
+
+	if old != nil {
+		old.shutdown(context.Background())
+	}
+}
+
+// Shutdown flushes spans still queued for export and stops the exporter
+func Shutdown(ctx context.Context) {
+	exporterMu.Lock()
+	e := exporter
+	exporter = nil
+	exporterMu.Unlock()
+
+	if e != nil {
+		e.shutdown(ctx)
+	}
+}
+
+func currentExporter() *otlpExporter {
+	exporterMu.RLock()
+	defer exporterMu.RUnlock()
+	return exporter
+}
+
+func parseHeaders(v string) map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(v, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			continue
+		}
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return headers
+}
+
+type otlpExporter struct {
+	endpoint string
+	service  string
+	headers  map[string]string
+	client   *http.Client
+
+	queue    chan *Span
+	done     chan struct{}
+	stopOnce sync.Once
+	mu       sync.RWMutex // guards sends on queue against close
+	closed   bool
+}
+
+// enqueue never blocks: spans are dropped when the collector can't keep up
+func (e *otlpExporter) enqueue(s *Span) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closed {
+		return
+	}
+	select {
+	case e.queue <- s:
+	default:
+	}
+}
+
+func (e *otlpExporter) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, exportBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		_ = e.export(batch)
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case s, ok := <-e.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, s)
+			if len(batch) >= exportBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func (e *otlpExporter) shutdown(ctx context.Context) {
+	e.stopOnce.Do(func() {
+		e.mu.Lock()
+		e.closed = true
+		close(e.queue)
+		e.mu.Unlock()
+	})
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+	}
+}
+
+func (e *otlpExporter) export(spans []*Span) error {
+	body, err := json.Marshal(e.payload(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("otlp export: %s", resp.Status)
+	}
+	return nil
+}
+
+// OTLP/JSON wire types (opentelemetry-proto, JSON encoding)
+
+type otlpAttribute struct {
+	Key   string            `json:"key"`
+	Value map[string]string `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+const (
+	spanKindInternal = 1
+	statusCodeOK     = 1
+	statusCodeError  = 2
+)
+
+func stringAttr(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: map[string]string{"stringValue": value}}
+}
+
+func (e *otlpExporter) payload(spans []*Span) map[string]any {
+	out := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		o := otlpSpan{
+			TraceID:           s.traceID,
+			SpanID:            s.spanID,
+			ParentSpanID:      s.parentID,
+			Name:              s.name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Status:            otlpStatus{Code: statusCodeOK},
+		}
+		for k, v := range s.attrs {
+			o.Attributes = append(o.Attributes, stringAttr(k, v))
+		}
+		if s.err != "" {
+			o.Status = otlpStatus{Code: statusCodeError, Message: s.err}
+		}
+		out = append(out, o)
+	}
+	return map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{
+				"attributes": []otlpAttribute{stringAttr("service.name", e.service)},
+			},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]string{"name": "github.com/surge-downloader/surge"},
+				"spans": out,
+			}},
+		}},
+	}
+}
@@ -0,0 +1,70 @@
+package trace
+
+import (
+	"context"
+	"time"
+)
+
+// Span times one unit of work. Spans cost nothing beyond the struct unless an
+// exporter is configured.
+type Span struct {
+	traceID  string
+	spanID   string
+	parentID string
+	name     string
+	start    time.Time
+	end      time.Time
+	attrs    map[string]string
+	err      string
+}
+
+// Start begins a span named name. It joins the trace in ctx, or starts a new
+// one, and returns a context whose trace ID and parent are the new span's.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	parent, _ := ctx.Value(contextKey{}).(spanContext)
+	s := &Span{
+		traceID:  parent.traceID,
+		spanID:   newSpanID(),
+		parentID: parent.spanID,
+		name:     name,
+		start:    time.Now(),
+	}
+	if s.traceID == "" {
+		s.traceID = NewID()
+	}
+	return context.WithValue(ctx, contextKey{}, spanContext{traceID: s.traceID, spanID: s.spanID}), s
+}
+
+// WithParent returns a context continuing a remote trace, as received in a
+// traceparent header
+func WithParent(ctx context.Context, traceID, spanID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, spanContext{traceID: traceID, spanID: spanID})
+}
+
+// TraceID returns the span's trace ID
+func (s *Span) TraceID() string {
+	return s.traceID
+}
+
+// SetAttr records a string attribute on the span
+func (s *Span) SetAttr(key, value string) {
+	if s.attrs == nil {
+		s.attrs = make(map[string]string)
+	}
+	s.attrs[key] = value
+}
+
+// End finishes the span, marking it failed when err is non-nil, and hands it
+// to the exporter
+func (s *Span) End(err error) {
+	s.end = time.Now()
+	if err != nil {
+		s.err = err.Error()
+	}
+	if e := currentExporter(); e != nil {
+		e.enqueue(s)
+	}
+}
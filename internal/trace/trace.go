@@ -0,0 +1,102 @@
+// Package trace ties log lines, events and API responses for one request or
+// one download together under a trace ID.
+//
+// IDs follow W3C Trace Context (32 hex digit trace IDs, 16 hex digit span
+// IDs), so an incoming traceparent header is honored and spans can be
+// exported to an OpenTelemetry collector (see ConfigureFromEnv).
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// Header carries the trace ID on every API response
+const Header = "X-Trace-Id"
+
+type contextKey struct{}
+
+type spanContext struct {
+	traceID string
+	spanID  string
+}
+
+// NewID returns a random trace ID
+func NewID() string {
+	return randomHex(16)
+}
+
+func newSpanID() string {
+	return randomHex(8)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand does not fail on supported platforms
+		panic(fmt.Sprintf("trace: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// WithID returns a context carrying traceID
+func WithID(ctx context.Context, traceID string) context.Context {
+	if traceID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, contextKey{}, spanContext{traceID: traceID})
+}
+
+// FromContext returns the trace ID carried by ctx, or ""
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	sc, _ := ctx.Value(contextKey{}).(spanContext)
+	return sc.traceID
+}
+
+// ParseTraceparent extracts the trace and parent span IDs from a W3C
+// traceparent header value ("00-<trace-id>-<span-id>-<flags>")
+func ParseTraceparent(v string) (traceID, spanID string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return "", "", false
+	}
+	traceID, spanID = strings.ToLower(parts[1]), strings.ToLower(parts[2])
+	if !validHex(traceID, 32) || !validHex(spanID, 16) {
+		return "", "", false
+	}
+	return traceID, spanID, true
+}
+
+// ValidID reports whether id is a well-formed, non-zero trace ID
+func ValidID(id string) bool {
+	return validHex(strings.ToLower(id), 32)
+}
+
+func validHex(s string, n int) bool {
+	if len(s) != n || strings.Trim(s, "0") == "" {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// Debug is utils.Debug with the context's trace ID prefixed, so one grep of
+// the debug log follows a request or download across layers
+func Debug(ctx context.Context, format string, args ...any) {
+	if !utils.IsVerbose() {
+		return
+	}
+	if id := FromContext(ctx); id != "" {
+		utils.Debug("[trace %s] "+format, append([]any{id}, args...)...)
+		return
+	}
+	utils.Debug(format, args...)
+}
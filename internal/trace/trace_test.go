@@ -0,0 +1,140 @@
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewID(t *testing.T) {
+	a, b := NewID(), NewID()
+	if !ValidID(a) || !ValidID(b) {
+		t.Fatalf("invalid ids %q %q", a, b)
+	}
+	if a == b {
+		t.Fatal("ids should be random")
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		in     string
+		trace  string
+		span   string
+		wantOK bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", true},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00F067AA0BA902B7-00", "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", true},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", "", "", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", "", "", false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "", "", false},
+		{"00-xyz-00f067aa0ba902b7-01", "", "", false},
+		{"", "", "", false},
+	}
+	for _, tt := range tests {
+		traceID, spanID, ok := ParseTraceparent(tt.in)
+		if ok != tt.wantOK || traceID != tt.trace || spanID != tt.span {
+			t.Errorf("ParseTraceparent(%q) = %q, %q, %v", tt.in, traceID, spanID, ok)
+		}
+	}
+}
+
+func TestStart_JoinsTrace(t *testing.T) {
+	if got := FromContext(context.Background()); got != "" {
+		t.Fatalf("empty context carried %q", got)
+	}
+
+	ctx := WithID(context.Background(), "4bf92f3577b34da6a3ce929d0e0e4736")
+	ctx, parent := Start(ctx, "parent")
+	_, child := Start(ctx, "child")
+
+	if parent.TraceID() != "4bf92f3577b34da6a3ce929d0e0e4736" || child.TraceID() != parent.TraceID() {
+		t.Fatalf("trace ids = %q, %q", parent.TraceID(), child.TraceID())
+	}
+	if child.parentID != parent.spanID {
+		t.Fatalf("child parent = %q, want %q", child.parentID, parent.spanID)
+	}
+	if FromContext(ctx) != parent.TraceID() {
+		t.Fatalf("FromContext = %q", FromContext(ctx))
+	}
+
+	_, root := Start(context.Background(), "root")
+	if !ValidID(root.TraceID()) || root.parentID != "" {
+		t.Fatalf("root span = %+v", root)
+	}
+}
+
+func TestExport_PostsOTLPJSON(t *testing.T) {
+	got := make(chan map[string]any, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("X-Api-Key") != "secret" {
+			t.Errorf("request %s with key %q", r.URL.Path, r.Header.Get("X-Api-Key"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		var payload map[string]any
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("bad payload: %v", err)
+		}
+		got <- payload
+	}))
+	defer srv.Close()
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", srv.URL)
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "X-Api-Key=secret")
+	t.Setenv("OTEL_SERVICE_NAME", "surge-test")
+	if !ConfigureFromEnv() {
+		t.Fatal("export not enabled")
+	}
+
+	_, span := Start(context.Background(), "download")
+	span.SetAttr("download.id", "abc")
+	span.End(errors.New("boom"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	Shutdown(ctx)
+
+	var payload map[string]any
+	select {
+	case payload = <-got:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no spans exported")
+	}
+
+	rs := payload["resourceSpans"].([]any)[0].(map[string]any)
+	attrs := rs["resource"].(map[string]any)["attributes"].([]any)
+	service := attrs[0].(map[string]any)["value"].(map[string]any)["stringValue"]
+	if service != "surge-test" {
+		t.Fatalf("service.name = %v", service)
+	}
+	spans := rs["scopeSpans"].([]any)[0].(map[string]any)["spans"].([]any)
+	if len(spans) != 1 {
+		t.Fatalf("exported %d spans, want 1", len(spans))
+	}
+	s := spans[0].(map[string]any)
+	if s["traceId"] != span.TraceID() || s["name"] != "download" {
+		t.Fatalf("span = %v", s)
+	}
+	if status := s["status"].(map[string]any); status["code"] != float64(statusCodeError) || status["message"] != "boom" {
+		t.Fatalf("status = %v", status)
+	}
+}
+
+func TestConfigureFromEnv_Disabled(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	if ConfigureFromEnv() {
+		t.Fatal("export enabled without an endpoint")
+	}
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318")
+	t.Setenv("OTEL_TRACES_EXPORTER", "none")
+	if ConfigureFromEnv() {
+		t.Fatal("export enabled despite OTEL_TRACES_EXPORTER=none")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
 // AddDownloadModal renders input-driven download forms (add download / extension prompt).
@@ -22,6 +23,10 @@ type AddDownloadModal struct {
 	BorderColor     lipgloss.TerminalColor
 	Width           int
 	Height          int
+
+	// Suggestions are rendered under the first input (URL history matches)
+	Suggestions        []string
+	SelectedSuggestion int
 }
 
 // View renders the inner content (without border box).
@@ -46,13 +51,37 @@ func (m AddDownloadModal) View() string {
 			}
 			row = lipgloss.JoinHorizontal(lipgloss.Left, row, hintStyle.Render("[Tab] Browse"))
 		}
-		content = append(content, row, "")
+		content = append(content, row)
+		if i == 0 && len(m.Suggestions) > 0 {
+			content = append(content, m.renderSuggestions(labelStyle.GetWidth())...)
+		}
+		content = append(content, "")
 	}
 
 	content = append(content, m.Help.View(m.HelpKeys))
 	return lipgloss.NewStyle().Padding(0, 2).Render(lipgloss.JoinVertical(lipgloss.Left, content...))
 }
 
+// renderSuggestions renders the suggestion list aligned with the input column.
+func (m AddDownloadModal) renderSuggestions(indent int) []string {
+	maxWidth := m.Width - indent - 8
+	itemStyle := lipgloss.NewStyle().MarginLeft(indent).Foreground(colors.LightGray)
+	selectedStyle := lipgloss.NewStyle().MarginLeft(indent).Foreground(colors.NeonPink).Bold(true)
+
+	lines := make([]string, 0, len(m.Suggestions))
+	for i, s := range m.Suggestions {
+		if maxWidth > 3 {
+			s = ansi.Truncate(s, maxWidth, "...")
+		}
+		if i == m.SelectedSuggestion {
+			lines = append(lines, selectedStyle.Render("› "+s))
+		} else {
+			lines = append(lines, itemStyle.Render("  "+s))
+		}
+	}
+	return lines
+}
+
 // RenderWithBtopBox renders the modal with btop-style border.
 func (m AddDownloadModal) RenderWithBtopBox(
 	renderBox func(leftTitle, rightTitle, content string, width, height int, borderColor lipgloss.TerminalColor) string,
@@ -0,0 +1,83 @@
+package tui
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/tui/colors"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// errorHistoryMsg carries the error history of the download it was asked for
+type errorHistoryMsg struct {
+	filename string
+	failures []types.DownloadFailure
+	err      error
+}
+
+// fetchErrorHistoryCmd loads a download's error history for the popup
+func fetchErrorHistoryCmd(service core.DownloadService, id, filename string) tea.Cmd {
+	return func() tea.Msg {
+		status, err := service.GetStatus(id)
+		if err != nil {
+			return errorHistoryMsg{filename: filename, err: err}
+		}
+		return errorHistoryMsg{filename: filename, failures: status.Errors}
+	}
+}
+
+// errorHistoryLine describes one failure in the popup, newest at the bottom
+func errorHistoryLine(f types.DownloadFailure, width int) string {
+	when := time.Unix(f.At, 0).Format("01-02 15:04:05")
+	what := f.Kind
+	if f.Status != 0 {
+		what = fmt.Sprintf("%s %d", f.Kind, f.Status)
+	}
+	where := ""
+	if f.Kind != types.FailureFatal {
+		where = "@" + utils.ConvertBytesToHumanReadable(f.Offset) + " "
+	}
+	line := fmt.Sprintf("%s  %-11s %s%s", when, what, where, f.Message)
+	if u, err := url.Parse(f.Mirror); err == nil && u.Host != "" {
+		line += " (" + u.Host + ")"
+	}
+	return truncateString(line, width)
+}
+
+// viewErrorHistory renders the error history popup for the selected download
+func (m RootModel) viewErrorHistory() string {
+	width := min(110, m.width-4)
+	height := min(max(len(m.errorHistory), 1)+6, m.height-4)
+	innerWidth := width - 6
+	rows := height - 6
+
+	var lines []string
+	if len(m.errorHistory) == 0 {
+		lines = append(lines, lipgloss.NewStyle().Foreground(colors.LightGray).Render("No errors recorded"))
+	}
+	shown := m.errorHistory[max(0, len(m.errorHistory)-rows):]
+	if hidden := len(m.errorHistory) - len(shown); hidden > 0 {
+		lines = append(lines, lipgloss.NewStyle().Foreground(colors.LightGray).Render(fmt.Sprintf("… %d earlier", hidden)))
+		shown = shown[1:]
+	}
+	for _, f := range shown {
+		style := lipgloss.NewStyle().Foreground(colors.LightGray)
+		if f.Kind == types.FailureFatal {
+			style = style.Foreground(colors.NeonPink)
+		}
+		lines = append(lines, style.Render(errorHistoryLine(f, innerWidth)))
+	}
+	lines = append(lines, "", lipgloss.NewStyle().Foreground(colors.Gray).Render("esc close"))
+
+	content := lipgloss.NewStyle().Padding(1, 2).Render(strings.Join(lines, "\n"))
+	title := PaneTitleStyle.Render(" Errors: " + truncateString(m.errorHistoryName, 40) + " ")
+	box := renderBtopBox(title, "", content, width, height, colors.NeonPink)
+	return m.renderModalWithOverlay(box)
+}
@@ -29,10 +29,14 @@ type DashboardKeyMap struct {
 	Pause          key.Binding
 	Refresh        key.Binding
 	Delete         key.Binding
+	Archive        key.Binding
+	Undelete       key.Binding
+	Errors         key.Binding
 	Settings       key.Binding
 	Log            key.Binding
 	History        key.Binding
 	OpenFile       key.Binding
+	OpenFolder     key.Binding
 	Quit           key.Binding
 	ForceQuit      key.Binding
 	CategoryFilter key.Binding
@@ -55,6 +59,9 @@ type InputKeyMap struct {
 	Up     key.Binding
 	Down   key.Binding
 	Cancel key.Binding
+	// URL history suggestions
+	HistoryNext key.Binding
+	HistoryPrev key.Binding
 }
 
 // FilePickerKeyMap defines keybindings for the file picker
@@ -181,6 +188,18 @@ var Keys = KeyMap{
 			key.WithKeys("x"),
 			key.WithHelp("x", "delete"),
 		),
+		Archive: key.NewBinding(
+			key.WithKeys("X"),
+			key.WithHelp("X", "delete, keep data"),
+		),
+		Undelete: key.NewBinding(
+			key.WithKeys("u"),
+			key.WithHelp("u", "undo delete"),
+		),
+		Errors: key.NewBinding(
+			key.WithKeys("E"),
+			key.WithHelp("E", "error history"),
+		),
 		Settings: key.NewBinding(
 			key.WithKeys("s"),
 			key.WithHelp("s", "settings"),
@@ -197,6 +216,10 @@ var Keys = KeyMap{
 			key.WithKeys("o"),
 			key.WithHelp("o", "open file"),
 		),
+		OpenFolder: key.NewBinding(
+			key.WithKeys("O"),
+			key.WithHelp("O", "open folder"),
+		),
 		Quit: key.NewBinding(
 			key.WithKeys("ctrl+c", "ctrl+q"),
 			key.WithHelp("ctrl+q", "quit"),
@@ -263,6 +286,14 @@ var Keys = KeyMap{
 			key.WithKeys("esc"),
 			key.WithHelp("esc", "cancel"),
 		),
+		HistoryNext: key.NewBinding(
+			key.WithKeys("ctrl+n"),
+			key.WithHelp("ctrl+n/p", "history"),
+		),
+		HistoryPrev: key.NewBinding(
+			key.WithKeys("ctrl+p"),
+			key.WithHelp("ctrl+p", "prev history"),
+		),
 	},
 	FilePicker: FilePickerKeyMap{
 		UseDir: key.NewBinding(
@@ -449,17 +480,17 @@ func (k DashboardKeyMap) ShortHelp() []key.Binding {
 func (k DashboardKeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{k.TabQueued, k.TabActive, k.TabDone, k.NextTab},
-		{k.Add, k.Search, k.CategoryFilter, k.Pause, k.Refresh, k.Delete, k.Settings},
-		{k.Log, k.History, k.Quit},
+		{k.Add, k.Search, k.CategoryFilter, k.Pause, k.Refresh, k.Delete, k.Archive, k.Undelete, k.Settings},
+		{k.OpenFile, k.OpenFolder, k.Log, k.History, k.Errors, k.Quit},
 	}
 }
 
 func (k InputKeyMap) ShortHelp() []key.Binding {
-	return []key.Binding{k.Tab, k.Enter, k.Esc}
+	return []key.Binding{k.Tab, k.Enter, k.HistoryNext, k.Esc}
 }
 
 func (k InputKeyMap) FullHelp() [][]key.Binding {
-	return [][]key.Binding{{k.Tab, k.Enter, k.Esc}}
+	return [][]key.Binding{{k.Tab, k.Enter, k.HistoryNext, k.HistoryPrev, k.Esc}}
 }
 
 func (k FilePickerKeyMap) ShortHelp() []key.Binding {
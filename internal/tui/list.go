@@ -36,6 +36,10 @@ func (i DownloadItem) Description() string {
 		styledStatus = lipgloss.NewStyle().Foreground(colors.StatePaused).Render("⏸ Pausing...")
 	} else if d.resuming {
 		styledStatus = lipgloss.NewStyle().Foreground(colors.StateDownloading).Render("▶ Resuming...")
+	} else if label := phaseLabel(d); label != "" {
+		styledStatus = lipgloss.NewStyle().Foreground(colors.StateDownloading).Render(label)
+	} else if label := pauseLabel(d); label != "" {
+		styledStatus = lipgloss.NewStyle().Foreground(colors.StatePaused).Render(label)
 	} else {
 		styledStatus = components.DetermineStatus(d.done, d.paused, d.err != nil, d.Speed, d.Downloaded).Render()
 	}
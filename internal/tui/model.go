@@ -18,9 +18,11 @@ import (
 
 	"github.com/surge-downloader/surge/internal/config"
 	"github.com/surge-downloader/surge/internal/core"
+	"github.com/surge-downloader/surge/internal/engine/state"
 	"github.com/surge-downloader/surge/internal/engine/types"
 	"github.com/surge-downloader/surge/internal/processing"
 	"github.com/surge-downloader/surge/internal/tui/colors"
+	"github.com/surge-downloader/surge/internal/utils"
 	"github.com/surge-downloader/surge/internal/version"
 )
 
@@ -41,6 +43,8 @@ const (
 	UpdateAvailableState                      // UpdateAvailableState is 11
 	URLUpdateState                            // URLUpdateState is 12
 	CategoryManagerState                      // CategoryManagerState is 13
+	OrphanCleanupState                        // OrphanCleanupState is 14
+	ErrorHistoryState                         // ErrorHistoryState is 15
 )
 
 const (
@@ -59,6 +63,10 @@ type DownloadModel struct {
 	Downloaded    int64
 	Speed         float64
 	Connections   int
+	Tags          []string
+	Note          string
+	Metadata      map[string]string
+	Response      *types.CachedProbe // What the server answered when probed, if known
 
 	StartTime time.Time
 	Elapsed   time.Duration
@@ -75,6 +83,11 @@ type DownloadModel struct {
 	paused   bool
 	pausing  bool // UI state: transitioning to pause
 	resuming bool // UI state: waiting for async resume
+
+	pauseReason string // Why it is paused (types.Pause*), "" if unknown
+
+	phase         string  // Post-download phase still running (types.Phase*), if any
+	phaseProgress float64 // Percentage 0-100 within phase
 }
 
 type RootModel struct {
@@ -85,6 +98,11 @@ type RootModel struct {
 	activeTab    int // 0=Queued, 1=Active, 2=Done
 	inputs       []textinput.Model
 	focusedInput int
+
+	// Quick-add URL history (fuzzy suggestions under the URL input)
+	urlHistory          []types.URLHistoryEntry
+	urlSuggestions      []string
+	urlSuggestionCursor int
 	// Service Interface
 	// Core
 	Service      core.DownloadService
@@ -112,6 +130,8 @@ type RootModel struct {
 	pendingFilename      string   // Filename pending confirmation
 	pendingMirrors       []string // Mirrors pending confirmation
 	pendingHeaders       map[string]string
+	pendingTags          []string
+	pendingCategory      string
 	duplicateInfo        string // Info about the duplicate
 
 	// Graph Data
@@ -129,6 +149,7 @@ type RootModel struct {
 	SettingsSelectedRow   int              // Selected setting within current tab
 	SettingsIsEditing     bool             // Whether currently editing a value
 	SettingsInput         textinput.Model  // Input for editing string/int values
+	SettingsInputError    string           // Validation error for the value being edited
 	SettingsFileBrowsing  bool             // Whether browsing for a directory
 	ExtensionFileBrowsing bool             // Whether browsing for extension prompt path
 
@@ -148,6 +169,13 @@ type RootModel struct {
 	// URL Refresh
 	urlUpdateInput textinput.Model // Text input for updating URL
 
+	// Orphan cleanup prompt
+	orphans *state.Orphans // Found at startup, waiting for the user's answer
+
+	// Error history popup
+	errorHistory     []types.DownloadFailure // Failures of the download shown, oldest first
+	errorHistoryName string                  // Filename of the download shown
+
 	// Category manager
 	categoryFilter     string             // Dashboard filter ("" = all)
 	catMgrCursor       int                // Selected category index
@@ -257,6 +285,10 @@ func InitialRootModel(serverPort int, currentVersion string, service core.Downlo
 			for _, s := range statuses {
 				dm := NewDownloadModel(s.ID, s.URL, s.Filename, s.TotalSize)
 				dm.Downloaded = s.Downloaded
+				dm.Tags = s.Tags
+				dm.Note = s.Note
+				dm.Metadata = s.Metadata
+				dm.Response = s.Response
 				if s.DestPath != "" {
 					dm.Destination = s.DestPath
 				} else {
@@ -272,7 +304,8 @@ func InitialRootModel(serverPort int, currentVersion string, service core.Downlo
 				case "pausing":
 					dm.pausing = true
 				case "paused":
-					if settings.General.AutoResume {
+					dm.pauseReason = s.PauseReason
+					if settings.General.AutoResume && types.AutoResumable(s.PauseReason) {
 						dm.resuming = true
 						dm.paused = true // Will update when resume event received
 					} else {
@@ -316,7 +349,7 @@ func InitialRootModel(serverPort int, currentVersion string, service core.Downlo
 
 	// Initialize search input
 	searchInput := textinput.New()
-	searchInput.Placeholder = "Type to search..."
+	searchInput.Placeholder = "Type to search (#tag filters by tag)..."
 	searchInput.Width = 30
 	searchInput.Prompt = ""
 
@@ -417,6 +450,11 @@ func (m RootModel) Init() tea.Cmd {
 		cmds = append(cmds, checkForUpdateCmd(m.CurrentVersion))
 	}
 
+	// Orphans can only be inspected where the files are
+	if m.Orchestrator != nil {
+		cmds = append(cmds, findOrphansCmd(m.Settings))
+	}
+
 	// Async resume of downloads
 	var resumeIDs []string
 	for _, d := range m.downloads {
@@ -465,7 +503,7 @@ func (m *RootModel) FindDownloadByID(id string) *DownloadModel {
 // Helper to get downloads for the current tab
 func (m RootModel) getFilteredDownloads() []*DownloadModel {
 	var filtered []*DownloadModel
-	searchLower := strings.ToLower(m.searchQuery)
+	searchLower, searchTags := parseSearchQuery(m.searchQuery)
 
 	for _, d := range m.downloads {
 		// Apply tab filter first
@@ -492,10 +530,11 @@ func (m RootModel) getFilteredDownloads() []*DownloadModel {
 		}
 
 		// Apply search filter if query is set
-		if m.searchQuery != "" {
-			if !strings.Contains(d.FilenameLower, searchLower) {
-				continue
-			}
+		if searchLower != "" && !strings.Contains(d.FilenameLower, searchLower) {
+			continue
+		}
+		if !hasAllTags(d.Tags, searchTags) {
+			continue
 		}
 
 		filtered = append(filtered, d)
@@ -503,6 +542,31 @@ func (m RootModel) getFilteredDownloads() []*DownloadModel {
 	return filtered
 }
 
+// parseSearchQuery splits a search query into lowercase filename text and
+// "#tag" filters, e.g. "ubuntu #iso" matches ubuntu files tagged iso
+func parseSearchQuery(query string) (text string, tags []string) {
+	var words []string
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		if tag, ok := strings.CutPrefix(word, "#"); ok {
+			if tag != "" {
+				tags = append(tags, tag)
+			}
+			continue
+		}
+		words = append(words, word)
+	}
+	return strings.Join(words, " "), tags
+}
+
+func hasAllTags(have, want []string) bool {
+	for _, tag := range want {
+		if !utils.HasTag(have, tag) {
+			return false
+		}
+	}
+	return true
+}
+
 func (m RootModel) matchesCategoryFilter(d *DownloadModel) bool {
 	filter := m.categoryFilter
 	if filter == "" {
@@ -0,0 +1,60 @@
+package tui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/processing"
+)
+
+type orphansFoundMsg struct {
+	orphans *state.Orphans
+	err     error
+}
+
+type orphansRemovedMsg struct {
+	files      int
+	removedIDs []string // Downloads whose rows were removed
+	err        error
+}
+
+// findOrphansCmd scans for orphaned downloads in the background
+func findOrphansCmd(settings *config.Settings) tea.Cmd {
+	return func() tea.Msg {
+		orphans, err := processing.FindOrphans(settings)
+		return orphansFoundMsg{orphans: orphans, err: err}
+	}
+}
+
+// removeOrphansCmd removes what the user agreed to clean up
+func removeOrphansCmd(orphans *state.Orphans) tea.Cmd {
+	return func() tea.Msg {
+		files, _, err := state.RemoveOrphans(orphans)
+		var removed []string
+		for _, e := range orphans.Entries {
+			if entry, getErr := state.GetDownload(e.ID); getErr == nil && entry == nil {
+				removed = append(removed, e.ID)
+			}
+		}
+		return orphansRemovedMsg{files: files, removedIDs: removed, err: err}
+	}
+}
+
+// orphansSummary describes what was found, for the prompt and the log
+func orphansSummary(o *state.Orphans) string {
+	return fmt.Sprintf("%d orphaned files and %d downloads with missing files", len(o.Files), len(o.Entries))
+}
+
+// orphansDetail names one of the orphans so the prompt shows what it is about
+func orphansDetail(o *state.Orphans) string {
+	if len(o.Files) > 0 {
+		return truncateString(o.Files[0], 50)
+	}
+	if len(o.Entries) > 0 {
+		return truncateString(o.Entries[0].DestPath, 50)
+	}
+	return ""
+}
@@ -179,13 +179,21 @@ func (m RootModel) viewSettings() string {
 			Width(rightWidth - 4).
 			Render(meta.Description)
 
-		rightContent = lipgloss.JoinVertical(lipgloss.Left,
-			valueDisplay,
-			"",
-			divider,
-			"",
-			descDisplay,
-		)
+		rightLines := []string{valueDisplay}
+		if m.SettingsIsEditing && m.SettingsInputError != "" {
+			rightLines = append(rightLines, lipgloss.NewStyle().
+				Foreground(colors.StateError).
+				Render("✖ "+m.SettingsInputError))
+		}
+		rightLines = append(rightLines, "", divider, "", descDisplay)
+		if hints := settingHints(meta); hints != "" {
+			rightLines = append(rightLines, "", lipgloss.NewStyle().
+				Foreground(colors.LightGray).
+				Width(rightWidth-4).
+				Render(hints))
+		}
+
+		rightContent = lipgloss.JoinVertical(lipgloss.Left, rightLines...)
 	}
 
 	rightBox := lipgloss.NewStyle().
@@ -251,6 +259,7 @@ func (m RootModel) getSettingsValues(category string) map[string]interface{} {
 	case "General":
 		values["default_download_dir"] = m.Settings.General.DefaultDownloadDir
 		values["warn_on_duplicate"] = m.Settings.General.WarnOnDuplicate
+		values["download_archive"] = m.Settings.General.DownloadArchive
 		values["extension_prompt"] = m.Settings.General.ExtensionPrompt
 		values["auto_resume"] = m.Settings.General.AutoResume
 		values["skip_update_check"] = m.Settings.General.SkipUpdateCheck
@@ -258,6 +267,10 @@ func (m RootModel) getSettingsValues(category string) map[string]interface{} {
 		values["clipboard_monitor"] = m.Settings.General.ClipboardMonitor
 		values["theme"] = m.Settings.General.Theme
 		values["log_retention_count"] = m.Settings.General.LogRetentionCount
+		values["reverify_after_days"] = m.Settings.General.ReverifyAfterDays
+		values["trash_retention_days"] = m.Settings.General.TrashRetentionDays
+		values["state_store"] = m.Settings.General.StateStore
+		values["encrypt_state"] = m.Settings.General.EncryptState
 
 	case "Network":
 		values["max_connections_per_host"] = m.Settings.Network.MaxConnectionsPerHost
@@ -267,12 +280,19 @@ func (m RootModel) getSettingsValues(category string) map[string]interface{} {
 		values["sequential_download"] = m.Settings.Network.SequentialDownload
 		values["min_chunk_size"] = m.Settings.Network.MinChunkSize
 		values["worker_buffer_size"] = m.Settings.Network.WorkerBufferSize
+		values["global_rate_limit"] = m.Settings.Network.GlobalRateLimit
+		values["keep_compressed_responses"] = m.Settings.Network.KeepCompressed
+		values["prewarm_connections"] = m.Settings.Network.PrewarmConnections
+		values["auto_calibrate"] = m.Settings.Network.AutoCalibrate
 	case "Performance":
 		values["max_task_retries"] = m.Settings.Performance.MaxTaskRetries
 		values["slow_worker_threshold"] = m.Settings.Performance.SlowWorkerThreshold
 		values["slow_worker_grace_period"] = m.Settings.Performance.SlowWorkerGracePeriod
 		values["stall_timeout"] = m.Settings.Performance.StallTimeout
 		values["speed_ema_alpha"] = m.Settings.Performance.SpeedEmaAlpha
+		values["max_buffer_memory"] = m.Settings.Performance.MaxBufferMemory
+		values["dense_preallocation"] = m.Settings.Performance.DensePreallocation
+		values["db_busy_timeout"] = m.Settings.Performance.DBBusyTimeout
 	case "Categories":
 		values["category_enabled"] = m.Settings.General.CategoryEnabled
 	}
@@ -330,6 +350,8 @@ func (m *RootModel) setGeneralSetting(key, value, typ string) error {
 		m.Settings.General.DefaultDownloadDir = value
 	case "warn_on_duplicate":
 		m.Settings.General.WarnOnDuplicate = !m.Settings.General.WarnOnDuplicate
+	case "download_archive":
+		m.Settings.General.DownloadArchive = !m.Settings.General.DownloadArchive
 	case "extension_prompt":
 		m.Settings.General.ExtensionPrompt = !m.Settings.General.ExtensionPrompt
 	case "auto_resume":
@@ -370,6 +392,24 @@ func (m *RootModel) setGeneralSetting(key, value, typ string) error {
 			}
 			m.Settings.General.LogRetentionCount = v
 		}
+	case "reverify_after_days":
+		if v, err := strconv.Atoi(value); err == nil {
+			if v < 0 {
+				v = 0
+			}
+			m.Settings.General.ReverifyAfterDays = v
+		}
+	case "trash_retention_days":
+		if v, err := strconv.Atoi(value); err == nil {
+			if v < 0 {
+				v = 0
+			}
+			m.Settings.General.TrashRetentionDays = v
+		}
+	case "state_store":
+		m.Settings.General.StateStore = strings.TrimSpace(value)
+	case "encrypt_state":
+		m.Settings.General.EncryptState = !m.Settings.General.EncryptState
 	}
 	return nil
 }
@@ -411,6 +451,35 @@ func (m *RootModel) setNetworkSetting(key, value, typ string) error {
 		if v, err := strconv.ParseFloat(value, 64); err == nil {
 			m.Settings.Network.WorkerBufferSize = int(v * float64(config.KB))
 		}
+	case "global_rate_limit":
+		// Entered in KB/s, stored in bytes/sec
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			if v < 0 {
+				v = 0
+			}
+			m.Settings.Network.GlobalRateLimit = int64(v * float64(config.KB))
+		}
+	case "keep_compressed_responses":
+		if value == "" {
+			m.Settings.Network.KeepCompressed = !m.Settings.Network.KeepCompressed
+		} else {
+			b, _ := strconv.ParseBool(value)
+			m.Settings.Network.KeepCompressed = b
+		}
+	case "prewarm_connections":
+		if value == "" {
+			m.Settings.Network.PrewarmConnections = !m.Settings.Network.PrewarmConnections
+		} else {
+			b, _ := strconv.ParseBool(value)
+			m.Settings.Network.PrewarmConnections = b
+		}
+	case "auto_calibrate":
+		if value == "" {
+			m.Settings.Network.AutoCalibrate = !m.Settings.Network.AutoCalibrate
+		} else {
+			b, _ := strconv.ParseBool(value)
+			m.Settings.Network.AutoCalibrate = b
+		}
 	}
 	return nil
 }
@@ -447,6 +516,13 @@ func (m *RootModel) setPerformanceSetting(key, value, typ string) error {
 		if v, err := time.ParseDuration(value); err == nil {
 			m.Settings.Performance.StallTimeout = v
 		}
+	case "db_busy_timeout":
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			value += "s"
+		}
+		if v, err := time.ParseDuration(value); err == nil && v > 0 {
+			m.Settings.Performance.DBBusyTimeout = v
+		}
 	case "speed_ema_alpha":
 		if v, err := strconv.ParseFloat(value, 64); err == nil {
 			// Clamp to valid range 0.0-1.0
@@ -457,6 +533,21 @@ func (m *RootModel) setPerformanceSetting(key, value, typ string) error {
 			}
 			m.Settings.Performance.SpeedEmaAlpha = v
 		}
+	case "max_buffer_memory":
+		// Entered in MB, stored in bytes
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			if v < 0 {
+				v = 0
+			}
+			m.Settings.Performance.MaxBufferMemory = int64(v * float64(config.MB))
+		}
+	case "dense_preallocation":
+		if value == "" {
+			m.Settings.Performance.DensePreallocation = !m.Settings.Performance.DensePreallocation
+		} else {
+			b, _ := strconv.ParseBool(value)
+			m.Settings.Performance.DensePreallocation = b
+		}
 	}
 	return nil
 }
@@ -511,21 +602,29 @@ func (m RootModel) getSettingsCount() int {
 
 // getSettingUnit returns the unit suffix for the currently selected setting
 func (m RootModel) getSettingUnit() string {
-	key := m.getCurrentSettingKey()
-	switch key {
-	case "min_chunk_size":
-		return " MB"
-	case "worker_buffer_size":
-		return " KB"
-	case "max_task_retries":
-		return " retries"
-	case "slow_worker_grace_period", "stall_timeout":
-		return " seconds"
-	case "slow_worker_threshold", "speed_ema_alpha":
-		return " (0.0-1.0)"
-	default:
+	meta := m.getCurrentSettingMeta()
+	if meta == nil || meta.Unit == "" {
 		return ""
 	}
+	return " " + meta.Unit
+}
+
+// settingHints renders the unit, range and example lines for meta
+func settingHints(meta config.SettingMeta) string {
+	var hints []string
+	if meta.Unit != "" {
+		hints = append(hints, "Unit: "+meta.Unit)
+	}
+	if meta.Range != nil {
+		hints = append(hints, "Range: "+meta.Range.String())
+	}
+	if len(meta.Choices) > 0 {
+		hints = append(hints, "Choices: "+strings.Join(meta.Choices, ", "))
+	}
+	if meta.Example != "" {
+		hints = append(hints, "Example: "+meta.Example)
+	}
+	return strings.Join(hints, "\n")
 }
 
 // formatSettingValueForEdit returns a plain value without units for editing
@@ -542,7 +641,17 @@ func formatSettingValueForEdit(value interface{}, typ, key string) string {
 			kb := float64(v.Int()) / float64(config.KB)
 			return fmt.Sprintf("%.0f", kb)
 		}
-	case "slow_worker_grace_period", "stall_timeout":
+	case "global_rate_limit":
+		if v, ok := value.(int64); ok {
+			kb := float64(v) / float64(config.KB)
+			return fmt.Sprintf("%.0f", kb)
+		}
+	case "max_buffer_memory":
+		if v, ok := value.(int64); ok {
+			mb := float64(v) / float64(config.MB)
+			return fmt.Sprintf("%.0f", mb)
+		}
+	case "slow_worker_grace_period", "stall_timeout", "db_busy_timeout":
 		// Show duration as plain seconds number (e.g., "5" instead of "5s")
 		if d, ok := value.(time.Duration); ok {
 			return fmt.Sprintf("%.0f", d.Seconds())
@@ -631,6 +740,8 @@ func (m *RootModel) resetSettingToDefault(category, key string, defaults *config
 			m.Settings.General.DefaultDownloadDir = defaults.General.DefaultDownloadDir
 		case "warn_on_duplicate":
 			m.Settings.General.WarnOnDuplicate = defaults.General.WarnOnDuplicate
+		case "download_archive":
+			m.Settings.General.DownloadArchive = defaults.General.DownloadArchive
 		case "extension_prompt":
 			m.Settings.General.ExtensionPrompt = defaults.General.ExtensionPrompt
 		case "auto_resume":
@@ -644,6 +755,14 @@ func (m *RootModel) resetSettingToDefault(category, key string, defaults *config
 			m.Settings.General.Theme = defaults.General.Theme
 		case "log_retention_count":
 			m.Settings.General.LogRetentionCount = defaults.General.LogRetentionCount
+		case "reverify_after_days":
+			m.Settings.General.ReverifyAfterDays = defaults.General.ReverifyAfterDays
+		case "trash_retention_days":
+			m.Settings.General.TrashRetentionDays = defaults.General.TrashRetentionDays
+		case "state_store":
+			m.Settings.General.StateStore = defaults.General.StateStore
+		case "encrypt_state":
+			m.Settings.General.EncryptState = defaults.General.EncryptState
 		}
 
 	case "Network":
@@ -662,6 +781,14 @@ func (m *RootModel) resetSettingToDefault(category, key string, defaults *config
 			m.Settings.Network.MinChunkSize = defaults.Network.MinChunkSize
 		case "worker_buffer_size":
 			m.Settings.Network.WorkerBufferSize = defaults.Network.WorkerBufferSize
+		case "global_rate_limit":
+			m.Settings.Network.GlobalRateLimit = defaults.Network.GlobalRateLimit
+		case "keep_compressed_responses":
+			m.Settings.Network.KeepCompressed = defaults.Network.KeepCompressed
+		case "prewarm_connections":
+			m.Settings.Network.PrewarmConnections = defaults.Network.PrewarmConnections
+		case "auto_calibrate":
+			m.Settings.Network.AutoCalibrate = defaults.Network.AutoCalibrate
 		}
 	case "Performance":
 		switch key {
@@ -675,6 +802,12 @@ func (m *RootModel) resetSettingToDefault(category, key string, defaults *config
 			m.Settings.Performance.StallTimeout = defaults.Performance.StallTimeout
 		case "speed_ema_alpha":
 			m.Settings.Performance.SpeedEmaAlpha = defaults.Performance.SpeedEmaAlpha
+		case "max_buffer_memory":
+			m.Settings.Performance.MaxBufferMemory = defaults.Performance.MaxBufferMemory
+		case "dense_preallocation":
+			m.Settings.Performance.DensePreallocation = defaults.Performance.DensePreallocation
+		case "db_busy_timeout":
+			m.Settings.Performance.DBBusyTimeout = defaults.Performance.DBBusyTimeout
 		}
 	case "Categories":
 		switch key {
@@ -2,12 +2,11 @@ package tui
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
-	"runtime"
 	"strings"
 	"time"
 
@@ -15,6 +14,7 @@ import (
 
 	"github.com/surge-downloader/surge/internal/clipboard"
 	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/core"
 	"github.com/surge-downloader/surge/internal/engine/events"
 	"github.com/surge-downloader/surge/internal/engine/state"
 	"github.com/surge-downloader/surge/internal/engine/types"
@@ -52,6 +52,12 @@ type enqueueErrorMsg struct {
 	err    error
 }
 
+type batchEnqueuedMsg struct {
+	report  *processing.BatchReport
+	skipped int // Duplicates left out of the batch
+	err     error
+}
+
 // checkForUpdateCmd performs an async update check
 func checkForUpdateCmd(currentVersion string) tea.Cmd {
 	return func() tea.Msg {
@@ -60,6 +66,26 @@ func checkForUpdateCmd(currentVersion string) tea.Cmd {
 	}
 }
 
+// responseMetadataMsg carries what the server answered when a download was probed
+type responseMetadataMsg struct {
+	id       string
+	response *types.CachedProbe
+}
+
+// fetchResponseCmd loads the probe's response metadata for the details pane
+func fetchResponseCmd(service core.DownloadService, id string) tea.Cmd {
+	if service == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		status, err := service.GetStatus(id)
+		if err != nil || status == nil || status.Response == nil {
+			return nil
+		}
+		return responseMetadataMsg{id: id, response: status.Response}
+	}
+}
+
 func shutdownCmd(service interface{ Shutdown() error }) tea.Cmd {
 	return func() tea.Msg {
 		if service == nil {
@@ -69,26 +95,6 @@ func shutdownCmd(service interface{ Shutdown() error }) tea.Cmd {
 	}
 }
 
-// openWithSystem opens a file or URL with the system's default application
-func openWithSystem(path string) error {
-	var cmd *exec.Cmd
-	switch runtime.GOOS {
-	case "darwin":
-		cmd = exec.Command("open", path)
-	case "windows":
-		cmd = exec.Command("cmd", "/c", "start", "", path)
-	default: // linux and others
-		cmd = exec.Command("xdg-open", path)
-	}
-	err := cmd.Start()
-	if err == nil {
-		go func() {
-			_ = cmd.Wait()
-		}()
-	}
-	return err
-}
-
 // addLogEntry adds a log entry to the log viewport
 func (m *RootModel) addLogEntry(msg string) {
 	timestamp := time.Now().Format("15:04:05")
@@ -179,7 +185,7 @@ func (m RootModel) checkForDuplicate(url string) *processing.DuplicateResult {
 }
 
 // startDownload initiates a new download
-func (m RootModel) startDownload(url string, mirrors []string, headers map[string]string, path string, isDefaultPath bool, filename, id string) (RootModel, tea.Cmd) {
+func (m RootModel) startDownload(url string, mirrors []string, headers map[string]string, tags []string, category string, path string, isDefaultPath bool, filename, id string) (RootModel, tea.Cmd) {
 	if m.Service == nil {
 		m.addLogEntry(LogStyleError.Render("✖ Service unavailable"))
 		return m, nil
@@ -213,6 +219,8 @@ func (m RootModel) startDownload(url string, mirrors []string, headers map[strin
 		Path:               path,
 		Mirrors:            mirrors,
 		Headers:            headers,
+		Tags:               tags,
+		Category:           category,
 		IsExplicitCategory: !isDefaultPath,
 		SkipApproval:       true,
 	}
@@ -246,28 +254,13 @@ func (m RootModel) startDownload(url string, mirrors []string, headers map[strin
 			newID string
 			err   error
 		)
+		resolved := *req
+		resolved.Path = resolvedPath
+		resolved.Filename = resolvedFilename
 		if requestID != "" {
-			newID, err = m.Service.AddWithID(
-				url,
-				resolvedPath,
-				resolvedFilename,
-				mirrors,
-				headers,
-				requestID,
-				0,
-				false,
-			)
+			newID, err = m.Service.AddWithID(&resolved, requestID)
 		} else {
-			newID, err = m.Service.Add(
-				url,
-				resolvedPath,
-				resolvedFilename,
-				mirrors,
-				headers,
-				!isDefaultPath,
-				0,
-				false,
-			)
+			newID, err = m.Service.Add(&resolved)
 		}
 		if err != nil {
 			m.removeDownloadByID(optimisticID)
@@ -311,6 +304,35 @@ func (m RootModel) startDownload(url string, mirrors []string, headers map[strin
 	return m, cmd
 }
 
+// enqueueBatch queues the non-duplicate urls as one all-or-nothing batch.
+// Rows appear through the queued events once the batch commits.
+func (m RootModel) enqueueBatch(urls []string, path string) tea.Cmd {
+	path = utils.EnsureAbsPath(path)
+	skipped := 0
+	var reqs []*processing.DownloadRequest
+	for _, url := range urls {
+		if m.checkForDuplicate(url) != nil {
+			skipped++
+			continue
+		}
+		reqs = append(reqs, &processing.DownloadRequest{
+			URL:          url,
+			Path:         path,
+			SkipApproval: true,
+		})
+	}
+
+	orchestrator := m.Orchestrator
+	ctx := m.downloadEnqueueContext()
+	return func() tea.Msg {
+		if len(reqs) == 0 {
+			return batchEnqueuedMsg{report: &processing.BatchReport{}, skipped: skipped}
+		}
+		report, err := orchestrator.EnqueueBatch(ctx, reqs)
+		return batchEnqueuedMsg{report: report, skipped: skipped, err: err}
+	}
+}
+
 func (m RootModel) defaultDownloadPath() string {
 	if m.Settings != nil {
 		if path := strings.TrimSpace(m.Settings.General.DefaultDownloadDir); path != "" {
@@ -397,7 +419,39 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.UpdateListItems()
 		return m, nil
 
+	case batchEnqueuedMsg:
+		if msg.report != nil {
+			for _, item := range msg.report.Items {
+				switch item.Status {
+				case processing.BatchItemFailed:
+					m.addLogEntry(LogStyleError.Render(fmt.Sprintf("✖ %s: %s", item.URL, item.Error)))
+				case processing.BatchItemSkipped:
+					m.addLogEntry(LogStyleStarted.Render(fmt.Sprintf("⏭ Skipped %s: already downloaded", item.URL)))
+				}
+			}
+		}
+		if msg.err != nil {
+			m.addLogEntry(LogStyleError.Render("✖ Batch not added: " + msg.err.Error()))
+			return m, nil
+		}
+		added := msg.report.Count(processing.BatchItemQueued)
+		if msg.skipped > 0 {
+			m.addLogEntry(LogStyleStarted.Render(fmt.Sprintf("⬇ Added %d downloads from batch (%d duplicates skipped)", added, msg.skipped)))
+		} else {
+			m.addLogEntry(LogStyleStarted.Render(fmt.Sprintf("⬇ Added %d downloads from batch", added)))
+		}
+		return m, nil
+
 	case enqueueErrorMsg:
+		if errors.Is(msg.err, processing.ErrAlreadyDownloaded) {
+			// Archived URLs are skipped, not failed, so drop the optimistic row
+			if msg.tempID != "" {
+				m.removeDownloadByID(msg.tempID)
+				m.UpdateListItems()
+			}
+			m.addLogEntry(LogStyleStarted.Render("⏭ Skipped download: already downloaded"))
+			return m, nil
+		}
 		if msg.tempID != "" {
 			if d := m.FindDownloadByID(msg.tempID); d != nil {
 				d.err = msg.err
@@ -437,6 +491,8 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.pendingURL = msg.URL
 			m.pendingMirrors = msg.Mirrors
 			m.pendingHeaders = msg.Headers
+			m.pendingTags = msg.Tags
+			m.pendingCategory = msg.Category
 			m.pendingPath = path
 			m.pendingIsDefaultPath = isDefaultPath
 			m.pendingFilename = msg.Filename
@@ -449,6 +505,8 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.pendingURL = msg.URL
 			m.pendingMirrors = msg.Mirrors
 			m.pendingHeaders = msg.Headers
+			m.pendingTags = msg.Tags
+			m.pendingCategory = msg.Category
 			m.pendingPath = path
 			m.pendingIsDefaultPath = isDefaultPath
 			m.pendingFilename = msg.Filename
@@ -463,7 +521,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
-		return m.startDownload(msg.URL, msg.Mirrors, msg.Headers, path, isDefaultPath, msg.Filename, msg.ID)
+		return m.startDownload(msg.URL, msg.Mirrors, msg.Headers, msg.Tags, msg.Category, path, isDefaultPath, msg.Filename, msg.ID)
 
 	case events.DownloadStartedMsg:
 		found := false
@@ -483,6 +541,9 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if d.state == nil && msg.State != nil {
 				d.state = msg.State
 			}
+			if len(msg.Tags) > 0 {
+				d.Tags = msg.Tags
+			}
 			if d.state != nil {
 				d.state.SetTotalSize(msg.Total) // Keep state updated for verification if needed
 			}
@@ -492,6 +553,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if !found {
 			newDownload := NewDownloadModel(msg.DownloadID, msg.URL, msg.Filename, msg.Total)
 			newDownload.Destination = msg.DestPath
+			newDownload.Tags = msg.Tags
 			if msg.State != nil {
 				newDownload.state = msg.State
 			}
@@ -500,8 +562,16 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		m.UpdateListItems()
 		m.addLogEntry(LogStyleStarted.Render("⬇ Started: " + msg.Filename))
+		// Pick up the probe cached when the download was queued
+		cmds = append(cmds, fetchResponseCmd(m.Service, msg.DownloadID))
 		return m, tea.Batch(cmds...)
 
+	case responseMetadataMsg:
+		if d := m.FindDownloadByID(msg.id); d != nil {
+			d.Response = msg.response
+		}
+		return m, nil
+
 	case events.ProgressMsg:
 		m.processProgressMsg(msg)
 		return m, nil
@@ -558,6 +628,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			d.paused = true
 			d.pausing = false
 			d.resuming = false
+			d.pauseReason = msg.Reason
 			d.Downloaded = msg.Downloaded
 			d.Speed = 0
 			m.addLogEntry(LogStylePaused.Render("⏸ Paused: " + d.Filename))
@@ -570,6 +641,14 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			d.paused = false
 			d.pausing = false
 			d.resuming = true
+			d.pauseReason = ""
+			if d.err != nil {
+				// A failed download retried: it starts over with a new state
+				d.err = nil
+				d.done = false
+				d.state = nil
+				d.Downloaded = 0
+			}
 			m.addLogEntry(LogStyleStarted.Render("▶ Resumed: " + d.Filename))
 		}
 		m.UpdateListItems()
@@ -579,12 +658,14 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// We optimistically added it, but if it came from elsewhere, handle it
 		found := false
 		if d := m.FindDownloadByID(msg.DownloadID); d != nil {
+			d.Tags = msg.Tags
 			found = true
 		}
 		if !found {
 			// Add placeholder
 			newDownload := NewDownloadModel(msg.DownloadID, msg.URL, msg.Filename, 0)
 			newDownload.Destination = msg.DestPath
+			newDownload.Tags = msg.Tags
 			m.downloads = append(m.downloads, newDownload)
 			m.UpdateListItems()
 		}
@@ -599,6 +680,57 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, tea.Batch(cmds...)
 
+	case events.DownloadRestoredMsg:
+		if m.FindDownloadByID(msg.DownloadID) == nil {
+			d := NewDownloadModel(msg.DownloadID, msg.URL, msg.Filename, msg.TotalSize)
+			d.Destination = msg.DestPath
+			d.Downloaded = msg.Downloaded
+			d.Tags = msg.Tags
+			if msg.Status == "completed" {
+				d.done = true
+				cmds = append(cmds, d.progress.SetPercent(1.0))
+			} else {
+				d.paused = true
+				d.pauseReason = types.PauseUser
+				if msg.TotalSize > 0 {
+					cmds = append(cmds, d.progress.SetPercent(float64(msg.Downloaded)/float64(msg.TotalSize)))
+				}
+			}
+			m.downloads = append(m.downloads, d)
+			m.addLogEntry(LogStyleStarted.Render("↺ Restored: " + msg.Filename))
+			m.UpdateListItems()
+		}
+		return m, tea.Batch(cmds...)
+
+	case events.DownloadPhaseMsg:
+		if d := m.FindDownloadByID(msg.DownloadID); d != nil {
+			if types.IsFinalizingPhase(msg.Phase) {
+				d.phase = msg.Phase
+				d.phaseProgress = msg.Progress
+			} else {
+				d.phase = ""
+				d.phaseProgress = 0
+			}
+		}
+		m.UpdateListItems()
+		return m, tea.Batch(cmds...)
+
+	case events.DownloadMovedMsg:
+		if d := m.FindDownloadByID(msg.DownloadID); d != nil {
+			d.Filename = msg.Filename
+			d.Destination = msg.DestPath
+			m.addLogEntry(LogStyleStarted.Render("→ Moved: " + msg.DestPath))
+			m.UpdateListItems()
+		}
+		return m, tea.Batch(cmds...)
+
+	case events.DownloadNoteMsg:
+		if d := m.FindDownloadByID(msg.DownloadID); d != nil {
+			d.Note = msg.Note
+			d.Metadata = msg.Metadata
+		}
+		return m, tea.Batch(cmds...)
+
 	case events.SystemLogMsg:
 		if msg.Message != "" {
 			m.addLogEntry(LogStyleStarted.Render("ℹ " + msg.Message))
@@ -632,6 +764,48 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Notification tick is still used but logs don't expire
 		return m, nil
 
+	case orphansFoundMsg:
+		if msg.err != nil {
+			utils.Debug("Orphan scan failed: %v", msg.err)
+			return m, nil
+		}
+		if msg.orphans.Empty() {
+			return m, nil
+		}
+		if m.state != DashboardState {
+			// Don't interrupt whatever the user is doing
+			m.addLogEntry(LogStylePaused.Render("⚠ Found " + orphansSummary(msg.orphans) + "; run 'surge prune --orphans' to clean up"))
+			return m, nil
+		}
+		m.orphans = msg.orphans
+		m.state = OrphanCleanupState
+		return m, nil
+
+	case errorHistoryMsg:
+		if msg.err != nil {
+			m.addLogEntry(LogStyleError.Render("✖ Failed to load error history: " + msg.err.Error()))
+			return m, nil
+		}
+		if m.state != DashboardState {
+			return m, nil
+		}
+		m.errorHistory = msg.failures
+		m.errorHistoryName = msg.filename
+		m.state = ErrorHistoryState
+		return m, nil
+
+	case orphansRemovedMsg:
+		for _, id := range msg.removedIDs {
+			m.removeDownloadByID(id)
+		}
+		m.UpdateListItems()
+		if msg.err != nil {
+			m.addLogEntry(LogStyleError.Render(fmt.Sprintf("✖ Orphan cleanup failed: %v", msg.err)))
+			return m, nil
+		}
+		m.addLogEntry(LogStyleComplete.Render(fmt.Sprintf("✔ Removed %d orphaned files and %d downloads", msg.files, len(msg.removedIDs))))
+		return m, nil
+
 	case UpdateCheckResultMsg:
 		if msg.Info != nil && msg.Info.UpdateAvailable {
 			m.UpdateInfo = msg.Info
@@ -781,6 +955,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					url = clipboard.ReadURL()
 				}
 				m.inputs[0].SetValue(url)
+				m.loadURLHistory()
 				return m, nil
 			}
 
@@ -815,6 +990,58 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 			}
 
+			// Delete download but keep its partial data for restore-partial
+			if key.Matches(msg, m.keys.Dashboard.Archive) {
+				if m.list.FilterState() == list.Filtering {
+					// Fall through
+				} else if d := m.GetSelectedDownload(); d != nil {
+					archiver, ok := m.Service.(core.Archiver)
+					if !ok {
+						m.addLogEntry(LogStyleError.Render("✖ Keeping partial data is not supported"))
+						return m, nil
+					}
+					if err := archiver.Archive(d.ID); err != nil {
+						m.addLogEntry(LogStyleError.Render("✖ Delete failed: " + err.Error()))
+					} else {
+						m.addLogEntry(LogStylePaused.Render("⏸ Archived: " + d.Filename))
+						m.removeDownloadByID(d.ID)
+					}
+					m.UpdateListItems()
+					return m, nil
+				}
+			}
+
+			// Bring back the most recently deleted download from the trash
+			if key.Matches(msg, m.keys.Dashboard.Undelete) {
+				if m.list.FilterState() == list.Filtering {
+					// Fall through
+				} else {
+					trasher, ok := m.Service.(core.Trasher)
+					if !ok {
+						m.addLogEntry(LogStyleError.Render("✖ Trash is not supported"))
+						return m, nil
+					}
+					trashed, err := state.LoadTrash()
+					if err != nil || len(trashed) == 0 {
+						m.addLogEntry(LogStyleError.Render("✖ The trash is empty"))
+						return m, nil
+					}
+					if err := trasher.RestoreTrashed(trashed[0].ID); err != nil {
+						m.addLogEntry(LogStyleError.Render("✖ Restore failed: " + err.Error()))
+					}
+					return m, nil
+				}
+			}
+
+			// Error history of the selected download
+			if key.Matches(msg, m.keys.Dashboard.Errors) {
+				if m.list.FilterState() == list.Filtering {
+					// Fall through
+				} else if d := m.GetSelectedDownload(); d != nil && m.Service != nil {
+					return m, fetchErrorHistoryCmd(m.Service, d.ID, d.Filename)
+				}
+			}
+
 			// History
 			if key.Matches(msg, m.keys.Dashboard.History) {
 				// Note: accessing state directly here breaks abstraction.
@@ -874,7 +1101,21 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						if !d.done {
 							filePath = d.Destination + types.IncompleteSuffix
 						}
-						_ = openWithSystem(filePath)
+						_ = utils.OpenWithSystem(filePath)
+					}
+				}
+				return m, nil
+			}
+
+			// Open the folder holding the file
+			if key.Matches(msg, m.keys.Dashboard.OpenFolder) {
+				if d := m.GetSelectedDownload(); d != nil && d.Destination != "" {
+					filePath := d.Destination
+					if !d.done {
+						filePath = d.Destination + types.IncompleteSuffix
+					}
+					if err := utils.RevealInFolder(filePath); err != nil {
+						m.addLogEntry(LogStyleError.Render("✖ Could not open folder: " + err.Error()))
 					}
 				}
 				return m, nil
@@ -998,6 +1239,21 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.state = DashboardState
 				return m, nil
 			}
+			// URL history: cycle suggestions and accept with Tab
+			if m.focusedInput == 0 && len(m.urlSuggestions) > 0 {
+				if key.Matches(msg, m.keys.Input.HistoryNext) {
+					m.urlSuggestionCursor = (m.urlSuggestionCursor + 1) % len(m.urlSuggestions)
+					return m, nil
+				}
+				if key.Matches(msg, m.keys.Input.HistoryPrev) {
+					m.urlSuggestionCursor = (m.urlSuggestionCursor - 1 + len(m.urlSuggestions)) % len(m.urlSuggestions)
+					return m, nil
+				}
+				if key.Matches(msg, m.keys.Input.Tab) {
+					m.acceptURLSuggestion()
+					return m, nil
+				}
+			}
 			// Tab to open file picker when on path input
 			if key.Matches(msg, m.keys.Input.Tab) && m.focusedInput == 2 {
 				m.state = FilePickerState
@@ -1076,6 +1332,8 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.pendingURL = url
 					m.pendingMirrors = mirrors
 					m.pendingHeaders = nil
+					m.pendingTags = nil
+					m.pendingCategory = ""
 					m.pendingPath = path
 					m.pendingIsDefaultPath = isDefaultPath
 					m.pendingFilename = filename
@@ -1091,7 +1349,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.inputs[2].SetValue(path) // Keep path
 				m.inputs[3].SetValue("")
 
-				return m.startDownload(url, mirrors, nil, path, isDefaultPath, filename, "")
+				return m.startDownload(url, mirrors, nil, nil, "", path, isDefaultPath, filename, "")
 			}
 
 			// Up/Down navigation between inputs
@@ -1110,6 +1368,9 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			var cmd tea.Cmd
 			m.inputs[m.focusedInput], cmd = m.inputs[m.focusedInput].Update(msg)
+			if m.focusedInput == 0 {
+				m.refreshURLSuggestions()
+			}
 			return m, cmd
 
 		case FilePickerState:
@@ -1195,7 +1456,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if key.Matches(msg, m.keys.Duplicate.Continue) {
 				// Continue anyway - startDownload handles unique filename generation
 				m.state = DashboardState
-				return m.startDownload(m.pendingURL, m.pendingMirrors, m.pendingHeaders, m.pendingPath, m.pendingIsDefaultPath, m.pendingFilename, "")
+				return m.startDownload(m.pendingURL, m.pendingMirrors, m.pendingHeaders, m.pendingTags, m.pendingCategory, m.pendingPath, m.pendingIsDefaultPath, m.pendingFilename, "")
 			}
 			if key.Matches(msg, m.keys.Duplicate.Cancel) {
 				// Cancel - don't add
@@ -1257,7 +1518,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 				// No duplicate (or warning disabled) - add to queue
 				m.state = DashboardState
-				return m.startDownload(m.pendingURL, m.pendingMirrors, m.pendingHeaders, m.pendingPath, m.pendingIsDefaultPath, m.pendingFilename, "")
+				return m.startDownload(m.pendingURL, m.pendingMirrors, m.pendingHeaders, m.pendingTags, m.pendingCategory, m.pendingPath, m.pendingIsDefaultPath, m.pendingFilename, "")
 			}
 			if key.Matches(msg, m.keys.Extension.Cancel) {
 				// Cancelled
@@ -1326,6 +1587,14 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					path = "."
 				}
 
+				if m.Orchestrator != nil {
+					cmd := m.enqueueBatch(m.pendingBatchURLs, path)
+					m.pendingBatchURLs = nil
+					m.batchFilePath = ""
+					m.state = DashboardState
+					return m, cmd
+				}
+
 				added := 0
 				skipped := 0
 				var batchCmds []tea.Cmd
@@ -1336,7 +1605,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						continue
 					}
 					var cmd tea.Cmd
-					m, cmd = m.startDownload(url, nil, nil, path, true, "", "")
+					m, cmd = m.startDownload(url, nil, nil, nil, "", path, true, "", "")
 					if cmd != nil {
 						batchCmds = append(batchCmds, cmd)
 					}
@@ -1372,16 +1641,26 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if key.Matches(msg, m.keys.SettingsEditor.Cancel) {
 					// Cancel editing
 					m.SettingsIsEditing = false
+					m.SettingsInputError = ""
 					m.SettingsInput.Blur()
 					return m, nil
 				}
 				if key.Matches(msg, m.keys.SettingsEditor.Confirm) {
+					// Keep the editor open until the value is valid
+					if meta := m.getCurrentSettingMeta(); meta != nil {
+						if err := meta.Validate(m.SettingsInput.Value()); err != nil {
+							m.SettingsInputError = err.Error()
+							return m, nil
+						}
+					}
+
 					// Commit the value
 					categories := config.CategoryOrder()
 					currentCategory := categories[m.SettingsActiveTab]
 					settingKey := m.getCurrentSettingKey()
 					_ = m.setSettingValue(currentCategory, settingKey, m.SettingsInput.Value())
 					m.SettingsIsEditing = false
+					m.SettingsInputError = ""
 					m.SettingsInput.Blur()
 					return m, nil
 				}
@@ -1389,6 +1668,12 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				// Pass to text input
 				var cmd tea.Cmd
 				m.SettingsInput, cmd = m.SettingsInput.Update(msg)
+				m.SettingsInputError = ""
+				if meta := m.getCurrentSettingMeta(); meta != nil && m.SettingsInput.Value() != "" {
+					if err := meta.Validate(m.SettingsInput.Value()); err != nil {
+						m.SettingsInputError = err.Error()
+					}
+				}
 				return m, cmd
 			}
 
@@ -1485,6 +1770,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				} else {
 					// Enter edit mode
 					m.SettingsIsEditing = true
+					m.SettingsInputError = ""
 					// Pre-fill with current value (without units)
 					categories := config.CategoryOrder()
 					currentCategory := categories[m.SettingsActiveTab]
@@ -1517,11 +1803,33 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			return m, nil
 
+		case ErrorHistoryState:
+			if msg.String() == "esc" || msg.String() == "q" || msg.String() == "enter" {
+				m.errorHistory = nil
+				m.state = DashboardState
+			}
+			return m, nil
+
+		case OrphanCleanupState:
+			if key.Matches(msg, m.keys.BatchConfirm.Confirm) {
+				orphans := m.orphans
+				m.orphans = nil
+				m.state = DashboardState
+				return m, removeOrphansCmd(orphans)
+			}
+			if key.Matches(msg, m.keys.BatchConfirm.Cancel) {
+				m.addLogEntry(LogStylePaused.Render("⚠ Kept " + orphansSummary(m.orphans) + "; run 'surge prune --orphans' to clean up"))
+				m.orphans = nil
+				m.state = DashboardState
+				return m, nil
+			}
+			return m, nil
+
 		case UpdateAvailableState:
 			if key.Matches(msg, m.keys.Update.OpenGitHub) {
 				// Open the release page in browser
 				if m.UpdateInfo != nil && m.UpdateInfo.ReleaseURL != "" {
-					_ = openWithSystem(m.UpdateInfo.ReleaseURL)
+					_ = utils.OpenWithSystem(m.UpdateInfo.ReleaseURL)
 				}
 				m.state = DashboardState
 				m.UpdateInfo = nil
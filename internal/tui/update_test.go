@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/surge-downloader/surge/internal/core"
 	"github.com/surge-downloader/surge/internal/download"
 	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/state"
 	"github.com/surge-downloader/surge/internal/engine/types"
 	"github.com/surge-downloader/surge/internal/processing"
 )
@@ -433,7 +435,7 @@ func TestStartDownload_UsesProvidedIDWhenServiceSupportsIt(t *testing.T) {
 	}
 
 	requestID := "request-id-123"
-	updated, _ := m.startDownload("https://example.com/file.bin", nil, nil, t.TempDir(), false, "file.bin", requestID)
+	updated, _ := m.startDownload("https://example.com/file.bin", nil, nil, nil, "", t.TempDir(), false, "file.bin", requestID)
 
 	if len(updated.downloads) != 1 {
 		t.Fatalf("expected 1 queued download, got %d", len(updated.downloads))
@@ -453,7 +455,7 @@ func TestStartDownload_UsesModelEnqueueContext(t *testing.T) {
 	cancel()
 
 	orchestrator := processing.NewLifecycleManager(
-		func(string, string, string, []string, map[string]string, bool, int64, bool) (string, error) {
+		func(*processing.DownloadRequest) (string, error) {
 			t.Fatal("enqueue dispatch should not run after context cancellation")
 			return "", nil
 		},
@@ -470,7 +472,7 @@ func TestStartDownload_UsesModelEnqueueContext(t *testing.T) {
 		logViewport:   viewport.New(40, 5),
 	}
 
-	updated, cmd := m.startDownload("https://example.com/file.bin", nil, nil, t.TempDir(), false, "file.bin", "")
+	updated, cmd := m.startDownload("https://example.com/file.bin", nil, nil, nil, "", t.TempDir(), false, "file.bin", "")
 	if cmd == nil {
 		t.Fatal("expected enqueue command")
 	}
@@ -495,7 +497,7 @@ func TestStartDownload_GuessesFilenameOptimisticallyWhenProvidedOrInferred(t *te
 	})
 
 	orchestrator := processing.NewLifecycleManager(
-		func(string, string, string, []string, map[string]string, bool, int64, bool) (string, error) {
+		func(*processing.DownloadRequest) (string, error) {
 			return "real-id", nil
 		},
 		nil,
@@ -510,7 +512,7 @@ func TestStartDownload_GuessesFilenameOptimisticallyWhenProvidedOrInferred(t *te
 		logViewport:  viewport.New(40, 5),
 	}
 
-	updated, _ := m.startDownload("https://example.com/100MB.bin", nil, nil, targetDir, true, "", "")
+	updated, _ := m.startDownload("https://example.com/100MB.bin", nil, nil, nil, "", targetDir, true, "", "")
 
 	if len(updated.downloads) != 1 {
 		t.Fatalf("expected 1 optimistic queued download, got %d", len(updated.downloads))
@@ -531,7 +533,7 @@ func TestStartDownload_UsesGenericQueuedNameForExplicitFilenameUntilLifecycleCon
 	})
 
 	orchestrator := processing.NewLifecycleManager(
-		func(string, string, string, []string, map[string]string, bool, int64, bool) (string, error) {
+		func(*processing.DownloadRequest) (string, error) {
 			return "real-id", nil
 		},
 		nil,
@@ -546,7 +548,7 @@ func TestStartDownload_UsesGenericQueuedNameForExplicitFilenameUntilLifecycleCon
 		logViewport:  viewport.New(40, 5),
 	}
 
-	updated, _ := m.startDownload("https://example.com/archive.zip", nil, nil, targetDir, false, "archive.zip", "")
+	updated, _ := m.startDownload("https://example.com/archive.zip", nil, nil, nil, "", targetDir, false, "archive.zip", "")
 
 	if len(updated.downloads) != 1 {
 		t.Fatalf("expected 1 optimistic queued download, got %d", len(updated.downloads))
@@ -628,7 +630,7 @@ func TestWithEnqueueContext_OverridesStartDownloadContext(t *testing.T) {
 	cancel()
 
 	orchestrator := processing.NewLifecycleManager(
-		func(string, string, string, []string, map[string]string, bool, int64, bool) (string, error) {
+		func(*processing.DownloadRequest) (string, error) {
 			t.Fatal("enqueue dispatch should not run after shared context cancellation")
 			return "", nil
 		},
@@ -638,7 +640,7 @@ func TestWithEnqueueContext_OverridesStartDownloadContext(t *testing.T) {
 	m := InitialRootModel(1700, "test-version", svc, orchestrator, false)
 	m = m.WithEnqueueContext(ctx, func() {})
 
-	_, cmd := m.startDownload("https://example.com/file.bin", nil, nil, t.TempDir(), false, "file.bin", "")
+	_, cmd := m.startDownload("https://example.com/file.bin", nil, nil, nil, "", t.TempDir(), false, "file.bin", "")
 	if cmd == nil {
 		t.Fatal("expected enqueue command")
 	}
@@ -681,3 +683,235 @@ func TestUpdate_RefreshShortcut(t *testing.T) {
 		t.Errorf("Expected urlUpdateInput to be pre-filled with 'http://example.com/file', got '%s'", newRoot.urlUpdateInput.Value())
 	}
 }
+
+func TestUpdate_SettingsEditorRejectsOutOfRangeValue(t *testing.T) {
+	row := -1
+	for i, meta := range config.GetSettingsMetadata()["Network"] {
+		if meta.Key == "min_chunk_size" {
+			row = i
+		}
+	}
+	if row < 0 {
+		t.Fatal("min_chunk_size metadata missing")
+	}
+
+	input := textinput.New()
+	input.Focus()
+	m := RootModel{
+		state:               SettingsState,
+		Settings:            config.DefaultSettings(),
+		keys:                Keys,
+		SettingsActiveTab:   1, // Network
+		SettingsSelectedRow: row,
+		SettingsIsEditing:   true,
+		SettingsInput:       input,
+	}
+	m.SettingsInput.SetValue("209715")
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'2'}})
+	m = updated.(RootModel)
+	if m.SettingsInputError == "" {
+		t.Fatal("expected inline error while typing a byte count")
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(RootModel)
+	if !m.SettingsIsEditing {
+		t.Fatal("expected editor to stay open on invalid value")
+	}
+	if m.Settings.Network.MinChunkSize != config.DefaultSettings().Network.MinChunkSize {
+		t.Fatalf("invalid value was applied: %d", m.Settings.Network.MinChunkSize)
+	}
+
+	m.SettingsInput.SetValue("4")
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(RootModel)
+	if m.SettingsIsEditing || m.SettingsInputError != "" {
+		t.Fatal("expected valid value to close the editor")
+	}
+	if m.Settings.Network.MinChunkSize != 4*config.MB {
+		t.Fatalf("expected 4MB chunk size, got %d", m.Settings.Network.MinChunkSize)
+	}
+}
+
+func TestUpdate_DownloadPhaseShowsFinalizeProgress(t *testing.T) {
+	d := NewDownloadModel("phase-id", "https://example.com/big.iso", "big.iso", 100)
+	d.done = true
+	m := RootModel{
+		downloads: []*DownloadModel{d},
+		list:      NewDownloadList(80, 20),
+	}
+
+	updated, _ := m.Update(events.DownloadPhaseMsg{DownloadID: "phase-id", Phase: types.PhaseMoving, Progress: 42})
+	m = updated.(RootModel)
+	if got := getDownloadStatus(m.downloads[0]); !strings.Contains(got, "Moving 42%") {
+		t.Fatalf("expected moving status, got %q", got)
+	}
+
+	updated, _ = m.Update(events.DownloadPhaseMsg{DownloadID: "phase-id", Phase: types.PhaseComplete, Progress: 100})
+	m = updated.(RootModel)
+	if got := getDownloadStatus(m.downloads[0]); strings.Contains(got, "Moving") {
+		t.Fatalf("expected finalize status to clear, got %q", got)
+	}
+}
+
+func TestUpdate_DownloadPausedShowsSystemReason(t *testing.T) {
+	d := NewDownloadModel("pause-id", "https://example.com/big.iso", "big.iso", 100)
+	m := RootModel{
+		downloads:   []*DownloadModel{d},
+		list:        NewDownloadList(80, 20),
+		logViewport: viewport.New(40, 5),
+	}
+
+	updated, _ := m.Update(events.DownloadPausedMsg{DownloadID: "pause-id", Downloaded: 10, Reason: types.PauseDiskFull})
+	m = updated.(RootModel)
+	if got := getDownloadStatus(m.downloads[0]); !strings.Contains(got, "Paused (disk full)") {
+		t.Fatalf("expected disk full reason, got %q", got)
+	}
+
+	updated, _ = m.Update(events.DownloadPausedMsg{DownloadID: "pause-id", Downloaded: 10, Reason: types.PauseUser})
+	m = updated.(RootModel)
+	if got := getDownloadStatus(m.downloads[0]); strings.Contains(got, "(") {
+		t.Fatalf("user pause should not show a reason, got %q", got)
+	}
+}
+
+func TestUpdate_OrphansPromptForCleanup(t *testing.T) {
+	orphans := &state.Orphans{
+		Files:   []string{"/downloads/stray.iso.surge"},
+		Entries: []types.DownloadEntry{{ID: "lost", DestPath: "/downloads/lost.iso"}},
+	}
+	m := RootModel{
+		downloads:   []*DownloadModel{NewDownloadModel("lost", "https://example.com/lost.iso", "lost.iso", 100)},
+		list:        NewDownloadList(80, 20),
+		logViewport: viewport.New(40, 5),
+		keys:        Keys,
+	}
+
+	updated, _ := m.Update(orphansFoundMsg{orphans: orphans})
+	m = updated.(RootModel)
+	if m.state != OrphanCleanupState {
+		t.Fatalf("state = %v, want the orphan cleanup prompt", m.state)
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'n'}})
+	m = updated.(RootModel)
+	if m.state != DashboardState || m.orphans != nil {
+		t.Fatalf("declining should close the prompt, state = %v", m.state)
+	}
+	if len(m.downloads) != 1 {
+		t.Fatal("declining must not remove anything")
+	}
+
+	updated, _ = m.Update(orphansRemovedMsg{files: 1, removedIDs: []string{"lost"}})
+	m = updated.(RootModel)
+	if m.FindDownloadByID("lost") != nil {
+		t.Error("removed download is still listed")
+	}
+}
+
+func TestUpdate_ErrorHistoryPopup(t *testing.T) {
+	m := RootModel{
+		list:        NewDownloadList(80, 20),
+		logViewport: viewport.New(40, 5),
+		keys:        Keys,
+		width:       120,
+		height:      40,
+	}
+
+	failures := []types.DownloadFailure{
+		{At: 1000, Kind: "5xx", Status: 503, Mirror: "https://mirror.example.com/a.iso", Offset: types.MB, Message: "unexpected status: 503"},
+		{At: 1010, Kind: types.FailureFatal, Message: "all mirrors failed"},
+	}
+	updated, _ := m.Update(errorHistoryMsg{filename: "a.iso", failures: failures})
+	m = updated.(RootModel)
+	if m.state != ErrorHistoryState {
+		t.Fatalf("state = %v, want the error history popup", m.state)
+	}
+	view := m.View()
+	for _, want := range []string{"a.iso", "5xx 503", "mirror.example.com", "all mirrors failed"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("popup is missing %q", want)
+		}
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = updated.(RootModel)
+	if m.state != DashboardState || m.errorHistory != nil {
+		t.Fatalf("esc should close the popup, state = %v", m.state)
+	}
+}
+
+func TestUpdate_BatchEnqueuedReportsFailedItems(t *testing.T) {
+	m := RootModel{
+		list:        NewDownloadList(80, 20),
+		logViewport: viewport.New(40, 5),
+		Settings:    config.DefaultSettings(),
+	}
+
+	report := &processing.BatchReport{Items: []processing.BatchItemResult{
+		{URL: "https://example.com/a", Status: processing.BatchItemRolledBack},
+		{URL: "https://example.com/b", Status: processing.BatchItemFailed, Error: "probe failed: 404"},
+	}}
+	updated, _ := m.Update(batchEnqueuedMsg{report: report, err: processing.ErrBatchRolledBack})
+	logs := strings.Join(updated.(RootModel).logEntries, "\n")
+
+	if !strings.Contains(logs, "https://example.com/b") || !strings.Contains(logs, "404") {
+		t.Errorf("expected the failing URL to be logged, got %q", logs)
+	}
+	if !strings.Contains(logs, "Batch not added") || strings.Contains(logs, "Added") {
+		t.Errorf("expected the batch to be reported as not added, got %q", logs)
+	}
+}
+
+func TestGetFilteredDownloads_TagSearch(t *testing.T) {
+	iso := NewDownloadModel("d1", "https://example.com/ubuntu.iso", "ubuntu.iso", 0)
+	iso.Tags = []string{"iso", "work"}
+	movie := NewDownloadModel("d2", "https://example.com/movie.mp4", "movie.mp4", 0)
+	movie.Tags = []string{"media"}
+	plain := NewDownloadModel("d3", "https://example.com/ubuntu-notes.txt", "ubuntu-notes.txt", 0)
+
+	m := RootModel{
+		activeTab: TabQueued,
+		downloads: []*DownloadModel{iso, movie, plain},
+		Settings:  config.DefaultSettings(),
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"d1", "d2", "d3"}},
+		{"ubuntu", []string{"d1", "d3"}},
+		{"#iso", []string{"d1"}},
+		{"ubuntu #ISO", []string{"d1"}},
+		{"#iso #media", nil},
+		{"#", []string{"d1", "d2", "d3"}},
+	}
+	for _, tt := range tests {
+		m.searchQuery = tt.query
+		var got []string
+		for _, d := range m.getFilteredDownloads() {
+			got = append(got, d.ID)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("search %q = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestUpdate_QueuedEventCarriesTags(t *testing.T) {
+	m := RootModel{
+		list:        NewDownloadList(80, 20),
+		logViewport: viewport.New(40, 5),
+		Settings:    config.DefaultSettings(),
+	}
+
+	updated, _ := m.Update(events.DownloadQueuedMsg{DownloadID: "q1", URL: "https://example.com/a.iso", Filename: "a.iso", Tags: []string{"iso"}})
+	m2 := updated.(RootModel)
+
+	d := m2.FindDownloadByID("q1")
+	if d == nil || strings.Join(d.Tags, ",") != "iso" {
+		t.Fatalf("queued download = %+v, want tags [iso]", d)
+	}
+}
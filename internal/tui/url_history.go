@@ -0,0 +1,110 @@
+package tui
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/surge-downloader/surge/internal/engine/state"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+const (
+	urlHistoryLoadLimit = 100 // URLs loaded from the database when the add form opens
+	maxURLSuggestions   = 5   // Suggestions shown under the URL input
+)
+
+// fuzzyScore reports whether every rune of query appears in candidate in order
+// (case-insensitive) and scores the match. Contiguous runs and substring hits
+// score higher so "ubuntu iso" style queries rank the obvious URL first.
+func fuzzyScore(query, candidate string) (int, bool) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return 0, true
+	}
+	target := strings.ToLower(candidate)
+
+	score := 0
+	if idx := strings.Index(target, query); idx >= 0 {
+		score += 100 - min(idx, 50)
+	}
+
+	qi := 0
+	q := []rune(query)
+	prevMatched := false
+	for _, r := range target {
+		if qi >= len(q) {
+			break
+		}
+		if r == q[qi] {
+			score += 1
+			if prevMatched {
+				score += 5
+			}
+			prevMatched = true
+			qi++
+			continue
+		}
+		prevMatched = false
+	}
+	if qi < len(q) {
+		return 0, false
+	}
+	return score, true
+}
+
+// loadURLHistory refreshes the cached URL history used for add-form suggestions
+func (m *RootModel) loadURLHistory() {
+	entries, err := state.LoadURLHistory(urlHistoryLoadLimit)
+	if err != nil {
+		utils.Debug("Failed to load url history: %v", err)
+		m.urlHistory = nil
+	} else {
+		m.urlHistory = entries
+	}
+	m.urlSuggestionCursor = 0
+	m.refreshURLSuggestions()
+}
+
+// refreshURLSuggestions filters the history against the URL input. Matches are
+// ranked by fuzzy score, with recency breaking ties.
+func (m *RootModel) refreshURLSuggestions() {
+	query := m.inputs[0].Value()
+
+	type match struct {
+		url   string
+		score int
+	}
+	var matches []match
+	for _, e := range m.urlHistory {
+		if e.URL == strings.TrimSpace(query) {
+			continue // Already typed out in full
+		}
+		if score, ok := fuzzyScore(query, e.URL); ok {
+			matches = append(matches, match{url: e.URL, score: score})
+		}
+	}
+	// History is newest first, so a stable sort keeps recency among equal scores
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	m.urlSuggestions = m.urlSuggestions[:0]
+	for i := 0; i < len(matches) && i < maxURLSuggestions; i++ {
+		m.urlSuggestions = append(m.urlSuggestions, matches[i].url)
+	}
+	if m.urlSuggestionCursor >= len(m.urlSuggestions) {
+		m.urlSuggestionCursor = 0
+	}
+}
+
+// acceptURLSuggestion fills the URL input with the highlighted suggestion
+func (m *RootModel) acceptURLSuggestion() bool {
+	if m.urlSuggestionCursor < 0 || m.urlSuggestionCursor >= len(m.urlSuggestions) {
+		return false
+	}
+	m.inputs[0].SetValue(m.urlSuggestions[m.urlSuggestionCursor])
+	m.inputs[0].CursorEnd()
+	m.urlSuggestionCursor = 0
+	m.refreshURLSuggestions()
+	return true
+}
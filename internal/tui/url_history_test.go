@@ -0,0 +1,83 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	if _, ok := fuzzyScore("ubnt", "https://releases.ubuntu.com/noble.iso"); !ok {
+		t.Error("expected subsequence to match")
+	}
+	if _, ok := fuzzyScore("xyz", "https://example.com/file.zip"); ok {
+		t.Error("expected missing runes not to match")
+	}
+	if _, ok := fuzzyScore("", "https://example.com"); !ok {
+		t.Error("expected empty query to match everything")
+	}
+
+	substr, _ := fuzzyScore("ubuntu", "https://releases.ubuntu.com/noble.iso")
+	scattered, _ := fuzzyScore("ubuntu", "https://u.b.u.n.t.u.example.com/file")
+	if substr <= scattered {
+		t.Errorf("expected substring match to outrank scattered match: %d vs %d", substr, scattered)
+	}
+}
+
+func newURLHistoryTestModel(history ...string) RootModel {
+	m := RootModel{
+		state:  InputState,
+		keys:   Keys,
+		inputs: []textinput.Model{textinput.New(), textinput.New(), textinput.New(), textinput.New()},
+	}
+	for _, u := range history {
+		m.urlHistory = append(m.urlHistory, types.URLHistoryEntry{URL: u})
+	}
+	m.inputs[0].Focus()
+	m.refreshURLSuggestions()
+	return m
+}
+
+func TestURLSuggestions_FilterAsYouType(t *testing.T) {
+	m := newURLHistoryTestModel(
+		"https://example.com/movie.mkv",
+		"https://releases.ubuntu.com/noble.iso",
+		"https://example.com/song.mp3",
+	)
+	if len(m.urlSuggestions) != 3 {
+		t.Fatalf("expected all history with empty input, got %v", m.urlSuggestions)
+	}
+
+	for _, r := range "iso" {
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		m = updated.(RootModel)
+	}
+
+	if len(m.urlSuggestions) != 1 || m.urlSuggestions[0] != "https://releases.ubuntu.com/noble.iso" {
+		t.Fatalf("expected only the iso URL, got %v", m.urlSuggestions)
+	}
+}
+
+func TestURLSuggestions_CycleAndAccept(t *testing.T) {
+	m := newURLHistoryTestModel(
+		"https://example.com/a.zip",
+		"https://example.com/b.zip",
+	)
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyCtrlN})
+	m = updated.(RootModel)
+	if m.urlSuggestionCursor != 1 {
+		t.Fatalf("expected cursor 1 after ctrl+n, got %d", m.urlSuggestionCursor)
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m = updated.(RootModel)
+	if got := m.inputs[0].Value(); got != "https://example.com/b.zip" {
+		t.Fatalf("expected accepted suggestion in URL input, got %q", got)
+	}
+	if m.state != InputState {
+		t.Fatalf("expected to stay in input state, got %v", m.state)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/types"
 	"github.com/surge-downloader/surge/internal/tui/colors"
 	"github.com/surge-downloader/surge/internal/tui/components"
 	"github.com/surge-downloader/surge/internal/utils"
@@ -97,6 +98,11 @@ func (m RootModel) View() string {
 			Width:           80,
 			Height:          11,
 		}
+		if m.focusedInput == 0 && len(m.urlSuggestions) > 0 {
+			modal.Suggestions = m.urlSuggestions
+			modal.SelectedSuggestion = m.urlSuggestionCursor
+			modal.Height += len(m.urlSuggestions) + 1
+		}
 		box := modal.RenderWithBtopBox(renderBtopBox, PaneTitleStyle)
 		return m.renderModalWithOverlay(box)
 	}
@@ -188,6 +194,25 @@ func (m RootModel) View() string {
 		return m.renderModalWithOverlay(box)
 	}
 
+	if m.state == OrphanCleanupState && m.orphans != nil {
+		modal := components.ConfirmationModal{
+			Title:       "Orphaned Downloads",
+			Message:     "Remove " + orphansSummary(m.orphans) + "?",
+			Detail:      orphansDetail(m.orphans),
+			Keys:        m.keys.BatchConfirm,
+			Help:        m.help,
+			BorderColor: colors.NeonPink,
+			Width:       70,
+			Height:      10,
+		}
+		box := modal.RenderWithBtopBox(renderBtopBox, PaneTitleStyle)
+		return m.renderModalWithOverlay(box)
+	}
+
+	if m.state == ErrorHistoryState {
+		return m.viewErrorHistory()
+	}
+
 	if m.state == UpdateAvailableState && m.UpdateInfo != nil {
 		modal := components.ConfirmationModal{
 			Title:       "⬆ Update Available",
@@ -758,6 +783,9 @@ func (m RootModel) View() string {
 	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Top, fullView)
 }
 
+// maxWorkerErrorRows caps the heatmap rows shown in the details pane
+const maxWorkerErrorRows = 8
+
 // Helper to render the detailed info pane
 func renderFocusedDetails(d *DownloadModel, w int) string {
 	pct := 0.0
@@ -803,11 +831,24 @@ func renderFocusedDetails(d *DownloadModel, w int) string {
 		displayPath = d.URL
 	}
 
-	fileInfoContent := lipgloss.JoinVertical(lipgloss.Left,
+	fileInfoLines := []string{
 		lipgloss.JoinHorizontal(lipgloss.Left, StatsLabelStyle.Render("File: "), StatsValueStyle.Render(truncateString(displayFilename, contentWidth-8))),
 		lipgloss.JoinHorizontal(lipgloss.Left, StatsLabelStyle.Render("Path: "), StatsValueStyle.Render(truncateString(displayPath, contentWidth-8))),
 		lipgloss.JoinHorizontal(lipgloss.Left, StatsLabelStyle.Render("ID:   "), lipgloss.NewStyle().Foreground(colors.LightGray).Render(d.ID)),
-	)
+	}
+	if len(d.Tags) > 0 {
+		fileInfoLines = append(fileInfoLines,
+			lipgloss.JoinHorizontal(lipgloss.Left, StatsLabelStyle.Render("Tags: "), StatsValueStyle.Render(truncateString("#"+strings.Join(d.Tags, " #"), contentWidth-8))))
+	}
+	if d.Note != "" {
+		fileInfoLines = append(fileInfoLines,
+			lipgloss.JoinHorizontal(lipgloss.Left, StatsLabelStyle.Render("Note: "), StatsValueStyle.Render(truncateString(strings.Join(strings.Fields(d.Note), " "), contentWidth-8))))
+	}
+	if len(d.Metadata) > 0 {
+		fileInfoLines = append(fileInfoLines,
+			lipgloss.JoinHorizontal(lipgloss.Left, StatsLabelStyle.Render("Meta: "), StatsValueStyle.Render(truncateString(utils.FormatMetadata(d.Metadata), contentWidth-8))))
+	}
+	fileInfoContent := lipgloss.JoinVertical(lipgloss.Left, fileInfoLines...)
 	fileSection := sectionStyle.Render(fileInfoContent)
 
 	// --- 3. Progress Section ---
@@ -937,7 +978,44 @@ func renderFocusedDetails(d *DownloadModel, w int) string {
 		mirrorSection = sectionStyle.Render(lipgloss.JoinVertical(lipgloss.Left, mirrorLabel, mirrorStats))
 	}
 
-	// --- 6. Error Section ---
+	// --- 6. Response Section ---
+	var responseSection string
+	if r := d.Response; r != nil {
+		var lines []string
+		for _, field := range []struct{ label, value string }{
+			{"Type:   ", r.ContentType},
+			{"Server: ", r.Server},
+			{"ETag:   ", r.ETag},
+			{"Mod:    ", r.LastModified},
+			{"Final:  ", r.FinalURL},
+		} {
+			if field.value != "" {
+				lines = append(lines, lipgloss.JoinHorizontal(lipgloss.Left,
+					StatsLabelStyle.Render(field.label), StatsValueStyle.Render(truncateString(field.value, contentWidth-10))))
+			}
+		}
+		if len(lines) > 0 {
+			responseLabel := StatsLabelStyle.Render("Response")
+			responseSection = sectionStyle.Render(lipgloss.JoinVertical(lipgloss.Left, append([]string{responseLabel}, lines...)...))
+		}
+	}
+
+	// --- 7. Worker Error Heatmap ---
+	var workerErrSection string
+	if d.state != nil {
+		if lines := types.FormatWorkerErrorHeatmap(d.state.GetWorkerErrors()); len(lines) > 0 {
+			// Header plus the first rows; the debug log has the full map
+			if len(lines) > maxWorkerErrorRows+1 {
+				more := len(lines) - maxWorkerErrorRows - 1
+				lines = append(lines[:maxWorkerErrorRows+1], fmt.Sprintf("+%d more workers", more))
+			}
+			workerErrLabel := StatsLabelStyle.Render("Worker Errors")
+			workerErrRows := lipgloss.NewStyle().Foreground(colors.LightGray).Render(strings.Join(lines, "\n"))
+			workerErrSection = sectionStyle.Render(lipgloss.JoinVertical(lipgloss.Left, workerErrLabel, workerErrRows))
+		}
+	}
+
+	// --- 8. Error Section ---
 	var errorSection string
 	if d.err != nil {
 		errorSection = sectionStyle.
@@ -960,6 +1038,16 @@ func renderFocusedDetails(d *DownloadModel, w int) string {
 		parts = append(parts, mirrorSection)
 	}
 
+	if responseSection != "" {
+		parts = append(parts, divider)
+		parts = append(parts, responseSection)
+	}
+
+	if workerErrSection != "" {
+		parts = append(parts, divider)
+		parts = append(parts, workerErrSection)
+	}
+
 	if errorSection != "" {
 		parts = append(parts, divider)
 		parts = append(parts, errorSection)
@@ -972,6 +1060,35 @@ func renderFocusedDetails(d *DownloadModel, w int) string {
 		Render(content)
 }
 
+// phaseLabel describes a post-download phase that is still running, or ""
+func phaseLabel(d *DownloadModel) string {
+	if !d.done || d.err != nil || !types.IsFinalizingPhase(d.phase) {
+		return ""
+	}
+	name := strings.ToUpper(d.phase[:1]) + d.phase[1:]
+	return fmt.Sprintf("⚙ %s %.0f%%", name, d.phaseProgress)
+}
+
+// pauseReasons name the reasons a download was paused without the user asking
+var pauseReasons = map[string]string{
+	types.PauseShutdown:    "on exit",
+	types.PauseInterrupted: "interrupted",
+	types.PauseDiskFull:    "disk full",
+}
+
+// pauseLabel describes why a paused download stopped when the user didn't
+// pause it, or ""
+func pauseLabel(d *DownloadModel) string {
+	if !d.paused || d.done || d.err != nil {
+		return ""
+	}
+	reason, ok := pauseReasons[d.pauseReason]
+	if !ok {
+		return ""
+	}
+	return "⏸ Paused (" + reason + ")"
+}
+
 func getDownloadStatus(d *DownloadModel) string {
 	if d.pausing {
 		return lipgloss.NewStyle().Foreground(colors.StatePaused).Render("⏸ Pausing...")
@@ -979,6 +1096,12 @@ func getDownloadStatus(d *DownloadModel) string {
 	if d.resuming {
 		return lipgloss.NewStyle().Foreground(colors.StateDownloading).Render("▶ Resuming...")
 	}
+	if label := phaseLabel(d); label != "" {
+		return lipgloss.NewStyle().Foreground(colors.StateDownloading).Render(label)
+	}
+	if label := pauseLabel(d); label != "" {
+		return lipgloss.NewStyle().Foreground(colors.StatePaused).Render(label)
+	}
 	status := components.DetermineStatus(d.done, d.paused, d.err != nil, d.Speed, d.Downloaded)
 	return status.Render()
 }
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package utils
+
+import (
+	"errors"
+	"syscall"
+)
+
+// IsDiskFull reports whether err means the filesystem ran out of space
+func IsDiskFull(err error) bool {
+	return errors.Is(err, syscall.ENOSPC)
+}
@@ -7,6 +7,12 @@ import (
 
 // CopyFile centralizes the rename-fallback copy path used by download finalization.
 func CopyFile(src, dst string) error {
+	return CopyFileWithProgress(src, dst, nil)
+}
+
+// CopyFileWithProgress is CopyFile that calls onProgress with the bytes
+// copied so far after each block. onProgress may be nil.
+func CopyFileWithProgress(src, dst string, onProgress func(copied int64)) error {
 	in, err := os.Open(src)
 	if err != nil {
 		return err
@@ -27,9 +33,32 @@ func CopyFile(src, dst string) error {
 		}
 	}()
 
-	buf := make([]byte, 1<<20)
-	if _, err := io.CopyBuffer(out, in, buf); err != nil {
-		return err
+	if onProgress == nil {
+		buf := make([]byte, 1<<20)
+		if _, err := io.CopyBuffer(out, in, buf); err != nil {
+			return err
+		}
+		return out.Sync()
+	}
+
+	// Copy in segments so progress can be reported without giving up the
+	// kernel copy fast paths os.File.ReadFrom uses
+	var copied int64
+	for {
+		n, err := io.CopyN(out, in, copyProgressStep)
+		copied += n
+		if n > 0 {
+			onProgress(copied)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
 	}
 	return out.Sync()
 }
+
+// copyProgressStep is how much CopyFileWithProgress copies between reports
+const copyProgressStep = 16 << 20
@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"mime"
 	"net/http"
+	"path"
 	"strings"
 )
 
@@ -31,3 +33,30 @@ func CopyRedirectHeaders(dst, src *http.Request) {
 		}
 	}
 }
+
+// IsTransferGzip reports whether resp carries a gzip Content-Encoding layered
+// on top of the payload, as opposed to a .gz file the server mislabelled.
+// Lengths and byte ranges of such responses describe the compressed stream,
+// not the file, so they can only be fetched sequentially and decoded.
+func IsTransferGzip(resp *http.Response, filename string) bool {
+	if resp == nil {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "gzip", "x-gzip":
+	default:
+		return false
+	}
+
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".gz", ".tgz", ".gzip":
+		return false
+	}
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+		switch mediaType {
+		case "application/gzip", "application/x-gzip", "application/x-tgz":
+			return false
+		}
+	}
+	return true
+}
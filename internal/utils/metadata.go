@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ParseMetadata turns "key=value" pairs into a map. The value may contain
+// '=' and may be empty; the key may not.
+func ParseMetadata(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	m := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		k, v, ok := strings.Cut(pair, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid metadata %q, expected key=value", pair)
+		}
+		m[k] = v
+	}
+	return m, nil
+}
+
+// ParseHeaders turns "Name: value" lines, as curl takes them, into a header
+// map keyed by canonical name
+func ParseHeaders(lines []string) (map[string]string, error) {
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	h := make(map[string]string, len(lines))
+	for _, line := range lines {
+		k, v, ok := strings.Cut(line, ":")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" || strings.ContainsAny(k, " \t") {
+			return nil, fmt.Errorf("invalid header %q, expected Name: value", line)
+		}
+		h[http.CanonicalHeaderKey(k)] = strings.TrimSpace(v)
+	}
+	return h, nil
+}
+
+// FormatMetadata renders metadata as "key=value" pairs sorted by key
+func FormatMetadata(m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + m[k]
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMetadata(t *testing.T) {
+	got, err := ParseMetadata([]string{"source=forum", " url = a=b", "empty="})
+	if err != nil {
+		t.Fatalf("ParseMetadata: %v", err)
+	}
+	want := map[string]string{"source": "forum", "url": " a=b", "empty": ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseMetadata = %q, want %q", got, want)
+	}
+
+	for _, bad := range []string{"novalue", "=x"} {
+		if _, err := ParseMetadata([]string{bad}); err == nil {
+			t.Errorf("ParseMetadata(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	got, err := ParseHeaders([]string{"cookie: a=1; b=2", "Referer:https://example.com/x"})
+	if err != nil {
+		t.Fatalf("ParseHeaders: %v", err)
+	}
+	want := map[string]string{"Cookie": "a=1; b=2", "Referer": "https://example.com/x"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseHeaders = %q, want %q", got, want)
+	}
+
+	for _, bad := range []string{"novalue", ": x", "Bad Name: x"} {
+		if _, err := ParseHeaders([]string{bad}); err == nil {
+			t.Errorf("ParseHeaders(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestFormatMetadata(t *testing.T) {
+	if got := FormatMetadata(map[string]string{"b": "2", "a": "1"}); got != "a=1, b=2" {
+		t.Errorf("FormatMetadata = %q", got)
+	}
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// OpenWithSystem opens a file or URL with the system's default application
+func OpenWithSystem(path string) error {
+	return startOpener(openerCommand(runtime.GOOS, path, false))
+}
+
+// RevealInFolder opens the folder holding path in the system's file
+// manager, with path selected where the file manager supports it. When
+// path no longer exists its folder is opened as it is.
+func RevealInFolder(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return startOpener(openerCommand(runtime.GOOS, filepath.Dir(path), false))
+	}
+	return startOpener(openerCommand(runtime.GOOS, path, true))
+}
+
+// openerCommand returns the program and arguments that open path on goos,
+// or reveal it in its folder
+func openerCommand(goos, path string, reveal bool) (string, []string) {
+	switch goos {
+	case "darwin":
+		if reveal {
+			return "open", []string{"-R", path}
+		}
+		return "open", []string{path}
+	case "windows":
+		if reveal {
+			return "explorer", []string{"/select," + path}
+		}
+		return "cmd", []string{"/c", "start", "", path}
+	}
+	// linux and others: xdg-open can't select a file, so open its folder
+	if reveal {
+		path = filepath.Dir(path)
+	}
+	return "xdg-open", []string{path}
+}
+
+// startOpener starts an opener without waiting for the application it
+// launches to exit
+func startOpener(name string, args []string) error {
+	cmd := exec.Command(name, args...)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go func() {
+		_ = cmd.Wait()
+	}()
+	return nil
+}
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestOpenerCommand(t *testing.T) {
+	for _, c := range []struct {
+		goos   string
+		path   string
+		reveal bool
+		name   string
+		args   []string
+	}{
+		{"linux", "/srv/isos/a.iso", false, "xdg-open", []string{"/srv/isos/a.iso"}},
+		{"linux", "/srv/isos/a.iso", true, "xdg-open", []string{"/srv/isos"}},
+		{"freebsd", "/srv/isos", false, "xdg-open", []string{"/srv/isos"}},
+		{"darwin", "/srv/isos/a.iso", false, "open", []string{"/srv/isos/a.iso"}},
+		{"darwin", "/srv/isos/a.iso", true, "open", []string{"-R", "/srv/isos/a.iso"}},
+		{"windows", `C:\isos\a.iso`, false, "cmd", []string{"/c", "start", "", `C:\isos\a.iso`}},
+		{"windows", `C:\isos\a.iso`, true, "explorer", []string{`/select,C:\isos\a.iso`}},
+	} {
+		name, args := openerCommand(c.goos, c.path, c.reveal)
+		if name != c.name || !slices.Equal(args, c.args) {
+			t.Errorf("openerCommand(%s, %q, %v) = %s %q, want %s %q", c.goos, c.path, c.reveal, name, args, c.name, c.args)
+		}
+	}
+}
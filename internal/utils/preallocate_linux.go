@@ -0,0 +1,25 @@
+//go:build linux
+
+package utils
+
+import (
+	"os"
+	"syscall"
+)
+
+// PreallocateFile sizes file to size bytes. A plain truncate leaves the file
+// sparse so only downloaded ranges take up disk; dense reserves every block
+// up front and falls back to truncation where fallocate is unsupported.
+func PreallocateFile(file *os.File, size int64, dense bool) error {
+	if size <= 0 {
+		return nil
+	}
+
+	if dense {
+		if err := syscall.Fallocate(int(file.Fd()), 0, 0, size); err == nil {
+			return nil
+		}
+	}
+
+	return file.Truncate(size)
+}
@@ -0,0 +1,50 @@
+//go:build linux
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func allocatedBytes(t *testing.T, path string) int64 {
+	t.Helper()
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		t.Fatal(err)
+	}
+	return st.Blocks * 512
+}
+
+func TestPreallocateFile_SparseByDefault(t *testing.T) {
+	const size = 64 << 20
+	for _, dense := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "file.surge")
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := PreallocateFile(f, size, dense); err != nil {
+			t.Fatalf("PreallocateFile(dense=%v): %v", dense, err)
+		}
+		_ = f.Close()
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() != size {
+			t.Fatalf("dense=%v: expected size %d, got %d", dense, size, info.Size())
+		}
+
+		allocated := allocatedBytes(t, path)
+		if !dense && allocated >= size {
+			t.Errorf("expected sparse file, %d bytes allocated", allocated)
+		}
+		if dense && allocated < size {
+			t.Logf("filesystem did not reserve blocks (%d allocated); fallocate likely unsupported", allocated)
+		}
+	}
+}
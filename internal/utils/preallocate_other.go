@@ -0,0 +1,15 @@
+//go:build !linux && !windows
+
+package utils
+
+import "os"
+
+// PreallocateFile sizes file to size bytes. Truncation is sparse on the
+// filesystems these platforms use, and there is no portable way to reserve
+// blocks, so dense behaves the same.
+func PreallocateFile(file *os.File, size int64, dense bool) error {
+	if size <= 0 {
+		return nil
+	}
+	return file.Truncate(size)
+}
@@ -0,0 +1,27 @@
+//go:build windows
+
+package utils
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// PreallocateFile sizes file to size bytes. NTFS allocates every cluster on
+// SetEndOfFile, so the file is marked sparse first unless dense is set.
+// Volumes without sparse support (FAT, exFAT) are extended densely.
+func PreallocateFile(file *os.File, size int64, dense bool) error {
+	if size <= 0 {
+		return nil
+	}
+
+	if !dense {
+		var returned uint32
+		if err := windows.DeviceIoControl(windows.Handle(file.Fd()), windows.FSCTL_SET_SPARSE, nil, 0, nil, 0, &returned, nil); err != nil {
+			Debug("Could not mark %s sparse: %v", file.Name(), err)
+		}
+	}
+
+	return file.Truncate(size)
+}
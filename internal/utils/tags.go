@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"strings"
+)
+
+// NormalizeTags lowercases and trims tags, splitting any comma-separated
+// values, dropping empties and keeping the first occurrence of duplicates.
+// Tags are stored comma-joined, so a tag can never contain a comma.
+func NormalizeTags(tags []string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, raw := range tags {
+		for _, t := range strings.Split(raw, ",") {
+			t = strings.ToLower(strings.TrimSpace(t))
+			if t == "" || seen[t] {
+				continue
+			}
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// HasTag reports whether tags contains tag, ignoring case
+func HasTag(tags []string, tag string) bool {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		in   []string
+		want []string
+	}{
+		{nil, nil},
+		{[]string{"", "  "}, nil},
+		{[]string{"Work", " ISO "}, []string{"work", "iso"}},
+		{[]string{"work,media", "WORK"}, []string{"work", "media"}},
+	}
+	for _, tt := range tests {
+		if got := NormalizeTags(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("NormalizeTags(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestHasTag(t *testing.T) {
+	tags := []string{"work", "iso"}
+	if !HasTag(tags, " ISO") {
+		t.Error("expected iso to match")
+	}
+	if HasTag(tags, "media") {
+		t.Error("media should not match")
+	}
+}
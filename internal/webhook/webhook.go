@@ -0,0 +1,204 @@
+// Package webhook posts download events to the webhooks configured in
+// settings.
+//
+// Each delivery runs on its own, so a slow or failing webhook never holds up
+// the event stream or the other webhooks. Failed deliveries are retried with
+// exponential backoff.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/types"
+	"github.com/surge-downloader/surge/internal/utils"
+)
+
+// Delivery limits: attempts per event and webhook, the wait before the first
+// retry (doubled after each) and how long one request may take
+const (
+	defaultAttempts = 5
+	defaultBackoff  = time.Second
+	requestTimeout  = 10 * time.Second
+)
+
+// Payload describes one download event. It is the default body and what
+// webhook templates render.
+type Payload struct {
+	Event    string  `json:"event"` // download.started, download.completed or download.error
+	ID       string  `json:"id"`
+	Filename string  `json:"filename"`
+	URL      string  `json:"url,omitempty"`
+	Path     string  `json:"path,omitempty"`
+	Category string  `json:"category,omitempty"`
+	Size     int64   `json:"size,omitempty"`            // Bytes, when known
+	Elapsed  float64 `json:"elapsed_seconds,omitempty"` // completed
+	AvgSpeed float64 `json:"avg_speed,omitempty"`       // completed, bytes/sec
+	Error    string  `json:"error,omitempty"`           // error
+	Time     int64   `json:"time"`                      // Unix time of the event
+}
+
+// Dispatcher delivers download events to webhooks
+type Dispatcher struct {
+	hooks    func() []config.Webhook
+	lookup   func(id string) (*types.DownloadStatus, error)
+	client   *http.Client
+	attempts int
+	backoff  time.Duration
+	wg       sync.WaitGroup
+}
+
+// NewDispatcher returns a dispatcher for the webhooks hooks returns, read
+// again for every event so changes apply at once. lookup, if set, fills in
+// what an event leaves out, such as the URL of a completed download.
+func NewDispatcher(hooks func() []config.Webhook, lookup func(id string) (*types.DownloadStatus, error)) *Dispatcher {
+	return &Dispatcher{
+		hooks:    hooks,
+		lookup:   lookup,
+		client:   &http.Client{Timeout: requestTimeout},
+		attempts: defaultAttempts,
+		backoff:  defaultBackoff,
+	}
+}
+
+// Run delivers the events of stream until it is closed
+func (d *Dispatcher) Run(stream <-chan interface{}) {
+	for msg := range stream {
+		d.Handle(msg)
+	}
+}
+
+// Handle starts delivering msg to the webhooks that want it. Messages other
+// than started, completed and error are ignored.
+func (d *Dispatcher) Handle(msg interface{}) {
+	event, payload := d.payload(msg)
+	if event == "" {
+		return
+	}
+	for _, hook := range d.hooks() {
+		if !hook.Wants(event) {
+			continue
+		}
+		body, err := Render(hook, payload)
+		if err != nil {
+			utils.Debug("Webhook %s: %v", hook.ID, err)
+			continue
+		}
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			d.deliver(hook, body)
+		}()
+	}
+}
+
+// Wait blocks until every started delivery has succeeded or given up
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) payload(msg interface{}) (string, Payload) {
+	var event string
+	p := Payload{Time: time.Now().Unix()}
+	switch m := msg.(type) {
+	case events.DownloadStartedMsg:
+		event = config.WebhookStarted
+		p.ID, p.Filename, p.URL, p.Path, p.Category, p.Size = m.DownloadID, m.Filename, m.URL, m.DestPath, m.Category, m.Total
+	case events.DownloadCompleteMsg:
+		event = config.WebhookCompleted
+		p.ID, p.Filename, p.Size = m.DownloadID, m.Filename, m.Total
+		p.Elapsed, p.AvgSpeed = m.Elapsed.Seconds(), m.AvgSpeed
+	case events.DownloadErrorMsg:
+		event = config.WebhookError
+		p.ID, p.Filename, p.Path = m.DownloadID, m.Filename, m.DestPath
+		if m.Err != nil {
+			p.Error = m.Err.Error()
+		}
+	default:
+		return "", p
+	}
+	p.Event = "download." + event
+
+	if d.lookup != nil && (p.URL == "" || p.Path == "") {
+		if status, err := d.lookup(p.ID); err == nil && status != nil {
+			if p.URL == "" {
+				p.URL = status.URL
+			}
+			if p.Path == "" {
+				p.Path = status.DestPath
+			}
+			if p.Category == "" {
+				p.Category = status.Category
+			}
+		}
+	}
+	return event, p
+}
+
+// Render returns the body hook sends for p: its template executed over p, or
+// p as JSON without one. A template must produce valid JSON.
+func Render(hook config.Webhook, p Payload) ([]byte, error) {
+	tmpl, err := hook.ParseTemplate()
+	if err != nil {
+		return nil, fmt.Errorf("template: %w", err)
+	}
+	if tmpl == nil {
+		return json.Marshal(p)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, p); err != nil {
+		return nil, fmt.Errorf("template: %w", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("template did not produce valid JSON")
+	}
+	return buf.Bytes(), nil
+}
+
+// deliver posts body to hook, retrying network errors, 429 and 5xx
+// responses with backoff
+func (d *Dispatcher) deliver(hook config.Webhook, body []byte) {
+	wait := d.backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := d.post(hook, body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= d.attempts {
+			utils.Debug("Webhook %s: giving up after %d attempt(s): %v", hook.ID, attempt, err)
+			return
+		}
+		utils.Debug("Webhook %s: attempt %d failed, retrying in %s: %v", hook.ID, attempt, wait, err)
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// post sends one request and reports whether a failure is worth retrying
+func (d *Dispatcher) post(hook config.Webhook, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range hook.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("webhook returned %s", resp.Status)
+}
@@ -0,0 +1,147 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/surge-downloader/surge/internal/config"
+	"github.com/surge-downloader/surge/internal/engine/events"
+	"github.com/surge-downloader/surge/internal/engine/types"
+)
+
+// recorder is a webhook endpoint that answers with statuses in turn, then 200
+type recorder struct {
+	mu       sync.Mutex
+	bodies   []string
+	headers  []http.Header
+	statuses []int
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bodies = append(r.bodies, string(body))
+	r.headers = append(r.headers, req.Header.Clone())
+	if len(r.statuses) > 0 {
+		w.WriteHeader(r.statuses[0])
+		r.statuses = r.statuses[1:]
+	}
+}
+
+func newTestDispatcher(hooks ...config.Webhook) *Dispatcher {
+	d := NewDispatcher(func() []config.Webhook { return hooks }, func(id string) (*types.DownloadStatus, error) {
+		if id != "dl-1" {
+			return nil, errors.New("not found")
+		}
+		return &types.DownloadStatus{ID: id, URL: "https://example.com/file.iso", DestPath: "/data/file.iso"}, nil
+	})
+	d.backoff = time.Millisecond
+	return d
+}
+
+func TestDispatcher_DefaultPayload(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	d := newTestDispatcher(config.Webhook{ID: "h", URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer x"}})
+	d.Handle(events.DownloadCompleteMsg{DownloadID: "dl-1", Filename: "file.iso", Total: 2048, Elapsed: 2 * time.Second, AvgSpeed: 1024})
+	d.Handle(events.DownloadPausedMsg{DownloadID: "dl-1"}) // Not a webhook event
+	d.Wait()
+
+	if len(rec.bodies) != 1 {
+		t.Fatalf("deliveries = %d, want 1", len(rec.bodies))
+	}
+	var p Payload
+	if err := json.Unmarshal([]byte(rec.bodies[0]), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Event != "download.completed" || p.ID != "dl-1" || p.Size != 2048 || p.Elapsed != 2 || p.AvgSpeed != 1024 {
+		t.Errorf("payload = %+v", p)
+	}
+	if p.URL != "https://example.com/file.iso" || p.Path != "/data/file.iso" {
+		t.Errorf("lookup did not fill in url and path: %+v", p)
+	}
+	if got := rec.headers[0].Get("Authorization"); got != "Bearer x" {
+		t.Errorf("Authorization = %q", got)
+	}
+}
+
+func TestDispatcher_TemplateAndEvents(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	d := newTestDispatcher(config.Webhook{
+		ID:       "chat",
+		URL:      srv.URL,
+		Events:   []string{config.WebhookError},
+		Template: `{"text": {{json (printf "%s failed: %s" .Filename .Error)}}}`,
+	})
+	d.Handle(events.DownloadStartedMsg{DownloadID: "dl-2", Filename: "a.bin"})
+	d.Handle(events.DownloadErrorMsg{DownloadID: "dl-2", Filename: "a \"quoted\".bin", Err: errors.New("disk full")})
+	d.Wait()
+
+	if len(rec.bodies) != 1 {
+		t.Fatalf("deliveries = %v, want only the error", rec.bodies)
+	}
+	if want := `{"text": "a \"quoted\".bin failed: disk full"}`; rec.bodies[0] != want {
+		t.Errorf("body = %s, want %s", rec.bodies[0], want)
+	}
+}
+
+func TestDispatcher_Retries(t *testing.T) {
+	rec := &recorder{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	d := newTestDispatcher(config.Webhook{ID: "h", URL: srv.URL})
+	d.Handle(events.DownloadStartedMsg{DownloadID: "dl-1", Filename: "file.iso"})
+	d.Wait()
+	if len(rec.bodies) != 3 {
+		t.Errorf("attempts = %d, want 2 failures and a success", len(rec.bodies))
+	}
+
+	// Client errors other than 429 are not retried
+	var attempts atomic.Int32
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer bad.Close()
+	d = newTestDispatcher(config.Webhook{ID: "h", URL: bad.URL})
+	d.Handle(events.DownloadStartedMsg{DownloadID: "dl-1"})
+	d.Wait()
+	if n := attempts.Load(); n != 1 {
+		t.Errorf("attempts after 400 = %d, want 1", n)
+	}
+
+	// Retries stop after the last attempt
+	attempts.Store(0)
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+	d = newTestDispatcher(config.Webhook{ID: "h", URL: down.URL})
+	d.Handle(events.DownloadStartedMsg{DownloadID: "dl-1"})
+	d.Wait()
+	if n := attempts.Load(); n != defaultAttempts {
+		t.Errorf("attempts against a failing webhook = %d, want %d", n, defaultAttempts)
+	}
+}
+
+func TestRender_RejectsInvalidJSON(t *testing.T) {
+	hook := config.Webhook{URL: "https://example.com/", Template: `{"text": {{.Filename}}}`}
+	if _, err := Render(hook, Payload{Filename: "a.bin"}); err == nil {
+		t.Error("expected an error for a template that renders invalid JSON")
+	}
+}
@@ -0,0 +1,315 @@
+// Package websocket is the server side of RFC 6455, as much of it as the
+// event stream needs: the upgrade handshake, unfragmented writes, reads of
+// masked client frames, ping/pong and the closing handshake.
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Opcodes of the frames a Conn reads and writes
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+
+	continuationFrame = 0
+)
+
+// Close codes sent with a close frame
+const (
+	CloseNormal    = 1000
+	CloseGoingAway = 1001
+	CloseProtocol  = 1002
+	CloseTooBig    = 1009
+)
+
+// MaxMessageSize bounds a message read from a client. The event stream only
+// expects control frames back, so this is generous.
+const MaxMessageSize = 1 << 20
+
+// acceptGUID is appended to the client's key to prove the server speaks
+// WebSocket
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// ErrClosed is returned by ReadMessage once the client closed the connection
+var ErrClosed = errors.New("websocket: connection closed")
+
+// Conn is an upgraded connection. Writes may come from any goroutine; reads
+// from one at a time.
+type Conn struct {
+	conn net.Conn
+	br   *bufio.Reader
+
+	wmu    sync.Mutex
+	closed bool
+
+	// OnPong is called with the payload of every pong read, if set
+	OnPong func(data []byte)
+}
+
+// IsUpgrade reports whether r asks to switch to WebSocket
+func IsUpgrade(r *http.Request) bool {
+	return headerHasToken(r.Header, "Connection", "upgrade") && headerHasToken(r.Header, "Upgrade", "websocket")
+}
+
+// Upgrade completes the handshake of r and takes over its connection. If the
+// client offers one of protocols, the first one it offers is selected. On
+// failure an HTTP error has been written to w.
+func Upgrade(w http.ResponseWriter, r *http.Request, protocols ...string) (*Conn, error) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return nil, fmt.Errorf("websocket: method %s", r.Method)
+	}
+	if !IsUpgrade(r) {
+		http.Error(w, "Expected a WebSocket upgrade", http.StatusUpgradeRequired)
+		return nil, errors.New("websocket: not an upgrade request")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "Unsupported WebSocket version", http.StatusUpgradeRequired)
+		return nil, errors.New("websocket: unsupported version")
+	}
+	key := strings.TrimSpace(r.Header.Get("Sec-WebSocket-Key"))
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		http.Error(w, "Invalid Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, errors.New("websocket: invalid key")
+	}
+
+	selected := ""
+	for _, offered := range Subprotocols(r) {
+		for _, p := range protocols {
+			if offered == p && selected == "" {
+				selected = p
+			}
+		}
+	}
+
+	netConn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, "Upgrade unsupported", http.StatusInternalServerError)
+		return nil, fmt.Errorf("websocket: %w", err)
+	}
+	// The server may have set deadlines for ordinary requests
+	_ = netConn.SetDeadline(time.Time{})
+
+	var resp strings.Builder
+	resp.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	resp.WriteString("Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n")
+	if selected != "" {
+		resp.WriteString("Sec-WebSocket-Protocol: " + selected + "\r\n")
+	}
+	resp.WriteString("\r\n")
+	if _, err := rw.WriteString(resp.String()); err == nil {
+		err = rw.Flush()
+	}
+	if err != nil {
+		_ = netConn.Close()
+		return nil, fmt.Errorf("websocket: handshake: %w", err)
+	}
+	return &Conn{conn: netConn, br: rw.Reader}, nil
+}
+
+// Subprotocols returns the protocols the client offered, in its order
+func Subprotocols(r *http.Request) []string {
+	var out []string
+	for _, v := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				out = append(out, p)
+			}
+		}
+	}
+	return out
+}
+
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WriteMessage sends data as one frame, giving up after timeout (0 waits
+// forever)
+func (c *Conn) WriteMessage(opcode byte, data []byte, timeout time.Duration) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	return c.writeFrameLocked(opcode, data, timeout)
+}
+
+func (c *Conn) writeFrameLocked(opcode byte, data []byte, timeout time.Duration) error {
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | opcode // FIN, no fragmentation
+	switch n := len(data); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+
+	deadline := time.Time{}
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	if err := c.conn.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+	bufs := net.Buffers{header, data}
+	_, err := bufs.WriteTo(c.conn)
+	return err
+}
+
+// ReadMessage returns the next data message from the client. Pings are
+// answered and pongs passed to OnPong on the way. When the client closes, the
+// close is echoed and ErrClosed returned.
+func (c *Conn) ReadMessage() (opcode byte, data []byte, err error) {
+	var message []byte
+	var messageOp byte
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch op {
+		case PingMessage:
+			if err := c.WriteMessage(PongMessage, payload, time.Second*5); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			if c.OnPong != nil {
+				c.OnPong(payload)
+			}
+			continue
+		case CloseMessage:
+			code := uint16(CloseNormal)
+			if len(payload) >= 2 {
+				code = binary.BigEndian.Uint16(payload)
+			}
+			_ = c.Close(code)
+			return 0, nil, ErrClosed
+		case TextMessage, BinaryMessage:
+			if message != nil {
+				_ = c.Close(CloseProtocol)
+				return 0, nil, errors.New("websocket: new message inside a fragmented one")
+			}
+			messageOp, message = op, payload
+		case continuationFrame:
+			if message == nil {
+				_ = c.Close(CloseProtocol)
+				return 0, nil, errors.New("websocket: continuation without a message")
+			}
+			message = append(message, payload...)
+		default:
+			_ = c.Close(CloseProtocol)
+			return 0, nil, fmt.Errorf("websocket: unknown opcode %d", op)
+		}
+
+		if len(message) > MaxMessageSize {
+			_ = c.Close(CloseTooBig)
+			return 0, nil, errors.New("websocket: message too big")
+		}
+		if fin {
+			return messageOp, message, nil
+		}
+	}
+}
+
+// readFrame reads one frame and unmasks its payload
+func (c *Conn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = head[0]&0x80 != 0
+	opcode = head[0] & 0x0F
+	if head[0]&0x70 != 0 {
+		_ = c.Close(CloseProtocol)
+		return false, 0, nil, errors.New("websocket: reserved bits set")
+	}
+	if head[1]&0x80 == 0 {
+		_ = c.Close(CloseProtocol)
+		return false, 0, nil, errors.New("websocket: client frame not masked")
+	}
+
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if opcode >= CloseMessage && (length > 125 || !fin) {
+		_ = c.Close(CloseProtocol)
+		return false, 0, nil, errors.New("websocket: invalid control frame")
+	}
+	if length > MaxMessageSize {
+		_ = c.Close(CloseTooBig)
+		return false, 0, nil, errors.New("websocket: frame too big")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
+
+// Close sends a close frame with code, if none was sent yet, and closes the
+// connection
+func (c *Conn) Close(code uint16) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	_ = c.writeFrameLocked(CloseMessage, binary.BigEndian.AppendUint16(nil, code), time.Second)
+	return c.conn.Close()
+}
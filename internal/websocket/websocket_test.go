@@ -0,0 +1,162 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dial opens a raw connection to srv and sends an upgrade request with headers
+func dial(t *testing.T, srv *httptest.Server, headers string) (net.Conn, *bufio.Reader, string) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req := "GET / HTTP/1.1\r\nHost: test\r\n" + headers + "\r\n"
+	if _, err := io.WriteString(conn, req); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(conn)
+	var head strings.Builder
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading handshake: %v", err)
+		}
+		if line == "\r\n" {
+			break
+		}
+		head.WriteString(line)
+	}
+	return conn, br, head.String()
+}
+
+// writeClientFrame sends a masked frame, as a client must
+func writeClientFrame(t *testing.T, conn net.Conn, opcode byte, payload []byte) {
+	t.Helper()
+	mask := [4]byte{1, 2, 3, 4}
+	frame := []byte{0x80 | opcode, 0x80 | byte(len(payload))}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readServerFrame reads an unmasked frame of up to 64 KiB
+func readServerFrame(t *testing.T, br *bufio.Reader) (byte, []byte) {
+	t.Helper()
+	var head [2]byte
+	if _, err := io.ReadFull(br, head[:]); err != nil {
+		t.Fatalf("reading frame: %v", err)
+	}
+	length := int(head[1] & 0x7F)
+	if length == 126 {
+		var ext [2]byte
+		_, _ = io.ReadFull(br, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(br, payload); err != nil {
+		t.Fatalf("reading payload: %v", err)
+	}
+	return head[0] & 0x0F, payload
+}
+
+const upgradeHeaders = "Upgrade: websocket\r\nConnection: keep-alive, Upgrade\r\nSec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"
+
+func TestUpgradeAndEcho(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, "surge.events")
+		if err != nil {
+			return
+		}
+		for {
+			op, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			_ = conn.WriteMessage(op, append([]byte("echo: "), data...), time.Second)
+		}
+	}))
+	defer srv.Close()
+
+	conn, br, head := dial(t, srv, upgradeHeaders+"Sec-WebSocket-Protocol: bearer.x, surge.events\r\n")
+	// The accept key for the RFC 6455 sample nonce
+	if !strings.HasPrefix(head, "HTTP/1.1 101") || !strings.Contains(head, "Sec-WebSocket-Accept: s3pPLMBiTxaQ9kYGzzhZRbK+xOo=") {
+		t.Fatalf("handshake = %q", head)
+	}
+	if !strings.Contains(head, "Sec-WebSocket-Protocol: surge.events") {
+		t.Errorf("protocol not selected: %q", head)
+	}
+
+	writeClientFrame(t, conn, TextMessage, []byte("hi"))
+	if op, data := readServerFrame(t, br); op != TextMessage || string(data) != "echo: hi" {
+		t.Errorf("echo = %d %q", op, data)
+	}
+
+	writeClientFrame(t, conn, PingMessage, []byte("p"))
+	if op, data := readServerFrame(t, br); op != PongMessage || string(data) != "p" {
+		t.Errorf("ping answered with %d %q, want a pong", op, data)
+	}
+
+	writeClientFrame(t, conn, CloseMessage, binary.BigEndian.AppendUint16(nil, CloseNormal))
+	if op, data := readServerFrame(t, br); op != CloseMessage || binary.BigEndian.Uint16(data) != CloseNormal {
+		t.Errorf("close answered with %d %v", op, data)
+	}
+}
+
+func TestUpgradeRejectsPlainRequests(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = Upgrade(w, r)
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusUpgradeRequired {
+		t.Errorf("status = %d, want 426", resp.StatusCode)
+	}
+}
+
+func TestReadRejectsUnmaskedFrames(t *testing.T) {
+	errs := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			errs <- err
+			return
+		}
+		_, _, err = conn.ReadMessage()
+		errs <- err
+	}))
+	defer srv.Close()
+
+	conn, _, _ := dial(t, srv, upgradeHeaders)
+	if _, err := conn.Write([]byte{0x81, 0x02, 'h', 'i'}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errs:
+		if err == nil || !strings.Contains(err.Error(), "not masked") {
+			t.Errorf("ReadMessage = %v, want an unmasked frame error", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ReadMessage did not return")
+	}
+}